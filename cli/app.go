@@ -14,7 +14,9 @@ import (
 	"github.com/samber/lo"
 	"github.com/urfave/cli/v3"
 
+	"go.viam.com/rdk/data/convert"
 	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/services/datamanager/builtin/shared"
 )
 
 // CLI flags.
@@ -154,6 +156,13 @@ const (
 	dataFlagIndexName                      = "index-name"
 	dataFlagIndexSpecFile                  = "index-path"
 	dataFlagLimit                          = "limit"
+	dataFlagCaptureDir                     = "capture-dir"
+	dataFlagValuePath                      = "value-path"
+	dataFlagBucket                         = "bucket"
+	dataFlagCaptureFormat                  = "format"
+	dataFlagInProgressOnly                 = "in-progress-only"
+	dataFlagKeyFile                        = "key-file"
+	dataFlagPreviousKeyFiles               = "previous-key-files"
 
 	datapipelineFlagSchedule       = "schedule"
 	datapipelineFlagEnableBackfill = "enable-backfill"
@@ -179,6 +188,9 @@ const (
 	tunnelFlagLocalPort       = "local-port"
 	tunnelFlagDestinationPort = "destination-port"
 
+	navigationFlagService = "service"
+	navigationFlagFormat  = "format"
+
 	organizationFlagSupportEmail = "support-email"
 	organizationFlagLogoPath     = "logo-path"
 
@@ -300,6 +312,18 @@ var commonFilterFlags = []cli.Flag{
 	},
 }
 
+var dataCaptureKeyFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:      dataFlagKeyFile,
+		Usage:     "file holding the current key capture files are encrypted with, as hex or base64",
+		TakesFile: true,
+	},
+	&cli.StringSliceFlag{
+		Name:  dataFlagPreviousKeyFiles,
+		Usage: "files holding keys capture files were encrypted with before the current key was rotated",
+	},
+}
+
 var dataTagByIDsFlags = []cli.Flag{
 	&cli.StringSliceFlag{
 		Name:     generalFlagTags,
@@ -1817,6 +1841,122 @@ Note: There is no progress meter while copying is in progress.
 						},
 					},
 				},
+				{
+					Name:            "capture",
+					Usage:           "work with data captured on this machine",
+					UsageText:       createUsageText("data capture", nil, false, true),
+					HideHelpCommand: true,
+					Commands: []*cli.Command{
+						{
+							Name:  "query",
+							Usage: "aggregate captured tabular data without uploading it",
+							UsageText: createUsageText("data capture query",
+								[]string{generalFlagResourceName, generalFlagMethod, dataFlagValuePath}, true, false),
+							Flags: append([]cli.Flag{
+								&cli.StringFlag{
+									Name:      dataFlagCaptureDir,
+									Usage:     "capture directory of the data manager",
+									Value:     shared.ViamCaptureDotDir,
+									TakesFile: true,
+								},
+								&cli.StringFlag{
+									Name:     generalFlagResourceName,
+									Required: true,
+									Usage:    "name of the captured resource",
+								},
+								&cli.StringFlag{
+									Name:     generalFlagMethod,
+									Required: true,
+									Usage:    "captured method name",
+								},
+								&cli.StringFlag{
+									Name:     dataFlagValuePath,
+									Required: true,
+									Usage:    "path to the numeric value to aggregate in each reading, e.g. 'readings.temperature' or 'pose.x'",
+								},
+								&cli.StringFlag{
+									Name:  generalFlagStart,
+									Usage: "ISO-8601 timestamp in RFC3339 format indicating the start of the interval",
+								},
+								&cli.StringFlag{
+									Name:  generalFlagEnd,
+									Usage: "ISO-8601 timestamp in RFC3339 format indicating the end of the interval",
+								},
+								&cli.DurationFlag{
+									Name:  dataFlagBucket,
+									Usage: "size of the buckets to aggregate into, e.g. 1m; aggregates the whole interval if omitted",
+								},
+							}, dataCaptureKeyFlags...),
+							Action: createActionCommandWithT[dataCaptureQueryArgs](DataCaptureQueryAction),
+						},
+						{
+							Name:  "convert",
+							Usage: "convert capture files to parquet, mcap or a folder of files for offline analysis",
+							Description: `Converts the completed capture files in the capture directory:
+  parquet: a parquet file of the tabular readings of each resource and method, with a column per key
+  mcap:    capture.mcap, with a channel per resource and method
+  files:   the images, point clouds and other files in binary readings, listed in index.json`,
+							UsageText: createUsageText("data capture convert",
+								[]string{generalFlagDestination, dataFlagCaptureFormat}, true, false),
+							Flags: append([]cli.Flag{
+								&cli.StringFlag{
+									Name:      dataFlagCaptureDir,
+									Usage:     "capture directory of the data manager",
+									Value:     shared.ViamCaptureDotDir,
+									TakesFile: true,
+								},
+								&cli.StringFlag{
+									Name:      generalFlagDestination,
+									Required:  true,
+									Usage:     "output directory for the converted data",
+									TakesFile: true,
+								},
+								&cli.StringFlag{
+									Name:     dataFlagCaptureFormat,
+									Required: true,
+									Usage: formatAcceptedValues("format to convert to",
+										string(convert.FormatParquet), string(convert.FormatMCAP), string(convert.FormatFiles)),
+								},
+							}, dataCaptureKeyFlags...),
+							Action: createActionCommandWithT[dataCaptureConvertArgs](DataCaptureConvertAction),
+						},
+						{
+							Name:  "repair",
+							Usage: "salvage the readable records of truncated or corrupt capture files",
+							Description: `Rewrites each capture file with a truncated or corrupt record, such as those left behind when the
+machine lost power while capturing, keeping the records before it. In progress files are completed so they can sync,
+and files without any readable records are removed. Stop viam-server before repairing its capture directory.`,
+							UsageText: createUsageText("data capture repair", nil, true, false),
+							Flags: append([]cli.Flag{
+								&cli.StringFlag{
+									Name:      dataFlagCaptureDir,
+									Usage:     "capture directory of the data manager",
+									Value:     shared.ViamCaptureDotDir,
+									TakesFile: true,
+								},
+								&cli.BoolFlag{
+									Name:  dataFlagInProgressOnly,
+									Usage: "only repair in progress capture files, skipping the checksum verification of completed ones",
+								},
+							}, dataCaptureKeyFlags...),
+							Action: createActionCommandWithT[dataCaptureRepairArgs](DataCaptureRepairAction),
+						},
+						{
+							Name:      "rewrap",
+							Usage:     "rewrap encrypted capture files with the current key, so that previous keys can be retired",
+							UsageText: createUsageText("data capture rewrap", []string{dataFlagKeyFile}, true, false),
+							Flags: append([]cli.Flag{
+								&cli.StringFlag{
+									Name:      dataFlagCaptureDir,
+									Usage:     "capture directory of the data manager",
+									Value:     shared.ViamCaptureDotDir,
+									TakesFile: true,
+								},
+							}, dataCaptureKeyFlags...),
+							Action: createActionCommandWithT[dataCaptureRewrapArgs](DataCaptureRewrapAction),
+						},
+					},
+				},
 			},
 		},
 		{
@@ -3298,6 +3438,34 @@ and --` + loginFlagKey + `.`,
 								},
 							},
 						},
+						{
+							Name:  "navigation",
+							Usage: "exchange waypoints, obstacles and bounding regions with GIS tools",
+							Commands: []*cli.Command{
+								{
+									Name:  "import",
+									Usage: "import waypoints, obstacles and bounding regions from a GeoJSON or KML file",
+									UsageText: createUsageText("machines part navigation import",
+										[]string{generalFlagPart, navigationFlagService, generalFlagPath}, true, false),
+									Description: `Import a GeoJSON or KML file, such as one drawn in QGIS or Google Earth, into a navigation service.
+Points and lines become waypoints and polygons become obstacles, unless a feature's "kind" property
+(ExtendedData in KML) says otherwise. Valid kinds are waypoint, obstacle and bounding_region. In KML, a
+Folder named after a kind sets the kind of the placemarks inside it. Polygons are turned into prisms as
+tall as their "height_mm" property, or 5m by default. Imported obstacles and bounding regions last until the service is reconfigured, so the
+configs to add to the service's obstacles and bounding_regions attributes are printed.`,
+									Flags:  append(commonPartFlags, navigationGeoFlags("path of the file to import")...),
+									Action: createActionCommandWithT[navigationGeoArgs](navigationImportAction),
+								},
+								{
+									Name:  "export",
+									Usage: "export waypoints, obstacles and bounding regions to a GeoJSON or KML file",
+									UsageText: createUsageText("machines part navigation export",
+										[]string{generalFlagPart, navigationFlagService, generalFlagPath}, true, false),
+									Flags:  append(commonPartFlags, navigationGeoFlags("path of the file to write")...),
+									Action: createActionCommandWithT[navigationGeoArgs](navigationExportAction),
+								},
+							},
+						},
 						{
 							Name:  "add-trigger",
 							Usage: "add a trigger to a machine part",
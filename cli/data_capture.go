@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/data/convert"
+)
+
+type dataCaptureQueryArgs struct {
+	CaptureDir       string
+	ResourceName     string
+	Method           string
+	ValuePath        string
+	Start            string
+	End              string
+	Bucket           time.Duration
+	KeyFile          string
+	PreviousKeyFiles []string
+}
+
+// DataCaptureQueryAction is the corresponding action for 'data capture query'.
+func DataCaptureQueryAction(ctx context.Context, cmd *cli.Command, args dataCaptureQueryArgs) error {
+	keyring, err := loadCaptureKeyring(args.KeyFile, args.PreviousKeyFiles)
+	if err != nil {
+		return err
+	}
+	query := data.TabularQuery{
+		ResourceName: args.ResourceName,
+		MethodName:   args.Method,
+		Path:         args.ValuePath,
+		BucketSize:   args.Bucket,
+	}
+	for _, t := range []struct {
+		flag  string
+		value string
+		dst   *time.Time
+	}{{generalFlagStart, args.Start, &query.Start}, {generalFlagEnd, args.End, &query.End}} {
+		if t.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, t.value)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse --%s", t.flag)
+		}
+		*t.dst = parsed
+	}
+
+	result, err := data.QueryCapturedTabularData(ctx, []string{args.CaptureDir}, query, data.WithCaptureKeyring(keyring))
+	if err != nil {
+		return err
+	}
+	w := cmd.Root().Writer
+	for _, b := range result.Buckets {
+		start := "all"
+		if query.BucketSize > 0 {
+			start = b.Start.Format(time.RFC3339)
+		}
+		printf(w, "%s\tcount=%d min=%g max=%g avg=%g", start, b.Count, b.Min, b.Max, b.Avg)
+	}
+	printf(w, "%d readings, %d without a number at %q", result.Readings, result.Skipped, args.ValuePath)
+	return nil
+}
+
+type dataCaptureConvertArgs struct {
+	CaptureDir       string
+	Destination      string
+	Format           string
+	KeyFile          string
+	PreviousKeyFiles []string
+}
+
+// DataCaptureConvertAction is the corresponding action for 'data capture convert'.
+func DataCaptureConvertAction(ctx context.Context, cmd *cli.Command, args dataCaptureConvertArgs) error {
+	keyring, err := loadCaptureKeyring(args.KeyFile, args.PreviousKeyFiles)
+	if err != nil {
+		return err
+	}
+	dst := args.Destination
+	if convert.Format(args.Format) == convert.FormatMCAP {
+		dst = filepath.Join(dst, "capture.mcap")
+	}
+	summary, err := convert.CaptureDir(ctx, args.CaptureDir, dst, convert.Format(args.Format), data.WithCaptureKeyring(keyring))
+	if err != nil {
+		return err
+	}
+	printf(cmd.Root().Writer, "Converted %d readings from %d capture files to %d files in %s",
+		summary.Readings, summary.CaptureFiles, len(summary.Outputs), args.Destination)
+	return nil
+}
+
+type dataCaptureRepairArgs struct {
+	CaptureDir       string
+	InProgressOnly   bool
+	KeyFile          string
+	PreviousKeyFiles []string
+}
+
+// DataCaptureRepairAction is the corresponding action for 'data capture repair'.
+func DataCaptureRepairAction(ctx context.Context, cmd *cli.Command, args dataCaptureRepairArgs) error {
+	keyring, err := loadCaptureKeyring(args.KeyFile, args.PreviousKeyFiles)
+	if err != nil {
+		return err
+	}
+	repairs, err := data.RepairCaptureFiles(args.CaptureDir, !args.InProgressOnly, data.WithCaptureKeyring(keyring))
+	w := cmd.Root().Writer
+	var records int
+	for _, repair := range repairs {
+		records += repair.Records
+		if repair.RepairedPath == "" {
+			printf(w, "Removed %s: no readable records", repair.Path)
+			continue
+		}
+		printf(w, "Repaired %s: salvaged %d records to %s, discarded %d bytes",
+			repair.Path, repair.Records, repair.RepairedPath, repair.DiscardedBytes)
+	}
+	printf(w, "Repaired %d capture files, salvaging %d records", len(repairs), records)
+	return err
+}
+
+type dataCaptureRewrapArgs struct {
+	CaptureDir       string
+	KeyFile          string
+	PreviousKeyFiles []string
+}
+
+// DataCaptureRewrapAction is the corresponding action for 'data capture rewrap'.
+func DataCaptureRewrapAction(ctx context.Context, cmd *cli.Command, args dataCaptureRewrapArgs) error {
+	if args.KeyFile == "" {
+		return errors.Errorf("--%s is required to rewrap capture files", dataFlagKeyFile)
+	}
+	keyring, err := loadCaptureKeyring(args.KeyFile, args.PreviousKeyFiles)
+	if err != nil {
+		return err
+	}
+	rewrapped, err := data.RewrapCaptureFiles(args.CaptureDir, keyring)
+	printf(cmd.Root().Writer, "Rewrapped %d capture files", len(rewrapped))
+	return err
+}
+
+// loadCaptureKeyring returns the keyring of the keys in keyFile and previousKeyFiles, which capture files are
+// encrypted and decrypted with, or nil if none are given.
+func loadCaptureKeyring(keyFile string, previousKeyFiles []string) (*data.CaptureKeyring, error) {
+	if keyFile == "" && len(previousKeyFiles) == 0 {
+		return nil, nil
+	}
+	var current []byte
+	if keyFile != "" {
+		key, err := data.LoadCaptureKey(keyFile, "")
+		if err != nil {
+			return nil, err
+		}
+		current = key
+	}
+	previous := make([][]byte, 0, len(previousKeyFiles))
+	for _, path := range previousKeyFiles {
+		key, err := data.LoadCaptureKey(path, "")
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+	return data.NewCaptureKeyring(current, previous...)
+}
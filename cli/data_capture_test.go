@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"go.viam.com/rdk/data"
+)
+
+func TestDataCaptureQueryAction(t *testing.T) {
+	captureDir := t.TempDir()
+	f, err := data.NewCaptureFile(captureDir, &v1.DataCaptureMetadata{
+		ComponentName: "thermometer",
+		MethodName:    "Readings",
+		Type:          v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+	})
+	test.That(t, err, test.ShouldBeNil)
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, temperature := range []float64{20, 22, 30} {
+		reading, err := structpb.NewStruct(map[string]interface{}{"readings": map[string]interface{}{"temperature": temperature}})
+		test.That(t, err, test.ShouldBeNil)
+		requested := timestamppb.New(t0.Add(time.Duration(i) * 40 * time.Second))
+		test.That(t, f.WriteNext(&v1.SensorData{
+			Metadata: &v1.SensorMetadata{TimeRequested: requested, TimeReceived: requested},
+			Data:     &v1.SensorData_Struct{Struct: reading},
+		}), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	out, errOut := &testWriter{}, &testWriter{}
+	cmd := buildTestCmd(out, errOut, nil)
+	args := dataCaptureQueryArgs{
+		CaptureDir:   captureDir,
+		ResourceName: "thermometer",
+		Method:       "Readings",
+		ValuePath:    "readings.temperature",
+		Bucket:       time.Minute,
+	}
+	test.That(t, DataCaptureQueryAction(context.Background(), cmd, args), test.ShouldBeNil)
+	test.That(t, out.messages, test.ShouldResemble, []string{
+		"2026-01-01T12:00:00Z\tcount=2 min=20 max=22 avg=21\n",
+		"2026-01-01T12:01:00Z\tcount=1 min=30 max=30 avg=30\n",
+		"3 readings, 0 without a number at \"readings.temperature\"\n",
+	})
+
+	out.messages = nil
+	args.Bucket = 0
+	args.Start = "2026-01-01T12:00:30Z"
+	test.That(t, DataCaptureQueryAction(context.Background(), cmd, args), test.ShouldBeNil)
+	test.That(t, out.messages[0], test.ShouldEqual, "all\tcount=2 min=22 max=30 avg=26\n")
+
+	args.End = "tomorrow"
+	err = DataCaptureQueryAction(context.Background(), cmd, args)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "could not parse --end")
+	test.That(t, errOut.messages, test.ShouldBeEmpty)
+}
+
+func TestDataCaptureConvertAction(t *testing.T) {
+	captureDir := t.TempDir()
+	f, err := data.NewCaptureFile(captureDir, &v1.DataCaptureMetadata{
+		ComponentType: "rdk:component:sensor",
+		ComponentName: "thermometer",
+		MethodName:    "Readings",
+		Type:          v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+	})
+	test.That(t, err, test.ShouldBeNil)
+	reading, err := structpb.NewStruct(map[string]interface{}{"readings": map[string]interface{}{"temperature": 20}})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.WriteNext(&v1.SensorData{
+		Metadata: &v1.SensorMetadata{TimeRequested: timestamppb.Now(), TimeReceived: timestamppb.Now()},
+		Data:     &v1.SensorData_Struct{Struct: reading},
+	}), test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	out, errOut := &testWriter{}, &testWriter{}
+	cmd := buildTestCmd(out, errOut, nil)
+	dst := t.TempDir()
+	args := dataCaptureConvertArgs{CaptureDir: captureDir, Destination: dst, Format: "mcap"}
+	test.That(t, DataCaptureConvertAction(context.Background(), cmd, args), test.ShouldBeNil)
+	test.That(t, out.messages, test.ShouldResemble, []string{
+		"Converted 1 readings from 1 capture files to 1 files in " + dst + "\n",
+	})
+	_, err = os.Stat(filepath.Join(dst, "capture.mcap"))
+	test.That(t, err, test.ShouldBeNil)
+
+	args.Format = "csv"
+	test.That(t, DataCaptureConvertAction(context.Background(), cmd, args), test.ShouldBeError,
+		`unknown format "csv", expected one of [parquet mcap files]`)
+}
+
+func TestDataCaptureRepairAction(t *testing.T) {
+	captureDir := t.TempDir()
+	f, err := data.NewCaptureFile(captureDir, &v1.DataCaptureMetadata{
+		ComponentName: "thermometer",
+		MethodName:    "Readings",
+		Type:          v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+	})
+	test.That(t, err, test.ShouldBeNil)
+	for _, temperature := range []float64{20, 22} {
+		reading, err := structpb.NewStruct(map[string]interface{}{"readings": map[string]interface{}{"temperature": temperature}})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, f.WriteNext(&v1.SensorData{
+			Metadata: &v1.SensorMetadata{TimeRequested: timestamppb.Now(), TimeReceived: timestamppb.Now()},
+			Data:     &v1.SensorData_Struct{Struct: reading},
+		}), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	// truncate the last record, as if the machine lost power while writing it
+	path := f.GetPath()
+	info, err := os.Stat(path)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, os.Truncate(path, info.Size()-3), test.ShouldBeNil)
+
+	out, errOut := &testWriter{}, &testWriter{}
+	cmd := buildTestCmd(out, errOut, nil)
+	args := dataCaptureRepairArgs{CaptureDir: captureDir, InProgressOnly: true}
+	test.That(t, DataCaptureRepairAction(context.Background(), cmd, args), test.ShouldBeNil)
+	test.That(t, out.messages, test.ShouldResemble, []string{"Repaired 0 capture files, salvaging 0 records\n"})
+
+	out.messages = nil
+	args.InProgressOnly = false
+	test.That(t, DataCaptureRepairAction(context.Background(), cmd, args), test.ShouldBeNil)
+	test.That(t, out.messages, test.ShouldHaveLength, 2)
+	test.That(t, out.messages[0], test.ShouldStartWith, "Repaired "+path+": salvaged 1 records to "+path)
+	test.That(t, out.messages[1], test.ShouldEqual, "Repaired 1 capture files, salvaging 1 records\n")
+	test.That(t, errOut.messages, test.ShouldBeEmpty)
+
+	readings, err := data.SensorDataFromCaptureFilePath(path)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readings, test.ShouldHaveLength, 1)
+}
+
+func TestDataCaptureRewrapAction(t *testing.T) {
+	keyDir := t.TempDir()
+	writeKey := func(name string, b byte) string {
+		path := filepath.Join(keyDir, name)
+		key := strings.Repeat(hex.EncodeToString([]byte{b}), data.CaptureKeySize)
+		test.That(t, os.WriteFile(path, []byte(key), 0o600), test.ShouldBeNil)
+		return path
+	}
+	oldKeyFile, newKeyFile := writeKey("old.key", 1), writeKey("new.key", 2)
+
+	captureDir := t.TempDir()
+	keyring, err := loadCaptureKeyring(oldKeyFile, nil)
+	test.That(t, err, test.ShouldBeNil)
+	f, err := data.NewCaptureFile(captureDir, &v1.DataCaptureMetadata{
+		ComponentName: "thermometer",
+		MethodName:    "Readings",
+		Type:          v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+	}, data.WithCaptureKeyring(keyring))
+	test.That(t, err, test.ShouldBeNil)
+	reading, err := structpb.NewStruct(map[string]interface{}{"readings": map[string]interface{}{"temperature": 20}})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.WriteNext(&v1.SensorData{
+		Metadata: &v1.SensorMetadata{TimeRequested: timestamppb.Now(), TimeReceived: timestamppb.Now()},
+		Data:     &v1.SensorData_Struct{Struct: reading},
+	}), test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	out, errOut := &testWriter{}, &testWriter{}
+	cmd := buildTestCmd(out, errOut, nil)
+	err = DataCaptureRewrapAction(context.Background(), cmd, dataCaptureRewrapArgs{CaptureDir: captureDir})
+	test.That(t, err, test.ShouldBeError, "--key-file is required to rewrap capture files")
+
+	args := dataCaptureRewrapArgs{CaptureDir: captureDir, KeyFile: newKeyFile, PreviousKeyFiles: []string{oldKeyFile}}
+	test.That(t, DataCaptureRewrapAction(context.Background(), cmd, args), test.ShouldBeNil)
+	test.That(t, out.messages, test.ShouldResemble, []string{"Rewrapped 1 capture files\n"})
+
+	// the file can now be read with only the new key
+	out.messages = nil
+	queryArgs := dataCaptureQueryArgs{
+		CaptureDir:   captureDir,
+		ResourceName: "thermometer",
+		Method:       "Readings",
+		ValuePath:    "readings.temperature",
+		KeyFile:      newKeyFile,
+	}
+	test.That(t, DataCaptureQueryAction(context.Background(), cmd, queryArgs), test.ShouldBeNil)
+	test.That(t, out.messages, test.ShouldResemble, []string{
+		"all\tcount=1 min=20 max=20 avg=20\n",
+		"1 readings, 0 without a number at \"readings.temperature\"\n",
+	})
+	test.That(t, errOut.messages, test.ShouldBeEmpty)
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v3"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/services/navigation"
+)
+
+func navigationGeoFlags(pathUsage string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     navigationFlagService,
+			Usage:    "name of the navigation service",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:      generalFlagPath,
+			Usage:     pathUsage,
+			Required:  true,
+			TakesFile: true,
+		},
+		&cli.StringFlag{
+			Name:  navigationFlagFormat,
+			Usage: "geojson or kml. defaults to the format given by the file extension",
+		},
+	}
+}
+
+type navigationGeoArgs struct {
+	Organization string
+	Location     string
+	Machine      string
+	Part         string
+
+	Service string
+	Path    string
+	Format  string
+}
+
+func (args navigationGeoArgs) geoFormat() (navigation.GeoFormat, error) {
+	if args.Format != "" {
+		return navigation.GeoFormat(args.Format), nil
+	}
+	return navigation.GeoFormatFromPath(args.Path)
+}
+
+// withNavigationService connects to the machine part and calls f with its navigation service.
+func withNavigationService(
+	ctx context.Context, cmd *cli.Command, args navigationGeoArgs, f func(navigation.Service) error,
+) error {
+	client, err := newViamClient(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	globalArgs, err := getGlobalArgs(cmd)
+	if err != nil {
+		return err
+	}
+
+	dialCtx, fqdn, rpcOpts, err := client.prepareDial(ctx, args.Organization, args.Location, args.Machine, args.Part, globalArgs.Debug)
+	if err != nil {
+		return err
+	}
+
+	logger := globalArgs.createLogger()
+
+	robotClient, err := client.connectToRobot(dialCtx, fqdn, rpcOpts, globalArgs.Debug, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.UncheckedError(robotClient.Close(ctx))
+	}()
+
+	navService, err := navigation.FromProvider(robotClient, args.Service)
+	if err != nil {
+		return fmt.Errorf("no navigation service named %q: %w", args.Service, err)
+	}
+	return f(navService)
+}
+
+func navigationImportAction(ctx context.Context, cmd *cli.Command, args navigationGeoArgs) error {
+	format, err := args.geoFormat()
+	if err != nil {
+		return err
+	}
+	//nolint:gosec
+	data, err := os.ReadFile(args.Path)
+	if err != nil {
+		return err
+	}
+	// parse locally first so that mistakes in the file are reported without touching the machine
+	if _, err := navigation.UnmarshalGeoData(format, data); err != nil {
+		return err
+	}
+
+	return withNavigationService(ctx, cmd, args, func(navService navigation.Service) error {
+		resp, err := navService.DoCommand(ctx, map[string]interface{}{
+			navigation.DoImportGeo: map[string]interface{}{"format": string(format), "data": string(data)},
+		})
+		if err != nil {
+			return err
+		}
+		imported, ok := resp[navigation.DoImportGeo].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected response from navigation service: %v", resp)
+		}
+		printf(cmd.Root().Writer, "imported %v waypoints into %q", imported["waypoints"], args.Service)
+		for _, key := range []string{"obstacles", "bounding_regions"} {
+			cfgs, _ := imported[key].([]interface{})
+			if len(cfgs) == 0 {
+				continue
+			}
+			cfgJSON, err := json.MarshalIndent(cfgs, "", "  ")
+			if err != nil {
+				return err
+			}
+			printf(cmd.Root().Writer, "imported %d %s, add them to the %q attribute of the service to keep them:\n%s",
+				len(cfgs), key, key, cfgJSON)
+		}
+		return nil
+	})
+}
+
+func navigationExportAction(ctx context.Context, cmd *cli.Command, args navigationGeoArgs) error {
+	format, err := args.geoFormat()
+	if err != nil {
+		return err
+	}
+
+	return withNavigationService(ctx, cmd, args, func(navService navigation.Service) error {
+		resp, err := navService.DoCommand(ctx, map[string]interface{}{
+			navigation.DoExportGeo: map[string]interface{}{"format": string(format)},
+		})
+		if err != nil {
+			return err
+		}
+		exported, ok := resp[navigation.DoExportGeo].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected response from navigation service: %v", resp)
+		}
+		data, ok := exported["data"].(string)
+		if !ok {
+			return fmt.Errorf("unexpected response from navigation service: %v", resp)
+		}
+		if err := os.WriteFile(args.Path, []byte(data), 0o600); err != nil {
+			return err
+		}
+		printf(cmd.Root().Writer, "exported %q to %s", args.Service, args.Path)
+		return nil
+	})
+}
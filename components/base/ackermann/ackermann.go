@@ -0,0 +1,402 @@
+// Package ackermann implements a base steered like a car.
+package ackermann
+
+/*
+   The ackermann base turns its front wheels with a steering servo and drives its wheels with one or more motors, which
+   are all driven at the same speed, as though through a differential. Because it steers rather than spinning its wheels
+   against each other, it can't turn tighter than its minimum turning radius, which it gives in its properties. The
+   turning radius follows from the wheelbase and the largest angle the front wheels turn to.
+
+   The steering servo points the wheels straight ahead at servo_center_deg, 90 by default, and turns them left as its
+   angle increases by servo_deg_per_steering_deg, 1 by default, for every degree they turn. A servo mounted the other
+   way turns the wheels right as its angle increases, which a negative servo_deg_per_steering_deg accounts for.
+
+   Spin turns the base through the angle around its tightest turn, driving forward, since it can't spin in place.
+
+   Example Config:
+   {
+     "name": "tractor",
+     "type": "base",
+     "model": "ackermann",
+     "attributes": {
+       "steering_servo": "steering",
+       "motors": ["rear-left", "rear-right"],
+       "wheelbase_mm": 1500,
+       "width_mm": 1200,
+       "wheel_circumference_mm": 2000,
+       "max_steering_angle_deg": 35,
+     },
+     "depends_on": ["steering", "rear-left", "rear-right"],
+   },
+*/
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/servo"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// Model is the name of the ackermann model of a base component.
+var Model = resource.DefaultModelFamily.WithModel("ackermann")
+
+const (
+	defaultServoCenterDeg         = 90
+	defaultServoDegPerSteeringDeg = 1.
+)
+
+// Config is how you configure an ackermann base.
+type Config struct {
+	SteeringServo        string   `json:"steering_servo"`
+	Motors               []string `json:"motors"`
+	WheelbaseMM          int      `json:"wheelbase_mm"`
+	WidthMM              int      `json:"width_mm,omitempty"`
+	WheelCircumferenceMM int      `json:"wheel_circumference_mm"`
+	MaxSteeringAngleDeg  float64  `json:"max_steering_angle_deg"`
+	// ServoCenterDeg is the angle of the steering servo which points the wheels straight ahead.
+	ServoCenterDeg *int `json:"servo_center_deg,omitempty"`
+	// ServoDegPerSteeringDeg is how far the steering servo turns for the wheels to turn a degree to the left.
+	ServoDegPerSteeringDeg float64 `json:"servo_deg_per_steering_deg,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	if cfg.SteeringServo == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "steering_servo")
+	}
+	if len(cfg.Motors) == 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "motors")
+	}
+	if cfg.WheelbaseMM <= 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "wheelbase_mm")
+	}
+	if cfg.WheelCircumferenceMM <= 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "wheel_circumference_mm")
+	}
+	if cfg.WidthMM < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("width_mm can't be negative"))
+	}
+	if cfg.MaxSteeringAngleDeg <= 0 || cfg.MaxSteeringAngleDeg >= 90 {
+		return nil, nil, resource.NewConfigValidationError(path,
+			fmt.Errorf("max_steering_angle_deg must be between 0 and 90, not %v", cfg.MaxSteeringAngleDeg))
+	}
+	center, perDeg := cfg.servoCalibration()
+	for _, steering := range []float64{-cfg.MaxSteeringAngleDeg, cfg.MaxSteeringAngleDeg} {
+		if angle := float64(center) + perDeg*steering; angle < 0 || angle > 180 {
+			return nil, nil, resource.NewConfigValidationError(path,
+				fmt.Errorf("steering %v degrees needs the steering servo at %v degrees, outside of 0 to 180", steering, angle))
+		}
+	}
+
+	deps := []string{cfg.SteeringServo}
+	deps = append(deps, cfg.Motors...)
+	return deps, nil, nil
+}
+
+// servoCalibration returns the servo angle which points the wheels straight ahead, and how far the servo turns for
+// each degree the wheels turn left.
+func (cfg *Config) servoCalibration() (int, float64) {
+	center := defaultServoCenterDeg
+	if cfg.ServoCenterDeg != nil {
+		center = *cfg.ServoCenterDeg
+	}
+	perDeg := cfg.ServoDegPerSteeringDeg
+	if perDeg == 0 {
+		perDeg = defaultServoDegPerSteeringDeg
+	}
+	return center, perDeg
+}
+
+func init() {
+	resource.RegisterComponent(base.API, Model, resource.Registration[base.Base, *Config]{Constructor: createAckermannBase})
+}
+
+type ackermannBase struct {
+	resource.Named
+	resource.AlwaysRebuild
+
+	steering               servo.Servo
+	motors                 []motor.Motor
+	wheelbaseMM            float64
+	widthMM                float64
+	wheelCircumferenceMM   float64
+	maxSteeringAngleDeg    float64
+	servoCenterDeg         float64
+	servoDegPerSteeringDeg float64
+	geometries             []spatialmath.Geometry
+
+	opMgr  *operation.SingleOperationManager
+	logger logging.Logger
+}
+
+// createAckermannBase returns a new ackermann base defined by the given config.
+func createAckermannBase(
+	ctx context.Context,
+	deps resource.Dependencies,
+	conf resource.Config,
+	logger logging.Logger,
+) (base.Base, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+
+	steering, err := servo.FromProvider(deps, newConf.SteeringServo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "no steering servo named (%s)", newConf.SteeringServo)
+	}
+	motors := make([]motor.Motor, 0, len(newConf.Motors))
+	for _, name := range newConf.Motors {
+		m, err := motor.FromProvider(deps, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "no motor named (%s)", name)
+		}
+		motors = append(motors, m)
+	}
+
+	geometries := []spatialmath.Geometry{}
+	if conf.Frame != nil {
+		frame, err := conf.Frame.ParseConfig()
+		if err != nil {
+			return nil, err
+		}
+		if geom := frame.Geometry(); geom != nil {
+			geometries = append(geometries, geom)
+		}
+	}
+
+	center, perDeg := newConf.servoCalibration()
+	ab := &ackermannBase{
+		Named:                  conf.ResourceName().AsNamed(),
+		steering:               steering,
+		motors:                 motors,
+		wheelbaseMM:            float64(newConf.WheelbaseMM),
+		widthMM:                float64(newConf.WidthMM),
+		wheelCircumferenceMM:   float64(newConf.WheelCircumferenceMM),
+		maxSteeringAngleDeg:    newConf.MaxSteeringAngleDeg,
+		servoCenterDeg:         float64(center),
+		servoDegPerSteeringDeg: perDeg,
+		geometries:             geometries,
+		opMgr:                  operation.NewSingleOperationManager(),
+		logger:                 logger,
+	}
+	// start with the wheels straight
+	if err := ab.steer(ctx, 0); err != nil {
+		return nil, err
+	}
+	return ab, nil
+}
+
+// turningRadiusMM returns the radius of the tightest turn the base can make.
+func (ab *ackermannBase) turningRadiusMM() float64 {
+	return ab.wheelbaseMM / math.Tan(rdkutils.DegToRad(ab.maxSteeringAngleDeg))
+}
+
+// steer turns the front wheels to the angle, in degrees to the left, limited to the largest they turn to.
+func (ab *ackermannBase) steer(ctx context.Context, steeringDeg float64) error {
+	steeringDeg = math.Max(-ab.maxSteeringAngleDeg, math.Min(ab.maxSteeringAngleDeg, steeringDeg))
+	angle := math.Round(ab.servoCenterDeg + ab.servoDegPerSteeringDeg*steeringDeg)
+	return ab.steering.Move(ctx, uint32(math.Max(0, angle)), nil)
+}
+
+// steeringFor returns the steering angle, in degrees to the left, which turns the base at the angular velocity when
+// driving at the linear velocity. The base turns the opposite way for the same steering when driving in reverse.
+func (ab *ackermannBase) steeringFor(mmPerSec, degsPerSec float64) float64 {
+	return rdkutils.RadToDeg(math.Atan(ab.wheelbaseMM * rdkutils.DegToRad(degsPerSec) / mmPerSec))
+}
+
+// rpmFor returns the motor rpm which drives the base at the linear velocity.
+func (ab *ackermannBase) rpmFor(mmPerSec float64) float64 {
+	return 60 * mmPerSec / ab.wheelCircumferenceMM
+}
+
+// Spin turns the base through the angle around its tightest turn, driving forward at the speed which turns it at the
+// angular velocity, as it can't spin in place.
+func (ab *ackermannBase) Spin(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+	ctx, done := ab.opMgr.New(ctx)
+	defer done()
+	ab.logger.CDebugf(ctx, "received a Spin with angleDeg:%.2f, degsPerSec:%.2f", angleDeg, degsPerSec)
+
+	if math.Abs(angleDeg) < 0.0001 {
+		return fmt.Errorf("cannot move base %v for an angle that is nearly 0", ab.Name().ShortName())
+	}
+	if math.Abs(degsPerSec) < 0.0001 {
+		err := ab.Stop(ctx, nil)
+		if err != nil {
+			return errors.Errorf("error when trying to spin at a speed of 0: %v", err)
+		}
+		return err
+	}
+
+	if err := ab.steer(ctx, math.Copysign(ab.maxSteeringAngleDeg, angleDeg)); err != nil {
+		return err
+	}
+	radius := ab.turningRadiusMM()
+	distance := radius * rdkutils.DegToRad(math.Abs(angleDeg))
+	mmPerSec := radius * rdkutils.DegToRad(math.Abs(degsPerSec))
+	return ab.runAllGoFor(ctx, ab.rpmFor(mmPerSec), distance/ab.wheelCircumferenceMM)
+}
+
+// MoveStraight commands a base to drive forward or backwards at a linear speed and for a specific distance.
+func (ab *ackermannBase) MoveStraight(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+	ctx, done := ab.opMgr.New(ctx)
+	defer done()
+	ab.logger.CDebugf(ctx, "received a MoveStraight with distanceMM:%d, mmPerSec:%.2f", distanceMm, mmPerSec)
+
+	if math.Abs(mmPerSec) < 0.0001 || distanceMm == 0 {
+		err := ab.Stop(ctx, nil)
+		if err != nil {
+			return errors.Errorf("error when trying to move straight at a speed and/or distance of 0: %v", err)
+		}
+		return err
+	}
+
+	if err := ab.steer(ctx, 0); err != nil {
+		return err
+	}
+	return ab.runAllGoFor(ctx, ab.rpmFor(mmPerSec), float64(distanceMm)/ab.wheelCircumferenceMM)
+}
+
+// SetVelocity steers the base to turn at the angular velocity while driving at the linear velocity, as tightly as it
+// can if that is tighter than its turning radius. Without a linear velocity the base can't turn, so it only steers.
+func (ab *ackermannBase) SetVelocity(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	ctx, done := ab.opMgr.New(ctx)
+	defer done()
+	ab.logger.CDebugf(ctx,
+		"received a SetVelocity with linear.X: %.2f, linear.Y: %.2f linear.Z: %.2f(mmPerSec),"+
+			" angular.X: %.2f, angular.Y: %.2f, angular.Z: %.2f",
+		linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z)
+
+	if linear.Norm() == 0 && angular.Norm() == 0 {
+		ab.logger.CDebug(ctx, "received a SetVelocity command of linear 0,0,0, and angular 0,0,0, stopping base")
+		return ab.Stop(ctx, nil)
+	}
+
+	var steeringDeg float64
+	if linear.Y == 0 {
+		ab.logger.CDebug(ctx, "an ackermann base can't turn without moving, only steering")
+		steeringDeg = math.Copysign(ab.maxSteeringAngleDeg, angular.Z)
+	} else {
+		steeringDeg = ab.steeringFor(linear.Y, angular.Z)
+	}
+	if err := ab.steer(ctx, steeringDeg); err != nil {
+		return err
+	}
+	if linear.Y == 0 {
+		return ab.stopMotors(ctx, extra)
+	}
+	return ab.runAll(ctx, func(ctx context.Context, m motor.Motor) error {
+		return m.SetRPM(ctx, ab.rpmFor(linear.Y), nil)
+	})
+}
+
+// SetPower drives the motors at the linear power and steers the wheels by the angular power, as a fraction of the
+// largest angle they turn to.
+func (ab *ackermannBase) SetPower(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	ab.opMgr.CancelRunning(ctx)
+
+	ab.logger.CDebugf(ctx,
+		"received a SetPower with linear.X: %.2f, linear.Y: %.2f linear.Z: %.2f,"+
+			" angular.X: %.2f, angular.Y: %.2f, angular.Z: %.2f",
+		linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z)
+
+	if linear.Norm() == 0 && angular.Norm() == 0 {
+		ab.logger.CDebug(ctx, "received a SetPower command of linear 0,0,0, and angular 0,0,0, stopping base")
+		return ab.Stop(ctx, nil)
+	}
+
+	if err := ab.steer(ctx, angular.Z*ab.maxSteeringAngleDeg); err != nil {
+		return err
+	}
+	power := math.Max(-1, math.Min(1, linear.Y))
+	return ab.runAll(ctx, func(ctx context.Context, m motor.Motor) error {
+		return m.SetPower(ctx, power, extra)
+	})
+}
+
+// runAllGoFor runs all of the motors for the revolutions at the rpm, stopping the base if an error occurs.
+// Callers must register an operation via `ab.opMgr.New`.
+func (ab *ackermannBase) runAllGoFor(ctx context.Context, rpm, revolutions float64) error {
+	if math.Abs(rpm) <= 10 {
+		ab.logger.CWarn(ctx, "low motor speed detected, motors may not behave as expected")
+	}
+	err := ab.runAll(ctx, func(ctx context.Context, m motor.Motor) error {
+		return m.GoFor(ctx, rpm, revolutions, nil)
+	})
+	// Ignore the context canceled error - this occurs when the base is stopped by the user.
+	if errors.Is(err, context.Canceled) {
+		ab.logger.Warnf("Context cancelled during GoFor %v", err)
+		return nil
+	}
+	return err
+}
+
+// runAll runs the function on all of the motors in parallel, stopping them if any fails.
+func (ab *ackermannBase) runAll(ctx context.Context, f func(context.Context, motor.Motor) error) error {
+	funcs := make([]rdkutils.SimpleFunc, 0, len(ab.motors))
+	for _, m := range ab.motors {
+		funcs = append(funcs, func(ctx context.Context) error { return f(ctx, m) })
+	}
+	if _, err := rdkutils.RunInParallel(ctx, funcs); err != nil {
+		return multierr.Combine(err, ab.stopMotors(ctx, nil))
+	}
+	return nil
+}
+
+// stopMotors stops all of the motors.
+func (ab *ackermannBase) stopMotors(ctx context.Context, extra map[string]interface{}) error {
+	funcs := make([]rdkutils.SimpleFunc, 0, len(ab.motors))
+	for _, m := range ab.motors {
+		funcs = append(funcs, func(ctx context.Context) error { return m.Stop(ctx, extra) })
+	}
+	_, err := rdkutils.RunInParallel(ctx, funcs)
+	return err
+}
+
+// Stop commands the base to stop moving. The wheels are left steered as they were.
+func (ab *ackermannBase) Stop(ctx context.Context, extra map[string]interface{}) error {
+	ab.opMgr.CancelRunning(ctx)
+	return ab.stopMotors(ctx, extra)
+}
+
+func (ab *ackermannBase) IsMoving(ctx context.Context) (bool, error) {
+	for _, m := range ab.motors {
+		isMoving, _, err := m.IsPowered(ctx, nil)
+		if err != nil {
+			return false, err
+		}
+		if isMoving {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Close stops the base.
+func (ab *ackermannBase) Close(ctx context.Context) error {
+	return ab.Stop(ctx, nil)
+}
+
+func (ab *ackermannBase) Properties(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
+	return base.Properties{
+		TurningRadiusMeters:      ab.turningRadiusMM() * 0.001,
+		WidthMeters:              ab.widthMM * 0.001,
+		WheelCircumferenceMeters: ab.wheelCircumferenceMM * 0.001,
+	}, nil
+}
+
+func (ab *ackermannBase) Geometries(ctx context.Context, extra map[string]interface{}) ([]spatialmath.Geometry, error) {
+	return ab.geometries, nil
+}
@@ -0,0 +1,193 @@
+package ackermann
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/servo"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+// recorder records the commands sent to the steering servo and motors.
+type recorder struct {
+	mu          sync.Mutex
+	servoAngles []uint32
+	rpms        []float64
+	revolutions []float64
+	powers      []float64
+	stops       int
+}
+
+func (r *recorder) lastAngle() uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.servoAngles[len(r.servoAngles)-1]
+}
+
+func newTestBase(t *testing.T, conf *Config) (base.Base, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	steering := inject.NewServo("steering")
+	steering.MoveFunc = func(ctx context.Context, angleDeg uint32, extra map[string]interface{}) error {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.servoAngles = append(rec.servoAngles, angleDeg)
+		return nil
+	}
+	deps := resource.Dependencies{servo.Named("steering"): steering}
+	for _, name := range conf.Motors {
+		m := inject.NewMotor(name)
+		m.GoForFunc = func(ctx context.Context, rpm, revolutions float64, extra map[string]interface{}) error {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.rpms = append(rec.rpms, rpm)
+			rec.revolutions = append(rec.revolutions, revolutions)
+			return nil
+		}
+		m.SetRPMFunc = func(ctx context.Context, rpm float64, extra map[string]interface{}) error {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.rpms = append(rec.rpms, rpm)
+			return nil
+		}
+		m.SetPowerFunc = func(ctx context.Context, powerPct float64, extra map[string]interface{}) error {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.powers = append(rec.powers, powerPct)
+			return nil
+		}
+		m.StopFunc = func(ctx context.Context, extra map[string]interface{}) error {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.stops++
+			return nil
+		}
+		m.IsPoweredFunc = func(ctx context.Context, extra map[string]interface{}) (bool, float64, error) {
+			return false, 0, nil
+		}
+		deps[motor.Named(name)] = m
+	}
+
+	_, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	b, err := createAckermannBase(context.Background(), deps, resource.Config{
+		Name: "tractor", API: base.API, Model: Model, ConvertedAttributes: conf,
+	}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	return b, rec
+}
+
+func newTestConfig() *Config {
+	return &Config{
+		SteeringServo:        "steering",
+		Motors:               []string{"left", "right"},
+		WheelbaseMM:          1000,
+		WidthMM:              800,
+		WheelCircumferenceMM: 1000,
+		MaxSteeringAngleDeg:  45,
+	}
+}
+
+func TestAckermannBase(t *testing.T) {
+	ctx := context.Background()
+	b, rec := newTestBase(t, newTestConfig())
+	// the wheels start straight
+	test.That(t, rec.lastAngle(), test.ShouldEqual, 90)
+
+	t.Run("properties", func(t *testing.T) {
+		props, err := b.Properties(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props.TurningRadiusMeters, test.ShouldAlmostEqual, 1)
+		test.That(t, props.WidthMeters, test.ShouldAlmostEqual, 0.8)
+		test.That(t, props.WheelCircumferenceMeters, test.ShouldAlmostEqual, 1)
+	})
+
+	t.Run("move straight", func(t *testing.T) {
+		test.That(t, b.SetPower(ctx, r3.Vector{Y: 0.5}, r3.Vector{Z: 1}, nil), test.ShouldBeNil)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 135)
+		test.That(t, rec.powers, test.ShouldResemble, []float64{0.5, 0.5})
+
+		test.That(t, b.MoveStraight(ctx, -2000, 500, nil), test.ShouldBeNil)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 90)
+		test.That(t, rec.rpms[len(rec.rpms)-1], test.ShouldAlmostEqual, 30)
+		test.That(t, rec.revolutions[len(rec.revolutions)-1], test.ShouldAlmostEqual, -2)
+	})
+
+	t.Run("spin", func(t *testing.T) {
+		// a quarter turn right around the 1m turning radius
+		test.That(t, b.Spin(ctx, -90, 45, nil), test.ShouldBeNil)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 45)
+		test.That(t, rec.revolutions[len(rec.revolutions)-1], test.ShouldAlmostEqual, math.Pi/2)
+		test.That(t, rec.rpms[len(rec.rpms)-1], test.ShouldAlmostEqual, 60*math.Pi/4)
+	})
+
+	t.Run("set velocity", func(t *testing.T) {
+		// turning at 0.5 rad/s at 1 m/s is a 2m radius
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 1000}, r3.Vector{Z: 90 / math.Pi}, nil), test.ShouldBeNil)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 90+int(math.Round(math.Atan(0.5)*180/math.Pi)))
+		test.That(t, rec.rpms[len(rec.rpms)-1], test.ShouldAlmostEqual, 60)
+
+		// in reverse, the same turn steers the other way
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: -1000}, r3.Vector{Z: 90 / math.Pi}, nil), test.ShouldBeNil)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 90-int(math.Round(math.Atan(0.5)*180/math.Pi)))
+
+		// turning tighter than it can is limited to its tightest turn
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 100}, r3.Vector{Z: 90}, nil), test.ShouldBeNil)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 135)
+
+		// it can't turn without moving
+		stops := rec.stops
+		test.That(t, b.SetVelocity(ctx, r3.Vector{}, r3.Vector{Z: -10}, nil), test.ShouldBeNil)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 45)
+		test.That(t, rec.stops, test.ShouldBeGreaterThan, stops)
+	})
+
+	t.Run("servo calibration", func(t *testing.T) {
+		conf := newTestConfig()
+		center := 100
+		conf.ServoCenterDeg = &center
+		conf.ServoDegPerSteeringDeg = -2
+		conf.MaxSteeringAngleDeg = 40
+		b, rec := newTestBase(t, conf)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 100)
+		// full lock to the left
+		test.That(t, b.Spin(ctx, 30, 30, nil), test.ShouldBeNil)
+		test.That(t, rec.lastAngle(), test.ShouldEqual, 20)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	conf := &Config{}
+	_, _, err := conf.Validate("path")
+	test.That(t, resource.GetFieldFromFieldRequiredError(err), test.ShouldEqual, "steering_servo")
+
+	conf = newTestConfig()
+	deps, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"steering", "left", "right"})
+
+	conf.WheelbaseMM = 0
+	_, _, err = conf.Validate("path")
+	test.That(t, resource.GetFieldFromFieldRequiredError(err), test.ShouldEqual, "wheelbase_mm")
+
+	conf = newTestConfig()
+	conf.MaxSteeringAngleDeg = 90
+	_, _, err = conf.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "max_steering_angle_deg")
+
+	// the servo can't turn far enough to steer 45 degrees each way from 20
+	conf = newTestConfig()
+	center := 20
+	conf.ServoCenterDeg = &center
+	_, _, err = conf.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "outside of 0 to 180")
+}
@@ -0,0 +1,528 @@
+// Package geofence implements a base which wraps another base and keeps it inside configured keep-in regions and out
+// of configured keep-out regions on the globe, independently of any planning. It refuses motion which it can predict
+// will break the geofence, and watches a movement sensor in the background to stop the base when its current
+// velocity would carry it across the geofence within a lookahead time.
+package geofence
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+	goutils "go.viam.com/utils"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+)
+
+const (
+	// DoGetState returns the last position the geofence checked, whether it is being broken and the recent violations.
+	DoGetState = "get_state"
+
+	defaultLookaheadSec     = 2.
+	defaultCheckFrequencyHz = 10.
+	// sampleStepMM is how finely paths are checked against the geofence.
+	sampleStepMM = 100.
+	// maxViolations is how many of the most recent violations are kept for DoGetState.
+	maxViolations = 20
+	// unknownHeadingStepDeg is the spacing of the headings checked when the movement sensor can't report a heading.
+	unknownHeadingStepDeg = 15.
+)
+
+// Model is the name used to refer to the geofence base model.
+var Model = resource.DefaultModelFamily.WithModel("geofence")
+
+func init() {
+	resource.RegisterComponent(
+		base.API,
+		Model,
+		resource.Registration[base.Base, *Config]{Constructor: createGeofenceBase})
+}
+
+// Config configures a geofence base.
+type Config struct {
+	Base           string `json:"base"`
+	MovementSensor string `json:"movement_sensor"`
+	// KeepIn are the regions the base must stay within, if any are given. Being inside any one of them is enough.
+	KeepIn []*spatialmath.GeoGeometryConfig `json:"keep_in,omitempty"`
+	// KeepOut are the regions the base must never enter.
+	KeepOut []*spatialmath.GeoGeometryConfig `json:"keep_out,omitempty"`
+	// LookaheadSec is how far ahead the base's current velocity is followed when checking the geofence.
+	LookaheadSec     float64 `json:"lookahead_sec,omitempty"`
+	CheckFrequencyHz float64 `json:"check_frequency_hz,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	if cfg.Base == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "base")
+	}
+	if cfg.MovementSensor == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "movement_sensor")
+	}
+	if len(cfg.KeepIn) == 0 && len(cfg.KeepOut) == 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("need at least one keep_in or keep_out region"))
+	}
+	if _, err := spatialmath.GeoGeometriesFromConfigs(cfg.KeepIn); err != nil {
+		return nil, nil, resource.NewConfigValidationError(path, errors.Wrap(err, "invalid keep_in region"))
+	}
+	if _, err := spatialmath.GeoGeometriesFromConfigs(cfg.KeepOut); err != nil {
+		return nil, nil, resource.NewConfigValidationError(path, errors.Wrap(err, "invalid keep_out region"))
+	}
+	if cfg.LookaheadSec < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("lookahead_sec can't be negative"))
+	}
+	if cfg.CheckFrequencyHz < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("check_frequency_hz can't be negative"))
+	}
+	return []string{cfg.Base, cfg.MovementSensor}, nil, nil
+}
+
+// fence is the outline on the ground of a keep-in or keep-out region.
+type fence struct {
+	label      string
+	footprints [][]*geo.Point
+}
+
+func newFences(configs []*spatialmath.GeoGeometryConfig, kind string) ([]fence, error) {
+	regions, err := spatialmath.GeoGeometriesFromConfigs(configs)
+	if err != nil {
+		return nil, err
+	}
+	fences := make([]fence, 0, len(regions))
+	for i, region := range regions {
+		label := fmt.Sprintf("%s %d", kind, i)
+		if geoms := region.Geometries(); len(geoms) > 0 && geoms[0].Label() != "" {
+			label = geoms[0].Label()
+		}
+		fences = append(fences, fence{label: label, footprints: spatialmath.GeoGeometryFootprints(region)})
+	}
+	return fences, nil
+}
+
+// violation is a time the geofence was broken, or would have been.
+type violation struct {
+	time     time.Time
+	position *geo.Point
+	reason   string
+}
+
+type geofenceBase struct {
+	resource.Named
+	resource.AlwaysRebuild
+	logger logging.Logger
+
+	wrapped         base.Base
+	ms              movementsensor.MovementSensor
+	keepIn, keepOut []fence
+	lookahead       time.Duration
+	hasHeading      bool
+	hasVelocity     bool
+
+	opMgr   *operation.SingleOperationManager
+	workers *goutils.StoppableWorkers
+
+	mu sync.Mutex
+	// position, checked and reason are the result of the last check of the geofence.
+	position   *geo.Point
+	checked    time.Time
+	reason     string
+	recovering bool
+	violations []violation
+}
+
+func createGeofenceBase(
+	ctx context.Context,
+	deps resource.Dependencies,
+	conf resource.Config,
+	logger logging.Logger,
+) (base.Base, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	g := &geofenceBase{
+		Named:     conf.ResourceName().AsNamed(),
+		logger:    logger,
+		lookahead: time.Duration(defaultLookaheadSec * float64(time.Second)),
+		opMgr:     operation.NewSingleOperationManager(),
+	}
+	if newConf.LookaheadSec > 0 {
+		g.lookahead = time.Duration(newConf.LookaheadSec * float64(time.Second))
+	}
+	if g.keepIn, err = newFences(newConf.KeepIn, "keep_in"); err != nil {
+		return nil, err
+	}
+	if g.keepOut, err = newFences(newConf.KeepOut, "keep_out"); err != nil {
+		return nil, err
+	}
+
+	if g.wrapped, err = base.FromProvider(deps, newConf.Base); err != nil {
+		return nil, errors.Wrapf(err, "no base named (%s)", newConf.Base)
+	}
+	if g.ms, err = movementsensor.FromProvider(deps, newConf.MovementSensor); err != nil {
+		return nil, errors.Wrapf(err, "no movement sensor named (%s)", newConf.MovementSensor)
+	}
+	props, err := g.ms.Properties(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !props.PositionSupported {
+		return nil, errors.Errorf("movement sensor %s can't report its position", newConf.MovementSensor)
+	}
+	g.hasHeading = props.CompassHeadingSupported
+	g.hasVelocity = props.CompassHeadingSupported && props.LinearVelocitySupported
+	if !g.hasHeading {
+		logger.CWarnf(ctx, "movement sensor %s can't report a heading, so motion will be checked in every direction",
+			newConf.MovementSensor)
+	}
+
+	freq := defaultCheckFrequencyHz
+	if newConf.CheckFrequencyHz > 0 {
+		freq = newConf.CheckFrequencyHz
+	}
+	g.workers = goutils.NewBackgroundStoppableWorkers(func(ctx context.Context) {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / freq))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := g.check(ctx); err != nil && ctx.Err() == nil {
+				g.logger.CDebugw(ctx, "failed to check the geofence", "error", err)
+			}
+		}
+	})
+	return g, nil
+}
+
+// localFences are the fences in millimeters east and north of an origin.
+type localFences struct {
+	keepIn, keepOut []localFence
+}
+
+type localFence struct {
+	label    string
+	polygons [][]r3.Vector
+}
+
+func (g *geofenceBase) localFences(origin *geo.Point) localFences {
+	toLocal := func(fences []fence) []localFence {
+		local := make([]localFence, 0, len(fences))
+		for _, f := range fences {
+			lf := localFence{label: f.label}
+			for _, footprint := range f.footprints {
+				polygon := make([]r3.Vector, 0, len(footprint))
+				for _, pt := range footprint {
+					polygon = append(polygon, spatialmath.GeoPointToPoint(pt, origin))
+				}
+				lf.polygons = append(lf.polygons, polygon)
+			}
+			local = append(local, lf)
+		}
+		return local
+	}
+	return localFences{keepIn: toLocal(g.keepIn), keepOut: toLocal(g.keepOut)}
+}
+
+// violation returns why being at p breaks the geofence, or "" if it doesn't.
+func (lf localFences) violation(p r3.Vector) string {
+	for _, f := range lf.keepOut {
+		if f.contains(p) {
+			return fmt.Sprintf("inside keep-out region %q", f.label)
+		}
+	}
+	if len(lf.keepIn) == 0 {
+		return ""
+	}
+	for _, f := range lf.keepIn {
+		if f.contains(p) {
+			return ""
+		}
+	}
+	return "outside every keep-in region"
+}
+
+// pathViolation returns why driving straight from a to b breaks the geofence, or "" if it doesn't.
+func (lf localFences) pathViolation(a, b r3.Vector) string {
+	steps := int(math.Ceil(b.Sub(a).Norm() / sampleStepMM))
+	for i := 1; i <= steps; i++ {
+		if reason := lf.violation(a.Add(b.Sub(a).Mul(float64(i) / float64(steps)))); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func (f localFence) contains(p r3.Vector) bool {
+	for _, polygon := range f.polygons {
+		if spatialmath.PointInPolygon(p, polygon) {
+			return true
+		}
+	}
+	return false
+}
+
+// direction returns the unit vector east and north of a compass heading.
+func direction(headingDeg float64) r3.Vector {
+	rad := headingDeg * math.Pi / 180
+	return r3.Vector{X: math.Sin(rad), Y: math.Cos(rad)}
+}
+
+// headings returns the compass headings the base may be facing, which is every direction if the movement sensor
+// can't tell.
+func (g *geofenceBase) headings(ctx context.Context) ([]float64, error) {
+	if g.hasHeading {
+		heading, err := g.ms.CompassHeading(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{heading}, nil
+	}
+	var headings []float64
+	for heading := 0.; heading < 360; heading += unknownHeadingStepDeg {
+		headings = append(headings, heading)
+	}
+	return headings, nil
+}
+
+// velocity returns the velocity of the base in millimeters per second east and north, measured by the movement
+// sensor if it can, or else estimated from the change in position since the last check.
+func (g *geofenceBase) velocity(ctx context.Context, position *geo.Point, now time.Time) (r3.Vector, error) {
+	if g.hasVelocity {
+		linear, err := g.ms.LinearVelocity(ctx, nil)
+		if err != nil {
+			return r3.Vector{}, err
+		}
+		heading, err := g.ms.CompassHeading(ctx, nil)
+		if err != nil {
+			return r3.Vector{}, err
+		}
+		// linear velocity is in meters per second, with Y forward and X to the right
+		forward, right := direction(heading), direction(heading+90)
+		return forward.Mul(1000 * linear.Y).Add(right.Mul(1000 * linear.X)), nil
+	}
+	g.mu.Lock()
+	last, checked := g.position, g.checked
+	g.mu.Unlock()
+	if last == nil || !now.After(checked) {
+		return r3.Vector{}, nil
+	}
+	return spatialmath.GeoPointToPoint(position, last).Mul(1 / now.Sub(checked).Seconds()), nil
+}
+
+// check compares the base's position, and where its velocity would take it within the lookahead time, to the
+// geofence, and stops the base if it is about to break it. A base which already breaks the geofence is stopped
+// unless it is moving straight back inside it, or its velocity will bring it back within the lookahead time.
+func (g *geofenceBase) check(ctx context.Context) error {
+	position, _, err := g.ms.Position(ctx, nil)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	velocity, err := g.velocity(ctx, position, now)
+	if err != nil {
+		return err
+	}
+	fences := g.localFences(position)
+	reason := fences.violation(r3.Vector{})
+	lookahead := velocity.Mul(g.lookahead.Seconds())
+	ahead := fences.violation(lookahead)
+	if reason == "" {
+		ahead = fences.pathViolation(r3.Vector{}, lookahead)
+	}
+
+	g.mu.Lock()
+	previous := g.reason
+	g.position, g.checked, g.reason = position, now, reason
+	recovering := g.recovering
+	g.mu.Unlock()
+
+	if reason == "" && ahead == "" {
+		if previous != "" {
+			g.logger.CInfof(ctx, "base %s is back within its geofence", g.Name().ShortName())
+		}
+		return nil
+	}
+	if reason != "" && reason != previous {
+		g.recordViolation(ctx, position, reason)
+	}
+	if reason != "" && (recovering || ahead == "") {
+		return nil
+	}
+	moving, err := g.wrapped.IsMoving(ctx)
+	if err != nil {
+		return err
+	}
+	if !moving {
+		return nil
+	}
+	if reason == "" {
+		g.recordViolation(ctx, position, fmt.Sprintf("stopped within %v of being %s", g.lookahead, ahead))
+	}
+	g.opMgr.CancelRunning(ctx)
+	return g.wrapped.Stop(ctx, nil)
+}
+
+func (g *geofenceBase) recordViolation(ctx context.Context, position *geo.Point, reason string) {
+	g.logger.CWarnf(ctx, "base %s geofence violation at (%v, %v): %s", g.Name().ShortName(), position.Lat(), position.Lng(), reason)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.violations = append(g.violations, violation{time: time.Now(), position: position, reason: reason})
+	if len(g.violations) > maxViolations {
+		g.violations = g.violations[len(g.violations)-maxViolations:]
+	}
+}
+
+// checkMove returns an error if driving distanceMM along any heading the base may be facing would break the
+// geofence. A base which already breaks the geofence may only move to somewhere which doesn't, and the returned
+// bool reports whether this is such a recovery.
+func (g *geofenceBase) checkMove(ctx context.Context, distanceMM float64) (bool, error) {
+	position, _, err := g.ms.Position(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	headings, err := g.headings(ctx)
+	if err != nil {
+		return false, err
+	}
+	fences := g.localFences(position)
+	recovering := fences.violation(r3.Vector{}) != ""
+	for _, heading := range headings {
+		end := direction(heading).Mul(distanceMM)
+		reason := fences.pathViolation(r3.Vector{}, end)
+		if recovering {
+			reason = fences.violation(end)
+		}
+		if reason != "" {
+			reason = fmt.Sprintf("refused to move %.0fmm, which would end up %s", distanceMM, reason)
+			g.recordViolation(ctx, position, reason)
+			return false, errors.Errorf("base %s %s", g.Name().ShortName(), reason)
+		}
+	}
+	return recovering, nil
+}
+
+// checkStationary returns an error if the base is breaking the geofence, for motion which can't be predicted.
+func (g *geofenceBase) checkStationary(ctx context.Context) error {
+	position, _, err := g.ms.Position(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if reason := g.localFences(position).violation(r3.Vector{}); reason != "" {
+		return errors.Errorf("base %s is %s, and may only move straight back within its geofence", g.Name().ShortName(), reason)
+	}
+	return nil
+}
+
+func (g *geofenceBase) setRecovering(recovering bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.recovering = recovering
+}
+
+// MoveStraight moves the wrapped base straight if it won't break the geofence.
+func (g *geofenceBase) MoveStraight(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+	ctx, done := g.opMgr.New(ctx)
+	defer done()
+	distance := float64(distanceMm)
+	if mmPerSec < 0 {
+		distance = -distance
+	}
+	recovering, err := g.checkMove(ctx, distance)
+	if err != nil {
+		return err
+	}
+	g.setRecovering(recovering)
+	defer g.setRecovering(false)
+	return g.wrapped.MoveStraight(ctx, distanceMm, mmPerSec, extra)
+}
+
+// Spin turns the wrapped base. Turning in place can't break the geofence, and a base which can't turn in place is
+// left to the background check to stop in time.
+func (g *geofenceBase) Spin(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+	ctx, done := g.opMgr.New(ctx)
+	defer done()
+	return g.wrapped.Spin(ctx, angleDeg, degsPerSec, extra)
+}
+
+// SetPower sets the power of the wrapped base, unless it is breaking the geofence. Where the base will go isn't
+// known, so it is left to the background check to stop it in time.
+func (g *geofenceBase) SetPower(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	g.opMgr.CancelRunning(ctx)
+	if err := g.checkStationary(ctx); err != nil {
+		return err
+	}
+	return g.wrapped.SetPower(ctx, linear, angular, extra)
+}
+
+// SetVelocity sets the velocity of the wrapped base if it won't break the geofence within the lookahead time.
+func (g *geofenceBase) SetVelocity(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	g.opMgr.CancelRunning(ctx)
+	if _, err := g.checkMove(ctx, linear.Y*g.lookahead.Seconds()); err != nil {
+		return err
+	}
+	return g.wrapped.SetVelocity(ctx, linear, angular, extra)
+}
+
+func (g *geofenceBase) Stop(ctx context.Context, extra map[string]interface{}) error {
+	g.opMgr.CancelRunning(ctx)
+	return g.wrapped.Stop(ctx, extra)
+}
+
+func (g *geofenceBase) IsMoving(ctx context.Context) (bool, error) {
+	return g.wrapped.IsMoving(ctx)
+}
+
+func (g *geofenceBase) Properties(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
+	return g.wrapped.Properties(ctx, extra)
+}
+
+func (g *geofenceBase) Geometries(ctx context.Context, extra map[string]interface{}) ([]spatialmath.Geometry, error) {
+	return g.wrapped.Geometries(ctx, extra)
+}
+
+// DoCommand returns the state of the geofence, or passes the command on to the wrapped base.
+func (g *geofenceBase) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := cmd[DoGetState]; !ok {
+		return g.wrapped.DoCommand(ctx, cmd)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	violations := make([]interface{}, 0, len(g.violations))
+	for _, v := range g.violations {
+		violations = append(violations, map[string]interface{}{
+			"time":      v.time.Format(time.RFC3339Nano),
+			"latitude":  v.position.Lat(),
+			"longitude": v.position.Lng(),
+			"reason":    v.reason,
+		})
+	}
+	state := map[string]interface{}{
+		"in_violation": g.reason != "",
+		"reason":       g.reason,
+		"violations":   violations,
+	}
+	if g.position != nil {
+		state["latitude"] = g.position.Lat()
+		state["longitude"] = g.position.Lng()
+		state["checked"] = g.checked.Format(time.RFC3339Nano)
+	}
+	return state, nil
+}
+
+func (g *geofenceBase) Close(ctx context.Context) error {
+	g.workers.Stop()
+	return g.wrapped.Stop(ctx, nil)
+}
@@ -0,0 +1,232 @@
+package geofence
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	commonpb "go.viam.com/api/common/v1"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+var origin = geo.NewPoint(40, -74)
+
+// fakeWorld is where the movement sensor says the base is, and what the wrapped base was told to do.
+type fakeWorld struct {
+	mu       sync.Mutex
+	position r3.Vector // mm east and north of origin
+	heading  float64
+	velocity r3.Vector // m/s
+	moving   bool
+	stops    int
+	moves    []int
+}
+
+func (w *fakeWorld) set(position r3.Vector, heading float64, velocity r3.Vector, moving bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.position, w.heading, w.velocity, w.moving = position, heading, velocity, moving
+}
+
+func geoConfig(t *testing.T, center r3.Vector, sizeMM float64, label string) *spatialmath.GeoGeometryConfig {
+	t.Helper()
+	location := spatialmath.PointToGeoPoint(center, origin)
+	return &spatialmath.GeoGeometryConfig{
+		Location: &commonpb.GeoPoint{Latitude: location.Lat(), Longitude: location.Lng()},
+		Geometries: []*spatialmath.GeometryConfig{
+			{Type: spatialmath.BoxType, X: sizeMM, Y: sizeMM, Z: 10, Label: label},
+		},
+	}
+}
+
+// newTestBase returns a geofence base which must stay in a 10m yard around the origin and out of a 2m pond whose
+// south edge is 2m north of the origin.
+func newTestBase(t *testing.T, compass bool) (*geofenceBase, *fakeWorld) {
+	t.Helper()
+	w := &fakeWorld{}
+	wrapped := inject.NewBase("wrapped")
+	wrapped.MoveStraightFunc = func(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.moves = append(w.moves, distanceMm)
+		return nil
+	}
+	wrapped.SetVelocityFunc = func(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+		return nil
+	}
+	wrapped.SetPowerFunc = func(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+		return nil
+	}
+	wrapped.StopFunc = func(ctx context.Context, extra map[string]interface{}) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.stops++
+		w.moving = false
+		return nil
+	}
+	wrapped.IsMovingFunc = func(ctx context.Context) (bool, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.moving, nil
+	}
+
+	ms := inject.NewMovementSensor("gps")
+	ms.PropertiesFunc = func(ctx context.Context, extra map[string]interface{}) (*movementsensor.Properties, error) {
+		return &movementsensor.Properties{
+			PositionSupported:       true,
+			CompassHeadingSupported: compass,
+			LinearVelocitySupported: compass,
+		}, nil
+	}
+	ms.PositionFunc = func(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return spatialmath.PointToGeoPoint(w.position, origin), 0, nil
+	}
+	ms.CompassHeadingFunc = func(ctx context.Context, extra map[string]interface{}) (float64, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.heading, nil
+	}
+	ms.LinearVelocityFunc = func(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.velocity, nil
+	}
+
+	conf := &Config{
+		Base:           "wrapped",
+		MovementSensor: "gps",
+		KeepIn:         []*spatialmath.GeoGeometryConfig{geoConfig(t, r3.Vector{}, 10000, "yard")},
+		KeepOut:        []*spatialmath.GeoGeometryConfig{geoConfig(t, r3.Vector{Y: 3000}, 2000, "pond")},
+		// check by hand rather than in the background
+		CheckFrequencyHz: 0.001,
+	}
+	_, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	b, err := createGeofenceBase(context.Background(), resource.Dependencies{
+		base.Named("wrapped"):       wrapped,
+		movementsensor.Named("gps"): ms,
+	}, resource.Config{Name: "fenced", API: base.API, Model: Model, ConvertedAttributes: conf}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	t.Cleanup(func() { test.That(t, b.Close(context.Background()), test.ShouldBeNil) })
+	return b.(*geofenceBase), w
+}
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+	b, w := newTestBase(t, true)
+
+	t.Run("move straight", func(t *testing.T) {
+		test.That(t, b.MoveStraight(ctx, 1000, 100, nil), test.ShouldBeNil)
+		err := b.MoveStraight(ctx, 2500, 100, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, `inside keep-out region "pond"`)
+
+		// driving through the pond is refused even though it ends past it
+		err = b.MoveStraight(ctx, 4500, 100, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "pond")
+
+		// backing up goes south, away from the pond
+		test.That(t, b.MoveStraight(ctx, 2500, -100, nil), test.ShouldBeNil)
+		err = b.MoveStraight(ctx, -6000, 100, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "outside every keep-in region")
+		test.That(t, w.moves, test.ShouldResemble, []int{1000, 2500})
+	})
+
+	t.Run("set velocity", func(t *testing.T) {
+		// two seconds ahead at 0.5m/s is short of the pond, but not at 1.5m/s
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 500}, r3.Vector{}, nil), test.ShouldBeNil)
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 1500}, r3.Vector{}, nil), test.ShouldNotBeNil)
+
+		// facing east, the pond isn't in the way
+		w.set(r3.Vector{}, 90, r3.Vector{}, false)
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 1500}, r3.Vector{}, nil), test.ShouldBeNil)
+	})
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	b, w := newTestBase(t, true)
+
+	// slowly north isn't stopped
+	w.set(r3.Vector{}, 0, r3.Vector{Y: 0.5}, true)
+	test.That(t, b.check(ctx), test.ShouldBeNil)
+	test.That(t, w.stops, test.ShouldEqual, 0)
+
+	// quickly north would reach the pond within the lookahead
+	w.set(r3.Vector{}, 0, r3.Vector{Y: 1.5}, true)
+	test.That(t, b.check(ctx), test.ShouldBeNil)
+	test.That(t, w.stops, test.ShouldEqual, 1)
+
+	// strafing left from facing east is also north
+	w.set(r3.Vector{}, 90, r3.Vector{X: -1.5}, true)
+	test.That(t, b.check(ctx), test.ShouldBeNil)
+	test.That(t, w.stops, test.ShouldEqual, 2)
+
+	// in the pond, the base is stopped unless it's leaving
+	w.set(r3.Vector{Y: 3000}, 0, r3.Vector{Y: 0.2}, true)
+	test.That(t, b.check(ctx), test.ShouldBeNil)
+	test.That(t, w.stops, test.ShouldEqual, 3)
+	w.set(r3.Vector{Y: 3000}, 0, r3.Vector{Y: -1}, true)
+	test.That(t, b.check(ctx), test.ShouldBeNil)
+	test.That(t, w.stops, test.ShouldEqual, 3)
+
+	// only moving straight out of the pond is allowed
+	test.That(t, b.SetPower(ctx, r3.Vector{Y: 1}, r3.Vector{}, nil), test.ShouldNotBeNil)
+	test.That(t, b.MoveStraight(ctx, 500, -100, nil), test.ShouldNotBeNil)
+	test.That(t, b.MoveStraight(ctx, 2000, -100, nil), test.ShouldBeNil)
+
+	state, err := b.DoCommand(ctx, map[string]interface{}{DoGetState: true})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, state["in_violation"], test.ShouldBeTrue)
+	test.That(t, state["reason"], test.ShouldEqual, `inside keep-out region "pond"`)
+	test.That(t, state["latitude"], test.ShouldAlmostEqual, spatialmath.PointToGeoPoint(r3.Vector{Y: 3000}, origin).Lat())
+	violations := state["violations"].([]interface{})
+	// two stops, entering the pond, and refusing a move which ends in it
+	test.That(t, violations, test.ShouldHaveLength, 4)
+
+	w.set(r3.Vector{}, 0, r3.Vector{}, false)
+	test.That(t, b.check(ctx), test.ShouldBeNil)
+	state, err = b.DoCommand(ctx, map[string]interface{}{DoGetState: true})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, state["in_violation"], test.ShouldBeFalse)
+}
+
+func TestUnknownHeading(t *testing.T) {
+	ctx := context.Background()
+	b, _ := newTestBase(t, false)
+	// the base could be facing the pond, so it can only move short of it
+	test.That(t, b.MoveStraight(ctx, 2500, 100, nil), test.ShouldNotBeNil)
+	test.That(t, b.MoveStraight(ctx, 1500, 100, nil), test.ShouldBeNil)
+}
+
+func TestValidate(t *testing.T) {
+	conf := &Config{}
+	_, _, err := conf.Validate("path")
+	test.That(t, resource.GetFieldFromFieldRequiredError(err), test.ShouldEqual, "base")
+
+	conf = &Config{Base: "wrapped", MovementSensor: "gps"}
+	_, _, err = conf.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "need at least one keep_in or keep_out region")
+
+	conf.KeepOut = []*spatialmath.GeoGeometryConfig{geoConfig(t, r3.Vector{}, 1000, "pond")}
+	deps, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"wrapped", "gps"})
+
+	conf.LookaheadSec = -1
+	_, _, err = conf.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "lookahead_sec")
+}
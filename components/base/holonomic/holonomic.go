@@ -0,0 +1,406 @@
+// Package holonomic implements bases with mecanum or omni wheels, which can drive in any direction.
+package holonomic
+
+/*
+   The holonomic base drives sideways as well as forward and turning, with either four mecanum wheels, whose rollers
+   are at 45 degrees to form an X seen from above, or three omni wheels 120 degrees apart. The lateral component of
+   SetVelocity and SetPower, linear X, strafes the base to its right.
+
+   A mecanum base's motors are given front-left, front-right, back-left, back-right, and each drives its wheel forward
+   at a positive speed. An omni base's motors are given front, back-left, back-right, with the front wheel centered at
+   the front of the base, and each turns the base counterclockwise, seen from above, at a positive speed.
+
+   Example Config:
+   {
+     "name": "cart",
+     "type": "base",
+     "model": "holonomic",
+     "attributes": {
+       "layout": "mecanum",
+       "motors": ["fl", "fr", "bl", "br"],
+       "wheel_circumference_mm": 300,
+       "width_mm": 400,
+       "length_mm": 350,
+     },
+     "depends_on": ["fl", "fr", "bl", "br"],
+   },
+*/
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// Model is the name of the holonomic model of a base component.
+var Model = resource.DefaultModelFamily.WithModel("holonomic")
+
+const (
+	// LayoutMecanum is four mecanum wheels at the corners of the base.
+	LayoutMecanum = "mecanum"
+	// LayoutOmni is three omni wheels 120 degrees apart.
+	LayoutOmni = "omni"
+)
+
+// Config is how you configure a holonomic base.
+type Config struct {
+	// Layout is either mecanum, the default, or omni.
+	Layout               string   `json:"layout,omitempty"`
+	Motors               []string `json:"motors"`
+	WheelCircumferenceMM int      `json:"wheel_circumference_mm"`
+	// WidthMM and LengthMM are the distances between the left and right wheels and between the front and back wheels
+	// of a mecanum base.
+	WidthMM  int `json:"width_mm,omitempty"`
+	LengthMM int `json:"length_mm,omitempty"`
+	// RadiusMM is the distance from the center of an omni base to its wheels.
+	RadiusMM int `json:"radius_mm,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	if cfg.WheelCircumferenceMM <= 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "wheel_circumference_mm")
+	}
+	switch cfg.Layout {
+	case "", LayoutMecanum:
+		if len(cfg.Motors) != 4 {
+			return nil, nil, resource.NewConfigValidationError(path,
+				fmt.Errorf("a mecanum base needs 4 motors, not %d", len(cfg.Motors)))
+		}
+		if cfg.WidthMM <= 0 {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "width_mm")
+		}
+		if cfg.LengthMM <= 0 {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "length_mm")
+		}
+	case LayoutOmni:
+		if len(cfg.Motors) != 3 {
+			return nil, nil, resource.NewConfigValidationError(path,
+				fmt.Errorf("an omni base needs 3 motors, not %d", len(cfg.Motors)))
+		}
+		if cfg.RadiusMM <= 0 {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "radius_mm")
+		}
+	default:
+		return nil, nil, resource.NewConfigValidationError(path,
+			fmt.Errorf("layout must be %q or %q, not %q", LayoutMecanum, LayoutOmni, cfg.Layout))
+	}
+	return cfg.Motors, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(base.API, Model, resource.Registration[base.Base, *Config]{Constructor: createHolonomicBase})
+}
+
+// wheel is how fast a wheel's surface moves, in mm/s, for each component of the base's velocity: forward in mm/s, to
+// the left in mm/s, and counterclockwise in rad/s.
+type wheel struct {
+	forward, left, turn float64
+}
+
+func (w wheel) speed(forward, left, turn float64) float64 {
+	return w.forward*forward + w.left*left + w.turn*turn
+}
+
+// wheelsFor returns the inverse kinematics of the wheels of the layout, in the order of the configured motors.
+func wheelsFor(cfg *Config) []wheel {
+	if cfg.Layout == LayoutOmni {
+		// each wheel drives along the tangent of the circle it is on, counterclockwise
+		wheels := make([]wheel, 0, 3)
+		for _, angle := range []float64{0, 2 * math.Pi / 3, 4 * math.Pi / 3} {
+			wheels = append(wheels, wheel{forward: -math.Sin(angle), left: math.Cos(angle), turn: float64(cfg.RadiusMM)})
+		}
+		return wheels
+	}
+	// the rollers of the front-left and back-right wheels push the base to the right when they drive forward, and
+	// those of the others to the left
+	k := float64(cfg.WidthMM+cfg.LengthMM) / 2
+	return []wheel{
+		{forward: 1, left: -1, turn: -k},
+		{forward: 1, left: 1, turn: k},
+		{forward: 1, left: 1, turn: -k},
+		{forward: 1, left: -1, turn: k},
+	}
+}
+
+type holonomicBase struct {
+	resource.Named
+	resource.AlwaysRebuild
+
+	motors               []motor.Motor
+	wheels               []wheel
+	wheelCircumferenceMM float64
+	widthMM              float64
+	geometries           []spatialmath.Geometry
+
+	opMgr  *operation.SingleOperationManager
+	logger logging.Logger
+}
+
+// createHolonomicBase returns a new holonomic base defined by the given config.
+func createHolonomicBase(
+	ctx context.Context,
+	deps resource.Dependencies,
+	conf resource.Config,
+	logger logging.Logger,
+) (base.Base, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+
+	motors := make([]motor.Motor, 0, len(newConf.Motors))
+	for _, name := range newConf.Motors {
+		m, err := motor.FromProvider(deps, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "no motor named (%s)", name)
+		}
+		motors = append(motors, m)
+	}
+
+	// the footprint of the base, unless its frame has a geometry
+	width, length := float64(newConf.WidthMM), float64(newConf.LengthMM)
+	center := r3.Vector{}
+	if newConf.Layout == LayoutOmni {
+		// from the front wheel back to the other two
+		radius := float64(newConf.RadiusMM)
+		width, length = math.Sqrt(3)*radius, 1.5*radius
+		center.Y = radius / 4
+	}
+	var geometry spatialmath.Geometry
+	if conf.Frame != nil {
+		frame, err := conf.Frame.ParseConfig()
+		if err != nil {
+			return nil, err
+		}
+		geometry = frame.Geometry()
+	}
+	if geometry == nil {
+		height := float64(newConf.WheelCircumferenceMM) / math.Pi
+		geometry, err = spatialmath.NewBox(spatialmath.NewPoseFromPoint(center), r3.Vector{X: width, Y: length, Z: height}, conf.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &holonomicBase{
+		Named:                conf.ResourceName().AsNamed(),
+		motors:               motors,
+		wheels:               wheelsFor(newConf),
+		wheelCircumferenceMM: float64(newConf.WheelCircumferenceMM),
+		widthMM:              width,
+		geometries:           []spatialmath.Geometry{geometry},
+		opMgr:                operation.NewSingleOperationManager(),
+		logger:               logger,
+	}, nil
+}
+
+// wheelSpeeds returns the speed of each wheel's surface, in mm/s, which moves the base at the velocity: forward and to
+// the left in mm/s, and counterclockwise in rad/s.
+func (hb *holonomicBase) wheelSpeeds(forward, left, turn float64) []float64 {
+	speeds := make([]float64, len(hb.wheels))
+	for i, w := range hb.wheels {
+		speeds[i] = w.speed(forward, left, turn)
+	}
+	return speeds
+}
+
+// Spin commands a base to turn about its center at a angular speed and for a specific angle.
+func (hb *holonomicBase) Spin(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+	ctx, done := hb.opMgr.New(ctx)
+	defer done()
+	hb.logger.CDebugf(ctx, "received a Spin with angleDeg:%.2f, degsPerSec:%.2f", angleDeg, degsPerSec)
+
+	if math.Abs(angleDeg) < 0.0001 {
+		return fmt.Errorf("cannot move base %v for an angle that is nearly 0", hb.Name().ShortName())
+	}
+	if math.Abs(degsPerSec) < 0.0001 {
+		err := hb.Stop(ctx, nil)
+		if err != nil {
+			return errors.Errorf("error when trying to spin at a speed of 0: %v", err)
+		}
+		return err
+	}
+
+	// a negative speed spins the other way, so the direction is the product of the signs
+	if degsPerSec < 0 {
+		angleDeg, degsPerSec = -angleDeg, -degsPerSec
+	}
+	return hb.runAllGoFor(ctx,
+		hb.wheelSpeeds(0, 0, rdkutils.DegToRad(degsPerSec)),
+		hb.wheelSpeeds(0, 0, rdkutils.DegToRad(angleDeg)))
+}
+
+// MoveStraight commands a base to drive forward or backwards at a linear speed and for a specific distance.
+func (hb *holonomicBase) MoveStraight(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+	ctx, done := hb.opMgr.New(ctx)
+	defer done()
+	hb.logger.CDebugf(ctx, "received a MoveStraight with distanceMM:%d, mmPerSec:%.2f", distanceMm, mmPerSec)
+
+	if math.Abs(mmPerSec) < 0.0001 || distanceMm == 0 {
+		err := hb.Stop(ctx, nil)
+		if err != nil {
+			return errors.Errorf("error when trying to move straight at a speed and/or distance of 0: %v", err)
+		}
+		return err
+	}
+
+	// a negative speed drives the other way, so the direction is the product of the signs
+	distance := float64(distanceMm)
+	if mmPerSec < 0 {
+		distance, mmPerSec = -distance, -mmPerSec
+	}
+	return hb.runAllGoFor(ctx, hb.wheelSpeeds(mmPerSec, 0, 0), hb.wheelSpeeds(distance, 0, 0))
+}
+
+// SetVelocity commands the base to move at the linear velocity, strafing to the right at linear X, while turning at
+// the angular velocity.
+func (hb *holonomicBase) SetVelocity(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	ctx, done := hb.opMgr.New(ctx)
+	defer done()
+	hb.logger.CDebugf(ctx,
+		"received a SetVelocity with linear.X: %.2f, linear.Y: %.2f linear.Z: %.2f(mmPerSec),"+
+			" angular.X: %.2f, angular.Y: %.2f, angular.Z: %.2f",
+		linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z)
+
+	if linear.Norm() == 0 && angular.Norm() == 0 {
+		hb.logger.CDebug(ctx, "received a SetVelocity command of linear 0,0,0, and angular 0,0,0, stopping base")
+		return hb.Stop(ctx, nil)
+	}
+
+	speeds := hb.wheelSpeeds(linear.Y, -linear.X, rdkutils.DegToRad(angular.Z))
+	return hb.runAll(ctx, func(ctx context.Context, i int, m motor.Motor) error {
+		return m.SetRPM(ctx, 60*speeds[i]/hb.wheelCircumferenceMM, nil)
+	})
+}
+
+// SetPower commands the base motors to run at powers which move the base in the direction of the linear power,
+// strafing to the right at linear X, while turning by the angular power. The powers are scaled down together when
+// any would be more than full power.
+func (hb *holonomicBase) SetPower(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	hb.opMgr.CancelRunning(ctx)
+
+	hb.logger.CDebugf(ctx,
+		"received a SetPower with linear.X: %.2f, linear.Y: %.2f linear.Z: %.2f,"+
+			" angular.X: %.2f, angular.Y: %.2f, angular.Z: %.2f",
+		linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z)
+
+	if linear.Norm() == 0 && angular.Norm() == 0 {
+		hb.logger.CDebug(ctx, "received a SetPower command of linear 0,0,0, and angular 0,0,0, stopping base")
+		return hb.Stop(ctx, nil)
+	}
+
+	powers := hb.powers(linear.Y, -linear.X, angular.Z)
+	return hb.runAll(ctx, func(ctx context.Context, i int, m motor.Motor) error {
+		return m.SetPower(ctx, powers[i], extra)
+	})
+}
+
+// powers returns the power of each motor for the forward, left and counterclockwise powers, with turning weighted as
+// though the wheels were a unit distance from the center.
+func (hb *holonomicBase) powers(forward, left, turn float64) []float64 {
+	powers := make([]float64, len(hb.wheels))
+	largest := 1.
+	for i, w := range hb.wheels {
+		powers[i] = w.forward*forward + w.left*left + math.Copysign(1, w.turn)*turn
+		largest = math.Max(largest, math.Abs(powers[i]))
+	}
+	for i := range powers {
+		powers[i] /= largest
+	}
+	return powers
+}
+
+// runAllGoFor runs each motor at the speed of its wheel for the distance its wheel travels, stopping those whose
+// wheels don't move. Callers must register an operation via `hb.opMgr.New`.
+func (hb *holonomicBase) runAllGoFor(ctx context.Context, speeds, distances []float64) error {
+	err := hb.runAll(ctx, func(ctx context.Context, i int, m motor.Motor) error {
+		revolutions := distances[i] / hb.wheelCircumferenceMM
+		if math.Abs(revolutions) < 1e-6 {
+			return m.Stop(ctx, nil)
+		}
+		rpm := 60 * math.Abs(speeds[i]) / hb.wheelCircumferenceMM
+		if rpm <= 10 {
+			hb.logger.CWarn(ctx, "low motor speed detected, motors may not behave as expected")
+		}
+		return m.GoFor(ctx, rpm, revolutions, nil)
+	})
+	// Ignore the context canceled error - this occurs when the base is stopped by the user.
+	if errors.Is(err, context.Canceled) {
+		hb.logger.Warnf("Context cancelled during GoFor %v", err)
+		return nil
+	}
+	return err
+}
+
+// runAll runs the function on all of the motors in parallel, stopping them if any fails.
+func (hb *holonomicBase) runAll(ctx context.Context, f func(context.Context, int, motor.Motor) error) error {
+	funcs := make([]rdkutils.SimpleFunc, 0, len(hb.motors))
+	for i, m := range hb.motors {
+		funcs = append(funcs, func(ctx context.Context) error { return f(ctx, i, m) })
+	}
+	if _, err := rdkutils.RunInParallel(ctx, funcs); err != nil {
+		return multierr.Combine(err, hb.stopMotors(ctx, nil))
+	}
+	return nil
+}
+
+// stopMotors stops all of the motors.
+func (hb *holonomicBase) stopMotors(ctx context.Context, extra map[string]interface{}) error {
+	funcs := make([]rdkutils.SimpleFunc, 0, len(hb.motors))
+	for _, m := range hb.motors {
+		funcs = append(funcs, func(ctx context.Context) error { return m.Stop(ctx, extra) })
+	}
+	_, err := rdkutils.RunInParallel(ctx, funcs)
+	return err
+}
+
+// Stop commands the base to stop moving.
+func (hb *holonomicBase) Stop(ctx context.Context, extra map[string]interface{}) error {
+	hb.opMgr.CancelRunning(ctx)
+	return hb.stopMotors(ctx, extra)
+}
+
+func (hb *holonomicBase) IsMoving(ctx context.Context) (bool, error) {
+	for _, m := range hb.motors {
+		isMoving, _, err := m.IsPowered(ctx, nil)
+		if err != nil {
+			return false, err
+		}
+		if isMoving {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Close stops the base.
+func (hb *holonomicBase) Close(ctx context.Context) error {
+	return hb.Stop(ctx, nil)
+}
+
+// Properties returns the width of the base and the circumference of its wheels. It can spin in place, so it has no
+// turning radius.
+func (hb *holonomicBase) Properties(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
+	return base.Properties{
+		WidthMeters:              hb.widthMM * 0.001,
+		WheelCircumferenceMeters: hb.wheelCircumferenceMM * 0.001,
+	}, nil
+}
+
+// Geometries returns the geometry of the base's frame, or a box around its wheels if the frame has none.
+func (hb *holonomicBase) Geometries(ctx context.Context, extra map[string]interface{}) ([]spatialmath.Geometry, error) {
+	return hb.geometries, nil
+}
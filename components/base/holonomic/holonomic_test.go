@@ -0,0 +1,246 @@
+package holonomic
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+// motorCommand is the last command sent to a motor.
+type motorCommand struct {
+	rpm, revolutions, power float64
+	stopped                 bool
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	commands map[string]motorCommand
+}
+
+// last returns the last command sent to each motor, in order.
+func (r *recorder) last(names []string) []motorCommand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	commands := make([]motorCommand, 0, len(names))
+	for _, name := range names {
+		commands = append(commands, r.commands[name])
+	}
+	return commands
+}
+
+func (r *recorder) set(name string, command motorCommand) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands[name] = command
+}
+
+func newTestBase(t *testing.T, conf *Config) (base.Base, *recorder) {
+	t.Helper()
+	rec := &recorder{commands: map[string]motorCommand{}}
+	deps := resource.Dependencies{}
+	for _, name := range conf.Motors {
+		m := inject.NewMotor(name)
+		m.GoForFunc = func(ctx context.Context, rpm, revolutions float64, extra map[string]interface{}) error {
+			rec.set(name, motorCommand{rpm: rpm, revolutions: revolutions})
+			return nil
+		}
+		m.SetRPMFunc = func(ctx context.Context, rpm float64, extra map[string]interface{}) error {
+			rec.set(name, motorCommand{rpm: rpm})
+			return nil
+		}
+		m.SetPowerFunc = func(ctx context.Context, powerPct float64, extra map[string]interface{}) error {
+			rec.set(name, motorCommand{power: powerPct})
+			return nil
+		}
+		m.StopFunc = func(ctx context.Context, extra map[string]interface{}) error {
+			rec.set(name, motorCommand{stopped: true})
+			return nil
+		}
+		deps[motor.Named(name)] = m
+	}
+
+	_, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	b, err := createHolonomicBase(context.Background(), deps, resource.Config{
+		Name: "cart", API: base.API, Model: Model, ConvertedAttributes: conf,
+	}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	return b, rec
+}
+
+func TestMecanum(t *testing.T) {
+	ctx := context.Background()
+	conf := &Config{
+		Motors:               []string{"fl", "fr", "bl", "br"},
+		WheelCircumferenceMM: 100,
+		WidthMM:              400,
+		LengthMM:             200,
+	}
+	b, rec := newTestBase(t, conf)
+	rpms := func() []float64 {
+		var rpms []float64
+		for _, c := range rec.last(conf.Motors) {
+			rpms = append(rpms, c.rpm)
+		}
+		return rpms
+	}
+
+	t.Run("strafe", func(t *testing.T) {
+		// 100mm/s is a wheel rpm of 60
+		test.That(t, b.SetVelocity(ctx, r3.Vector{X: 100}, r3.Vector{}, nil), test.ShouldBeNil)
+		test.That(t, rpms(), test.ShouldResemble, []float64{60, -60, -60, 60})
+
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 100}, r3.Vector{}, nil), test.ShouldBeNil)
+		test.That(t, rpms(), test.ShouldResemble, []float64{60, 60, 60, 60})
+
+		// driving diagonally forward and right only uses the front-left and back-right wheels
+		test.That(t, b.SetVelocity(ctx, r3.Vector{X: 50, Y: 50}, r3.Vector{}, nil), test.ShouldBeNil)
+		test.That(t, rpms(), test.ShouldResemble, []float64{60, 0, 0, 60})
+	})
+
+	t.Run("turn", func(t *testing.T) {
+		// turning at 1 rad/s moves each wheel at (width + length) / 2 = 300mm/s
+		test.That(t, b.SetVelocity(ctx, r3.Vector{}, r3.Vector{Z: 180 / math.Pi}, nil), test.ShouldBeNil)
+		for i, rpm := range []float64{-180, 180, -180, 180} {
+			test.That(t, rpms()[i], test.ShouldAlmostEqual, rpm)
+		}
+
+		test.That(t, b.Spin(ctx, -90, 90, nil), test.ShouldBeNil)
+		for i, c := range rec.last(conf.Motors) {
+			test.That(t, c.rpm, test.ShouldAlmostEqual, 90*math.Pi)
+			test.That(t, c.revolutions, test.ShouldAlmostEqual, []float64{1.5, -1.5, 1.5, -1.5}[i]*math.Pi)
+		}
+
+		// a negative speed spins the other way
+		test.That(t, b.Spin(ctx, 90, -90, nil), test.ShouldBeNil)
+		for i, c := range rec.last(conf.Motors) {
+			test.That(t, c.rpm, test.ShouldAlmostEqual, 90*math.Pi)
+			test.That(t, c.revolutions, test.ShouldAlmostEqual, []float64{1.5, -1.5, 1.5, -1.5}[i]*math.Pi)
+		}
+		test.That(t, b.Spin(ctx, -90, -90, nil), test.ShouldBeNil)
+		for i, c := range rec.last(conf.Motors) {
+			test.That(t, c.revolutions, test.ShouldAlmostEqual, []float64{-1.5, 1.5, -1.5, 1.5}[i]*math.Pi)
+		}
+	})
+
+	t.Run("move straight", func(t *testing.T) {
+		test.That(t, b.MoveStraight(ctx, -300, 100, nil), test.ShouldBeNil)
+		for _, c := range rec.last(conf.Motors) {
+			test.That(t, c.rpm, test.ShouldAlmostEqual, 60)
+			test.That(t, c.revolutions, test.ShouldAlmostEqual, -3)
+		}
+
+		// a negative speed drives the other way
+		test.That(t, b.MoveStraight(ctx, 1000, -500, nil), test.ShouldBeNil)
+		for _, c := range rec.last(conf.Motors) {
+			test.That(t, c.rpm, test.ShouldAlmostEqual, 300)
+			test.That(t, c.revolutions, test.ShouldAlmostEqual, -10)
+		}
+		test.That(t, b.MoveStraight(ctx, -300, -100, nil), test.ShouldBeNil)
+		for _, c := range rec.last(conf.Motors) {
+			test.That(t, c.revolutions, test.ShouldAlmostEqual, 3)
+		}
+	})
+
+	t.Run("set power", func(t *testing.T) {
+		// full power forward and to the right is scaled down so no wheel is over full power
+		test.That(t, b.SetPower(ctx, r3.Vector{X: 1, Y: 1}, r3.Vector{}, nil), test.ShouldBeNil)
+		var powers []float64
+		for _, c := range rec.last(conf.Motors) {
+			powers = append(powers, c.power)
+		}
+		test.That(t, powers, test.ShouldResemble, []float64{1, 0, 0, 1})
+	})
+
+	t.Run("properties", func(t *testing.T) {
+		props, err := b.Properties(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props.TurningRadiusMeters, test.ShouldEqual, 0)
+		test.That(t, props.WidthMeters, test.ShouldAlmostEqual, 0.4)
+		test.That(t, props.WheelCircumferenceMeters, test.ShouldAlmostEqual, 0.1)
+
+		geometries, err := b.Geometries(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, geometries, test.ShouldHaveLength, 1)
+		test.That(t, geometries[0].Label(), test.ShouldEqual, "cart")
+	})
+}
+
+func TestOmni(t *testing.T) {
+	ctx := context.Background()
+	conf := &Config{
+		Layout:               LayoutOmni,
+		Motors:               []string{"front", "back-left", "back-right"},
+		WheelCircumferenceMM: 100,
+		RadiusMM:             100,
+	}
+	b, rec := newTestBase(t, conf)
+	speeds := func() []float64 {
+		var speeds []float64
+		for _, c := range rec.last(conf.Motors) {
+			speeds = append(speeds, c.rpm*100/60)
+		}
+		return speeds
+	}
+
+	// the front wheel drives to the left, so strafing right turns it backward and the others share the rest
+	test.That(t, b.SetVelocity(ctx, r3.Vector{X: 100}, r3.Vector{}, nil), test.ShouldBeNil)
+	for i, speed := range []float64{-100, 50, 50} {
+		test.That(t, speeds()[i], test.ShouldAlmostEqual, speed)
+	}
+
+	// driving forward doesn't turn the front wheel
+	test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 100}, r3.Vector{}, nil), test.ShouldBeNil)
+	for i, speed := range []float64{0, -100 * math.Sqrt(3) / 2, 100 * math.Sqrt(3) / 2} {
+		test.That(t, speeds()[i], test.ShouldAlmostEqual, speed)
+	}
+	test.That(t, b.MoveStraight(ctx, 1000, 100, nil), test.ShouldBeNil)
+	commands := rec.last(conf.Motors)
+	test.That(t, commands[0].stopped, test.ShouldBeTrue)
+	test.That(t, commands[1].revolutions, test.ShouldAlmostEqual, -10*math.Sqrt(3)/2)
+	test.That(t, commands[2].revolutions, test.ShouldAlmostEqual, 10*math.Sqrt(3)/2)
+
+	// turning moves every wheel the same way
+	test.That(t, b.SetVelocity(ctx, r3.Vector{}, r3.Vector{Z: 180 / math.Pi}, nil), test.ShouldBeNil)
+	for _, speed := range speeds() {
+		test.That(t, speed, test.ShouldAlmostEqual, 100)
+	}
+
+	props, err := b.Properties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.WidthMeters, test.ShouldAlmostEqual, 0.1*math.Sqrt(3))
+}
+
+func TestValidate(t *testing.T) {
+	conf := &Config{}
+	_, _, err := conf.Validate("path")
+	test.That(t, resource.GetFieldFromFieldRequiredError(err), test.ShouldEqual, "wheel_circumference_mm")
+
+	conf.WheelCircumferenceMM = 100
+	conf.Motors = []string{"a", "b", "c"}
+	_, _, err = conf.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "a mecanum base needs 4 motors, not 3")
+
+	conf.Layout = LayoutOmni
+	_, _, err = conf.Validate("path")
+	test.That(t, resource.GetFieldFromFieldRequiredError(err), test.ShouldEqual, "radius_mm")
+
+	conf.RadiusMM = 100
+	deps, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"a", "b", "c"})
+
+	conf.Layout = "tank"
+	_, _, err = conf.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "layout must be")
+}
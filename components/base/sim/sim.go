@@ -0,0 +1,543 @@
+// Package sim implements a simulated base, which integrates the velocities it is commanded into a ground truth pose
+// on the globe, with configurable wheel slip and noise. Simulated movement sensors emulating GPS, IMU and wheel
+// encoders from its ground truth are in the movementsensor sim package, so that motion planning and navigation can be
+// tested end to end without hardware.
+package sim
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// Model is the name used to refer to the simulated base model.
+var Model = resource.DefaultModelFamily.WithModel("simulated")
+
+// Kinematics of the simulated base.
+const (
+	KinematicsDifferential = "differential"
+	KinematicsAckermann    = "ackermann"
+)
+
+const (
+	defaultWidthMM              = 600
+	defaultWheelCircumferenceMM = 600
+	defaultWheelbaseMM          = 800
+	defaultMaxSteeringAngleDeg  = 30
+	defaultMaxSpeedMMPerSec     = 1000
+	defaultMaxAngularDegsPerSec = 90
+
+	// how often the pose is integrated when simulating time.
+	timeStep = 10 * time.Millisecond
+	// how long the ground truth is kept for, so that sensors can emulate latency.
+	historyDuration = 5 * time.Second
+)
+
+// DoCommand keys.
+const (
+	// DoGroundTruth returns the ground truth of the base.
+	DoGroundTruth = "ground_truth"
+	// DoSetPose moves the base to the given "latitude", "longitude" and "heading_deg" without driving there.
+	DoSetPose = "set_pose"
+)
+
+// Config is used for converting config attributes.
+type Config struct {
+	// Kinematics is either differential, the default, which can spin in place, or ackermann, which steers its
+	// front wheels and so turns no tighter than its minimum turning radius.
+	Kinematics           string `json:"kinematics,omitempty"`
+	WidthMM              int    `json:"width_mm,omitempty"`
+	WheelCircumferenceMM int    `json:"wheel_circumference_mm,omitempty"`
+	// WheelbaseMM and MaxSteeringAngleDeg give the minimum turning radius of an ackermann base.
+	WheelbaseMM         int     `json:"wheelbase_mm,omitempty"`
+	MaxSteeringAngleDeg float64 `json:"max_steering_angle_deg,omitempty"`
+
+	// MaxSpeedMMPerSec and MaxAngularDegsPerSec are the velocities at full power. Commanded velocities are limited
+	// to them.
+	MaxSpeedMMPerSec     float64 `json:"max_speed_mm_per_sec,omitempty"`
+	MaxAngularDegsPerSec float64 `json:"max_angular_degs_per_sec,omitempty"`
+
+	// The pose the base starts at.
+	Latitude   float64 `json:"latitude,omitempty"`
+	Longitude  float64 `json:"longitude,omitempty"`
+	HeadingDeg float64 `json:"heading_deg,omitempty"`
+
+	// SlipFraction and SpinSlipFraction are the fractions of the commanded linear and angular velocities lost to
+	// wheel slip. Wheel encoders measure the commanded motion, before slip.
+	SlipFraction     float64 `json:"slip_fraction,omitempty"`
+	SpinSlipFraction float64 `json:"spin_slip_fraction,omitempty"`
+	// LinearNoiseMMPerSec and AngularNoiseDegsPerSec are the standard deviations of noise added to the velocities
+	// of the base while it is driven.
+	LinearNoiseMMPerSec    float64 `json:"linear_noise_mm_per_sec,omitempty"`
+	AngularNoiseDegsPerSec float64 `json:"angular_noise_degs_per_sec,omitempty"`
+	// Seed seeds the noise, so that runs are repeatable.
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (conf *Config) Validate(path string) ([]string, []string, error) {
+	switch conf.Kinematics {
+	case "", KinematicsDifferential, KinematicsAckermann:
+	default:
+		return nil, nil, resource.NewConfigValidationError(path,
+			errors.Errorf("kinematics must be %q or %q", KinematicsDifferential, KinematicsAckermann))
+	}
+	if conf.WidthMM < 0 || conf.WheelCircumferenceMM < 0 || conf.WheelbaseMM < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("dimensions must be non-negative"))
+	}
+	if conf.MaxSteeringAngleDeg < 0 || conf.MaxSteeringAngleDeg >= 90 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("max_steering_angle_deg must be in [0, 90)"))
+	}
+	if conf.MaxSpeedMMPerSec < 0 || conf.MaxAngularDegsPerSec < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("maximum velocities must be non-negative"))
+	}
+	if conf.SlipFraction < 0 || conf.SlipFraction >= 1 || conf.SpinSlipFraction < 0 || conf.SpinSlipFraction >= 1 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("slip fractions must be in [0, 1)"))
+	}
+	if conf.LinearNoiseMMPerSec < 0 || conf.AngularNoiseDegsPerSec < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("noise must be non-negative"))
+	}
+	if conf.Latitude < -90 || conf.Latitude > 90 || conf.Longitude < -180 || conf.Longitude > 180 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("invalid starting latitude or longitude"))
+	}
+	return nil, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(base.API, Model, resource.Registration[base.Base, *Config]{
+		Constructor: NewBase,
+	})
+}
+
+// GroundTruth is the true state of a simulated base at an instant.
+type GroundTruth struct {
+	Time     time.Time
+	Position *geo.Point
+	// HeadingDeg is the compass heading of the base, clockwise from north.
+	HeadingDeg float64
+	// LinearVelocityMMPerSec is the forward velocity and AngularVelocityDegsPerSec the counterclockwise velocity of
+	// the base.
+	LinearVelocityMMPerSec    float64
+	AngularVelocityDegsPerSec float64
+	// LinearAcceleration is in meters per second per second in the frame of the base, with Y forward.
+	LinearAcceleration r3.Vector
+	// LeftWheelMM and RightWheelMM are the total distances driven by each side's wheels, as the wheels measure it.
+	LeftWheelMM  float64
+	RightWheelMM float64
+}
+
+// Simulated is implemented by simulated bases, giving simulated sensors the ground truth to measure.
+type Simulated interface {
+	base.Base
+	// GroundTruthAt returns the ground truth as it was at t, or as long ago as is kept if t is earlier.
+	GroundTruthAt(t time.Time) GroundTruth
+	// WidthMM is the distance between the left and right wheels.
+	WidthMM() float64
+}
+
+// operation is an in-flight MoveStraight or Spin, which drives until the wheels have covered the distance.
+type operation struct {
+	// remaining is in mm for MoveStraight and degrees for Spin.
+	remaining float64
+	spin      bool
+	done      bool
+	stopped   bool
+}
+
+type simulatedBase struct {
+	resource.Named
+	resource.AlwaysRebuild
+
+	kinematics           string
+	widthMM              float64
+	wheelCircumferenceMM float64
+	minTurningRadiusMM   float64
+	maxSpeedMMPerSec     float64
+	maxAngularDegsPerSec float64
+	slipFraction         float64
+	spinSlipFraction     float64
+	linearNoise          float64
+	angularNoise         float64
+	geometries           []spatialmath.Geometry
+
+	ctx    context.Context
+	cancel func()
+	// timeSimulation updates the pose every few milliseconds. Without it, as in tests, the base only moves when
+	// updateForTime is called.
+	timeSimulation *utils.StoppableWorkers
+
+	mu          sync.Mutex
+	rand        *rand.Rand
+	origin      *geo.Point
+	lastUpdated time.Time
+	// eastMM and northMM are the position relative to origin, yaw the counterclockwise heading from north in radians.
+	eastMM, northMM, yaw float64
+	// commanded velocities, in mm/s forward and degrees/s counterclockwise.
+	cmdLinear, cmdAngular float64
+	// velocities actually achieved over the last update.
+	linear, angular           float64
+	accel                     r3.Vector
+	leftWheelMM, rightWheelMM float64
+	op                        *operation
+	// history of the ground truth, oldest first.
+	history []GroundTruth
+
+	logger logging.Logger
+}
+
+// NewBase is the constructor registered for the simulated base model.
+func NewBase(ctx context.Context, _ resource.Dependencies, conf resource.Config, logger logging.Logger) (base.Base, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	var geometries []spatialmath.Geometry
+	if conf.Frame != nil && conf.Frame.Geometry != nil {
+		geometry, err := conf.Frame.Geometry.ParseConfig()
+		if err != nil {
+			return nil, err
+		}
+		geometries = append(geometries, geometry)
+	}
+	return newBase(conf.ResourceName().AsNamed(), newConf, geometries, true, logger), nil
+}
+
+func newBase(
+	named resource.Named, conf *Config, geometries []spatialmath.Geometry, simulateTime bool, logger logging.Logger,
+) *simulatedBase {
+	orDefault := func(v, def float64) float64 {
+		if v > 0 {
+			return v
+		}
+		return def
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sb := &simulatedBase{
+		Named:                named,
+		kinematics:           KinematicsDifferential,
+		widthMM:              orDefault(float64(conf.WidthMM), defaultWidthMM),
+		wheelCircumferenceMM: orDefault(float64(conf.WheelCircumferenceMM), defaultWheelCircumferenceMM),
+		maxSpeedMMPerSec:     orDefault(conf.MaxSpeedMMPerSec, defaultMaxSpeedMMPerSec),
+		maxAngularDegsPerSec: orDefault(conf.MaxAngularDegsPerSec, defaultMaxAngularDegsPerSec),
+		slipFraction:         conf.SlipFraction,
+		spinSlipFraction:     conf.SpinSlipFraction,
+		linearNoise:          conf.LinearNoiseMMPerSec,
+		angularNoise:         conf.AngularNoiseDegsPerSec,
+		geometries:           geometries,
+		ctx:                  ctx,
+		cancel:               cancel,
+		//nolint:gosec
+		rand:   rand.New(rand.NewSource(conf.Seed)),
+		origin: geo.NewPoint(conf.Latitude, conf.Longitude),
+		yaw:    -rdkutils.DegToRad(conf.HeadingDeg),
+		logger: logger,
+	}
+	if conf.Kinematics == KinematicsAckermann {
+		sb.kinematics = KinematicsAckermann
+		wheelbase := orDefault(float64(conf.WheelbaseMM), defaultWheelbaseMM)
+		steering := orDefault(conf.MaxSteeringAngleDeg, defaultMaxSteeringAngleDeg)
+		sb.minTurningRadiusMM = wheelbase / math.Tan(rdkutils.DegToRad(steering))
+	}
+
+	if simulateTime {
+		// avoid the zero time ever being seen, lest the first update integrate over all of it
+		sb.lastUpdated = time.Now()
+	}
+	sb.history = []GroundTruth{sb.groundTruth()}
+	if simulateTime {
+		sb.timeSimulation = utils.NewStoppableWorkerWithTicker(timeStep, func(_ context.Context) {
+			sb.updateForTime(time.Now())
+		})
+	}
+	return sb
+}
+
+// updateForTime integrates the commanded velocities up to now. The base only moves when it is called, which tests can
+// use to pass time deterministically.
+func (sb *simulatedBase) updateForTime(now time.Time) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	dt := now.Sub(sb.lastUpdated).Seconds()
+	sb.lastUpdated = now
+	if dt <= 0 {
+		return
+	}
+
+	// finish an operation part way through the step once the wheels have covered its distance
+	moveDt := dt
+	if op := sb.op; op != nil && !op.done && !op.stopped {
+		rate := math.Abs(sb.cmdLinear)
+		if op.spin {
+			rate = math.Abs(sb.cmdAngular)
+		}
+		if rate*dt >= op.remaining {
+			moveDt = op.remaining / rate
+			op.remaining = 0
+			op.done = true
+		} else {
+			op.remaining -= rate * dt
+		}
+	}
+	sb.integrate(moveDt)
+	if sb.op != nil && sb.op.done {
+		sb.cmdLinear, sb.cmdAngular = 0, 0
+		sb.op = nil
+	}
+
+	sb.history = append(sb.history, sb.groundTruth())
+	cutoff := now.Add(-historyDuration)
+	drop := sort.Search(len(sb.history), func(i int) bool { return !sb.history[i].Time.Before(cutoff) })
+	if drop > 0 && drop < len(sb.history) {
+		sb.history = append(sb.history[:0], sb.history[drop:]...)
+	}
+}
+
+// integrate moves the base for dt seconds at its commanded velocities, less slip and with noise.
+func (sb *simulatedBase) integrate(dt float64) {
+	linear := sb.cmdLinear * (1 - sb.slipFraction)
+	angular := sb.cmdAngular * (1 - sb.spinSlipFraction)
+	if sb.cmdLinear != 0 {
+		linear += sb.rand.NormFloat64() * sb.linearNoise
+	}
+	if sb.cmdAngular != 0 {
+		angular += sb.rand.NormFloat64() * sb.angularNoise
+	}
+	if dt > 0 {
+		sb.accel = r3.Vector{
+			// centripetal, to the left of the base when turning counterclockwise
+			X: -linear * rdkutils.DegToRad(angular) / 1000,
+			Y: (linear - sb.linear) / dt / 1000,
+		}
+	}
+	sb.linear, sb.angular = linear, angular
+
+	// the wheels measure the commanded motion, slip is them turning without the base moving as far
+	spinMM := rdkutils.DegToRad(sb.cmdAngular) * sb.widthMM / 2
+	sb.leftWheelMM += (sb.cmdLinear - spinMM) * dt
+	sb.rightWheelMM += (sb.cmdLinear + spinMM) * dt
+
+	// drive along the arc, approximated by the chord at the mean heading
+	dYaw := rdkutils.DegToRad(angular) * dt
+	mid := sb.yaw + dYaw/2
+	dist := linear * dt
+	sb.eastMM -= dist * math.Sin(mid)
+	sb.northMM += dist * math.Cos(mid)
+	sb.yaw += dYaw
+}
+
+func (sb *simulatedBase) groundTruth() GroundTruth {
+	heading := math.Mod(-rdkutils.RadToDeg(sb.yaw), 360)
+	if heading < 0 {
+		heading += 360
+	}
+	return GroundTruth{
+		Time:                      sb.lastUpdated,
+		Position:                  spatialmath.PointToGeoPoint(r3.Vector{X: sb.eastMM, Y: sb.northMM}, sb.origin),
+		HeadingDeg:                heading,
+		LinearVelocityMMPerSec:    sb.linear,
+		AngularVelocityDegsPerSec: sb.angular,
+		LinearAcceleration:        sb.accel,
+		LeftWheelMM:               sb.leftWheelMM,
+		RightWheelMM:              sb.rightWheelMM,
+	}
+}
+
+// GroundTruthAt returns the ground truth as it was at t, or as long ago as is kept if t is earlier.
+func (sb *simulatedBase) GroundTruthAt(t time.Time) GroundTruth {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	i := sort.Search(len(sb.history), func(i int) bool { return sb.history[i].Time.After(t) })
+	if i == 0 {
+		return sb.history[0]
+	}
+	return sb.history[i-1]
+}
+
+// WidthMM is the distance between the left and right wheels.
+func (sb *simulatedBase) WidthMM() float64 {
+	return sb.widthMM
+}
+
+// setVelocity sets the commanded velocities, limited to what the base can do. sb.mu must be held.
+func (sb *simulatedBase) setVelocity(linear, angular float64) {
+	linear = math.Max(-sb.maxSpeedMMPerSec, math.Min(sb.maxSpeedMMPerSec, linear))
+	angular = math.Max(-sb.maxAngularDegsPerSec, math.Min(sb.maxAngularDegsPerSec, angular))
+	if sb.minTurningRadiusMM > 0 {
+		// an ackermann base turns no tighter than its minimum radius, and not at all when stopped
+		maxAngular := rdkutils.RadToDeg(math.Abs(linear) / sb.minTurningRadiusMM)
+		angular = math.Max(-maxAngular, math.Min(maxAngular, angular))
+	}
+	sb.cmdLinear, sb.cmdAngular = linear, angular
+}
+
+// stopOperation stops any in-flight MoveStraight or Spin. sb.mu must be held.
+func (sb *simulatedBase) stopOperation() {
+	if sb.op != nil {
+		sb.op.stopped = true
+		sb.op = nil
+	}
+}
+
+// runOperation starts driving at the given velocities until op is done, blocking until it is.
+func (sb *simulatedBase) runOperation(ctx context.Context, op *operation, linear, angular float64) error {
+	sb.mu.Lock()
+	sb.stopOperation()
+	sb.setVelocity(linear, angular)
+	sb.op = op
+	sb.mu.Unlock()
+
+	for {
+		sb.mu.Lock()
+		done, stopped := op.done, op.stopped
+		sb.mu.Unlock()
+		if done {
+			return nil
+		}
+		if stopped {
+			return errors.New("stopped before completing the move")
+		}
+		select {
+		case <-ctx.Done():
+			return multierr.Combine(ctx.Err(), sb.Stop(context.Background(), nil))
+		case <-sb.ctx.Done():
+			return sb.ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+// MoveStraight drives straight until the wheels have covered distanceMm.
+func (sb *simulatedBase) MoveStraight(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+	if distanceMm == 0 || mmPerSec == 0 {
+		return sb.Stop(ctx, nil)
+	}
+	speed := math.Abs(mmPerSec)
+	if (distanceMm < 0) != (mmPerSec < 0) {
+		speed = -speed
+	}
+	op := &operation{remaining: math.Abs(float64(distanceMm))}
+	return sb.runOperation(ctx, op, speed, 0)
+}
+
+// Spin turns in place until the wheels have covered angleDeg.
+func (sb *simulatedBase) Spin(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+	if sb.kinematics == KinematicsAckermann {
+		return errors.New("an ackermann base cannot spin in place")
+	}
+	if angleDeg == 0 || degsPerSec == 0 {
+		return sb.Stop(ctx, nil)
+	}
+	speed := math.Abs(degsPerSec)
+	if (angleDeg < 0) != (degsPerSec < 0) {
+		speed = -speed
+	}
+	op := &operation{remaining: math.Abs(angleDeg), spin: true}
+	return sb.runOperation(ctx, op, 0, speed)
+}
+
+// SetPower drives at the given fractions of the maximum velocities until told otherwise.
+func (sb *simulatedBase) SetPower(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	sb.stopOperation()
+	sb.setVelocity(linear.Y*sb.maxSpeedMMPerSec, angular.Z*sb.maxAngularDegsPerSec)
+	return nil
+}
+
+// SetVelocity drives at the given velocities, in mm/s and degrees/s, until told otherwise.
+func (sb *simulatedBase) SetVelocity(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	sb.stopOperation()
+	sb.setVelocity(linear.Y, angular.Z)
+	return nil
+}
+
+// Stop stops the base.
+func (sb *simulatedBase) Stop(ctx context.Context, extra map[string]interface{}) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	sb.stopOperation()
+	sb.cmdLinear, sb.cmdAngular = 0, 0
+	return nil
+}
+
+// IsMoving returns whether the base is being driven.
+func (sb *simulatedBase) IsMoving(ctx context.Context) (bool, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.cmdLinear != 0 || sb.cmdAngular != 0, nil
+}
+
+// Properties returns the base's properties.
+func (sb *simulatedBase) Properties(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
+	return base.Properties{
+		TurningRadiusMeters:      sb.minTurningRadiusMM / 1000,
+		WidthMeters:              sb.widthMM / 1000,
+		WheelCircumferenceMeters: sb.wheelCircumferenceMM / 1000,
+	}, nil
+}
+
+// Geometries returns the geometries of the base's frame.
+func (sb *simulatedBase) Geometries(ctx context.Context, extra map[string]interface{}) ([]spatialmath.Geometry, error) {
+	return sb.geometries, nil
+}
+
+// DoCommand returns the ground truth, or moves the base without driving it.
+func (sb *simulatedBase) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := cmd[DoGroundTruth]; ok {
+		gt := sb.GroundTruthAt(time.Now())
+		return map[string]interface{}{
+			"latitude":                      gt.Position.Lat(),
+			"longitude":                     gt.Position.Lng(),
+			"heading_deg":                   gt.HeadingDeg,
+			"linear_velocity_mm_per_sec":    gt.LinearVelocityMMPerSec,
+			"angular_velocity_degs_per_sec": gt.AngularVelocityDegsPerSec,
+		}, nil
+	}
+	if req, ok := cmd[DoSetPose]; ok {
+		args, err := rdkutils.AssertType[map[string]interface{}](req)
+		if err != nil {
+			return nil, err
+		}
+		lat, latOK := args["latitude"].(float64)
+		lng, lngOK := args["longitude"].(float64)
+		heading, _ := args["heading_deg"].(float64)
+		if !latOK || !lngOK {
+			return nil, errors.Errorf("%s requires a latitude and longitude", DoSetPose)
+		}
+		sb.mu.Lock()
+		defer sb.mu.Unlock()
+		p := spatialmath.GeoPointToPoint(geo.NewPoint(lat, lng), sb.origin)
+		sb.eastMM, sb.northMM = p.X, p.Y
+		sb.yaw = -rdkutils.DegToRad(heading)
+		sb.history = append(sb.history, sb.groundTruth())
+		return map[string]interface{}{DoSetPose: true}, nil
+	}
+	return nil, resource.ErrDoUnimplemented
+}
+
+// Close stops the base and simulating time.
+func (sb *simulatedBase) Close(ctx context.Context) error {
+	sb.cancel()
+	if sb.timeSimulation != nil {
+		sb.timeSimulation.Stop()
+	}
+	return nil
+}
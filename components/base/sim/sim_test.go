@@ -0,0 +1,169 @@
+package sim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+)
+
+var testOrigin = geo.NewPoint(40, -74)
+
+func newTestBase(t *testing.T, conf *Config) (*simulatedBase, time.Time) {
+	t.Helper()
+	conf.Latitude, conf.Longitude = testOrigin.Lat(), testOrigin.Lng()
+	_, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	sb := newBase(base.Named("base").AsNamed(), conf, nil, false, logging.NewTestLogger(t))
+	start := time.Unix(1000, 0)
+	sb.updateForTime(start)
+	return sb, start
+}
+
+// runUntilStopped passes time in steps until the base stops moving, returning the time it stopped at.
+func runUntilStopped(t *testing.T, sb *simulatedBase, now time.Time, move func() error) time.Time {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- move()
+	}()
+	for {
+		select {
+		case err := <-errCh:
+			test.That(t, err, test.ShouldBeNil)
+			return now
+		default:
+		}
+		sb.mu.Lock()
+		started := sb.op != nil
+		sb.mu.Unlock()
+		if started {
+			now = now.Add(timeStep)
+			sb.updateForTime(now)
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+}
+
+// localPosition returns the position of the base in meters east and north of the origin.
+func localPosition(gt GroundTruth) r3.Vector {
+	return spatialmath.GeoPointToPoint(gt.Position, testOrigin).Mul(1e-3)
+}
+
+func TestMoveStraightAndSpin(t *testing.T) {
+	ctx := context.Background()
+	sb, now := newTestBase(t, &Config{})
+
+	now = runUntilStopped(t, sb, now, func() error { return sb.MoveStraight(ctx, 1000, 500, nil) })
+	gt := sb.GroundTruthAt(now)
+	p := localPosition(gt)
+	test.That(t, p.X, test.ShouldAlmostEqual, 0, 1e-6)
+	test.That(t, p.Y, test.ShouldAlmostEqual, 1, 1e-3)
+	test.That(t, gt.HeadingDeg, test.ShouldAlmostEqual, 0)
+	moving, err := sb.IsMoving(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, moving, test.ShouldBeFalse)
+
+	// spinning counterclockwise turns the base west
+	now = runUntilStopped(t, sb, now, func() error { return sb.Spin(ctx, 90, 45, nil) })
+	gt = sb.GroundTruthAt(now)
+	test.That(t, gt.HeadingDeg, test.ShouldAlmostEqual, 270, 1e-6)
+	test.That(t, gt.RightWheelMM-gt.LeftWheelMM, test.ShouldAlmostEqual, 0.6*1000*3.14159265/2, 0.1)
+
+	// driving backwards keeps the heading
+	now = runUntilStopped(t, sb, now, func() error { return sb.MoveStraight(ctx, -500, 250, nil) })
+	p = localPosition(sb.GroundTruthAt(now))
+	test.That(t, p.X, test.ShouldAlmostEqual, 0.5, 1e-3)
+	test.That(t, p.Y, test.ShouldAlmostEqual, 1, 1e-3)
+
+	// the history is kept for emulating latency
+	test.That(t, localPosition(sb.GroundTruthAt(now.Add(-historyDuration-time.Second))).Y, test.ShouldBeGreaterThan, 0)
+	test.That(t, sb.GroundTruthAt(now.Add(-time.Second)).Time.Before(now), test.ShouldBeTrue)
+}
+
+func TestSetVelocity(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("slip", func(t *testing.T) {
+		sb, now := newTestBase(t, &Config{SlipFraction: 0.1})
+		test.That(t, sb.SetVelocity(ctx, r3.Vector{Y: 1000}, r3.Vector{}, nil), test.ShouldBeNil)
+		for range 100 {
+			now = now.Add(timeStep)
+			sb.updateForTime(now)
+		}
+		test.That(t, sb.Stop(ctx, nil), test.ShouldBeNil)
+		gt := sb.GroundTruthAt(now)
+		// the wheels turned a full meter but the base only went 90cm
+		test.That(t, localPosition(gt).Y, test.ShouldAlmostEqual, 0.9, 1e-6)
+		test.That(t, gt.LeftWheelMM, test.ShouldAlmostEqual, 1000, 1e-6)
+		test.That(t, gt.RightWheelMM, test.ShouldAlmostEqual, 1000, 1e-6)
+	})
+
+	t.Run("velocities are limited", func(t *testing.T) {
+		sb, _ := newTestBase(t, &Config{MaxSpeedMMPerSec: 500})
+		test.That(t, sb.SetPower(ctx, r3.Vector{Y: -1}, r3.Vector{Z: 0.5}, nil), test.ShouldBeNil)
+		test.That(t, sb.cmdLinear, test.ShouldEqual, -500)
+		test.That(t, sb.cmdAngular, test.ShouldEqual, 45)
+		test.That(t, sb.SetVelocity(ctx, r3.Vector{Y: 800}, r3.Vector{Z: -200}, nil), test.ShouldBeNil)
+		test.That(t, sb.cmdLinear, test.ShouldEqual, 500)
+		test.That(t, sb.cmdAngular, test.ShouldEqual, -90)
+	})
+
+	t.Run("ackermann", func(t *testing.T) {
+		sb, now := newTestBase(t, &Config{Kinematics: KinematicsAckermann, WheelbaseMM: 1000, MaxSteeringAngleDeg: 45})
+		props, err := sb.Properties(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props.TurningRadiusMeters, test.ShouldAlmostEqual, 1)
+		test.That(t, sb.Spin(ctx, 90, 45, nil), test.ShouldNotBeNil)
+
+		// asking to turn tighter than the minimum radius drives a circle of the minimum radius
+		test.That(t, sb.SetVelocity(ctx, r3.Vector{Y: 1000}, r3.Vector{Z: 90}, nil), test.ShouldBeNil)
+		halfCircle := time.Duration(3.14159265 * float64(time.Second))
+		for elapsed := time.Duration(0); elapsed < halfCircle; elapsed += time.Millisecond {
+			now = now.Add(time.Millisecond)
+			sb.updateForTime(now)
+		}
+		p := localPosition(sb.GroundTruthAt(now))
+		test.That(t, p.X, test.ShouldAlmostEqual, -2, 1e-2)
+		test.That(t, p.Y, test.ShouldAlmostEqual, 0, 1e-2)
+	})
+}
+
+func TestDoCommand(t *testing.T) {
+	ctx := context.Background()
+	sb, _ := newTestBase(t, &Config{})
+	_, err := sb.DoCommand(ctx, map[string]interface{}{
+		DoSetPose: map[string]interface{}{"latitude": 40.001, "longitude": -74.0, "heading_deg": 90.0},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	resp, err := sb.DoCommand(ctx, map[string]interface{}{DoGroundTruth: true})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp["latitude"], test.ShouldAlmostEqual, 40.001, 1e-9)
+	test.That(t, resp["heading_deg"], test.ShouldAlmostEqual, 90)
+}
+
+func TestValidate(t *testing.T) {
+	for _, conf := range []*Config{
+		{Kinematics: "tank"},
+		{SlipFraction: 1},
+		{MaxSteeringAngleDeg: 90},
+		{Latitude: 91},
+	} {
+		_, _, err := conf.Validate("path")
+		test.That(t, err, test.ShouldNotBeNil)
+	}
+
+	sb, err := NewBase(context.Background(), nil, resource.Config{
+		Name: "base", API: base.API, Model: Model, ConvertedAttributes: &Config{},
+	}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sb.Close(context.Background()), test.ShouldBeNil)
+}
@@ -6,7 +6,8 @@ package wheeled
    number of motors on its left and right sides. The base's width and wheel circumference dimensions are required to
    compute wheel speeds to move the base straight distances or spin to headings at the desired input speeds. A spin slip
    factor acts as a multiplier to adjust power delivery to the wheels when each side of the base is undergoing unequal
-   friction because of the surface it is moving on.
+   friction because of the surface it is moving on. A turning radius is only reported in the base's properties; it
+   doesn't change how the base moves, which still spins in place.
    Any motors can be used for the base motors (encoded, un-encoded, steppers, servos) as long as they update their position
    continuously (not limited to 0-360 or any other domain).
 
@@ -60,6 +61,8 @@ type Config struct {
 	SpinSlipFactor       float64  `json:"spin_slip_factor,omitempty"`
 	Left                 []string `json:"left"`
 	Right                []string `json:"right"`
+	// TurningRadiusMM is the turning radius the base reports in its properties. It doesn't change how the base moves.
+	TurningRadiusMM int `json:"turning_radius_mm,omitempty"`
 }
 
 // Validate ensures all parts of the config are valid.
@@ -81,6 +84,10 @@ func (cfg *Config) Validate(path string) ([]string, []string, error) {
 		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "right")
 	}
 
+	if cfg.TurningRadiusMM < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("turning_radius_mm can't be negative"))
+	}
+
 	if len(cfg.Left) != len(cfg.Right) {
 		return nil, nil, resource.NewConfigValidationError(path,
 			fmt.Errorf("left and right need to have the same number of motors, not %d vs %d",
@@ -102,6 +109,7 @@ type wheeledBase struct {
 	widthMm              int
 	wheelCircumferenceMm int
 	spinSlipFactor       float64
+	turningRadiusMm      int
 	geometries           []spatialmath.Geometry
 
 	left      []motor.Motor
@@ -202,6 +210,8 @@ func (wb *wheeledBase) reconfigure(ctx context.Context, deps resource.Dependenci
 		wb.wheelCircumferenceMm = newConf.WheelCircumferenceMM
 	}
 
+	wb.turningRadiusMm = newConf.TurningRadiusMM
+
 	return nil
 }
 
@@ -556,7 +566,7 @@ func (wb *wheeledBase) Close(ctx context.Context) error {
 
 func (wb *wheeledBase) Properties(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
 	return base.Properties{
-		TurningRadiusMeters:      0.0,
+		TurningRadiusMeters:      float64(wb.turningRadiusMm) * 0.001,      // convert to meters from mm
 		WidthMeters:              float64(wb.widthMm) * 0.001,              // convert to meters from mm
 		WheelCircumferenceMeters: float64(wb.wheelCircumferenceMm) * 0.001, // convert to meters from mm
 	}, nil
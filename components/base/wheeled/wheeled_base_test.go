@@ -85,6 +85,7 @@ func TestWheelBaseMath(t *testing.T) {
 		props, err := wb.Properties(ctx, nil)
 		test.That(t, err, test.ShouldBeNil)
 		test.That(t, props.WidthMeters, test.ShouldEqual, 100*0.001)
+		test.That(t, props.TurningRadiusMeters, test.ShouldEqual, 0)
 
 		geometries, err := wb.Geometries(ctx, nil)
 		test.That(t, len(geometries), test.ShouldBeZeroValue)
@@ -430,12 +431,16 @@ func TestWheeledBaseReconfigure(t *testing.T) {
 		WheelCircumferenceMM: 1000,
 		Left:                 []string{"fl-m", "bl-m", "ml-m"},
 		Right:                []string{"fr-m", "br-m", "mr-m"},
+		TurningRadiusMM:      1500,
 	}
 
 	deps, _, err = newestTestCfg.Validate("path", resource.APITypeComponentName)
 	test.That(t, err, test.ShouldBeNil)
 	motorDeps = fakeMotorDependencies(t, deps)
 	test.That(t, wb.reconfigure(ctx, motorDeps, newestTestCfg), test.ShouldBeNil)
+	props, err := wb.Properties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.TurningRadiusMeters, test.ShouldAlmostEqual, 1.5)
 }
 
 func TestValidate(t *testing.T) {
@@ -468,6 +473,10 @@ func TestValidate(t *testing.T) {
 	deps, _, err = cfg.Validate("path")
 	test.That(t, deps, test.ShouldResemble, []string{"fl-m", "bl-m", "fr-m", "br-m"})
 	test.That(t, err, test.ShouldBeNil)
+
+	cfg.TurningRadiusMM = -1
+	_, _, err = cfg.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "turning_radius_mm can't be negative")
 }
 
 // waitForMotorsToStop polls all motors to see if they're on, used only for testing.
@@ -0,0 +1,431 @@
+// Package ekf implements a movement sensor which fuses GPS position, compass heading, wheel odometry and IMU
+// measurements with an extended Kalman filter, estimating the pose and velocities of a base along with their
+// covariance.
+package ekf
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// Model is the name used to refer to the ekf movement sensor model.
+var Model = resource.DefaultModelFamily.WithModel("ekf")
+
+// DoCovariance is the DoCommand key which returns the state of the filter and its covariance.
+const DoCovariance = "covariance"
+
+const (
+	defaultUpdateRateHz                   = 20
+	defaultPositionNoiseM                 = 1
+	defaultHeadingNoiseDeg                = 5
+	defaultVelocityNoiseMPerSec           = 0.1
+	defaultAngularVelocityNoiseDegsPerSec = 2
+	defaultAccelNoise                     = 0.5
+	defaultYawAccelNoiseDeg               = 30
+	defaultGPSTimeoutSec                  = 3
+
+	// steadyReadingInterval is how long a reading must stay the same before it is fused again. A sensor slower than
+	// the filter reports the same reading until it has a new one, which must only be fused once, but one which is truly
+	// steady, such as the odometry of a base at rest, should keep confirming its reading.
+	steadyReadingInterval = time.Second
+
+	// squared mahalanobis distances beyond which measurements are rejected as outliers, the 99.9th percentiles of
+	// the chi-squared distributions with one and two degrees of freedom.
+	gate1D = 10.83
+	gate2D = 13.82
+)
+
+// Config is used for converting config attributes.
+type Config struct {
+	// The movement sensors to fuse. GPS provides position, Compass heading, Odometry (such as a wheeled odometry
+	// sensor) linear and angular velocity, and IMU angular velocity and forward acceleration.
+	GPS      string `json:"gps,omitempty"`
+	Compass  string `json:"compass,omitempty"`
+	Odometry string `json:"odometry,omitempty"`
+	IMU      string `json:"imu,omitempty"`
+
+	// UpdateRateHz is how often the sensors are read and the estimate updated.
+	UpdateRateHz float64 `json:"update_rate_hz,omitempty"`
+
+	// The standard deviations of the noise in each measurement.
+	PositionNoiseM                 float64 `json:"position_noise_m,omitempty"`
+	HeadingNoiseDeg                float64 `json:"heading_noise_deg,omitempty"`
+	VelocityNoiseMPerSec           float64 `json:"velocity_noise_m_per_sec,omitempty"`
+	AngularVelocityNoiseDegsPerSec float64 `json:"angular_velocity_noise_degs_per_sec,omitempty"`
+
+	// AccelNoiseMPerSec2 and YawAccelNoiseDegsPerSec2 are how quickly the base's speed and turn rate are expected to
+	// change, as the standard deviations of the changes over a second. Larger values follow changes faster, smaller
+	// values smooth the estimate more.
+	AccelNoiseMPerSec2       float64 `json:"accel_noise_m_per_sec2,omitempty"`
+	YawAccelNoiseDegsPerSec2 float64 `json:"yaw_accel_noise_degs_per_sec2,omitempty"`
+
+	// GPSTimeoutSec is how long the GPS may fail to report a fix before it is considered to have dropped out, and the
+	// position is dead reckoned.
+	GPSTimeoutSec float64 `json:"gps_timeout_sec,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (conf *Config) Validate(path string) ([]string, []string, error) {
+	var deps []string
+	for _, name := range []string{conf.GPS, conf.Compass, conf.Odometry, conf.IMU} {
+		if name != "" {
+			deps = append(deps, name)
+		}
+	}
+	if conf.GPS == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "gps")
+	}
+	for _, v := range []float64{
+		conf.UpdateRateHz, conf.PositionNoiseM, conf.HeadingNoiseDeg, conf.VelocityNoiseMPerSec,
+		conf.AngularVelocityNoiseDegsPerSec, conf.AccelNoiseMPerSec2, conf.YawAccelNoiseDegsPerSec2, conf.GPSTimeoutSec,
+	} {
+		if v < 0 {
+			return nil, nil, resource.NewConfigValidationError(path, errors.New("rates and noise must be non-negative"))
+		}
+	}
+	return deps, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(movementsensor.API, Model, resource.Registration[movementsensor.MovementSensor, *Config]{
+		Constructor: newEKF,
+	})
+}
+
+type ekf struct {
+	resource.Named
+	resource.AlwaysRebuild
+
+	gps, compass, odometry, imu movementsensor.MovementSensor
+
+	positionVar        float64
+	headingVar         float64
+	velocityVar        float64
+	angularVelocityVar float64
+	gpsTimeout         time.Duration
+
+	mu     sync.Mutex
+	filter *filter
+	// origin is the first GPS fix, which the filter's position is relative to.
+	origin   *geo.Point
+	altitude float64
+	// the readings last fused from each sensor.
+	fix, heading, speed, odometryYawRate, imuYawRate fusedReading
+	// lastFixTime is when the GPS last reported a fix.
+	lastFixTime time.Time
+	dropout     bool
+	lastUpdate  time.Time
+	// accel is the latest forward acceleration measured by the IMU.
+	accel     float64
+	linearAcc r3.Vector
+
+	workers *utils.StoppableWorkers
+	logger  logging.Logger
+}
+
+func newEKF(
+	ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger,
+) (movementsensor.MovementSensor, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newFusedSensor(deps, conf.ResourceName().AsNamed(), newConf, logger)
+	if err != nil {
+		return nil, err
+	}
+	rate := newConf.UpdateRateHz
+	if rate == 0 {
+		rate = defaultUpdateRateHz
+	}
+	e.workers = utils.NewStoppableWorkerWithTicker(time.Duration(float64(time.Second)/rate), func(ctx context.Context) {
+		e.update(ctx, time.Now())
+	})
+	return e, nil
+}
+
+func newFusedSensor(deps resource.Dependencies, named resource.Named, conf *Config, logger logging.Logger) (*ekf, error) {
+	sensor := func(name string) (movementsensor.MovementSensor, error) {
+		if name == "" {
+			return nil, nil
+		}
+		return movementsensor.FromProvider(deps, name)
+	}
+	orDefault := func(v, def float64) float64 {
+		if v > 0 {
+			return v
+		}
+		return def
+	}
+
+	e := &ekf{
+		Named:              named,
+		positionVar:        math.Pow(orDefault(conf.PositionNoiseM, defaultPositionNoiseM), 2),
+		headingVar:         math.Pow(rdkutils.DegToRad(orDefault(conf.HeadingNoiseDeg, defaultHeadingNoiseDeg)), 2),
+		velocityVar:        math.Pow(orDefault(conf.VelocityNoiseMPerSec, defaultVelocityNoiseMPerSec), 2),
+		angularVelocityVar: math.Pow(rdkutils.DegToRad(orDefault(conf.AngularVelocityNoiseDegsPerSec, defaultAngularVelocityNoiseDegsPerSec)), 2),
+		gpsTimeout:         time.Duration(orDefault(conf.GPSTimeoutSec, defaultGPSTimeoutSec) * float64(time.Second)),
+		logger:             logger,
+	}
+	var err error
+	if e.gps, err = sensor(conf.GPS); err != nil {
+		return nil, err
+	}
+	if e.compass, err = sensor(conf.Compass); err != nil {
+		return nil, err
+	}
+	if e.odometry, err = sensor(conf.Odometry); err != nil {
+		return nil, err
+	}
+	if e.imu, err = sensor(conf.IMU); err != nil {
+		return nil, err
+	}
+
+	// until measured, the heading could be anything and the base is taken to be at rest
+	e.filter = newFilter(
+		orDefault(conf.AccelNoiseMPerSec2, defaultAccelNoise),
+		rdkutils.DegToRad(orDefault(conf.YawAccelNoiseDegsPerSec2, defaultYawAccelNoiseDeg)),
+		[stateDim]float64{e.positionVar, e.positionVar, math.Pi * math.Pi, e.velocityVar, e.angularVelocityVar},
+	)
+	return e, nil
+}
+
+// fusedReading is the reading last fused from a sensor.
+type fusedReading struct {
+	values  []float64
+	fusedAt time.Time
+}
+
+// changed returns whether a reading taken at now should be fused, recording it as fused if so: when it differs from
+// the last reading fused, or when it has stayed the same for steadyReadingInterval.
+func (r *fusedReading) changed(now time.Time, values ...float64) bool {
+	if !r.fusedAt.IsZero() && slices.Equal(values, r.values) && now.Sub(r.fusedAt) < steadyReadingInterval {
+		return false
+	}
+	r.values, r.fusedAt = values, now
+	return true
+}
+
+// update predicts the state at now and fuses the latest measurements of each sensor. Sensors which fail to measure
+// are skipped, leaving the filter to predict through the gap, as are readings which haven't changed since they were
+// fused, so that sensors slower than the filter are not counted more than once.
+func (e *ekf) update(ctx context.Context, now time.Time) {
+	fix, alt, fixErr := e.gps.Position(ctx, nil)
+	var heading, linear, angular, imuAngular float64
+	var headingErr, linearErr, angularErr, imuAngularErr, accelErr error
+	var accel r3.Vector
+	if e.compass != nil {
+		heading, headingErr = e.compass.CompassHeading(ctx, nil)
+	}
+	if e.odometry != nil {
+		var v r3.Vector
+		v, linearErr = e.odometry.LinearVelocity(ctx, nil)
+		linear = v.Y
+		var w spatialmath.AngularVelocity
+		w, angularErr = e.odometry.AngularVelocity(ctx, nil)
+		angular = w.Z
+	}
+	if e.imu != nil {
+		var w spatialmath.AngularVelocity
+		w, imuAngularErr = e.imu.AngularVelocity(ctx, nil)
+		imuAngular = w.Z
+		accel, accelErr = e.imu.LinearAcceleration(ctx, nil)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.lastUpdate.IsZero() {
+		e.filter.predict(now.Sub(e.lastUpdate).Seconds(), e.accel)
+	}
+	e.lastUpdate = now
+
+	if e.imu != nil {
+		if accelErr == nil {
+			e.accel, e.linearAcc = accel.Y, accel
+		}
+		if imuAngularErr == nil && e.imuYawRate.changed(now, imuAngular) {
+			e.filter.update([]int{stateYawRate}, []float64{rdkutils.DegToRad(imuAngular)}, []float64{e.angularVelocityVar}, gate1D)
+		}
+	}
+	if e.odometry != nil {
+		if linearErr == nil && e.speed.changed(now, linear) {
+			e.filter.update([]int{stateSpeed}, []float64{linear}, []float64{e.velocityVar}, gate1D)
+		}
+		if angularErr == nil && e.odometryYawRate.changed(now, angular) {
+			e.filter.update([]int{stateYawRate}, []float64{rdkutils.DegToRad(angular)}, []float64{e.angularVelocityVar}, gate1D)
+		}
+	}
+	if e.compass != nil && headingErr == nil && !math.IsNaN(heading) && e.heading.changed(now, heading) {
+		e.filter.update([]int{stateYaw}, []float64{-rdkutils.DegToRad(heading)}, []float64{e.headingVar}, gate1D)
+	}
+
+	validFix := fixErr == nil && fix != nil && !math.IsNaN(fix.Lat()) && !math.IsNaN(fix.Lng())
+	if validFix {
+		e.lastFixTime, e.altitude = now, alt
+	}
+	switch {
+	case validFix && e.origin == nil:
+		e.origin = fix
+		e.fix.changed(now, fix.Lat(), fix.Lng())
+	case validFix && e.fix.changed(now, fix.Lat(), fix.Lng()):
+		p := spatialmath.GeoPointToPoint(fix, e.origin).Mul(1e-3)
+		if !e.filter.update([]int{stateEast, stateNorth}, []float64{p.X, p.Y},
+			[]float64{e.positionVar, e.positionVar}, gate2D) {
+			e.logger.CDebugw(ctx, "rejected outlying GPS fix", "fix", fix)
+		}
+	}
+	// a GPS which keeps reporting the same fix is still working, as the base may be at rest
+	dropout := e.origin != nil && now.Sub(e.lastFixTime) > e.gpsTimeout
+	if dropout != e.dropout {
+		if dropout {
+			e.logger.CWarnw(ctx, "no GPS fix, dead reckoning", "since", e.lastFixTime, "error", fixErr)
+		} else {
+			e.logger.CInfo(ctx, "GPS fix regained")
+		}
+		e.dropout = dropout
+	}
+}
+
+// headingDeg returns the estimated compass heading. e.mu must be held.
+func (e *ekf) headingDeg() float64 {
+	heading := math.Mod(-rdkutils.RadToDeg(e.filter.state(stateYaw)), 360)
+	if heading < 0 {
+		heading += 360
+	}
+	return heading
+}
+
+// Position returns the estimated position, dead reckoned while the GPS has dropped out.
+func (e *ekf) Position(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.origin == nil {
+		return geo.NewPoint(math.NaN(), math.NaN()), math.NaN(), errors.New("waiting for a first GPS fix")
+	}
+	p := r3.Vector{X: e.filter.state(stateEast), Y: e.filter.state(stateNorth)}.Mul(1e3)
+	return spatialmath.PointToGeoPoint(p, e.origin), e.altitude, nil
+}
+
+// CompassHeading returns the estimated heading, clockwise from north.
+func (e *ekf) CompassHeading(ctx context.Context, extra map[string]interface{}) (float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.headingDeg(), nil
+}
+
+// Orientation returns the estimated heading as a rotation about Z.
+func (e *ekf) Orientation(ctx context.Context, extra map[string]interface{}) (spatialmath.Orientation, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return &spatialmath.OrientationVectorDegrees{OZ: 1, Theta: math.Mod(360-e.headingDeg(), 360)}, nil
+}
+
+// LinearVelocity returns the estimated forward speed in meters per second, along Y.
+func (e *ekf) LinearVelocity(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return r3.Vector{Y: e.filter.state(stateSpeed)}, nil
+}
+
+// AngularVelocity returns the estimated counterclockwise turn rate in degrees per second, about Z.
+func (e *ekf) AngularVelocity(ctx context.Context, extra map[string]interface{}) (spatialmath.AngularVelocity, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return spatialmath.AngularVelocity{Z: rdkutils.RadToDeg(e.filter.state(stateYawRate))}, nil
+}
+
+// LinearAcceleration returns the latest acceleration measured by the IMU.
+func (e *ekf) LinearAcceleration(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	if e.imu == nil {
+		return r3.Vector{}, movementsensor.ErrMethodUnimplementedLinearAcceleration
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.linearAcc, nil
+}
+
+// Accuracy returns the standard deviations of the estimate, and whether the GPS has dropped out.
+func (e *ekf) Accuracy(ctx context.Context, extra map[string]interface{}) (*movementsensor.Accuracy, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	dropout := float32(0)
+	if e.dropout {
+		dropout = 1
+	}
+	headingStd := rdkutils.RadToDeg(e.filter.stddev(stateYaw))
+	return &movementsensor.Accuracy{
+		AccuracyMap: map[string]float32{
+			"east_std_m":                float32(e.filter.stddev(stateEast)),
+			"north_std_m":               float32(e.filter.stddev(stateNorth)),
+			"heading_std_deg":           float32(headingStd),
+			"speed_std_m_per_sec":       float32(e.filter.stddev(stateSpeed)),
+			"yaw_rate_std_degs_per_sec": float32(rdkutils.RadToDeg(e.filter.stddev(stateYawRate))),
+			"gps_dropout":               dropout,
+		},
+		Hdop:               float32(math.NaN()),
+		Vdop:               float32(math.NaN()),
+		NmeaFix:            -1,
+		CompassDegreeError: float32(headingStd),
+	}, nil
+}
+
+// Properties returns what the fused sensors allow to be estimated.
+func (e *ekf) Properties(ctx context.Context, extra map[string]interface{}) (*movementsensor.Properties, error) {
+	return &movementsensor.Properties{
+		PositionSupported:           true,
+		OrientationSupported:        true,
+		CompassHeadingSupported:     true,
+		LinearVelocitySupported:     true,
+		AngularVelocitySupported:    true,
+		LinearAccelerationSupported: e.imu != nil,
+	}, nil
+}
+
+// Readings returns the estimates.
+func (e *ekf) Readings(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+	return movementsensor.DefaultAPIReadings(ctx, e, extra)
+}
+
+// DoCommand returns the state of the filter, as east and north of the first GPS fix (m), counterclockwise heading
+// from north (rad), speed (m/s) and turn rate (rad/s), along with its covariance.
+func (e *ekf) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := cmd[DoCovariance]; !ok {
+		return nil, resource.ErrDoUnimplemented
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := make([]interface{}, 0, stateDim)
+	covariance := make([]interface{}, 0, stateDim)
+	for i := range stateDim {
+		state = append(state, e.filter.state(i))
+		row := make([]interface{}, 0, stateDim)
+		for j := range stateDim {
+			row = append(row, e.filter.p.At(i, j))
+		}
+		covariance = append(covariance, row)
+	}
+	return map[string]interface{}{"state": state, "covariance": covariance, "gps_dropout": e.dropout}, nil
+}
+
+// Close stops updating the estimate. The fused sensors are left for their own drivers to close.
+func (e *ekf) Close(ctx context.Context) error {
+	if e.workers != nil {
+		e.workers.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package ekf
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+var (
+	testOrigin = geo.NewPoint(40, -74)
+	errNoFix   = errors.New("no fix")
+)
+
+// world is a base driving at a constant speed and turn rate, measured by injected sensors.
+type world struct {
+	now      time.Time
+	start    time.Time
+	speed    float64 // m/s
+	yawRate  float64 // deg/s, counterclockwise
+	headingD float64 // starting compass heading
+
+	// gpsPeriod is how often the GPS has a new fix, which it reports until the next one.
+	gpsPeriod time.Duration
+	gpsDown   bool
+	gpsOffset r3.Vector // added to the fix, in meters
+}
+
+// truth returns the position in meters east and north of the origin and the compass heading at the given time.
+func (w *world) truth(t time.Time) (r3.Vector, float64) {
+	var pos r3.Vector
+	heading := w.headingD
+	const step = time.Millisecond
+	for at := w.start; at.Before(t); at = at.Add(step) {
+		rad := heading * math.Pi / 180
+		pos = pos.Add(r3.Vector{X: math.Sin(rad), Y: math.Cos(rad)}.Mul(w.speed * step.Seconds()))
+		heading -= w.yawRate * step.Seconds()
+	}
+	return pos, math.Mod(heading+360, 360)
+}
+
+func (w *world) sensors() resource.Dependencies {
+	gps := inject.NewMovementSensor("gps")
+	gps.PositionFunc = func(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+		if w.gpsDown {
+			return nil, 0, errNoFix
+		}
+		fixTime := w.start.Add(w.now.Sub(w.start).Truncate(w.gpsPeriod))
+		pos, _ := w.truth(fixTime)
+		return spatialmath.PointToGeoPoint(pos.Add(w.gpsOffset).Mul(1e3), testOrigin), 10, nil
+	}
+	compass := inject.NewMovementSensor("compass")
+	compass.CompassHeadingFunc = func(ctx context.Context, extra map[string]interface{}) (float64, error) {
+		_, heading := w.truth(w.now)
+		return heading, nil
+	}
+	odometry := inject.NewMovementSensor("odometry")
+	odometry.LinearVelocityFunc = func(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+		return r3.Vector{Y: w.speed}, nil
+	}
+	odometry.AngularVelocityFunc = func(ctx context.Context, extra map[string]interface{}) (spatialmath.AngularVelocity, error) {
+		return spatialmath.AngularVelocity{Z: w.yawRate}, nil
+	}
+	imu := inject.NewMovementSensor("imu")
+	imu.AngularVelocityFunc = odometry.AngularVelocityFunc
+	imu.LinearAccelerationFunc = func(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+		return r3.Vector{Z: 9.8}, nil
+	}
+	return resource.Dependencies{
+		movementsensor.Named("gps"):      gps,
+		movementsensor.Named("compass"):  compass,
+		movementsensor.Named("odometry"): odometry,
+		movementsensor.Named("imu"):      imu,
+	}
+}
+
+func newTestEKF(t *testing.T, w *world, conf *Config) *ekf {
+	t.Helper()
+	_, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	e, err := newFusedSensor(w.sensors(), movementsensor.Named("ekf").AsNamed(), conf, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	return e
+}
+
+// run updates the filter at the given rate for the duration.
+func run(e *ekf, w *world, rate time.Duration, d time.Duration) {
+	for end := w.now.Add(d); w.now.Before(end); {
+		w.now = w.now.Add(rate)
+		e.update(context.Background(), w.now)
+	}
+}
+
+func localPosition(t *testing.T, e *ekf) r3.Vector {
+	t.Helper()
+	p, _, err := e.Position(context.Background(), nil)
+	test.That(t, err, test.ShouldBeNil)
+	return spatialmath.GeoPointToPoint(p, testOrigin).Mul(1e-3)
+}
+
+func allSensors() *Config {
+	return &Config{GPS: "gps", Compass: "compass", Odometry: "odometry", IMU: "imu"}
+}
+
+func TestFusion(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+	w := &world{now: start, start: start, speed: 1, yawRate: 10, headingD: 30, gpsPeriod: time.Second}
+	e := newTestEKF(t, w, allSensors())
+
+	_, _, err := e.Position(ctx, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	// the GPS updates once a second while the filter runs at 20Hz
+	e.update(ctx, w.now)
+	run(e, w, 50*time.Millisecond, 20*time.Second)
+
+	pos, heading := w.truth(w.now)
+	test.That(t, localPosition(t, e).Sub(pos).Norm(), test.ShouldBeLessThan, 0.5)
+	h, err := e.CompassHeading(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, h, test.ShouldAlmostEqual, heading, 1)
+	o, err := e.Orientation(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, o.OrientationVectorDegrees().Theta, test.ShouldAlmostEqual, 360-heading, 1)
+	v, err := e.LinearVelocity(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, v.Y, test.ShouldAlmostEqual, 1, 0.05)
+	av, err := e.AngularVelocity(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, av.Z, test.ShouldAlmostEqual, 10, 0.5)
+	a, err := e.LinearAcceleration(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, a.Z, test.ShouldEqual, 9.8)
+
+	acc, err := e.Accuracy(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, acc.AccuracyMap["east_std_m"], test.ShouldBeLessThan, 1)
+	test.That(t, acc.AccuracyMap["gps_dropout"], test.ShouldEqual, 0)
+	test.That(t, acc.CompassDegreeError, test.ShouldBeLessThan, 5)
+
+	resp, err := e.DoCommand(ctx, map[string]interface{}{DoCovariance: true})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp["state"], test.ShouldHaveLength, stateDim)
+	covariance := resp["covariance"].([]interface{})
+	test.That(t, covariance, test.ShouldHaveLength, stateDim)
+	test.That(t, covariance[stateEast].([]interface{})[stateEast], test.ShouldAlmostEqual,
+		math.Pow(float64(acc.AccuracyMap["east_std_m"]), 2), 1e-4)
+
+	readings, err := e.Readings(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readings, test.ShouldContainKey, "position")
+	test.That(t, readings, test.ShouldContainKey, "linear_acceleration")
+}
+
+func TestGPSDropout(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+	w := &world{now: start, start: start, speed: 1, headingD: 90, gpsPeriod: 200 * time.Millisecond}
+	e := newTestEKF(t, w, &Config{GPS: "gps", Compass: "compass", Odometry: "odometry"})
+	e.update(ctx, w.now)
+	run(e, w, 50*time.Millisecond, 10*time.Second)
+	acc, err := e.Accuracy(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	stdBefore := acc.AccuracyMap["east_std_m"]
+
+	// without a GPS fix the position is dead reckoned from the odometry and compass, becoming less certain
+	w.gpsDown = true
+	run(e, w, 50*time.Millisecond, 10*time.Second)
+	pos, _ := w.truth(w.now)
+	test.That(t, localPosition(t, e).Sub(pos).Norm(), test.ShouldBeLessThan, 0.5)
+	acc, err = e.Accuracy(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, acc.AccuracyMap["gps_dropout"], test.ShouldEqual, 1)
+	test.That(t, acc.AccuracyMap["east_std_m"], test.ShouldBeGreaterThan, stdBefore)
+
+	w.gpsDown = false
+	run(e, w, 50*time.Millisecond, time.Second)
+	acc, err = e.Accuracy(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, acc.AccuracyMap["gps_dropout"], test.ShouldEqual, 0)
+}
+
+func TestOutlierRejected(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+	w := &world{now: start, start: start, speed: 1, gpsPeriod: 200 * time.Millisecond}
+	e := newTestEKF(t, w, &Config{GPS: "gps", Compass: "compass", Odometry: "odometry"})
+	e.update(ctx, w.now)
+	run(e, w, 50*time.Millisecond, 5*time.Second)
+
+	// a fix 50m off is ignored
+	w.gpsOffset = r3.Vector{X: 50}
+	run(e, w, 50*time.Millisecond, 200*time.Millisecond)
+	pos, _ := w.truth(w.now)
+	test.That(t, localPosition(t, e).Sub(pos).Norm(), test.ShouldBeLessThan, 0.5)
+}
+
+func TestValidate(t *testing.T) {
+	_, _, err := (&Config{Compass: "compass"}).Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	_, _, err = (&Config{GPS: "gps", PositionNoiseM: -1}).Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	deps, _, err := (&Config{GPS: "gps", IMU: "imu"}).Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"gps", "imu"})
+
+	w := &world{gpsPeriod: time.Second}
+	ctx := context.Background()
+	ms, err := newEKF(ctx, w.sensors(), resource.Config{
+		Name: "ekf", API: movementsensor.API, Model: Model, ConvertedAttributes: &Config{GPS: "gps"},
+	}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	props, err := ms.Properties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.LinearAccelerationSupported, test.ShouldBeFalse)
+	_, err = ms.LinearAcceleration(ctx, nil)
+	test.That(t, err, test.ShouldBeError, movementsensor.ErrMethodUnimplementedLinearAcceleration)
+	test.That(t, ms.Close(ctx), test.ShouldBeNil)
+}
+
+func TestSteadyReadings(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+	// a base at rest, whose sensors keep reporting the same readings
+	w := &world{now: start, start: start, headingD: 90, gpsPeriod: time.Hour}
+	e := newTestEKF(t, w, allSensors())
+	e.update(ctx, w.now)
+	run(e, w, 50*time.Millisecond, 10*time.Second)
+
+	acc, err := e.Accuracy(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	// the same fix is still a fix
+	test.That(t, acc.AccuracyMap["gps_dropout"], test.ShouldEqual, 0)
+	// readings are fused once a second, not once per update, and so don't make the filter overconfident
+	test.That(t, acc.AccuracyMap["east_std_m"], test.ShouldBeGreaterThan, 0.2)
+	test.That(t, acc.AccuracyMap["heading_std_deg"], test.ShouldBeGreaterThan, 1)
+}
@@ -0,0 +1,169 @@
+package ekf
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+
+	"go.viam.com/rdk/utils"
+)
+
+// indices into the state of the filter.
+const (
+	// east and north of the origin, in meters.
+	stateEast = iota
+	stateNorth
+	// counterclockwise heading from north, in radians.
+	stateYaw
+	// forward speed, in meters per second.
+	stateSpeed
+	// counterclockwise turn rate, in radians per second.
+	stateYawRate
+	stateDim
+)
+
+// filter is an extended Kalman filter estimating the planar pose and velocities of a base driving forward and turning,
+// as wheeled bases do. The motion model holds the speed and turn rate constant between measurements, changing them by
+// white noise, and the measurements each observe a part of the state directly, so only predicting is nonlinear.
+type filter struct {
+	x *mat.VecDense
+	p *mat.Dense
+	// standard deviations of the changes in speed (m/s) and turn rate (rad/s) over a second, from white noise
+	// acceleration, so the uncertainty grows the same however often the filter predicts.
+	accelNoise    float64
+	yawAccelNoise float64
+}
+
+// newFilter returns a filter at rest at the origin whose heading, and any other part of the state with a given
+// variance, is unknown until measured.
+func newFilter(accelNoise, yawAccelNoise float64, variances [stateDim]float64) *filter {
+	p := mat.NewDense(stateDim, stateDim, nil)
+	for i, v := range variances {
+		p.Set(i, i, v)
+	}
+	return &filter{
+		x:             mat.NewVecDense(stateDim, nil),
+		p:             p,
+		accelNoise:    accelNoise,
+		yawAccelNoise: yawAccelNoise,
+	}
+}
+
+// predict advances the state by dt seconds, during which the base accelerated forward by accel m/s².
+func (f *filter) predict(dt, accel float64) {
+	if dt <= 0 {
+		return
+	}
+	yaw, speed, yawRate := f.x.AtVec(stateYaw), f.x.AtVec(stateSpeed), f.x.AtVec(stateYawRate)
+	sin, cos := math.Sincos(yaw)
+
+	f.x.SetVec(stateEast, f.x.AtVec(stateEast)-speed*sin*dt)
+	f.x.SetVec(stateNorth, f.x.AtVec(stateNorth)+speed*cos*dt)
+	f.x.SetVec(stateYaw, utils.DegToRad(180-utils.ModAngDeg(180-utils.RadToDeg(yaw+yawRate*dt))))
+	f.x.SetVec(stateSpeed, speed+accel*dt)
+
+	// jacobian of the motion model
+	jac := identity()
+	jac.Set(stateEast, stateYaw, -speed*cos*dt)
+	jac.Set(stateEast, stateSpeed, -sin*dt)
+	jac.Set(stateNorth, stateYaw, -speed*sin*dt)
+	jac.Set(stateNorth, stateSpeed, cos*dt)
+	jac.Set(stateYaw, stateYawRate, dt)
+
+	// the process noise is white acceleration, forward and in turn rate, integrated over the step
+	noiseGain := mat.NewDense(stateDim, 2, nil)
+	noiseGain.Set(stateEast, 0, -sin*dt*dt/2)
+	noiseGain.Set(stateNorth, 0, cos*dt*dt/2)
+	noiseGain.Set(stateSpeed, 0, dt)
+	noiseGain.Set(stateYaw, 1, dt*dt/2)
+	noiseGain.Set(stateYawRate, 1, dt)
+	noise := mat.NewDiagDense(2, []float64{f.accelNoise * f.accelNoise / dt, f.yawAccelNoise * f.yawAccelNoise / dt})
+
+	var q, gq mat.Dense
+	gq.Mul(noiseGain, noise)
+	q.Mul(&gq, noiseGain.T())
+
+	var fp mat.Dense
+	fp.Mul(jac, f.p)
+	f.p.Mul(&fp, jac.T())
+	f.p.Add(f.p, &q)
+}
+
+// update fuses a measurement z of the given parts of the state, with the given variances, unless it is further than
+// gate (in squared standard deviations of the innovation) from the prediction, which rejects outliers. It returns
+// whether the measurement was fused.
+func (f *filter) update(indices []int, z, variances []float64, gate float64) bool {
+	n := len(indices)
+	h := mat.NewDense(n, stateDim, nil)
+	innovation := mat.NewVecDense(n, nil)
+	for row, i := range indices {
+		h.Set(row, i, 1)
+		y := z[row] - f.x.AtVec(i)
+		if i == stateYaw {
+			// the shortest way around, in (-π, π]
+			y = utils.DegToRad(180 - utils.ModAngDeg(180-utils.RadToDeg(y)))
+		}
+		innovation.SetVec(row, y)
+	}
+	r := mat.NewDiagDense(n, variances)
+
+	// S = HPHᵀ + R
+	var hp, s mat.Dense
+	hp.Mul(h, f.p)
+	s.Mul(&hp, h.T())
+	s.Add(&s, r)
+	var sInv mat.Dense
+	if err := sInv.Inverse(&s); err != nil {
+		return false
+	}
+	if gate > 0 && mat.Inner(innovation, &sInv, innovation) > gate {
+		return false
+	}
+
+	// K = PHᵀS⁻¹
+	var pht, k mat.Dense
+	pht.Mul(f.p, h.T())
+	k.Mul(&pht, &sInv)
+
+	var dx mat.VecDense
+	dx.MulVec(&k, innovation)
+	f.x.AddVec(f.x, &dx)
+	f.x.SetVec(stateYaw, utils.DegToRad(180-utils.ModAngDeg(180-utils.RadToDeg(f.x.AtVec(stateYaw)))))
+
+	// the Joseph form, P = (I-KH)P(I-KH)ᵀ + KRKᵀ, which keeps P symmetric and positive definite
+	var kh, ikh, ikhp, krk, kr mat.Dense
+	kh.Mul(&k, h)
+	ikh.Sub(identity(), &kh)
+	ikhp.Mul(&ikh, f.p)
+	f.p.Mul(&ikhp, ikh.T())
+	kr.Mul(&k, r)
+	krk.Mul(&kr, k.T())
+	f.p.Add(f.p, &krk)
+	return true
+}
+
+// setState sets part of the state, with the given variance and uncorrelated with the rest of the state.
+func (f *filter) setState(i int, v, variance float64) {
+	f.x.SetVec(i, v)
+	for j := range stateDim {
+		f.p.Set(i, j, 0)
+		f.p.Set(j, i, 0)
+	}
+	f.p.Set(i, i, variance)
+}
+
+func (f *filter) state(i int) float64 {
+	return f.x.AtVec(i)
+}
+
+func (f *filter) stddev(i int) float64 {
+	return math.Sqrt(f.p.At(i, i))
+}
+
+func identity() *mat.Dense {
+	m := mat.NewDense(stateDim, stateDim, nil)
+	for i := range stateDim {
+		m.Set(i, i, 1)
+	}
+	return m
+}
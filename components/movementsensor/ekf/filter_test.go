@@ -0,0 +1,64 @@
+package ekf
+
+import (
+	"math"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestFilter(t *testing.T) {
+	f := newFilter(0.5, 0.1, [stateDim]float64{1, 1, 0.01, 0.01, 0.01})
+	f.setState(stateSpeed, 2, 0.01)
+	f.setState(stateYaw, math.Pi/2, 0.25)
+
+	// driving west at 2m/s for a second, in ten steps, grows the uncertainty the same as in one
+	coarse := newFilter(0.5, 0.1, [stateDim]float64{1, 1, 0.01, 0.01, 0.01})
+	coarse.setState(stateSpeed, 2, 0.01)
+	coarse.setState(stateYaw, math.Pi/2, 0.25)
+	for range 10 {
+		f.predict(0.1, 0)
+	}
+	coarse.predict(1, 0)
+	test.That(t, f.state(stateEast), test.ShouldAlmostEqual, -2)
+	test.That(t, f.state(stateNorth), test.ShouldAlmostEqual, 0)
+	test.That(t, f.stddev(stateSpeed), test.ShouldAlmostEqual, coarse.stddev(stateSpeed))
+	test.That(t, f.stddev(stateEast), test.ShouldBeGreaterThan, 1)
+
+	// the heading uncertainty spreads the position across the direction of travel
+	test.That(t, f.stddev(stateNorth), test.ShouldBeGreaterThan, f.stddev(stateEast))
+
+	// a measurement of the position shrinks its uncertainty and moves the estimate toward it
+	before := f.stddev(stateEast)
+	test.That(t, f.update([]int{stateEast, stateNorth}, []float64{-2.5, 0}, []float64{0.25, 0.25}, gate2D), test.ShouldBeTrue)
+	test.That(t, f.stddev(stateEast), test.ShouldBeLessThan, before)
+	test.That(t, f.state(stateEast), test.ShouldBeBetween, -2.5, -2)
+
+	// one far outside of the uncertainty is rejected
+	test.That(t, f.update([]int{stateEast, stateNorth}, []float64{100, 0}, []float64{0.25, 0.25}, gate2D), test.ShouldBeFalse)
+	test.That(t, f.state(stateEast), test.ShouldBeBetween, -2.5, -2)
+
+	// headings are compared the short way around
+	f.setState(stateYaw, math.Pi-0.05, 0.01)
+	test.That(t, f.update([]int{stateYaw}, []float64{-math.Pi + 0.05}, []float64{0.01}, gate1D), test.ShouldBeTrue)
+	test.That(t, math.Abs(f.state(stateYaw)), test.ShouldAlmostEqual, math.Pi, 1e-9)
+
+	for i := range stateDim {
+		for j := range stateDim {
+			test.That(t, f.p.At(i, j), test.ShouldAlmostEqual, f.p.At(j, i))
+		}
+	}
+}
+
+func TestFilterHeadingWraps(t *testing.T) {
+	f := newFilter(0.5, 0.1, [stateDim]float64{1, 1, 0.01, 0.01, 0.01})
+	f.setState(stateYaw, math.Pi-0.05, 0.01)
+	f.setState(stateYawRate, 0.1, 0.01)
+	f.predict(1, 0)
+	test.That(t, f.state(stateYaw), test.ShouldAlmostEqual, -math.Pi+0.05)
+
+	f.setState(stateYaw, -math.Pi, 0.01)
+	f.setState(stateYawRate, 0, 0.01)
+	f.predict(1, 0)
+	test.That(t, f.state(stateYaw), test.ShouldAlmostEqual, math.Pi)
+}
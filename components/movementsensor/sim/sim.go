@@ -0,0 +1,404 @@
+// Package sim implements movement sensors which measure the ground truth of a simulated base, emulating a GPS, an IMU
+// or wheel encoders with configurable noise and latency.
+package sim
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/components/base"
+	basesim "go.viam.com/rdk/components/base/sim"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// Model is the name used to refer to the simulated movement sensor model.
+var Model = resource.DefaultModelFamily.WithModel("simulated")
+
+// Kinds of sensor that can be simulated.
+const (
+	// KindGPS measures position, heading and velocity.
+	KindGPS = "gps"
+	// KindIMU measures orientation, heading, angular velocity and linear acceleration.
+	KindIMU = "imu"
+	// KindWheelEncoders measures position, orientation and velocities by odometry from the base's wheel encoders,
+	// and so drifts as the wheels slip.
+	KindWheelEncoders = "wheel_encoders"
+)
+
+// how often wheel encoder odometry is integrated when simulating time.
+const odometryTimeStep = 20 * time.Millisecond
+
+// Config is used for converting config attributes.
+type Config struct {
+	// Base is the name of the simulated base to measure.
+	Base string `json:"base"`
+	// Kind is gps, imu or wheel_encoders.
+	Kind string `json:"kind"`
+
+	// LatencyMS is how old the measurements are.
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+
+	// The standard deviations of noise added to each measurement.
+	PositionNoiseM                 float64 `json:"position_noise_m,omitempty"`
+	HeadingNoiseDeg                float64 `json:"heading_noise_deg,omitempty"`
+	VelocityNoiseMPerSec           float64 `json:"velocity_noise_m_per_sec,omitempty"`
+	AngularVelocityNoiseDegsPerSec float64 `json:"angular_velocity_noise_degs_per_sec,omitempty"`
+	AccelerationNoiseMPerSec2      float64 `json:"acceleration_noise_m_per_sec2,omitempty"`
+	// GyroBiasDegsPerSec is a constant error in the angular velocity measured by an IMU.
+	GyroBiasDegsPerSec float64 `json:"gyro_bias_degs_per_sec,omitempty"`
+
+	// WheelScaleError is the fraction by which the left wheel encoders overestimate, and the right wheel encoders
+	// underestimate, the distance driven, as from uneven tire wear.
+	WheelScaleError float64 `json:"wheel_scale_error,omitempty"`
+	// TicksPerRotation quantizes the distance measured by the wheel encoders. Zero measures it exactly.
+	TicksPerRotation int `json:"ticks_per_rotation,omitempty"`
+
+	// Seed seeds the noise, so that runs are repeatable.
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (conf *Config) Validate(path string) ([]string, []string, error) {
+	if conf.Base == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "base")
+	}
+	switch conf.Kind {
+	case KindGPS, KindIMU, KindWheelEncoders:
+	case "":
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "kind")
+	default:
+		return nil, nil, resource.NewConfigValidationError(path,
+			errors.Errorf("kind must be %q, %q or %q", KindGPS, KindIMU, KindWheelEncoders))
+	}
+	for _, v := range []float64{
+		conf.LatencyMS, conf.PositionNoiseM, conf.HeadingNoiseDeg, conf.VelocityNoiseMPerSec,
+		conf.AngularVelocityNoiseDegsPerSec, conf.AccelerationNoiseMPerSec2,
+	} {
+		if v < 0 {
+			return nil, nil, resource.NewConfigValidationError(path, errors.New("latency and noise must be non-negative"))
+		}
+	}
+	if math.Abs(conf.WheelScaleError) >= 1 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("wheel_scale_error must be in (-1, 1)"))
+	}
+	if conf.TicksPerRotation < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("ticks_per_rotation must be non-negative"))
+	}
+	return []string{conf.Base}, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(movementsensor.API, Model, resource.Registration[movementsensor.MovementSensor, *Config]{
+		Constructor: NewMovementSensor,
+	})
+}
+
+// odometry is the pose and velocities estimated from the wheel encoders.
+type odometry struct {
+	// time of the ground truth last integrated.
+	time time.Time
+	// distances measured by the encoders at that time.
+	leftMM, rightMM float64
+	// position relative to where the base started, and counterclockwise heading from north in radians.
+	eastMM, northMM, yaw float64
+	linear, angular      float64
+}
+
+type simulatedSensor struct {
+	resource.Named
+	resource.AlwaysRebuild
+
+	base    basesim.Simulated
+	conf    *Config
+	latency time.Duration
+	// tickMM is the distance driven per encoder tick, or zero if the encoders are not quantized.
+	tickMM float64
+	// now is the clock measurements are taken by, which tests replace to pass time deterministically.
+	now func() time.Time
+
+	mu   sync.Mutex
+	rand *rand.Rand
+	// origin is where the base was when the sensor started, which wheel encoder odometry is relative to.
+	origin *geo.Point
+	odom   odometry
+
+	// odometrySimulation integrates wheel encoder odometry every few milliseconds.
+	odometrySimulation *utils.StoppableWorkers
+
+	logger logging.Logger
+}
+
+// NewMovementSensor is the constructor registered for the simulated movement sensor model.
+func NewMovementSensor(
+	ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger,
+) (movementsensor.MovementSensor, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base.FromProvider(deps, newConf.Base)
+	if err != nil {
+		return nil, err
+	}
+	simBase, ok := b.(basesim.Simulated)
+	if !ok {
+		return nil, errors.Errorf("base %q is not a simulated base", newConf.Base)
+	}
+	return newSensor(ctx, conf.ResourceName().AsNamed(), newConf, simBase, true, logger)
+}
+
+func newSensor(
+	ctx context.Context,
+	named resource.Named,
+	conf *Config,
+	simBase basesim.Simulated,
+	simulateTime bool,
+	logger logging.Logger,
+) (*simulatedSensor, error) {
+	s := &simulatedSensor{
+		Named:   named,
+		base:    simBase,
+		conf:    conf,
+		latency: time.Duration(conf.LatencyMS * float64(time.Millisecond)),
+		now:     time.Now,
+		//nolint:gosec
+		rand:   rand.New(rand.NewSource(conf.Seed)),
+		logger: logger,
+	}
+	if conf.TicksPerRotation > 0 {
+		props, err := simBase.Properties(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		s.tickMM = props.WheelCircumferenceMeters * 1000 / float64(conf.TicksPerRotation)
+	}
+
+	gt := simBase.GroundTruthAt(s.now())
+	s.origin = gt.Position
+	s.odom = odometry{
+		time:    gt.Time,
+		leftMM:  s.encoderMM(gt.LeftWheelMM, 1+conf.WheelScaleError),
+		rightMM: s.encoderMM(gt.RightWheelMM, 1-conf.WheelScaleError),
+		yaw:     -rdkutils.DegToRad(gt.HeadingDeg),
+	}
+	if conf.Kind == KindWheelEncoders && simulateTime {
+		s.odometrySimulation = utils.NewStoppableWorkerWithTicker(odometryTimeStep, func(_ context.Context) {
+			s.updateOdometry()
+		})
+	}
+	return s, nil
+}
+
+// encoderMM returns the distance the encoders measure the wheels to have driven.
+func (s *simulatedSensor) encoderMM(wheelMM, scale float64) float64 {
+	mm := wheelMM * scale
+	if s.tickMM > 0 {
+		mm = math.Trunc(mm/s.tickMM) * s.tickMM
+	}
+	return mm
+}
+
+// updateOdometry integrates the distances the wheel encoders measured since it was last called.
+func (s *simulatedSensor) updateOdometry() {
+	gt := s.groundTruth()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dt := gt.Time.Sub(s.odom.time).Seconds()
+	if dt <= 0 {
+		return
+	}
+	left := s.encoderMM(gt.LeftWheelMM, 1+s.conf.WheelScaleError)
+	right := s.encoderMM(gt.RightWheelMM, 1-s.conf.WheelScaleError)
+	dLeft, dRight := left-s.odom.leftMM, right-s.odom.rightMM
+	dist := (dLeft + dRight) / 2
+	dYaw := (dRight - dLeft) / s.base.WidthMM()
+
+	mid := s.odom.yaw + dYaw/2
+	s.odom.eastMM -= dist * math.Sin(mid)
+	s.odom.northMM += dist * math.Cos(mid)
+	s.odom.yaw += dYaw
+	s.odom.linear = dist / dt
+	s.odom.angular = rdkutils.RadToDeg(dYaw / dt)
+	s.odom.time, s.odom.leftMM, s.odom.rightMM = gt.Time, left, right
+}
+
+// groundTruth returns the ground truth of the base as it was when the latest measurement was taken.
+func (s *simulatedSensor) groundTruth() basesim.GroundTruth {
+	return s.base.GroundTruthAt(s.now().Add(-s.latency))
+}
+
+// noise returns a sample of zero mean noise with the given standard deviation.
+func (s *simulatedSensor) noise(stddev float64) float64 {
+	if stddev == 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.NormFloat64() * stddev
+}
+
+func (s *simulatedSensor) headingDeg() float64 {
+	var heading float64
+	if s.conf.Kind == KindWheelEncoders {
+		s.mu.Lock()
+		heading = -rdkutils.RadToDeg(s.odom.yaw)
+		s.mu.Unlock()
+	} else {
+		heading = s.groundTruth().HeadingDeg + s.noise(s.conf.HeadingNoiseDeg)
+	}
+	heading = math.Mod(heading, 360)
+	if heading < 0 {
+		heading += 360
+	}
+	return heading
+}
+
+// Position returns the measured position, at an altitude of zero.
+func (s *simulatedSensor) Position(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+	switch s.conf.Kind {
+	case KindGPS:
+		p := s.groundTruth().Position
+		offset := r3.Vector{X: s.noise(s.conf.PositionNoiseM), Y: s.noise(s.conf.PositionNoiseM)}.Mul(1000)
+		if offset.Norm() == 0 {
+			return p, 0, nil
+		}
+		return spatialmath.PointToGeoPoint(offset, p), 0, nil
+	case KindWheelEncoders:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return spatialmath.PointToGeoPoint(r3.Vector{X: s.odom.eastMM, Y: s.odom.northMM}, s.origin), 0, nil
+	default:
+		return nil, 0, movementsensor.ErrMethodUnimplementedPosition
+	}
+}
+
+// LinearVelocity returns the measured velocity in meters per second, with Y forward.
+func (s *simulatedSensor) LinearVelocity(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	switch s.conf.Kind {
+	case KindGPS:
+		v := s.groundTruth().LinearVelocityMMPerSec / 1000
+		return r3.Vector{Y: v + s.noise(s.conf.VelocityNoiseMPerSec)}, nil
+	case KindWheelEncoders:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return r3.Vector{Y: s.odom.linear / 1000}, nil
+	default:
+		return r3.Vector{}, movementsensor.ErrMethodUnimplementedLinearVelocity
+	}
+}
+
+// AngularVelocity returns the measured angular velocity in degrees per second.
+func (s *simulatedSensor) AngularVelocity(ctx context.Context, extra map[string]interface{}) (spatialmath.AngularVelocity, error) {
+	switch s.conf.Kind {
+	case KindIMU:
+		w := s.groundTruth().AngularVelocityDegsPerSec
+		return spatialmath.AngularVelocity{Z: w + s.conf.GyroBiasDegsPerSec + s.noise(s.conf.AngularVelocityNoiseDegsPerSec)}, nil
+	case KindWheelEncoders:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return spatialmath.AngularVelocity{Z: s.odom.angular}, nil
+	default:
+		return spatialmath.AngularVelocity{}, movementsensor.ErrMethodUnimplementedAngularVelocity
+	}
+}
+
+// LinearAcceleration returns the measured acceleration in meters per second per second, with Y forward.
+func (s *simulatedSensor) LinearAcceleration(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	if s.conf.Kind != KindIMU {
+		return r3.Vector{}, movementsensor.ErrMethodUnimplementedLinearAcceleration
+	}
+	a := s.groundTruth().LinearAcceleration
+	return r3.Vector{
+		X: a.X + s.noise(s.conf.AccelerationNoiseMPerSec2),
+		Y: a.Y + s.noise(s.conf.AccelerationNoiseMPerSec2),
+		Z: a.Z + s.noise(s.conf.AccelerationNoiseMPerSec2),
+	}, nil
+}
+
+// CompassHeading returns the measured heading, clockwise from north.
+func (s *simulatedSensor) CompassHeading(ctx context.Context, extra map[string]interface{}) (float64, error) {
+	if s.conf.Kind == KindWheelEncoders {
+		return 0, movementsensor.ErrMethodUnimplementedCompassHeading
+	}
+	return s.headingDeg(), nil
+}
+
+// Orientation returns the measured orientation, a rotation about Z.
+func (s *simulatedSensor) Orientation(ctx context.Context, extra map[string]interface{}) (spatialmath.Orientation, error) {
+	if s.conf.Kind == KindGPS {
+		return nil, movementsensor.ErrMethodUnimplementedOrientation
+	}
+	return &spatialmath.OrientationVectorDegrees{OZ: 1, Theta: math.Mod(360-s.headingDeg(), 360)}, nil
+}
+
+// Properties returns what the kind of sensor measures.
+func (s *simulatedSensor) Properties(ctx context.Context, extra map[string]interface{}) (*movementsensor.Properties, error) {
+	switch s.conf.Kind {
+	case KindGPS:
+		return &movementsensor.Properties{
+			PositionSupported:       true,
+			CompassHeadingSupported: true,
+			LinearVelocitySupported: true,
+		}, nil
+	case KindIMU:
+		return &movementsensor.Properties{
+			OrientationSupported:        true,
+			CompassHeadingSupported:     true,
+			AngularVelocitySupported:    true,
+			LinearAccelerationSupported: true,
+		}, nil
+	default:
+		return &movementsensor.Properties{
+			PositionSupported:        true,
+			OrientationSupported:     true,
+			LinearVelocitySupported:  true,
+			AngularVelocitySupported: true,
+		}, nil
+	}
+}
+
+// Accuracy returns the configured noise of the sensor.
+func (s *simulatedSensor) Accuracy(ctx context.Context, extra map[string]interface{}) (*movementsensor.Accuracy, error) {
+	acc := &movementsensor.Accuracy{
+		AccuracyMap:        map[string]float32{},
+		CompassDegreeError: float32(math.NaN()),
+	}
+	if s.conf.Kind == KindGPS {
+		acc.AccuracyMap["position_noise_m"] = float32(s.conf.PositionNoiseM)
+		// an RTK fix while the noise is within a few centimeters, otherwise a GPS fix
+		acc.NmeaFix = 1
+		if s.conf.PositionNoiseM <= 0.05 {
+			acc.NmeaFix = 4
+		}
+	}
+	if s.conf.Kind != KindWheelEncoders {
+		acc.CompassDegreeError = float32(s.conf.HeadingNoiseDeg)
+	}
+	return acc, nil
+}
+
+// Readings returns the measurements the sensor supports.
+func (s *simulatedSensor) Readings(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+	return movementsensor.DefaultAPIReadings(ctx, s, extra)
+}
+
+// Close stops integrating odometry.
+func (s *simulatedSensor) Close(ctx context.Context) error {
+	if s.odometrySimulation != nil {
+		s.odometrySimulation.Stop()
+	}
+	return nil
+}
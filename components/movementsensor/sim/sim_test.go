@@ -0,0 +1,199 @@
+package sim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	basesim "go.viam.com/rdk/components/base/sim"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+)
+
+var testOrigin = geo.NewPoint(40, -74)
+
+// fakeBase is a simulated base which reports a ground truth set by the test.
+type fakeBase struct {
+	basesim.Simulated
+	history []basesim.GroundTruth
+}
+
+func (fb *fakeBase) GroundTruthAt(t time.Time) basesim.GroundTruth {
+	latest := fb.history[0]
+	for _, gt := range fb.history {
+		if gt.Time.After(t) {
+			break
+		}
+		latest = gt
+	}
+	return latest
+}
+
+func (fb *fakeBase) WidthMM() float64 {
+	return 500
+}
+
+func (fb *fakeBase) Properties(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
+	return base.Properties{WheelCircumferenceMeters: 0.5}, nil
+}
+
+func newTestSensor(t *testing.T, conf *Config, fb *fakeBase, now time.Time) *simulatedSensor {
+	t.Helper()
+	conf.Base = "base"
+	_, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	s, err := newSensor(context.Background(), movementsensor.Named("sensor").AsNamed(), conf, fb, false, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestGPS(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+	fb := &fakeBase{history: []basesim.GroundTruth{
+		{Time: start, Position: testOrigin, HeadingDeg: 10, LinearVelocityMMPerSec: 500},
+		{Time: start.Add(time.Second), Position: geo.NewPoint(40.001, -74), HeadingDeg: 20},
+	}}
+
+	s := newTestSensor(t, &Config{Kind: KindGPS, LatencyMS: 500}, fb, start.Add(time.Second))
+	// the measurements are half a second old
+	p, _, err := s.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, p, test.ShouldResemble, testOrigin)
+	heading, err := s.CompassHeading(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, heading, test.ShouldEqual, 10)
+	v, err := s.LinearVelocity(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, v, test.ShouldResemble, r3.Vector{Y: 0.5})
+	_, err = s.Orientation(ctx, nil)
+	test.That(t, err, test.ShouldBeError, movementsensor.ErrMethodUnimplementedOrientation)
+
+	s = newTestSensor(t, &Config{Kind: KindGPS, PositionNoiseM: 1, Seed: 1}, fb, start.Add(time.Second))
+	var sum r3.Vector
+	for range 1000 {
+		p, _, err := s.Position(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		offset := spatialmath.GeoPointToPoint(p, fb.history[1].Position).Mul(1e-3)
+		test.That(t, offset.Norm(), test.ShouldBeLessThan, 6)
+		sum = sum.Add(offset)
+	}
+	test.That(t, sum.Mul(1e-3).Norm(), test.ShouldBeLessThan, 0.1)
+	acc, err := s.Accuracy(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, acc.NmeaFix, test.ShouldEqual, 1)
+}
+
+func TestIMU(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+	fb := &fakeBase{history: []basesim.GroundTruth{{
+		Time: start, Position: testOrigin, HeadingDeg: 90, AngularVelocityDegsPerSec: 10,
+		LinearAcceleration: r3.Vector{X: -1, Y: 2},
+	}}}
+	s := newTestSensor(t, &Config{Kind: KindIMU, GyroBiasDegsPerSec: 0.5}, fb, start)
+
+	o, err := s.Orientation(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, o.OrientationVectorDegrees().Theta, test.ShouldAlmostEqual, 270)
+	w, err := s.AngularVelocity(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, w.Z, test.ShouldAlmostEqual, 10.5)
+	a, err := s.LinearAcceleration(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, a, test.ShouldResemble, r3.Vector{X: -1, Y: 2})
+	_, _, err = s.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeError, movementsensor.ErrMethodUnimplementedPosition)
+
+	readings, err := s.Readings(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readings, test.ShouldContainKey, "orientation")
+	test.That(t, readings, test.ShouldNotContainKey, "position")
+}
+
+func TestWheelEncoders(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+	fb := &fakeBase{history: []basesim.GroundTruth{{Time: start, Position: testOrigin}}}
+	s := newTestSensor(t, &Config{Kind: KindWheelEncoders}, fb, start)
+
+	// drive a meter forward then spin a quarter turn counterclockwise, one second each
+	quarterTurnMM := 500 * 3.14159265 / 4
+	fb.history = append(fb.history, basesim.GroundTruth{
+		Time: start.Add(time.Second), LeftWheelMM: 1000, RightWheelMM: 1000,
+	})
+	s.now = func() time.Time { return start.Add(time.Second) }
+	s.updateOdometry()
+	v, err := s.LinearVelocity(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, v.Y, test.ShouldAlmostEqual, 1)
+
+	fb.history = append(fb.history, basesim.GroundTruth{
+		Time: start.Add(2 * time.Second), LeftWheelMM: 1000 - quarterTurnMM, RightWheelMM: 1000 + quarterTurnMM,
+	})
+	s.now = func() time.Time { return start.Add(2 * time.Second) }
+	s.updateOdometry()
+
+	p, _, err := s.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	local := spatialmath.GeoPointToPoint(p, testOrigin).Mul(1e-3)
+	test.That(t, local.X, test.ShouldAlmostEqual, 0, 1e-6)
+	test.That(t, local.Y, test.ShouldAlmostEqual, 1, 1e-6)
+	o, err := s.Orientation(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, o.OrientationVectorDegrees().Theta, test.ShouldAlmostEqual, 90, 1e-6)
+	w, err := s.AngularVelocity(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, w.Z, test.ShouldAlmostEqual, 90, 1e-6)
+
+	t.Run("scale error and ticks", func(t *testing.T) {
+		fb := &fakeBase{history: []basesim.GroundTruth{{Time: start, Position: testOrigin}}}
+		s := newTestSensor(t, &Config{Kind: KindWheelEncoders, WheelScaleError: 0.01, TicksPerRotation: 100}, fb, start)
+		fb.history = append(fb.history, basesim.GroundTruth{
+			Time: start.Add(time.Second), LeftWheelMM: 1000, RightWheelMM: 1000,
+		})
+		s.now = func() time.Time { return start.Add(time.Second) }
+		s.updateOdometry()
+		// the left wheel reads 1010mm and the right 990mm, each a multiple of the 5mm tick
+		o, err := s.Orientation(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, o.OrientationVectorDegrees().Theta, test.ShouldAlmostEqual, 360-20.0/500*180/3.14159265, 1e-3)
+	})
+}
+
+func TestNewMovementSensor(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	b, err := basesim.NewBase(ctx, nil, resource.Config{
+		Name: "base", API: base.API, Model: basesim.Model, ConvertedAttributes: &basesim.Config{Latitude: 40, Longitude: -74},
+	}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, b.Close(ctx), test.ShouldBeNil)
+	}()
+	deps := resource.Dependencies{base.Named("base"): b}
+
+	ms, err := NewMovementSensor(ctx, deps, resource.Config{
+		Name: "gps", API: movementsensor.API, Model: Model, ConvertedAttributes: &Config{Base: "base", Kind: KindWheelEncoders},
+	}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, ms.Close(ctx), test.ShouldBeNil)
+	}()
+	p, _, err := ms.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, p.Lat(), test.ShouldAlmostEqual, 40)
+
+	_, _, err = (&Config{Kind: KindGPS}).Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	_, _, err = (&Config{Base: "base", Kind: "lidar"}).Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+}
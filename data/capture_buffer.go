@@ -22,14 +22,16 @@ type CaptureBuffer struct {
 	nextFile           *CaptureFile
 	lock               sync.Mutex
 	maxCaptureFileSize int64
+	fileOpts           []CaptureFileOption
 }
 
-// NewCaptureBuffer returns a new Buffer.
-func NewCaptureBuffer(dir string, md *v1.DataCaptureMetadata, maxCaptureFileSize int64) *CaptureBuffer {
+// NewCaptureBuffer returns a new Buffer, whose files are created with opts.
+func NewCaptureBuffer(dir string, md *v1.DataCaptureMetadata, maxCaptureFileSize int64, opts ...CaptureFileOption) *CaptureBuffer {
 	return &CaptureBuffer{
 		Directory:          dir,
 		MetaData:           md,
 		maxCaptureFileSize: maxCaptureFileSize,
+		fileOpts:           opts,
 	}
 }
 
@@ -55,7 +57,7 @@ func (b *CaptureBuffer) WriteBinary(item *v1.SensorData, mimeType string) error
 
 	// assign mime type to DataCaptureMetadata
 	b.MetaData.MimeType = mimeType
-	binFile, err := NewCaptureFile(b.Directory, b.MetaData)
+	binFile, err := NewCaptureFile(b.Directory, b.MetaData, b.fileOpts...)
 	if err != nil {
 		return err
 	}
@@ -85,7 +87,7 @@ func (b *CaptureBuffer) WriteTabular(item *v1.SensorData) error {
 	}
 
 	if b.nextFile == nil {
-		nextFile, err := NewCaptureFile(b.Directory, b.MetaData)
+		nextFile, err := NewCaptureFile(b.Directory, b.MetaData, b.fileOpts...)
 		if err != nil {
 			return err
 		}
@@ -94,7 +96,7 @@ func (b *CaptureBuffer) WriteTabular(item *v1.SensorData) error {
 		if err := b.flushInternal(); err != nil {
 			return err
 		}
-		nextFile, err := NewCaptureFile(b.Directory, b.MetaData)
+		nextFile, err := NewCaptureFile(b.Directory, b.MetaData, b.fileOpts...)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,401 @@
+package data
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.viam.com/utils"
+)
+
+// ErrCaptureKeyUnavailable is returned when reading a capture file encrypted with a key which isn't in the keyring.
+var ErrCaptureKeyUnavailable = errors.New("capture file is encrypted with a key which is not available")
+
+// encryptedCaptureFileMagic begins capture files whose records are encrypted. It is followed by a framed record of
+// the ID of the key the file's data key is wrapped with and the wrapped data key, then the framed records of the
+// file, each the nonce and AES-GCM ciphertext of a message.
+var encryptedCaptureFileMagic = []byte{0xff, 'V', 'C', 'A', 'P', 2}
+
+const (
+	// CaptureKeySize is the size in bytes of the keys capture files are encrypted with, which are AES-256 keys.
+	CaptureKeySize  = 32
+	captureKeyIDLen = 8
+)
+
+// CaptureKeyring holds the keys capture files are encrypted with. Each capture file is encrypted with its own
+// random data key, and only that data key is encrypted (wrapped) with a key of the keyring, so that rotating keys
+// only rewrites the header of each file.
+type CaptureKeyring struct {
+	current *captureKey
+	keys    map[[captureKeyIDLen]byte]*captureKey
+}
+
+type captureKey struct {
+	id   [captureKeyIDLen]byte
+	aead cipher.AEAD
+}
+
+// NewCaptureKeyring returns a keyring which encrypts new capture files with current, and decrypts files encrypted
+// with current or any of previous. Previous keys are kept after rotating keys, until the files encrypted with them
+// are synced or rewrapped. If current is nil new files aren't encrypted.
+func NewCaptureKeyring(current []byte, previous ...[]byte) (*CaptureKeyring, error) {
+	keyring := &CaptureKeyring{keys: map[[captureKeyIDLen]byte]*captureKey{}}
+	for i, key := range append([][]byte{current}, previous...) {
+		if i == 0 && key == nil {
+			continue
+		}
+		k, err := newCaptureKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			keyring.current = k
+		}
+		keyring.keys[k.id] = k
+	}
+	return keyring, nil
+}
+
+// CurrentKeyID returns the ID of the key new capture files are encrypted with, or an empty string if they aren't.
+func (k *CaptureKeyring) CurrentKeyID() string {
+	if k == nil || k.current == nil {
+		return ""
+	}
+	return hex.EncodeToString(k.current.id[:])
+}
+
+// Equal returns whether k and o have the same keys and the same current key.
+func (k *CaptureKeyring) Equal(o *CaptureKeyring) bool {
+	if k == nil || o == nil {
+		return k == o
+	}
+	if k.CurrentKeyID() != o.CurrentKeyID() || len(k.keys) != len(o.keys) {
+		return false
+	}
+	for id := range k.keys {
+		if o.keys[id] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// CaptureKeyID returns the ID of key, which is stored in the files encrypted with it: the hex encoded start of its
+// SHA-256 digest.
+func CaptureKeyID(key []byte) string {
+	id := captureKeyIDOf(key)
+	return hex.EncodeToString(id[:])
+}
+
+func captureKeyIDOf(key []byte) [captureKeyIDLen]byte {
+	var id [captureKeyIDLen]byte
+	digest := sha256.Sum256(key)
+	copy(id[:], digest[:])
+	return id
+}
+
+func newCaptureKey(key []byte) (*captureKey, error) {
+	if len(key) != CaptureKeySize {
+		return nil, errors.Errorf("capture encryption keys must be %d bytes, got %d", CaptureKeySize, len(key))
+	}
+	aead, err := newCaptureAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &captureKey{id: captureKeyIDOf(key), aead: aead}, nil
+}
+
+func newCaptureAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ParseCaptureKey decodes a capture encryption key encoded as hex or base64.
+func ParseCaptureKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == CaptureKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == CaptureKeySize {
+		return key, nil
+	}
+	return nil, errors.Errorf("capture encryption keys must be %d bytes encoded as hex or base64", CaptureKeySize)
+}
+
+// LoadCaptureKey reads a capture encryption key from the file at path, or from the environment variable env if path
+// is empty.
+func LoadCaptureKey(path, env string) ([]byte, error) {
+	if path != "" {
+		//nolint:gosec
+		encoded, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseCaptureKey(string(encoded))
+		return key, errors.Wrapf(err, "invalid key in %s", path)
+	}
+	encoded, ok := os.LookupEnv(env)
+	if !ok {
+		return nil, errors.Errorf("environment variable %s is not set", env)
+	}
+	key, err := ParseCaptureKey(encoded)
+	return key, errors.Wrapf(err, "invalid key in environment variable %s", env)
+}
+
+// sealCaptureRecord encrypts plaintext with aead, returning a random nonce followed by the ciphertext.
+func sealCaptureRecord(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// openCaptureRecord decrypts a record sealed by sealCaptureRecord.
+func openCaptureRecord(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrCorruptCaptureRecord
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, errors.Wrap(ErrCorruptCaptureRecord, err.Error())
+	}
+	return plaintext, nil
+}
+
+// newCaptureDataKey returns a new random data key for a capture file, and its encryption header: the ID of the
+// current key of keyring followed by the data key wrapped with it.
+func newCaptureDataKey(keyring *CaptureKeyring) (cipher.AEAD, []byte, error) {
+	dataKey := make([]byte, CaptureKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	header, err := wrapCaptureDataKey(keyring.current, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newCaptureAEAD(dataKey)
+	return aead, header, err
+}
+
+func wrapCaptureDataKey(key *captureKey, dataKey []byte) ([]byte, error) {
+	wrapped, err := sealCaptureRecord(key.aead, dataKey, key.id[:])
+	if err != nil {
+		return nil, err
+	}
+	return append(key.id[:], wrapped...), nil
+}
+
+// unwrapCaptureDataKey returns the data key in the encryption header of a capture file.
+func unwrapCaptureDataKey(keyring *CaptureKeyring, header []byte) ([]byte, error) {
+	if len(header) < captureKeyIDLen {
+		return nil, ErrCorruptCaptureRecord
+	}
+	var id [captureKeyIDLen]byte
+	copy(id[:], header)
+	if keyring == nil || keyring.keys[id] == nil {
+		return nil, errors.Wrapf(ErrCaptureKeyUnavailable, "key %s", hex.EncodeToString(id[:]))
+	}
+	return openCaptureRecord(keyring.keys[id].aead, header[captureKeyIDLen:], id[:])
+}
+
+// readCaptureFileEncryption reads the encryption header of a capture file after its magic number, returning the
+// cipher of its data key, unwrapped with keyring, and the number of bytes read.
+func readCaptureFileEncryption(r io.Reader, keyring *CaptureKeyring) (cipher.AEAD, int, error) {
+	header, n, err := readCaptureFrame(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	dataKey, err := unwrapCaptureDataKey(keyring, header)
+	if err != nil {
+		return nil, 0, err
+	}
+	aead, err := newCaptureAEAD(dataKey)
+	return aead, n, err
+}
+
+// RewrapCaptureFile rewraps the data key of the encrypted capture file at path with the current key of keyring, so
+// that the key it was encrypted with can be retired. Its records are copied as they are, since they are encrypted
+// with the data key. It returns false if the file isn't encrypted or is already wrapped with the current key.
+func RewrapCaptureFile(path string, keyring *CaptureKeyring) (bool, error) {
+	if keyring == nil || keyring.current == nil {
+		return false, errors.New("no current capture encryption key to rewrap with")
+	}
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer utils.UncheckedErrorFunc(f.Close)
+
+	magic := make([]byte, len(encryptedCaptureFileMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, encryptedCaptureFileMagic) {
+		return false, nil
+	}
+	header, _, err := readCaptureFrame(f)
+	if err != nil {
+		return false, err
+	}
+	if bytes.HasPrefix(header, keyring.current.id[:]) {
+		return false, nil
+	}
+	dataKey, err := unwrapCaptureDataKey(keyring, header)
+	if err != nil {
+		return false, err
+	}
+	header, err = wrapCaptureDataKey(keyring.current, dataKey)
+	if err != nil {
+		return false, err
+	}
+	err = writeFileAtomically(path, "_rewrap", func(w io.Writer) error {
+		if _, err := w.Write(encryptedCaptureFileMagic); err != nil {
+			return err
+		}
+		if _, err := writeCaptureFrame(w, header); err != nil {
+			return err
+		}
+		_, err := io.Copy(w, f)
+		return err
+	})
+	return err == nil, err
+}
+
+// RewrapCaptureFiles rewraps the completed encrypted capture files under dir with the current key of keyring,
+// returning the paths of the files rewrapped. Files which can't be rewrapped are skipped, and returned as errors.
+func RewrapCaptureFiles(dir string, keyring *CaptureKeyring) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == CompletedCaptureFileExt {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rewrapped []string
+	var errs []error
+	for _, path := range paths {
+		ok, err := RewrapCaptureFile(path, keyring)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to rewrap %s", path))
+			continue
+		}
+		if ok {
+			rewrapped = append(rewrapped, path)
+		}
+	}
+	return rewrapped, multierr.Combine(errs...)
+}
+
+// RetainCaptureKeys returns keyring with the keys of previous it doesn't hold which capture files under dirs are still
+// encrypted with added as decrypt-only keys, so that removing or rotating away a key doesn't strand the files which
+// haven't been synced yet. A removed key is dropped by the first call which finds no file encrypted with it. If dirs
+// can't be read every removed key is kept, and the error is returned.
+func RetainCaptureKeys(keyring, previous *CaptureKeyring, dirs []string) (*CaptureKeyring, error) {
+	if previous == nil {
+		return keyring, nil
+	}
+	removed := map[[captureKeyIDLen]byte]*captureKey{}
+	for id, key := range previous.keys {
+		if keyring == nil || keyring.keys[id] == nil {
+			removed[id] = key
+		}
+	}
+	if len(removed) == 0 {
+		return keyring, nil
+	}
+
+	inUse := map[[captureKeyIDLen]byte]bool{}
+	var errs []error
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || (filepath.Ext(path) != CompletedCaptureFileExt && filepath.Ext(path) != InProgressCaptureFileExt) {
+				return nil
+			}
+			id, encrypted, err := captureFileKeyID(path)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return errors.Wrapf(err, "failed to read encryption header of %s", path)
+			}
+			if encrypted {
+				inUse[id] = true
+			}
+			return nil
+		})
+		errs = append(errs, err)
+	}
+	err := multierr.Combine(errs...)
+
+	retained := &CaptureKeyring{keys: map[[captureKeyIDLen]byte]*captureKey{}}
+	if keyring != nil {
+		retained.current = keyring.current
+		for id, key := range keyring.keys {
+			retained.keys[id] = key
+		}
+	}
+	for id, key := range removed {
+		if err != nil || inUse[id] {
+			retained.keys[id] = key
+		}
+	}
+	if len(retained.keys) == 0 || (keyring != nil && len(retained.keys) == len(keyring.keys)) {
+		return keyring, err
+	}
+	return retained, err
+}
+
+// captureFileKeyID returns the ID of the key the capture file at path is encrypted with, and false if it isn't
+// encrypted.
+func captureFileKeyID(path string) ([captureKeyIDLen]byte, bool, error) {
+	var id [captureKeyIDLen]byte
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return id, false, err
+	}
+	defer utils.UncheckedErrorFunc(f.Close)
+
+	magic := make([]byte, len(encryptedCaptureFileMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, encryptedCaptureFileMagic) {
+		return id, false, nil
+	}
+	header, _, err := readCaptureFrame(f)
+	if err != nil {
+		return id, false, err
+	}
+	if len(header) < captureKeyIDLen {
+		return id, false, ErrCorruptCaptureRecord
+	}
+	copy(id[:], header)
+	return id, true, nil
+}
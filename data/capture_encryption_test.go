@@ -0,0 +1,254 @@
+package data
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+)
+
+func newTestCaptureKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, CaptureKeySize)
+	_, err := rand.Read(key)
+	test.That(t, err, test.ShouldBeNil)
+	return key
+}
+
+// newTestCaptureKeyring returns an option to read and write capture files with a keyring of the keys.
+func newTestCaptureKeyring(t *testing.T, current []byte, previous ...[]byte) CaptureFileOption {
+	t.Helper()
+	keyring, err := NewCaptureKeyring(current, previous...)
+	test.That(t, err, test.ShouldBeNil)
+	return WithCaptureKeyring(keyring)
+}
+
+func TestEncryptedCaptureFile(t *testing.T) {
+	key := newTestCaptureKey(t)
+	keyring := newTestCaptureKeyring(t, key)
+
+	dir := t.TempDir()
+	md := &v1.DataCaptureMetadata{ComponentName: "sensitive-sensor", Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR}
+	f, err := NewCaptureFile(dir, md, keyring)
+	test.That(t, err, test.ShouldBeNil)
+	for i := 0; i < 3; i++ {
+		test.That(t, f.WriteNext(tabularSensorData(t, float64(i))), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+	path := f.GetPath()
+
+	contents, err := os.ReadFile(path)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, contents[:len(encryptedCaptureFileMagic)], test.ShouldResemble, encryptedCaptureFileMagic)
+	test.That(t, bytes.Contains(contents, []byte("sensitive-sensor")), test.ShouldBeFalse)
+	test.That(t, bytes.Contains(contents, []byte("value")), test.ShouldBeFalse)
+
+	test.That(t, readValues(t, path, keyring), test.ShouldResemble, []float64{0, 1, 2})
+
+	t.Run("key unavailable", func(t *testing.T) {
+		_, err := SensorDataFromCaptureFilePath(path, newTestCaptureKeyring(t, newTestCaptureKey(t)))
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err, test.ShouldWrap, ErrCaptureKeyUnavailable)
+		_, err = SensorDataFromCaptureFilePath(path)
+		test.That(t, err, test.ShouldWrap, ErrCaptureKeyUnavailable)
+	})
+
+	t.Run("tampered record", func(t *testing.T) {
+		tampered := filepath.Join(t.TempDir(), "tampered"+CompletedCaptureFileExt)
+		contents := bytes.Clone(contents)
+		contents[len(contents)-1] ^= 1
+		test.That(t, os.WriteFile(tampered, contents, 0o600), test.ShouldBeNil)
+		_, err := SensorDataFromCaptureFilePath(tampered, keyring)
+		test.That(t, err, test.ShouldBeError, ErrCorruptCaptureRecord)
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		truncated := filepath.Join(t.TempDir(), "truncated"+CompletedCaptureFileExt)
+		test.That(t, os.WriteFile(truncated, contents[:len(contents)-1], 0o600), test.ShouldBeNil)
+		repair, repaired, err := RepairCaptureFile(truncated, keyring)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, repaired, test.ShouldBeTrue)
+		test.That(t, repair.Records, test.ShouldEqual, 2)
+		test.That(t, readValues(t, truncated, keyring), test.ShouldResemble, []float64{0, 1})
+		repairedContents, err := os.ReadFile(truncated)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, repairedContents[:len(encryptedCaptureFileMagic)], test.ShouldResemble, encryptedCaptureFileMagic)
+
+		test.That(t, os.WriteFile(truncated, contents[:len(contents)-1], 0o600), test.ShouldBeNil)
+		_, _, err = RepairCaptureFile(truncated, newTestCaptureKeyring(t, nil, key))
+		test.That(t, err, test.ShouldBeError, "no current capture encryption key to encrypt the repaired file with")
+	})
+}
+
+func TestEncryptedBinaryPayloadReader(t *testing.T) {
+	f, err := NewCaptureFile(t.TempDir(), &v1.DataCaptureMetadata{Type: v1.DataType_DATA_TYPE_BINARY_SENSOR},
+		newTestCaptureKeyring(t, newTestCaptureKey(t)))
+	test.That(t, err, test.ShouldBeNil)
+	payload := []byte("an image")
+	test.That(t, f.WriteNext(&v1.SensorData{
+		Metadata: &v1.SensorMetadata{MimeType: v1.MimeType_MIME_TYPE_IMAGE_JPEG},
+		Data:     &v1.SensorData_Binary{Binary: payload},
+	}), test.ShouldBeNil)
+	test.That(t, f.WriteNext(tabularSensorData(t, 1)), test.ShouldBeNil)
+	test.That(t, f.Flush(), test.ShouldBeNil)
+
+	md, n, r, err := f.BinaryPayloadReader()
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, md.GetMimeType(), test.ShouldEqual, v1.MimeType_MIME_TYPE_IMAGE_JPEG)
+	test.That(t, n, test.ShouldEqual, len(payload))
+	read, err := io.ReadAll(r)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, read, test.ShouldResemble, payload)
+
+	_, _, _, err = f.BinaryPayloadReader()
+	test.That(t, err, test.ShouldBeError, ErrNoBinaryField)
+	_, _, _, err = f.BinaryPayloadReader()
+	test.That(t, err, test.ShouldBeError, io.EOF)
+	test.That(t, f.Close(), test.ShouldBeNil)
+}
+
+func TestRewrapCaptureFiles(t *testing.T) {
+	oldKey, newKey := newTestCaptureKey(t), newTestCaptureKey(t)
+	dir := t.TempDir()
+	md := &v1.DataCaptureMetadata{ComponentName: "sensor1", Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR}
+
+	plain, err := NewCaptureFile(dir, md)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, plain.WriteNext(tabularSensorData(t, 1)), test.ShouldBeNil)
+	test.That(t, plain.Close(), test.ShouldBeNil)
+
+	oldKeyring, err := NewCaptureKeyring(oldKey)
+	test.That(t, err, test.ShouldBeNil)
+	encrypted, err := NewCaptureFile(dir, md, WithCaptureKeyring(oldKeyring))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, encrypted.WriteNext(tabularSensorData(t, 2)), test.ShouldBeNil)
+	test.That(t, encrypted.Close(), test.ShouldBeNil)
+
+	_, err = RewrapCaptureFiles(dir, oldKeyring)
+	test.That(t, err, test.ShouldBeNil)
+
+	// after rotating, files encrypted with the old key are still read
+	rotated, err := NewCaptureKeyring(newKey, oldKey)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readValues(t, encrypted.GetPath(), WithCaptureKeyring(rotated)), test.ShouldResemble, []float64{2})
+
+	rewrapped, err := RewrapCaptureFiles(dir, rotated)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, rewrapped, test.ShouldResemble, []string{encrypted.GetPath()})
+	rewrapped, err = RewrapCaptureFiles(dir, rotated)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, rewrapped, test.ShouldBeEmpty)
+
+	// once rewrapped, the old key can be retired
+	retired := newTestCaptureKeyring(t, newKey)
+	test.That(t, readValues(t, encrypted.GetPath(), retired), test.ShouldResemble, []float64{2})
+	test.That(t, readValues(t, plain.GetPath(), retired), test.ShouldResemble, []float64{1})
+
+	_, err = RewrapCaptureFile(encrypted.GetPath(), nil)
+	test.That(t, err, test.ShouldBeError, "no current capture encryption key to rewrap with")
+}
+
+func TestRetainCaptureKeys(t *testing.T) {
+	oldKey, newKey := newTestCaptureKey(t), newTestCaptureKey(t)
+	dir := t.TempDir()
+	md := &v1.DataCaptureMetadata{ComponentName: "sensor1", Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR}
+
+	oldKeyring, err := NewCaptureKeyring(oldKey)
+	test.That(t, err, test.ShouldBeNil)
+	encrypted, err := NewCaptureFile(dir, md, WithCaptureKeyring(oldKeyring))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, encrypted.WriteNext(tabularSensorData(t, 1)), test.ShouldBeNil)
+	test.That(t, encrypted.Close(), test.ShouldBeNil)
+
+	t.Run("keeps removed keys files are still encrypted with", func(t *testing.T) {
+		keyring, err := RetainCaptureKeys(nil, oldKeyring, []string{dir})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, keyring, test.ShouldNotBeNil)
+		test.That(t, keyring.CurrentKeyID(), test.ShouldBeEmpty)
+		test.That(t, readValues(t, encrypted.GetPath(), WithCaptureKeyring(keyring)), test.ShouldResemble, []float64{1})
+
+		// new files aren't encrypted with a retained key
+		plain, err := NewCaptureFile(dir, md, WithCaptureKeyring(keyring))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, plain.Close(), test.ShouldBeNil)
+		_, isEncrypted, err := captureFileKeyID(plain.GetPath())
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, isEncrypted, test.ShouldBeFalse)
+		test.That(t, os.Remove(plain.GetPath()), test.ShouldBeNil)
+
+		rotated, err := NewCaptureKeyring(newKey)
+		test.That(t, err, test.ShouldBeNil)
+		keyring, err = RetainCaptureKeys(rotated, oldKeyring, []string{dir})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, keyring.CurrentKeyID(), test.ShouldEqual, CaptureKeyID(newKey))
+		test.That(t, readValues(t, encrypted.GetPath(), WithCaptureKeyring(keyring)), test.ShouldResemble, []float64{1})
+	})
+
+	t.Run("keeps every removed key if the files can't be read", func(t *testing.T) {
+		unreadable := filepath.Join(dir, "unreadable"+CompletedCaptureFileExt)
+		test.That(t, os.WriteFile(unreadable, encryptedCaptureFileMagic, 0o600), test.ShouldBeNil)
+		defer func() { test.That(t, os.Remove(unreadable), test.ShouldBeNil) }()
+
+		keyring, err := RetainCaptureKeys(nil, oldKeyring, []string{dir})
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, readValues(t, encrypted.GetPath(), WithCaptureKeyring(keyring)), test.ShouldResemble, []float64{1})
+	})
+
+	t.Run("drops removed keys once no file is encrypted with them", func(t *testing.T) {
+		test.That(t, os.Remove(encrypted.GetPath()), test.ShouldBeNil)
+		keyring, err := RetainCaptureKeys(nil, oldKeyring, []string{dir})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, keyring, test.ShouldBeNil)
+
+		rotated, err := NewCaptureKeyring(newKey)
+		test.That(t, err, test.ShouldBeNil)
+		keyring, err = RetainCaptureKeys(rotated, oldKeyring, []string{dir})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, keyring, test.ShouldEqual, rotated)
+	})
+}
+
+func TestParseCaptureKey(t *testing.T) {
+	key := newTestCaptureKey(t)
+	for _, encoded := range []string{hex.EncodeToString(key), base64.StdEncoding.EncodeToString(key) + "\n"} {
+		parsed, err := ParseCaptureKey(encoded)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, parsed, test.ShouldResemble, key)
+	}
+	_, err := ParseCaptureKey(hex.EncodeToString(key[:16]))
+	test.That(t, err, test.ShouldBeError, "capture encryption keys must be 32 bytes encoded as hex or base64")
+
+	path := filepath.Join(t.TempDir(), "capture.key")
+	test.That(t, os.WriteFile(path, []byte(hex.EncodeToString(key)), 0o600), test.ShouldBeNil)
+	loaded, err := LoadCaptureKey(path, "")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, loaded, test.ShouldResemble, key)
+
+	t.Setenv("TEST_CAPTURE_KEY", base64.StdEncoding.EncodeToString(key))
+	loaded, err = LoadCaptureKey("", "TEST_CAPTURE_KEY")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, loaded, test.ShouldResemble, key)
+	_, err = LoadCaptureKey("", "TEST_CAPTURE_KEY_UNSET")
+	test.That(t, err, test.ShouldBeError, "environment variable TEST_CAPTURE_KEY_UNSET is not set")
+
+	keyring, err := NewCaptureKeyring(key)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, keyring.CurrentKeyID(), test.ShouldEqual, CaptureKeyID(key))
+	same, err := NewCaptureKeyring(key)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, keyring.Equal(same), test.ShouldBeTrue)
+	test.That(t, keyring.Equal(nil), test.ShouldBeFalse)
+	keyring, err = NewCaptureKeyring(nil, key)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, keyring.CurrentKeyID(), test.ShouldBeEmpty)
+	test.That(t, keyring.Equal(same), test.ShouldBeFalse)
+	_, err = NewCaptureKeyring(key[:16])
+	test.That(t, err, test.ShouldBeError, "capture encryption keys must be 32 bytes, got 16")
+}
@@ -2,8 +2,11 @@ package data
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/cipher"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -38,6 +41,19 @@ var ErrUnparsableBinaryCapture = errors.New("capture file cannot be streamed due
 // file has been closed (via Close or Delete). It matches errors.Is(err, os.ErrClosed).
 var ErrFileClosed = fmt.Errorf("capture file already closed: %w", os.ErrClosed)
 
+// ErrCorruptCaptureRecord is returned when a record of a capture file fails its checksum, or can't be decoded.
+var ErrCorruptCaptureRecord = errors.New("capture file record is corrupt")
+
+// captureFileMagic begins capture files whose records are framed with a checksum. Files without it are read as
+// plain length delimited protobuf messages. Its first byte is a varint continuation byte, so a plain file would
+// need a metadata message of 11135 bytes beginning with the rest of the magic to be mistaken for a framed one.
+var captureFileMagic = []byte{0xff, 'V', 'C', 'A', 'P', 1}
+
+// maxCaptureRecordSize bounds the length read from a record header, so that a corrupt length can't exhaust memory.
+const maxCaptureRecordSize = 1 << 30
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
 // TODO Data-343: Reorganize this into a more standard interface/package, and add tests.
 
 const (
@@ -69,6 +85,14 @@ const (
 // CaptureFile is the data structure containing data captured by collectors. It is backed by a file on disk containing
 // length delimited protobuf messages, where the first message is the CaptureMetadata for the file, and ensuing
 // messages contain the captured data.
+//
+// Files begin with a magic number, and each message is framed as its length as a varint, the little endian CRC-32C
+// of the message, then the message, so that a record truncated or corrupted by losing power mid-write is detected.
+// Files written before framing, without the magic number, are still read.
+//
+// Files written with a CaptureKeyring (see WithCaptureKeyring) are encrypted: each message is sealed with AES-GCM
+// using a data key of the file, which is stored in the file wrapped with the current key of the keyring. Encrypted
+// files are decrypted transparently when read with a keyring holding that key.
 type CaptureFile struct {
 	path     string
 	lock     sync.Mutex
@@ -77,14 +101,38 @@ type CaptureFile struct {
 	size     int64
 	metadata *v1.DataCaptureMetadata
 	closed   bool
+	codec    captureRecordCodec
 
 	initialReadOffset int64
 	readOffset        int64
 	writeOffset       int64
 }
 
+// CaptureFileOption configures how capture files are written and read.
+type CaptureFileOption func(*captureFileOptions)
+
+type captureFileOptions struct {
+	keyring *CaptureKeyring
+}
+
+func newCaptureFileOptions(opts []CaptureFileOption) captureFileOptions {
+	var o captureFileOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCaptureKeyring encrypts new capture files with the current key of keyring, if it has one, and decrypts files
+// encrypted with any of its keys. Without a keyring files are written unencrypted, and encrypted files can't be read.
+func WithCaptureKeyring(keyring *CaptureKeyring) CaptureFileOption {
+	return func(o *captureFileOptions) {
+		o.keyring = keyring
+	}
+}
+
 // ReadCaptureFile creates a File struct from a passed os.File previously constructed using NewFile.
-func ReadCaptureFile(f *os.File) (*CaptureFile, error) {
+func ReadCaptureFile(f *os.File, opts ...CaptureFileOption) (*CaptureFile, error) {
 	if !IsDataCaptureFile(f) {
 		return nil, errors.Errorf("%s is not a data capture file", f.Name())
 	}
@@ -93,8 +141,7 @@ func ReadCaptureFile(f *os.File) (*CaptureFile, error) {
 		return nil, err
 	}
 
-	md := &v1.DataCaptureMetadata{}
-	initOffset, err := pbutil.ReadDelimited(f, md)
+	codec, md, initOffset, err := readCaptureFileHeader(f, newCaptureFileOptions(opts).keyring)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read DataCaptureMetadata from %s", f.Name())
 	}
@@ -105,16 +152,17 @@ func ReadCaptureFile(f *os.File) (*CaptureFile, error) {
 		writer:            bufio.NewWriter(f),
 		size:              finfo.Size(),
 		metadata:          md,
-		initialReadOffset: int64(initOffset),
-		readOffset:        int64(initOffset),
-		writeOffset:       int64(initOffset),
+		codec:             codec,
+		initialReadOffset: initOffset,
+		readOffset:        initOffset,
+		writeOffset:       initOffset,
 	}
 
 	return &ret, nil
 }
 
 // NewCaptureFile creates a new *CaptureFile with the specified md in the specified directory.
-func NewCaptureFile(dir string, md *v1.DataCaptureMetadata) (*CaptureFile, error) {
+func NewCaptureFile(dir string, md *v1.DataCaptureMetadata, opts ...CaptureFileOption) (*CaptureFile, error) {
 	fileName := CaptureFilePathWithReplacedReservedChars(
 		filepath.Join(dir, getFileTimestampName()) + InProgressCaptureFileExt)
 	//nolint:gosec
@@ -123,8 +171,8 @@ func NewCaptureFile(dir string, md *v1.DataCaptureMetadata) (*CaptureFile, error
 		return nil, err
 	}
 
-	// Then write first metadata message to the file.
-	n, err := pbutil.WriteDelimited(f, md)
+	// Then write the magic number and first metadata message to the file.
+	codec, n, err := writeCaptureFileHeader(f, md, newCaptureFileOptions(opts).keyring)
 	if err != nil {
 		return nil, err
 	}
@@ -133,13 +181,186 @@ func NewCaptureFile(dir string, md *v1.DataCaptureMetadata) (*CaptureFile, error
 		writer:            bufio.NewWriter(f),
 		file:              f,
 		metadata:          md,
-		size:              int64(n),
-		initialReadOffset: int64(n),
-		readOffset:        int64(n),
-		writeOffset:       int64(n),
+		codec:             codec,
+		size:              n,
+		initialReadOffset: n,
+		readOffset:        n,
+		writeOffset:       n,
 	}, nil
 }
 
+// writeCaptureFileHeader writes the magic number and metadata which begin a capture file, encrypting it with a new
+// data key if keyring has a current key. It returns the codec of the file's records and the number of bytes written.
+func writeCaptureFileHeader(
+	w io.Writer, md *v1.DataCaptureMetadata, keyring *CaptureKeyring,
+) (captureRecordCodec, int64, error) {
+	codec := captureRecordCodec{framed: true}
+	magic := captureFileMagic
+	var encryption []byte
+	if keyring != nil && keyring.current != nil {
+		var err error
+		if codec.aead, encryption, err = newCaptureDataKey(keyring); err != nil {
+			return captureRecordCodec{}, 0, err
+		}
+		magic = encryptedCaptureFileMagic
+	}
+	if _, err := w.Write(magic); err != nil {
+		return captureRecordCodec{}, 0, err
+	}
+	n := len(magic)
+	if encryption != nil {
+		written, err := writeCaptureFrame(w, encryption)
+		if err != nil {
+			return captureRecordCodec{}, 0, err
+		}
+		n += written
+	}
+	written, err := codec.write(w, md)
+	if err != nil {
+		return captureRecordCodec{}, 0, err
+	}
+	return codec, int64(n + written), nil
+}
+
+// readCaptureFileHeader reads the magic number and metadata which begin a capture file, returning the codec of the
+// file's records, its metadata and the number of bytes read. Files without a magic number are read from their start
+// as unframed files, and encrypted files are decrypted with keyring.
+func readCaptureFileHeader(r io.ReadSeeker, keyring *CaptureKeyring) (captureRecordCodec, *v1.DataCaptureMetadata, int64, error) {
+	var codec captureRecordCodec
+	var n int
+	magic := make([]byte, len(captureFileMagic))
+	_, err := io.ReadFull(r, magic)
+	switch {
+	case err == nil && bytes.Equal(magic, captureFileMagic):
+		codec.framed = true
+		n = len(magic)
+	case err == nil && bytes.Equal(magic, encryptedCaptureFileMagic):
+		aead, read, err := readCaptureFileEncryption(r, keyring)
+		if err != nil {
+			return captureRecordCodec{}, nil, 0, err
+		}
+		codec = captureRecordCodec{framed: true, aead: aead}
+		n = len(magic) + read
+	default:
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return captureRecordCodec{}, nil, 0, err
+		}
+	}
+	md := &v1.DataCaptureMetadata{}
+	read, err := codec.read(r, md)
+	if err != nil {
+		return captureRecordCodec{}, nil, 0, err
+	}
+	return codec, md, int64(n + read), nil
+}
+
+// captureRecord is a message of a capture file: a generated message, which implements both protobuf APIs so that
+// it can be read and written without framing by pbutil.
+type captureRecord interface {
+	proto.Message
+	Reset()
+	String() string
+	ProtoMessage()
+}
+
+// captureRecordCodec reads and writes the records of a capture file: framed with a checksum, and encrypted if aead
+// is set, or length delimited if the file was written before framing.
+type captureRecordCodec struct {
+	framed bool
+	aead   cipher.AEAD
+}
+
+// write writes m to w, returning the number of bytes written.
+func (c captureRecordCodec) write(w io.Writer, m captureRecord) (int, error) {
+	if !c.framed {
+		return pbutil.WriteDelimited(w, m)
+	}
+	buf, err := proto.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	if c.aead != nil {
+		if buf, err = sealCaptureRecord(c.aead, buf, nil); err != nil {
+			return 0, err
+		}
+	}
+	return writeCaptureFrame(w, buf)
+}
+
+// read reads the next record of r into m, returning the number of bytes read. It returns io.EOF if r has no more
+// records, io.ErrUnexpectedEOF if the record is truncated, and ErrCorruptCaptureRecord if a framed record fails its
+// checksum or can't be decrypted.
+func (c captureRecordCodec) read(r io.Reader, m captureRecord) (int, error) {
+	if !c.framed {
+		return pbutil.ReadDelimited(r, m)
+	}
+	buf, n, err := readCaptureFrame(r)
+	if err != nil {
+		return 0, err
+	}
+	if c.aead != nil {
+		if buf, err = openCaptureRecord(c.aead, buf, nil); err != nil {
+			return 0, err
+		}
+	}
+	if err := proto.Unmarshal(buf, m); err != nil {
+		return 0, errors.Wrap(ErrCorruptCaptureRecord, err.Error())
+	}
+	return n, nil
+}
+
+// writeCaptureFrame writes buf to w framed by its length and checksum, returning the number of bytes written.
+func writeCaptureFrame(w io.Writer, buf []byte) (int, error) {
+	var header [binary.MaxVarintLen64 + crc32.Size]byte
+	n := binary.PutUvarint(header[:], uint64(len(buf)))
+	binary.LittleEndian.PutUint32(header[n:], crc32.Checksum(buf, castagnoliTable))
+	n += crc32.Size
+	if _, err := w.Write(header[:n]); err != nil {
+		return 0, err
+	}
+	written, err := w.Write(buf)
+	return n + written, err
+}
+
+// readCaptureFrame reads the next frame of r, returning its contents and the number of bytes read. It returns
+// io.EOF if r has no more frames, io.ErrUnexpectedEOF if the frame is truncated, and ErrCorruptCaptureRecord if it
+// fails its checksum.
+func readCaptureFrame(r io.Reader) ([]byte, int, error) {
+	var header [binary.MaxVarintLen64]byte
+	var n int
+	for {
+		if _, err := io.ReadFull(r, header[n:n+1]); err != nil {
+			if n > 0 && errors.Is(err, io.EOF) {
+				return nil, 0, io.ErrUnexpectedEOF
+			}
+			return nil, 0, err
+		}
+		n++
+		if header[n-1] < 0x80 {
+			break
+		}
+		if n == len(header) {
+			return nil, 0, ErrCorruptCaptureRecord
+		}
+	}
+	length, _ := binary.Uvarint(header[:n])
+	if length > maxCaptureRecordSize {
+		return nil, 0, ErrCorruptCaptureRecord
+	}
+	var checksum [crc32.Size]byte
+	if _, err := io.ReadFull(r, checksum[:]); err != nil {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	if crc32.Checksum(buf, castagnoliTable) != binary.LittleEndian.Uint32(checksum[:]) {
+		return nil, 0, ErrCorruptCaptureRecord
+	}
+	return buf, n + crc32.Size + int(length), nil
+}
+
 // ReadMetadata reads and returns the metadata in f.
 func (f *CaptureFile) ReadMetadata() *v1.DataCaptureMetadata {
 	return f.metadata
@@ -161,7 +382,7 @@ func (f *CaptureFile) ReadNext() (*v1.SensorData, error) {
 		return nil, err
 	}
 	r := v1.SensorData{}
-	read, err := pbutil.ReadDelimited(f.file, &r)
+	read, err := f.codec.read(f.file, &r)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +402,7 @@ func (f *CaptureFile) WriteNext(data *v1.SensorData) error {
 	if _, err := f.file.Seek(f.writeOffset, 0); err != nil {
 		return err
 	}
-	n, err := pbutil.WriteDelimited(f.writer, data)
+	n, err := f.codec.write(f.writer, data)
 	if err != nil {
 		return err
 	}
@@ -300,13 +521,13 @@ func getFileTimestampName() string {
 
 // SensorDataFromCaptureFilePath returns all readings in the file at filePath.
 // NOTE: (Nick S) At time of writing this is only used in tests.
-func SensorDataFromCaptureFilePath(filePath string) ([]*v1.SensorData, error) {
+func SensorDataFromCaptureFilePath(filePath string, opts ...CaptureFileOption) ([]*v1.SensorData, error) {
 	//nolint:gosec
 	f, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
-	dcFile, err := ReadCaptureFile(f)
+	dcFile, err := ReadCaptureFile(f, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -333,6 +554,25 @@ func SensorDataFromCaptureFile(f *CaptureFile) ([]*v1.SensorData, error) {
 	return ret, nil
 }
 
+// decryptedBinaryPayload reads and decrypts the next SensorData message of an encrypted f, returning its binary
+// payload like BinaryPayloadReader. The caller must hold f.lock.
+func (f *CaptureFile) decryptedBinaryPayload() (*v1.SensorMetadata, int64, io.Reader, error) {
+	if _, err := f.file.Seek(f.readOffset, io.SeekStart); err != nil {
+		return nil, 0, nil, err
+	}
+	var sd v1.SensorData
+	read, err := f.codec.read(f.file, &sd)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	f.readOffset += int64(read)
+	payload, ok := sd.GetData().(*v1.SensorData_Binary)
+	if !ok {
+		return nil, 0, nil, ErrNoBinaryField
+	}
+	return sd.GetMetadata(), int64(len(payload.Binary)), bytes.NewReader(payload.Binary), nil
+}
+
 // BinaryPayloadReader reads the next SensorData message from f without loading
 // the binary payload into memory. It returns the SensorMetadata, payload size,
 // and an io.Reader for streaming the payload.
@@ -357,6 +597,11 @@ func (f *CaptureFile) BinaryPayloadReader() (*v1.SensorMetadata, int64, io.Reade
 		return nil, 0, nil, ErrFileClosed
 	}
 
+	// Encrypted records can't be streamed from the file, so they are decrypted into memory.
+	if f.codec.aead != nil {
+		return f.decryptedBinaryPayload()
+	}
+
 	// Seek to where we left off. seekOffset is captured before the seek so we can
 	// compute absolute file positions for the SectionReader later.
 	seekOffset := f.readOffset
@@ -373,8 +618,16 @@ func (f *CaptureFile) BinaryPayloadReader() (*v1.SensorMetadata, int64, io.Reade
 		return nil, 0, nil, err // io.EOF means no more messages
 	}
 
+	// Framed records have a checksum after their length. It isn't verified, since that would need the whole
+	// payload to be read before it can be streamed.
+	if f.codec.framed {
+		if _, err := io.CopyN(io.Discard, varintCR, crc32.Size); err != nil {
+			return nil, 0, nil, io.ErrUnexpectedEOF
+		}
+	}
+
 	// msgStart is the absolute file offset of the first byte of the SensorData fields
-	// (i.e., just past the outerLen varint). We use this later to anchor the SectionReader.
+	// (i.e., just past the outerLen varint and checksum). We use this later to anchor the SectionReader.
 	msgStart := seekOffset + varintCR.count
 
 	// Advance readOffset past this entire record so the next call starts at the right place,
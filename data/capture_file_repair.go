@@ -0,0 +1,192 @@
+package data
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/utils"
+)
+
+// CaptureFileRepair describes the repair of a capture file.
+type CaptureFileRepair struct {
+	// Path is the path of the file which was repaired.
+	Path string
+	// RepairedPath is the path of the completed capture file the salvaged records were written to. It is empty if
+	// no records could be salvaged, in which case the file was removed.
+	RepairedPath string
+	// Records is the number of records salvaged.
+	Records int
+	// DiscardedBytes is the number of bytes after the last salvaged record which were discarded.
+	DiscardedBytes int64
+}
+
+// RepairCaptureFile salvages the records of the capture file at path up to its first truncated or corrupt record,
+// and writes them to a completed capture file named like it, replacing it. In progress files, such as those left
+// behind when a machine loses power, are always completed. Completed files are only rewritten if they have records
+// which can't be read, and otherwise false is returned.
+//
+// A file whose metadata can't be read can't be repaired, and is left as it is. Files are read and repaired files
+// written with opts, so an encrypted file can only be rewritten with a keyring which has a current key.
+func RepairCaptureFile(path string, opts ...CaptureFileOption) (CaptureFileRepair, bool, error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return CaptureFileRepair{}, false, err
+	}
+	cf, err := ReadCaptureFile(f, opts...)
+	if err != nil {
+		utils.UncheckedError(f.Close())
+		return CaptureFileRepair{}, false, err
+	}
+	var records []*v1.SensorData
+	var readErr error
+	for {
+		record, err := cf.ReadNext()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				readErr = err
+			}
+			break
+		}
+		records = append(records, record)
+	}
+	repair := CaptureFileRepair{
+		Path:           path,
+		Records:        len(records),
+		DiscardedBytes: cf.Size() - cf.readOffset,
+	}
+	if err := cf.Close(); err != nil {
+		return CaptureFileRepair{}, false, err
+	}
+	// Closing an in progress file completes it, so from here on it is at its completed path.
+	path = cf.GetPath()
+	if filepath.Ext(repair.Path) == CompletedCaptureFileExt && readErr == nil {
+		return repair, false, nil
+	}
+
+	if len(records) == 0 {
+		return repair, true, os.Remove(path)
+	}
+	repair.RepairedPath = strings.TrimSuffix(path, filepath.Ext(path)) + CompletedCaptureFileExt
+	if readErr == nil {
+		// the file was only in progress, and closing it completed it
+		return repair, true, nil
+	}
+	keyring := newCaptureFileOptions(opts).keyring
+	if cf.codec.aead != nil && (keyring == nil || keyring.current == nil) {
+		// the salvaged records must not be written back unencrypted
+		return CaptureFileRepair{}, false, errors.New("no current capture encryption key to encrypt the repaired file with")
+	}
+	if err := writeCaptureFile(repair.RepairedPath, cf.ReadMetadata(), records, keyring); err != nil {
+		return CaptureFileRepair{}, false, err
+	}
+	return repair, true, nil
+}
+
+// writeCaptureFile atomically writes a completed capture file at path, encrypted with the current key of keyring if
+// it has one.
+func writeCaptureFile(path string, md *v1.DataCaptureMetadata, records []*v1.SensorData, keyring *CaptureKeyring) error {
+	return writeFileAtomically(path, "_repair", func(w io.Writer) error {
+		codec, _, err := writeCaptureFileHeader(w, md, keyring)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if _, err := codec.write(w, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeFileAtomically replaces the file at path with what write writes, by writing it to an in progress file next
+// to path named with suffix and renaming that. If the machine loses power before the rename, the in progress file
+// is repaired in turn.
+func writeFileAtomically(path, suffix string, write func(w io.Writer) error) (err error) {
+	tmpPath := strings.TrimSuffix(path, filepath.Ext(path)) + suffix + InProgressCaptureFileExt
+	//nolint:gosec
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = multierr.Combine(err, os.Remove(tmpPath))
+		}
+	}()
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return multierr.Combine(err, f.Close())
+	}
+	if err := w.Flush(); err != nil {
+		return multierr.Combine(err, f.Close())
+	}
+	if err := f.Sync(); err != nil {
+		return multierr.Combine(err, f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// RepairCaptureFiles repairs the in progress capture files under dir, and the completed ones too if
+// includeCompleted is true, returning the repairs made. Files which can't be repaired are skipped, and returned as
+// errors.
+//
+// In progress files must not be being written to, so dir must not be captured to while it is repaired.
+func RepairCaptureFiles(dir string, includeCompleted bool, opts ...CaptureFileOption) ([]CaptureFileRepair, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case InProgressCaptureFileExt:
+			if !inSequencesDir(path) {
+				paths = append(paths, path)
+			}
+		case CompletedCaptureFileExt:
+			if includeCompleted {
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var repairs []CaptureFileRepair
+	var errs []error
+	for _, path := range paths {
+		repair, repaired, err := RepairCaptureFile(path, opts...)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to repair %s", path))
+			continue
+		}
+		if repaired {
+			repairs = append(repairs, repair)
+		}
+	}
+	return repairs, multierr.Combine(errs...)
+}
+
+// inSequencesDir returns whether path is in the sequences directory of a capture directory.
+func inSequencesDir(path string) bool {
+	return filepath.Base(filepath.Dir(path)) == SequencesDir
+}
@@ -0,0 +1,161 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matttproud/golang_protobuf_extensions/pbutil"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func tabularSensorData(t *testing.T, value float64) *v1.SensorData {
+	t.Helper()
+	s, err := structpb.NewStruct(map[string]interface{}{"value": value})
+	test.That(t, err, test.ShouldBeNil)
+	return &v1.SensorData{Metadata: &v1.SensorMetadata{}, Data: &v1.SensorData_Struct{Struct: s}}
+}
+
+// writeInProgressCaptureFile writes n readings to an in progress capture file, as if the machine lost power before
+// it was closed, and returns its path.
+func writeInProgressCaptureFile(t *testing.T, dir string, n int) string {
+	t.Helper()
+	f, err := NewCaptureFile(dir, &v1.DataCaptureMetadata{ComponentName: "sensor1", Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR})
+	test.That(t, err, test.ShouldBeNil)
+	for i := 0; i < n; i++ {
+		test.That(t, f.WriteNext(tabularSensorData(t, float64(i))), test.ShouldBeNil)
+	}
+	test.That(t, f.Flush(), test.ShouldBeNil)
+	test.That(t, f.file.Close(), test.ShouldBeNil)
+	return f.GetPath()
+}
+
+func readValues(t *testing.T, path string, opts ...CaptureFileOption) []float64 {
+	t.Helper()
+	readings, err := SensorDataFromCaptureFilePath(path, opts...)
+	test.That(t, err, test.ShouldBeNil)
+	var values []float64
+	for _, reading := range readings {
+		values = append(values, reading.GetStruct().GetFields()["value"].GetNumberValue())
+	}
+	return values
+}
+
+func TestCaptureFileChecksums(t *testing.T) {
+	path := writeInProgressCaptureFile(t, t.TempDir(), 3)
+	contents, err := os.ReadFile(path)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, contents[:len(captureFileMagic)], test.ShouldResemble, captureFileMagic)
+
+	// flip a bit in the last byte, which is in the last record
+	contents[len(contents)-1] ^= 1
+	test.That(t, os.WriteFile(path, contents, 0o600), test.ShouldBeNil)
+	_, err = SensorDataFromCaptureFilePath(path)
+	test.That(t, err, test.ShouldBeError, ErrCorruptCaptureRecord)
+}
+
+func TestReadUnframedCaptureFile(t *testing.T) {
+	// capture files written before records had checksums are plain length delimited messages
+	path := filepath.Join(t.TempDir(), "old"+CompletedCaptureFileExt)
+	f, err := os.Create(path)
+	test.That(t, err, test.ShouldBeNil)
+	_, err = pbutil.WriteDelimited(f, &v1.DataCaptureMetadata{ComponentName: "sensor1"})
+	test.That(t, err, test.ShouldBeNil)
+	for i := 0; i < 2; i++ {
+		_, err = pbutil.WriteDelimited(f, tabularSensorData(t, float64(i)))
+		test.That(t, err, test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+	test.That(t, readValues(t, path), test.ShouldResemble, []float64{0, 1})
+}
+
+func TestRepairCaptureFile(t *testing.T) {
+	t.Run("completes an intact in progress file", func(t *testing.T) {
+		path := writeInProgressCaptureFile(t, t.TempDir(), 3)
+		repair, repaired, err := RepairCaptureFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, repaired, test.ShouldBeTrue)
+		completed := strings.TrimSuffix(path, InProgressCaptureFileExt) + CompletedCaptureFileExt
+		test.That(t, repair, test.ShouldResemble, CaptureFileRepair{Path: path, RepairedPath: completed, Records: 3})
+		test.That(t, readValues(t, completed), test.ShouldResemble, []float64{0, 1, 2})
+		_, err = os.Stat(path)
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	})
+
+	t.Run("salvages the records before a truncated one", func(t *testing.T) {
+		path := writeInProgressCaptureFile(t, t.TempDir(), 3)
+		info, err := os.Stat(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, os.Truncate(path, info.Size()-5), test.ShouldBeNil)
+
+		repair, repaired, err := RepairCaptureFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, repaired, test.ShouldBeTrue)
+		test.That(t, repair.Records, test.ShouldEqual, 2)
+		test.That(t, repair.DiscardedBytes, test.ShouldBeGreaterThan, 0)
+		test.That(t, readValues(t, repair.RepairedPath), test.ShouldResemble, []float64{0, 1})
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, matches, test.ShouldResemble, []string{repair.RepairedPath})
+	})
+
+	t.Run("removes a file without records", func(t *testing.T) {
+		path := writeInProgressCaptureFile(t, t.TempDir(), 0)
+		repair, repaired, err := RepairCaptureFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, repaired, test.ShouldBeTrue)
+		test.That(t, repair.RepairedPath, test.ShouldBeEmpty)
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, matches, test.ShouldBeEmpty)
+	})
+
+	t.Run("leaves intact completed files alone", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeInProgressCaptureFile(t, dir, 2)
+		_, _, err := RepairCaptureFile(path)
+		test.That(t, err, test.ShouldBeNil)
+
+		repairs, err := RepairCaptureFiles(dir, true)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, repairs, test.ShouldBeEmpty)
+	})
+}
+
+func TestRepairCaptureFiles(t *testing.T) {
+	dir := t.TempDir()
+	inProgress := writeInProgressCaptureFile(t, dir, 2)
+	corruptDir := filepath.Join(dir, "failed")
+	test.That(t, os.MkdirAll(corruptDir, 0o700), test.ShouldBeNil)
+	corrupt := writeInProgressCaptureFile(t, corruptDir, 2)
+	f, err := os.OpenFile(corrupt, os.O_APPEND|os.O_WRONLY, 0o600)
+	test.That(t, err, test.ShouldBeNil)
+	_, err = f.WriteString("invalid data")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+	completed := strings.TrimSuffix(corrupt, InProgressCaptureFileExt) + CompletedCaptureFileExt
+	test.That(t, os.Rename(corrupt, completed), test.ShouldBeNil)
+	unreadable := filepath.Join(dir, "unreadable"+InProgressCaptureFileExt)
+	test.That(t, os.WriteFile(unreadable, []byte{0xff, 'V', 'C', 'A', 'P', 1, 0xff}, 0o600), test.ShouldBeNil)
+
+	// only in progress files are repaired by default
+	repairs, err := RepairCaptureFiles(dir, false)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "unreadable")
+	test.That(t, repairs, test.ShouldHaveLength, 1)
+	test.That(t, repairs[0].Path, test.ShouldEqual, inProgress)
+
+	repairs, err = RepairCaptureFiles(dir, true)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, repairs, test.ShouldHaveLength, 1)
+	test.That(t, repairs[0], test.ShouldResemble, CaptureFileRepair{
+		Path:           completed,
+		RepairedPath:   completed,
+		Records:        2,
+		DiscardedBytes: int64(len("invalid data")),
+	})
+	test.That(t, readValues(t, completed), test.ShouldResemble, []float64{0, 1})
+}
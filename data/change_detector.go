@@ -0,0 +1,224 @@
+package data
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	// register the decoders of the image formats captured from cameras.
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"math/bits"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChangeDetector suppresses the samples of a collector which haven't changed meaningfully since the last sample it
+// captured. Samples are compared to the last one captured, rather than the last one taken, so that slow drifts are
+// still captured once they exceed the tolerance.
+//
+// Tabular samples change when a key is added or removed, a number changes by more than its key's tolerance, or any
+// other value changes. Keys of nested values are joined by dots and list elements by their index, e.g.
+// "readings.position.2".
+//
+// Binary samples change when their number of files changes, or any file changes. Images change when the Hamming
+// distance between the difference hashes of the images exceeds the configured distance, so that sensor noise and
+// compression artifacts don't count as changes. Other files change when their bytes do.
+type ChangeDetector struct {
+	defaultTolerance  float64
+	tolerances        map[string]float64
+	imageHashDistance int
+	maxSuppressed     time.Duration
+
+	mu           sync.Mutex
+	captured     bool
+	lastCaptured time.Time
+	lastValues   map[string]interface{}
+	lastFiles    []fileFingerprint
+
+	suppressed atomic.Int64
+}
+
+// fileFingerprint identifies the contents of a binary file: by its difference hash if it is an image and by its
+// checksum otherwise.
+type fileFingerprint struct {
+	image    bool
+	hash     uint64
+	checksum [sha256.Size]byte
+}
+
+// NewChangeDetector returns a ChangeDetector which tolerates numeric changes of up to defaultTolerance, or of up to
+// the value in tolerances for the keys in it, and image changes of up to imageHashDistance bits. When maxSuppressed
+// is positive a sample is captured once that long has passed since the last one, even if it hasn't changed.
+func NewChangeDetector(
+	defaultTolerance float64,
+	tolerances map[string]float64,
+	imageHashDistance int,
+	maxSuppressed time.Duration,
+) *ChangeDetector {
+	return &ChangeDetector{
+		defaultTolerance:  defaultTolerance,
+		tolerances:        tolerances,
+		imageHashDistance: imageHashDistance,
+		maxSuppressed:     maxSuppressed,
+	}
+}
+
+// Changed returns whether result changed from the last sample captured, in which case it becomes the sample later
+// samples are compared to. Otherwise the sample is counted as suppressed.
+func (d *ChangeDetector) Changed(result CaptureResult) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var values map[string]interface{}
+	var files []fileFingerprint
+	var changed bool
+	switch result.Type {
+	case CaptureTypeTabular:
+		values = map[string]interface{}{}
+		if result.TabularData.Payload != nil {
+			FlattenReading("", result.TabularData.Payload.AsMap(), values)
+		}
+		changed = d.tabularChanged(values)
+	case CaptureTypeBinary:
+		for _, binary := range result.Binaries {
+			files = append(files, fingerprint(binary))
+		}
+		changed = d.binaryChanged(files)
+	case CaptureTypeUnspecified:
+		changed = true
+	default:
+		changed = true
+	}
+
+	if !changed && d.maxSuppressed > 0 && result.TimeRequested.Sub(d.lastCaptured) >= d.maxSuppressed {
+		changed = true
+	}
+	if !changed {
+		d.suppressed.Add(1)
+		return false
+	}
+	d.captured = true
+	d.lastCaptured = result.TimeRequested
+	d.lastValues = values
+	d.lastFiles = files
+	return true
+}
+
+// Suppressed returns the number of samples which were suppressed as unchanged.
+func (d *ChangeDetector) Suppressed() int64 {
+	return d.suppressed.Load()
+}
+
+func (d *ChangeDetector) tolerance(key string) float64 {
+	if tolerance, ok := d.tolerances[key]; ok {
+		return tolerance
+	}
+	return d.defaultTolerance
+}
+
+func (d *ChangeDetector) tabularChanged(values map[string]interface{}) bool {
+	if !d.captured || len(values) != len(d.lastValues) {
+		return true
+	}
+	for key, value := range values {
+		last, ok := d.lastValues[key]
+		if !ok {
+			return true
+		}
+		number, isNumber := value.(float64)
+		lastNumber, lastIsNumber := last.(float64)
+		if isNumber && lastIsNumber {
+			if math.Abs(number-lastNumber) > d.tolerance(key) {
+				return true
+			}
+			continue
+		}
+		if !reflect.DeepEqual(value, last) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *ChangeDetector) binaryChanged(files []fileFingerprint) bool {
+	if !d.captured || len(files) != len(d.lastFiles) {
+		return true
+	}
+	for i, file := range files {
+		last := d.lastFiles[i]
+		if file.image != last.image {
+			return true
+		}
+		if file.image {
+			if bits.OnesCount64(file.hash^last.hash) > d.imageHashDistance {
+				return true
+			}
+			continue
+		}
+		if file.checksum != last.checksum {
+			return true
+		}
+	}
+	return false
+}
+
+func fingerprint(binary Binary) fileFingerprint {
+	if strings.HasPrefix(binary.MimeType, "image/") {
+		if img, _, err := image.Decode(bytes.NewReader(binary.Payload)); err == nil {
+			return fileFingerprint{image: true, hash: differenceHash(img)}
+		}
+	}
+	return fileFingerprint{checksum: sha256.Sum256(binary.Payload)}
+}
+
+// differenceHash returns the 64 bit difference hash of img: each bit is whether the mean luminance of a cell in a
+// 9x8 grid over the image is lower than that of the cell to its right. Similar images have hashes which differ in
+// few bits.
+func differenceHash(img image.Image) uint64 {
+	const cols, rows = 9, 8
+	b := img.Bounds()
+	var luminance [rows][cols]float64
+	for y := 0; y < rows; y++ {
+		y0 := b.Min.Y + y*b.Dy()/rows
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/rows, y0+1)
+		for x := 0; x < cols; x++ {
+			x0 := b.Min.X + x*b.Dx()/cols
+			x1 := max(b.Min.X+(x+1)*b.Dx()/cols, x0+1)
+			luminance[y][x] = meanLuminance(img, image.Rect(x0, y0, x1, y1).Intersect(b))
+		}
+	}
+	var hash uint64
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols-1; x++ {
+			hash <<= 1
+			if luminance[y][x] < luminance[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// meanLuminance returns the mean luminance of up to 8x8 pixels sampled evenly from r.
+func meanLuminance(img image.Image, r image.Rectangle) float64 {
+	const samples = 8
+	stepX := max(r.Dx()/samples, 1)
+	stepY := max(r.Dy()/samples, 1)
+	var sum float64
+	var n int
+	for y := r.Min.Y; y < r.Max.Y; y += stepY {
+		for x := r.Min.X; x < r.Max.X; x += stepX {
+			red, green, blue, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(red) + 0.587*float64(green) + 0.114*float64(blue)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
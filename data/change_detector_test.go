@@ -0,0 +1,142 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"go.viam.com/utils/testutils"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.viam.com/rdk/logging"
+)
+
+func tabularResult(t *testing.T, at time.Time, readings map[string]interface{}) CaptureResult {
+	t.Helper()
+	s, err := structpb.NewStruct(map[string]interface{}{"readings": readings})
+	test.That(t, err, test.ShouldBeNil)
+	return CaptureResult{
+		Timestamps:  Timestamps{TimeRequested: at, TimeReceived: at},
+		Type:        CaptureTypeTabular,
+		TabularData: TabularData{s},
+	}
+}
+
+func TestChangeDetectorTabular(t *testing.T) {
+	d := NewChangeDetector(0.5, map[string]float64{"readings.temperature": 2}, 0, 0)
+	changed := func(readings map[string]interface{}) bool {
+		return d.Changed(tabularResult(t, dummyTime, readings))
+	}
+	test.That(t, changed(map[string]interface{}{"temperature": 20, "humidity": 40, "state": "ok"}), test.ShouldBeTrue)
+	// within the tolerances of each key
+	test.That(t, changed(map[string]interface{}{"temperature": 21.5, "humidity": 40.4, "state": "ok"}), test.ShouldBeFalse)
+	// readings are compared to the last one captured, so drifts add up
+	test.That(t, changed(map[string]interface{}{"temperature": 22.5, "humidity": 40, "state": "ok"}), test.ShouldBeTrue)
+	test.That(t, changed(map[string]interface{}{"temperature": 22.5, "humidity": 40.6, "state": "ok"}), test.ShouldBeTrue)
+	test.That(t, changed(map[string]interface{}{"temperature": 22.5, "humidity": 40.6, "state": "warn"}), test.ShouldBeTrue)
+	test.That(t, changed(map[string]interface{}{"temperature": 22.5, "humidity": 40.6}), test.ShouldBeTrue)
+	test.That(t, changed(map[string]interface{}{"temperature": 22.5, "humidity": 40.6}), test.ShouldBeFalse)
+	test.That(t, d.Suppressed(), test.ShouldEqual, 2)
+}
+
+func TestChangeDetectorMaxSuppressed(t *testing.T) {
+	d := NewChangeDetector(0, nil, 0, time.Minute)
+	reading := map[string]interface{}{"temperature": 20}
+	test.That(t, d.Changed(tabularResult(t, dummyTime, reading)), test.ShouldBeTrue)
+	test.That(t, d.Changed(tabularResult(t, dummyTime.Add(59*time.Second), reading)), test.ShouldBeFalse)
+	test.That(t, d.Changed(tabularResult(t, dummyTime.Add(time.Minute), reading)), test.ShouldBeTrue)
+	test.That(t, d.Changed(tabularResult(t, dummyTime.Add(90*time.Second), reading)), test.ShouldBeFalse)
+}
+
+// gradientPNG returns a PNG of a horizontal gradient, brightened by offset, and inverted if invert is true.
+func gradientPNG(t *testing.T, offset uint8, invert bool) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 90, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 90; x++ {
+			v := uint8(x*2) + offset
+			if invert {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{v})
+		}
+	}
+	var buf bytes.Buffer
+	test.That(t, png.Encode(&buf, img), test.ShouldBeNil)
+	return buf.Bytes()
+}
+
+func TestChangeDetectorBinary(t *testing.T) {
+	d := NewChangeDetector(0, nil, 4, 0)
+	changed := func(binaries ...Binary) bool {
+		return d.Changed(NewBinaryCaptureResult(Timestamps{TimeRequested: dummyTime}, binaries))
+	}
+	img := Binary{Payload: gradientPNG(t, 0, false), MimeType: "image/png"}
+	brighter := Binary{Payload: gradientPNG(t, 10, false), MimeType: "image/png"}
+	inverted := Binary{Payload: gradientPNG(t, 0, true), MimeType: "image/png"}
+	pcd := Binary{Payload: []byte("points"), MimeType: "pointcloud/pcd"}
+
+	test.That(t, changed(img, pcd), test.ShouldBeTrue)
+	// the brighter image has the same difference hash
+	test.That(t, changed(brighter, pcd), test.ShouldBeFalse)
+	test.That(t, changed(inverted, pcd), test.ShouldBeTrue)
+	test.That(t, changed(inverted, Binary{Payload: []byte("other points"), MimeType: "pointcloud/pcd"}), test.ShouldBeTrue)
+	test.That(t, changed(inverted), test.ShouldBeTrue)
+	test.That(t, d.Suppressed(), test.ShouldEqual, 1)
+}
+
+func TestCollectorChangeDetection(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	tmpDir := t.TempDir()
+	target := newSignalingBuffer(ctx, tmpDir)
+	mockClock := clock.NewMock()
+	interval := timerCaptureCutoff + 1
+	changeDetector := NewChangeDetector(0, nil, 0, 0)
+	c, err := NewCollector(structCapturer, CollectorParams{
+		DataType:       CaptureTypeTabular,
+		ComponentName:  "testComponent",
+		Interval:       interval,
+		MethodParams:   map[string]*anypb.Any{"name": fakeVal},
+		Target:         target,
+		QueueSize:      queueSize,
+		BufferSize:     bufferSize,
+		Logger:         logging.NewTestLogger(t),
+		Clock:          mockClock,
+		ChangeDetector: changeDetector,
+	})
+	test.That(t, err, test.ShouldBeNil)
+	c.Collect()
+
+	mockClock.Add(interval)
+	select {
+	case <-ctx.Done():
+		t.Fatalf("timed out waiting for data to be written")
+	case <-target.wrote:
+	}
+	// the same reading is captured every interval after the first, and suppressed
+	for i := 1; i <= 2; i++ {
+		mockClock.Add(interval)
+		testutils.WaitForAssertion(t, func(tb testing.TB) {
+			test.That(tb, changeDetector.Suppressed(), test.ShouldEqual, i)
+		})
+	}
+	c.Close()
+
+	var readings []*v1.SensorData
+	for _, file := range getAllFiles(tmpDir) {
+		fileReadings, err := SensorDataFromCaptureFilePath(filepath.Join(tmpDir, file.Name()))
+		test.That(t, err, test.ShouldBeNil)
+		readings = append(readings, fileReadings...)
+	}
+	test.That(t, readings, test.ShouldHaveLength, 1)
+}
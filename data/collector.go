@@ -82,6 +82,7 @@ type collector struct {
 	target           CaptureBufferedWriter
 	lastLoggedErrors map[string]int64
 	dataType         CaptureType
+	changeDetector   *ChangeDetector
 }
 
 // Close closes the channels backing the Collector. It should always be called before disposing of a Collector to avoid
@@ -228,6 +229,10 @@ func (c *collector) getAndPushNextReading() {
 		return
 	}
 
+	if c.changeDetector != nil && !c.changeDetector.Changed(result) {
+		return
+	}
+
 	select {
 	// If c.captureResults is full, c.captureResults <- a can block indefinitely.
 	// This additional select block allows cancel to
@@ -268,6 +273,7 @@ func NewCollector(captureFunc CaptureFunc, params CollectorParams) (Collector, e
 		target:           params.Target,
 		clock:            c,
 		lastLoggedErrors: make(map[string]int64, 0),
+		changeDetector:   params.ChangeDetector,
 	}, nil
 }
 
@@ -0,0 +1,136 @@
+// Package convert converts the capture files of a capture directory into formats for offline analysis: Parquet files of
+// tabular readings, an MCAP recording of every reading, and folders of the files in binary readings.
+package convert
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	v1 "go.viam.com/api/app/datasync/v1"
+
+	"go.viam.com/rdk/data"
+)
+
+// Format is a format capture files can be converted to.
+type Format string
+
+const (
+	// FormatParquet writes a Parquet file of the tabular readings of each resource and method.
+	FormatParquet Format = "parquet"
+	// FormatMCAP writes an MCAP file with a channel for each resource and method.
+	FormatMCAP Format = "mcap"
+	// FormatFiles writes the file in each binary reading, such as images and point clouds, with a JSON index.
+	FormatFiles Format = "files"
+)
+
+// Formats are the formats capture files can be converted to.
+var Formats = []Format{FormatParquet, FormatMCAP, FormatFiles}
+
+// Summary describes what a conversion read and wrote.
+type Summary struct {
+	CaptureFiles int
+	Readings     int
+	// Outputs are the paths of the files written.
+	Outputs []string
+}
+
+// CaptureDir converts the completed capture files in captureDir to format, writing the results to dst. For
+// FormatParquet and FormatFiles dst is a directory; for FormatMCAP it is the path of the MCAP file. Capture files are
+// read with opts.
+func CaptureDir(ctx context.Context, captureDir, dst string, format Format, opts ...data.CaptureFileOption) (Summary, error) {
+	switch format {
+	case FormatParquet:
+		return ToParquet(ctx, captureDir, dst, opts...)
+	case FormatMCAP:
+		return ToMCAP(ctx, captureDir, dst, opts...)
+	case FormatFiles:
+		return ToFiles(ctx, captureDir, dst, opts...)
+	default:
+		return Summary{}, errors.Errorf("unknown format %q, expected one of %v", format, Formats)
+	}
+}
+
+// captureFile is the metadata and readings of a capture file.
+type captureFile struct {
+	path     string
+	md       *v1.DataCaptureMetadata
+	readings []*v1.SensorData
+}
+
+// resourceMethod identifies the resource and method a capture file was captured from.
+type resourceMethod struct {
+	componentType string
+	componentName string
+	method        string
+}
+
+func (f captureFile) resourceMethod() resourceMethod {
+	return resourceMethod{
+		componentType: f.md.GetComponentType(),
+		componentName: f.md.GetComponentName(),
+		method:        f.md.GetMethodName(),
+	}
+}
+
+// dir returns the directory under root of the files converted from the resource and method, laid out like the capture
+// directory.
+func (rm resourceMethod) dir(root string) string {
+	return data.CaptureFilePathWithReplacedReservedChars(filepath.Join(root, rm.componentType, rm.componentName, rm.method))
+}
+
+// forEachCaptureFile calls fn with each completed capture file in captureDir, in the order of their paths, which within
+// a collector's directory is the order they were captured in. Files still being written are skipped.
+func forEachCaptureFile(
+	ctx context.Context, captureDir string, opts []data.CaptureFileOption, fn func(captureFile) error,
+) (int, error) {
+	var paths []string
+	err := filepath.WalkDir(captureDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == data.CompletedCaptureFileExt {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		f, err := readCaptureFile(path, opts)
+		if err != nil {
+			return 0, err
+		}
+		if err := fn(f); err != nil {
+			return 0, err
+		}
+	}
+	return len(paths), nil
+}
+
+func readCaptureFile(path string, opts []data.CaptureFileOption) (captureFile, error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return captureFile{}, err
+	}
+	defer f.Close() //nolint:errcheck
+	dcFile, err := data.ReadCaptureFile(f, opts...)
+	if err != nil {
+		return captureFile{}, err
+	}
+	readings, err := data.SensorDataFromCaptureFile(dcFile)
+	if err != nil {
+		return captureFile{}, errors.Wrapf(err, "failed to read %s", path)
+	}
+	return captureFile{path: path, md: dcFile.ReadMetadata(), readings: readings}, nil
+}
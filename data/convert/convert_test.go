@@ -0,0 +1,221 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/foxglove/mcap/go/mcap"
+	"github.com/parquet-go/parquet-go"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"go.viam.com/rdk/data"
+)
+
+var t0 = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func metadataAt(at time.Time, mimeType v1.MimeType) *v1.SensorMetadata {
+	return &v1.SensorMetadata{
+		TimeRequested: timestamppb.New(at),
+		TimeReceived:  timestamppb.New(at.Add(time.Millisecond)),
+		MimeType:      mimeType,
+	}
+}
+
+func writeCaptureFile(t *testing.T, captureDir string, md *v1.DataCaptureMetadata, readings []*v1.SensorData) {
+	t.Helper()
+	dir := data.CaptureFilePathWithReplacedReservedChars(filepath.Join(captureDir, md.ComponentType, md.ComponentName, md.MethodName))
+	test.That(t, os.MkdirAll(dir, 0o700), test.ShouldBeNil)
+	f, err := data.NewCaptureFile(dir, md)
+	test.That(t, err, test.ShouldBeNil)
+	for _, reading := range readings {
+		test.That(t, f.WriteNext(reading), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+}
+
+// newCaptureDir returns a capture directory with three sensor readings and two images.
+func newCaptureDir(t *testing.T) string {
+	t.Helper()
+	captureDir := t.TempDir()
+	var readings []*v1.SensorData
+	for i, state := range []interface{}{"open", true, "closed"} {
+		s, err := structpb.NewStruct(map[string]interface{}{"readings": map[string]interface{}{
+			"temperature": 20 + i,
+			"position":    []interface{}{0, i},
+			"state":       state,
+		}})
+		test.That(t, err, test.ShouldBeNil)
+		readings = append(readings, &v1.SensorData{
+			Metadata: metadataAt(t0.Add(time.Duration(i)*time.Second), v1.MimeType_MIME_TYPE_UNSPECIFIED),
+			Data:     &v1.SensorData_Struct{Struct: s},
+		})
+	}
+	writeCaptureFile(t, captureDir, &v1.DataCaptureMetadata{
+		ComponentType: "rdk:component:sensor",
+		ComponentName: "thermometer",
+		MethodName:    "Readings",
+		Type:          v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+		FileExtension: data.ExtDat,
+	}, readings)
+
+	writeCaptureFile(t, captureDir, &v1.DataCaptureMetadata{
+		ComponentType: "rdk:component:camera",
+		ComponentName: "cam",
+		MethodName:    "GetImages",
+		Type:          v1.DataType_DATA_TYPE_BINARY_SENSOR,
+		Tags:          []string{"yard"},
+	}, []*v1.SensorData{
+		{Metadata: metadataAt(t0, v1.MimeType_MIME_TYPE_IMAGE_JPEG), Data: &v1.SensorData_Binary{Binary: []byte("jpeg")}},
+		{Metadata: metadataAt(t0, v1.MimeType_MIME_TYPE_IMAGE_JPEG), Data: &v1.SensorData_Binary{Binary: []byte("second")}},
+	})
+	return captureDir
+}
+
+func readParquet(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	//nolint:gosec
+	f, err := os.Open(path)
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	r := parquet.NewReader(f)
+	columns := r.Schema().Columns()
+	var rows []map[string]interface{}
+	buf := make([]parquet.Row, 1)
+	for {
+		n, err := r.ReadRows(buf)
+		if n == 0 {
+			test.That(t, err, test.ShouldEqual, io.EOF)
+			return rows
+		}
+		row := map[string]interface{}{}
+		for _, value := range buf[0] {
+			name := columns[value.Column()][0]
+			switch {
+			case value.IsNull():
+			case value.Kind() == parquet.Double:
+				row[name] = value.Double()
+			case value.Kind() == parquet.Boolean:
+				row[name] = value.Boolean()
+			case value.Kind() == parquet.Int64:
+				row[name] = time.Unix(0, value.Int64()).UTC()
+			default:
+				row[name] = string(value.ByteArray())
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestToParquet(t *testing.T) {
+	captureDir := newCaptureDir(t)
+	dst := t.TempDir()
+	summary, err := CaptureDir(context.Background(), captureDir, dst, FormatParquet)
+	test.That(t, err, test.ShouldBeNil)
+	path := filepath.Join(dst, "rdk_component_sensor", "thermometer", "Readings.parquet")
+	test.That(t, summary, test.ShouldResemble, Summary{CaptureFiles: 2, Readings: 3, Outputs: []string{path}})
+
+	rows := readParquet(t, path)
+	test.That(t, rows, test.ShouldHaveLength, 3)
+	test.That(t, rows[1], test.ShouldResemble, map[string]interface{}{
+		"time_requested":       t0.Add(time.Second),
+		"time_received":        t0.Add(time.Second + time.Millisecond),
+		"readings.temperature": 21.,
+		"readings.position.0":  0.,
+		"readings.position.1":  1.,
+		// state is a string in the other readings, so the bool is JSON encoded
+		"readings.state": "true",
+	})
+	test.That(t, rows[2]["readings.state"], test.ShouldEqual, "closed")
+}
+
+func TestToMCAP(t *testing.T) {
+	captureDir := newCaptureDir(t)
+	dst := filepath.Join(t.TempDir(), "capture.mcap")
+	summary, err := ToMCAP(context.Background(), captureDir, dst)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, summary, test.ShouldResemble, Summary{CaptureFiles: 2, Readings: 5, Outputs: []string{dst}})
+
+	//nolint:gosec
+	f, err := os.Open(dst)
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	r, err := mcap.NewReader(f)
+	test.That(t, err, test.ShouldBeNil)
+	it, err := r.Messages(mcap.UsingIndex(true))
+	test.That(t, err, test.ShouldBeNil)
+	byTopic := map[string][]*mcap.Message{}
+	err = mcap.Range(it, func(schema *mcap.Schema, channel *mcap.Channel, message *mcap.Message) error {
+		if channel.Topic == "/rdk:component:camera/cam/GetImages" {
+			test.That(t, schema.Name, test.ShouldEqual, "viam.app.datasync.v1.SensorData")
+			test.That(t, channel.MessageEncoding, test.ShouldEqual, "protobuf")
+		} else {
+			test.That(t, schema, test.ShouldBeNil)
+			test.That(t, channel.MessageEncoding, test.ShouldEqual, "json")
+		}
+		byTopic[channel.Topic] = append(byTopic[channel.Topic], message)
+		return nil
+	})
+	test.That(t, err, test.ShouldBeNil)
+
+	readings := byTopic["/rdk:component:sensor/thermometer/Readings"]
+	test.That(t, readings, test.ShouldHaveLength, 3)
+	test.That(t, readings[0].PublishTime, test.ShouldEqual, uint64(t0.UnixNano()))
+	test.That(t, readings[0].LogTime, test.ShouldEqual, uint64(t0.Add(time.Millisecond).UnixNano()))
+	var reading map[string]interface{}
+	test.That(t, json.Unmarshal(readings[2].Data, &reading), test.ShouldBeNil)
+	test.That(t, reading["readings"].(map[string]interface{})["temperature"], test.ShouldEqual, 22)
+
+	images := byTopic["/rdk:component:camera/cam/GetImages"]
+	test.That(t, images, test.ShouldHaveLength, 2)
+	test.That(t, images[1].Sequence, test.ShouldEqual, 2)
+	var image v1.SensorData
+	test.That(t, proto.Unmarshal(images[1].Data, &image), test.ShouldBeNil)
+	test.That(t, image.GetBinary(), test.ShouldResemble, []byte("second"))
+	test.That(t, image.GetMetadata().GetMimeType(), test.ShouldEqual, v1.MimeType_MIME_TYPE_IMAGE_JPEG)
+}
+
+func TestToFiles(t *testing.T) {
+	captureDir := newCaptureDir(t)
+	dst := t.TempDir()
+	summary, err := ToFiles(context.Background(), captureDir, dst)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, summary.CaptureFiles, test.ShouldEqual, 2)
+	test.That(t, summary.Readings, test.ShouldEqual, 2)
+
+	//nolint:gosec
+	encoded, err := os.ReadFile(filepath.Join(dst, IndexFileName))
+	test.That(t, err, test.ShouldBeNil)
+	var index []IndexEntry
+	test.That(t, json.Unmarshal(encoded, &index), test.ShouldBeNil)
+	test.That(t, index, test.ShouldHaveLength, 2)
+	test.That(t, index[0], test.ShouldResemble, IndexEntry{
+		Path:          "rdk_component_camera/cam/GetImages/2026-01-01T12_00_00.000000000Z.jpeg",
+		ComponentType: "rdk:component:camera",
+		ComponentName: "cam",
+		Method:        "GetImages",
+		TimeRequested: t0,
+		TimeReceived:  t0.Add(time.Millisecond),
+		Tags:          []string{"yard"},
+	})
+	// both images were requested at the same time
+	test.That(t, index[1].Path, test.ShouldEqual, "rdk_component_camera/cam/GetImages/2026-01-01T12_00_00.000000000Z_1.jpeg")
+	for _, entry := range index {
+		test.That(t, summary.Outputs, test.ShouldContain, filepath.Join(dst, entry.Path))
+	}
+	//nolint:gosec
+	second, err := os.ReadFile(filepath.Join(dst, index[1].Path))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, second, test.ShouldResemble, []byte("second"))
+
+	_, err = CaptureDir(context.Background(), captureDir, dst, "csv")
+	test.That(t, err, test.ShouldBeError, `unknown format "csv", expected one of [parquet mcap files]`)
+}
@@ -0,0 +1,123 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"go.viam.com/rdk/data"
+)
+
+// IndexFileName is the name of the JSON index ToFiles writes alongside the files.
+const IndexFileName = "index.json"
+
+// IndexEntry describes a file written by ToFiles.
+type IndexEntry struct {
+	// Path is relative to the directory the files were written to.
+	Path          string    `json:"path"`
+	ComponentType string    `json:"component_type"`
+	ComponentName string    `json:"component_name"`
+	Method        string    `json:"method"`
+	TimeRequested time.Time `json:"time_requested"`
+	TimeReceived  time.Time `json:"time_received"`
+	Tags          []string  `json:"tags,omitempty"`
+	// Annotations are the bounding boxes and classifications of the reading, as JSON.
+	Annotations json.RawMessage `json:"annotations,omitempty"`
+}
+
+// ToFiles writes the file in each binary reading in captureDir, such as an image or point cloud, under dst at
+// <component type>/<component name>/<method>/<time requested><extension>. The files are listed in dst/index.json.
+func ToFiles(ctx context.Context, captureDir, dst string, opts ...data.CaptureFileOption) (Summary, error) {
+	var summary Summary
+	index := []IndexEntry{}
+	written := map[string]bool{}
+	files, err := forEachCaptureFile(ctx, captureDir, opts, func(f captureFile) error {
+		if f.md.GetType() == v1.DataType_DATA_TYPE_TABULAR_SENSOR {
+			return nil
+		}
+		dir := f.resourceMethod().dir(dst)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+		for _, reading := range f.readings {
+			md := reading.GetMetadata()
+			name := md.GetTimeRequested().AsTime().UTC().Format("2006-01-02T15:04:05.000000000Z")
+			path := data.CaptureFilePathWithReplacedReservedChars(filepath.Join(dir, name+binaryFileExt(f.md, md)))
+			// readings requested at the same time, like the images of GetImages, get a numbered suffix
+			for i := 1; written[path]; i++ {
+				path = data.CaptureFilePathWithReplacedReservedChars(
+					filepath.Join(dir, fmt.Sprintf("%s_%d%s", name, i, binaryFileExt(f.md, md))))
+			}
+			if err := os.WriteFile(path, reading.GetBinary(), 0o600); err != nil {
+				return err
+			}
+			written[path] = true
+			rel, err := filepath.Rel(dst, path)
+			if err != nil {
+				return err
+			}
+			entry := IndexEntry{
+				Path:          filepath.ToSlash(rel),
+				ComponentType: f.md.GetComponentType(),
+				ComponentName: f.md.GetComponentName(),
+				Method:        f.md.GetMethodName(),
+				TimeRequested: md.GetTimeRequested().AsTime(),
+				TimeReceived:  md.GetTimeReceived().AsTime(),
+				Tags:          f.md.GetTags(),
+			}
+			if annotations := md.GetAnnotations(); len(annotations.GetBboxes()) > 0 || len(annotations.GetClassifications()) > 0 {
+				marshaler := protojson.MarshalOptions{UseProtoNames: true}
+				if entry.Annotations, err = marshaler.Marshal(annotations); err != nil {
+					return err
+				}
+			}
+			index = append(index, entry)
+			summary.Outputs = append(summary.Outputs, path)
+			summary.Readings++
+		}
+		return nil
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+	summary.CaptureFiles = files
+
+	if err := os.MkdirAll(dst, 0o700); err != nil {
+		return Summary{}, err
+	}
+	encoded, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return Summary{}, err
+	}
+	indexPath := filepath.Join(dst, IndexFileName)
+	if err := os.WriteFile(indexPath, encoded, 0o600); err != nil {
+		return Summary{}, err
+	}
+	summary.Outputs = append(summary.Outputs, indexPath)
+	return summary, nil
+}
+
+// binaryFileExt returns the file extension of a binary reading, from its mime type if it has one and otherwise from
+// the extension of its capture file.
+func binaryFileExt(captureMD *v1.DataCaptureMetadata, md *v1.SensorMetadata) string {
+	switch md.GetMimeType() {
+	case v1.MimeType_MIME_TYPE_IMAGE_JPEG:
+		return data.ExtJpeg
+	case v1.MimeType_MIME_TYPE_IMAGE_PNG:
+		return data.ExtPng
+	case v1.MimeType_MIME_TYPE_APPLICATION_PCD:
+		return data.ExtPcd
+	case v1.MimeType_MIME_TYPE_VIDEO_MP4:
+		return data.ExtMP4
+	case v1.MimeType_MIME_TYPE_UNSPECIFIED:
+		fallthrough
+	default:
+		return captureMD.GetFileExtension()
+	}
+}
@@ -0,0 +1,151 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/foxglove/mcap/go/mcap"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"go.viam.com/rdk/data"
+)
+
+const mcapProfile = "viam"
+
+// ToMCAP writes every reading in captureDir to an MCAP file at dst, with a channel for each resource and method whose
+// topic is /<component type>/<component name>/<method>. Messages are logged at the time the reading was received and
+// published at the time it was requested.
+//
+// Tabular readings are JSON encoded without a schema so their values can be plotted directly. Binary readings are
+// encoded as viam.app.datasync.v1.SensorData protobuf messages, which keep their mime type and annotations.
+func ToMCAP(ctx context.Context, captureDir, dst string, opts ...data.CaptureFileOption) (summary Summary, err error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+		return Summary{}, err
+	}
+	//nolint:gosec
+	f, err := os.Create(dst)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer func() {
+		err = multierr.Combine(err, f.Close())
+	}()
+	w, err := mcap.NewWriter(f, &mcap.WriterOptions{
+		Chunked:     true,
+		ChunkSize:   4 << 20,
+		Compression: mcap.CompressionZSTD,
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+	if err := w.WriteHeader(&mcap.Header{Profile: mcapProfile}); err != nil {
+		return Summary{}, err
+	}
+
+	var sensorDataSchema uint16
+	channels := map[resourceMethod]*mcap.Channel{}
+	sequences := map[uint16]uint32{}
+	files, err := forEachCaptureFile(ctx, captureDir, opts, func(f captureFile) error {
+		binary := f.md.GetType() != v1.DataType_DATA_TYPE_TABULAR_SENSOR
+		channel, ok := channels[f.resourceMethod()]
+		if !ok {
+			channel = &mcap.Channel{
+				ID:              uint16(len(channels)),
+				Topic:           "/" + f.md.GetComponentType() + "/" + f.md.GetComponentName() + "/" + f.md.GetMethodName(),
+				MessageEncoding: "json",
+				Metadata: map[string]string{
+					"component_type": f.md.GetComponentType(),
+					"component_name": f.md.GetComponentName(),
+					"method":         f.md.GetMethodName(),
+				},
+			}
+			if binary {
+				if sensorDataSchema == 0 {
+					sensorDataSchema = 1
+					if err := writeSensorDataSchema(w, sensorDataSchema); err != nil {
+						return err
+					}
+				}
+				channel.SchemaID = sensorDataSchema
+				channel.MessageEncoding = "protobuf"
+			}
+			if err := w.WriteChannel(channel); err != nil {
+				return err
+			}
+			channels[f.resourceMethod()] = channel
+		}
+
+		for _, reading := range f.readings {
+			var payload []byte
+			var err error
+			if binary {
+				payload, err = proto.Marshal(reading)
+			} else {
+				payload, err = json.Marshal(reading.GetStruct().AsMap())
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to encode a reading of %s", f.path)
+			}
+			sequences[channel.ID]++
+			if err := w.WriteMessage(&mcap.Message{
+				ChannelID:   channel.ID,
+				Sequence:    sequences[channel.ID],
+				LogTime:     uint64(reading.GetMetadata().GetTimeReceived().AsTime().UnixNano()),
+				PublishTime: uint64(reading.GetMetadata().GetTimeRequested().AsTime().UnixNano()),
+				Data:        payload,
+			}); err != nil {
+				return err
+			}
+			summary.Readings++
+		}
+		return nil
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+	if err := w.Close(); err != nil {
+		return Summary{}, err
+	}
+	summary.CaptureFiles = files
+	summary.Outputs = []string{dst}
+	return summary, nil
+}
+
+// writeSensorDataSchema writes the protobuf schema of SensorData, a FileDescriptorSet of the file that defines it and
+// every file it depends on.
+func writeSensorDataSchema(w *mcap.Writer, id uint16) error {
+	descriptor := (&v1.SensorData{}).ProtoReflect().Descriptor()
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(descriptor.ParentFile())
+	schema, err := proto.Marshal(set)
+	if err != nil {
+		return err
+	}
+	return w.WriteSchema(&mcap.Schema{
+		ID:       id,
+		Name:     string(descriptor.FullName()),
+		Encoding: "protobuf",
+		Data:     schema,
+	})
+}
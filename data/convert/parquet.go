@@ -0,0 +1,189 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	v1 "go.viam.com/api/app/datasync/v1"
+
+	"go.viam.com/rdk/data"
+)
+
+const (
+	timeRequestedColumn = "time_requested"
+	timeReceivedColumn  = "time_received"
+)
+
+type columnKind int
+
+const (
+	columnNumber columnKind = iota
+	columnBool
+	columnString
+)
+
+// tabularTable is the flattened readings of a resource and method, and the columns they need.
+type tabularTable struct {
+	rows    []tabularRow
+	columns map[string]columnKind
+}
+
+type tabularRow struct {
+	md     *v1.SensorMetadata
+	values map[string]interface{}
+}
+
+// ToParquet writes the tabular readings in captureDir to a Parquet file for each resource and method under dst, at
+// <component type>/<component name>/<method>.parquet. Readings are flattened into a column per key, with nested keys
+// joined by dots and list elements by their index, e.g. "readings.position.2". A key whose values have different
+// types across readings is written as a string column, with the values JSON encoded.
+func ToParquet(ctx context.Context, captureDir, dst string, opts ...data.CaptureFileOption) (Summary, error) {
+	var summary Summary
+	tables := map[resourceMethod]*tabularTable{}
+	files, err := forEachCaptureFile(ctx, captureDir, opts, func(f captureFile) error {
+		if f.md.GetType() != v1.DataType_DATA_TYPE_TABULAR_SENSOR {
+			return nil
+		}
+		table, ok := tables[f.resourceMethod()]
+		if !ok {
+			table = &tabularTable{columns: map[string]columnKind{}}
+			tables[f.resourceMethod()] = table
+		}
+		for _, reading := range f.readings {
+			row := tabularRow{md: reading.GetMetadata(), values: map[string]interface{}{}}
+			data.FlattenReading("", reading.GetStruct().AsMap(), row.values)
+			for key, value := range row.values {
+				kind := kindOf(value)
+				if existing, ok := table.columns[key]; ok && existing != kind {
+					kind = columnString
+				}
+				table.columns[key] = kind
+			}
+			table.rows = append(table.rows, row)
+			summary.Readings++
+		}
+		return nil
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+	summary.CaptureFiles = files
+
+	for rm, table := range tables {
+		path := rm.dir(dst) + ".parquet"
+		if err := writeParquet(path, table); err != nil {
+			return Summary{}, errors.Wrapf(err, "failed to write %s", path)
+		}
+		summary.Outputs = append(summary.Outputs, path)
+	}
+	sort.Strings(summary.Outputs)
+	return summary, nil
+}
+
+func writeParquet(path string, table *tabularTable) (err error) {
+	group := parquet.Group{
+		timeRequestedColumn: parquet.Optional(parquet.Timestamp(parquet.Nanosecond)),
+		timeReceivedColumn:  parquet.Optional(parquet.Timestamp(parquet.Nanosecond)),
+	}
+	for key, kind := range table.columns {
+		if _, ok := group[key]; ok {
+			// the time columns take precedence over a reading with the same key
+			continue
+		}
+		switch kind {
+		case columnNumber:
+			group[key] = parquet.Optional(parquet.Leaf(parquet.DoubleType))
+		case columnBool:
+			group[key] = parquet.Optional(parquet.Leaf(parquet.BooleanType))
+		case columnString:
+			group[key] = parquet.Optional(parquet.String())
+		}
+	}
+	schema := parquet.NewSchema("readings", group)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	//nolint:gosec
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Combine(err, f.Close())
+	}()
+	w := parquet.NewWriter(f, schema)
+
+	columns := schema.Columns()
+	rows := make([]parquet.Row, 0, len(table.rows))
+	for _, r := range table.rows {
+		row := make(parquet.Row, len(columns))
+		for i, column := range columns {
+			// the schema is flat, so each column path is a single key
+			value, ok := parquetValue(column[0], r, group[column[0]])
+			if !ok {
+				row[i] = parquet.NullValue().Level(0, 0, i)
+				continue
+			}
+			row[i] = value.Level(0, 1, i)
+		}
+		rows = append(rows, row)
+	}
+	if _, err := w.WriteRows(rows); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// parquetValue returns the value of column in a row, converted to the type of the column's node.
+func parquetValue(column string, r tabularRow, node parquet.Node) (parquet.Value, bool) {
+	switch column {
+	case timeRequestedColumn:
+		if r.md.GetTimeRequested() == nil {
+			return parquet.Value{}, false
+		}
+		return parquet.Int64Value(r.md.GetTimeRequested().AsTime().UnixNano()), true
+	case timeReceivedColumn:
+		if r.md.GetTimeReceived() == nil {
+			return parquet.Value{}, false
+		}
+		return parquet.Int64Value(r.md.GetTimeReceived().AsTime().UnixNano()), true
+	}
+	value, ok := r.values[column]
+	if !ok {
+		return parquet.Value{}, false
+	}
+	switch node.Type().Kind() {
+	case parquet.Double:
+		return parquet.DoubleValue(value.(float64)), true
+	case parquet.Boolean:
+		return parquet.BooleanValue(value.(bool)), true
+	default:
+		if s, ok := value.(string); ok {
+			return parquet.ByteArrayValue([]byte(s)), true
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded = []byte(fmt.Sprint(value))
+		}
+		return parquet.ByteArrayValue(encoded), true
+	}
+}
+
+func kindOf(value interface{}) columnKind {
+	switch value.(type) {
+	case float64:
+		return columnNumber
+	case bool:
+		return columnBool
+	default:
+		return columnString
+	}
+}
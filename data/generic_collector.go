@@ -0,0 +1,81 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.viam.com/rdk/resource"
+)
+
+// MethodInvoker invokes the unary gRPC method of the resource of api named name, with args as its request, and
+// returns the response as JSON values.
+type MethodInvoker func(
+	ctx context.Context,
+	api resource.API,
+	name, method string,
+	args map[string]interface{},
+) (map[string]interface{}, error)
+
+// GenericCollectorConstructor returns the constructor of the collector of method for resources whose API has no
+// collector registered for it, such as resources of modular APIs. DoCommand is called with the payload under
+// "docommand_input" in the additional params, as registered DoCommand collectors do. Any other method is invoked
+// over gRPC by reflection, with the additional params as its request, and its response is captured as tabular data.
+func GenericCollectorConstructor(method string) CollectorConstructor {
+	if method == "DoCommand" {
+		return newGenericDoCommandCollector
+	}
+	return newMethodInvokerCollector
+}
+
+func newGenericDoCommandCollector(res interface{}, params CollectorParams) (Collector, error) {
+	r, ok := res.(resource.Resource)
+	if !ok {
+		return nil, errors.Errorf("expected a resource but got %T", res)
+	}
+	return NewCollector(NewDoCommandCaptureFunc(r, params), params)
+}
+
+func newMethodInvokerCollector(res interface{}, params CollectorParams) (Collector, error) {
+	r, ok := res.(resource.Resource)
+	if !ok {
+		return nil, errors.Errorf("expected a resource but got %T", res)
+	}
+	if params.MethodInvoker == nil {
+		return nil, errors.Errorf("no collector is registered for %s, and it can't be invoked over gRPC", params.MethodName)
+	}
+	if params.DataType != CaptureTypeTabular {
+		return nil, errors.Errorf("%s can only be captured as tabular data when invoked over gRPC", params.MethodName)
+	}
+	args := make(map[string]interface{}, len(params.MethodParams))
+	for key, value := range params.MethodParams {
+		arg, err := UnmarshalToValueOrString(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid additional param %s", key)
+		}
+		args[key] = arg
+	}
+
+	api := r.Name().API
+	cFunc := func(ctx context.Context, _ map[string]*anypb.Any) (CaptureResult, error) {
+		timeRequested := time.Now()
+		var result CaptureResult
+		response, err := params.MethodInvoker(ctx, api, params.ComponentName, params.MethodName, args)
+		if err != nil {
+			return result, NewFailedToReadError(params.ComponentName, params.MethodName, err)
+		}
+		payload, err := structpb.NewStruct(response)
+		if err != nil {
+			return result, err
+		}
+		return CaptureResult{
+			Timestamps:  Timestamps{TimeRequested: timeRequested, TimeReceived: time.Now()},
+			Type:        CaptureTypeTabular,
+			TabularData: TabularData{Payload: payload},
+		}, nil
+	}
+	return NewCollector(cFunc, params)
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+var gizmoAPI = resource.APINamespace("acme").WithComponentType("gizmo")
+
+// gizmo is a resource of a modular API, which has no collectors registered.
+type gizmo struct {
+	resource.Named
+	resource.AlwaysRebuild
+	resource.TriviallyCloseable
+}
+
+func (g *gizmo) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	return map[string]interface{}{"echo": cmd["command"]}, nil
+}
+
+func structParam(t *testing.T, m map[string]interface{}) *anypb.Any {
+	t.Helper()
+	value, err := structpb.NewValue(m)
+	test.That(t, err, test.ShouldBeNil)
+	param, err := anypb.New(value)
+	test.That(t, err, test.ShouldBeNil)
+	return param
+}
+
+// collectOnce runs the collector built by constructor until it captures a reading, and returns it.
+func collectOnce(t *testing.T, constructor CollectorConstructor, params CollectorParams) *v1.SensorData {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	tmpDir := t.TempDir()
+	target := newSignalingBuffer(ctx, tmpDir)
+	mockClock := clock.NewMock()
+	params.ComponentName = "gizmo1"
+	params.DataType = CaptureTypeTabular
+	params.Interval = timerCaptureCutoff + 1
+	params.Logger = logging.NewTestLogger(t)
+	params.Clock = mockClock
+	params.Target = target
+
+	c, err := constructor(&gizmo{Named: resource.NewName(gizmoAPI, "gizmo1").AsNamed()}, params)
+	test.That(t, err, test.ShouldBeNil)
+	c.Collect()
+	mockClock.Add(params.Interval)
+	select {
+	case <-ctx.Done():
+		t.Fatalf("timed out waiting for data to be written")
+	case <-target.wrote:
+	}
+	c.Close()
+
+	files := getAllFiles(tmpDir)
+	test.That(t, files, test.ShouldHaveLength, 1)
+	readings, err := SensorDataFromCaptureFilePath(filepath.Join(tmpDir, files[0].Name()))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readings, test.ShouldHaveLength, 1)
+	return readings[0]
+}
+
+func TestGenericCollector(t *testing.T) {
+	t.Run("DoCommand of a modular resource", func(t *testing.T) {
+		reading := collectOnce(t, GenericCollectorConstructor("DoCommand"), CollectorParams{
+			MethodName:   "DoCommand",
+			MethodParams: map[string]*anypb.Any{"docommand_input": structParam(t, map[string]interface{}{"command": "status"})},
+		})
+		test.That(t, reading.GetStruct().AsMap(), test.ShouldResemble, map[string]interface{}{
+			"docommand_output": map[string]interface{}{"echo": "status"},
+		})
+	})
+
+	t.Run("method invoked over gRPC", func(t *testing.T) {
+		var invokedAPI resource.API
+		var invokedName, invokedMethod string
+		var invokedArgs map[string]interface{}
+		invoker := func(
+			ctx context.Context, api resource.API, name, method string, args map[string]interface{},
+		) (map[string]interface{}, error) {
+			invokedAPI, invokedName, invokedMethod, invokedArgs = api, name, method, args
+			return map[string]interface{}{"position": 1.5, "state": map[string]interface{}{"moving": false}}, nil
+		}
+		reading := collectOnce(t, GenericCollectorConstructor("GetPosition"), CollectorParams{
+			MethodName: "GetPosition",
+			MethodParams: map[string]*anypb.Any{
+				"extra": structParam(t, map[string]interface{}{"units": "mm"}),
+			},
+			MethodInvoker: invoker,
+		})
+		test.That(t, reading.GetStruct().AsMap(), test.ShouldResemble, map[string]interface{}{
+			"position": 1.5,
+			"state":    map[string]interface{}{"moving": false},
+		})
+		test.That(t, invokedAPI, test.ShouldResemble, gizmoAPI)
+		test.That(t, invokedName, test.ShouldEqual, "gizmo1")
+		test.That(t, invokedMethod, test.ShouldEqual, "GetPosition")
+		test.That(t, invokedArgs, test.ShouldResemble, map[string]interface{}{"extra": map[string]interface{}{"units": "mm"}})
+	})
+
+	t.Run("methods can't be invoked without an invoker", func(t *testing.T) {
+		params := CollectorParams{
+			ComponentName: "gizmo1",
+			MethodName:    "GetPosition",
+			DataType:      CaptureTypeTabular,
+			Logger:        logging.NewTestLogger(t),
+			Target:        NewCaptureBuffer(t.TempDir(), nil, 50),
+		}
+		res := &gizmo{Named: resource.NewName(gizmoAPI, "gizmo1").AsNamed()}
+		_, err := GenericCollectorConstructor("GetPosition")(res, params)
+		test.That(t, err, test.ShouldBeError, "no collector is registered for GetPosition, and it can't be invoked over gRPC")
+
+		params.MethodInvoker = func(
+			context.Context, resource.API, string, string, map[string]interface{},
+		) (map[string]interface{}, error) {
+			return nil, nil
+		}
+		params.DataType = CaptureTypeBinary
+		_, err = GenericCollectorConstructor("GetPosition")(res, params)
+		test.That(t, err, test.ShouldBeError, "GetPosition can only be captured as tabular data when invoked over gRPC")
+	})
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"strconv"
 	"time"
 
 	"go.viam.com/rdk/utils"
@@ -14,3 +15,29 @@ func GetDurationFromHz(captureFrequencyHz float32) time.Duration {
 	}
 	return time.Duration(float32(time.Second) / captureFrequencyHz)
 }
+
+// FlattenReading adds the leaf values of a reading to out, keyed by their dot separated path from prefix, with list
+// elements keyed by their index. Nil values are left out.
+func FlattenReading(prefix string, value interface{}, out map[string]interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			FlattenReading(join(key), elem, out)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			FlattenReading(join(strconv.Itoa(i)), elem, out)
+		}
+	case nil:
+	default:
+		if prefix != "" {
+			out[prefix] = v
+		}
+	}
+}
@@ -15,3 +15,20 @@ func TestGetDurationFromHz(t *testing.T) {
 	test.That(t, GetDurationFromHz(0), test.ShouldEqual, 0)
 	test.That(t, GetDurationFromHz(1e-7), test.ShouldEqual, 0)
 }
+
+func TestFlattenReading(t *testing.T) {
+	out := map[string]interface{}{}
+	FlattenReading("", map[string]interface{}{
+		"position": map[string]interface{}{"x": 1., "y": 2.},
+		"joints":   []interface{}{0.5, true},
+		"name":     "arm",
+		"missing":  nil,
+	}, out)
+	test.That(t, out, test.ShouldResemble, map[string]interface{}{
+		"position.x": 1.,
+		"position.y": 2.,
+		"joints.0":   0.5,
+		"joints.1":   true,
+		"name":       "arm",
+	})
+}
@@ -33,6 +33,10 @@ type CollectorParams struct {
 	MongoCollection *mongo.Collection
 	QueueSize       int
 	Target          CaptureBufferedWriter
+	// ChangeDetector, when non nil, suppresses the samples which haven't changed since the last one captured.
+	ChangeDetector *ChangeDetector
+	// MethodInvoker, when non nil, invokes methods over gRPC for collectors from GenericCollectorConstructor.
+	MethodInvoker MethodInvoker
 }
 
 // Validate validates that p contains all required parameters.
@@ -11,6 +11,8 @@ type SequenceFile struct {
 	EndTime      time.Time          `json:"end_time"`
 	Resources    []SequenceResource `json:"resources"`
 	SequenceTags []string           `json:"sequence_tags,omitempty"`
+	// Trigger is set on sequences written by triggered capture.
+	Trigger *SequenceTrigger `json:"trigger,omitempty"`
 }
 
 // SequenceResource is the on-disk form of a resource/method pair in a sequence.
@@ -18,3 +20,16 @@ type SequenceResource struct {
 	ResourceName string `json:"resource_name"`
 	MethodName   string `json:"method_name"`
 }
+
+// SequenceTrigger is the on-disk form of the trigger which caused a triggered capture sequence.
+type SequenceTrigger struct {
+	Name string `json:"name"`
+	// Source is what fired the trigger: "sensor", "vision" or "do_command".
+	Source string `json:"source"`
+	// Reason describes why the trigger fired, such as the reading or detection which matched.
+	Reason string `json:"reason,omitempty"`
+	// Time is when the trigger first fired.
+	Time time.Time `json:"time"`
+	// Count is how many times the trigger fired; each firing extends the sequence.
+	Count int `json:"count"`
+}
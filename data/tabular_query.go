@@ -0,0 +1,241 @@
+package data
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "go.viam.com/api/app/datasync/v1"
+)
+
+// TabularQuery selects captured tabular readings and the value within them to aggregate.
+type TabularQuery struct {
+	// ResourceName and MethodName select the collector whose readings are queried. Both are required.
+	ResourceName string
+	MethodName   string
+	// Start and End bound the time the readings were requested, as [Start, End). A zero time leaves that side open.
+	Start time.Time
+	End   time.Time
+	// Path selects a numeric value in each reading, e.g. "readings.temperature" or "$.readings.position[2]".
+	// Readings without a number at Path are skipped.
+	Path string
+	// BucketSize splits the aggregates into buckets aligned to multiples of it. If zero, everything is one bucket.
+	BucketSize time.Duration
+}
+
+// TabularBucket holds the aggregates of the values of one bucket of a TabularQuery.
+type TabularBucket struct {
+	Start time.Time
+	Count int
+	Min   float64
+	Max   float64
+	Avg   float64
+}
+
+// TabularQueryResult is the result of a TabularQuery. Buckets without readings are left out.
+type TabularQueryResult struct {
+	Buckets []TabularBucket
+	// Readings is how many readings were in the time range, and Skipped how many of them had no number at the path.
+	Readings int
+	Skipped  int
+}
+
+type tabularPathElem struct {
+	key   string
+	index int
+}
+
+// QueryCapturedTabularData aggregates the tabular readings captured in captureDirs which match q. Capture files which
+// are still being written are read too, but readings a collector hasn't flushed to disk yet are not seen. A file in
+// more than one of the directories, such as when one is within another, is only read once. Files are read with opts.
+func QueryCapturedTabularData(
+	ctx context.Context, captureDirs []string, q TabularQuery, opts ...CaptureFileOption,
+) (TabularQueryResult, error) {
+	if q.ResourceName == "" || q.MethodName == "" {
+		return TabularQueryResult{}, errors.New("a resource name and method are required to query captured data")
+	}
+	if q.BucketSize < 0 {
+		return TabularQueryResult{}, errors.New("bucket size can't be negative")
+	}
+	path, err := parseTabularPath(q.Path)
+	if err != nil {
+		return TabularQueryResult{}, err
+	}
+
+	var result TabularQueryResult
+	buckets := map[time.Time]*TabularBucket{}
+	aggregate := func(reading *v1.SensorData) {
+		requested := reading.GetMetadata().GetTimeRequested().AsTime()
+		if (!q.Start.IsZero() && requested.Before(q.Start)) || (!q.End.IsZero() && !requested.Before(q.End)) {
+			return
+		}
+		result.Readings++
+		value, ok := tabularValue(reading.GetStruct().AsMap(), path)
+		if !ok {
+			result.Skipped++
+			return
+		}
+		var start time.Time
+		if q.BucketSize > 0 {
+			start = requested.Truncate(q.BucketSize)
+		}
+		b, ok := buckets[start]
+		if !ok {
+			b = &TabularBucket{Start: start, Min: value, Max: value}
+			buckets[start] = b
+		}
+		b.Count++
+		b.Min = math.Min(b.Min, value)
+		b.Max = math.Max(b.Max, value)
+		// Avg holds the sum until every reading is read
+		b.Avg += value
+	}
+
+	read := map[string]bool{}
+	for _, captureDir := range captureDirs {
+		err = filepath.WalkDir(captureDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// the capture directory may not exist yet, and sync deletes files as it uploads them
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ext := filepath.Ext(path)
+			if d.IsDir() || (ext != CompletedCaptureFileExt && ext != InProgressCaptureFileExt) {
+				return nil
+			}
+			if abs, err := filepath.Abs(path); err == nil {
+				if read[abs] {
+					return nil
+				}
+				read[abs] = true
+			}
+			return readTabularCaptureFile(path, q, aggregate, opts)
+		})
+		if err != nil {
+			return TabularQueryResult{}, err
+		}
+	}
+
+	for _, b := range buckets {
+		b.Avg /= float64(b.Count)
+		result.Buckets = append(result.Buckets, *b)
+	}
+	sort.Slice(result.Buckets, func(i, j int) bool { return result.Buckets[i].Start.Before(result.Buckets[j].Start) })
+	return result, nil
+}
+
+// readTabularCaptureFile calls aggregate with each reading in the capture file at path, if it is a tabular file of the
+// queried resource and method.
+func readTabularCaptureFile(path string, q TabularQuery, aggregate func(*v1.SensorData), opts []CaptureFileOption) error {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+	captureFile, err := ReadCaptureFile(f, opts...)
+	if err != nil {
+		if filepath.Ext(path) == InProgressCaptureFileExt {
+			// the collector may not have written the metadata yet
+			return nil
+		}
+		return err
+	}
+	md := captureFile.ReadMetadata()
+	if md.GetType() != v1.DataType_DATA_TYPE_TABULAR_SENSOR ||
+		md.GetComponentName() != q.ResourceName || md.GetMethodName() != q.MethodName {
+		return nil
+	}
+	for {
+		reading, err := captureFile.ReadNext()
+		if err != nil {
+			// the last reading of a file which is still being written may be incomplete
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || filepath.Ext(path) == InProgressCaptureFileExt {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+		aggregate(reading)
+	}
+}
+
+// parseTabularPath parses a dotted path with optional list indexes, like "$.readings.position[2]".
+func parseTabularPath(path string) ([]tabularPathElem, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, errors.New("a path to the value to aggregate is required")
+	}
+	var elems []tabularPathElem
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && rest == "" {
+			return nil, errors.Errorf("path %q has an empty key", path)
+		}
+		if key != "" {
+			elems = append(elems, tabularPathElem{key: key, index: -1})
+		}
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			i, err := strconv.Atoi(index)
+			if !ok || err != nil || i < 0 {
+				return nil, errors.Errorf("path %q has an invalid list index", path)
+			}
+			elems = append(elems, tabularPathElem{index: i})
+			if after == "" {
+				break
+			}
+			if !strings.HasPrefix(after, "[") {
+				return nil, errors.Errorf("path %q has an invalid list index", path)
+			}
+			rest = after[1:]
+		}
+	}
+	return elems, nil
+}
+
+// tabularValue returns the number at path in a reading.
+func tabularValue(reading map[string]interface{}, path []tabularPathElem) (float64, bool) {
+	var value interface{} = reading
+	for _, elem := range path {
+		if elem.index < 0 {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return 0, false
+			}
+			value = m[elem.key]
+			continue
+		}
+		l, ok := value.([]interface{})
+		if !ok || elem.index >= len(l) {
+			return 0, false
+		}
+		value = l[elem.index]
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func writeTabularCaptureFile(t *testing.T, dir, name, method string, complete bool, readings map[time.Time]map[string]interface{}) {
+	t.Helper()
+	test.That(t, os.MkdirAll(dir, 0o700), test.ShouldBeNil)
+	f, err := NewCaptureFile(dir, &v1.DataCaptureMetadata{
+		ComponentName: name,
+		MethodName:    method,
+		Type:          v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+	})
+	test.That(t, err, test.ShouldBeNil)
+	for at, reading := range readings {
+		s, err := structpb.NewStruct(reading)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, f.WriteNext(&v1.SensorData{
+			Metadata: &v1.SensorMetadata{TimeRequested: timestamppb.New(at), TimeReceived: timestamppb.New(at)},
+			Data:     &v1.SensorData_Struct{Struct: s},
+		}), test.ShouldBeNil)
+	}
+	if complete {
+		test.That(t, f.Close(), test.ShouldBeNil)
+		return
+	}
+	test.That(t, f.Flush(), test.ShouldBeNil)
+	t.Cleanup(func() { f.Close() })
+}
+
+func TestQueryCapturedTabularData(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	temperature := func(v interface{}) map[string]interface{} {
+		return map[string]interface{}{"readings": map[string]interface{}{"temperature": v, "position": []interface{}{0, 1, v}}}
+	}
+	writeTabularCaptureFile(t, filepath.Join(dir, "a"), "thermometer", "Readings", true, map[time.Time]map[string]interface{}{
+		t0:                       temperature(20),
+		t0.Add(10 * time.Second): temperature(22),
+		t0.Add(70 * time.Second): temperature(30),
+	})
+	// a file which is still being written is read too
+	writeTabularCaptureFile(t, filepath.Join(dir, "b"), "thermometer", "Readings", false, map[time.Time]map[string]interface{}{
+		t0.Add(80 * time.Second):  temperature(34),
+		t0.Add(90 * time.Second):  temperature("unknown"),
+		t0.Add(130 * time.Second): temperature(40),
+	})
+	writeTabularCaptureFile(t, filepath.Join(dir, "c"), "other", "Readings", true, map[time.Time]map[string]interface{}{
+		t0: temperature(100),
+	})
+
+	query := TabularQuery{
+		ResourceName: "thermometer",
+		MethodName:   "Readings",
+		Path:         "readings.temperature",
+		BucketSize:   time.Minute,
+	}
+	result, err := QueryCapturedTabularData(context.Background(), []string{dir}, query)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, result, test.ShouldResemble, TabularQueryResult{
+		Buckets: []TabularBucket{
+			{Start: t0, Count: 2, Min: 20, Max: 22, Avg: 21},
+			{Start: t0.Add(time.Minute), Count: 2, Min: 30, Max: 34, Avg: 32},
+			{Start: t0.Add(2 * time.Minute), Count: 1, Min: 40, Max: 40, Avg: 40},
+		},
+		Readings: 6,
+		Skipped:  1,
+	})
+
+	query.Start = t0.Add(10 * time.Second)
+	query.End = t0.Add(130 * time.Second)
+	query.BucketSize = 0
+	query.Path = "$.readings.position[2]"
+	result, err = QueryCapturedTabularData(context.Background(), []string{dir}, query)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, result.Buckets, test.ShouldResemble, []TabularBucket{{Count: 3, Min: 22, Max: 34, Avg: 86. / 3}})
+	test.That(t, result.Readings, test.ShouldEqual, 4)
+
+	// readings are found in every capture directory, and a file in two of them is only read once
+	other := t.TempDir()
+	writeTabularCaptureFile(t, filepath.Join(other, "d"), "thermometer", "Readings", true, map[time.Time]map[string]interface{}{
+		t0.Add(20 * time.Second): temperature(26),
+	})
+	result, err = QueryCapturedTabularData(context.Background(), []string{dir, other, filepath.Join(dir, "a")}, query)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, result.Buckets, test.ShouldResemble, []TabularBucket{{Count: 4, Min: 22, Max: 34, Avg: 112. / 4}})
+	test.That(t, result.Readings, test.ShouldEqual, 5)
+
+	// a capture directory which doesn't exist yet has no readings
+	result, err = QueryCapturedTabularData(context.Background(), []string{filepath.Join(dir, "missing")}, query)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, result, test.ShouldResemble, TabularQueryResult{})
+
+	query.Path = ""
+	_, err = QueryCapturedTabularData(context.Background(), []string{dir}, query)
+	test.That(t, err, test.ShouldBeError, "a path to the value to aggregate is required")
+}
+
+func TestParseTabularPath(t *testing.T) {
+	path, err := parseTabularPath("$.a.b[1][0]")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, path, test.ShouldResemble, []tabularPathElem{{key: "a", index: -1}, {key: "b", index: -1}, {index: 1}, {index: 0}})
+
+	for _, invalid := range []string{"a..b", "a[x]", "a[1", "a[1]b", "a[-1]"} {
+		_, err := parseTabularPath(invalid)
+		test.That(t, err, test.ShouldNotBeNil)
+	}
+}
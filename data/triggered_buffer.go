@@ -0,0 +1,171 @@
+package data
+
+import (
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// TriggeredBuffer is a CaptureBufferedWriter which holds readings in a bounded in-memory ring buffer, and only writes
+// those captured around a trigger to its target. Readings are timed by when they arrive at the buffer.
+//
+// The ring buffer is only held in memory. Readings still in it are not written by Flush, and are lost when the
+// buffer is discarded, e.g. when the collector closes or the process exits.
+type TriggeredBuffer struct {
+	target   CaptureBufferedWriter
+	clk      clock.Clock
+	pre      time.Duration
+	post     time.Duration
+	maxBytes int64
+
+	mu   sync.Mutex
+	ring []bufferedReading
+	size int64
+	// until is the end of the current trigger window; readings arriving before it are written to target.
+	until time.Time
+	// flushed is whether target has been flushed since the last trigger window ended.
+	flushed bool
+	dropped int64
+}
+
+type bufferedReading struct {
+	item     *v1.SensorData
+	mimeType string
+	binary   bool
+	at       time.Time
+	size     int64
+}
+
+// NewTriggeredBuffer returns a TriggeredBuffer which keeps readings from the last pre, writes them to target when
+// triggered, and then writes the readings which arrive within post of the trigger. At most maxBytes of readings are
+// held; the oldest are dropped to make room for new ones.
+func NewTriggeredBuffer(target CaptureBufferedWriter, clk clock.Clock, pre, post time.Duration, maxBytes int64) *TriggeredBuffer {
+	return &TriggeredBuffer{
+		target:   target,
+		clk:      clk,
+		pre:      pre,
+		post:     post,
+		maxBytes: maxBytes,
+		flushed:  true,
+	}
+}
+
+// WriteBinary buffers or writes a binary reading.
+func (b *TriggeredBuffer) WriteBinary(item *v1.SensorData, mimeType string) error {
+	if !IsBinary(item) {
+		return errInvalidBinarySensorData
+	}
+	return b.write(bufferedReading{item: item, mimeType: mimeType, binary: true})
+}
+
+// WriteTabular buffers or writes a tabular reading.
+func (b *TriggeredBuffer) WriteTabular(item *v1.SensorData) error {
+	if IsBinary(item) {
+		return errInvalidTabularSensorData
+	}
+	return b.write(bufferedReading{item: item})
+}
+
+func (b *TriggeredBuffer) write(r bufferedReading) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r.at = b.clk.Now()
+	if r.at.Before(b.until) {
+		return b.writeTarget(r)
+	}
+	if !b.flushed {
+		// the trigger window has ended, so complete the files it was written to
+		b.flushed = true
+		if err := b.target.Flush(); err != nil {
+			return err
+		}
+	}
+	r.size = int64(proto.Size(r.item))
+	b.ring = append(b.ring, r)
+	b.size += r.size
+	b.evict(r.at)
+	return nil
+}
+
+// evict drops readings which are too old to be in a trigger window, then the oldest readings until the buffer is
+// within its size limit. The caller must hold b.mu.
+func (b *TriggeredBuffer) evict(now time.Time) {
+	oldest := now.Add(-b.pre)
+	i := 0
+	for ; i < len(b.ring); i++ {
+		if !b.ring[i].at.Before(oldest) && b.size <= b.maxBytes {
+			break
+		}
+		b.size -= b.ring[i].size
+		if !b.ring[i].at.Before(oldest) {
+			b.dropped++
+		}
+	}
+	// clear the dropped entries so their readings can be garbage collected
+	clear(b.ring[:i])
+	b.ring = b.ring[i:]
+}
+
+func (b *TriggeredBuffer) writeTarget(r bufferedReading) error {
+	if r.binary {
+		return b.target.WriteBinary(r.item, r.mimeType)
+	}
+	return b.target.WriteTabular(r.item)
+}
+
+// Trigger writes the buffered readings which arrived within pre before at to the target, and makes readings which
+// arrive up to post after at be written too. A trigger during the window of an earlier one extends the window.
+// It returns the window of readings which are written.
+func (b *TriggeredBuffer) Trigger(at time.Time) (start, end time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start = at.Add(-b.pre)
+	for i, r := range b.ring {
+		if r.at.Before(start) {
+			continue
+		}
+		if err := b.writeTarget(r); err != nil {
+			// keep what hasn't been written so a later trigger can try again
+			clear(b.ring[:i])
+			b.ring = b.ring[i:]
+			b.size = 0
+			for _, r := range b.ring {
+				b.size += r.size
+			}
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	clear(b.ring)
+	b.ring = b.ring[:0]
+	b.size = 0
+	if end = at.Add(b.post); end.After(b.until) {
+		b.until = end
+	}
+	b.flushed = false
+	return start, b.until, nil
+}
+
+// Dropped returns how many readings were dropped from the buffer to keep it within its size limit.
+func (b *TriggeredBuffer) Dropped() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// Flush flushes the target. Readings in the ring buffer stay there.
+func (b *TriggeredBuffer) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.clk.Now().Before(b.until) {
+		b.flushed = true
+	}
+	return b.target.Flush()
+}
+
+// Path returns the path of the target.
+func (b *TriggeredBuffer) Path() string {
+	return b.target.Path()
+}
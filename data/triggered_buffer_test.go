@@ -0,0 +1,108 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// recordingWriter records what a TriggeredBuffer writes through to it.
+type recordingWriter struct {
+	written []float64
+	flushes int
+}
+
+func (w *recordingWriter) WriteBinary(item *v1.SensorData, mimeType string) error {
+	w.written = append(w.written, float64(len(item.GetBinary())))
+	return nil
+}
+
+func (w *recordingWriter) WriteTabular(item *v1.SensorData) error {
+	w.written = append(w.written, item.GetStruct().Fields["n"].GetNumberValue())
+	return nil
+}
+
+func (w *recordingWriter) Flush() error {
+	w.flushes++
+	return nil
+}
+
+func (w *recordingWriter) Path() string {
+	return "recording"
+}
+
+func tabularReading(n float64) *v1.SensorData {
+	return &v1.SensorData{
+		Metadata: &v1.SensorMetadata{},
+		Data: &v1.SensorData_Struct{Struct: &structpb.Struct{
+			Fields: map[string]*structpb.Value{"n": structpb.NewNumberValue(n)},
+		}},
+	}
+}
+
+func TestTriggeredBuffer(t *testing.T) {
+	clk := clock.NewMock()
+	target := &recordingWriter{}
+	b := NewTriggeredBuffer(target, clk, 3*time.Second, 2*time.Second, 1<<20)
+
+	// one reading a second; only the last three seconds are kept
+	for i := range 10 {
+		test.That(t, b.WriteTabular(tabularReading(float64(i))), test.ShouldBeNil)
+		clk.Add(time.Second)
+	}
+	test.That(t, target.written, test.ShouldBeEmpty)
+	// flushing, as sync does, doesn't persist the ring buffer
+	test.That(t, b.Flush(), test.ShouldBeNil)
+	test.That(t, target.written, test.ShouldBeEmpty)
+
+	// at 10s, readings from 7s are in the window
+	start, end, err := b.Trigger(clk.Now())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, start, test.ShouldEqual, clk.Now().Add(-3*time.Second))
+	test.That(t, end, test.ShouldEqual, clk.Now().Add(2*time.Second))
+	test.That(t, target.written, test.ShouldResemble, []float64{7, 8, 9})
+
+	// readings within two seconds of the trigger are written straight through
+	test.That(t, b.WriteTabular(tabularReading(10)), test.ShouldBeNil)
+	clk.Add(time.Second)
+	// triggering again extends the window
+	_, end, err = b.Trigger(clk.Now())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, end, test.ShouldEqual, clk.Now().Add(2*time.Second))
+	for i := 11; i < 15; i++ {
+		test.That(t, b.WriteTabular(tabularReading(float64(i))), test.ShouldBeNil)
+		clk.Add(time.Second)
+	}
+	test.That(t, target.written, test.ShouldResemble, []float64{7, 8, 9, 10, 11, 12})
+	// the first reading after the window completed the files written during it
+	test.That(t, target.flushes, test.ShouldEqual, 2)
+
+	// readings after the window are buffered again, so the next trigger writes them but not those already written
+	_, _, err = b.Trigger(clk.Now())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, target.written, test.ShouldResemble, []float64{7, 8, 9, 10, 11, 12, 13, 14})
+}
+
+func TestTriggeredBufferMaxBytes(t *testing.T) {
+	clk := clock.NewMock()
+	target := &recordingWriter{}
+	image := func(size int) *v1.SensorData {
+		return &v1.SensorData{Metadata: &v1.SensorMetadata{}, Data: &v1.SensorData_Binary{Binary: make([]byte, size)}}
+	}
+	// room for about two of the images
+	b := NewTriggeredBuffer(target, clk, time.Minute, 0, 250)
+	for _, size := range []int{100, 101, 102, 103} {
+		test.That(t, b.WriteBinary(image(size), "image/jpeg"), test.ShouldBeNil)
+		clk.Add(time.Millisecond)
+	}
+	test.That(t, b.Dropped(), test.ShouldEqual, 2)
+	test.That(t, b.WriteBinary(tabularReading(1), "image/jpeg"), test.ShouldBeError, errInvalidBinarySensorData)
+
+	_, _, err := b.Trigger(clk.Now())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, target.written, test.ShouldResemble, []float64{102, 103})
+}
@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/pkg/errors"
+	"go.viam.com/utils/rpc"
+	"google.golang.org/grpc/metadata"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	"go.viam.com/rdk/resource"
+)
+
+// apiServiceIndex is the index of the resource type in the dot separated name of the gRPC service of an API. For
+// example, viam.component.movementsensor.v1.MovementSensorService has movementsensor as its second index.
+const apiServiceIndex = 2
+
+// ResourceReflection invokes the unary gRPC methods of the resources of an API by name, finding the service of the
+// API and the request and response types of its methods by server reflection. Descriptors are cached, so one
+// ResourceReflection should be used for repeated invocations.
+type ResourceReflection struct {
+	conn    rpc.ClientConn
+	client  *grpcreflect.Client
+	source  grpcurl.DescriptorSource
+	service string
+}
+
+// NewResourceReflection looks up the gRPC service of api on conn, which must serve reflection, such as a connection
+// to the module server of a robot. Reflection requests are made with ctx, which must outlive the
+// ResourceReflection.
+func NewResourceReflection(ctx context.Context, conn rpc.ClientConn, api resource.API) (*ResourceReflection, error) {
+	refCtx := metadata.NewOutgoingContext(ctx, nil)
+	client := grpcreflect.NewClientV1Alpha(refCtx, reflectpb.NewServerReflectionClient(conn))
+	// TODO(RSDK-9718)
+	// client.AllowMissingFileDescriptors()
+	source := grpcurl.DescriptorSourceFromServer(ctx, client)
+	// some subtypes have an underscore in their name, like audio_in, audio_out, input_controller,
+	// or pose_tracker, while their APIs do not - so we have to remove the underscore.
+	resourceType := strings.ReplaceAll(api.SubtypeName, "_", "")
+	services, err := source.ListServices()
+	if err != nil {
+		client.Reset()
+		return nil, err
+	}
+	for _, srv := range services {
+		if parts := strings.Split(srv, "."); len(parts) > apiServiceIndex && parts[apiServiceIndex] == resourceType {
+			return &ResourceReflection{conn: conn, client: client, source: source, service: srv}, nil
+		}
+	}
+	client.Reset()
+	return nil, errors.Errorf("could not find a service for type: %s", resourceType)
+}
+
+// Invoke invokes method of the resource named name. The request is args, as JSON values, with the name of the
+// resource set, and the response is returned as JSON values.
+func (r *ResourceReflection) Invoke(
+	ctx context.Context,
+	name, method string,
+	args map[string]interface{},
+) (map[string]interface{}, error) {
+	request := make(map[string]interface{}, len(args)+1)
+	maps.Copy(request, args)
+	request[resource.GetResourceNameOverride(r.service, method)] = name
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not serialize gRPC method arguments")
+	}
+	options := grpcurl.FormatOptions{
+		EmitJSONDefaultFields: true,
+		IncludeTextSeparator:  true,
+		AllowUnknownFields:    true,
+	}
+	rf, formatter, err := grpcurl.RequestParserAndFormatter(
+		grpcurl.Format("json"),
+		r.source,
+		bytes.NewBuffer(requestBytes),
+		options)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create parser and formatter for grpc requests")
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := &grpcurl.DefaultEventHandler{
+		Out:            buffer,
+		Formatter:      formatter,
+		VerbosityLevel: 0,
+	}
+	if err := grpcurl.InvokeRPC(ctx, r.source, r.conn, r.service+"."+method, nil, h, rf.Next); err != nil {
+		return nil, err
+	}
+	if h.Status != nil && h.Status.Err() != nil {
+		// if the method panics, it seems to be captured here.
+		return nil, h.Status.Err()
+	}
+	response := map[string]interface{}{}
+	if err := json.Unmarshal(buffer.Bytes(), &response); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling grpc response failed")
+	}
+	return response, nil
+}
+
+// Close closes the reflection stream.
+func (r *ResourceReflection) Close() {
+	r.client.Reset()
+}
@@ -0,0 +1,69 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go.viam.com/test"
+	"go.viam.com/utils/rpc"
+
+	"go.viam.com/rdk/components/sensor"
+	viamgrpc "go.viam.com/rdk/grpc"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+type fakeSensor struct {
+	resource.Named
+	resource.AlwaysRebuild
+	resource.TriviallyCloseable
+	extra map[string]interface{}
+}
+
+func (s *fakeSensor) Readings(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+	s.extra = extra
+	return map[string]interface{}{"temperature": 21.5}, nil
+}
+
+func TestResourceReflection(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	listener, err := net.Listen("tcp", "localhost:0")
+	test.That(t, err, test.ShouldBeNil)
+	rpcServer, err := rpc.NewServer(logger, rpc.WithUnauthenticated())
+	test.That(t, err, test.ShouldBeNil)
+
+	thermometer := &fakeSensor{Named: sensor.Named("thermometer").AsNamed()}
+	sensorSvc, err := resource.NewAPIResourceCollection(sensor.API, map[resource.Name]sensor.Sensor{thermometer.Name(): thermometer})
+	test.That(t, err, test.ShouldBeNil)
+	resourceAPI, ok, err := resource.LookupAPIRegistration[sensor.Sensor](sensor.API)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, resourceAPI.RegisterRPCService(context.Background(), rpcServer, sensorSvc, logger), test.ShouldBeNil)
+	go rpcServer.Serve(listener)
+	defer rpcServer.Stop()
+
+	conn, err := viamgrpc.Dial(context.Background(), listener.Addr().String(), logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer conn.Close()
+
+	reflection, err := viamgrpc.NewResourceReflection(context.Background(), conn, sensor.API)
+	test.That(t, err, test.ShouldBeNil)
+	defer reflection.Close()
+
+	response, err := reflection.Invoke(context.Background(), "thermometer", "GetReadings",
+		map[string]interface{}{"extra": map[string]interface{}{"units": "celsius"}})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, response, test.ShouldResemble, map[string]interface{}{
+		"readings": map[string]interface{}{"temperature": 21.5},
+	})
+	test.That(t, thermometer.extra, test.ShouldResemble, map[string]interface{}{"units": "celsius"})
+
+	_, err = reflection.Invoke(context.Background(), "missing", "GetReadings", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = reflection.Invoke(context.Background(), "thermometer", "NoSuchMethod", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	_, err = viamgrpc.NewResourceReflection(context.Background(), conn, resource.APINamespace("acme").WithComponentType("gizmo"))
+	test.That(t, err, test.ShouldBeError, "could not find a service for type: gizmo")
+}
@@ -240,6 +240,9 @@ type PlanMeta struct {
 	// GoalsCBIRRTSolved returns the number of waypoints that CBIRRT solved for.
 	GoalsCBIRRTSolved int
 
+	// GoalsRoadmapSolved returns the number of waypoints that were solved for by searching a roadmap.
+	GoalsRoadmapSolved int
+
 	// SubgoalsPerGoal will have size of `GoalsProcessed`. If there are no linear/orientation
 	// constraints, we do not create any additional subgoals/waypoints. SubgoalsPerGoal in that case
 	// will be set to 1 for each goal index. Otherwise it will be sent to the number of internal
@@ -296,7 +299,12 @@ func PlanMotion(ctx context.Context, parentLogger logging.Logger, request *PlanR
 		return nil, meta, errors.New("must populate start state configuration")
 	}
 
-	sfPlanner, err := newPlanManager(ctx, logger, request, meta)
+	planRequest, err := resolveCoordinatedConstraints(request)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	sfPlanner, err := newPlanManager(ctx, logger, planRequest, meta)
 	if err != nil {
 		return nil, meta, err
 	}
@@ -65,6 +65,9 @@ func realMain() error {
 	showPoses := flag.Bool("show-poses", false, "show shadows at each path position")
 	tryManySeeds := flag.Int("try-many-seeds", 1, "try planning with more seeds and report L2 distances")
 	quiet := flag.Bool("quiet", false, "quiet")
+	buildRoadmap := flag.String("build-roadmap", "", "build a roadmap for the request's world and write it to this file")
+	roadmapNodes := flag.Int("roadmap-nodes", 0, "number of nodes to sample when building a roadmap")
+	roadmapLazy := flag.Bool("roadmap-lazy", false, "do not collision check roadmap edges while building")
 
 	flag.Parse()
 
@@ -138,6 +141,19 @@ func realMain() error {
 		req.PlannerOptions.RandomSeed = *seed
 	}
 
+	if *buildRoadmap != "" {
+		rm, err := armplanning.BuildRoadmap(ctx, logger, req, armplanning.RoadmapConfig{
+			Nodes:      *roadmapNodes,
+			Lazy:       *roadmapLazy,
+			RandomSeed: req.PlannerOptions.RandomSeed,
+		})
+		if err != nil {
+			return err
+		}
+		logger.Infof("writing roadmap to %s", *buildRoadmap)
+		return rm.WriteToFile(*buildRoadmap)
+	}
+
 	err = armplanning.PrepSmartSeed(req.FrameSystem, logger)
 	if err != nil {
 		return err
@@ -170,6 +170,13 @@ func NewPlanSegmentContext(ctx context.Context, pc *PlanContext, start *referenc
 	if err != nil {
 		return nil, err
 	}
+	if pc.planOpts.ContinuousCollisionChecking {
+		err = psc.Checker.EnableContinuousCollisionChecking(
+			pc.fs, movingFrameNames, pc.planOpts.CollisionBufferMM, pc.planOpts.ContinuousCollisionToleranceMM)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return psc, nil
 }
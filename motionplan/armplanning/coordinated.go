@@ -0,0 +1,115 @@
+package armplanning
+
+import (
+	"fmt"
+
+	"go.viam.com/rdk/motionplan"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/spatialmath"
+)
+
+// resolveCoordinatedConstraints returns a copy of a validated request in which the coordinated motion constraints are
+// made concrete: relative poses left unset are fixed to their value at the start state, goals from SynchronizedArrival
+// constraints are added to the final goal, and a goal given for only one frame of a RelativePoseConstraint is extended
+// with the goal of the other. The request itself is left untouched, as it may be used as a cache key.
+func resolveCoordinatedConstraints(request *PlanRequest) (*PlanRequest, error) {
+	c := request.Constraints
+	if len(c.RelativePoseConstraint) == 0 && len(c.SynchronizedArrival) == 0 {
+		return request, nil
+	}
+	fs := request.FrameSystem
+
+	resolved := *request
+	constraints := *c
+	constraints.RelativePoseConstraint = make([]motionplan.RelativePoseConstraint, 0, len(c.RelativePoseConstraint))
+	for _, rc := range c.RelativePoseConstraint {
+		for _, name := range []string{rc.Frame1, rc.Frame2} {
+			if fs.Frame(name) == nil {
+				return nil, referenceframe.NewFrameMissingError(name)
+			}
+		}
+		if rc.RelativePose == nil {
+			rel, err := motionplan.RelativePose(fs, request.StartState.LinearConfiguration(), rc.Frame1, rc.Frame2)
+			if err != nil {
+				return nil, err
+			}
+			rc.RelativePose = rel
+		}
+		constraints.RelativePoseConstraint = append(constraints.RelativePoseConstraint, rc)
+	}
+	resolved.Constraints = &constraints
+
+	resolved.Goals = make([]*PlanState, 0, len(request.Goals))
+	for i, g := range request.Goals {
+		if len(g.structuredConfiguration) > 0 {
+			resolved.Goals = append(resolved.Goals, g)
+			continue
+		}
+		poses := referenceframe.FrameSystemPoses{}
+		for name, pif := range g.poses {
+			poses[name] = pif
+		}
+		if i == len(request.Goals)-1 {
+			for _, sa := range c.SynchronizedArrival {
+				for name, pif := range sa.Goals {
+					if fs.Frame(name) == nil {
+						return nil, referenceframe.NewFrameMissingError(name)
+					}
+					if fs.Frame(pif.Parent()) == nil {
+						return nil, referenceframe.NewParentFrameMissingError(name, pif.Parent())
+					}
+					if _, ok := poses[name]; ok {
+						return nil, fmt.Errorf("more than one goal given for %q", name)
+					}
+					poses[name] = pif
+				}
+			}
+		}
+		if err := addRelativePoseGoals(poses, constraints.RelativePoseConstraint); err != nil {
+			return nil, err
+		}
+		resolved.Goals = append(resolved.Goals, NewPlanState(poses, nil))
+	}
+	return &resolved, nil
+}
+
+// addRelativePoseGoals derives the goal of one frame of a RelativePoseConstraint from the goal of the other, and
+// checks that goals given for both frames agree with the constraint.
+func addRelativePoseGoals(poses referenceframe.FrameSystemPoses, constraints []motionplan.RelativePoseConstraint) error {
+	for _, rc := range constraints {
+		goal1, ok1 := poses[rc.Frame1]
+		goal2, ok2 := poses[rc.Frame2]
+		switch {
+		case ok1 && ok2:
+			if goal1.Parent() != goal2.Parent() {
+				continue
+			}
+			rel := spatialmath.PoseBetween(goal1.Pose(), goal2.Pose())
+			lineTol, orientTol := rc.Tolerances()
+			if rel.Point().Distance(rc.RelativePose.Point()) > lineTol ||
+				motionplan.OrientDist(rel.Orientation(), rc.RelativePose.Orientation()) > orientTol {
+				return fmt.Errorf("goals for %q and %q do not satisfy their relative pose constraint", rc.Frame1, rc.Frame2)
+			}
+		case ok1:
+			poses[rc.Frame2] = referenceframe.NewPoseInFrame(goal1.Parent(), spatialmath.Compose(goal1.Pose(), rc.RelativePose))
+		case ok2:
+			poses[rc.Frame1] = referenceframe.NewPoseInFrame(
+				goal2.Parent(), spatialmath.Compose(goal2.Pose(), spatialmath.PoseInverse(rc.RelativePose)))
+		}
+	}
+	return nil
+}
+
+// holdRelativePoses replaces the interpolated pose of the second frame of each RelativePoseConstraint with the one
+// which holds its pose relative to the first frame.
+func holdRelativePoses(poses referenceframe.FrameSystemPoses, constraints []motionplan.RelativePoseConstraint) {
+	for _, rc := range constraints {
+		pif1, ok1 := poses[rc.Frame1]
+		pif2, ok2 := poses[rc.Frame2]
+		if !ok1 || !ok2 || pif1.Parent() != pif2.Parent() {
+			continue
+		}
+		poses[rc.Frame2] = referenceframe.NewPoseInFrameWithGoalCloud(
+			pif2.Parent(), spatialmath.Compose(pif1.Pose(), rc.RelativePose), pif2.GoalCloud)
+	}
+}
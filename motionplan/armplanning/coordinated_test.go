@@ -0,0 +1,101 @@
+package armplanning
+
+import (
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/motionplan"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+func TestResolveCoordinatedConstraints(t *testing.T) {
+	fs := referenceframe.NewEmptyFrameSystem("")
+	for _, name := range []string{"left", "right"} {
+		model, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), name)
+		test.That(t, err, test.ShouldBeNil)
+		y := 300.
+		if name == "right" {
+			y = -300
+		}
+		base, err := referenceframe.NewStaticFrame(name+"_base", spatialmath.NewPoseFromPoint(r3.Vector{Y: y}))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, fs.AddFrame(base, fs.World()), test.ShouldBeNil)
+		test.That(t, fs.AddFrame(model, base), test.ShouldBeNil)
+	}
+	start := referenceframe.NewZeroInputs(fs)
+	startRel, err := motionplan.RelativePose(fs, start.ToLinearInputs(), "left", "right")
+	test.That(t, err, test.ShouldBeNil)
+
+	goalPose := spatialmath.NewPose(r3.Vector{X: 300, Y: 300, Z: 400}, &spatialmath.OrientationVectorDegrees{OZ: -1, Theta: 30})
+	newRequest := func(goal referenceframe.FrameSystemPoses, constraints *motionplan.Constraints) *PlanRequest {
+		req := &PlanRequest{
+			FrameSystem: fs,
+			StartState:  NewPlanState(nil, start),
+			Goals:       []*PlanState{NewPlanState(goal, nil)},
+			Constraints: constraints,
+		}
+		test.That(t, req.validatePlanRequest(), test.ShouldBeNil)
+		return req
+	}
+
+	t.Run("derives the goal of the other frame", func(t *testing.T) {
+		req := newRequest(
+			referenceframe.FrameSystemPoses{"left": referenceframe.NewPoseInFrame(referenceframe.World, goalPose)},
+			&motionplan.Constraints{RelativePoseConstraint: []motionplan.RelativePoseConstraint{{Frame1: "left", Frame2: "right"}}},
+		)
+		resolved, err := resolveCoordinatedConstraints(req)
+		test.That(t, err, test.ShouldBeNil)
+		// The request itself is unchanged.
+		test.That(t, req.Constraints.RelativePoseConstraint[0].RelativePose, test.ShouldBeNil)
+		test.That(t, len(req.Goals[0].Poses()), test.ShouldEqual, 1)
+
+		rel := resolved.Constraints.RelativePoseConstraint[0].RelativePose
+		test.That(t, spatialmath.PoseAlmostEqual(rel, startRel), test.ShouldBeTrue)
+		goals := resolved.Goals[0].Poses()
+		test.That(t, len(goals), test.ShouldEqual, 2)
+		test.That(t, spatialmath.PoseAlmostEqual(spatialmath.PoseBetween(goals["left"].Pose(), goals["right"].Pose()), rel),
+			test.ShouldBeTrue)
+
+		// Intermediate waypoints hold the relative pose even while rotating.
+		halfway := referenceframe.FrameSystemPoses{
+			"left":  referenceframe.NewPoseInFrame(referenceframe.World, spatialmath.Interpolate(spatialmath.NewZeroPose(), goalPose, 0.5)),
+			"right": referenceframe.NewPoseInFrame(referenceframe.World, spatialmath.NewZeroPose()),
+		}
+		holdRelativePoses(halfway, resolved.Constraints.RelativePoseConstraint)
+		test.That(t, spatialmath.PoseAlmostEqual(spatialmath.PoseBetween(halfway["left"].Pose(), halfway["right"].Pose()), rel),
+			test.ShouldBeTrue)
+	})
+
+	t.Run("synchronized arrival goals", func(t *testing.T) {
+		rightGoal := referenceframe.NewPoseInFrame(referenceframe.World, spatialmath.NewPoseFromPoint(r3.Vector{X: 300, Y: -300, Z: 400}))
+		req := newRequest(
+			referenceframe.FrameSystemPoses{"left": referenceframe.NewPoseInFrame(referenceframe.World, goalPose)},
+			&motionplan.Constraints{SynchronizedArrival: []motionplan.SynchronizedArrival{{
+				Goals: map[string]*referenceframe.PoseInFrame{"right": rightGoal},
+			}}},
+		)
+		resolved, err := resolveCoordinatedConstraints(req)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, resolved.Goals[0].Poses()["right"], test.ShouldEqual, rightGoal)
+
+		req.Constraints.SynchronizedArrival[0].Goals["left"] = rightGoal
+		_, err = resolveCoordinatedConstraints(req)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("inconsistent goals", func(t *testing.T) {
+		req := newRequest(
+			referenceframe.FrameSystemPoses{
+				"left":  referenceframe.NewPoseInFrame(referenceframe.World, goalPose),
+				"right": referenceframe.NewPoseInFrame(referenceframe.World, goalPose),
+			},
+			&motionplan.Constraints{RelativePoseConstraint: []motionplan.RelativePoseConstraint{{Frame1: "left", Frame2: "right"}}},
+		)
+		_, err := resolveCoordinatedConstraints(req)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,366 @@
+package armplanning
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.viam.com/utils/trace"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/motionplan"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/utils"
+)
+
+const (
+	// Start configurations are rounded to this many radians or mm when computing cache keys, so that the small
+	// jitter in the robot's reported position does not defeat the cache. Reused plans are re-anchored at the exact
+	// start configuration and re-validated, so the rounding never produces an unchecked motion.
+	planCacheInputResolution = 1e-3
+	// Goal poses are rounded to this many mm, and orientations to this many units of the orientation vector.
+	planCachePoseResolution = 1e-2
+
+	defaultMaxPlanCacheEntries = 1000
+
+	planCacheFileSuffix = ".plan.json"
+)
+
+// DefaultPlanCacheDir is the directory under the viam home in which plans are cached by default.
+var DefaultPlanCacheDir = filepath.Join(utils.ViamDotDir, "motion", "plan_cache")
+
+// PlanCache persists successful plans on disk so that repeated requests with the same frame system, start
+// configuration, goals and constraints can skip planning. A cached plan is only reused after it has been
+// re-validated against the goals, constraints and obstacles of the new request.
+type PlanCache struct {
+	dir        string
+	maxEntries int
+	logger     logging.Logger
+
+	mu sync.Mutex
+}
+
+type planCacheEntry struct {
+	Created    time.Time             `json:"created"`
+	Trajectory motionplan.Trajectory `json:"trajectory"`
+}
+
+// NewPlanCache returns a PlanCache storing plans in dir, creating it if needed. maxEntries bounds the number of
+// plans kept; the least recently used plans are evicted first. A non-positive maxEntries selects the default.
+func NewPlanCache(dir string, maxEntries int, logger logging.Logger) (*PlanCache, error) {
+	if dir == "" {
+		dir = DefaultPlanCacheDir
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxPlanCacheEntries
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &PlanCache{dir: dir, maxEntries: maxEntries, logger: logger}, nil
+}
+
+// PlanCacheKey returns the key under which a plan for the request is cached. It covers the serialized frame system,
+// including the parent of every frame and any world state transforms, the start configuration, the goals, the
+// constraints and the planner options.
+// Obstacles are deliberately excluded: a cached plan is instead checked against the current obstacles before reuse.
+// The second return value is false if the request cannot be cached.
+func PlanCacheKey(req *PlanRequest) (string, bool) {
+	if req == nil || req.FrameSystem == nil || req.StartState == nil || len(req.Goals) == 0 {
+		return "", false
+	}
+
+	// FrameSystem.Hash is independent of the topology, so two frame systems with the same frames attached differently
+	// would share plans. The serialization records parents, and sorts its maps, so it is stable across calls.
+	fsJSON, err := req.FrameSystem.MarshalJSON()
+	if err != nil {
+		return "", false
+	}
+	var sb strings.Builder
+	sb.WriteString("fs:")
+	sb.Write(fsJSON)
+	sb.WriteByte(';')
+	sb.WriteString("start:")
+	writeInputsKey(&sb, req.StartState.Configuration())
+	for _, goal := range req.Goals {
+		sb.WriteString("goal:")
+		if !writePosesKey(&sb, goal.Poses()) {
+			return "", false
+		}
+		writeInputsKey(&sb, goal.Configuration())
+	}
+	if req.Constraints != nil {
+		constraintsJSON, err := json.Marshal(req.Constraints)
+		if err != nil {
+			return "", false
+		}
+		sb.WriteString("constraints:")
+		sb.Write(constraintsJSON)
+	}
+	if req.PlannerOptions != nil {
+		// The timeout affects how long we may search, not which plans are acceptable.
+		opts := *req.PlannerOptions
+		opts.Timeout = 0
+		optsJSON, err := json.Marshal(opts)
+		if err != nil {
+			return "", false
+		}
+		sb.WriteString("opts:")
+		sb.Write(optsJSON)
+	}
+
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:]), true
+}
+
+func writeInputsKey(sb *strings.Builder, inputs referenceframe.FrameSystemInputs) {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		for _, v := range inputs[name] {
+			fmt.Fprintf(sb, "%d,", int64(math.Round(v/planCacheInputResolution)))
+		}
+		sb.WriteByte(';')
+	}
+}
+
+func writePosesKey(sb *strings.Builder, poses referenceframe.FrameSystemPoses) bool {
+	names := make([]string, 0, len(poses))
+	for name := range poses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	round := func(v float64) int64 { return int64(math.Round(v / planCachePoseResolution)) }
+	for _, name := range names {
+		pif := poses[name]
+		if pif.GoalCloud != nil {
+			// Goal clouds accept a region of poses; the plan found for one request is not a meaningful answer to
+			// another.
+			return false
+		}
+		pt := pif.Pose().Point()
+		ov := pif.Pose().Orientation().OrientationVectorDegrees()
+		fmt.Fprintf(sb, "%s@%s=%d,%d,%d,%d,%d,%d,%d;", name, pif.Parent(),
+			round(pt.X), round(pt.Y), round(pt.Z), round(ov.OX), round(ov.OY), round(ov.OZ), round(ov.Theta))
+	}
+	return true
+}
+
+func (pc *PlanCache) path(key string) string {
+	return filepath.Join(pc.dir, key+planCacheFileSuffix)
+}
+
+// Lookup returns a cached plan for the request if one exists and, anchored at the request's exact start
+// configuration, still reaches its goals within its constraints and obstacles. Invalid entries are evicted.
+func (pc *PlanCache) Lookup(ctx context.Context, req *PlanRequest) (motionplan.Plan, bool) {
+	ctx, span := trace.StartSpan(ctx, "PlanCache::Lookup")
+	defer span.End()
+
+	key, ok := PlanCacheKey(req)
+	if !ok {
+		return nil, false
+	}
+
+	pc.mu.Lock()
+	data, err := os.ReadFile(pc.path(key))
+	if err == nil {
+		// Touch the entry so eviction is least-recently-used rather than least-recently-created.
+		now := time.Now()
+		//nolint:errcheck
+		_ = os.Chtimes(pc.path(key), now, now)
+	}
+	pc.mu.Unlock()
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			pc.logger.CWarnf(ctx, "failed to read cached plan %s: %v", key, err)
+		}
+		return nil, false
+	}
+
+	var entry planCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || len(entry.Trajectory) < 2 {
+		pc.logger.CWarnf(ctx, "discarding unreadable cached plan %s: %v", key, err)
+		pc.evict(key)
+		return nil, false
+	}
+
+	traj := make([]*referenceframe.LinearInputs, len(entry.Trajectory))
+	for i, step := range entry.Trajectory {
+		traj[i] = step.ToLinearInputs()
+	}
+	// The key rounds the start configuration, so anchor the plan at the exact current start.
+	traj[0] = req.StartState.LinearConfiguration()
+
+	if err := validateCachedTrajectory(ctx, pc.logger, req, traj); err != nil {
+		pc.logger.CDebugf(ctx, "cached plan %s is no longer valid, replanning: %v", key, err)
+		pc.evict(key)
+		return nil, false
+	}
+
+	plan, err := motionplan.NewSimplePlanFromTrajectory(traj, req.FrameSystem)
+	if err != nil {
+		pc.logger.CWarnf(ctx, "failed to rebuild cached plan %s: %v", key, err)
+		return nil, false
+	}
+	pc.logger.CDebugf(ctx, "reusing cached plan %s with %d steps", key, len(traj))
+	return plan, true
+}
+
+// Store caches a plan for the request, evicting the least recently used plans if the cache is full.
+func (pc *PlanCache) Store(req *PlanRequest, plan motionplan.Plan) error {
+	key, ok := PlanCacheKey(req)
+	if !ok || plan == nil {
+		return nil
+	}
+	data, err := json.Marshal(planCacheEntry{Created: time.Now(), Trajectory: plan.Trajectory()})
+	if err != nil {
+		return err
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	// Write then rename so concurrent readers never see a partial file.
+	tmp := pc.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, pc.path(key)); err != nil {
+		return err
+	}
+	return pc.prune()
+}
+
+func (pc *PlanCache) evict(key string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if err := os.Remove(pc.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		pc.logger.Warnf("failed to evict cached plan %s: %v", key, err)
+	}
+}
+
+// prune removes the least recently used entries beyond maxEntries. Callers must hold mu.
+func (pc *PlanCache) prune() error {
+	dirEntries, err := os.ReadDir(pc.dir)
+	if err != nil {
+		return err
+	}
+	type cached struct {
+		path    string
+		modTime time.Time
+	}
+	var entries []cached
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), planCacheFileSuffix) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cached{path: filepath.Join(pc.dir, de.Name()), modTime: info.ModTime()})
+	}
+	if len(entries) <= pc.maxEntries {
+		return nil
+	}
+	slices.SortFunc(entries, func(a, b cached) int { return a.modTime.Compare(b.modTime) })
+	for _, e := range entries[:len(entries)-pc.maxEntries] {
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCachedTrajectory checks that a trajectory still answers the request. Each goal must be reached in order,
+// with the last reached at the final step, and every segment between goals must satisfy the full constraint set of
+// the request, including its obstacles. Neither can be assumed from the key alone: the start is re-anchored, which
+// moves the first segment, and obstacles are not part of the key.
+func validateCachedTrajectory(
+	ctx context.Context,
+	logger logging.Logger,
+	req *PlanRequest,
+	traj []*referenceframe.LinearInputs,
+) error {
+	checked := *req
+	if checked.PlannerOptions == nil {
+		checked.PlannerOptions = NewBasicPlannerOptions()
+	}
+	pc, err := NewPlanContext(ctx, logger, &checked, &PlanMeta{})
+	if err != nil {
+		return err
+	}
+
+	segmentStart := 0
+	for goalIdx, goal := range req.Goals {
+		goalPoses, err := goal.ComputePoses(ctx, req.FrameSystem)
+		if err != nil {
+			return err
+		}
+		psc, err := NewPlanSegmentContext(ctx, pc, traj[segmentStart], goalPoses)
+		if err != nil {
+			return err
+		}
+
+		reached := -1
+		if goalIdx == len(req.Goals)-1 {
+			if goalReached(psc, goal, traj[len(traj)-1]) {
+				reached = len(traj) - 1
+			}
+		} else {
+			for i := segmentStart + 1; i < len(traj); i++ {
+				if goalReached(psc, goal, traj[i]) {
+					reached = i
+					break
+				}
+			}
+		}
+		if reached < 0 {
+			return fmt.Errorf("goal %d is not reached", goalIdx)
+		}
+
+		for i := segmentStart + 1; i <= reached; i++ {
+			if err := psc.CheckPath(ctx, traj[i-1], traj[i], i == reached, nil); err != nil {
+				return fmt.Errorf("segment %d: %w", i, err)
+			}
+		}
+		segmentStart = reached
+	}
+	return nil
+}
+
+// goalReached returns whether inputs satisfy goal, within the goal threshold of the planner options for pose goals.
+func goalReached(psc *PlanSegmentContext, goal *PlanState, inputs *referenceframe.LinearInputs) bool {
+	for name, want := range goal.Configuration() {
+		got := inputs.Get(name)
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range want {
+			if math.Abs(got[i]-want[i]) > planCacheInputResolution {
+				return false
+			}
+		}
+	}
+	if len(goal.Poses()) == 0 {
+		return true
+	}
+	metric := psc.pc.planOpts.GetGoalMetric(psc.goal)
+	return metric(&motionplan.StateFS{Configuration: inputs, FS: psc.pc.fs}) <= psc.pc.planOpts.GoalThreshold
+}
@@ -0,0 +1,175 @@
+package armplanning
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/motionplan"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+func TestPlanCache(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+
+	model, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "xarm6")
+	test.That(t, err, test.ShouldBeNil)
+	fs := referenceframe.NewEmptyFrameSystem("")
+	test.That(t, fs.AddFrame(model, fs.World()), test.ShouldBeNil)
+
+	goal := referenceframe.FrameSystemInputs{"xarm6": {0.5, 0, 0, 0, 0, 0}}
+	newRequest := func(start []referenceframe.Input, obstacles ...spatialmath.Geometry) *PlanRequest {
+		return &PlanRequest{
+			FrameSystem:           fs,
+			StartState:            NewPlanState(nil, referenceframe.FrameSystemInputs{"xarm6": start}),
+			Goals:                 []*PlanState{NewPlanState(nil, goal)},
+			ObstaclesInWorldFrame: referenceframe.NewGeometriesInFrame(referenceframe.World, obstacles),
+			PlannerOptions:        NewBasicPlannerOptions(),
+		}
+	}
+	plan := motionplan.NewSimplePlan(nil, motionplan.Trajectory{
+		{"xarm6": {0, 0, 0, 0, 0, 0}},
+		{"xarm6": {0.25, 0, 0, 0, 0, 0}},
+		goal,
+	})
+
+	cache, err := NewPlanCache(t.TempDir(), 0, logger)
+	test.That(t, err, test.ShouldBeNil)
+
+	req := newRequest([]referenceframe.Input{0, 0, 0, 0, 0, 0})
+	_, ok := cache.Lookup(ctx, req)
+	test.That(t, ok, test.ShouldBeFalse)
+	test.That(t, cache.Store(req, plan), test.ShouldBeNil)
+
+	t.Run("hit re-anchors at the exact start", func(t *testing.T) {
+		start := []referenceframe.Input{1e-4, 0, 0, 0, 0, -1e-4}
+		cached, ok := cache.Lookup(ctx, newRequest(start))
+		test.That(t, ok, test.ShouldBeTrue)
+		traj := cached.Trajectory()
+		test.That(t, len(traj), test.ShouldEqual, 3)
+		test.That(t, traj[0]["xarm6"], test.ShouldResemble, start)
+		test.That(t, traj[2], test.ShouldResemble, goal)
+	})
+
+	t.Run("different start misses", func(t *testing.T) {
+		_, ok := cache.Lookup(ctx, newRequest([]referenceframe.Input{0.1, 0, 0, 0, 0, 0}))
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+
+	t.Run("obstacle in the way invalidates and evicts the plan", func(t *testing.T) {
+		eePose, err := model.Transform(goal["xarm6"])
+		test.That(t, err, test.ShouldBeNil)
+		box, err := spatialmath.NewBox(spatialmath.NewPoseFromPoint(eePose.Point()), r3.Vector{X: 100, Y: 100, Z: 100}, "box")
+		test.That(t, err, test.ShouldBeNil)
+
+		blocked := newRequest([]referenceframe.Input{0, 0, 0, 0, 0, 0}, box)
+		_, ok := cache.Lookup(ctx, blocked)
+		test.That(t, ok, test.ShouldBeFalse)
+
+		key, ok := PlanCacheKey(blocked)
+		test.That(t, ok, test.ShouldBeTrue)
+		_, err = os.Stat(cache.path(key))
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	})
+
+	t.Run("plan that does not reach the goal is rejected", func(t *testing.T) {
+		short := newRequest([]referenceframe.Input{0, 0, 0, 0, 0, 0})
+		short.Goals = []*PlanState{NewPlanState(nil, referenceframe.FrameSystemInputs{"xarm6": {0.3, 0, 0, 0, 0, 0}})}
+		test.That(t, cache.Store(short, plan), test.ShouldBeNil)
+		_, ok := cache.Lookup(ctx, short)
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+
+	t.Run("plan that violates a constraint is rejected", func(t *testing.T) {
+		eePose, err := model.Transform(goal["xarm6"])
+		test.That(t, err, test.ShouldBeNil)
+		constrained := newRequest([]referenceframe.Input{0, 0, 0, 0, 0, 0})
+		constrained.Goals = []*PlanState{NewPlanState(referenceframe.FrameSystemPoses{
+			"xarm6": referenceframe.NewPoseInFrame(referenceframe.World, eePose),
+		}, nil)}
+		constrained.Constraints = motionplan.NewConstraints([]motionplan.LinearConstraint{{LineToleranceMm: 1}}, nil, nil, nil)
+		// The joint space path swings the end effector in an arc, far from the straight line to the goal.
+		test.That(t, cache.Store(constrained, plan), test.ShouldBeNil)
+		_, ok := cache.Lookup(ctx, constrained)
+		test.That(t, ok, test.ShouldBeFalse)
+
+		constrained.Constraints = nil
+		test.That(t, cache.Store(constrained, plan), test.ShouldBeNil)
+		_, ok = cache.Lookup(ctx, constrained)
+		test.That(t, ok, test.ShouldBeTrue)
+	})
+
+	t.Run("eviction keeps the cache bounded", func(t *testing.T) {
+		small, err := NewPlanCache(t.TempDir(), 2, logger)
+		test.That(t, err, test.ShouldBeNil)
+		for i := 0; i < 4; i++ {
+			test.That(t, small.Store(newRequest([]referenceframe.Input{float64(i), 0, 0, 0, 0, 0}), plan), test.ShouldBeNil)
+		}
+		entries, err := os.ReadDir(small.dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(entries), test.ShouldEqual, 2)
+	})
+}
+
+func TestPlanCacheKey(t *testing.T) {
+	model, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "xarm6")
+	test.That(t, err, test.ShouldBeNil)
+	fs := referenceframe.NewEmptyFrameSystem("")
+	test.That(t, fs.AddFrame(model, fs.World()), test.ShouldBeNil)
+
+	start := NewPlanState(nil, referenceframe.FrameSystemInputs{"xarm6": make([]referenceframe.Input, 6)})
+	goalAt := func(x float64) *PlanState {
+		return NewPlanState(referenceframe.FrameSystemPoses{
+			"xarm6": referenceframe.NewPoseInFrame(referenceframe.World, spatialmath.NewPoseFromPoint(r3.Vector{X: x, Z: 300})),
+		}, nil)
+	}
+
+	key1, ok := PlanCacheKey(&PlanRequest{FrameSystem: fs, StartState: start, Goals: []*PlanState{goalAt(200)}})
+	test.That(t, ok, test.ShouldBeTrue)
+	key2, ok := PlanCacheKey(&PlanRequest{FrameSystem: fs, StartState: start, Goals: []*PlanState{goalAt(200.001)}})
+	test.That(t, ok, test.ShouldBeTrue)
+	key3, ok := PlanCacheKey(&PlanRequest{FrameSystem: fs, StartState: start, Goals: []*PlanState{goalAt(210)}})
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, key1, test.ShouldEqual, key2)
+	test.That(t, key1, test.ShouldNotEqual, key3)
+
+	withConstraint, ok := PlanCacheKey(&PlanRequest{
+		FrameSystem: fs, StartState: start, Goals: []*PlanState{goalAt(200)},
+		Constraints: motionplan.NewConstraints([]motionplan.LinearConstraint{{LineToleranceMm: 1}}, nil, nil, nil),
+	})
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, withConstraint, test.ShouldNotEqual, key1)
+
+	// the same frames attached differently must not share plans
+	offset, err := referenceframe.NewStaticFrame("offset", spatialmath.NewPoseFromPoint(r3.Vector{X: 100}))
+	test.That(t, err, test.ShouldBeNil)
+	other, err := referenceframe.NewStaticFrame("other", spatialmath.NewZeroPose())
+	test.That(t, err, test.ShouldBeNil)
+	fsA := referenceframe.NewEmptyFrameSystem("")
+	test.That(t, fsA.AddFrame(offset, fsA.World()), test.ShouldBeNil)
+	test.That(t, fsA.AddFrame(other, fsA.World()), test.ShouldBeNil)
+	test.That(t, fsA.AddFrame(model, offset), test.ShouldBeNil)
+	fsB := referenceframe.NewEmptyFrameSystem("")
+	test.That(t, fsB.AddFrame(offset, fsB.World()), test.ShouldBeNil)
+	test.That(t, fsB.AddFrame(other, fsB.World()), test.ShouldBeNil)
+	test.That(t, fsB.AddFrame(model, other), test.ShouldBeNil)
+	test.That(t, fsA.Hash(), test.ShouldEqual, fsB.Hash())
+	keyA, ok := PlanCacheKey(&PlanRequest{FrameSystem: fsA, StartState: start, Goals: []*PlanState{goalAt(200)}})
+	test.That(t, ok, test.ShouldBeTrue)
+	keyB, ok := PlanCacheKey(&PlanRequest{FrameSystem: fsB, StartState: start, Goals: []*PlanState{goalAt(200)}})
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, keyA, test.ShouldNotEqual, keyB)
+	keyA2, ok := PlanCacheKey(&PlanRequest{FrameSystem: fsA, StartState: start, Goals: []*PlanState{goalAt(200)}})
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, keyA2, test.ShouldEqual, keyA)
+
+	_, ok = PlanCacheKey(&PlanRequest{FrameSystem: fs, StartState: start})
+	test.That(t, ok, test.ShouldBeFalse)
+}
@@ -1,8 +1,11 @@
 package armplanning
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"go.viam.com/utils/trace"
@@ -18,6 +21,9 @@ type planManager struct {
 	pc      *PlanContext
 	request *PlanRequest
 	logger  logging.Logger
+
+	// roadmap is non-nil if the request names a roadmap that was built for this world.
+	roadmap *Roadmap
 }
 
 func newPlanManager(ctx context.Context, logger logging.Logger, request *PlanRequest, meta *PlanMeta) (*planManager, error) {
@@ -25,11 +31,23 @@ func newPlanManager(ctx context.Context, logger logging.Logger, request *PlanReq
 	if err != nil {
 		return nil, err
 	}
-	return &planManager{
+	pm := &planManager{
 		pc:      pc,
 		logger:  logger,
 		request: request,
-	}, nil
+	}
+	if fileName := request.PlannerOptions.RoadmapFile; fileName != "" {
+		rm, err := loadRoadmap(fileName)
+		switch {
+		case err != nil:
+			logger.Warnf("not using roadmap %s: %v", fileName, err)
+		case !rm.Matches(request):
+			logger.Infof("not using roadmap %s: it was built for a different frame system or obstacles", fileName)
+		default:
+			pm.roadmap = rm
+		}
+	}
+	return pm, nil
 }
 
 // planMultiWaypoint plans a motion through multiple waypoints, using identical constraints for each
@@ -155,6 +173,10 @@ func (pm *planManager) planToDirectJoints(
 		return nil, fmt.Errorf("want to go to specific joint config but it is invalid: %w", err)
 	}
 
+	if steps, err := pm.planWithRoadmap(ctx, psc, start, []*referenceframe.LinearInputs{fullConfig}); err == nil {
+		return steps, nil
+	}
+
 	pathPlanner, err := newCBiRRTMotionPlanner(ctx, pm.pc, psc, pm.logger.Sublogger("cbirrt"))
 	if err != nil {
 		return nil, err
@@ -211,6 +233,16 @@ func (pm *planManager) planSingleGoal(
 		return nil, fmt.Errorf("linear with cbirrt not allowed and no direct solutions found")
 	}
 
+	if pm.roadmap != nil {
+		goals := []*referenceframe.LinearInputs{planSeed.maps.optNode.inputs}
+		for n := range planSeed.maps.goalMap {
+			goals = append(goals, n.inputs)
+		}
+		if steps, err := pm.planWithRoadmap(ctx, psc, start, goals); err == nil {
+			return steps, nil
+		}
+	}
+
 	pm.logger.Debugf("initRRTSolutions goalMap size: %d", len(planSeed.maps.goalMap))
 	pathPlanner, err := newCBiRRTMotionPlanner(ctx, pm.pc, psc, pm.logger.Sublogger("cbirrt"))
 	if err != nil {
@@ -231,13 +263,57 @@ func (pm *planManager) planSingleGoal(
 	return finalSteps.steps, nil
 }
 
+// planWithRoadmap searches the roadmap for a path from start to the closest reachable goal configuration, and returns
+// the smoothed path. It returns an error if there is no roadmap or it contains no valid path.
+func (pm *planManager) planWithRoadmap(
+	ctx context.Context,
+	psc *PlanSegmentContext,
+	start *referenceframe.LinearInputs,
+	goals []*referenceframe.LinearInputs,
+) ([]*referenceframe.LinearInputs, error) {
+	if pm.roadmap == nil {
+		return nil, errors.New("no roadmap")
+	}
+	ctx, span := trace.StartSpan(ctx, "planWithRoadmap")
+	defer span.End()
+
+	// Try the goals closest to the start first, they are the most likely to give short paths.
+	goals = slices.Clone(goals)
+	slices.SortStableFunc(goals, func(a, b *referenceframe.LinearInputs) int {
+		return cmp.Compare(
+			pm.pc.ConfigurationDistanceFunc(&motionplan.SegmentFS{StartConfiguration: start, EndConfiguration: a}),
+			pm.pc.ConfigurationDistanceFunc(&motionplan.SegmentFS{StartConfiguration: start, EndConfiguration: b}),
+		)
+	})
+
+	roadmapStart := time.Now()
+	var err error
+	for _, goal := range goals[:min(len(goals), defaultRoadmapNeighbors)] {
+		var steps []*referenceframe.LinearInputs
+		steps, err = pm.roadmap.findPath(ctx, psc, start, goal, defaultRoadmapNeighbors)
+		if err != nil {
+			continue
+		}
+		steps, err = smoothPath(ctx, psc, steps)
+		if err != nil {
+			return nil, err
+		}
+		pm.logger.Debugf("roadmap found a path of %d steps in %v", len(steps), time.Since(roadmapStart))
+		pm.pc.planMeta.GoalsRoadmapSolved++
+		return steps, nil
+	}
+	pm.logger.Debugf("roadmap found no path after %v, falling back to cbirrt: %v", time.Since(roadmapStart), err)
+	return nil, err
+}
+
 // generateWaypoints will return the list of atomic waypoints that correspond to a specific goal in a plan request.
 // bool is if cbirrt is allowed
 func (pm *planManager) generateWaypoints(ctx context.Context, start, goal referenceframe.FrameSystemPoses,
 ) ([]referenceframe.FrameSystemPoses, bool, error) {
 	_, span := trace.StartSpan(ctx, "generateWaypoints")
 	defer span.End()
-	if len(pm.request.Constraints.LinearConstraint) == 0 {
+	relativeConstraints := pm.request.Constraints.RelativePoseConstraint
+	if len(pm.request.Constraints.LinearConstraint) == 0 && len(relativeConstraints) == 0 {
 		return []referenceframe.FrameSystemPoses{goal}, true, nil
 	}
 
@@ -247,6 +323,10 @@ func (pm *planManager) generateWaypoints(ctx context.Context, start, goal refere
 		tighestConstraint = min(tighestConstraint, lc.LineToleranceMm)
 		tighestConstraint = min(tighestConstraint, lc.OrientationToleranceDegs)
 	}
+	for _, rc := range relativeConstraints {
+		lineTol, orientTol := rc.Tolerances()
+		tighestConstraint = min(tighestConstraint, lineTol, orientTol)
+	}
 
 	tighestConstraint = max(tighestConstraint, 0)
 
@@ -285,11 +365,14 @@ func (pm *planManager) generateWaypoints(ctx context.Context, start, goal refere
 			// solved for.
 			to[frameName] = referenceframe.NewPoseInFrameWithGoalCloud(pif.Parent(), toPose, pif.GoalCloud)
 		}
+		// Interpolating two frames independently does not hold their relative pose when they rotate.
+		holdRelativePoses(to, relativeConstraints)
 
 		waypoints = append(waypoints, to)
 	}
 
-	return waypoints, tighestConstraint >= 10, nil
+	// Randomly sampled configurations will essentially never hold a relative pose, so cbirrt cannot help.
+	return waypoints, tighestConstraint >= 10 && len(relativeConstraints) == 0, nil
 }
 
 type rrtMap map[*node]*node
@@ -133,6 +133,18 @@ type PlannerOptions struct {
 	// This includes SolutionNodes and ConstraintFailuresByType. Disabled by default because
 	// accumulating this data can be expensive for large solution sets.
 	CollectSolutionDiagnostics bool `json:"collect_solution_diagnostics"`
+
+	// If true, segments are checked for collisions with obstacles continuously, using conservative advancement, rather
+	// than only at intermediate states spaced by Resolution. This prevents passing through thin obstacles.
+	ContinuousCollisionChecking bool `json:"continuous_collision_checking"`
+
+	// When checking collisions continuously, geometries are always advanced at least this far between checks.
+	// If left uninitialized, motionplan.DefaultContinuousCollisionToleranceMM is used.
+	ContinuousCollisionToleranceMM float64 `json:"continuous_collision_tolerance_mm"`
+
+	// Path to a roadmap written by Roadmap.WriteToFile. If the roadmap was built for the same frame system and
+	// obstacles as the request, it is searched for a path before falling back to cBiRRT.
+	RoadmapFile string `json:"roadmap_file"`
 }
 
 // NewPlannerOptionsFromExtra returns basic default settings updated by overridden parameters
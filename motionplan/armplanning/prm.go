@@ -0,0 +1,625 @@
+package armplanning
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.viam.com/utils/trace"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/motionplan"
+	"go.viam.com/rdk/referenceframe"
+)
+
+const (
+	defaultRoadmapNodes     = 1000
+	defaultRoadmapNeighbors = 10
+
+	// A roadmap node is sampled at most this many times per requested node before the builder gives up. This bounds the
+	// build time for workcells where most of the configuration space is in collision.
+	roadmapSampleAttemptsPerNode = 100
+
+	// Lazy queries re-search the graph after every edge that turns out to be invalid. Give up after this many searches.
+	maxRoadmapSearches = 200
+)
+
+var errRoadmapNoPath = errors.New("roadmap contains no valid path between start and goal")
+
+// RoadmapConfig describes how to build a probabilistic roadmap.
+type RoadmapConfig struct {
+	// Frames whose inputs are sampled. If empty, every frame in the frame system with degrees of freedom is used.
+	Frames []string `json:"frames"`
+
+	// Number of collision-free configurations to sample.
+	Nodes int `json:"nodes"`
+
+	// Each node is connected to this many of its nearest neighbors.
+	Neighbors int `json:"neighbors"`
+
+	// If true, edges are not collision checked while building. They are instead checked the first time a query
+	// tries to use them (lazy PRM), which makes building much faster at the cost of slower first queries.
+	Lazy bool `json:"lazy"`
+
+	// Number of goroutines used to sample nodes and check edges.
+	NumThreads int `json:"num_threads"`
+
+	// The random seed used for sampling.
+	RandomSeed int `json:"rseed"`
+}
+
+func (cfg *RoadmapConfig) setDefaults() {
+	if cfg.Nodes <= 0 {
+		cfg.Nodes = defaultRoadmapNodes
+	}
+	if cfg.Neighbors <= 0 {
+		cfg.Neighbors = defaultRoadmapNeighbors
+	}
+	if cfg.NumThreads <= 0 {
+		cfg.NumThreads = max(1, defaultNumThreads)
+	}
+}
+
+// Roadmap is a probabilistic roadmap (PRM) of collision-free configurations for a static workcell. It is built once
+// for a frame system and set of obstacles, persisted, and then queried by the planner whenever a request is made in
+// the same world. Every edge a query uses is checked against the request's constraints before it is returned.
+type Roadmap struct {
+	// Hash of the frame system the roadmap was built for. This includes any world state transforms.
+	FrameSystemHash int `json:"frame_system_hash"`
+	// Hash of the obstacles the roadmap was built around.
+	ObstaclesHash int `json:"obstacles_hash"`
+
+	// Frames and their degrees of freedom, in the order their inputs are concatenated in each node.
+	Frames []string `json:"frames"`
+	DoF    []int    `json:"dof"`
+
+	Nodes [][]float64 `json:"nodes"`
+	Edges [][2]int    `json:"edges"`
+
+	// Lazy is true if the edges were not collision checked when the roadmap was built.
+	Lazy bool `json:"lazy"`
+
+	adjOnce   sync.Once
+	adjacency [][]int
+}
+
+// BuildRoadmap samples a roadmap for the frame system, start configuration and obstacles of the request. The start
+// configuration determines the inputs of frames that are not sampled, and which collisions are allowed. The goals of
+// the request are ignored.
+func BuildRoadmap(ctx context.Context, logger logging.Logger, req *PlanRequest, cfg RoadmapConfig) (*Roadmap, error) {
+	ctx, span := trace.StartSpan(ctx, "BuildRoadmap")
+	defer span.End()
+
+	if req == nil || req.FrameSystem == nil {
+		return nil, errors.New("roadmap requires a frame system")
+	}
+	if req.StartState == nil || req.StartState.structuredConfiguration == nil {
+		return nil, errors.New("roadmap requires a start configuration")
+	}
+	buildReq := *req
+	if buildReq.PlannerOptions == nil {
+		buildReq.PlannerOptions = NewBasicPlannerOptions()
+	}
+	if buildReq.Constraints == nil {
+		buildReq.Constraints = &motionplan.Constraints{}
+	}
+	cfg.setDefaults()
+
+	rm := &Roadmap{
+		FrameSystemHash: req.FrameSystem.Hash(),
+		ObstaclesHash:   obstaclesHash(req.ObstaclesInWorldFrame),
+		Lazy:            cfg.Lazy,
+	}
+	frames := cfg.Frames
+	if len(frames) == 0 {
+		for _, name := range req.FrameSystem.FrameNames() {
+			if len(req.FrameSystem.Frame(name).DoF()) > 0 {
+				frames = append(frames, name)
+			}
+		}
+	}
+	frames = slices.Clone(frames)
+	slices.Sort(frames)
+	if len(frames) == 0 {
+		return nil, errors.New("frame system has no frames with degrees of freedom to build a roadmap for")
+	}
+	for _, name := range frames {
+		f := req.FrameSystem.Frame(name)
+		if f == nil {
+			return nil, referenceframe.NewFrameMissingError(name)
+		}
+		if len(f.DoF()) == 0 {
+			return nil, fmt.Errorf("frame %q has no degrees of freedom", name)
+		}
+		rm.Frames = append(rm.Frames, name)
+		rm.DoF = append(rm.DoF, len(f.DoF()))
+	}
+
+	start := req.StartState.LinearConfiguration()
+	startPoses, err := start.ComputePoses(req.FrameSystem)
+	if err != nil {
+		return nil, err
+	}
+	// The checker needs goals to determine which frames move. Every sampled frame does.
+	goal := referenceframe.FrameSystemPoses{}
+	for _, name := range rm.Frames {
+		goal[name] = startPoses[name]
+	}
+	// Each worker gets its own segment context, and so its own checker and collision cache.
+	newSegmentContext := func() (*PlanSegmentContext, error) {
+		pc, err := NewPlanContext(ctx, logger, &buildReq, &PlanMeta{})
+		if err != nil {
+			return nil, err
+		}
+		return NewPlanSegmentContext(ctx, pc, start, goal)
+	}
+	pscs := make([]*PlanSegmentContext, cfg.NumThreads)
+	for i := range pscs {
+		if pscs[i], err = newSegmentContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	startTime := time.Now()
+	if err := rm.sampleNodes(ctx, pscs, start, cfg); err != nil {
+		return nil, err
+	}
+	logger.Infof("sampled %d roadmap nodes in %v", len(rm.Nodes), time.Since(startTime))
+
+	startTime = time.Now()
+	if err := rm.connectNodes(ctx, pscs, start, cfg); err != nil {
+		return nil, err
+	}
+	logger.Infof("connected roadmap with %d edges (lazy: %v) in %v", len(rm.Edges), rm.Lazy, time.Since(startTime))
+
+	return rm, nil
+}
+
+// sampleNodes fills the roadmap with collision-free configurations, sampling on every worker in parallel.
+func (rm *Roadmap) sampleNodes(ctx context.Context, pscs []*PlanSegmentContext, start *referenceframe.LinearInputs, cfg RoadmapConfig) error {
+	var accepted, attempts atomic.Int64
+	maxAttempts := int64(cfg.Nodes * roadmapSampleAttemptsPerNode)
+	perWorker := make([][][]float64, len(pscs))
+
+	var wg sync.WaitGroup
+	for x := range pscs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			psc := pscs[x]
+			//nolint:gosec
+			rseed := rand.New(rand.NewSource(int64(cfg.RandomSeed + x)))
+			for ctx.Err() == nil && accepted.Load() < int64(cfg.Nodes) && attempts.Add(1) <= maxAttempts {
+				sample := make([]float64, 0, len(psc.pc.lis.GetLimits()))
+				for _, name := range rm.Frames {
+					sample = append(sample, referenceframe.RandomFrameInputs(psc.pc.fs.Frame(name), rseed)...)
+				}
+				_, err := psc.Checker.CheckStateFSConstraints(ctx, &motionplan.StateFS{
+					Configuration: rm.inputs(start, sample),
+					FS:            psc.pc.fs,
+				})
+				if err != nil {
+					continue
+				}
+				if accepted.Add(1) <= int64(cfg.Nodes) {
+					perWorker[x] = append(perWorker[x], sample)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for _, samples := range perWorker {
+		rm.Nodes = append(rm.Nodes, samples...)
+	}
+	if len(rm.Nodes) < 2 {
+		return fmt.Errorf("only found %d collision-free configurations in %d attempts", len(rm.Nodes), maxAttempts)
+	}
+	return nil
+}
+
+// connectNodes links every node to its nearest neighbors. Unless the roadmap is lazy, edges are collision checked in
+// parallel and edges that fail are dropped.
+func (rm *Roadmap) connectNodes(ctx context.Context, pscs []*PlanSegmentContext, start *referenceframe.LinearInputs, cfg RoadmapConfig) error {
+	seen := map[[2]int]bool{}
+	var candidates [][2]int
+	for i := range rm.Nodes {
+		for _, j := range rm.nearest(rm.Nodes[i], cfg.Neighbors, i) {
+			edge := [2]int{min(i, j), max(i, j)}
+			if !seen[edge] {
+				seen[edge] = true
+				candidates = append(candidates, edge)
+			}
+		}
+	}
+	slices.SortFunc(candidates, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
+	if rm.Lazy {
+		rm.Edges = candidates
+		return nil
+	}
+
+	valid := make([]bool, len(candidates))
+	var mainErr error
+	var errLock sync.Mutex
+	var wg sync.WaitGroup
+	for x := range pscs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			psc := pscs[x]
+			for i := x; i < len(candidates); i += len(pscs) {
+				if ctx.Err() != nil {
+					errLock.Lock()
+					mainErr = multierr.Combine(mainErr, ctx.Err())
+					errLock.Unlock()
+					return
+				}
+				edge := candidates[i]
+				valid[i] = psc.CheckPath(ctx, rm.inputs(start, rm.Nodes[edge[0]]), rm.inputs(start, rm.Nodes[edge[1]]), false, nil) == nil
+			}
+		}()
+	}
+	wg.Wait()
+	if mainErr != nil {
+		return mainErr
+	}
+
+	for i, edge := range candidates {
+		if valid[i] {
+			rm.Edges = append(rm.Edges, edge)
+		}
+	}
+	return nil
+}
+
+// Matches returns whether the roadmap was built for the frame system and obstacles of the request.
+func (rm *Roadmap) Matches(req *PlanRequest) bool {
+	return req.FrameSystem != nil &&
+		rm.FrameSystemHash == req.FrameSystem.Hash() &&
+		rm.ObstaclesHash == obstaclesHash(req.ObstaclesInWorldFrame)
+}
+
+// WriteToFile writes the roadmap to a .json file.
+func (rm *Roadmap) WriteToFile(fileName string) error {
+	data, err := json.Marshal(rm)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(fileName), data, 0o600)
+}
+
+// ReadRoadmapFromFile reads a roadmap written by WriteToFile.
+func ReadRoadmapFromFile(fileName string) (*Roadmap, error) {
+	//nolint:gosec
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	rm := &Roadmap{}
+	if err := json.Unmarshal(data, rm); err != nil {
+		return nil, err
+	}
+	if len(rm.Frames) != len(rm.DoF) {
+		return nil, fmt.Errorf("roadmap %s has %d frames but %d dof entries", fileName, len(rm.Frames), len(rm.DoF))
+	}
+	dof := 0
+	for _, d := range rm.DoF {
+		dof += d
+	}
+	for i, n := range rm.Nodes {
+		if len(n) != dof {
+			return nil, fmt.Errorf("roadmap %s node %d has %d inputs, expected %d", fileName, i, len(n), dof)
+		}
+	}
+	for _, e := range rm.Edges {
+		if e[0] < 0 || e[1] < 0 || e[0] >= len(rm.Nodes) || e[1] >= len(rm.Nodes) {
+			return nil, fmt.Errorf("roadmap %s has edge %v referencing a missing node", fileName, e)
+		}
+	}
+	return rm, nil
+}
+
+type loadedRoadmap struct {
+	modTime time.Time
+	size    int64
+	rm      *Roadmap
+}
+
+var (
+	loadedRoadmapsMu sync.Mutex
+	loadedRoadmaps   = map[string]loadedRoadmap{}
+)
+
+// loadRoadmap reads a roadmap from disk, reusing the previously read roadmap if the file has not changed.
+func loadRoadmap(fileName string) (*Roadmap, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	loadedRoadmapsMu.Lock()
+	defer loadedRoadmapsMu.Unlock()
+	if cached, ok := loadedRoadmaps[fileName]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.rm, nil
+	}
+	rm, err := ReadRoadmapFromFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	loadedRoadmaps[fileName] = loadedRoadmap{modTime: info.ModTime(), size: info.Size(), rm: rm}
+	return rm, nil
+}
+
+// obstaclesHash returns an order independent hash of the obstacles.
+func obstaclesHash(obstacles *referenceframe.GeometriesInFrame) int {
+	if obstacles == nil {
+		return 0
+	}
+	h := 0
+	for _, g := range obstacles.Geometries() {
+		h += g.Hash()
+	}
+	return h
+}
+
+// inputs returns base with the roadmap frames replaced by the values of the flattened sample.
+func (rm *Roadmap) inputs(base *referenceframe.LinearInputs, sample []float64) *referenceframe.LinearInputs {
+	ret := base.Copy()
+	offset := 0
+	for i, name := range rm.Frames {
+		ret.Put(name, sample[offset:offset+rm.DoF[i]])
+		offset += rm.DoF[i]
+	}
+	return ret
+}
+
+// flatten extracts the roadmap frames from a configuration. It returns false if a frame is missing.
+func (rm *Roadmap) flatten(li *referenceframe.LinearInputs) ([]float64, bool) {
+	var ret []float64
+	for i, name := range rm.Frames {
+		in := li.Get(name)
+		if len(in) != rm.DoF[i] {
+			return nil, false
+		}
+		ret = append(ret, in...)
+	}
+	return ret, true
+}
+
+// covers returns whether all motion between start and goal happens in roadmap frames.
+func (rm *Roadmap) covers(start, goal *referenceframe.LinearInputs) bool {
+	for name, in := range goal.Items() {
+		if slices.Contains(rm.Frames, name) {
+			continue
+		}
+		if referenceframe.InputsL2Distance(in, start.Get(name)) > defaultInputIdentDist {
+			return false
+		}
+	}
+	return true
+}
+
+func sampleDistance(a, b []float64) float64 {
+	dist := 0.
+	for i := range a {
+		dist += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Sqrt(dist)
+}
+
+// nearest returns the indices of the k roadmap nodes closest to sample, excluding the node at index skip.
+func (rm *Roadmap) nearest(sample []float64, k, skip int) []int {
+	type neighbor struct {
+		idx  int
+		dist float64
+	}
+	neighbors := make([]neighbor, 0, len(rm.Nodes))
+	for i, n := range rm.Nodes {
+		if i != skip {
+			neighbors = append(neighbors, neighbor{i, sampleDistance(sample, n)})
+		}
+	}
+	sort.Slice(neighbors, func(i, j int) bool { return neighbors[i].dist < neighbors[j].dist })
+	ret := make([]int, 0, k)
+	for _, n := range neighbors[:min(k, len(neighbors))] {
+		ret = append(ret, n.idx)
+	}
+	return ret
+}
+
+func (rm *Roadmap) buildAdjacency() {
+	rm.adjOnce.Do(func() {
+		rm.adjacency = make([][]int, len(rm.Nodes))
+		for _, e := range rm.Edges {
+			rm.adjacency[e[0]] = append(rm.adjacency[e[0]], e[1])
+			rm.adjacency[e[1]] = append(rm.adjacency[e[1]], e[0])
+		}
+	})
+}
+
+// findPath connects start and goal to the roadmap and searches it for the shortest path between them. Edges on
+// candidate paths are checked against the constraints of psc as they are encountered; invalid edges are removed and
+// the search is repeated (lazy PRM). The returned path begins with start and ends with goal.
+func (rm *Roadmap) findPath(
+	ctx context.Context,
+	psc *PlanSegmentContext,
+	start, goal *referenceframe.LinearInputs,
+	neighbors int,
+) ([]*referenceframe.LinearInputs, error) {
+	ctx, span := trace.StartSpan(ctx, "Roadmap::findPath")
+	defer span.End()
+
+	if !rm.covers(start, goal) {
+		return nil, errors.New("motion requires frames that are not in the roadmap")
+	}
+	startSample, ok := rm.flatten(start)
+	if !ok {
+		return nil, errors.New("start configuration does not match the roadmap frames")
+	}
+	goalSample, ok := rm.flatten(goal)
+	if !ok {
+		return nil, errors.New("goal configuration does not match the roadmap frames")
+	}
+	rm.buildAdjacency()
+
+	// The start and goal are temporary nodes appended after the roadmap's own nodes.
+	startIdx, goalIdx := len(rm.Nodes), len(rm.Nodes)+1
+	samples := func(i int) []float64 {
+		switch i {
+		case startIdx:
+			return startSample
+		case goalIdx:
+			return goalSample
+		default:
+			return rm.Nodes[i]
+		}
+	}
+	configuration := func(i int) *referenceframe.LinearInputs {
+		switch i {
+		case startIdx:
+			return start
+		case goalIdx:
+			return goal
+		default:
+			return rm.inputs(start, rm.Nodes[i])
+		}
+	}
+	startNeighbors := rm.nearest(startSample, neighbors, -1)
+	goalNeighbors := rm.nearest(goalSample, neighbors, -1)
+	edgesFrom := func(i int) []int {
+		switch i {
+		case startIdx:
+			return startNeighbors
+		case goalIdx:
+			return goalNeighbors
+		}
+		ret := rm.adjacency[i]
+		if slices.Contains(startNeighbors, i) {
+			ret = append(slices.Clip(ret), startIdx)
+		}
+		if slices.Contains(goalNeighbors, i) {
+			ret = append(slices.Clip(ret), goalIdx)
+		}
+		return ret
+	}
+
+	invalid := map[[2]int]bool{}
+	verified := map[[2]int]bool{}
+	for search := 0; search < maxRoadmapSearches; search++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		path := shortestRoadmapPath(startIdx, goalIdx, edgesFrom, samples, invalid)
+		if path == nil {
+			return nil, errRoadmapNoPath
+		}
+
+		blocked := false
+		for i := 1; i < len(path); i++ {
+			edge := [2]int{min(path[i-1], path[i]), max(path[i-1], path[i])}
+			if verified[edge] {
+				continue
+			}
+			if err := psc.CheckPath(ctx, configuration(path[i-1]), configuration(path[i]), i == len(path)-1, nil); err != nil {
+				invalid[edge] = true
+				blocked = true
+				break
+			}
+			verified[edge] = true
+		}
+		if blocked {
+			continue
+		}
+
+		steps := make([]*referenceframe.LinearInputs, 0, len(path))
+		for _, idx := range path {
+			steps = append(steps, configuration(idx))
+		}
+		return steps, nil
+	}
+	return nil, errRoadmapNoPath
+}
+
+// shortestRoadmapPath runs A* from start to goal using the Euclidean distance between samples as both the edge cost and
+// the heuristic. Returns nil if goal is unreachable.
+func shortestRoadmapPath(
+	start, goal int,
+	edgesFrom func(int) []int,
+	samples func(int) []float64,
+	invalid map[[2]int]bool,
+) []int {
+	cost := map[int]float64{start: 0}
+	parent := map[int]int{}
+	closed := map[int]bool{}
+	open := &roadmapQueue{{idx: start, priority: sampleDistance(samples(start), samples(goal))}}
+
+	for open.Len() > 0 {
+		cur := heap.Pop(open).(roadmapQueueItem).idx
+		if cur == goal {
+			path := []int{goal}
+			for path[len(path)-1] != start {
+				path = append(path, parent[path[len(path)-1]])
+			}
+			slices.Reverse(path)
+			return path
+		}
+		if closed[cur] {
+			continue
+		}
+		closed[cur] = true
+
+		for _, next := range edgesFrom(cur) {
+			if closed[next] || invalid[[2]int{min(cur, next), max(cur, next)}] {
+				continue
+			}
+			c := cost[cur] + sampleDistance(samples(cur), samples(next))
+			if old, ok := cost[next]; ok && old <= c {
+				continue
+			}
+			cost[next] = c
+			parent[next] = cur
+			heap.Push(open, roadmapQueueItem{idx: next, priority: c + sampleDistance(samples(next), samples(goal))})
+		}
+	}
+	return nil
+}
+
+type roadmapQueueItem struct {
+	idx      int
+	priority float64
+}
+
+// roadmapQueue is a min-heap of roadmap nodes ordered by priority.
+type roadmapQueue []roadmapQueueItem
+
+func (q roadmapQueue) Len() int           { return len(q) }
+func (q roadmapQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q roadmapQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *roadmapQueue) Push(x any) { *q = append(*q, x.(roadmapQueueItem)) }
+
+func (q *roadmapQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,112 @@
+package armplanning
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+func TestRoadmap(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+
+	model, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "xarm6")
+	test.That(t, err, test.ShouldBeNil)
+	fs := referenceframe.NewEmptyFrameSystem("")
+	test.That(t, fs.AddFrame(model, fs.World()), test.ShouldBeNil)
+
+	box, err := spatialmath.NewBox(spatialmath.NewPoseFromPoint(r3.Vector{X: 400, Z: 200}), r3.Vector{X: 100, Y: 100, Z: 100}, "box")
+	test.That(t, err, test.ShouldBeNil)
+	obstacles := referenceframe.NewGeometriesInFrame(referenceframe.World, []spatialmath.Geometry{box})
+
+	newRequest := func(start, goal referenceframe.FrameSystemInputs, obstacles *referenceframe.GeometriesInFrame) *PlanRequest {
+		opts := NewBasicPlannerOptions()
+		// Randomly sampled configurations are far apart; a coarse resolution keeps edge checks fast.
+		opts.Resolution = 20
+		req := &PlanRequest{
+			FrameSystem:           fs,
+			StartState:            NewPlanState(nil, start),
+			ObstaclesInWorldFrame: obstacles,
+			PlannerOptions:        opts,
+		}
+		if goal != nil {
+			req.Goals = []*PlanState{NewPlanState(nil, goal)}
+		}
+		return req
+	}
+	home := referenceframe.FrameSystemInputs{"xarm6": make([]referenceframe.Input, 6)}
+
+	for _, lazy := range []bool{false, true} {
+		rm, err := BuildRoadmap(ctx, logger, newRequest(home, nil, obstacles), RoadmapConfig{
+			Nodes:      60,
+			Neighbors:  5,
+			Lazy:       lazy,
+			NumThreads: 4,
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, rm.Frames, test.ShouldResemble, []string{"xarm6"})
+		test.That(t, len(rm.Nodes), test.ShouldEqual, 60)
+		test.That(t, len(rm.Edges), test.ShouldBeGreaterThan, 0)
+		test.That(t, rm.Lazy, test.ShouldEqual, lazy)
+
+		// Query between two roadmap nodes; every returned segment must be valid.
+		start := referenceframe.FrameSystemInputs{"xarm6": rm.Nodes[0]}
+		goal := referenceframe.FrameSystemInputs{"xarm6": rm.Nodes[len(rm.Nodes)-1]}
+		req := newRequest(start, goal, obstacles)
+		req.Constraints = nil
+		test.That(t, req.validatePlanRequest(), test.ShouldBeNil)
+		test.That(t, rm.Matches(req), test.ShouldBeTrue)
+
+		pc, err := NewPlanContext(ctx, logger, req, &PlanMeta{})
+		test.That(t, err, test.ShouldBeNil)
+		goalPoses, err := req.Goals[0].ComputePoses(ctx, fs)
+		test.That(t, err, test.ShouldBeNil)
+		psc, err := NewPlanSegmentContext(ctx, pc, start.ToLinearInputs(), goalPoses)
+		test.That(t, err, test.ShouldBeNil)
+
+		path, err := rm.findPath(ctx, psc, start.ToLinearInputs(), goal.ToLinearInputs(), defaultRoadmapNeighbors)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(path), test.ShouldBeGreaterThanOrEqualTo, 2)
+		test.That(t, path[0].Get("xarm6"), test.ShouldResemble, rm.Nodes[0])
+		test.That(t, path[len(path)-1].Get("xarm6"), test.ShouldResemble, rm.Nodes[len(rm.Nodes)-1])
+		for i := 1; i < len(path); i++ {
+			test.That(t, psc.CheckPath(ctx, path[i-1], path[i], true, nil), test.ShouldBeNil)
+		}
+
+		// Round trip through a file.
+		fileName := filepath.Join(t.TempDir(), "roadmap.json")
+		test.That(t, rm.WriteToFile(fileName), test.ShouldBeNil)
+		loaded, err := loadRoadmap(fileName)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, loaded.Nodes, test.ShouldResemble, rm.Nodes)
+		test.That(t, loaded.Edges, test.ShouldResemble, rm.Edges)
+		test.That(t, loaded.Matches(req), test.ShouldBeTrue)
+		again, err := loadRoadmap(fileName)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, again, test.ShouldEqual, loaded)
+
+		// A different world does not match.
+		test.That(t, loaded.Matches(newRequest(start, goal, nil)), test.ShouldBeFalse)
+	}
+}
+
+func TestShortestRoadmapPath(t *testing.T) {
+	// 0 - 1 - 3
+	//  \- 2 -/
+	samples := [][]float64{{0, 0}, {1, 1}, {1, -3}, {2, 0}}
+	adjacency := [][]int{{1, 2}, {0, 3}, {0, 3}, {1, 2}}
+	edgesFrom := func(i int) []int { return adjacency[i] }
+	sample := func(i int) []float64 { return samples[i] }
+
+	test.That(t, shortestRoadmapPath(0, 3, edgesFrom, sample, map[[2]int]bool{}), test.ShouldResemble, []int{0, 1, 3})
+	test.That(t, shortestRoadmapPath(0, 3, edgesFrom, sample, map[[2]int]bool{{1, 3}: true}), test.ShouldResemble, []int{0, 2, 3})
+	test.That(t, shortestRoadmapPath(0, 3, edgesFrom, sample, map[[2]int]bool{{1, 3}: true, {0, 2}: true}), test.ShouldBeNil)
+}
@@ -42,7 +42,10 @@ func smoothPathSimple(ctx context.Context, psc *PlanSegmentContext,
 	steps = simpleSmoothStep(ctx, psc, steps, 3)
 	steps = simpleSmoothStep(ctx, psc, steps, 1)
 
-	steps = tryOnlyMovingComponentsThatNeedToMove(ctx, psc, steps)
+	// Components which must move in lockstep cannot be moved one at a time.
+	if !psc.pc.request.Constraints.Synchronized() {
+		steps = tryOnlyMovingComponentsThatNeedToMove(ctx, psc, steps)
+	}
 
 	if len(steps) != originalSize {
 		psc.pc.logger.Debugf("simpleSmooth %d -> %d in %v", originalSize, len(steps), time.Since(start))
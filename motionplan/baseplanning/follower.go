@@ -0,0 +1,398 @@
+// Package baseplanning drives wheeled bases along paths in the plane, including bases which can't turn tighter than a
+// minimum radius, such as Ackermann steered bases.
+package baseplanning
+
+import (
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/utils"
+)
+
+const (
+	// the lookahead of pure pursuit is the distance the base drives in this long, but no less than the minimum.
+	pursuitLookaheadSec   = 1.5
+	minPursuitLookaheadMM = 500.
+	// headings further than this from the direction to follow are turned to by spinning in place first.
+	maxFollowHeadingErrorRad = math.Pi / 2
+
+	mpcHorizonSteps = 20
+	mpcStepSec      = 0.1
+	// the errors the MPC weighs equally with turning at the maximum angular velocity.
+	mpcLateralErrorMM        = 100.
+	mpcHeadingErrorRad       = 0.1
+	minApproachSpeedFraction = 0.2
+	// how far beyond its progress the base is matched to the path, in seconds of driving.
+	pathSearchWindowSec = 2.
+)
+
+// Pose is the pose of a base in the plane, in mm, with its heading Theta in radians counterclockwise from the X axis.
+type Pose struct {
+	X, Y, Theta float64
+}
+
+// FollowerConfig describes the base a PathFollower drives and how.
+type FollowerConfig struct {
+	// LinearMMPerSec is the speed to drive along the path at.
+	LinearMMPerSec float64
+	// AngularDegsPerSec is the fastest the base may turn.
+	AngularDegsPerSec float64
+	// TurningRadiusMM is the radius of the tightest turn the base can make, which it is never asked to turn tighter
+	// than. It is zero for a base which can spin in place.
+	TurningRadiusMM float64
+	// Reverse drives the base backward along the path, following it as though the base faced the other way.
+	Reverse bool
+}
+
+// A PathFollower computes the velocities which drive a base along a path of straight segments in the plane from the
+// base's current pose. A follower tracks the progress of the base along its path, so that a path which comes back
+// near itself is followed in order, and is used for a single traversal.
+type PathFollower interface {
+	// Velocities returns the forward (mm/s) and counterclockwise angular (deg/s) velocities to drive at from the pose.
+	Velocities(pose Pose) (float64, float64)
+}
+
+// errEmptyPath is returned when a follower is made for a path without any points.
+var errEmptyPath = errors.New("a path to follow must have at least one point")
+
+// NewPurePursuit returns a PathFollower which steers the base along the arc which meets the path a lookahead distance
+// ahead of it. A path of a single point has nowhere to drive along, so the base is kept stopped.
+func NewPurePursuit(path []r3.Vector, cfg FollowerConfig) (PathFollower, error) {
+	if len(path) == 0 {
+		return nil, errEmptyPath
+	}
+	if len(path) == 1 {
+		return stopped{}, nil
+	}
+	follower := &purePursuit{
+		tracker:          newPathTracker(path, cfg.LinearMMPerSec*pathSearchWindowSec),
+		lookaheadMM:      math.Max(minPursuitLookaheadMM, cfg.LinearMMPerSec*pursuitLookaheadSec),
+		linearMMPerSec:   cfg.LinearMMPerSec,
+		angularRadPerSec: utils.DegToRad(cfg.AngularDegsPerSec),
+		turningRadiusMM:  cfg.TurningRadiusMM,
+	}
+	if cfg.Reverse {
+		return reversing{follower}, nil
+	}
+	return follower, nil
+}
+
+// NewMPC returns a PathFollower which steers the base with a linear model predictive controller, which previews the
+// turns of the path over a short horizon and starts turning into them early. A path of a single point has nowhere to
+// drive along, so the base is kept stopped.
+func NewMPC(path []r3.Vector, cfg FollowerConfig) (PathFollower, error) {
+	if len(path) == 0 {
+		return nil, errEmptyPath
+	}
+	if len(path) == 1 {
+		return stopped{}, nil
+	}
+	follower := &mpc{
+		tracker:          newPathTracker(path, cfg.LinearMMPerSec*pathSearchWindowSec),
+		linearMMPerSec:   cfg.LinearMMPerSec,
+		angularRadPerSec: utils.DegToRad(cfg.AngularDegsPerSec),
+		turningRadiusMM:  cfg.TurningRadiusMM,
+	}
+	if cfg.Reverse {
+		return reversing{follower}, nil
+	}
+	return follower, nil
+}
+
+// stopped follows a path of a single point, which the base is already at the end of.
+type stopped struct{}
+
+func (stopped) Velocities(Pose) (float64, float64) {
+	return 0, 0
+}
+
+// headingDiff returns the angle in radians from b to a, in (-π, π].
+func headingDiff(a, b float64) float64 {
+	return utils.DegToRad(180 - utils.ModAngDeg(180-utils.RadToDeg(a-b)))
+}
+
+// forward returns the unit vector the pose faces.
+func forward(pose Pose) r3.Vector {
+	return r3.Vector{X: math.Cos(pose.Theta), Y: math.Sin(pose.Theta)}
+}
+
+// left returns the unit vector to the left of the pose.
+func left(pose Pose) r3.Vector {
+	return r3.Vector{X: -math.Sin(pose.Theta), Y: math.Cos(pose.Theta)}
+}
+
+// reversing drives a base backward along a path, by following it as though the base faced the other way.
+type reversing struct {
+	PathFollower
+}
+
+func (r reversing) Velocities(pose Pose) (float64, float64) {
+	pose.Theta += math.Pi
+	linear, angular := r.PathFollower.Velocities(pose)
+	return -linear, angular
+}
+
+// pathTracker tracks the progress of a base along a path of straight segments.
+type pathTracker struct {
+	path []r3.Vector
+	// cumulative is the distance along the path to each of its points.
+	cumulative []float64
+	// progress is the distance along the path the base has reached, which never goes backward so that a path which
+	// comes back near itself is followed in order.
+	progress float64
+	// window is how far beyond the progress the base may be matched to the path.
+	window float64
+}
+
+// newPathTracker returns a tracker of the progress along path, which must have at least one point.
+func newPathTracker(path []r3.Vector, window float64) *pathTracker {
+	cumulative := make([]float64, len(path))
+	for i := 1; i < len(path); i++ {
+		cumulative[i] = cumulative[i-1] + path[i].Sub(path[i-1]).Norm()
+	}
+	return &pathTracker{path: path, cumulative: cumulative, window: window}
+}
+
+func (pt *pathTracker) length() float64 {
+	return pt.cumulative[len(pt.cumulative)-1]
+}
+
+func (pt *pathTracker) remaining() float64 {
+	return pt.length() - pt.progress
+}
+
+// update advances the progress to the point on the path nearest to p, returning the distance to that point.
+func (pt *pathTracker) update(p r3.Vector) float64 {
+	best, bestDist := pt.progress, p.Sub(pt.pointAt(pt.progress)).Norm()
+	for i := 1; i < len(pt.path); i++ {
+		if pt.cumulative[i] < pt.progress || pt.cumulative[i-1] > pt.progress+pt.window {
+			continue
+		}
+		a, b := pt.path[i-1], pt.path[i]
+		segment := b.Sub(a)
+		along := 0.
+		if l := segment.Norm2(); l > 0 {
+			along = math.Max(0, math.Min(1, p.Sub(a).Dot(segment)/l))
+		}
+		s := pt.cumulative[i-1] + along*(pt.cumulative[i]-pt.cumulative[i-1])
+		if s < pt.progress || s > pt.progress+pt.window {
+			continue
+		}
+		if dist := p.Sub(a.Add(segment.Mul(along))).Norm(); dist < bestDist {
+			best, bestDist = s, dist
+		}
+	}
+	pt.progress = best
+	return bestDist
+}
+
+// segmentAt returns the index of the end of the segment the distance s along the path is on.
+func (pt *pathTracker) segmentAt(s float64) int {
+	for i := 1; i < len(pt.path); i++ {
+		if s <= pt.cumulative[i] {
+			return i
+		}
+	}
+	return len(pt.path) - 1
+}
+
+// pointAt returns the point the distance s along the path, clamped to its ends.
+func (pt *pathTracker) pointAt(s float64) r3.Vector {
+	if len(pt.path) == 1 || s <= 0 {
+		return pt.path[0]
+	}
+	i := pt.segmentAt(s)
+	l := pt.cumulative[i] - pt.cumulative[i-1]
+	if l == 0 {
+		return pt.path[i]
+	}
+	along := math.Min(1, (s-pt.cumulative[i-1])/l)
+	return pt.path[i-1].Add(pt.path[i].Sub(pt.path[i-1]).Mul(along))
+}
+
+// headingAt returns the direction of the path the distance s along it, in radians counterclockwise from the X axis.
+func (pt *pathTracker) headingAt(s float64) float64 {
+	if len(pt.path) == 1 {
+		return 0
+	}
+	d := pt.path[pt.segmentAt(s)].Sub(pt.path[pt.segmentAt(s)-1])
+	return math.Atan2(d.Y, d.X)
+}
+
+// approachSpeed returns the speed to drive at, slowing over the last stretch of the path so as not to overshoot its
+// end.
+func approachSpeed(maxSpeed, remaining, slowingDistance float64) float64 {
+	return maxSpeed * math.Max(minApproachSpeedFraction, math.Min(1, remaining/slowingDistance))
+}
+
+// turnToward returns the velocities which turn the base toward a heading error, for when it is facing too far from
+// the path to drive along it. A base which can spin in place does so, and one with a turning radius drives slowly
+// around its tightest turn.
+func turnToward(headingError, linearMMPerSec, angularRadPerSec, turningRadiusMM float64) (float64, float64) {
+	if turningRadiusMM <= 0 {
+		return 0, utils.RadToDeg(math.Copysign(angularRadPerSec, headingError))
+	}
+	speed := math.Min(minApproachSpeedFraction*linearMMPerSec, angularRadPerSec*turningRadiusMM)
+	return speed, utils.RadToDeg(math.Copysign(speed/turningRadiusMM, headingError))
+}
+
+// purePursuit steers the base along the arc which meets the path a lookahead distance ahead of it.
+type purePursuit struct {
+	tracker          *pathTracker
+	lookaheadMM      float64
+	linearMMPerSec   float64
+	angularRadPerSec float64
+	turningRadiusMM  float64
+}
+
+func (pp *purePursuit) Velocities(pose Pose) (float64, float64) {
+	position := r3.Vector{X: pose.X, Y: pose.Y}
+	pp.tracker.update(position)
+	target := pp.tracker.pointAt(pp.tracker.progress + pp.lookaheadMM)
+	d := target.Sub(position)
+	ahead, toLeft := d.Dot(forward(pose)), d.Dot(left(pose))
+	headingError := math.Atan2(toLeft, ahead)
+	if math.Abs(headingError) > maxFollowHeadingErrorRad {
+		return turnToward(headingError, pp.linearMMPerSec, pp.angularRadPerSec, pp.turningRadiusMM)
+	}
+
+	// the arc through the target tangent to the heading has a curvature of 2y/L², where y is how far the target is
+	// to the left and L how far it is away
+	curvature := 2 * toLeft / math.Max(d.Norm2(), 1)
+	if pp.turningRadiusMM > 0 && math.Abs(curvature) > 1/pp.turningRadiusMM {
+		curvature = math.Copysign(1/pp.turningRadiusMM, curvature)
+	}
+	speed := approachSpeed(pp.linearMMPerSec, pp.tracker.remaining(), pp.lookaheadMM)
+	angular := speed * curvature
+	if math.Abs(angular) > pp.angularRadPerSec {
+		// turning sharper than the base can at this speed, so slow down to keep to the arc
+		angular = math.Copysign(pp.angularRadPerSec, angular)
+		speed = pp.angularRadPerSec / math.Abs(curvature)
+	}
+	return speed, utils.RadToDeg(angular)
+}
+
+// mpc steers the base with a linear model predictive controller. The base's lateral error d and heading error θ from
+// a reference running along the path are modeled as d' = vθ, θ' = ω - ψ', linearized about the reference, where v is
+// the speed, ω the turn rate and ψ the direction of the reference over the horizon, which is how the controller
+// anticipates turns. The quadratic cost of the errors and the turn rate is minimized over the horizon by a Riccati
+// recursion, and the first turn rate of the optimal sequence is commanded, limited to what the base can do.
+type mpc struct {
+	tracker          *pathTracker
+	linearMMPerSec   float64
+	angularRadPerSec float64
+	turningRadiusMM  float64
+}
+
+// mat2 is a 2x2 matrix.
+type mat2 [2][2]float64
+
+func (m mat2) mul(n mat2) mat2 {
+	var out mat2
+	for i := range 2 {
+		for j := range 2 {
+			out[i][j] = m[i][0]*n[0][j] + m[i][1]*n[1][j]
+		}
+	}
+	return out
+}
+
+func (m mat2) transpose() mat2 {
+	return mat2{{m[0][0], m[1][0]}, {m[0][1], m[1][1]}}
+}
+
+func (m mat2) mulVec(v [2]float64) [2]float64 {
+	return [2]float64{m[0][0]*v[0] + m[0][1]*v[1], m[1][0]*v[0] + m[1][1]*v[1]}
+}
+
+func (c *mpc) Velocities(pose Pose) (float64, float64) {
+	position := r3.Vector{X: pose.X, Y: pose.Y}
+	c.tracker.update(position)
+	s := c.tracker.progress
+	speed := approachSpeed(c.linearMMPerSec, c.tracker.remaining(), c.linearMMPerSec*pursuitLookaheadSec)
+	dt := mpcStepSec
+	reference := c.referenceHeadings(s, speed*dt, c.angularRadPerSec*dt)
+
+	// the errors are relative to the reference, which near a corner has already started to turn
+	offset := position.Sub(c.tracker.pointAt(s))
+	lateral := offset.Dot(left(Pose{Theta: reference[0]}))
+	headingError := headingDiff(pose.Theta, reference[0])
+	if math.Abs(headingError) > maxFollowHeadingErrorRad {
+		return turnToward(-headingError, c.linearMMPerSec, c.angularRadPerSec, c.turningRadiusMM)
+	}
+
+	a := mat2{{1, speed * dt}, {0, 1}}
+	b := [2]float64{0, dt}
+	q := mat2{{1 / (mpcLateralErrorMM * mpcLateralErrorMM), 0}, {0, 1 / (mpcHeadingErrorRad * mpcHeadingErrorRad)}}
+	r := 1 / (c.angularRadPerSec * c.angularRadPerSec)
+
+	// the cost to go from step k is xᵀPx + 2pᵀx, found backward from the end of the horizon
+	p, pLin := q, [2]float64{}
+	var gain [2]float64
+	var offsetGain float64
+	for k := mpcHorizonSteps - 1; k >= 0; k-- {
+		// the reference turning moves the heading error the other way
+		disturbance := [2]float64{0, -(reference[k+1] - reference[k])}
+
+		bp := [2]float64{b[0]*p[0][0] + b[1]*p[1][0], b[0]*p[0][1] + b[1]*p[1][1]}
+		scale := r + bp[0]*b[0] + bp[1]*b[1]
+		bpa := [2]float64{bp[0]*a[0][0] + bp[1]*a[1][0], bp[0]*a[0][1] + bp[1]*a[1][1]}
+		gain = [2]float64{bpa[0] / scale, bpa[1] / scale}
+
+		pc := p.mulVec(disturbance)
+		g := [2]float64{pc[0] + pLin[0], pc[1] + pLin[1]}
+		offsetGain = (b[0]*g[0] + b[1]*g[1]) / scale
+
+		// the closed loop, with u = -Kx - k
+		closed := mat2{{a[0][0] - b[0]*gain[0], a[0][1] - b[0]*gain[1]}, {a[1][0] - b[1]*gain[0], a[1][1] - b[1]*gain[1]}}
+		atpa := a.transpose().mul(p).mul(a)
+		var next mat2
+		for i := range 2 {
+			for j := range 2 {
+				next[i][j] = q[i][j] + atpa[i][j] - gain[i]*scale*gain[j]
+			}
+		}
+		residual := [2]float64{disturbance[0] - b[0]*offsetGain, disturbance[1] - b[1]*offsetGain}
+		pr := p.mulVec(residual)
+		carried := closed.transpose().mulVec([2]float64{pr[0] + pLin[0], pr[1] + pLin[1]})
+		pLin = [2]float64{gain[0]*r*offsetGain + carried[0], gain[1]*r*offsetGain + carried[1]}
+		p = next
+	}
+
+	angular := -gain[0]*lateral - gain[1]*headingError - offsetGain
+	limit := c.angularRadPerSec
+	if c.turningRadiusMM > 0 {
+		limit = math.Min(limit, speed/c.turningRadiusMM)
+	}
+	angular = math.Max(-limit, math.Min(limit, angular))
+	return speed, utils.RadToDeg(angular)
+}
+
+// referenceHeadings returns the direction of the path at each step of the horizon from s, unwrapped so that it
+// changes continuously. Corners, where the direction changes at once, are spread into turns no faster than maxTurn a
+// step and centered on the corner, which an unconstrained controller would otherwise plan to turn through in a single
+// step instead of anticipating.
+func (c *mpc) referenceHeadings(s, step, maxTurn float64) []float64 {
+	raw := make([]float64, mpcHorizonSteps+1)
+	raw[0] = c.tracker.headingAt(s)
+	for k := 1; k <= mpcHorizonSteps; k++ {
+		raw[k] = raw[k-1] + headingDiff(c.tracker.headingAt(s+step*float64(k)), c.tracker.headingAt(s+step*float64(k-1)))
+	}
+	limit := func(from, to float64) float64 {
+		return from + math.Max(-maxTurn, math.Min(maxTurn, to-from))
+	}
+	// turning after each corner, and turning before it, averaged
+	late, early := make([]float64, len(raw)), make([]float64, len(raw))
+	late[0], early[len(raw)-1] = raw[0], raw[len(raw)-1]
+	for k := 1; k < len(raw); k++ {
+		late[k] = limit(late[k-1], raw[k])
+		early[len(raw)-1-k] = limit(early[len(raw)-k], raw[len(raw)-1-k])
+	}
+	reference := make([]float64, len(raw))
+	for k := range raw {
+		reference[k] = (late[k] + early[k]) / 2
+	}
+	return reference
+}
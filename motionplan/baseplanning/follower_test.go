@@ -0,0 +1,138 @@
+package baseplanning
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/utils"
+)
+
+const (
+	// the distance from the end of the path at which a base driven by a follower has arrived.
+	arrivalToleranceMM = 100.
+	// the distance from the end of the path at which a base with a 2m turning radius, which joins the path late, has
+	// arrived. Correcting a lateral offset d takes an S bend about 2√(Rd) long, so the last of the offset left at the
+	// end of a short path can't be taken out.
+	wideTurnArrivalToleranceMM = 300.
+)
+
+// newFollower is the constructor of a kind of PathFollower.
+type newFollower func(path []r3.Vector, cfg FollowerConfig) (PathFollower, error)
+
+// driveFollower simulates a base driven by the follower along the path until it is within the tolerance of its end,
+// returning the largest distance it strayed from the path, the fewest velocity commands in a row it was stopped for
+// after starting, and how long it took. A base with a turning radius is checked to never be asked to turn tighter, and one which
+// reverses to never be asked to drive forward.
+func driveFollower(
+	t *testing.T,
+	kind newFollower,
+	path []r3.Vector,
+	start Pose,
+	turningRadiusMM float64,
+	reverse bool,
+	toleranceMM float64,
+) (float64, int, float64) {
+	t.Helper()
+	const dt = 0.05
+	follower, err := kind(path, FollowerConfig{
+		LinearMMPerSec: 1000, AngularDegsPerSec: 90, TurningRadiusMM: turningRadiusMM, Reverse: reverse,
+	})
+	test.That(t, err, test.ShouldBeNil)
+	// the deviation is measured as the follower tracks the path, by a tracker of its own
+	tracker := newPathTracker(path, 2000)
+
+	pose := start
+	maxDeviation, stops, started := 0., 0, false
+	for step := range 2000 {
+		position := r3.Vector{X: pose.X, Y: pose.Y}
+		if position.Sub(path[len(path)-1]).Norm() < toleranceMM {
+			return maxDeviation, stops, float64(step) * dt
+		}
+		linear, angular := follower.Velocities(pose)
+		test.That(t, math.Abs(linear), test.ShouldBeLessThanOrEqualTo, 1000+1e-6)
+		test.That(t, math.Abs(angular), test.ShouldBeLessThanOrEqualTo, 90+1e-6)
+		if turningRadiusMM > 0 {
+			test.That(t, math.Abs(utils.DegToRad(angular))*turningRadiusMM, test.ShouldBeLessThanOrEqualTo, math.Abs(linear)+1e-6)
+		}
+		speed := linear
+		if reverse {
+			test.That(t, linear, test.ShouldBeLessThanOrEqualTo, 0)
+			speed = -linear
+		}
+		if speed > 0 {
+			started = true
+		} else if started {
+			stops++
+		}
+		maxDeviation = math.Max(maxDeviation, tracker.update(position))
+		position = position.Add(forward(pose).Mul(linear * dt))
+		pose = Pose{X: position.X, Y: position.Y, Theta: pose.Theta + utils.DegToRad(angular)*dt}
+	}
+	t.Fatal("the base never reached the end of the path")
+	return 0, 0, 0
+}
+
+func TestPathFollowers(t *testing.T) {
+	// north 5m then west 5m
+	corner := []r3.Vector{{}, {Y: 5000}, {X: -5000, Y: 5000}}
+	for name, kind := range map[string]newFollower{"pure pursuit": NewPurePursuit, "mpc": NewMPC} {
+		t.Run(name, func(t *testing.T) {
+			// the base turns through the corner without stopping
+			deviation, stops, elapsed := driveFollower(t, kind, corner, Pose{Theta: math.Pi / 2}, 0, false, arrivalToleranceMM)
+			test.That(t, deviation, test.ShouldBeLessThan, 1000)
+			test.That(t, stops, test.ShouldEqual, 0)
+			test.That(t, elapsed, test.ShouldBeLessThan, 15)
+
+			// starting off to the side and facing away, the base spins toward the path and then joins it
+			deviation, _, _ = driveFollower(t, kind, []r3.Vector{{}, {Y: 5000}}, Pose{X: 500, Theta: 3 * math.Pi / 2}, 0, false, arrivalToleranceMM)
+			test.That(t, deviation, test.ShouldBeLessThan, 1000)
+
+			// a base which can't turn tighter than 2m cuts the corner wider, and joins the path without spinning
+			deviation, stops, _ = driveFollower(t, kind, corner, Pose{Theta: math.Pi / 2}, 2000, false, wideTurnArrivalToleranceMM)
+			test.That(t, deviation, test.ShouldBeLessThan, 2000)
+			test.That(t, stops, test.ShouldEqual, 0)
+			deviation, stops, _ = driveFollower(t, kind, []r3.Vector{{}, {Y: 5000}}, Pose{X: 500, Theta: math.Pi}, 2000, false, wideTurnArrivalToleranceMM)
+			test.That(t, deviation, test.ShouldBeLessThan, 2000)
+			test.That(t, stops, test.ShouldEqual, 0)
+
+			// reversing along the path the base faces away from
+			deviation, stops, _ = driveFollower(t, kind, []r3.Vector{{}, {Y: -3000}, {X: -2000, Y: -5000}}, Pose{Theta: math.Pi / 2}, 1000, true, arrivalToleranceMM)
+			test.That(t, deviation, test.ShouldBeLessThan, 1000)
+			test.That(t, stops, test.ShouldEqual, 0)
+
+			// there is nothing to follow without any points
+			_, err := kind(nil, FollowerConfig{LinearMMPerSec: 1000, AngularDegsPerSec: 90})
+			test.That(t, err, test.ShouldBeError, errEmptyPath)
+
+			// and nowhere to drive along a single point, forward or in reverse
+			for _, reverse := range []bool{false, true} {
+				follower, err := kind([]r3.Vector{{X: 1000}}, FollowerConfig{LinearMMPerSec: 1000, AngularDegsPerSec: 90, Reverse: reverse})
+				test.That(t, err, test.ShouldBeNil)
+				linear, angular := follower.Velocities(Pose{Theta: math.Pi / 2})
+				test.That(t, linear, test.ShouldEqual, 0)
+				test.That(t, angular, test.ShouldEqual, 0)
+			}
+		})
+	}
+}
+
+func TestPathTracker(t *testing.T) {
+	// a path which comes back past its start
+	path := []r3.Vector{{}, {Y: 1000}, {X: 100, Y: 1000}, {X: 100}}
+	tracker := newPathTracker(path, 500)
+	test.That(t, tracker.length(), test.ShouldAlmostEqual, 2100)
+	test.That(t, tracker.update(r3.Vector{X: 90, Y: 10}), test.ShouldAlmostEqual, math.Hypot(90, 0))
+	// near the end of the path, but only the start is within the window
+	test.That(t, tracker.progress, test.ShouldAlmostEqual, 10)
+
+	tracker.update(r3.Vector{X: 10, Y: 400})
+	tracker.update(r3.Vector{X: 10, Y: 900})
+	test.That(t, tracker.progress, test.ShouldAlmostEqual, 900)
+	test.That(t, tracker.pointAt(1050), test.ShouldResemble, r3.Vector{X: 50, Y: 1000})
+	test.That(t, tracker.headingAt(1050), test.ShouldAlmostEqual, 0)
+	test.That(t, tracker.headingAt(500), test.ShouldAlmostEqual, math.Pi/2)
+	test.That(t, tracker.pointAt(5000), test.ShouldResemble, r3.Vector{X: 100})
+}
@@ -1,6 +1,11 @@
 package motionplan
 
 import (
+	"encoding/json"
+
+	commonpb "go.viam.com/api/common/v1"
+
+	"go.viam.com/rdk/referenceframe"
 	"go.viam.com/rdk/spatialmath"
 )
 
@@ -11,6 +16,12 @@ type Constraints struct {
 	PseudolinearConstraint []PseudolinearConstraint `json:"pseudolinear_constraints"`
 	OrientationConstraint  []OrientationConstraint  `json:"orientation_constraints"`
 	CollisionSpecification []CollisionSpecification `json:"collision_specifications"`
+
+	// Constraints coordinating the motion of several components. These have no protobuf representation and are carried
+	// in the extra of motion requests.
+	RelativePoseConstraint []RelativePoseConstraint `json:"relative_pose_constraints,omitempty"`
+	SynchronizedArrival    []SynchronizedArrival    `json:"synchronized_arrival,omitempty"`
+	ClearanceConstraint    []ClearanceConstraint    `json:"clearance_constraints,omitempty"`
 }
 
 // NewEmptyConstraints creates a new, empty Constraints object.
@@ -103,6 +114,99 @@ type CollisionSpecification struct {
 	Allows []CollisionSpecificationAllowedFrameCollisions
 }
 
+// Tolerances used by a RelativePoseConstraint which does not set its own. A relative pose can never be held exactly.
+const (
+	defaultRelativePoseLineToleranceMm          = 1.
+	defaultRelativePoseOrientationToleranceDegs = 1.
+)
+
+// RelativePoseConstraint specifies that the pose of Frame2 relative to Frame1 must be held for the entire motion, for
+// example while two arms carry one object together. When a goal is given for only one of the two frames, the goal of
+// the other is derived from it.
+type RelativePoseConstraint struct {
+	Frame1, Frame2 string
+	// The pose of Frame2 in Frame1 to hold. If nil, the relative pose at the start of the motion is held.
+	RelativePose spatialmath.Pose
+	// Max deviation from RelativePose. Unset tolerances default to 1mm and 1 degree.
+	LineToleranceMm          float64
+	OrientationToleranceDegs float64
+}
+
+// Tolerances returns the line and orientation tolerances of the constraint, substituting defaults for unset ones.
+func (rc RelativePoseConstraint) Tolerances() (float64, float64) {
+	lineTol, orientTol := rc.LineToleranceMm, rc.OrientationToleranceDegs
+	if lineTol <= 0 {
+		lineTol = defaultRelativePoseLineToleranceMm
+	}
+	if orientTol <= 0 {
+		orientTol = defaultRelativePoseOrientationToleranceDegs
+	}
+	return lineTol, orientTol
+}
+
+type relativePoseConstraintJSON struct {
+	Frame1, Frame2           string
+	RelativePose             *commonpb.Pose `json:",omitempty"`
+	LineToleranceMm          float64
+	OrientationToleranceDegs float64
+}
+
+// MarshalJSON serializes a RelativePoseConstraint.
+func (rc RelativePoseConstraint) MarshalJSON() ([]byte, error) {
+	tmp := relativePoseConstraintJSON{
+		Frame1:                   rc.Frame1,
+		Frame2:                   rc.Frame2,
+		LineToleranceMm:          rc.LineToleranceMm,
+		OrientationToleranceDegs: rc.OrientationToleranceDegs,
+	}
+	if rc.RelativePose != nil {
+		tmp.RelativePose = spatialmath.PoseToProtobuf(rc.RelativePose)
+	}
+	return json.Marshal(tmp)
+}
+
+// UnmarshalJSON deserializes a RelativePoseConstraint.
+func (rc *RelativePoseConstraint) UnmarshalJSON(data []byte) error {
+	var tmp relativePoseConstraintJSON
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	*rc = RelativePoseConstraint{
+		Frame1:                   tmp.Frame1,
+		Frame2:                   tmp.Frame2,
+		LineToleranceMm:          tmp.LineToleranceMm,
+		OrientationToleranceDegs: tmp.OrientationToleranceDegs,
+	}
+	if tmp.RelativePose != nil {
+		rc.RelativePose = spatialmath.NewPoseFromProtobuf(tmp.RelativePose)
+	}
+	return nil
+}
+
+// SynchronizedArrival specifies that every component moved by a plan starts and arrives at each step of the plan
+// together, rather than one component at a time. Only arms can be moved together, by streaming each the same timed
+// trajectory. Goals holds goals for components other than the one named in a
+// motion request, keyed by component name, so that several components can be sent to their own goals at once.
+type SynchronizedArrival struct {
+	Goals map[string]*referenceframe.PoseInFrame
+}
+
+// ClearanceConstraint specifies that the geometries of Frame1 and Frame2, including anything mounted on them, must
+// stay at least MinDistanceMm apart. Unlike default collision checking, geometries already too close at the start of
+// the motion are not exempted; collisions allowed by a CollisionSpecification are.
+type ClearanceConstraint struct {
+	Frame1, Frame2 string
+	MinDistanceMm  float64
+}
+
+// Synchronized returns whether the constraints require the components moved by a plan to move in lockstep.
+func (c *Constraints) Synchronized() bool {
+	if c == nil {
+		return false
+	}
+	return len(c.RelativePoseConstraint) > 0 || len(c.SynchronizedArrival) > 0
+}
+
 // AddLinearConstraint appends a LinearConstraint to a Constraints object.
 func (c *Constraints) AddLinearConstraint(linConstraint LinearConstraint) {
 	c.LinearConstraint = append(c.LinearConstraint, linConstraint)
@@ -122,3 +226,18 @@ func (c *Constraints) AddOrientationConstraint(orientConstraint OrientationConst
 func (c *Constraints) AddCollisionSpecification(collConstraint CollisionSpecification) {
 	c.CollisionSpecification = append(c.CollisionSpecification, collConstraint)
 }
+
+// AddRelativePoseConstraint appends a RelativePoseConstraint to a Constraints object.
+func (c *Constraints) AddRelativePoseConstraint(relConstraint RelativePoseConstraint) {
+	c.RelativePoseConstraint = append(c.RelativePoseConstraint, relConstraint)
+}
+
+// AddSynchronizedArrival appends a SynchronizedArrival to a Constraints object.
+func (c *Constraints) AddSynchronizedArrival(syncArrival SynchronizedArrival) {
+	c.SynchronizedArrival = append(c.SynchronizedArrival, syncArrival)
+}
+
+// AddClearanceConstraint appends a ClearanceConstraint to a Constraints object.
+func (c *Constraints) AddClearanceConstraint(clearConstraint ClearanceConstraint) {
+	c.ClearanceConstraint = append(c.ClearanceConstraint, clearConstraint)
+}
@@ -16,9 +16,11 @@ import (
 
 // short descriptions of constraints used in error messages.
 const (
-	linearConstraintDescription      = "linear constraint"
-	orientationConstraintDescription = "orientation constraint"
-	planarConstraintDescription      = "planar constraint"
+	linearConstraintDescription       = "linear constraint"
+	orientationConstraintDescription  = "orientation constraint"
+	planarConstraintDescription       = "planar constraint"
+	relativePoseConstraintDescription = "relative pose constraint"
+	clearanceConstraintDescription    = "clearance constraint"
 
 	// collision constraint descriptions used in error messages.
 	boundingRegionConstraintDescription = "bounding region constraint"
@@ -50,6 +52,10 @@ type CollisionConstraints struct {
 type ConstraintChecker struct {
 	collisionConstraints CollisionConstraints
 	topoConstraint       StateFSConstraint
+	clearanceConstraint  StateFSConstraint
+
+	// continuous is non-nil if segments are checked with continuous rather than discrete collision checking.
+	continuous *continuousCollisionChecker
 
 	logger logging.Logger
 }
@@ -133,6 +139,11 @@ func NewConstraintChecker(
 		return nil, err
 	}
 
+	err = handler.addClearanceConstraints(fs, constraints.ClearanceConstraint, allowedCollisions)
+	if err != nil {
+		return nil, err
+	}
+
 	return handler, nil
 }
 
@@ -151,10 +162,24 @@ func (c *ConstraintChecker) addTopoConstraints(
 ) error {
 	if len(constraints.LinearConstraint) == 0 &&
 		len(constraints.PseudolinearConstraint) == 0 &&
-		len(constraints.OrientationConstraint) == 0 {
+		len(constraints.OrientationConstraint) == 0 &&
+		len(constraints.RelativePoseConstraint) == 0 {
 		return nil
 	}
 
+	relativePoses := make([]spatialmath.Pose, 0, len(constraints.RelativePoseConstraint))
+	for _, rc := range constraints.RelativePoseConstraint {
+		if rc.RelativePose != nil {
+			relativePoses = append(relativePoses, rc.RelativePose)
+			continue
+		}
+		rel, err := RelativePose(fs, startCfg, rc.Frame1, rc.Frame2)
+		if err != nil {
+			return err
+		}
+		relativePoses = append(relativePoses, rel)
+	}
+
 	fromPoses := referenceframe.FrameSystemPoses{}
 	for f, b := range fromPosesBad {
 		g := toPoses[f]
@@ -208,12 +233,110 @@ func (c *ConstraintChecker) addTopoConstraints(
 			}
 		}
 
+		for i, rc := range constraints.RelativePoseConstraint {
+			currRel, err := RelativePose(state.FS, state.Configuration, rc.Frame1, rc.Frame2)
+			if err != nil {
+				return err
+			}
+			err = checkRelativePoseConstraint(rc, relativePoses[i], currRel)
+			if err != nil {
+				return err
+			}
+		}
+
 		return nil
 	}
 
 	return nil
 }
 
+// RelativePose returns the pose of frame2 in frame1 at the given configuration.
+func RelativePose(fs *referenceframe.FrameSystem, inputs *referenceframe.LinearInputs, frame1, frame2 string) (spatialmath.Pose, error) {
+	tf, err := fs.Transform(inputs, referenceframe.NewZeroPoseInFrame(frame2), frame1)
+	if err != nil {
+		return nil, err
+	}
+	return tf.(*referenceframe.PoseInFrame).Pose(), nil
+}
+
+func checkRelativePoseConstraint(rc RelativePoseConstraint, want, currRel spatialmath.Pose) error {
+	lineTol, orientTol := rc.Tolerances()
+	if dist := want.Point().Distance(currRel.Point()); dist > lineTol {
+		return fmt.Errorf("%s and %s %s violated dist: %0.2f > %0.2f",
+			rc.Frame1, rc.Frame2, relativePoseConstraintDescription, dist, lineTol)
+	}
+	if dist := OrientDist(want.Orientation(), currRel.Orientation()); dist > orientTol {
+		return fmt.Errorf("%s and %s %s violated orientation dist: %0.5f > %0.5f",
+			rc.Frame1, rc.Frame2, relativePoseConstraintDescription, dist, orientTol)
+	}
+	return nil
+}
+
+// addClearanceConstraints adds a constraint keeping the geometries of the given pairs of frames, and of the frames
+// mounted on them, apart.
+func (c *ConstraintChecker) addClearanceConstraints(
+	fs *referenceframe.FrameSystem,
+	clearances []ClearanceConstraint,
+	allowedCollisions []Collision,
+) error {
+	if len(clearances) == 0 {
+		return nil
+	}
+
+	// The frames mounted on each frame named in a clearance constraint, including itself.
+	mounted := map[string]map[string]bool{}
+	for _, cc := range clearances {
+		for _, name := range []string{cc.Frame1, cc.Frame2} {
+			if fs.Frame(name) == nil {
+				return referenceframe.NewFrameMissingError(name)
+			}
+			mounted[name] = map[string]bool{}
+		}
+	}
+	for _, name := range fs.FrameNames() {
+		ancestors, err := fs.TracebackFrame(fs.Frame(name))
+		if err != nil {
+			return err
+		}
+		for _, a := range ancestors {
+			if m, ok := mounted[a.Name()]; ok {
+				m[name] = true
+			}
+		}
+	}
+
+	allowed := makeAllowedCollisionsLookup(allowedCollisions)
+	c.clearanceConstraint = func(state *StateFS) error {
+		for _, cc := range clearances {
+			geoms1, err := referenceframe.FrameSystemGeometriesForFrames(state.FS, state.Configuration, mounted[cc.Frame1])
+			if err != nil {
+				return err
+			}
+			geoms2, err := referenceframe.FrameSystemGeometriesForFrames(state.FS, state.Configuration, mounted[cc.Frame2])
+			if err != nil {
+				return err
+			}
+			var g1, g2 []spatialmath.Geometry
+			for _, gif := range geoms1 {
+				g1 = append(g1, gif.Geometries()...)
+			}
+			for _, gif := range geoms2 {
+				g2 = append(g2, gif.Geometries()...)
+			}
+			collisions, _, err := checkCollisionsHinted(g1, g2, allowed, cc.MinDistanceMm, false, nil, c.logger)
+			if err != nil {
+				return err
+			}
+			if len(collisions) != 0 {
+				return fmt.Errorf("%s violated between %s and %s geometries",
+					clearanceConstraintDescription, collisions[0].name1, collisions[0].name2)
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
 func orientationError(prefix string, from, to, curr spatialmath.Orientation, dist, max float64) error { //nolint: revive
 	return fmt.Errorf("%s %s violated dist: %0.5f > %0.5f from: %v to: %v currPose: %v",
 		prefix, orientationConstraintDescription, dist, max,
@@ -276,36 +399,46 @@ func checkOrientationConstraint(frame string, c OrientationConstraint, from, to,
 // CheckStateFSConstraints will check a given input against all FS state constraints.
 // first is closest obstacle, negative if in collision
 func (c *ConstraintChecker) CheckStateFSConstraints(ctx context.Context, state *StateFS) (float64, error) {
+	envDist, selfDist, err := c.checkStateFSConstraints(ctx, state)
+	return min(envDist, selfDist), err
+}
+
+// checkStateFSConstraints checks a state against all FS state constraints, returning separately the distance to the
+// closest obstacle or stationary robot geometry and the distance between the closest pair of moving geometries.
+func (c *ConstraintChecker) checkStateFSConstraints(ctx context.Context, state *StateFS) (float64, float64, error) {
 	_, span := trace.StartSpan(ctx, "CheckStateFSConstraints")
 	defer span.End()
 
-	closest := math.Inf(1)
+	envDist, selfDist := math.Inf(1), math.Inf(1)
 
 	for _, pair := range []struct {
 		name string
 		fn   CollisionConstraintFunc
+		dist *float64
 	}{
-		{ObstacleConstraintDescription, c.collisionConstraints.Obstacle},
-		{RobotCollisionConstraintDescription, c.collisionConstraints.RobotToRobot},
-		{selfCollisionConstraintDescription, c.collisionConstraints.SelfCollision},
+		{ObstacleConstraintDescription, c.collisionConstraints.Obstacle, &envDist},
+		{RobotCollisionConstraintDescription, c.collisionConstraints.RobotToRobot, &envDist},
+		{selfCollisionConstraintDescription, c.collisionConstraints.SelfCollision, &selfDist},
 	} {
 		if pair.fn == nil {
 			continue
 		}
 		d, err := pair.fn(state)
-		closest = min(closest, d)
+		*pair.dist = min(*pair.dist, d)
 		if err != nil {
-			return -1, errors.Wrap(err, pair.name)
+			return -1, -1, errors.Wrap(err, pair.name)
 		}
 	}
 
-	if c.topoConstraint != nil {
-		err := c.topoConstraint(state)
-		if err != nil {
-			return closest, err
+	for _, constraint := range []StateFSConstraint{c.topoConstraint, c.clearanceConstraint} {
+		if constraint == nil {
+			continue
+		}
+		if err := constraint(state); err != nil {
+			return envDist, selfDist, err
 		}
 	}
-	return closest, nil
+	return envDist, selfDist, nil
 }
 
 // InterpolateSegmentFS is a helper function which produces a list of intermediate inputs, between the start and end
@@ -320,31 +453,38 @@ func InterpolateSegmentFS(ci *SegmentFS, resolution float64) ([]*referenceframe.
 	// Create interpolated configurations for all frames
 	var interpolatedConfigurations []*referenceframe.LinearInputs
 	for i := 0; i <= maxSteps; i++ {
-		interp := float64(i) / float64(maxSteps)
-		frameConfigs := referenceframe.NewLinearInputs()
-
-		// Interpolate each frame's configuration
-		for frameName, startConfig := range ci.StartConfiguration.Items() {
-			// 0-DoF frames have nothing to interpolate.
-			if len(startConfig) == 0 {
-				continue
-			}
-			endConfig := ci.EndConfiguration.Get(frameName)
-			frame := ci.FS.Frame(frameName)
-
-			interpConfig, err := frame.Interpolate(startConfig, endConfig, interp)
-			if err != nil {
-				return nil, err
-			}
-			frameConfigs.Put(frameName, interpConfig)
+		frameConfigs, err := interpolateSegmentFSAt(ci, float64(i)/float64(maxSteps))
+		if err != nil {
+			return nil, err
 		}
-
 		interpolatedConfigurations = append(interpolatedConfigurations, frameConfigs)
 	}
 
 	return interpolatedConfigurations, nil
 }
 
+// interpolateSegmentFSAt returns the configuration the given fraction of the way along a segment.
+func interpolateSegmentFSAt(ci *SegmentFS, by float64) (*referenceframe.LinearInputs, error) {
+	frameConfigs := referenceframe.NewLinearInputs()
+
+	// Interpolate each frame's configuration
+	for frameName, startConfig := range ci.StartConfiguration.Items() {
+		// 0-DoF frames have nothing to interpolate.
+		if len(startConfig) == 0 {
+			continue
+		}
+		endConfig := ci.EndConfiguration.Get(frameName)
+		frame := ci.FS.Frame(frameName)
+
+		interpConfig, err := frame.Interpolate(startConfig, endConfig, by)
+		if err != nil {
+			return nil, err
+		}
+		frameConfigs.Put(frameName, interpConfig)
+	}
+	return frameConfigs, nil
+}
+
 // CheckStateConstraintsAcrossSegmentFS will interpolate the given input from the StartConfiguration to the EndConfiguration, and ensure
 // that all intermediate states as well as both endpoints satisfy all state constraints. If all constraints are satisfied, then this will
 // return `true, nil`. If any constraints fail, this will return false, and an SegmentFS representing the valid portion of the segment,
@@ -358,6 +498,10 @@ func (c *ConstraintChecker) CheckStateConstraintsAcrossSegmentFS(
 	ctx, span := trace.StartSpan(ctx, "CheckStateConstraintsAcrossSegmentFS")
 	defer span.End()
 
+	if c.continuous != nil {
+		return c.checkSegmentContinuous(ctx, ci, resolution, checkFinal)
+	}
+
 	interpolatedConfigurations, err := InterpolateSegmentFS(ci, resolution)
 	if err != nil {
 		return nil, err
@@ -383,7 +527,7 @@ func (c *ConstraintChecker) CheckStateConstraintsAcrossSegmentFS(
 		lastGood = interpC.Configuration
 
 		canSkip := int(min(100, math.Floor(closestObstacle/resolution)))
-		if canSkip > 0 && c.topoConstraint == nil {
+		if canSkip > 0 && c.topoConstraint == nil && c.clearanceConstraint == nil {
 			i += canSkip
 		}
 	}
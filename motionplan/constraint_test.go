@@ -2,6 +2,7 @@ package motionplan
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
@@ -707,3 +708,131 @@ func BenchmarkCollisionConstraintsObstructedEdge(b *testing.B) {
 		_, _ = handler.CheckStateFSConstraints(context.Background(), stateFS)
 	}
 }
+
+func TestCoordinatedConstraints(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+
+	// Two sliders along X, 200mm apart, each carrying a 10mm sphere.
+	fs := referenceframe.NewEmptyFrameSystem("test")
+	leftBall, err := spatial.NewSphere(spatial.NewZeroPose(), 10, "left")
+	test.That(t, err, test.ShouldBeNil)
+	left, err := referenceframe.NewTranslationalFrameWithGeometry(
+		"left", r3.Vector{X: 1}, referenceframe.Limit{Min: -500, Max: 500}, leftBall)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, fs.AddFrame(left, fs.World()), test.ShouldBeNil)
+	offset, err := referenceframe.NewStaticFrame("offset", spatial.NewPoseFromPoint(r3.Vector{X: 200}))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, fs.AddFrame(offset, fs.World()), test.ShouldBeNil)
+	rightBall, err := spatial.NewSphere(spatial.NewZeroPose(), 10, "right")
+	test.That(t, err, test.ShouldBeNil)
+	right, err := referenceframe.NewTranslationalFrameWithGeometry(
+		"right", r3.Vector{X: 1}, referenceframe.Limit{Min: -500, Max: 500}, rightBall)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, fs.AddFrame(right, offset), test.ShouldBeNil)
+
+	newChecker := func(constraints *Constraints) *ConstraintChecker {
+		handler, err := NewConstraintChecker(
+			defaultCollisionBufferMM,
+			constraints,
+			referenceframe.FrameSystemPoses{},
+			referenceframe.FrameSystemPoses{},
+			fs,
+			[]spatial.Geometry{},
+			[]spatial.Geometry{},
+			nil,
+			referenceframe.NewNeutralLinearInputs(fs),
+			nil,
+			logger,
+			nil,
+		)
+		test.That(t, err, test.ShouldBeNil)
+		return handler
+	}
+	state := func(l, r float64) *StateFS {
+		return &StateFS{FS: fs, Configuration: referenceframe.FrameSystemInputs{"left": {l}, "right": {r}}.ToLinearInputs()}
+	}
+
+	t.Run("relative pose", func(t *testing.T) {
+		handler := newChecker(&Constraints{
+			RelativePoseConstraint: []RelativePoseConstraint{{Frame1: "left", Frame2: "right", LineToleranceMm: 5}},
+		})
+		_, err := handler.CheckStateFSConstraints(ctx, state(100, 100))
+		test.That(t, err, test.ShouldBeNil)
+		_, err = handler.CheckStateFSConstraints(ctx, state(100, 103))
+		test.That(t, err, test.ShouldBeNil)
+		_, err = handler.CheckStateFSConstraints(ctx, state(100, 0))
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, relativePoseConstraintDescription)
+
+		// Moving both sliders together holds the relative pose across the whole segment, moving one does not.
+		together := &SegmentFS{StartConfiguration: state(0, 0).Configuration, EndConfiguration: state(300, 300).Configuration, FS: fs}
+		_, err = handler.CheckStateConstraintsAcrossSegmentFS(ctx, together, 1, true)
+		test.That(t, err, test.ShouldBeNil)
+		apart := &SegmentFS{StartConfiguration: state(0, 0).Configuration, EndConfiguration: state(300, 0).Configuration, FS: fs}
+		_, err = handler.CheckStateConstraintsAcrossSegmentFS(ctx, apart, 1, true)
+		test.That(t, err, test.ShouldNotBeNil)
+
+		// An explicit relative pose need not be the start pose.
+		handler = newChecker(&Constraints{
+			RelativePoseConstraint: []RelativePoseConstraint{{
+				Frame1: "left", Frame2: "right", RelativePose: spatial.NewPoseFromPoint(r3.Vector{X: 100}),
+			}},
+		})
+		_, err = handler.CheckStateFSConstraints(ctx, state(0, 0))
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = handler.CheckStateFSConstraints(ctx, state(0, -100))
+		test.That(t, err, test.ShouldBeNil)
+	})
+
+	t.Run("clearance", func(t *testing.T) {
+		handler := newChecker(&Constraints{
+			ClearanceConstraint: []ClearanceConstraint{{Frame1: "left", Frame2: "offset", MinDistanceMm: 50}},
+		})
+		_, err := handler.CheckStateFSConstraints(ctx, state(0, 0))
+		test.That(t, err, test.ShouldBeNil)
+		// 30mm between the spheres, which default collision checking would allow.
+		_, err = handler.CheckStateFSConstraints(ctx, state(0, -150))
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, clearanceConstraintDescription)
+
+		// Allowed collisions are exempt.
+		handler = newChecker(&Constraints{
+			ClearanceConstraint: []ClearanceConstraint{{Frame1: "left", Frame2: "right", MinDistanceMm: 50}},
+			CollisionSpecification: []CollisionSpecification{{
+				Allows: []CollisionSpecificationAllowedFrameCollisions{{Frame1: "left", Frame2: "right"}},
+			}},
+		})
+		_, err = handler.CheckStateFSConstraints(ctx, state(0, -150))
+		test.That(t, err, test.ShouldBeNil)
+
+		_, err = NewConstraintChecker(defaultCollisionBufferMM,
+			&Constraints{ClearanceConstraint: []ClearanceConstraint{{Frame1: "left", Frame2: "missing"}}},
+			nil, nil, fs, nil, nil, nil, referenceframe.NewNeutralLinearInputs(fs), nil, logger, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		c := &Constraints{
+			RelativePoseConstraint: []RelativePoseConstraint{
+				{Frame1: "left", Frame2: "right", LineToleranceMm: 2},
+				{Frame1: "left", Frame2: "right", RelativePose: spatial.NewPoseFromPoint(r3.Vector{X: 100})},
+			},
+			SynchronizedArrival: []SynchronizedArrival{{Goals: map[string]*referenceframe.PoseInFrame{
+				"right": referenceframe.NewPoseInFrame(referenceframe.World, spatial.NewPoseFromPoint(r3.Vector{X: 300})),
+			}}},
+			ClearanceConstraint: []ClearanceConstraint{{Frame1: "left", Frame2: "right", MinDistanceMm: 50}},
+		}
+		data, err := json.Marshal(c)
+		test.That(t, err, test.ShouldBeNil)
+		var parsed Constraints
+		test.That(t, json.Unmarshal(data, &parsed), test.ShouldBeNil)
+		test.That(t, parsed.RelativePoseConstraint[0], test.ShouldResemble, c.RelativePoseConstraint[0])
+		test.That(t, spatial.PoseAlmostEqual(parsed.RelativePoseConstraint[1].RelativePose, c.RelativePoseConstraint[1].RelativePose),
+			test.ShouldBeTrue)
+		test.That(t, spatial.PoseAlmostEqual(parsed.SynchronizedArrival[0].Goals["right"].Pose(),
+			c.SynchronizedArrival[0].Goals["right"].Pose()), test.ShouldBeTrue)
+		test.That(t, parsed.ClearanceConstraint, test.ShouldResemble, c.ClearanceConstraint)
+		test.That(t, parsed.Synchronized(), test.ShouldBeTrue)
+	})
+}
@@ -0,0 +1,151 @@
+package motionplan
+
+import (
+	"context"
+	"math"
+
+	"go.viam.com/utils/trace"
+
+	"go.viam.com/rdk/referenceframe"
+)
+
+// DefaultContinuousCollisionToleranceMM is the smallest distance geometries are advanced between collision checks
+// when checking collisions continuously. Obstacles thinner than this may still be passed through.
+const DefaultContinuousCollisionToleranceMM = 0.5
+
+// continuousCollisionChecker checks segments for collisions using conservative advancement: after measuring the
+// distance from the moving geometries to the nearest obstacle, the segment is advanced by exactly as much as
+// guarantees no geometry could have moved that far. Unlike discrete checking at a fixed resolution, this cannot step
+// over thin obstacles, and it takes large steps when far from obstacles.
+type continuousCollisionChecker struct {
+	// bounds of the frames whose inputs move the moving geometries.
+	bounds map[string]*referenceframe.KinematicBound
+	// reach bounds the distance from any joint to any moving geometry point.
+	reach       float64
+	bufferMM    float64
+	toleranceMM float64
+}
+
+// EnableContinuousCollisionChecking switches the checker from checking segments at discrete steps to continuously
+// checking them against obstacles and stationary robot geometries. movingFrameNames are the frames owning the moving
+// geometries. Collisions between moving geometries, and topological constraints, are still checked no more coarsely
+// than the resolution passed to CheckStateConstraintsAcrossSegmentFS. Returns an error if the motion of a frame cannot
+// be bounded, in which case the checker is unchanged.
+func (c *ConstraintChecker) EnableContinuousCollisionChecking(
+	fs *referenceframe.FrameSystem,
+	movingFrameNames map[string]bool,
+	collisionBufferMM, toleranceMM float64,
+) error {
+	if toleranceMM <= 0 {
+		toleranceMM = DefaultContinuousCollisionToleranceMM
+	}
+	cc := &continuousCollisionChecker{
+		bounds:      map[string]*referenceframe.KinematicBound{},
+		bufferMM:    collisionBufferMM,
+		toleranceMM: toleranceMM,
+	}
+
+	// Every link between a moving joint and a moving geometry is on the path from that geometry's frame to world.
+	chain := map[string]referenceframe.Frame{}
+	for name := range movingFrameNames {
+		f := fs.Frame(name)
+		if f == nil {
+			return referenceframe.NewFrameMissingError(name)
+		}
+		ancestors, err := fs.TracebackFrame(f)
+		if err != nil {
+			return err
+		}
+		for _, a := range ancestors {
+			chain[a.Name()] = a
+		}
+	}
+	for name, f := range chain {
+		bound, err := referenceframe.NewKinematicBound(f)
+		if err != nil {
+			return err
+		}
+		cc.reach += bound.Reach
+		if len(f.DoF()) > 0 {
+			cc.bounds[name] = bound
+		}
+	}
+
+	c.continuous = cc
+	return nil
+}
+
+// displacement bounds how far any moving geometry point can travel along the segment.
+func (cc *continuousCollisionChecker) displacement(ci *SegmentFS) float64 {
+	dist := 0.
+	for name, bound := range cc.bounds {
+		from, to := ci.StartConfiguration.Get(name), ci.EndConfiguration.Get(name)
+		if len(from) != len(bound.Rotational) || len(to) != len(bound.Rotational) {
+			continue
+		}
+		for i := range from {
+			dist += math.Abs(to[i]-from[i]) * (bound.Rotational[i]*cc.reach + bound.Translational[i])
+		}
+	}
+	return dist
+}
+
+// checkSegmentContinuous is the conservative advancement counterpart of CheckStateConstraintsAcrossSegmentFS.
+func (c *ConstraintChecker) checkSegmentContinuous(
+	ctx context.Context,
+	ci *SegmentFS,
+	resolution float64,
+	checkFinal bool,
+) (*SegmentFS, error) {
+	ctx, span := trace.StartSpan(ctx, "checkSegmentContinuous")
+	defer span.End()
+
+	discreteSteps, err := segmentStepCount(ci, resolution)
+	if err != nil {
+		return nil, err
+	}
+	discreteStep := 1 / float64(discreteSteps)
+	// The distance any moving point may travel per unit of interpolation.
+	rate := c.continuous.displacement(ci)
+
+	var lastGood *referenceframe.LinearInputs
+	for t := 0.; ; {
+		conf, err := interpolateSegmentFSAt(ci, t)
+		if err != nil {
+			return nil, err
+		}
+		envDist, selfDist, err := c.checkStateFSConstraints(ctx, &StateFS{FS: ci.FS, Configuration: conf})
+		if err != nil {
+			if lastGood == nil {
+				// fail on start pos
+				return nil, err
+			}
+			return &SegmentFS{StartConfiguration: ci.StartConfiguration, EndConfiguration: lastGood, FS: ci.FS}, err
+		}
+		lastGood = conf
+		if t >= 1 {
+			break
+		}
+
+		step := 1.
+		if rate > 0 {
+			// No point can reach an obstacle before moving envDist, which takes at least envDist/rate.
+			step = max(envDist-c.continuous.bufferMM, c.continuous.toleranceMM) / rate
+			// Two moving geometries can approach each other at twice the rate.
+			step = min(step, max(discreteStep, (selfDist-c.continuous.bufferMM)/(2*rate)))
+		}
+		if c.topoConstraint != nil || c.clearanceConstraint != nil {
+			step = min(step, discreteStep)
+		}
+
+		t += step
+		if t >= 1 {
+			if !checkFinal {
+				break
+			}
+			t = 1
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,107 @@
+package motionplan
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/referenceframe"
+	spatial "go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+func TestContinuousCollisionChecking(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+
+	newChecker := func(t *testing.T, fs *referenceframe.FrameSystem, frame string, obstacles ...spatial.Geometry) *ConstraintChecker {
+		t.Helper()
+		seed := referenceframe.NewNeutralFrameSystemInputs(fs)
+		moving, err := fs.Frame(frame).Geometries(seed[frame])
+		test.That(t, err, test.ShouldBeNil)
+		handler := NewEmptyConstraintChecker(logger)
+		handler.collisionConstraints, err = CreateAllCollisionConstraints(
+			fs,
+			moving.Geometries(),
+			map[string]bool{frame: true},
+			nil,
+			obstacles,
+			nil,
+			defaultCollisionBufferMM,
+			nil,
+			logger,
+		)
+		test.That(t, err, test.ShouldBeNil)
+		return handler
+	}
+
+	t.Run("thin obstacles are not stepped over", func(t *testing.T) {
+		// A small sphere slides 1m along X. A 2mm wall sits between two of the discrete steps.
+		ball, err := spatial.NewSphere(spatial.NewZeroPose(), 1, "ball")
+		test.That(t, err, test.ShouldBeNil)
+		slider, err := referenceframe.NewTranslationalFrameWithGeometry(
+			"slider", r3.Vector{X: 1}, referenceframe.Limit{Min: 0, Max: 10000}, ball)
+		test.That(t, err, test.ShouldBeNil)
+		fs := referenceframe.NewEmptyFrameSystem("test")
+		test.That(t, fs.AddFrame(slider, fs.World()), test.ShouldBeNil)
+
+		wall, err := spatial.NewBox(spatial.NewPoseFromPoint(r3.Vector{X: 505}), r3.Vector{X: 2, Y: 100, Z: 100}, "wall")
+		test.That(t, err, test.ShouldBeNil)
+		segment := &SegmentFS{
+			StartConfiguration: referenceframe.FrameSystemInputs{"slider": {0}}.ToLinearInputs(),
+			EndConfiguration:   referenceframe.FrameSystemInputs{"slider": {1000}}.ToLinearInputs(),
+			FS:                 fs,
+		}
+
+		handler := newChecker(t, fs, "slider", wall)
+		_, err = handler.CheckStateConstraintsAcrossSegmentFS(ctx, segment, 100, true)
+		test.That(t, err, test.ShouldBeNil)
+
+		test.That(t, handler.EnableContinuousCollisionChecking(fs, map[string]bool{"slider": true}, defaultCollisionBufferMM, 0), test.ShouldBeNil)
+		failSeg, err := handler.CheckStateConstraintsAcrossSegmentFS(ctx, segment, 100, true)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, failSeg, test.ShouldNotBeNil)
+		lastGood := failSeg.EndConfiguration.Get("slider")[0]
+		test.That(t, lastGood, test.ShouldBeLessThan, 504)
+		test.That(t, lastGood, test.ShouldBeGreaterThan, 500)
+	})
+
+	t.Run("arm segments", func(t *testing.T) {
+		model, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "arm")
+		test.That(t, err, test.ShouldBeNil)
+		fs := referenceframe.NewEmptyFrameSystem("test")
+		test.That(t, fs.AddFrame(model, fs.World()), test.ShouldBeNil)
+
+		box, err := spatial.NewBox(spatial.NewPoseFromPoint(r3.Vector{X: -130, Z: 300}), r3.Vector{X: 2, Y: 2, Z: 2}, "box")
+		test.That(t, err, test.ShouldBeNil)
+		handler := newChecker(t, fs, "arm", box)
+		test.That(t, handler.EnableContinuousCollisionChecking(fs, map[string]bool{"arm": true}, defaultCollisionBufferMM, 0), test.ShouldBeNil)
+
+		segment := func(to float64) *SegmentFS {
+			return &SegmentFS{
+				StartConfiguration: referenceframe.FrameSystemInputs{"arm": {0, 0, 0, 0, 0, 0}}.ToLinearInputs(),
+				EndConfiguration:   referenceframe.FrameSystemInputs{"arm": {to, 0, 0, 0, 0, 0}}.ToLinearInputs(),
+				FS:                 fs,
+			}
+		}
+		// Rotating an eighth of a turn stays clear of the box behind the arm, a half turn sweeps through it.
+		_, err = handler.CheckStateConstraintsAcrossSegmentFS(ctx, segment(math.Pi/4), 2, true)
+		test.That(t, err, test.ShouldBeNil)
+		_, err = handler.CheckStateConstraintsAcrossSegmentFS(ctx, segment(math.Pi), 2, true)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("unbounded frames are rejected", func(t *testing.T) {
+		pose, err := referenceframe.NewPoseFrame("pose", nil)
+		test.That(t, err, test.ShouldBeNil)
+		fs := referenceframe.NewEmptyFrameSystem("test")
+		test.That(t, fs.AddFrame(pose, fs.World()), test.ShouldBeNil)
+		handler := NewEmptyConstraintChecker(logger)
+		test.That(t, handler.EnableContinuousCollisionChecking(fs, map[string]bool{"pose": true}, 0, 0), test.ShouldNotBeNil)
+		test.That(t, handler.continuous, test.ShouldBeNil)
+	})
+}
@@ -0,0 +1,466 @@
+package motionplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/num/quat"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/spatialmath"
+)
+
+const (
+	// jacobianStep is the finite difference step, in radians or mm, used to numerically estimate the Jacobian.
+	jacobianStep = 1e-6
+	// clearanceGradientStep is the finite difference step, in radians or mm, used to estimate how the obstacle
+	// clearance changes with each joint. It is coarser than jacobianStep since collision distances are less smooth.
+	clearanceGradientStep = 1e-3
+
+	defaultServoDamping              = 0.05
+	servoMaxSingularityDamping       = 0.5
+	defaultServoManipulabilityThresh = 0.01
+	defaultServoJointLimitMargin     = 0.1  // fraction of a joint's range
+	defaultServoMaxJointSpeed        = 1.0  // rad/s or mm/s
+	defaultServoSlowdownDistanceMM   = 100. // begin scaling down motion toward obstacles within this clearance
+	defaultServoStopDistanceMM       = 10.  // refuse to move toward obstacles within this clearance
+)
+
+// ErrServoCollision is returned by ServoController.Step when the commanded motion would bring the robot closer to an
+// obstacle while within the configured stop distance of it.
+var ErrServoCollision = errors.New("servo motion stopped: too close to an obstacle")
+
+// Twist is a Cartesian velocity command. Linear is in mm/s and Angular is in rad/s. Both are expressed in the base
+// frame of the frame being servoed.
+type Twist struct {
+	Linear  r3.Vector `json:"linear"`
+	Angular r3.Vector `json:"angular"`
+}
+
+// IsZero returns whether the twist commands no motion.
+func (t Twist) IsZero() bool {
+	return t.Linear.Norm() == 0 && t.Angular.Norm() == 0
+}
+
+// ServoConfig holds the tuning parameters of a ServoController. Zero values select the defaults.
+type ServoConfig struct {
+	// Damping is the base damping factor (lambda) of the damped least squares solve.
+	Damping float64 `json:"damping"`
+	// ManipulabilityThreshold is the manipulability measure below which damping is increased to move
+	// gracefully through singularities.
+	ManipulabilityThreshold float64 `json:"manipulability_threshold"`
+	// JointLimitMargin is the fraction of each joint's range near either limit in which motion toward
+	// that limit is progressively slowed.
+	JointLimitMargin float64 `json:"joint_limit_margin"`
+	// MaxJointSpeed caps every joint's commanded speed, in rad/s or mm/s.
+	MaxJointSpeed float64 `json:"max_joint_speed"`
+	// SlowdownDistanceMM is the obstacle clearance below which the part of the commanded motion toward the
+	// obstacle is scaled down.
+	SlowdownDistanceMM float64 `json:"slowdown_distance_mm"`
+	// StopDistanceMM is the obstacle clearance below which motion toward the obstacle is refused entirely. Motion
+	// away from or along the obstacle is still allowed.
+	StopDistanceMM float64 `json:"stop_distance_mm"`
+}
+
+func (c *ServoConfig) setDefaults() {
+	if c.Damping <= 0 {
+		c.Damping = defaultServoDamping
+	}
+	if c.ManipulabilityThreshold <= 0 {
+		c.ManipulabilityThreshold = defaultServoManipulabilityThresh
+	}
+	if c.JointLimitMargin <= 0 {
+		c.JointLimitMargin = defaultServoJointLimitMargin
+	}
+	if c.MaxJointSpeed <= 0 {
+		c.MaxJointSpeed = defaultServoMaxJointSpeed
+	}
+	if c.SlowdownDistanceMM <= 0 {
+		c.SlowdownDistanceMM = defaultServoSlowdownDistanceMM
+	}
+	if c.StopDistanceMM <= 0 {
+		c.StopDistanceMM = defaultServoStopDistanceMM
+	}
+}
+
+// ServoClearanceFunc returns the distance, in mm, between the servoed robot and the closest obstacle for the given
+// inputs of the servoed frame. A negative value or an error indicates a collision.
+type ServoClearanceFunc func(ctx context.Context, inputs []referenceframe.Input) (float64, error)
+
+// ServoStep is the output of a single ServoController update.
+type ServoStep struct {
+	// Velocities are the commanded joint velocities, in rad/s or mm/s.
+	Velocities []float64
+	// Positions are the joint positions reached by integrating Velocities over the step duration.
+	Positions []referenceframe.Input
+	// Manipulability is the Yoshikawa manipulability measure at the starting configuration.
+	Manipulability float64
+	// Scale is the factor, in [0, 1], by which the part of the commanded motion toward the closest obstacle was
+	// scaled due to its proximity.
+	Scale float64
+}
+
+// ServoController converts Cartesian twists into joint velocities for a single frame using a damped least squares
+// inverse of the frame's Jacobian.
+type ServoController struct {
+	frame     referenceframe.Frame
+	cfg       ServoConfig
+	clearance ServoClearanceFunc
+	logger    logging.Logger
+}
+
+// NewServoController returns a ServoController for the given frame. clearance may be nil, in which case no
+// collision-distance scaling is performed.
+func NewServoController(
+	frame referenceframe.Frame,
+	cfg ServoConfig,
+	clearance ServoClearanceFunc,
+	logger logging.Logger,
+) (*ServoController, error) {
+	if frame == nil {
+		return nil, errors.New("cannot servo a nil frame")
+	}
+	if len(frame.DoF()) == 0 {
+		return nil, fmt.Errorf("cannot servo frame %s with no degrees of freedom", frame.Name())
+	}
+	cfg.setDefaults()
+	if cfg.StopDistanceMM >= cfg.SlowdownDistanceMM {
+		return nil, fmt.Errorf("servo stop distance %v must be less than slowdown distance %v", cfg.StopDistanceMM, cfg.SlowdownDistanceMM)
+	}
+	return &ServoController{frame: frame, cfg: cfg, clearance: clearance, logger: logger}, nil
+}
+
+// Config returns the configuration of the controller with defaults applied.
+func (sc *ServoController) Config() ServoConfig {
+	return sc.cfg
+}
+
+// Step computes the joint velocities which best realize the given twist from the current inputs, and integrates them
+// over dt seconds to produce the next joint position target.
+func (sc *ServoController) Step(ctx context.Context, twist Twist, current []referenceframe.Input, dt float64) (*ServoStep, error) {
+	limits := sc.frame.DoF()
+	if len(current) != len(limits) {
+		return nil, referenceframe.NewIncorrectDoFError(len(current), len(limits))
+	}
+	if dt <= 0 {
+		return nil, fmt.Errorf("servo step duration must be positive, got %v", dt)
+	}
+
+	scale := 1.
+	var dist float64
+	if sc.clearance != nil {
+		var err error
+		dist, err = sc.clearance(ctx, current)
+		if err != nil {
+			return nil, errors.Join(ErrServoCollision, err)
+		}
+		scale = clearanceScale(dist, sc.cfg.StopDistanceMM, sc.cfg.SlowdownDistanceMM)
+	}
+
+	jac, err := ComputeJacobian(sc.frame, current)
+	if err != nil {
+		return nil, err
+	}
+	// Solve in meters rather than mm so that the linear and angular rows are of comparable magnitude, which keeps the
+	// damping and manipulability thresholds meaningful across arm sizes.
+	for r := 0; r < 3; r++ {
+		for c := 0; c < len(current); c++ {
+			jac.Set(r, c, jac.At(r, c)/1000)
+		}
+	}
+	manip := Manipulability(jac)
+
+	step := &ServoStep{
+		Velocities:     make([]float64, len(current)),
+		Positions:      append([]referenceframe.Input{}, current...),
+		Manipulability: manip,
+		Scale:          scale,
+	}
+	if twist.IsZero() {
+		return step, nil
+	}
+
+	damping := sc.cfg.Damping
+	if manip < sc.cfg.ManipulabilityThreshold {
+		// Nakamura & Hanafusa: grow damping smoothly as the arm approaches a singularity.
+		ratio := manip / sc.cfg.ManipulabilityThreshold
+		damping = math.Sqrt(damping*damping + (1-ratio)*(1-ratio)*servoMaxSingularityDamping*servoMaxSingularityDamping)
+	}
+
+	v := mat.NewVecDense(6, []float64{
+		twist.Linear.X / 1000, twist.Linear.Y / 1000, twist.Linear.Z / 1000,
+		twist.Angular.X, twist.Angular.Y, twist.Angular.Z,
+	})
+	qdot, err := dampedLeastSquares(jac, v, damping)
+	if err != nil {
+		return nil, err
+	}
+	if scale < 1 {
+		// Only the part of the motion which closes in on the obstacle is scaled, so that the arm can always back away
+		// or slide along the obstacle, even once it is within the stop distance.
+		grad, err := sc.clearanceGradient(ctx, current, dist)
+		if err != nil {
+			return nil, errors.Join(ErrServoCollision, err)
+		}
+		qdot = scaleApproach(qdot, grad, scale)
+	}
+
+	// Respect joint limits: slow joints moving toward a limit, then cap every joint at the max speed while preserving
+	// the direction of motion.
+	for i, lim := range limits {
+		qdot[i] *= jointLimitScale(lim, current[i], qdot[i], sc.cfg.JointLimitMargin)
+	}
+	speedScale := 1.
+	for _, qd := range qdot {
+		if math.Abs(qd) > sc.cfg.MaxJointSpeed {
+			speedScale = math.Min(speedScale, sc.cfg.MaxJointSpeed/math.Abs(qd))
+		}
+	}
+	for i := range qdot {
+		qdot[i] *= speedScale
+		lo, hi, _ := limits[i].GoodLimits()
+		step.Positions[i] = math.Max(lo, math.Min(hi, current[i]+qdot[i]*dt))
+		step.Velocities[i] = (step.Positions[i] - current[i]) / dt
+	}
+
+	if sc.clearance != nil {
+		// Joint limits and speed caps reshape the motion after it was scaled, so check where it actually leads. Within
+		// the stop distance, only steps which do not reduce the clearance any further are allowed.
+		next, err := sc.clearance(ctx, step.Positions)
+		if err != nil {
+			return nil, errors.Join(ErrServoCollision, err)
+		}
+		if next < sc.cfg.StopDistanceMM && next < dist {
+			return nil, ErrServoCollision
+		}
+	}
+	return step, nil
+}
+
+// clearanceGradient numerically estimates the gradient of the obstacle clearance with respect to the joint positions,
+// given the clearance dist at current.
+func (sc *ServoController) clearanceGradient(ctx context.Context, current []referenceframe.Input, dist float64) ([]float64, error) {
+	grad := make([]float64, len(current))
+	perturbed := append([]referenceframe.Input{}, current...)
+	for i := range current {
+		perturbed[i] = current[i] + clearanceGradientStep
+		d, err := sc.clearance(ctx, perturbed)
+		if err != nil {
+			return nil, err
+		}
+		perturbed[i] = current[i]
+		grad[i] = (d - dist) / clearanceGradientStep
+	}
+	return grad, nil
+}
+
+// scaleApproach scales the component of the joint velocities qdot along which the clearance decreases, as given by
+// the clearance gradient grad, by scale. Components which keep or increase the clearance are left untouched.
+func scaleApproach(qdot, grad []float64, scale float64) []float64 {
+	var dot, norm2 float64
+	for i := range qdot {
+		dot += qdot[i] * grad[i]
+		norm2 += grad[i] * grad[i]
+	}
+	if dot >= 0 || norm2 == 0 {
+		return qdot
+	}
+	k := (1 - scale) * dot / norm2
+	scaled := make([]float64, len(qdot))
+	for i := range qdot {
+		scaled[i] = qdot[i] - k*grad[i]
+	}
+	return scaled
+}
+
+// ComputeJacobian numerically estimates the 6xN geometric Jacobian of the given frame at the given inputs. The first
+// three rows map joint velocities to linear velocity in mm, and the last three to angular velocity in radians, both
+// in the base frame of the given frame.
+func ComputeJacobian(frame referenceframe.Frame, inputs []referenceframe.Input) (*mat.Dense, error) {
+	base, err := frame.Transform(inputs)
+	if err != nil {
+		return nil, err
+	}
+	baseQuatInv := quat.Conj(base.Orientation().Quaternion())
+
+	jac := mat.NewDense(6, len(inputs), nil)
+	perturbed := append([]referenceframe.Input{}, inputs...)
+	for i := range inputs {
+		perturbed[i] = inputs[i] + jacobianStep
+		pose, err := frame.Transform(perturbed)
+		if err != nil {
+			return nil, err
+		}
+		perturbed[i] = inputs[i]
+
+		dp := pose.Point().Sub(base.Point()).Mul(1 / jacobianStep)
+		// The rotation taking the base orientation to the perturbed one. It is tiny, so its axis-angle vector is twice
+		// the imaginary part of the quaternion; the spatialmath conversions would round it to zero.
+		dq := quat.Mul(pose.Orientation().Quaternion(), baseQuatInv)
+		if dq.Real < 0 {
+			dq = quat.Scale(-1, dq)
+		}
+		dw := r3.Vector{X: dq.Imag, Y: dq.Jmag, Z: dq.Kmag}.Mul(2 / jacobianStep)
+		jac.SetCol(i, []float64{dp.X, dp.Y, dp.Z, dw.X, dw.Y, dw.Z})
+	}
+	return jac, nil
+}
+
+// Manipulability returns the Yoshikawa manipulability measure sqrt(det(J*J^T)) of the given Jacobian. It approaches
+// zero as the frame approaches a singular configuration.
+func Manipulability(jac *mat.Dense) float64 {
+	rows, _ := jac.Dims()
+	var jjt mat.Dense
+	jjt.Mul(jac, jac.T())
+	if det := mat.Det(&jjt); det > 0 {
+		return math.Sqrt(det)
+	}
+	// Fewer joints than task dimensions; fall back to the product of singular values.
+	var svd mat.SVD
+	if !svd.Factorize(jac, mat.SVDNone) {
+		return 0
+	}
+	vals := svd.Values(nil)
+	prod := 1.
+	for i := 0; i < len(vals) && i < rows; i++ {
+		prod *= vals[i]
+	}
+	return prod
+}
+
+// dampedLeastSquares solves for qdot = J^T (J J^T + lambda^2 I)^-1 v.
+func dampedLeastSquares(jac *mat.Dense, v *mat.VecDense, lambda float64) ([]float64, error) {
+	rows, cols := jac.Dims()
+	var a mat.Dense
+	a.Mul(jac, jac.T())
+	for i := 0; i < rows; i++ {
+		a.Set(i, i, a.At(i, i)+lambda*lambda)
+	}
+	var y mat.VecDense
+	if err := y.SolveVec(&a, v); err != nil {
+		return nil, err
+	}
+	qdot := mat.NewVecDense(cols, nil)
+	qdot.MulVec(jac.T(), &y)
+	return qdot.RawVector().Data, nil
+}
+
+// jointLimitScale returns a factor in [0, 1] to apply to a joint velocity so that the joint slows as it approaches a
+// limit it is moving toward. Motion away from a limit is never scaled.
+func jointLimitScale(limit referenceframe.Limit, pos, vel, margin float64) float64 {
+	lo, hi, rng := limit.GoodLimits()
+	band := rng * margin
+	if band <= 0 || vel == 0 {
+		return 1
+	}
+	var dist float64
+	if vel > 0 {
+		dist = hi - pos
+	} else {
+		dist = pos - lo
+	}
+	return math.Max(0, math.Min(1, dist/band))
+}
+
+// clearanceScale maps an obstacle clearance to the scale of motion toward the obstacle: 1 beyond slowdown, 0 within
+// stop, linear in between.
+func clearanceScale(dist, stop, slowdown float64) float64 {
+	if dist >= slowdown {
+		return 1
+	}
+	if dist <= stop {
+		return 0
+	}
+	return (dist - stop) / (slowdown - stop)
+}
+
+// NewServoClearanceFunc builds a ServoClearanceFunc for the named frame of a frame system. All frames which move
+// with the servoed frame are checked against the given obstacles and against the rest of the robot.
+func NewServoClearanceFunc(
+	fs *referenceframe.FrameSystem,
+	frameName string,
+	seed referenceframe.FrameSystemInputs,
+	obstaclesInWorldFrame *referenceframe.GeometriesInFrame,
+	collisionBufferMM float64,
+	logger logging.Logger,
+) (ServoClearanceFunc, error) {
+	servoed := fs.Frame(frameName)
+	if servoed == nil {
+		return nil, referenceframe.NewFrameMissingError(frameName)
+	}
+	seedLinear := seed.ToLinearInputs()
+	geoms, err := referenceframe.FrameSystemGeometriesLinearInputs(fs, seedLinear)
+	if err != nil {
+		return nil, err
+	}
+
+	movingFrameNames := map[string]bool{}
+	var moving, static []spatialmath.Geometry
+	for name, gif := range geoms {
+		f := fs.Frame(name)
+		if f == nil {
+			continue
+		}
+		chain, err := fs.TracebackFrame(f)
+		if err != nil {
+			return nil, err
+		}
+		isMoving := false
+		for _, ancestor := range chain {
+			if ancestor.Name() == frameName {
+				isMoving = true
+				break
+			}
+		}
+		if isMoving {
+			movingFrameNames[name] = true
+			moving = append(moving, gif.Geometries()...)
+		} else {
+			static = append(static, gif.Geometries()...)
+		}
+	}
+
+	var obstacles []spatialmath.Geometry
+	if obstaclesInWorldFrame != nil {
+		obstacles = obstaclesInWorldFrame.Geometries()
+	}
+	if collisionBufferMM <= 0 {
+		collisionBufferMM = defaultCollisionBufferMM
+	}
+	constraints, err := CreateAllCollisionConstraints(
+		fs, moving, movingFrameNames, static, obstacles, nil, collisionBufferMM, NewCollisionCache(), logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, inputs []referenceframe.Input) (float64, error) {
+		config := seedLinear.Copy()
+		config.Put(frameName, inputs)
+		state := &StateFS{FS: fs, Configuration: config}
+
+		// Self-collision distances are between adjacent links and would pin the clearance near zero, so they are only
+		// used to reject a configuration, not to scale motion.
+		if constraints.SelfCollision != nil {
+			if _, err := constraints.SelfCollision(state); err != nil {
+				return -1, errors.Join(errors.New(selfCollisionConstraintDescription), err)
+			}
+		}
+		closest := math.Inf(1)
+		for _, fn := range []CollisionConstraintFunc{constraints.Obstacle, constraints.RobotToRobot} {
+			if fn == nil {
+				continue
+			}
+			d, err := fn(state)
+			if err != nil {
+				return -1, err
+			}
+			closest = min(closest, d)
+		}
+		return closest, nil
+	}, nil
+}
@@ -0,0 +1,224 @@
+package motionplan
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/referenceframe"
+	spatial "go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+func TestComputeJacobian(t *testing.T) {
+	m, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "")
+	test.That(t, err, test.ShouldBeNil)
+
+	inputs := []referenceframe.Input{0.1, -0.3, -0.5, 0.2, 0.7, -0.1}
+	jac, err := ComputeJacobian(m, inputs)
+	test.That(t, err, test.ShouldBeNil)
+	rows, cols := jac.Dims()
+	test.That(t, rows, test.ShouldEqual, 6)
+	test.That(t, cols, test.ShouldEqual, 6)
+
+	// A small joint motion should move the end effector by approximately J*dq.
+	dq := []float64{1e-4, -2e-4, 1e-4, 3e-4, -1e-4, 2e-4}
+	moved := make([]referenceframe.Input, len(inputs))
+	for i := range inputs {
+		moved[i] = inputs[i] + dq[i]
+	}
+	start, err := m.Transform(inputs)
+	test.That(t, err, test.ShouldBeNil)
+	end, err := m.Transform(moved)
+	test.That(t, err, test.ShouldBeNil)
+
+	predicted := make([]float64, 6)
+	for r := 0; r < 6; r++ {
+		for c := 0; c < 6; c++ {
+			predicted[r] += jac.At(r, c) * dq[c]
+		}
+	}
+	actual := end.Point().Sub(start.Point())
+	test.That(t, predicted[0], test.ShouldAlmostEqual, actual.X, 1e-3)
+	test.That(t, predicted[1], test.ShouldAlmostEqual, actual.Y, 1e-3)
+	test.That(t, predicted[2], test.ShouldAlmostEqual, actual.Z, 1e-3)
+
+	rot := spatial.Compose(end, spatial.PoseInverse(start)).Orientation().AxisAngles().ToR3()
+	test.That(t, predicted[3], test.ShouldAlmostEqual, rot.X, 1e-6)
+	test.That(t, predicted[4], test.ShouldAlmostEqual, rot.Y, 1e-6)
+	test.That(t, predicted[5], test.ShouldAlmostEqual, rot.Z, 1e-6)
+}
+
+func TestServoControllerStep(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	m, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "")
+	test.That(t, err, test.ShouldBeNil)
+	inputs := []referenceframe.Input{0.1, -0.3, -0.5, 0.2, 0.7, -0.1}
+
+	t.Run("linear twist moves the end effector along the commanded axis", func(t *testing.T) {
+		sc, err := NewServoController(m, ServoConfig{}, nil, logger)
+		test.That(t, err, test.ShouldBeNil)
+
+		start, err := m.Transform(inputs)
+		test.That(t, err, test.ShouldBeNil)
+		step, err := sc.Step(ctx, Twist{Linear: r3.Vector{X: 50}}, inputs, 0.02)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, step.Scale, test.ShouldEqual, 1.)
+		test.That(t, step.Manipulability, test.ShouldBeGreaterThan, 0)
+
+		end, err := m.Transform(step.Positions)
+		test.That(t, err, test.ShouldBeNil)
+		delta := end.Point().Sub(start.Point())
+		test.That(t, delta.X, test.ShouldAlmostEqual, 1, 0.1)
+		test.That(t, math.Abs(delta.Y), test.ShouldBeLessThan, 0.05)
+		test.That(t, math.Abs(delta.Z), test.ShouldBeLessThan, 0.05)
+		test.That(t, spatial.OrientationAlmostEqualEps(start.Orientation(), end.Orientation(), 1e-3), test.ShouldBeTrue)
+	})
+
+	t.Run("zero twist holds position", func(t *testing.T) {
+		sc, err := NewServoController(m, ServoConfig{}, nil, logger)
+		test.That(t, err, test.ShouldBeNil)
+		step, err := sc.Step(ctx, Twist{}, inputs, 0.02)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, step.Positions, test.ShouldResemble, inputs)
+		for _, v := range step.Velocities {
+			test.That(t, v, test.ShouldEqual, 0)
+		}
+	})
+
+	t.Run("joint speed is capped", func(t *testing.T) {
+		sc, err := NewServoController(m, ServoConfig{MaxJointSpeed: 0.1}, nil, logger)
+		test.That(t, err, test.ShouldBeNil)
+		step, err := sc.Step(ctx, Twist{Linear: r3.Vector{X: 5000}}, inputs, 0.02)
+		test.That(t, err, test.ShouldBeNil)
+		for _, v := range step.Velocities {
+			test.That(t, math.Abs(v), test.ShouldBeLessThanOrEqualTo, 0.1+1e-9)
+		}
+	})
+
+	t.Run("bad inputs", func(t *testing.T) {
+		sc, err := NewServoController(m, ServoConfig{}, nil, logger)
+		test.That(t, err, test.ShouldBeNil)
+		_, err = sc.Step(ctx, Twist{}, inputs[:3], 0.02)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = sc.Step(ctx, Twist{}, inputs, 0)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = NewServoController(m, ServoConfig{StopDistanceMM: 50, SlowdownDistanceMM: 20}, nil, logger)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
+
+func TestServoClearance(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	m, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "arm")
+	test.That(t, err, test.ShouldBeNil)
+	fs := referenceframe.NewEmptyFrameSystem("test")
+	test.That(t, fs.AddFrame(m, fs.World()), test.ShouldBeNil)
+
+	inputs := []referenceframe.Input{0.1, -0.3, -0.5, 0.2, 0.7, -0.1}
+	seed := referenceframe.FrameSystemInputs{"arm": inputs}
+	eePose, err := m.Transform(inputs)
+	test.That(t, err, test.ShouldBeNil)
+
+	// Place a wall just beyond the end effector along +X, inside the slowdown distance.
+	box, err := spatial.NewBox(
+		spatial.NewPoseFromPoint(eePose.Point().Add(r3.Vector{X: 160})),
+		r3.Vector{X: 20, Y: 200, Z: 200},
+		"wall",
+	)
+	test.That(t, err, test.ShouldBeNil)
+	obstacles := referenceframe.NewGeometriesInFrame(referenceframe.World, []spatial.Geometry{box})
+
+	clearance, err := NewServoClearanceFunc(fs, "arm", seed, obstacles, 0, logger)
+	test.That(t, err, test.ShouldBeNil)
+	dist, err := clearance(ctx, inputs)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, dist, test.ShouldBeLessThan, defaultServoSlowdownDistanceMM)
+
+	sc, err := NewServoController(m, ServoConfig{}, clearance, logger)
+	test.That(t, err, test.ShouldBeNil)
+	step, err := sc.Step(ctx, Twist{Linear: r3.Vector{X: 50}}, inputs, 0.02)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, step.Scale, test.ShouldBeGreaterThan, 0)
+	test.That(t, step.Scale, test.ShouldBeLessThan, 1)
+
+	t.Run("within the stop distance the arm can still back away or slide along the obstacle", func(t *testing.T) {
+		// A wall whose clearance is within the stop distance.
+		var near *referenceframe.GeometriesInFrame
+		var nearDist float64
+		for offset := 160.; offset > 0 && (nearDist == 0 || nearDist >= defaultServoStopDistanceMM); offset-- {
+			wall, err := spatial.NewBox(
+				spatial.NewPoseFromPoint(eePose.Point().Add(r3.Vector{X: offset})),
+				r3.Vector{X: 20, Y: 200, Z: 200},
+				"wall",
+			)
+			test.That(t, err, test.ShouldBeNil)
+			near = referenceframe.NewGeometriesInFrame(referenceframe.World, []spatial.Geometry{wall})
+			nearClearance, err := NewServoClearanceFunc(fs, "arm", seed, near, 0, logger)
+			test.That(t, err, test.ShouldBeNil)
+			nearDist, err = nearClearance(ctx, inputs)
+			if err != nil {
+				nearDist = 0
+			}
+		}
+		test.That(t, nearDist, test.ShouldBeGreaterThan, 0)
+		test.That(t, nearDist, test.ShouldBeLessThan, defaultServoStopDistanceMM)
+		nearClearance, err := NewServoClearanceFunc(fs, "arm", seed, near, 0, logger)
+		test.That(t, err, test.ShouldBeNil)
+		sc, err := NewServoController(m, ServoConfig{}, nearClearance, logger)
+		test.That(t, err, test.ShouldBeNil)
+
+		step, err := sc.Step(ctx, Twist{Linear: r3.Vector{X: -50}}, inputs, 0.02)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, step.Scale, test.ShouldEqual, 0.)
+		after, err := nearClearance(ctx, step.Positions)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, after, test.ShouldBeGreaterThan, nearDist)
+
+		step, err = sc.Step(ctx, Twist{Linear: r3.Vector{Y: 50}}, inputs, 0.02)
+		test.That(t, err, test.ShouldBeNil)
+		start, err := m.Transform(inputs)
+		test.That(t, err, test.ShouldBeNil)
+		end, err := m.Transform(step.Positions)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, end.Point().Sub(start.Point()).Y, test.ShouldBeGreaterThan, 0.5)
+
+		// Motion straight at the wall must not bring the arm any closer.
+		step, err = sc.Step(ctx, Twist{Linear: r3.Vector{X: 50}}, inputs, 0.02)
+		if err != nil {
+			test.That(t, errors.Is(err, ErrServoCollision), test.ShouldBeTrue)
+		} else {
+			after, err := nearClearance(ctx, step.Positions)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, after, test.ShouldBeGreaterThanOrEqualTo, nearDist)
+		}
+	})
+
+	_, err = NewServoClearanceFunc(fs, "missing", seed, obstacles, 0, logger)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestServoScales(t *testing.T) {
+	lim := referenceframe.Limit{Min: -1, Max: 1}
+	test.That(t, jointLimitScale(lim, 0, 1, 0.1), test.ShouldEqual, 1.)
+	test.That(t, jointLimitScale(lim, 0.9, 1, 0.1), test.ShouldAlmostEqual, 0.5)
+	test.That(t, jointLimitScale(lim, 0.9, -1, 0.1), test.ShouldEqual, 1.)
+	test.That(t, jointLimitScale(lim, 1, 1, 0.1), test.ShouldEqual, 0.)
+
+	test.That(t, clearanceScale(200, 10, 100), test.ShouldEqual, 1.)
+	test.That(t, clearanceScale(5, 10, 100), test.ShouldEqual, 0.)
+	test.That(t, clearanceScale(55, 10, 100), test.ShouldAlmostEqual, 0.5)
+
+	// clearance grows with the first joint: motion along it is kept, motion against it is scaled
+	grad := []float64{2, 0}
+	test.That(t, scaleApproach([]float64{1, 1}, grad, 0), test.ShouldResemble, []float64{1, 1})
+	test.That(t, scaleApproach([]float64{-1, 1}, grad, 0), test.ShouldResemble, []float64{0, 1})
+	test.That(t, scaleApproach([]float64{-1, 1}, grad, 0.5), test.ShouldResemble, []float64{-0.5, 1})
+}
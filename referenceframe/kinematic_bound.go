@@ -0,0 +1,125 @@
+package referenceframe
+
+import (
+	"fmt"
+	"math"
+
+	spatial "go.viam.com/rdk/spatialmath"
+)
+
+// KinematicBound bounds how far the points attached to a frame can move when its inputs change. A change of dq in
+// input i moves any point attached to the frame (or to frames beneath it) by at most
+//
+//	|dq| * (Rotational[i] * r + Translational[i])
+//
+// where r bounds the distance from the point to the joint's axis. Reach is an upper bound on the length of any
+// kinematic path through the frame, i.e. on how much the frame itself contributes to r.
+type KinematicBound struct {
+	Reach         float64
+	Rotational    []float64
+	Translational []float64
+}
+
+// NewKinematicBound computes the KinematicBound of a frame. Frames whose inputs do not interpolate linearly, such as
+// pose frames, are not supported.
+func NewKinematicBound(f Frame) (*KinematicBound, error) {
+	dof := len(f.DoF())
+	kb := &KinematicBound{Rotational: make([]float64, dof), Translational: make([]float64, dof)}
+	switch frame := f.(type) {
+	case *namedFrame:
+		return NewKinematicBound(frame.Frame)
+	case *tailGeometryStaticFrame:
+		return NewKinematicBound(frame.staticFrame)
+	case *staticFrame:
+		kb.Reach = frame.transform.Point().Norm() + geometryExtent(frame.geometry)
+	case *rotationalFrame:
+		kb.Rotational[0] = 1
+	case *translationalFrame:
+		limit := frame.limits[0]
+		extent := math.Max(math.Abs(limit.Min), math.Abs(limit.Max))
+		if math.IsInf(extent, 0) {
+			return nil, fmt.Errorf("cannot bound the motion of unlimited translational frame %q", f.Name())
+		}
+		kb.Reach = extent + geometryExtent(frame.geometry)
+		kb.Translational[0] = 1
+	case *SimpleModel:
+		return frame.kinematicBound()
+	default:
+		if dof > 0 {
+			return nil, fmt.Errorf("cannot bound the motion of frame %q of type %T", f.Name(), f)
+		}
+		gif, err := f.Geometries(nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range gif.Geometries() {
+			kb.Reach = math.Max(kb.Reach, geometryExtent(g))
+		}
+	}
+	return kb, nil
+}
+
+func (m *SimpleModel) kinematicBound() (*KinematicBound, error) {
+	dof := len(m.DoF())
+	kb := &KinematicBound{Rotational: make([]float64, dof), Translational: make([]float64, dof)}
+	offset := 0
+	for _, f := range m.framesInOrder() {
+		if len(f.DoF()) == 0 {
+			continue
+		}
+		inner, err := NewKinematicBound(f)
+		if err != nil {
+			return nil, err
+		}
+		kb.Reach += inner.Reach
+		for i := range inner.Rotational {
+			kb.Rotational[offset+i] += inner.Rotational[i]
+			kb.Translational[offset+i] += inner.Translational[i]
+		}
+		offset += len(inner.Rotational)
+	}
+	// Mimic frames are driven by another input, scaled by their multiplier.
+	for name, mm := range m.mimicMappings {
+		f := m.internalFS.Frame(name)
+		if f == nil {
+			continue
+		}
+		inner, err := NewKinematicBound(f)
+		if err != nil {
+			return nil, err
+		}
+		kb.Reach += inner.Reach
+		for i := range inner.Rotational {
+			kb.Rotational[mm.sourceInputIdx] += math.Abs(mm.valueMultiplier) * inner.Rotational[i]
+			kb.Translational[mm.sourceInputIdx] += math.Abs(mm.valueMultiplier) * inner.Translational[i]
+		}
+	}
+	// Static links hold no inputs but still lengthen the chain.
+	for _, name := range m.internalFS.FrameNames() {
+		f := m.internalFS.Frame(name)
+		if len(f.DoF()) > 0 {
+			continue
+		}
+		inner, err := NewKinematicBound(f)
+		if err != nil {
+			return nil, err
+		}
+		kb.Reach += inner.Reach
+	}
+	return kb, nil
+}
+
+// geometryExtent bounds the distance from a frame's origin to any point of a geometry attached to it.
+func geometryExtent(g spatial.Geometry) float64 {
+	if g == nil {
+		return 0
+	}
+	if bound, err := spatial.BoundingSphere(g); err == nil {
+		return bound.ToProtobuf().GetSphere().GetRadiusMm()
+	}
+	extent := 0.
+	for _, pt := range g.ToPoints(1) {
+		extent = math.Max(extent, pt.Norm())
+	}
+	return g.Pose().Point().Norm() + extent
+}
@@ -0,0 +1,69 @@
+package referenceframe
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	spatial "go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+func TestKinematicBound(t *testing.T) {
+	t.Run("bounds the motion of an arm", func(t *testing.T) {
+		m, err := ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "")
+		test.That(t, err, test.ShouldBeNil)
+		kb, err := NewKinematicBound(m)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(kb.Rotational), test.ShouldEqual, 6)
+		test.That(t, kb.Reach, test.ShouldBeGreaterThan, 0)
+
+		//nolint:gosec
+		rseed := rand.New(rand.NewSource(1))
+		for i := 0; i < 100; i++ {
+			from := GenerateRandomConfiguration(m, rseed)
+			to := make([]Input, len(from))
+			bound := 0.
+			for j := range from {
+				lim := m.DoF()[j]
+				to[j] = math.Max(lim.Min, math.Min(lim.Max, from[j]+(rseed.Float64()-0.5)*0.2))
+				bound += math.Abs(to[j]-from[j]) * (kb.Rotational[j]*kb.Reach + kb.Translational[j])
+			}
+			fromGeoms, err := m.Geometries(from)
+			test.That(t, err, test.ShouldBeNil)
+			toGeoms, err := m.Geometries(to)
+			test.That(t, err, test.ShouldBeNil)
+			for k, g := range fromGeoms.Geometries() {
+				moved := g.Pose().Point().Distance(toGeoms.Geometries()[k].Pose().Point())
+				test.That(t, moved, test.ShouldBeLessThanOrEqualTo, bound)
+			}
+		}
+	})
+
+	t.Run("translational frames", func(t *testing.T) {
+		sphere, err := spatial.NewSphere(spatial.NewZeroPose(), 5, "")
+		test.That(t, err, test.ShouldBeNil)
+		f, err := NewTranslationalFrameWithGeometry("slider", r3.Vector{X: 1}, Limit{Min: -100, Max: 50}, sphere)
+		test.That(t, err, test.ShouldBeNil)
+		kb, err := NewKinematicBound(NewNamedFrame(f, "renamed"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, kb.Reach, test.ShouldAlmostEqual, 105)
+		test.That(t, kb.Translational, test.ShouldResemble, []float64{1})
+		test.That(t, kb.Rotational, test.ShouldResemble, []float64{0})
+
+		unlimited, err := NewTranslationalFrame("unlimited", r3.Vector{X: 1}, Limit{Min: math.Inf(-1), Max: math.Inf(1)})
+		test.That(t, err, test.ShouldBeNil)
+		_, err = NewKinematicBound(unlimited)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("pose frames are unsupported", func(t *testing.T) {
+		f, err := NewPoseFrame("pose", nil)
+		test.That(t, err, test.ShouldBeNil)
+		_, err = NewKinematicBound(f)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
@@ -3,25 +3,18 @@
 package jobmanager
 
 import (
-	"bytes"
 	"container/ring"
 	"context"
-	"encoding/json"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/fullstorydev/grpcurl"
 	"github.com/go-co-op/gocron/v2"
 	"github.com/google/uuid"
-	"github.com/jhump/protoreflect/grpcreflect"
-	"github.com/pkg/errors"
 	"go.uber.org/multierr"
 	"go.viam.com/utils"
 	"go.viam.com/utils/rpc"
-	"google.golang.org/grpc/metadata"
-	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 
 	"go.viam.com/rdk/config"
 	"go.viam.com/rdk/grpc"
@@ -31,14 +24,7 @@ import (
 	"go.viam.com/rdk/utils/ssync"
 )
 
-const (
-	// componentServiceIndex is an index for the desired component or service within the
-	// viam service notation. For example, viam.component.movementsensor.v1.MovementSensorService
-	// has movementsensor as its second index in a dot separated slice, which is the resource
-	// the job manager will be looking for.
-	componentServiceIndex int = 2
-	historyLength         int = 10
-)
+const historyLength int = 10
 
 // JobManager keeps track of the currently scheduled jobs and updates the schedule with
 // respect to the "jobs" part of the config.
@@ -166,39 +152,6 @@ func (jm *JobManager) Close() error {
 	return jm.scheduler.Shutdown()
 }
 
-// createDescriptorSourceAndgRPCMethod sets up a DescriptorSource for grpc translations
-// and sets up parts of the grpc method string that will be invoked later.
-func (jm *JobManager) createDescriptorSourceAndgRPCMethod(
-	res resource.Resource,
-	method string,
-) (grpcurl.DescriptorSource, string, string, error) {
-	refCtx := metadata.NewOutgoingContext(jm.ctx, nil)
-	refClient := grpcreflect.NewClientV1Alpha(refCtx, reflectpb.NewServerReflectionClient(jm.conn))
-	// TODO(RSDK-9718)
-	// refClient.AllowMissingFileDescriptors()
-	reflSource := grpcurl.DescriptorSourceFromServer(jm.ctx, refClient)
-	descSource := reflSource
-	resourceType := res.Name().API.SubtypeName
-	// some subtypes have an underscore in their name, like audio_in, audio_out, input_controller,
-	// or pose_tracker, while their APIs do not - so we have to remove the underscore.
-	resourceType = strings.ReplaceAll(resourceType, "_", "")
-	services, err := descSource.ListServices()
-	if err != nil {
-		return nil, "", "", err
-	}
-	var grpcService string
-	for _, srv := range services {
-		if strings.Split(srv, ".")[componentServiceIndex] == resourceType {
-			grpcService = srv
-			break
-		}
-	}
-	if grpcService == "" {
-		return nil, "", "", errors.Errorf("could not find a service for type: %s", resourceType)
-	}
-	return descSource, grpcService, method, nil
-}
-
 // createJobFunction returns a function that the job scheduler puts on its queue.
 func (jm *JobManager) createJobFunction(jc config.JobConfig, continuous bool) func(ctx context.Context) error {
 	jobLogger := jm.logger.Sublogger(jc.Name)
@@ -230,59 +183,19 @@ func (jm *JobManager) createJobFunction(jc config.JobConfig, continuous bool) fu
 			return nil
 		}
 
-		descSource, grpcService, grpcMethod, err := jm.createDescriptorSourceAndgRPCMethod(res, jc.Method)
+		reflection, err := grpc.NewResourceReflection(jm.ctx, jm.conn, res.Name().API)
 		if err != nil {
 			jobLogger.CWarnw(jm.ctx, "grpc setup failed", "error", err)
 			return err
 		}
+		defer reflection.Close()
 
-		gRPCArgument := resource.GetResourceNameOverride(grpcService, grpcMethod)
-		argumentMap := map[string]string{
-			gRPCArgument: jc.Resource,
-		}
-		argumentBytes, err := json.Marshal(argumentMap)
-		if err != nil {
-			jobLogger.CWarnw(jm.ctx, "could not serialize gRPC method arguments", "error", err.Error())
-			return err
-		}
-		options := grpcurl.FormatOptions{
-			EmitJSONDefaultFields: true,
-			IncludeTextSeparator:  true,
-			AllowUnknownFields:    true,
-		}
-		rf, formatter, err := grpcurl.RequestParserAndFormatter(
-			grpcurl.Format("json"),
-			descSource,
-			bytes.NewBuffer(argumentBytes),
-			options)
-		if err != nil {
-			jobLogger.CWarnw(jm.ctx, "could not create parser and formatter for grpc requests", "error", err.Error())
-			return err
-		}
-
-		buffer := bytes.NewBuffer(make([]byte, 0))
-		h := &grpcurl.DefaultEventHandler{
-			Out:            buffer,
-			Formatter:      formatter,
-			VerbosityLevel: 0,
-		}
 		jobLogger.CDebugw(jm.ctx, "Job triggered", "name", jc.Name)
-		grpcMethodCombined := grpcService + "." + grpcMethod
-		err = grpcurl.InvokeRPC(jm.ctx, descSource, jm.conn, grpcMethodCombined, nil, h, rf.Next)
+		response, err := reflection.Invoke(jm.ctx, jc.Resource, jc.Method, nil)
 		if err != nil {
+			// this includes panics of the method.
 			jobLogger.CWarnw(jm.ctx, "Job failed", "name", jc.Name, "error", err.Error())
 			return err
-		} else if h.Status != nil && h.Status.Err() != nil {
-			// if job panics, it seems to be captured here.
-			jobLogger.CWarnw(jm.ctx, "Job failed", "name", jc.Name, "error", h.Status.Err())
-			return h.Status.Err()
-		}
-		response := map[string]any{}
-		err = json.Unmarshal(buffer.Bytes(), &response)
-		if err != nil {
-			jobLogger.CWarnw(jm.ctx, "Unmarshalling grpc response failed with error", "name", jc.Name,
-				"error", err.Error())
-			return err
 		}
 		jobLogger.CDebugw(jm.ctx, "Job succeeded", "name", jc.Name, "response", response)
 		return nil
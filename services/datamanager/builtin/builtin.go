@@ -25,11 +25,13 @@ import (
 	"google.golang.org/grpc"
 
 	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/internal/cloud"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/resource"
 	"go.viam.com/rdk/robot"
 	"go.viam.com/rdk/robot/framesystem"
+	"go.viam.com/rdk/robot/web"
 	"go.viam.com/rdk/services/datamanager"
 	"go.viam.com/rdk/services/datamanager/builtin/capture"
 	"go.viam.com/rdk/services/datamanager/builtin/shared"
@@ -91,7 +93,14 @@ type builtIn struct {
 	sync               *datasync.Sync
 	diskSummaryTracker *diskSummaryTracker
 
+	// captureDirMu guards captureDirs, the directories sync uploads from, which hold everything captured, and the
+	// keyring the capture files in them are decrypted with.
+	captureDirMu sync.Mutex
+	captureDirs  []string
+	keyring      *data.CaptureKeyring
+
 	captureControlPoller *goutils.StoppableWorkers
+	methodInvoker        *methodInvoker
 }
 
 // New returns a new builtin data manager service for the given robot.
@@ -125,6 +134,7 @@ func New(
 		capture:            capture,
 		sync:               sync,
 		diskSummaryTracker: diskSummaryTracker,
+		methodInvoker:      newMethodInvoker(logger.Sublogger("method_invoker")),
 	}
 
 	if err := svc.BuiltInReconfigure(ctx, deps, conf); err != nil {
@@ -144,6 +154,7 @@ func (b *builtIn) Close(ctx context.Context) error {
 	b.diskSummaryTracker.close()
 	b.capture.Close(ctx)
 	b.sync.Close()
+	b.methodInvoker.close()
 	return nil
 }
 
@@ -162,6 +173,114 @@ func (b *builtIn) Sync(ctx context.Context, extra map[string]interface{}) error
 	return b.sync.Sync(ctx, extra)
 }
 
+// DoCommand fires a data capture trigger when given DoTrigger with its name, and optionally a reason to record in the
+// trigger's sequence. Given DoQuery, it aggregates the tabular data in the capture directory. Given
+// DoRetentionReport, it reports the files retention rules would delete.
+func (b *builtIn) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if rawName, ok := cmd[datamanager.DoTrigger]; ok {
+		return b.doTrigger(rawName, cmd)
+	}
+	if rawQuery, ok := cmd[datamanager.DoQuery]; ok {
+		return b.doQuery(ctx, rawQuery)
+	}
+	if _, ok := cmd[datamanager.DoRetentionReport]; ok {
+		return b.doRetentionReport(ctx)
+	}
+	return nil, resource.ErrDoUnimplemented
+}
+
+func (b *builtIn) doTrigger(rawName interface{}, cmd map[string]interface{}) (map[string]interface{}, error) {
+	name, ok := rawName.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be the name of a trigger", datamanager.DoTrigger)
+	}
+	reason, _ := cmd["reason"].(string)
+	// capture guards its own trigger state, so this doesn't wait on b.mu, which is held for the whole of a manual sync
+	seq, err := b.capture.Trigger(name, capture.TriggerSourceDoCommand, reason)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]interface{}, 0, len(seq.Resources))
+	for _, r := range seq.Resources {
+		resources = append(resources, map[string]interface{}{"resource_name": r.ResourceName, "method": r.MethodName})
+	}
+	return map[string]interface{}{
+		"sequence_id": seq.ID,
+		"start_time":  seq.StartAt.Format(time.RFC3339Nano),
+		"end_time":    seq.EndAt.Format(time.RFC3339Nano),
+		"resources":   resources,
+	}, nil
+}
+
+func (b *builtIn) doQuery(ctx context.Context, rawQuery interface{}) (map[string]interface{}, error) {
+	params, ok := rawQuery.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a map of query parameters", datamanager.DoQuery)
+	}
+	query := data.TabularQuery{}
+	query.ResourceName, _ = params["resource_name"].(string)
+	query.MethodName, _ = params["method"].(string)
+	query.Path, _ = params["path"].(string)
+	for key, t := range map[string]*time.Time{"start": &query.Start, "end": &query.End} {
+		raw, ok := params[key].(string)
+		if !ok {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s of %s must be an RFC3339 time: %w", key, datamanager.DoQuery, err)
+		}
+		*t = parsed
+	}
+	if bucketSec, ok := params["bucket_sec"].(float64); ok {
+		query.BucketSize = time.Duration(bucketSec * float64(time.Second))
+	}
+
+	// the capture directories are guarded apart from b.mu, which is held for the whole of a manual sync
+	b.captureDirMu.Lock()
+	captureDirs, keyring := b.captureDirs, b.keyring
+	b.captureDirMu.Unlock()
+	result, err := data.QueryCapturedTabularData(ctx, captureDirs, query, data.WithCaptureKeyring(keyring))
+	if err != nil {
+		return nil, err
+	}
+	buckets := make([]interface{}, 0, len(result.Buckets))
+	for _, bucket := range result.Buckets {
+		m := map[string]interface{}{"count": bucket.Count, "min": bucket.Min, "max": bucket.Max, "avg": bucket.Avg}
+		if query.BucketSize > 0 {
+			m["start_time"] = bucket.Start.Format(time.RFC3339Nano)
+		}
+		buckets = append(buckets, m)
+	}
+	return map[string]interface{}{
+		"buckets":  buckets,
+		"readings": result.Readings,
+		"skipped":  result.Skipped,
+	}, nil
+}
+
+func (b *builtIn) doRetentionReport(ctx context.Context) (map[string]interface{}, error) {
+	// sync guards its own config, so this doesn't wait on b.mu, which is held for the whole of a manual sync
+	report, err := b.sync.RetentionReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]interface{}, 0, len(report.Files))
+	for _, file := range report.Files {
+		files = append(files, map[string]interface{}{
+			"path":       file.Path,
+			"rule":       file.Rule,
+			"age_hours":  file.Age.Hours(),
+			"size_bytes": file.Size,
+		})
+	}
+	return map[string]interface{}{
+		"dry_run": report.DryRun,
+		"files":   files,
+		"bytes":   report.Bytes,
+	}, nil
+}
+
 // Reconfigure updates the data manager service when the config has changed.
 // At time of writing Reconfigure only returns an error in one of the following unrecoverable error cases:
 //  1. There is some static (aka compile time) error which we currently are only able to detected at runtime:
@@ -173,6 +292,8 @@ func (b *builtIn) Sync(ctx context.Context, extra map[string]interface{}) error
 // 2. The user is running data manager in an untrusted env (see the comment above ErrCaptureDirectoryConfigurationDisabled
 // for more details) and has specified a non default capture directory.
 //
+// 3. Encryption is configured and its keys can't be loaded, in which case capture must not continue unencrypted.
+//
 // The only time long lived resources (aka goroutines) are booted is in the calls to capture.Reconfigure and sync.Reconfigure
 // It is important that we only call those methods after we have checked for all errors, otherwise we could leak resources
 // when errors occur.
@@ -199,6 +320,13 @@ func (b *builtIn) BuiltInReconfigure(ctx context.Context, deps resource.Dependen
 		return err
 	}
 
+	var keyring *data.CaptureKeyring
+	if c.Encryption != nil {
+		if keyring, err = c.Encryption.keyring(); err != nil {
+			return fmt.Errorf("failed to load capture encryption keys: %w", err)
+		}
+	}
+
 	captureConfig := c.captureConfig(b.logger)
 	collectorConfigsByResource, err := lookupCollectorConfigsByResource(deps, conf, captureConfig.CaptureDir, b.logger)
 	if err != nil {
@@ -218,6 +346,16 @@ func (b *builtIn) BuiltInReconfigure(ctx context.Context, deps resource.Dependen
 	}
 	syncSensor, syncSensorEnabled := syncSensorFromDeps(c.SelectiveSyncerName, deps, b.logger)
 	syncConfig := c.syncConfig(syncSensor, syncSensorEnabled, b.logger)
+	syncConfig.CapturePriorities = capture.SyncPriorities(collectorConfigsByResource)
+	// Keys removed from the config are kept to decrypt the files still encrypted with them until those are synced.
+	b.captureDirMu.Lock()
+	previousKeyring := b.keyring
+	b.captureDirMu.Unlock()
+	keyring, err = data.RetainCaptureKeys(keyring, previousKeyring, syncConfig.SyncPaths())
+	if err != nil {
+		b.logger.Warnw("failed to check which capture files are encrypted with removed keys, keeping them all", "error", err)
+	}
+	syncConfig.Keyring = keyring
 
 	controlSensor, controlSensorKey := captureControlSensorFromDeps(c.CaptureControlSensor, deps, b.logger)
 
@@ -227,6 +365,14 @@ func (b *builtIn) BuiltInReconfigure(ctx context.Context, deps resource.Dependen
 	} else {
 		frameSystem = svc
 	}
+	if svc, err := resource.FromProvider[web.Service](deps, web.InternalServiceName); err != nil {
+		b.logger.Debugw("web service unavailable; methods with no registered collector can't be captured over gRPC",
+			"error", err)
+	} else {
+		b.methodInvoker.setAddress(svc.ModuleAddresses())
+	}
+	captureConfig.MethodInvoker = b.methodInvoker.invoke
+	captureConfig.Keyring = keyring
 
 	b.stopCaptureControlPoller()
 	b.mu.Lock()
@@ -241,6 +387,10 @@ func (b *builtIn) BuiltInReconfigure(ctx context.Context, deps resource.Dependen
 
 	b.diskSummaryTracker.reconfigure(syncConfig.SyncPaths(), syncConfig.SyncIntervalMins, shouldSync)
 	b.capture.Reconfigure(ctx, frameSystem, collectorConfigsByResource, resourcesByShortName, captureConfig)
+	b.captureDirMu.Lock()
+	b.captureDirs = syncConfig.SyncPaths()
+	b.keyring = keyring
+	b.captureDirMu.Unlock()
 	b.sync.Reconfigure(ctx, syncConfig, cloudConnSvc)
 
 	if controlSensor != nil && !captureConfig.CaptureDisabled {
@@ -508,10 +658,13 @@ type dataManagerStats struct {
 	SyncPaths               syncPathsSummary
 	DiskUsage               diskUsageSummary
 	FilesDeletedToFreeSpace int64
+	FilesDeletedByRetention int64
 	Upload                  datasync.FTDCUploadStats
+	// SuppressedSamples is the number of unchanged samples suppressed by change detection, by resource and method.
+	SuppressedSamples map[string]int64
 }
 
-// Stats satisfies the ftdc.Statser interface and will return the disk usage and sync statistics.
+// Stats satisfies the ftdc.Statser interface and will return the disk usage, capture and sync statistics.
 func (b *builtIn) Stats() any {
 	result := dataManagerStats{}
 
@@ -527,8 +680,13 @@ func (b *builtIn) Stats() any {
 	if b.sync != nil {
 		syncStats := b.sync.GetStats()
 		result.FilesDeletedToFreeSpace = syncStats.FilesDeletedToFreeSpace
+		result.FilesDeletedByRetention = syncStats.FilesDeletedByRetention
 		result.Upload = syncStats.Upload
 	}
 
+	if b.capture != nil {
+		result.SuppressedSamples = b.capture.SuppressedSamples()
+	}
+
 	return result
 }
@@ -10,11 +10,14 @@ import (
 	"github.com/golang/geo/r3"
 	v1 "go.viam.com/api/app/datasync/v1"
 	"go.viam.com/test"
+	"go.viam.com/utils/testutils"
 
 	"go.viam.com/rdk/components/arm"
 	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/datamanager"
+	"go.viam.com/rdk/services/datamanager/builtin/capture"
 	datasync "go.viam.com/rdk/services/datamanager/builtin/sync"
 	"go.viam.com/rdk/spatialmath"
 	"go.viam.com/rdk/testutils/inject"
@@ -348,3 +351,109 @@ func waitForCaptureFilesToExceedNFiles(captureDir string, n int, logger logging.
 		}
 	}
 }
+
+func TestTriggeredCapture(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	captureDir := t.TempDir()
+	r := setupRobot(nil, map[resource.Name]resource.Resource{
+		arm.Named("arm1"): &inject.Arm{
+			EndPositionFunc: func(ctx context.Context, extra map[string]interface{}) (spatialmath.Pose, error) {
+				return spatialmath.NewZeroPose(), nil
+			},
+		},
+	})
+	config, deps := setupConfig(t, r, enabledTabularCollectorConfigPath)
+	c := config.ConvertedAttributes.(*Config)
+	c.ScheduledSyncDisabled = true
+	c.CaptureDir = captureDir
+	c.Triggers = []capture.TriggerConfig{{Name: "bump"}}
+	for _, assocConf := range config.AssociatedAttributes {
+		methods := assocConf.(*datamanager.AssociatedConfig).CaptureMethods
+		for i := range methods {
+			methods[i].Triggered = &datamanager.TriggeredCaptureConfig{Trigger: "bump", PreTriggerSec: 10, PostTriggerSec: 0.1}
+		}
+	}
+
+	b, err := New(context.Background(), deps, config, datasync.NoOpCloudClientConstructor, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, b.Close(context.Background()), test.ShouldBeNil)
+	}()
+
+	// readings are kept in memory until the trigger fires
+	time.Sleep(100 * time.Millisecond)
+	test.That(t, getAllFileInfos(captureDir), test.ShouldBeEmpty)
+
+	_, err = b.DoCommand(context.Background(), map[string]interface{}{"unknown": true})
+	test.That(t, err, test.ShouldEqual, resource.ErrDoUnimplemented)
+	resp, err := b.DoCommand(context.Background(), map[string]interface{}{datamanager.DoTrigger: "bump", "reason": "test"})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp["resources"], test.ShouldResemble, []interface{}{
+		map[string]interface{}{"resource_name": "arm1", "method": "EndPosition"},
+	})
+
+	// the buffered readings are completed once the window ends, along with the sequence recording the trigger
+	armDir := data.CaptureFilePathWithReplacedReservedChars(filepath.Join(captureDir, arm.API.String(), "arm1"))
+	waitForCaptureFilesToExceedNFiles(armDir, 0, logger)
+	testFilesContainSensorData(t, armDir)
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		seqFiles, err := filepath.Glob(filepath.Join(captureDir, data.SequencesDir, "*"+data.CompletedSequenceFileExt))
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, seqFiles, test.ShouldHaveLength, 1)
+	})
+}
+
+func TestQueryCapturedData(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	captureDir := t.TempDir()
+	r := setupRobot(nil, map[resource.Name]resource.Resource{
+		arm.Named("arm1"): &inject.Arm{
+			EndPositionFunc: func(ctx context.Context, extra map[string]interface{}) (spatialmath.Pose, error) {
+				return spatialmath.NewPoseFromPoint(r3.Vector{X: 5, Y: 6, Z: 7}), nil
+			},
+		},
+	})
+	config, deps := setupConfig(t, r, enabledTabularCollectorConfigPath)
+	c := config.ConvertedAttributes.(*Config)
+	c.ScheduledSyncDisabled = true
+	c.CaptureDir = captureDir
+	// small files so readings reach the disk quickly
+	c.MaximumCaptureFileSizeBytes = 1
+
+	b, err := New(context.Background(), deps, config, datasync.NoOpCloudClientConstructor, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, b.Close(context.Background()), test.ShouldBeNil)
+	}()
+
+	query := map[string]interface{}{
+		"resource_name": "arm1",
+		"method":        "EndPosition",
+		"path":          "pose.x",
+		"start":         time.Now().Add(-time.Minute).Format(time.RFC3339Nano),
+		"bucket_sec":    3600.,
+	}
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		resp, err := b.DoCommand(context.Background(), map[string]interface{}{datamanager.DoQuery: query})
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, resp["skipped"], test.ShouldEqual, 0)
+		buckets, _ := resp["buckets"].([]interface{})
+		// the readings may span the hour
+		test.That(tb, len(buckets), test.ShouldBeBetweenOrEqual, 1, 2)
+		if len(buckets) == 0 {
+			return
+		}
+		bucket := buckets[0].(map[string]interface{})
+		test.That(tb, bucket["min"], test.ShouldEqual, 5)
+		test.That(tb, bucket["max"], test.ShouldEqual, 5)
+		test.That(tb, bucket["avg"], test.ShouldEqual, 5)
+		test.That(tb, bucket["start_time"], test.ShouldNotBeEmpty)
+	})
+
+	query["start"] = "yesterday"
+	_, err = b.DoCommand(context.Background(), map[string]interface{}{datamanager.DoQuery: query})
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "start of query must be an RFC3339 time")
+}
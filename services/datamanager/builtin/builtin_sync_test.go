@@ -33,6 +33,7 @@ import (
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/resource"
 	"go.viam.com/rdk/rimage"
+	"go.viam.com/rdk/services/datamanager"
 	datasync "go.viam.com/rdk/services/datamanager/builtin/sync"
 	"go.viam.com/rdk/spatialmath"
 	"go.viam.com/rdk/testutils/inject"
@@ -1244,3 +1245,45 @@ func newImgPng(t *testing.T) image.Image {
 	test.That(t, err, test.ShouldBeNil)
 	return imgPng
 }
+
+func TestRetentionReport(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	captureDir := t.TempDir()
+	r := setupRobot(nil, map[resource.Name]resource.Resource{arm.Named("arm1"): &inject.Arm{}})
+	config, deps := setupConfig(t, r, disabledTabularCollectorConfigPath)
+	c := config.ConvertedAttributes.(*Config)
+	c.ScheduledSyncDisabled = true
+	c.CaptureDir = captureDir
+
+	bSvc, err := New(context.Background(), deps, config, datasync.NoOpCloudClientConstructor, logger)
+	test.That(t, err, test.ShouldBeNil)
+	b := bSvc.(*builtIn)
+	defer func() {
+		test.That(t, b.Close(context.Background()), test.ShouldBeNil)
+	}()
+
+	_, err = b.DoCommand(context.Background(), map[string]interface{}{datamanager.DoRetentionReport: true})
+	test.That(t, err, test.ShouldBeError, "no retention rules are configured")
+
+	oldFile := filepath.Join(captureDir, "old.txt")
+	test.That(t, os.WriteFile(oldFile, []byte("old"), 0o600), test.ShouldBeNil)
+	dayAgo := time.Now().Add(-24 * time.Hour)
+	test.That(t, os.Chtimes(oldFile, dayAgo, dayAgo), test.ShouldBeNil)
+	test.That(t, os.WriteFile(filepath.Join(captureDir, "new.txt"), []byte("new"), 0o600), test.ShouldBeNil)
+
+	// the report doesn't delete anything, whether or not the retention config is a dry run
+	c.Retention = &datasync.RetentionConfig{Rules: []datasync.RetentionRule{{Name: "everything", MaxAgeHours: 12}}}
+	test.That(t, b.BuiltInReconfigure(context.Background(), deps, config), test.ShouldBeNil)
+	resp, err := b.DoCommand(context.Background(), map[string]interface{}{datamanager.DoRetentionReport: true})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp["dry_run"], test.ShouldBeTrue)
+	test.That(t, resp["bytes"], test.ShouldEqual, 3)
+	files := resp["files"].([]interface{})
+	test.That(t, files, test.ShouldHaveLength, 1)
+	file := files[0].(map[string]interface{})
+	test.That(t, file["path"], test.ShouldEqual, oldFile)
+	test.That(t, file["rule"], test.ShouldEqual, "everything")
+	test.That(t, file["age_hours"], test.ShouldAlmostEqual, 24, 0.1)
+	_, err = os.Stat(oldFile)
+	test.That(t, err, test.ShouldBeNil)
+}
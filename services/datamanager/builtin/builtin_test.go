@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -297,6 +298,47 @@ func TestReconfigure(t *testing.T) {
 	})
 }
 
+func TestReconfigureRemovingEncryption(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	b, closeFunc := builtinWithEmptyConfig(t, logger)
+	defer closeFunc()
+	svc := b.(*builtIn)
+
+	captureDir := t.TempDir()
+	keyPath := filepath.Join(t.TempDir(), "capture.key")
+	test.That(t, os.WriteFile(keyPath, []byte(strings.Repeat("ab", data.CaptureKeySize)), 0o600), test.ShouldBeNil)
+	encrypted := &Config{
+		CaptureDir:            captureDir,
+		ScheduledSyncDisabled: true,
+		Encryption:            &EncryptionConfig{Key: EncryptionKeyConfig{File: keyPath}},
+	}
+	err := svc.BuiltInReconfigure(ctx, mockDeps(nil, nil), resource.Config{ConvertedAttributes: encrypted})
+	test.That(t, err, test.ShouldBeNil)
+	keyring := svc.keyring
+	test.That(t, keyring.CurrentKeyID(), test.ShouldNotBeEmpty)
+
+	md := &v1.DataCaptureMetadata{ComponentName: "sensor1", Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR}
+	f, err := data.NewCaptureFile(captureDir, md, data.WithCaptureKeyring(keyring))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	// files encrypted before encryption was removed can still be decrypted, and new files aren't encrypted
+	unencrypted := &Config{CaptureDir: captureDir, ScheduledSyncDisabled: true}
+	err = svc.BuiltInReconfigure(ctx, mockDeps(nil, nil), resource.Config{ConvertedAttributes: unencrypted})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, svc.keyring, test.ShouldNotBeNil)
+	test.That(t, svc.keyring.CurrentKeyID(), test.ShouldBeEmpty)
+	_, err = data.SensorDataFromCaptureFilePath(f.GetPath(), data.WithCaptureKeyring(svc.keyring))
+	test.That(t, err, test.ShouldBeNil)
+
+	// once those files are gone the key is dropped
+	test.That(t, os.Remove(f.GetPath()), test.ShouldBeNil)
+	err = svc.BuiltInReconfigure(ctx, mockDeps(nil, nil), resource.Config{ConvertedAttributes: unencrypted})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, svc.keyring, test.ShouldBeNil)
+}
+
 func TestFileDeletion(t *testing.T) {
 	logger := logging.NewTestLogger(t)
 	mockClock := clock.NewMock()
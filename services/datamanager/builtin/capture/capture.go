@@ -58,6 +58,8 @@ type Capture struct {
 	logger      logging.Logger
 	clk         clock.Clock
 	frameSystem framesystem.Service
+	// methodInvoker is used by the collectors of methods which have no collector registered.
+	methodInvoker data.MethodInvoker
 
 	collectorsMu sync.Mutex
 	collectors   collectors
@@ -67,6 +69,9 @@ type Capture struct {
 	maxCaptureFileSize int64
 	mongoMU            sync.Mutex
 	mongo              captureMongo
+	// keyring is what capture files are encrypted with, stored so that collectors are rebuilt when its current key
+	// changes.
+	keyring *data.CaptureKeyring
 
 	// defaultCollectorConfigs are the default as specified in the machine config.
 	// These are stored in order to be compared to any capture override readings.
@@ -81,6 +86,15 @@ type Capture struct {
 
 	// openSequences holds in-flight sequences emitted by the capture control sensor.
 	openSequences map[openSequenceKey]*OpenSequence
+
+	// triggersMu protects the trigger state below.
+	triggersMu     sync.Mutex
+	triggerConfigs []TriggerConfig
+	// triggerResources are the sensors and vision services of triggerConfigs, to notice when they are rebuilt.
+	triggerResources []resource.Resource
+	triggerWorkers   *goutils.StoppableWorkers
+	// triggeredSequences holds the sequence of each trigger whose window hasn't ended, by trigger name.
+	triggeredSequences map[string]*triggeredSequence
 }
 
 type captureMongo struct {
@@ -103,6 +117,10 @@ type collectorAndConfig struct {
 	Resource  resource.Resource
 	Collector data.Collector
 	Config    datamanager.DataCaptureConfig
+	// triggered is the collector's ring buffer when it is configured for triggered capture.
+	triggered *data.TriggeredBuffer
+	// changeDetector suppresses unchanged samples when the collector is configured for change detection.
+	changeDetector *data.ChangeDetector
 }
 
 // Identifier for a particular collector: component name, component model, component type,
@@ -113,17 +131,20 @@ func New(
 	logger logging.Logger,
 ) *Capture {
 	return &Capture{
-		clk:           clock,
-		logger:        logger,
-		collectors:    collectors{},
-		openSequences: map[openSequenceKey]*OpenSequence{},
+		clk:                clock,
+		logger:             logger,
+		collectors:         collectors{},
+		openSequences:      map[openSequenceKey]*OpenSequence{},
+		triggeredSequences: map[string]*triggeredSequence{},
 	}
 }
 
 func format(c datamanager.DataCaptureConfig) string {
 	return fmt.Sprintf("datamanager.DataCaptureConfig{"+
-		"Name: %s, Method: %s, CaptureFrequencyHz: %f, CaptureQueueSize: %d, AdditionalParams:	%v, Disabled: %t, Tags: %v, CaptureDirectory: %s}",
-		c.Name, c.Method, c.CaptureFrequencyHz, c.CaptureQueueSize, c.AdditionalParams, c.Disabled, c.Tags, c.CaptureDirectory)
+		"Name: %s, Method: %s, CaptureFrequencyHz: %f, CaptureQueueSize: %d, AdditionalParams:	%v, Disabled: %t, Tags: %v, "+
+		"CaptureDirectory: %s, Triggered: %+v, SyncPriority: %d, ChangeDetection: %+v}",
+		c.Name, c.Method, c.CaptureFrequencyHz, c.CaptureQueueSize, c.AdditionalParams, c.Disabled, c.Tags,
+		c.CaptureDirectory, c.Triggered, c.SyncPriority, c.ChangeDetection)
 }
 
 func (c *Capture) newCollectors(
@@ -237,6 +258,7 @@ func (c *Capture) Reconfigure(
 
 	// The frame system is required for any collectors that capture data via the frame system, so we set it on the Capture struct.
 	c.frameSystem = frameSystem
+	c.methodInvoker = config.MethodInvoker
 
 	// Service is disabled, so close all collectors and clear the map so we can instantiate new ones if we enable this service.
 	if config.CaptureDisabled {
@@ -247,6 +269,10 @@ func (c *Capture) Reconfigure(
 
 	if c.captureDir != config.CaptureDir {
 		c.logger.Infof("capture_dir old: %s, new: %s", c.captureDir, config.CaptureDir)
+		// No collectors write to the new capture directory yet, so its in progress files were left behind by a
+		// previous run, e.g. one which lost power. The directories of the collectors are repaired as each is built,
+		// which covers those outside the capture directory.
+		c.repairInProgressFiles(config.CaptureDir, config.Keyring)
 	}
 
 	if c.maxCaptureFileSize != config.MaximumCaptureFileSizeBytes {
@@ -265,15 +291,18 @@ func (c *Capture) Reconfigure(
 	}
 	c.collectors = newCollectors
 	c.collectorsMu.Unlock()
+	c.reconfigureTriggers(config.Triggers, resourcesByShortName)
 	c.defaultCollectorConfigs = collectorConfigsByResource
 	c.resourcesByShortName = resourcesByShortName
 	c.defaultTags = config.Tags
 	c.captureDir = config.CaptureDir
 	c.maxCaptureFileSize = config.MaximumCaptureFileSizeBytes
+	c.keyring = config.Keyring
 }
 
 // Close closes the capture manager.
 func (c *Capture) Close(ctx context.Context) {
+	c.stopTriggers()
 	c.flushOpenSequences()
 	c.FlushCollectors()
 	c.closeCollectors()
@@ -308,10 +337,11 @@ func (c *Capture) initializeOrUpdateCollector(
 	collection *mongo.Collection,
 ) (*collectorAndConfig, error) {
 	maxFileSizeChanged := c.maxCaptureFileSize != config.MaximumCaptureFileSizeBytes
+	encryptionKeyChanged := c.keyring.CurrentKeyID() != config.Keyring.CurrentKeyID()
 	if storedCollectorAndConfig, ok := c.collectors[md]; ok {
 		if storedCollectorAndConfig.Config.Equals(&collectorConfig) &&
 			res == storedCollectorAndConfig.Resource &&
-			!maxFileSizeChanged {
+			!maxFileSizeChanged && !encryptionKeyChanged {
 			// If the attributes have not changed, do nothing and leave the existing collector.
 			return c.collectors[md], nil
 		}
@@ -327,6 +357,14 @@ func (c *Capture) initializeOrUpdateCollector(
 		return nil, errors.Errorf("capture_buffer_size can't be less than 0, current value: %d", collectorConfig.CaptureBufferSize)
 	}
 
+	if err := validateTriggeredCapture(collectorConfig.Triggered); err != nil {
+		return nil, err
+	}
+
+	if err := validateChangeDetection(collectorConfig.ChangeDetection); err != nil {
+		return nil, err
+	}
+
 	metadataKey := generateMetadataKey(md.MethodMetadata.API.String(), md.MethodMetadata.MethodName)
 	if additionalParamKey, ok := metadataToAdditionalParamFields[metadataKey]; ok {
 		if _, ok := collectorConfig.AdditionalParams[additionalParamKey]; !ok {
@@ -335,17 +373,22 @@ func (c *Capture) initializeOrUpdateCollector(
 		}
 	}
 
-	return c.buildCollector(res, md, collectorConfig, config.MaximumCaptureFileSizeBytes, collection)
+	// any collector this one replaces was closed above, so nothing writes to its target directory
+	return c.buildCollector(res, md, collectorConfig, config.MaximumCaptureFileSizeBytes, config.Keyring, collection, true)
 }
 
 // buildCollector constructs and starts a new collector, assuming the base config was already validated.
-// The override path (SetCaptureConfigs) calls this directly.
+// The override path (SetCaptureConfigs) calls this directly. The collector's files are encrypted with keyring if it
+// has a current key. The in progress files in the target directory of the collector are repaired before it starts if
+// repairTargetDir is true, which it must only be when no other collector is writing to that directory.
 func (c *Capture) buildCollector(
 	res resource.Resource,
 	md collectorMetadata,
 	collectorConfig datamanager.DataCaptureConfig,
 	maxCaptureFileSize int64,
+	keyring *data.CaptureKeyring,
 	collection *mongo.Collection,
+	repairTargetDir bool,
 ) (*collectorAndConfig, error) {
 	interval := data.GetDurationFromHz(collectorConfig.CaptureFrequencyHz)
 	if interval <= 0 {
@@ -360,10 +403,11 @@ func (c *Capture) buildCollector(
 		return nil, err
 	}
 
-	// Get collector constructor for the component API and method.
+	// Get collector constructor for the component API and method, falling back to the generic collector which calls
+	// DoCommand or invokes the method over gRPC.
 	collectorConstructor := data.CollectorLookup(md.MethodMetadata)
 	if collectorConstructor == nil {
-		return nil, errors.Errorf("failed to find collector constructor for %s", md.MethodMetadata)
+		collectorConstructor = data.GenericCollectorConstructor(md.MethodMetadata.MethodName)
 	}
 
 	targetDir := targetDir(collectorConfig.CaptureDirectory, collectorConfig)
@@ -371,6 +415,10 @@ func (c *Capture) buildCollector(
 	if err := os.MkdirAll(targetDir, 0o700); err != nil {
 		return nil, errors.Wrapf(err, "failed to create target directory %s with 700 file permissions", targetDir)
 	}
+	if repairTargetDir {
+		// this collector hasn't started, so the in progress files in its directory were left behind by a previous run
+		c.repairInProgressFiles(targetDir, keyring)
+	}
 	// Build metadata.
 	captureMetadata, dataType := data.BuildCaptureMetadata(
 		collectorConfig.Name.API,
@@ -383,6 +431,21 @@ func (c *Capture) buildCollector(
 	// Parameters to initialize collector.
 	queueSize := defaultIfZeroVal(collectorConfig.CaptureQueueSize, defaultCaptureQueueSize)
 	bufferSize := defaultIfZeroVal(collectorConfig.CaptureBufferSize, defaultCaptureBufferSize)
+	var target data.CaptureBufferedWriter = data.NewCaptureBuffer(targetDir, captureMetadata, maxCaptureFileSize,
+		data.WithCaptureKeyring(keyring))
+	var triggered *data.TriggeredBuffer
+	if trig := collectorConfig.Triggered; trig != nil {
+		triggered = data.NewTriggeredBuffer(target, c.clk,
+			secondsToDuration(trig.PreTriggerSec),
+			secondsToDuration(trig.PostTriggerSec),
+			defaultIfZeroVal(trig.MaxBufferBytes, defaultTriggeredBufferBytes))
+		target = triggered
+	}
+	var changeDetector *data.ChangeDetector
+	if cd := collectorConfig.ChangeDetection; cd != nil {
+		changeDetector = data.NewChangeDetector(cd.DefaultTolerance, cd.Tolerances, cd.ImageHashDistance,
+			secondsToDuration(cd.MaxSuppressedSec))
+	}
 	collector, err := collectorConstructor(res, data.CollectorParams{
 		MongoCollection: collection,
 		DataType:        dataType,
@@ -392,12 +455,14 @@ func (c *Capture) buildCollector(
 		MethodName:      collectorConfig.Method,
 		Interval:        interval,
 		MethodParams:    methodParams,
-		Target:          data.NewCaptureBuffer(targetDir, captureMetadata, maxCaptureFileSize),
+		Target:          target,
 		// Set queue size to defaultCaptureQueueSize if it was not set in the config.
-		QueueSize:  queueSize,
-		BufferSize: bufferSize,
-		Logger:     c.logger,
-		Clock:      c.clk,
+		QueueSize:      queueSize,
+		BufferSize:     bufferSize,
+		Logger:         c.logger,
+		Clock:          c.clk,
+		ChangeDetector: changeDetector,
+		MethodInvoker:  c.methodInvoker,
 	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "constructor for collector %s failed with config: %s",
@@ -408,7 +473,7 @@ func (c *Capture) buildCollector(
 		md, collectorConfigDescription(collectorConfig, targetDir, maxCaptureFileSize, queueSize, bufferSize))
 	collector.Collect()
 
-	return &collectorAndConfig{res, collector, collectorConfig}, nil
+	return &collectorAndConfig{res, collector, collectorConfig, triggered, changeDetector}, nil
 }
 
 func collectorConfigDescription(
@@ -425,6 +490,20 @@ func collectorConfigDescription(
 	)
 }
 
+// SyncPriorities returns the sync priority of each configured capture method which has one, keyed by the directory
+// its capture files are written to.
+func SyncPriorities(collectorConfigsByResource CollectorConfigsByResource) map[string]int {
+	priorities := map[string]int{}
+	for _, collectorConfigs := range collectorConfigsByResource {
+		for _, collectorConfig := range collectorConfigs {
+			if collectorConfig.SyncPriority != 0 {
+				priorities[targetDir(collectorConfig.CaptureDirectory, collectorConfig)] = collectorConfig.SyncPriority
+			}
+		}
+	}
+	return priorities
+}
+
 func targetDir(captureDir string, collectorConfig datamanager.DataCaptureConfig) string {
 	return data.CaptureFilePathWithReplacedReservedChars(
 		filepath.Join(captureDir, collectorConfig.Name.API.String(),
@@ -483,6 +562,58 @@ func (c *Capture) FlushCollectors() {
 	wg.Wait()
 }
 
+// repairInProgressFiles completes the in progress capture files in captureDir, salvaging their readable records, so
+// that they can be synced. Encrypted files are decrypted and rewritten with keyring.
+func (c *Capture) repairInProgressFiles(captureDir string, keyring *data.CaptureKeyring) {
+	repairs, err := data.RepairCaptureFiles(captureDir, false, data.WithCaptureKeyring(keyring))
+	for _, repair := range repairs {
+		if repair.RepairedPath == "" {
+			c.logger.Infof("removed in progress capture file %s which had no readable readings", repair.Path)
+			continue
+		}
+		c.logger.Infof("recovered %d readings from in progress capture file %s to %s, discarding %s",
+			repair.Records, repair.Path, repair.RepairedPath, rutils.FormatBytesI64(repair.DiscardedBytes))
+	}
+	if err != nil {
+		c.logger.Warnw("failed to recover in progress capture files", "error", err)
+	}
+}
+
+// SuppressedSamples returns the number of unchanged samples suppressed by each collector configured for change
+// detection, keyed by resource name and method.
+func (c *Capture) SuppressedSamples() map[string]int64 {
+	c.collectorsMu.Lock()
+	defer c.collectorsMu.Unlock()
+	suppressed := map[string]int64{}
+	for md, cac := range c.collectors {
+		if cac.changeDetector != nil {
+			suppressed[md.ResourceName+"/"+md.MethodMetadata.MethodName] += cac.changeDetector.Suppressed()
+		}
+	}
+	return suppressed
+}
+
+func validateChangeDetection(cd *datamanager.ChangeDetectionConfig) error {
+	if cd == nil {
+		return nil
+	}
+	if cd.DefaultTolerance < 0 {
+		return errors.New("default_tolerance can't be negative")
+	}
+	for key, tolerance := range cd.Tolerances {
+		if tolerance < 0 {
+			return errors.Errorf("the tolerance of %q can't be negative", key)
+		}
+	}
+	if cd.ImageHashDistance < 0 || cd.ImageHashDistance > 64 {
+		return errors.Errorf("image_hash_distance must be between 0 and 64, current value: %d", cd.ImageHashDistance)
+	}
+	if cd.MaxSuppressedSec < 0 {
+		return errors.New("max_suppressed_sec can't be negative")
+	}
+	return nil
+}
+
 func defaultIfZeroVal[T comparable](val, defaultVal T) T {
 	var zeroVal T
 	if val == zeroVal {
@@ -115,7 +115,10 @@ func (c *Capture) updateCollectors(effectiveCollectors map[collectorMetadata]eff
 
 		// Rebuild collectors to reflect override changes.
 		c.logCaptureConfigChange(key, existing, effectiveCfg)
-		coll, err := c.buildCollector(res, metadata, effectiveCfg, c.maxCaptureFileSize, c.mongo.collection)
+		// a collector being replaced is only closed once the new one is built, and may still be writing to the
+		// same directory
+		coll, err := c.buildCollector(res, metadata, effectiveCfg, c.maxCaptureFileSize, c.keyring, c.mongo.collection,
+			existing == nil)
 		if err != nil {
 			c.logger.Errorw("failed to build collector", "error", err, "key", key)
 			continue
@@ -1,11 +1,20 @@
 package capture
 
 import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
 	"go.viam.com/test"
 
 	"go.viam.com/rdk/components/arm"
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/services/datamanager"
 )
 
@@ -30,3 +39,76 @@ func TestDefaultIfZeroVal(t *testing.T) {
 	test.That(t, defaultIfZeroVal(nonDefaultF64, defaultValF64), test.ShouldAlmostEqual, nonDefaultF64)
 	test.That(t, defaultIfZeroVal(0, defaultValF64), test.ShouldAlmostEqual, defaultValF64)
 }
+
+func TestValidateChangeDetection(t *testing.T) {
+	test.That(t, validateChangeDetection(nil), test.ShouldBeNil)
+	test.That(t, validateChangeDetection(&datamanager.ChangeDetectionConfig{
+		DefaultTolerance: 0.1,
+		Tolerances:       map[string]float64{"readings.temperature": 1},
+		MaxSuppressedSec: 60,
+	}), test.ShouldBeNil)
+	err := validateChangeDetection(&datamanager.ChangeDetectionConfig{Tolerances: map[string]float64{"readings.temperature": -1}})
+	test.That(t, err, test.ShouldBeError, `the tolerance of "readings.temperature" can't be negative`)
+	err = validateChangeDetection(&datamanager.ChangeDetectionConfig{ImageHashDistance: 65})
+	test.That(t, err, test.ShouldBeError, "image_hash_distance must be between 0 and 64, current value: 65")
+}
+
+func TestReconfigureRepairsInProgressFiles(t *testing.T) {
+	captureDir := t.TempDir()
+	dir := filepath.Join(captureDir, "rdk_component_arm", "arm1", "JointPositions")
+	test.That(t, os.MkdirAll(dir, 0o700), test.ShouldBeNil)
+	// an in progress file left behind by a machine which lost power before closing it
+	f, err := data.NewCaptureFile(dir, &v1.DataCaptureMetadata{Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.WriteNext(&v1.SensorData{Metadata: &v1.SensorMetadata{}}), test.ShouldBeNil)
+	test.That(t, f.Flush(), test.ShouldBeNil)
+	inProgress := f.GetPath()
+
+	c := New(clock.New(), logging.NewTestLogger(t))
+	defer c.Close(context.Background())
+	c.Reconfigure(context.Background(), nil, CollectorConfigsByResource{}, nil, Config{CaptureDir: captureDir})
+
+	readings, err := data.SensorDataFromCaptureFilePath(strings.TrimSuffix(inProgress, data.InProgressCaptureFileExt) +
+		data.CompletedCaptureFileExt)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readings, test.ShouldHaveLength, 1)
+	// the in progress file was moved when it was repaired, so it can't be completed
+	test.That(t, f.Close(), test.ShouldNotBeNil)
+}
+
+func TestBuildCollectorRepairsTargetDir(t *testing.T) {
+	registerFakeCollector()
+	// a collector capturing to a directory of its own, outside the capture directory of the service
+	collectorCaptureDir := t.TempDir()
+	cfg := datamanager.DataCaptureConfig{
+		Name:               fakeRes.Name(),
+		Method:             "GetReadings",
+		CaptureFrequencyHz: 1,
+		CaptureDirectory:   collectorCaptureDir,
+	}
+	dir := targetDir(collectorCaptureDir, cfg)
+	test.That(t, os.MkdirAll(dir, 0o700), test.ShouldBeNil)
+	// the file is encrypted, so it is only repaired with the configured keyring
+	key := make([]byte, data.CaptureKeySize)
+	_, err := rand.Read(key)
+	test.That(t, err, test.ShouldBeNil)
+	keyring, err := data.NewCaptureKeyring(key)
+	test.That(t, err, test.ShouldBeNil)
+	f, err := data.NewCaptureFile(dir, &v1.DataCaptureMetadata{Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR},
+		data.WithCaptureKeyring(keyring))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.WriteNext(&v1.SensorData{Metadata: &v1.SensorMetadata{}}), test.ShouldBeNil)
+	test.That(t, f.Flush(), test.ShouldBeNil)
+	inProgress := f.GetPath()
+
+	c := New(clock.New(), logging.NewTestLogger(t))
+	defer c.Close(context.Background())
+	c.Reconfigure(context.Background(), nil, CollectorConfigsByResource{fakeRes: {cfg}}, nil,
+		Config{CaptureDir: t.TempDir(), Keyring: keyring})
+
+	readings, err := data.SensorDataFromCaptureFilePath(strings.TrimSuffix(inProgress, data.InProgressCaptureFileExt)+
+		data.CompletedCaptureFileExt, data.WithCaptureKeyring(keyring))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readings, test.ShouldHaveLength, 1)
+	test.That(t, f.Close(), test.ShouldNotBeNil)
+}
@@ -1,5 +1,7 @@
 package capture
 
+import "go.viam.com/rdk/data"
+
 // MongoConfig is the optional data capture mongo config.
 type MongoConfig struct {
 	URI        string `json:"uri"`
@@ -26,4 +28,14 @@ type Config struct {
 	MaximumCaptureFileSizeBytes int64
 
 	MongoConfig *MongoConfig
+
+	// Triggers are the triggers which persist the ring buffers of triggered collectors.
+	Triggers []TriggerConfig
+
+	// MethodInvoker invokes methods over gRPC for the collectors of methods which have no collector registered.
+	MethodInvoker data.MethodInvoker
+
+	// Keyring, when non nil, encrypts the capture files collectors write with its current key, and decrypts the in
+	// progress files which are repaired.
+	Keyring *data.CaptureKeyring
 }
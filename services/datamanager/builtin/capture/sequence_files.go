@@ -40,10 +40,15 @@ func writeClosedSequence(captureDir string, closed ClosedSequence) error {
 		Resources:    toSequenceResources(closed.Resources),
 		SequenceTags: closed.SequenceTags,
 	}
-	if err := writeSequenceFile(seqFilePath(captureDir, closed.ID), sf); err != nil {
+	return completeSequenceFile(captureDir, closed.ID, sf)
+}
+
+// completeSequenceFile writes <id>.seq and removes the corresponding <id>.progseq.
+func completeSequenceFile(captureDir, id string, sf data.SequenceFile) error {
+	if err := writeSequenceFile(seqFilePath(captureDir, id), sf); err != nil {
 		return err
 	}
-	if err := os.Remove(progSeqFilePath(captureDir, closed.ID)); err != nil && !os.IsNotExist(err) {
+	if err := os.Remove(progSeqFilePath(captureDir, id)); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove .progseq after writing .seq: %w", err)
 	}
 	return nil
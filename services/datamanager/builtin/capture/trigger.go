@@ -0,0 +1,455 @@
+package capture
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	goutils "go.viam.com/utils"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/datamanager"
+	"go.viam.com/rdk/services/vision"
+)
+
+// Trigger sources, as recorded in sequence files.
+const (
+	TriggerSourceSensor    = "sensor"
+	TriggerSourceVision    = "vision"
+	TriggerSourceDoCommand = "do_command"
+)
+
+const (
+	defaultTriggerCheckFrequencyHz = 2.
+	// defaultTriggeredBufferBytes bounds the ring buffer of a triggered collector which doesn't set max_buffer_bytes.
+	defaultTriggeredBufferBytes = int64(32 * 1024 * 1024)
+)
+
+// TriggerConfig describes a trigger which, when fired, persists the ring buffers of the collectors whose triggered
+// config names it. Every trigger can be fired through the data manager's DoCommand; it can also be fired by a sensor
+// reading or a vision detection.
+type TriggerConfig struct {
+	Name string `json:"name"`
+	// Sensor, if set, fires the trigger when a reading of a sensor matches.
+	Sensor *SensorTriggerConfig `json:"sensor,omitempty"`
+	// Vision, if set, fires the trigger when a vision service detects something in a camera's images.
+	Vision *VisionTriggerConfig `json:"vision,omitempty"`
+	// CheckFrequencyHz is how often the sensor or vision service is checked. It defaults to 2.
+	CheckFrequencyHz float64 `json:"check_frequency_hz,omitempty"`
+}
+
+// SensorTriggerConfig fires a trigger when the reading under Key of a sensor passes every given comparison. The
+// trigger fires when the reading starts matching, not again until it has stopped matching.
+type SensorTriggerConfig struct {
+	Name   string      `json:"name"`
+	Key    string      `json:"key"`
+	Above  *float64    `json:"above,omitempty"`
+	Below  *float64    `json:"below,omitempty"`
+	Equals interface{} `json:"equals,omitempty"`
+}
+
+// VisionTriggerConfig fires a trigger when a vision service detects one of Labels, or anything if Labels is empty,
+// in the images of Camera with at least MinConfidence. The trigger fires when such a detection appears, not again
+// until there have been none.
+type VisionTriggerConfig struct {
+	Name          string   `json:"name"`
+	Camera        string   `json:"camera"`
+	Labels        []string `json:"labels,omitempty"`
+	MinConfidence float64  `json:"min_confidence,omitempty"`
+}
+
+// Validate returns an error if the trigger is incomplete.
+func (t *TriggerConfig) Validate() error {
+	if t.Name == "" {
+		return errors.New("every trigger needs a name")
+	}
+	if t.Sensor != nil && t.Vision != nil {
+		return errors.Errorf("trigger %q can have a sensor or a vision service, not both", t.Name)
+	}
+	if t.CheckFrequencyHz < 0 {
+		return errors.Errorf("trigger %q check_frequency_hz can't be negative", t.Name)
+	}
+	if s := t.Sensor; s != nil {
+		if s.Name == "" || s.Key == "" {
+			return errors.Errorf("the sensor of trigger %q needs a name and a key", t.Name)
+		}
+		if s.Above == nil && s.Below == nil && s.Equals == nil {
+			return errors.Errorf("the sensor of trigger %q needs at least one of above, below or equals", t.Name)
+		}
+	}
+	if v := t.Vision; v != nil && (v.Name == "" || v.Camera == "") {
+		return errors.Errorf("the vision service of trigger %q needs a name and a camera", t.Name)
+	}
+	return nil
+}
+
+func validateTriggeredCapture(t *datamanager.TriggeredCaptureConfig) error {
+	if t == nil {
+		return nil
+	}
+	if t.Trigger == "" {
+		return errors.New("triggered capture needs a trigger")
+	}
+	if t.PreTriggerSec < 0 || t.PostTriggerSec < 0 {
+		return errors.New("pre_trigger_sec and post_trigger_sec can't be negative")
+	}
+	if t.MaxBufferBytes < 0 {
+		return errors.Errorf("max_buffer_bytes can't be less than 0, current value: %d", t.MaxBufferBytes)
+	}
+	return nil
+}
+
+func secondsToDuration(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second))
+}
+
+// TriggeredSequence describes the sequence written for a fired trigger.
+type TriggeredSequence struct {
+	ID        string
+	StartAt   time.Time
+	EndAt     time.Time
+	Resources []datamanager.ResourceMethod
+}
+
+// triggeredSequence is the sequence of a trigger whose window hasn't ended.
+type triggeredSequence struct {
+	TriggeredSequence
+	tags    []string
+	trigger data.SequenceTrigger
+	timer   *clock.Timer
+}
+
+func (s *triggeredSequence) file() data.SequenceFile {
+	trigger := s.trigger
+	return data.SequenceFile{
+		StartTime:    s.StartAt,
+		EndTime:      s.EndAt,
+		Resources:    toSequenceResources(s.Resources),
+		SequenceTags: s.tags,
+		Trigger:      &trigger,
+	}
+}
+
+// Trigger fires the named trigger, writing the ring buffers of the collectors which use it and keeping their
+// readings until their post trigger windows end. A sequence is written covering the window, recording the trigger.
+// Firing a trigger again before the window ends extends the window and its sequence.
+func (c *Capture) Trigger(name, source, reason string) (TriggeredSequence, error) {
+	now := c.clk.Now()
+	var start, end time.Time
+	var resources []datamanager.ResourceMethod
+	var errs []error
+	c.collectorsMu.Lock()
+	for md, cac := range c.collectors {
+		if cac.triggered == nil || cac.Config.Triggered.Trigger != name {
+			continue
+		}
+		s, e, err := cac.triggered.Trigger(now)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to write the ring buffer of %s", md))
+			continue
+		}
+		if start.IsZero() || s.Before(start) {
+			start = s
+		}
+		if e.After(end) {
+			end = e
+		}
+		resources = append(resources, datamanager.ResourceMethod{
+			ResourceName: cac.Config.Name.ShortName(),
+			MethodName:   cac.Config.Method,
+		})
+	}
+	c.collectorsMu.Unlock()
+	if len(resources) == 0 {
+		if len(errs) > 0 {
+			return TriggeredSequence{}, errs[0]
+		}
+		return TriggeredSequence{}, errors.Errorf("no collectors use trigger %q", name)
+	}
+	for _, err := range errs {
+		c.logger.Warnw("failed to persist triggered capture", "trigger", name, "error", err)
+	}
+	sortResources(resources)
+
+	c.triggersMu.Lock()
+	defer c.triggersMu.Unlock()
+	if c.triggeredSequences == nil {
+		c.triggeredSequences = map[string]*triggeredSequence{}
+	}
+	seq, ok := c.triggeredSequences[name]
+	if ok {
+		if start.Before(seq.StartAt) {
+			seq.StartAt = start
+		}
+		if end.After(seq.EndAt) {
+			seq.EndAt = end
+		}
+		for _, r := range resources {
+			if !slices.Contains(seq.Resources, r) {
+				seq.Resources = append(seq.Resources, r)
+			}
+		}
+		sortResources(seq.Resources)
+		seq.trigger.Count++
+		seq.timer.Reset(seq.EndAt.Sub(now))
+	} else {
+		seq = &triggeredSequence{
+			TriggeredSequence: TriggeredSequence{ID: uuid.NewString(), StartAt: start, EndAt: end, Resources: resources},
+			tags:              slices.Clone(c.defaultTags),
+			trigger:           data.SequenceTrigger{Name: name, Source: source, Reason: reason, Time: now, Count: 1},
+		}
+		c.triggeredSequences[name] = seq
+		seq.timer = c.clk.AfterFunc(end.Sub(now), func() { c.endTriggeredSequence(name, seq) })
+	}
+	c.logger.Infow("capture triggered",
+		"trigger", name, "source", source, "reason", reason, "start_at", seq.StartAt, "end_at", seq.EndAt, "resources", seq.Resources)
+	if err := writeSequenceFile(progSeqFilePath(c.captureDir, seq.ID), seq.file()); err != nil {
+		c.logger.Errorw("failed to persist triggered sequence", "error", err, "id", seq.ID, "trigger", name)
+	}
+	result := seq.TriggeredSequence
+	result.Resources = slices.Clone(seq.Resources)
+	return result, nil
+}
+
+// endTriggeredSequence completes the sequence of a trigger once its window has ended.
+func (c *Capture) endTriggeredSequence(name string, seq *triggeredSequence) {
+	c.triggersMu.Lock()
+	defer c.triggersMu.Unlock()
+	// the trigger may have fired again and extended the window since this timer fired
+	if c.triggeredSequences[name] != seq || c.clk.Now().Before(seq.EndAt) {
+		return
+	}
+	if err := completeSequenceFile(c.captureDir, seq.ID, seq.file()); err != nil {
+		c.logger.Errorw("failed to persist triggered sequence", "error", err, "id", seq.ID, "trigger", name)
+	}
+	delete(c.triggeredSequences, name)
+}
+
+func sortResources(resources []datamanager.ResourceMethod) {
+	slices.SortFunc(resources, func(a, b datamanager.ResourceMethod) int {
+		if c := cmp.Compare(a.ResourceName, b.ResourceName); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.MethodName, b.MethodName)
+	})
+}
+
+// triggerSource fires a trigger from the state of another resource.
+type triggerSource interface {
+	// check returns whether the trigger condition holds, and if so why.
+	check(ctx context.Context) (bool, string, error)
+}
+
+type sensorTrigger struct {
+	sensor resource.Sensor
+	conf   SensorTriggerConfig
+}
+
+func (t *sensorTrigger) check(ctx context.Context) (bool, string, error) {
+	readings, err := t.sensor.Readings(ctx, data.FromDMExtraMap)
+	if err != nil {
+		return false, "", err
+	}
+	value, ok := readings[t.conf.Key]
+	if !ok || !t.conf.matches(value) {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("%s reading %s is %v", t.conf.Name, t.conf.Key, value), nil
+}
+
+// matches returns whether a reading passes every comparison.
+func (s *SensorTriggerConfig) matches(value interface{}) bool {
+	f, numeric := toFloat(value)
+	if s.Above != nil && (!numeric || f <= *s.Above) {
+		return false
+	}
+	if s.Below != nil && (!numeric || f >= *s.Below) {
+		return false
+	}
+	if s.Equals != nil {
+		// config values are decoded from JSON, so numbers are compared by value rather than by type
+		if want, ok := toFloat(s.Equals); ok && numeric {
+			return f == want
+		}
+		return reflect.DeepEqual(value, s.Equals)
+	}
+	return true
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), !math.IsNaN(v.Float())
+	default:
+		return 0, false
+	}
+}
+
+type visionTrigger struct {
+	service vision.Service
+	conf    VisionTriggerConfig
+}
+
+func (t *visionTrigger) check(ctx context.Context) (bool, string, error) {
+	detections, err := t.service.DetectionsFromCamera(ctx, t.conf.Camera, nil)
+	if err != nil {
+		return false, "", err
+	}
+	for _, d := range detections {
+		if d.Score() < t.conf.MinConfidence {
+			continue
+		}
+		if len(t.conf.Labels) > 0 && !slices.Contains(t.conf.Labels, d.Label()) {
+			continue
+		}
+		return true, fmt.Sprintf("%s detected %q in %s with confidence %.2f", t.conf.Name, d.Label(), t.conf.Camera, d.Score()), nil
+	}
+	return false, "", nil
+}
+
+// newTriggerSource returns the source of a trigger, or nil for a trigger which is only fired through DoCommand.
+func newTriggerSource(conf TriggerConfig, resourcesByShortName map[string]resource.Resource) (triggerSource, error) {
+	switch {
+	case conf.Sensor != nil:
+		res, ok := resourcesByShortName[conf.Sensor.Name]
+		if !ok {
+			return nil, errors.Errorf("sensor %q not found", conf.Sensor.Name)
+		}
+		s, ok := res.(resource.Sensor)
+		if !ok {
+			return nil, errors.Errorf("%q doesn't have readings", conf.Sensor.Name)
+		}
+		return &sensorTrigger{sensor: s, conf: *conf.Sensor}, nil
+	case conf.Vision != nil:
+		res, ok := resourcesByShortName[conf.Vision.Name]
+		if !ok {
+			return nil, errors.Errorf("vision service %q not found", conf.Vision.Name)
+		}
+		svc, ok := res.(vision.Service)
+		if !ok {
+			return nil, errors.Errorf("%q isn't a vision service", conf.Vision.Name)
+		}
+		return &visionTrigger{service: svc, conf: *conf.Vision}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// reconfigureTriggers restarts the workers which check trigger sources when the triggers or their resources have
+// changed.
+func (c *Capture) reconfigureTriggers(configs []TriggerConfig, resourcesByShortName map[string]resource.Resource) {
+	sourceResources := make([]resource.Resource, len(configs))
+	for i, conf := range configs {
+		switch {
+		case conf.Sensor != nil:
+			sourceResources[i] = resourcesByShortName[conf.Sensor.Name]
+		case conf.Vision != nil:
+			sourceResources[i] = resourcesByShortName[conf.Vision.Name]
+		}
+	}
+	c.triggersMu.Lock()
+	unchanged := c.triggerWorkers != nil && reflect.DeepEqual(configs, c.triggerConfigs) &&
+		slices.Equal(sourceResources, c.triggerResources)
+	c.triggersMu.Unlock()
+	if unchanged {
+		return
+	}
+	c.stopTriggerWorkers()
+
+	var workers []func(context.Context)
+	for _, conf := range configs {
+		source, err := newTriggerSource(conf, resourcesByShortName)
+		if err != nil {
+			c.logger.Errorw("trigger can only be fired with DoCommand until its source is fixed", "trigger", conf.Name, "error", err)
+			continue
+		}
+		if source == nil {
+			continue
+		}
+		interval := secondsToDuration(1 / defaultIfZeroVal(conf.CheckFrequencyHz, defaultTriggerCheckFrequencyHz))
+		name, sourceType := conf.Name, TriggerSourceSensor
+		if conf.Vision != nil {
+			sourceType = TriggerSourceVision
+		}
+		// the ticker is made here rather than by the worker so that no tick is missed before it starts
+		ticker := c.clk.Ticker(interval)
+		workers = append(workers, func(ctx context.Context) { c.runTrigger(ctx, name, sourceType, source, ticker) })
+	}
+	c.triggersMu.Lock()
+	defer c.triggersMu.Unlock()
+	c.triggerConfigs = configs
+	c.triggerResources = sourceResources
+	c.triggerWorkers = goutils.NewBackgroundStoppableWorkers(workers...)
+}
+
+// runTrigger checks a trigger source every tick, firing the trigger when its condition starts to hold.
+func (c *Capture) runTrigger(ctx context.Context, name, sourceType string, source triggerSource, ticker *clock.Ticker) {
+	defer ticker.Stop()
+	var holding bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		holds, reason, err := source.check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.logger.Debugw("failed to check trigger", "trigger", name, "error", err)
+			continue
+		}
+		if holds && !holding {
+			if _, err := c.Trigger(name, sourceType, reason); err != nil {
+				c.logger.Warnw("failed to fire trigger", "trigger", name, "error", err)
+			}
+		}
+		holding = holds
+	}
+}
+
+func (c *Capture) stopTriggerWorkers() {
+	c.triggersMu.Lock()
+	workers := c.triggerWorkers
+	c.triggerWorkers = nil
+	c.triggerConfigs = nil
+	c.triggerResources = nil
+	c.triggersMu.Unlock()
+	// workers are stopped without holding triggersMu, as they take it when firing a trigger
+	if workers != nil {
+		workers.Stop()
+	}
+}
+
+// stopTriggers stops checking trigger sources and completes the sequences of triggers whose windows haven't ended,
+// ending them now. Called from Capture.Close.
+func (c *Capture) stopTriggers() {
+	c.stopTriggerWorkers()
+	c.triggersMu.Lock()
+	defer c.triggersMu.Unlock()
+	now := c.clk.Now()
+	for name, seq := range c.triggeredSequences {
+		seq.timer.Stop()
+		if now.Before(seq.EndAt) {
+			seq.EndAt = now
+		}
+		if err := completeSequenceFile(c.captureDir, seq.ID, seq.file()); err != nil {
+			c.logger.Errorw("failed to persist triggered sequence", "error", err, "id", seq.ID, "trigger", name)
+		}
+		delete(c.triggeredSequences, name)
+	}
+}
@@ -0,0 +1,263 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"go.viam.com/utils/testutils"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/datamanager"
+	"go.viam.com/rdk/services/vision"
+	"go.viam.com/rdk/vision/objectdetection"
+)
+
+const triggeredMethod = "GetTriggeredReadings"
+
+var (
+	registerTriggeredCollectorOnce sync.Once
+	triggeredTargetMu              sync.Mutex
+	triggeredTarget                data.CaptureBufferedWriter
+)
+
+func triggeredCollectorTarget() data.CaptureBufferedWriter {
+	triggeredTargetMu.Lock()
+	defer triggeredTargetMu.Unlock()
+	return triggeredTarget
+}
+
+// newTriggeredCapture returns a Capture with one collector on fake-1 which keeps 5s before and 2s after trigger "bump".
+func newTriggeredCapture(t *testing.T, triggers []TriggerConfig, resourcesByShortName map[string]resource.Resource) (*Capture, *clock.Mock) {
+	t.Helper()
+	registerTriggeredCollectorOnce.Do(func() {
+		data.RegisterCollector(
+			data.MethodMetadata{API: fakeAPI, MethodName: triggeredMethod},
+			func(_ interface{}, params data.CollectorParams) (data.Collector, error) {
+				triggeredTargetMu.Lock()
+				defer triggeredTargetMu.Unlock()
+				triggeredTarget = params.Target
+				return &mockCollector{}, nil
+			},
+		)
+	})
+	c, clk := newCaptureForTest(t)
+	captureDir := c.captureDir
+	cfg := datamanager.DataCaptureConfig{
+		Name:               resource.NewName(fakeAPI, "fake-1"),
+		Method:             triggeredMethod,
+		CaptureFrequencyHz: 1,
+		CaptureDirectory:   captureDir,
+		Triggered:          &datamanager.TriggeredCaptureConfig{Trigger: "bump", PreTriggerSec: 5, PostTriggerSec: 2},
+	}
+	c.Reconfigure(context.Background(), nil,
+		CollectorConfigsByResource{fakeRes: []datamanager.DataCaptureConfig{cfg}},
+		resourcesByShortName,
+		Config{CaptureDir: captureDir, MaximumCaptureFileSizeBytes: 256 * 1024, Tags: []string{"field"}, Triggers: triggers},
+	)
+	t.Cleanup(func() { c.Close(context.Background()) })
+	return c, clk
+}
+
+func writeReading(t *testing.T) {
+	t.Helper()
+	reading := &v1.SensorData{Metadata: &v1.SensorMetadata{}, Data: &v1.SensorData_Struct{Struct: &structpb.Struct{}}}
+	test.That(t, triggeredCollectorTarget().WriteTabular(reading), test.ShouldBeNil)
+}
+
+func progSeqFiles(t *testing.T, c *Capture) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(c.captureDir, data.SequencesDir, "*"+data.InProgressSequenceFileExt))
+	test.That(t, err, test.ShouldBeNil)
+	return matches
+}
+
+func TestTrigger(t *testing.T) {
+	c, clk := newTriggeredCapture(t, []TriggerConfig{{Name: "bump"}}, nil)
+	t0 := clk.Now()
+
+	for range 3 {
+		writeReading(t)
+		clk.Add(time.Second)
+	}
+	// nothing is written until the trigger fires
+	entries, err := os.ReadDir(triggeredCollectorTarget().Path())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, entries, test.ShouldBeEmpty)
+
+	_, err = c.Trigger("missing", TriggerSourceDoCommand, "")
+	test.That(t, err, test.ShouldBeError, `no collectors use trigger "missing"`)
+
+	seq, err := c.Trigger("bump", TriggerSourceDoCommand, "operator saw a stall")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, seq.StartAt, test.ShouldEqual, t0.Add(-2*time.Second))
+	test.That(t, seq.EndAt, test.ShouldEqual, t0.Add(5*time.Second))
+	test.That(t, seq.Resources, test.ShouldResemble, []datamanager.ResourceMethod{{ResourceName: "fake-1", MethodName: triggeredMethod}})
+	entries, err = os.ReadDir(triggeredCollectorTarget().Path())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, entries, test.ShouldHaveLength, 1)
+	test.That(t, progSeqFiles(t, c), test.ShouldHaveLength, 1)
+
+	// firing again within the window extends it
+	clk.Add(time.Second)
+	again, err := c.Trigger("bump", TriggerSourceDoCommand, "")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, again.ID, test.ShouldEqual, seq.ID)
+	test.That(t, again.EndAt, test.ShouldEqual, t0.Add(6*time.Second))
+	clk.Add(time.Second)
+	test.That(t, readSeqFiles(t, c), test.ShouldBeEmpty)
+
+	clk.Add(time.Second)
+	files := readSeqFiles(t, c)
+	test.That(t, files, test.ShouldHaveLength, 1)
+	test.That(t, progSeqFiles(t, c), test.ShouldBeEmpty)
+	test.That(t, files[0].StartTime, test.ShouldEqual, t0.Add(-2*time.Second))
+	test.That(t, files[0].EndTime, test.ShouldEqual, t0.Add(6*time.Second))
+	test.That(t, files[0].SequenceTags, test.ShouldResemble, []string{"field"})
+	test.That(t, files[0].Trigger, test.ShouldResemble, &data.SequenceTrigger{
+		Name:   "bump",
+		Source: TriggerSourceDoCommand,
+		Reason: "operator saw a stall",
+		Time:   t0.Add(3 * time.Second),
+		Count:  2,
+	})
+}
+
+func TestTriggerClose(t *testing.T) {
+	c, clk := newTriggeredCapture(t, nil, nil)
+	t0 := clk.Now()
+	_, err := c.Trigger("bump", TriggerSourceDoCommand, "")
+	test.That(t, err, test.ShouldBeNil)
+	clk.Add(time.Second)
+	c.Close(context.Background())
+
+	// closing ends the sequence early
+	files := readSeqFiles(t, c)
+	test.That(t, files, test.ShouldHaveLength, 1)
+	test.That(t, files[0].EndTime, test.ShouldEqual, t0.Add(time.Second))
+	test.That(t, progSeqFiles(t, c), test.ShouldBeEmpty)
+}
+
+// fakeSensor is a fakeResource with readings. inject.Sensor isn't used because importing inject registers the
+// collectors of every component, which conflict with registerFakeCollector.
+type fakeSensor struct {
+	*fakeResource
+	readings func() map[string]interface{}
+}
+
+func (f *fakeSensor) Readings(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+	return f.readings(), nil
+}
+
+func TestSensorTrigger(t *testing.T) {
+	var value atomic.Int64
+	var checks atomic.Int64
+	s := &fakeSensor{fakeResource: newFakeRes("imu"), readings: func() map[string]interface{} {
+		checks.Add(1)
+		return map[string]interface{}{"g": int(value.Load())}
+	}}
+	above := 2.
+	c, clk := newTriggeredCapture(t,
+		[]TriggerConfig{{Name: "bump", Sensor: &SensorTriggerConfig{Name: "imu", Key: "g", Above: &above}, CheckFrequencyHz: 10}},
+		map[string]resource.Resource{"imu": s})
+
+	tick := func() {
+		before := checks.Load()
+		clk.Add(100 * time.Millisecond)
+		testutils.WaitForAssertion(t, func(tb testing.TB) {
+			tb.Helper()
+			test.That(tb, checks.Load(), test.ShouldBeGreaterThan, before)
+		})
+	}
+	tick()
+	test.That(t, progSeqFiles(t, c), test.ShouldBeEmpty)
+
+	// the trigger fires once when the reading goes above 2, not again while it stays there
+	value.Store(3)
+	tick()
+	tick()
+	tick()
+	c.triggersMu.Lock()
+	seq := c.triggeredSequences["bump"]
+	test.That(t, seq, test.ShouldNotBeNil)
+	test.That(t, seq.trigger.Count, test.ShouldEqual, 1)
+	test.That(t, seq.trigger.Source, test.ShouldEqual, TriggerSourceSensor)
+	test.That(t, seq.trigger.Reason, test.ShouldEqual, "imu reading g is 3")
+	c.triggersMu.Unlock()
+
+	// reconfiguring with the same triggers keeps checking without firing again
+	c.reconfigureTriggers(c.triggerConfigs, map[string]resource.Resource{"imu": s})
+	tick()
+	c.triggersMu.Lock()
+	test.That(t, c.triggeredSequences["bump"].trigger.Count, test.ShouldEqual, 1)
+	c.triggersMu.Unlock()
+}
+
+func TestSensorTriggerMatches(t *testing.T) {
+	above, below := 1., 5.
+	between := SensorTriggerConfig{Above: &above, Below: &below}
+	test.That(t, between.matches(3), test.ShouldBeTrue)
+	test.That(t, between.matches(float32(4.5)), test.ShouldBeTrue)
+	test.That(t, between.matches(5), test.ShouldBeFalse)
+	test.That(t, between.matches("3"), test.ShouldBeFalse)
+
+	// equals is decoded from JSON, so 2 is a float64
+	equals := SensorTriggerConfig{Equals: 2.}
+	test.That(t, equals.matches(uint8(2)), test.ShouldBeTrue)
+	test.That(t, equals.matches(3), test.ShouldBeFalse)
+	equals = SensorTriggerConfig{Equals: "open"}
+	test.That(t, equals.matches("open"), test.ShouldBeTrue)
+	test.That(t, equals.matches("closed"), test.ShouldBeFalse)
+}
+
+type fakeDetector struct {
+	vision.Service
+	detections []objectdetection.Detection
+}
+
+func (f *fakeDetector) DetectionsFromCamera(
+	ctx context.Context, cameraName string, extra map[string]interface{},
+) ([]objectdetection.Detection, error) {
+	return f.detections, nil
+}
+
+func TestVisionTrigger(t *testing.T) {
+	box := image.Rect(0, 0, 10, 10)
+	detector := &fakeDetector{detections: []objectdetection.Detection{
+		objectdetection.NewDetectionWithoutImgBounds(box, 0.9, "cat"),
+		objectdetection.NewDetectionWithoutImgBounds(box, 0.4, "person"),
+	}}
+	trigger := &visionTrigger{service: detector, conf: VisionTriggerConfig{
+		Name: "detector", Camera: "cam", Labels: []string{"person"}, MinConfidence: 0.5,
+	}}
+	holds, _, err := trigger.check(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, holds, test.ShouldBeFalse)
+
+	detector.detections = append(detector.detections, objectdetection.NewDetectionWithoutImgBounds(box, 0.8, "person"))
+	holds, reason, err := trigger.check(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, holds, test.ShouldBeTrue)
+	test.That(t, reason, test.ShouldEqual, `detector detected "person" in cam with confidence 0.80`)
+}
+
+func TestTriggerConfigValidate(t *testing.T) {
+	test.That(t, (&TriggerConfig{Name: "manual"}).Validate(), test.ShouldBeNil)
+	err := (&TriggerConfig{Name: "both", Sensor: &SensorTriggerConfig{}, Vision: &VisionTriggerConfig{}}).Validate()
+	test.That(t, err, test.ShouldBeError, `trigger "both" can have a sensor or a vision service, not both`)
+	err = (&TriggerConfig{Name: "person", Vision: &VisionTriggerConfig{Name: "detector"}}).Validate()
+	test.That(t, err, test.ShouldBeError, `the vision service of trigger "person" needs a name and a camera`)
+
+	err = validateTriggeredCapture(&datamanager.TriggeredCaptureConfig{Trigger: "bump", PreTriggerSec: -1})
+	test.That(t, err, test.ShouldBeError, "pre_trigger_sec and post_trigger_sec can't be negative")
+}
@@ -2,15 +2,18 @@ package builtin
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
 	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/internal/cloud"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/robot/framesystem"
+	"go.viam.com/rdk/robot/web"
 	"go.viam.com/rdk/services/datamanager/builtin/capture"
 	"go.viam.com/rdk/services/datamanager/builtin/shared"
 	datasync "go.viam.com/rdk/services/datamanager/builtin/sync"
@@ -51,6 +54,65 @@ type CaptureControlSensorConfig struct {
 	Key string `json:"key"`
 }
 
+// EncryptionKeyConfig is where a capture encryption key is read from: a file, or an environment variable, holding a
+// 32 byte key encoded as hex or base64.
+type EncryptionKeyConfig struct {
+	File string `json:"file,omitempty"`
+	Env  string `json:"env,omitempty"`
+}
+
+func (c EncryptionKeyConfig) validate(path string) error {
+	if (c.File == "") == (c.Env == "") {
+		return fmt.Errorf("%s needs exactly one of file or env", path)
+	}
+	return nil
+}
+
+// EncryptionConfig describes the keys captured data is encrypted at rest with. New capture files are encrypted with
+// Key, and files encrypted with PreviousKeys are still decrypted, so that keys can be rotated by moving the old key
+// to PreviousKeys until the files encrypted with it are synced or rewrapped. If Key is empty new files aren't
+// encrypted.
+type EncryptionConfig struct {
+	Key          EncryptionKeyConfig   `json:"key"`
+	PreviousKeys []EncryptionKeyConfig `json:"previous_keys,omitempty"`
+}
+
+// Validate returns an error if a key doesn't say where it is read from.
+func (c *EncryptionConfig) Validate() error {
+	if c.Key != (EncryptionKeyConfig{}) {
+		if err := c.Key.validate("encryption.key"); err != nil {
+			return err
+		}
+	}
+	for i, key := range c.PreviousKeys {
+		if err := key.validate(fmt.Sprintf("encryption.previous_keys.%d", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyring loads the keys of c.
+func (c *EncryptionConfig) keyring() (*data.CaptureKeyring, error) {
+	var current []byte
+	if c.Key != (EncryptionKeyConfig{}) {
+		key, err := data.LoadCaptureKey(c.Key.File, c.Key.Env)
+		if err != nil {
+			return nil, err
+		}
+		current = key
+	}
+	previous := make([][]byte, 0, len(c.PreviousKeys))
+	for _, keyConfig := range c.PreviousKeys {
+		key, err := data.LoadCaptureKey(keyConfig.File, keyConfig.Env)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+	return data.NewCaptureKeyring(current, previous...)
+}
+
 // Config describes how to configure the service.
 // See sync.Config and capture.Config for docs on what each field does
 // to both sync & capture respectively.
@@ -73,9 +135,28 @@ type Config struct {
 	ScheduledSyncDisabled  bool     `json:"sync_disabled"`
 	SelectiveSyncerName    string   `json:"selective_syncer_name"`
 	SyncIntervalMins       float64  `json:"sync_interval_mins"`
+	// SyncDestination when set uploads files to an S3-compatible bucket, a local directory
+	// or an HTTP endpoint instead of the Viam cloud.
+	SyncDestination *datasync.DestinationConfig `json:"sync_destination,omitempty"`
+	// MaximumSyncBytesPerSec when positive caps the upload rate of sync, e.g. on a metered connection.
+	MaximumSyncBytesPerSec int64 `json:"maximum_sync_bytes_per_sec"`
+	// SyncWindows when set limits scheduled sync to those times of day.
+	SyncWindows []datasync.SyncWindow `json:"sync_windows,omitempty"`
+	// DeferBinarySyncUntilWiFi when true only syncs binary data while on Wi-Fi.
+	DeferBinarySyncUntilWiFi bool `json:"defer_binary_sync_until_wifi"`
 	// CaptureControlSensor when set specifies a sensor to poll for dynamic
 	// capture configurations.
 	CaptureControlSensor *CaptureControlSensorConfig `json:"capture_control_sensor,omitempty"`
+	// Triggers are fired by a sensor reading, a vision detection or DoCommand, and persist the
+	// ring buffers of the capture methods configured as triggered.
+	Triggers []capture.TriggerConfig `json:"triggers,omitempty"`
+	// Encryption when set encrypts captured data at rest. Files are decrypted when they are synced to the cloud, but
+	// are copied to a SyncDestination as they are. Keys removed from it are kept to decrypt the files still encrypted
+	// with them until those are synced.
+	Encryption *EncryptionConfig `json:"encryption,omitempty"`
+	// Retention when set deletes captured data once it is older than the rules it matches allow, whether or not it
+	// has been synced.
+	Retention *datasync.RetentionConfig `json:"retention,omitempty"`
 }
 
 // Validate returns components which will be depended upon weakly due to the above matcher.
@@ -101,7 +182,41 @@ func (c *Config) Validate(path string) ([]string, []string, error) {
 	if c.CaptureDirDeletionThreshold < 0 {
 		return nil, nil, errors.New("capture_dir_deletion_threshold can't be negative")
 	}
-	return []string{cloud.InternalServiceName.String()}, []string{framesystem.InternalServiceName.String()}, nil
+	if c.MaximumSyncBytesPerSec < 0 {
+		return nil, nil, errors.New("maximum_sync_bytes_per_sec can't be negative")
+	}
+	for _, window := range c.SyncWindows {
+		if err := window.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.SyncDestination != nil {
+		if err := c.SyncDestination.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.Encryption != nil {
+		if err := c.Encryption.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.Retention != nil {
+		if err := c.Retention.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	triggerNames := map[string]bool{}
+	for _, trigger := range c.Triggers {
+		if err := trigger.Validate(); err != nil {
+			return nil, nil, err
+		}
+		if triggerNames[trigger.Name] {
+			return nil, nil, fmt.Errorf("trigger %q is configured more than once", trigger.Name)
+		}
+		triggerNames[trigger.Name] = true
+	}
+	return []string{cloud.InternalServiceName.String()},
+		[]string{framesystem.InternalServiceName.String(), web.InternalServiceName.String()}, nil
 }
 
 func (c *Config) getCaptureDir(logger logging.Logger) string {
@@ -133,6 +248,7 @@ func (c *Config) captureConfig(logger logging.Logger) capture.Config {
 		Tags:                        c.Tags,
 		MaximumCaptureFileSizeBytes: maximumCaptureFileSizeBytes,
 		MongoConfig:                 c.MongoCaptureConfig,
+		Triggers:                    c.Triggers,
 	}
 }
 
@@ -189,5 +305,10 @@ func (c *Config) syncConfig(syncSensor sensor.Sensor, syncSensorEnabled bool, lo
 		SyncIntervalMins:            syncIntervalMins,
 		SelectiveSyncSensor:         syncSensor,
 		SelectiveSyncSensorEnabled:  syncSensorEnabled,
+		Destination:                 c.SyncDestination,
+		MaximumSyncBytesPerSec:      c.MaximumSyncBytesPerSec,
+		SyncWindows:                 c.SyncWindows,
+		DeferBinarySyncUntilWiFi:    c.DeferBinarySyncUntilWiFi,
+		Retention:                   c.Retention,
 	}
 }
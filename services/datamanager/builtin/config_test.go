@@ -5,10 +5,12 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"go.viam.com/test"
 
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/internal/cloud"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/services/datamanager/builtin/capture"
@@ -35,6 +37,7 @@ var fullConfig = &Config{
 
 func TestConfig(t *testing.T) {
 	logger := logging.NewTestLogger(t)
+	above := 2.
 	t.Run("Validate ", func(t *testing.T) {
 		type testCase struct {
 			name   string
@@ -84,6 +87,59 @@ func TestConfig(t *testing.T) {
 				config: Config{CaptureDirDeletionThreshold: -1},
 				err:    errors.New("capture_dir_deletion_threshold can't be negative"),
 			},
+			{
+				name:   "returns an error if MaximumSyncBytesPerSec is negative",
+				config: Config{MaximumSyncBytesPerSec: -1},
+				err:    errors.New("maximum_sync_bytes_per_sec can't be negative"),
+			},
+			{
+				name:   "returns an error if a sync window isn't a time of day",
+				config: Config{SyncWindows: []sync.SyncWindow{{Start: "22:00", End: "6am"}}},
+				err:    errors.New(`sync window time "6am" must be formatted as HH:MM`),
+			},
+			{
+				name: "returns an error if a trigger is configured twice",
+				config: Config{Triggers: []capture.TriggerConfig{
+					{Name: "bump"},
+					{Name: "bump", Sensor: &capture.SensorTriggerConfig{Name: "imu", Key: "g", Above: &above}},
+				}},
+				err: errors.New(`trigger "bump" is configured more than once`),
+			},
+			{
+				name: "returns an error if a sensor trigger has nothing to compare",
+				config: Config{Triggers: []capture.TriggerConfig{
+					{Name: "bump", Sensor: &capture.SensorTriggerConfig{Name: "imu", Key: "g"}},
+				}},
+				err: errors.New(`the sensor of trigger "bump" needs at least one of above, below or equals`),
+			},
+			{
+				name:   "returns an error if an encryption key has no source",
+				config: Config{Encryption: &EncryptionConfig{PreviousKeys: []EncryptionKeyConfig{{}}}},
+				err:    errors.New("encryption.previous_keys.0 needs exactly one of file or env"),
+			},
+			{
+				name:   "returns an error if an encryption key has two sources",
+				config: Config{Encryption: &EncryptionConfig{Key: EncryptionKeyConfig{File: "/etc/capture.key", Env: "CAPTURE_KEY"}}},
+				err:    errors.New("encryption.key needs exactly one of file or env"),
+			},
+			{
+				name:   "accepts encryption with only previous keys",
+				config: Config{Encryption: &EncryptionConfig{PreviousKeys: []EncryptionKeyConfig{{Env: "CAPTURE_KEY"}}}},
+				deps:   []string{cloud.InternalServiceName.String()},
+			},
+			{
+				name:   "returns an error if a retention rule has no max age",
+				config: Config{Retention: &sync.RetentionConfig{Rules: []sync.RetentionRule{{Name: "incidents", Tag: "incident"}}}},
+				err:    errors.New(`retention rule "incidents" needs a positive max_age_hours`),
+			},
+			{
+				name: "accepts retention rules",
+				config: Config{Retention: &sync.RetentionConfig{
+					Rules:                []sync.RetentionRule{{ResourceName: "camera-1", MaxAgeHours: 24}},
+					ProtectUnsyncedHours: 6,
+				}},
+				deps: []string{cloud.InternalServiceName.String()},
+			},
 		}
 
 		for _, tc := range tcs {
@@ -183,4 +239,26 @@ func TestConfig(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("Encryption.keyring()", func(t *testing.T) {
+		current := strings.Repeat("ab", data.CaptureKeySize)
+		previous := strings.Repeat("cd", data.CaptureKeySize)
+		keyPath := filepath.Join(t.TempDir(), "capture.key")
+		test.That(t, os.WriteFile(keyPath, []byte(current), 0o600), test.ShouldBeNil)
+		t.Setenv("TEST_PREVIOUS_CAPTURE_KEY", previous)
+
+		c := &EncryptionConfig{
+			Key:          EncryptionKeyConfig{File: keyPath},
+			PreviousKeys: []EncryptionKeyConfig{{Env: "TEST_PREVIOUS_CAPTURE_KEY"}},
+		}
+		keyring, err := c.keyring()
+		test.That(t, err, test.ShouldBeNil)
+		key, err := data.ParseCaptureKey(current)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, keyring.CurrentKeyID(), test.ShouldEqual, data.CaptureKeyID(key))
+
+		c.PreviousKeys = append(c.PreviousKeys, EncryptionKeyConfig{Env: "TEST_UNSET_CAPTURE_KEY"})
+		_, err = c.keyring()
+		test.That(t, err, test.ShouldBeError, errors.New("environment variable TEST_UNSET_CAPTURE_KEY is not set"))
+	})
 }
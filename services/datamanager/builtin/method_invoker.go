@@ -0,0 +1,110 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	goutils "go.viam.com/utils"
+	"go.viam.com/utils/rpc"
+
+	"go.viam.com/rdk/config"
+	viamgrpc "go.viam.com/rdk/grpc"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/utils"
+)
+
+// methodInvoker invokes the gRPC methods of resources which have no collector registered, by reflection over a
+// connection to the module server of the robot, as the job manager does. The connection is dialed when a method is
+// first invoked, and the reflection of each API is cached until the address changes.
+type methodInvoker struct {
+	logger logging.Logger
+
+	mu          sync.Mutex
+	addr        string
+	conn        rpc.ClientConn
+	ctx         context.Context
+	cancel      context.CancelFunc
+	reflections map[resource.API]*viamgrpc.ResourceReflection
+}
+
+func newMethodInvoker(logger logging.Logger) *methodInvoker {
+	return &methodInvoker{logger: logger}
+}
+
+// setAddress sets the address of the module server methods are invoked through, closing the connection to the
+// previous one.
+func (i *methodInvoker) setAddress(addrs config.ParentSockAddrs) {
+	addr := "unix:" + addrs.UnixAddr
+	if use, _ := utils.OnlyUseViamTCPSockets(); use {
+		addr = addrs.TCPAddr
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if addr == i.addr {
+		return
+	}
+	i.closeConn()
+	i.addr = addr
+}
+
+// invoke satisfies data.MethodInvoker.
+func (i *methodInvoker) invoke(
+	ctx context.Context,
+	api resource.API,
+	name, method string,
+	args map[string]interface{},
+) (map[string]interface{}, error) {
+	reflection, err := i.reflection(api)
+	if err != nil {
+		return nil, err
+	}
+	return reflection.Invoke(ctx, name, method, args)
+}
+
+func (i *methodInvoker) reflection(api resource.API) (*viamgrpc.ResourceReflection, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.conn == nil {
+		if i.addr == "" || i.addr == "unix:" {
+			return nil, errors.New("the address of the module server is unknown")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		conn, err := viamgrpc.Dial(ctx, i.addr, i.logger)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		i.conn, i.ctx, i.cancel = conn, ctx, cancel
+		i.reflections = map[resource.API]*viamgrpc.ResourceReflection{}
+	}
+	if reflection, ok := i.reflections[api]; ok {
+		return reflection, nil
+	}
+	reflection, err := viamgrpc.NewResourceReflection(i.ctx, i.conn, api)
+	if err != nil {
+		return nil, err
+	}
+	i.reflections[api] = reflection
+	return reflection, nil
+}
+
+// closeConn closes the connection and reflections, if any. i.mu must be held.
+func (i *methodInvoker) closeConn() {
+	if i.conn == nil {
+		return
+	}
+	for _, reflection := range i.reflections {
+		reflection.Close()
+	}
+	i.cancel()
+	goutils.UncheckedError(i.conn.Close())
+	i.conn, i.ctx, i.cancel, i.reflections = nil, nil, nil, nil
+}
+
+func (i *methodInvoker) close() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.closeConn()
+}
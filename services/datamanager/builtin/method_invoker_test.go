@@ -0,0 +1,59 @@
+package builtin
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"go.viam.com/test"
+	"go.viam.com/utils/rpc"
+
+	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/config"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+func TestMethodInvoker(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	invoker := newMethodInvoker(logger)
+	defer invoker.close()
+
+	_, err := invoker.invoke(context.Background(), sensor.API, "sensor1", "GetReadings", nil)
+	test.That(t, err, test.ShouldBeError, "the address of the module server is unknown")
+
+	sockPath := filepath.Join(t.TempDir(), "parent.sock")
+	listener, err := net.Listen("unix", sockPath)
+	test.That(t, err, test.ShouldBeNil)
+	rpcServer, err := rpc.NewServer(logger, rpc.WithUnauthenticated())
+	test.That(t, err, test.ShouldBeNil)
+	injectSensor := &inject.Sensor{}
+	injectSensor.ReadingsFunc = func(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"humidity": 40.0, "extra": extra}, nil
+	}
+	sensorSvc, err := resource.NewAPIResourceCollection(sensor.API, map[resource.Name]sensor.Sensor{sensor.Named("sensor1"): injectSensor})
+	test.That(t, err, test.ShouldBeNil)
+	resourceAPI, ok, err := resource.LookupAPIRegistration[sensor.Sensor](sensor.API)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, resourceAPI.RegisterRPCService(context.Background(), rpcServer, sensorSvc, logger), test.ShouldBeNil)
+	go rpcServer.Serve(listener)
+	defer rpcServer.Stop()
+
+	invoker.setAddress(config.ParentSockAddrs{UnixAddr: sockPath})
+	args := map[string]interface{}{"extra": map[string]interface{}{"window": "1m"}}
+	for i := 0; i < 2; i++ {
+		response, err := invoker.invoke(context.Background(), sensor.API, "sensor1", "GetReadings", args)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, response, test.ShouldResemble, map[string]interface{}{
+			"readings": map[string]interface{}{"humidity": 40.0, "extra": map[string]interface{}{"window": "1m"}},
+		})
+	}
+	test.That(t, invoker.reflections, test.ShouldHaveLength, 1)
+
+	// changing the address closes the connection to the previous one
+	invoker.setAddress(config.ParentSockAddrs{UnixAddr: filepath.Join(t.TempDir(), "other.sock")})
+	test.That(t, invoker.conn, test.ShouldBeNil)
+}
@@ -0,0 +1,57 @@
+package sync
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// bandwidthLimiter paces uploads so that, on average, at most bytesPerSec are uploaded across all sync threads.
+// Uploads are paced per file: a file starts uploading once the files before it have had time to upload at the cap.
+// A nil bandwidthLimiter doesn't limit.
+type bandwidthLimiter struct {
+	clock       clock.Clock
+	bytesPerSec int64
+
+	mu sync.Mutex
+	// next is when the bytes reserved so far will have been uploaded at the cap.
+	next time.Time
+}
+
+func newBandwidthLimiter(clk clock.Clock, bytesPerSec int64) *bandwidthLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &bandwidthLimiter{clock: clk, bytesPerSec: bytesPerSec}
+}
+
+// wait reserves n bytes of bandwidth, blocking until the bytes reserved before them have been uploaded at the cap
+// or ctx is done.
+func (l *bandwidthLimiter) wait(ctx context.Context, n int64) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := l.clock.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(time.Duration(float64(n) / float64(l.bytesPerSec) * float64(time.Second)))
+	l.mu.Unlock()
+
+	delay := start.Sub(now)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := l.clock.Timer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
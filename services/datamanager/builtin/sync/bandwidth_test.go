@@ -0,0 +1,40 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"go.viam.com/test"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	test.That(t, newBandwidthLimiter(clock.NewMock(), 0), test.ShouldBeNil)
+	var unlimited *bandwidthLimiter
+	test.That(t, unlimited.wait(context.Background(), 1<<30), test.ShouldBeNil)
+
+	clk := clock.NewMock()
+	start := clk.Now()
+	l := newBandwidthLimiter(clk, 1000)
+	// the first file starts right away, and the next once it has had 2 seconds to upload
+	test.That(t, l.wait(context.Background(), 2000), test.ShouldBeNil)
+	done := make(chan error)
+	go func() {
+		done <- l.wait(context.Background(), 500)
+	}()
+	for clk.Now().Before(start.Add(2 * time.Second)) {
+		select {
+		case <-done:
+			t.Fatal("wait returned before the previous file had time to upload")
+		default:
+		}
+		clk.Add(100 * time.Millisecond)
+	}
+	test.That(t, <-done, test.ShouldBeNil)
+
+	// the limiter stops waiting when its context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	test.That(t, l.wait(ctx, 500), test.ShouldBeError, context.Canceled)
+}
@@ -3,8 +3,12 @@ package sync
 import (
 	"reflect"
 	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/services/datamanager/builtin/shared"
 )
@@ -95,6 +99,82 @@ type Config struct {
 	// unil the Readings method of the SelectiveSyncSensor (when called on the SyncIntervalMins interval) returns
 	// the a key of datamanager.ShouldSyncKey and a value of `true`
 	SelectiveSyncSensor sensor.Sensor
+	// Destination, when non nil, is where files are uploaded to instead of the Viam cloud.
+	Destination *DestinationConfig
+	// CapturePriorities is the sync priority of capture directories, keyed by directory. Files in a directory with
+	// a higher priority are synced before those in a directory with a lower one. Directories which aren't present
+	// have a priority of 0.
+	CapturePriorities map[string]int
+	// MaximumSyncBytesPerSec, when positive, caps the rate files are uploaded at across all sync threads.
+	MaximumSyncBytesPerSec int64
+	// SyncWindows, when non empty, limits scheduled sync to the times of day within one of the windows.
+	// Sync called manually ignores them.
+	SyncWindows []SyncWindow
+	// DeferBinarySyncUntilWiFi, when true, only syncs binary data, meaning binary capture files and arbitrary
+	// files, while the machine's default route is through a wireless interface. Tabular data syncs regardless.
+	DeferBinarySyncUntilWiFi bool
+	// Retention, when non nil, deletes files in the CaptureDir once they are older than the rules they match allow,
+	// and protects recent files from being deleted when the disk is full.
+	Retention *RetentionConfig
+	// Keyring, when non nil, decrypts encrypted capture files so that they can be synced.
+	Keyring *data.CaptureKeyring
+}
+
+// SyncWindow is a time of day, in the machine's local time zone, during which scheduled sync may run.
+// A window whose End is before its Start spans midnight.
+type SyncWindow struct {
+	// Start and End are times of day formatted as HH:MM.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+const syncWindowLayout = "15:04"
+
+// Validate returns an error if either end of the window is not a time of day.
+func (w SyncWindow) Validate() error {
+	for _, t := range []string{w.Start, w.End} {
+		if _, err := time.Parse(syncWindowLayout, t); err != nil {
+			return errors.Errorf("sync window time %q must be formatted as HH:MM", t)
+		}
+	}
+	return nil
+}
+
+// contains returns true if the time of day of t is within the window. A window which starts and ends at the same
+// time spans the whole day.
+func (w SyncWindow) contains(t time.Time) bool {
+	start, err := time.Parse(syncWindowLayout, w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(syncWindowLayout, w.End)
+	if err != nil {
+		return false
+	}
+	startMin := start.Hour()*60 + start.Minute()
+	endMin := end.Hour()*60 + end.Minute()
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case startMin == endMin:
+		return true
+	case startMin < endMin:
+		return minute >= startMin && minute < endMin
+	default:
+		return minute >= startMin || minute < endMin
+	}
+}
+
+// InSyncWindow returns true if there are no sync windows, or if t is within one of them.
+func (c Config) InSyncWindow(t time.Time) bool {
+	if len(c.SyncWindows) == 0 {
+		return true
+	}
+	for _, w := range c.SyncWindows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
 }
 
 // SchedulerEnabled returns true if the sync scheduler should be running.
@@ -121,7 +201,14 @@ func (c Config) Equal(o Config) bool {
 		c.SyncIntervalMins == o.SyncIntervalMins &&
 		reflect.DeepEqual(c.Tags, o.Tags) &&
 		c.SelectiveSyncSensorEnabled == o.SelectiveSyncSensorEnabled &&
-		c.SelectiveSyncSensor == o.SelectiveSyncSensor
+		c.SelectiveSyncSensor == o.SelectiveSyncSensor &&
+		reflect.DeepEqual(c.Destination, o.Destination) &&
+		reflect.DeepEqual(c.CapturePriorities, o.CapturePriorities) &&
+		c.MaximumSyncBytesPerSec == o.MaximumSyncBytesPerSec &&
+		reflect.DeepEqual(c.SyncWindows, o.SyncWindows) &&
+		c.DeferBinarySyncUntilWiFi == o.DeferBinarySyncUntilWiFi &&
+		reflect.DeepEqual(c.Retention, o.Retention) &&
+		c.Keyring.Equal(o.Keyring)
 }
 
 func (c *Config) logDiff(o Config, logger logging.Logger) {
@@ -188,6 +275,34 @@ func (c *Config) logDiff(o Config, logger logging.Logger) {
 		}
 		logger.Infof("SelectiveSyncSensor: old: %s, new: %s", oldName, newName)
 	}
+
+	if !reflect.DeepEqual(c.Destination, o.Destination) {
+		logger.Infof("sync_destination: old: %s, new: %s", c.Destination.describe(), o.Destination.describe())
+	}
+
+	if !reflect.DeepEqual(c.CapturePriorities, o.CapturePriorities) {
+		logger.Infof("sync_priority: old: %v, new: %v", c.CapturePriorities, o.CapturePriorities)
+	}
+
+	if c.MaximumSyncBytesPerSec != o.MaximumSyncBytesPerSec {
+		logger.Infof("maximum_sync_bytes_per_sec: old: %d, new: %d", c.MaximumSyncBytesPerSec, o.MaximumSyncBytesPerSec)
+	}
+
+	if !reflect.DeepEqual(c.SyncWindows, o.SyncWindows) {
+		logger.Infof("sync_windows: old: %+v, new: %+v", c.SyncWindows, o.SyncWindows)
+	}
+
+	if c.DeferBinarySyncUntilWiFi != o.DeferBinarySyncUntilWiFi {
+		logger.Infof("defer_binary_sync_until_wifi: old: %t, new: %t", c.DeferBinarySyncUntilWiFi, o.DeferBinarySyncUntilWiFi)
+	}
+
+	if !reflect.DeepEqual(c.Retention, o.Retention) {
+		logger.Infof("retention: old: %+v, new: %+v", c.Retention, o.Retention)
+	}
+
+	if !c.Keyring.Equal(o.Keyring) {
+		logger.Infof("encryption key: old: %s, new: %s", c.Keyring.CurrentKeyID(), o.Keyring.CurrentKeyID())
+	}
 }
 
 // SyncPaths returns the capture directory and additional sync paths as a slice.
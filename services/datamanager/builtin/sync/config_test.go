@@ -2,6 +2,7 @@ package sync
 
 import (
 	"testing"
+	"time"
 
 	"go.viam.com/test"
 
@@ -201,6 +202,30 @@ func TestConfig(t *testing.T) {
 				},
 				equal: false,
 			},
+			{
+				name:  "different CapturePriorities are not equal",
+				a:     Config{CapturePriorities: map[string]int{"a": 1}},
+				b:     Config{CapturePriorities: map[string]int{"a": 2}},
+				equal: false,
+			},
+			{
+				name:  "different MaximumSyncBytesPerSec are not equal",
+				a:     Config{MaximumSyncBytesPerSec: 1000},
+				b:     Config{MaximumSyncBytesPerSec: 2000},
+				equal: false,
+			},
+			{
+				name:  "different SyncWindows are not equal",
+				a:     Config{SyncWindows: []SyncWindow{{Start: "22:00", End: "06:00"}}},
+				b:     Config{SyncWindows: []SyncWindow{{Start: "23:00", End: "06:00"}}},
+				equal: false,
+			},
+			{
+				name:  "different DeferBinarySyncUntilWiFi are not equal",
+				a:     Config{DeferBinarySyncUntilWiFi: true},
+				b:     Config{DeferBinarySyncUntilWiFi: false},
+				equal: false,
+			},
 		}
 
 		for _, tc := range tcs {
@@ -236,6 +261,26 @@ func TestConfig(t *testing.T) {
 		})
 	})
 
+	t.Run("InSyncWindow()", func(t *testing.T) {
+		at := func(hour, minute int) time.Time {
+			return time.Date(2026, 1, 1, hour, minute, 0, 0, time.UTC)
+		}
+		test.That(t, Config{}.InSyncWindow(at(12, 0)), test.ShouldBeTrue)
+
+		overnight := Config{SyncWindows: []SyncWindow{{Start: "22:00", End: "06:00"}}}
+		test.That(t, overnight.InSyncWindow(at(23, 30)), test.ShouldBeTrue)
+		test.That(t, overnight.InSyncWindow(at(5, 59)), test.ShouldBeTrue)
+		test.That(t, overnight.InSyncWindow(at(6, 0)), test.ShouldBeFalse)
+		test.That(t, overnight.InSyncWindow(at(12, 0)), test.ShouldBeFalse)
+
+		lunch := Config{SyncWindows: []SyncWindow{{Start: "22:00", End: "06:00"}, {Start: "12:00", End: "13:00"}}}
+		test.That(t, lunch.InSyncWindow(at(12, 0)), test.ShouldBeTrue)
+		test.That(t, lunch.InSyncWindow(at(13, 0)), test.ShouldBeFalse)
+
+		allDay := Config{SyncWindows: []SyncWindow{{Start: "00:00", End: "00:00"}}}
+		test.That(t, allDay.InSyncWindow(at(17, 0)), test.ShouldBeTrue)
+	})
+
 	t.Run("syncPaths()", func(t *testing.T) {
 		captureDir := "/some/capture/dir"
 		empty := Config{CaptureDir: captureDir}
@@ -0,0 +1,277 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+// Sync destination types.
+const (
+	// DestinationS3 uploads files to a bucket of AWS S3 or an S3-compatible service such as MinIO.
+	DestinationS3 = "s3"
+	// DestinationLocal copies files into a directory, such as an NFS mount.
+	DestinationLocal = "local"
+	// DestinationHTTP PUTs files to an HTTP endpoint.
+	DestinationHTTP = "http"
+
+	defaultS3Region = "us-east-1"
+)
+
+// errDestinationRejected is returned when a destination will never accept a file, so that it is moved to the failed
+// directory rather than retried.
+var errDestinationRejected = errors.New("sync destination rejected the file")
+
+// DestinationConfig configures a destination which sync uploads files to instead of the Viam cloud. Files are
+// uploaded as they are on disk, named by their path relative to the sync directory they are in.
+type DestinationConfig struct {
+	// Type is one of DestinationS3, DestinationLocal or DestinationHTTP.
+	Type string `json:"type"`
+
+	// Directory is where a local destination copies files to.
+	Directory string `json:"directory,omitempty"`
+
+	// URL is where an http destination PUTs files to, with the name of the file appended.
+	URL string `json:"url,omitempty"`
+	// Headers are added to each request of an http destination, for example for authorization.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Endpoint is the URL of an S3-compatible service. It is left empty for AWS S3.
+	Endpoint string `json:"endpoint,omitempty"`
+	Bucket   string `json:"bucket,omitempty"`
+	// KeyPrefix is prepended to the name of each file to make its key in the bucket.
+	KeyPrefix string `json:"key_prefix,omitempty"`
+	// Region defaults to us-east-1.
+	Region string `json:"region,omitempty"`
+	// AccessKeyID and SecretAccessKey are the credentials of an s3 destination. If they are not given, credentials
+	// are taken from the environment like other AWS tools.
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+}
+
+// Validate returns an error if the destination is missing fields its type needs.
+func (c *DestinationConfig) Validate() error {
+	switch c.Type {
+	case DestinationS3:
+		if c.Bucket == "" {
+			return errors.New("an s3 sync destination needs a bucket")
+		}
+		if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+			return errors.New("an s3 sync destination needs both access_key_id and secret_access_key, or neither")
+		}
+	case DestinationLocal:
+		if c.Directory == "" {
+			return errors.New("a local sync destination needs a directory")
+		}
+	case DestinationHTTP:
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return errors.Wrap(err, "invalid http sync destination url")
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.Errorf("http sync destination url %q must start with http:// or https://", c.URL)
+		}
+	default:
+		return errors.Errorf("sync destination type must be %q, %q or %q, not %q", DestinationS3, DestinationLocal, DestinationHTTP, c.Type)
+	}
+	return nil
+}
+
+// Destination is somewhere other than the Viam cloud that sync uploads files to.
+type Destination interface {
+	// Upload uploads the contents of a file under name, a slash separated path, and returns the number of bytes
+	// uploaded. An upload may be retried, so uploading the same name twice must overwrite the first upload.
+	Upload(ctx context.Context, name string, contents *io.SectionReader) (uint64, error)
+}
+
+// NewDestination returns the destination described by a config.
+func NewDestination(c DestinationConfig) (Destination, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	switch c.Type {
+	case DestinationS3:
+		return newS3Destination(c)
+	case DestinationLocal:
+		return &localDestination{dir: c.Directory}, nil
+	default:
+		return &httpDestination{url: strings.TrimSuffix(c.URL, "/"), headers: c.Headers, client: http.DefaultClient}, nil
+	}
+}
+
+// rejectedStatus returns whether an HTTP status means a request will never succeed. Other failures, including
+// authorization failures, are retried, as they may be fixed without changing the file.
+func rejectedStatus(code int) bool {
+	return code == http.StatusBadRequest || code == http.StatusRequestEntityTooLarge
+}
+
+type localDestination struct {
+	dir string
+}
+
+// Upload writes the file to a temporary file next to its destination, then renames it, so that nothing reading the
+// directory sees a partial file.
+func (d *localDestination) Upload(ctx context.Context, name string, contents *io.SectionReader) (uint64, error) {
+	dst := filepath.Join(d.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+		return 0, err
+	}
+	tmp := dst + ".tmp"
+	//nolint:gosec
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, contents)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, multierr.Combine(err, os.Remove(tmp))
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+type httpDestination struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (d *httpDestination) Upload(ctx context.Context, name string, contents *io.SectionReader) (uint64, error) {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, d.url+"/"+strings.Join(segments, "/"), contents)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = contents.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	for k, v := range d.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		//nolint:errcheck
+		io.Copy(io.Discard, resp.Body)
+		//nolint:errcheck
+		resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("uploading %s got status %s", name, resp.Status)
+		if rejectedStatus(resp.StatusCode) {
+			return 0, errors.Wrap(errDestinationRejected, err.Error())
+		}
+		return 0, err
+	}
+	return uint64(contents.Size()), nil
+}
+
+type s3Destination struct {
+	bucket    string
+	keyPrefix string
+	client    *s3.Client
+}
+
+func newS3Destination(c DestinationConfig) (*s3Destination, error) {
+	region := defaultS3Region
+	if c.Region != "" {
+		region = c.Region
+	}
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(region),
+		// retries are left to sync's exponential backoff
+		awsconfig.WithRetryMaxAttempts(1),
+	}
+	if c.AccessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, "")))
+	}
+	conf, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load s3 config")
+	}
+	client := s3.NewFromConfig(conf, func(o *s3.Options) {
+		if c.Endpoint != "" {
+			// S3-compatible services are usually only addressable by path, rather than by a subdomain per bucket, and
+			// may not support the checksums AWS S3 does
+			o.BaseEndpoint = aws.String(c.Endpoint)
+			o.UsePathStyle = true
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		}
+	})
+	return &s3Destination{bucket: c.Bucket, keyPrefix: c.KeyPrefix, client: client}, nil
+}
+
+func (d *s3Destination) Upload(ctx context.Context, name string, contents *io.SectionReader) (uint64, error) {
+	_, err := d.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(d.bucket),
+		Key:           aws.String(d.keyPrefix + name),
+		Body:          contents,
+		ContentLength: aws.Int64(contents.Size()),
+	})
+	if err != nil {
+		var respErr *smithyhttp.ResponseError
+		if errors.As(err, &respErr) && rejectedStatus(respErr.HTTPStatusCode()) {
+			return 0, errors.Wrap(errDestinationRejected, err.Error())
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, err
+	}
+	return uint64(contents.Size()), nil
+}
+
+// brokenDestination stands in for a destination which couldn't be created, so that files are kept rather than
+// uploaded anywhere else.
+type brokenDestination struct {
+	err error
+}
+
+func (d brokenDestination) Upload(ctx context.Context, name string, contents *io.SectionReader) (uint64, error) {
+	return 0, d.err
+}
+
+// describe returns where a destination uploads to, without any credentials, for logging.
+func (c *DestinationConfig) describe() string {
+	if c == nil {
+		return "cloud"
+	}
+	switch c.Type {
+	case DestinationS3:
+		where := "s3://" + c.Bucket + "/" + c.KeyPrefix
+		if c.Endpoint != "" {
+			where += " at " + c.Endpoint
+		}
+		return where
+	case DestinationLocal:
+		return c.Directory
+	default:
+		return c.URL
+	}
+}
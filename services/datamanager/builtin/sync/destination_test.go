@@ -0,0 +1,209 @@
+package sync
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"go.viam.com/test"
+	"go.viam.com/utils/testutils"
+
+	"go.viam.com/rdk/internal/cloud"
+	"go.viam.com/rdk/internal/testutils/inject"
+)
+
+// objectStore is a stand-in for an S3-compatible service like MinIO, or any HTTP endpoint accepting PUTs.
+type objectStore struct {
+	mu      gosync.Mutex
+	objects map[string]string
+	headers http.Header
+	// status, if set, is returned instead of storing objects
+	status int
+}
+
+func newObjectStore(t *testing.T) (*objectStore, *httptest.Server) {
+	t.Helper()
+	store := &objectStore{objects: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if store.status != 0 {
+			w.WriteHeader(store.status)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		store.objects[r.URL.Path] = string(body)
+		store.headers = r.Header.Clone()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	t.Cleanup(server.Close)
+	return store, server
+}
+
+func (s *objectStore) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *objectStore) get(path string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object, ok := s.objects[path]
+	return object, ok
+}
+
+func upload(t *testing.T, dest Destination, name, contents string) error {
+	t.Helper()
+	_, err := dest.Upload(context.Background(), name, io.NewSectionReader(strings.NewReader(contents), 0, int64(len(contents))))
+	return err
+}
+
+func TestDestinations(t *testing.T) {
+	t.Run("local", func(t *testing.T) {
+		dir := t.TempDir()
+		dest, err := NewDestination(DestinationConfig{Type: DestinationLocal, Directory: dir})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, upload(t, dest, "arm/positions/1.capture", "first"), test.ShouldBeNil)
+		// a retried upload replaces the first
+		test.That(t, upload(t, dest, "arm/positions/1.capture", "second"), test.ShouldBeNil)
+		contents, err := os.ReadFile(filepath.Join(dir, "arm", "positions", "1.capture"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, string(contents), test.ShouldEqual, "second")
+		entries, err := os.ReadDir(filepath.Join(dir, "arm", "positions"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, entries, test.ShouldHaveLength, 1)
+	})
+
+	t.Run("http", func(t *testing.T) {
+		store, server := newObjectStore(t)
+		dest, err := NewDestination(DestinationConfig{
+			Type:    DestinationHTTP,
+			URL:     server.URL + "/upload/",
+			Headers: map[string]string{"Authorization": "Bearer token"},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, upload(t, dest, "camera/read image/1.capture", "image"), test.ShouldBeNil)
+		contents, ok := store.get("/upload/camera/read image/1.capture")
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, contents, test.ShouldEqual, "image")
+		test.That(t, store.headers.Get("Authorization"), test.ShouldEqual, "Bearer token")
+
+		store.setStatus(http.StatusBadRequest)
+		err = upload(t, dest, "bad.capture", "")
+		test.That(t, errors.Is(err, errDestinationRejected), test.ShouldBeTrue)
+		test.That(t, terminalError(err), test.ShouldBeTrue)
+
+		// the endpoint may come back, so server errors are retried
+		store.setStatus(http.StatusServiceUnavailable)
+		err = upload(t, dest, "later.capture", "")
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, terminalError(err), test.ShouldBeFalse)
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		store, server := newObjectStore(t)
+		dest, err := NewDestination(DestinationConfig{
+			Type:            DestinationS3,
+			Endpoint:        server.URL,
+			Bucket:          "robots",
+			KeyPrefix:       "rover/",
+			AccessKeyID:     "minio",
+			SecretAccessKey: "minio123",
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, upload(t, dest, "arm/positions/1.capture", "positions"), test.ShouldBeNil)
+		contents, ok := store.get("/robots/rover/arm/positions/1.capture")
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, contents, test.ShouldEqual, "positions")
+		test.That(t, store.headers.Get("Authorization"), test.ShouldStartWith, "AWS4-HMAC-SHA256 Credential=minio/")
+
+		store.setStatus(http.StatusBadRequest)
+		err = upload(t, dest, "bad.capture", "")
+		test.That(t, errors.Is(err, errDestinationRejected), test.ShouldBeTrue)
+	})
+}
+
+func TestDestinationConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		conf DestinationConfig
+		err  string
+	}{
+		{DestinationConfig{Type: "ftp"}, `sync destination type must be "s3", "local" or "http", not "ftp"`},
+		{DestinationConfig{Type: DestinationS3}, "needs a bucket"},
+		{DestinationConfig{Type: DestinationS3, Bucket: "b", AccessKeyID: "id"}, "needs both access_key_id and secret_access_key"},
+		{DestinationConfig{Type: DestinationLocal}, "needs a directory"},
+		{DestinationConfig{Type: DestinationHTTP, URL: "example.com/upload"}, "must start with http:// or https://"},
+		{DestinationConfig{Type: DestinationHTTP, URL: "https://example.com/upload"}, ""},
+	} {
+		err := tc.conf.Validate()
+		if tc.err == "" {
+			test.That(t, err, test.ShouldBeNil)
+		} else {
+			test.That(t, err, test.ShouldNotBeNil)
+			test.That(t, err.Error(), test.ShouldContainSubstring, tc.err)
+		}
+	}
+}
+
+func TestSyncToDestination(t *testing.T) {
+	captureDir := t.TempDir()
+	destDir := t.TempDir()
+	s := newTestSync(t, MockDataSyncServiceClient{T: t}, false)
+	config := Config{
+		CaptureDir:            captureDir,
+		CaptureDisabled:       true,
+		MaximumNumSyncThreads: 1,
+		ScheduledSyncDisabled: true,
+		Destination:           &DestinationConfig{Type: DestinationLocal, Directory: destDir},
+	}
+	// the robot isn't cloud managed, which doesn't matter when syncing elsewhere
+	s.Reconfigure(context.Background(), config, &inject.CloudConnectionService{AcquireConnectionErr: cloud.ErrNotCloudManaged})
+
+	test.That(t, os.MkdirAll(filepath.Join(captureDir, "arm"), 0o700), test.ShouldBeNil)
+	writeTestFile(t, filepath.Join(captureDir, "arm"), "1.capture", []byte("capture"))
+	writeTestFile(t, captureDir, "notes.txt", []byte("notes"))
+	test.That(t, s.Sync(context.Background(), nil), test.ShouldBeNil)
+
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		stats := s.GetStats().Upload
+		test.That(tb, stats.TabularSensorUploadedFileCount, test.ShouldEqual, 1)
+		test.That(tb, stats.ArbitraryUploadedFileCount, test.ShouldEqual, 1)
+	})
+	for name, contents := range map[string]string{"arm/1.capture": "capture", "notes.txt": "notes"} {
+		uploaded, err := os.ReadFile(filepath.Join(destDir, name))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, string(uploaded), test.ShouldEqual, contents)
+		_, err = os.Stat(filepath.Join(captureDir, name))
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	}
+
+	t.Run("rejected files are moved to the failed directory", func(t *testing.T) {
+		store, server := newObjectStore(t)
+		store.setStatus(http.StatusRequestEntityTooLarge)
+		config.Destination = &DestinationConfig{Type: DestinationHTTP, URL: server.URL}
+		s.Reconfigure(context.Background(), config, nil)
+
+		writeTestFile(t, filepath.Join(captureDir, "arm"), "2.capture", []byte("too big"))
+		s.syncFile(config, filepath.Join(captureDir, "arm", "2.capture"))
+		_, err := os.Stat(filepath.Join(captureDir, FailedDir, "arm", "2.capture"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, s.GetStats().Upload.TabularSensorUploadFailedFileCount, test.ShouldEqual, 1)
+	})
+}
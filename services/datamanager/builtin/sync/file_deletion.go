@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/pkg/errors"
@@ -27,6 +28,7 @@ func deleteExcessFilesOnSchedule(
 	deleteEveryNth int,
 	diskUsageThreshold float64,
 	captureDirThreshold float64,
+	protectUnsynced time.Duration,
 	clock clock.Clock,
 	logger logging.Logger,
 	deletedFileCount *atomic.Int64,
@@ -47,7 +49,8 @@ func deleteExcessFilesOnSchedule(
 			return
 		case <-t.C:
 			maybeDeleteExcessFiles(
-				ctx, fileTracker, captureDir, deleteEveryNth, diskUsageThreshold, captureDirThreshold, clock, logger, deletedFileCount,
+				ctx, fileTracker, captureDir, deleteEveryNth, diskUsageThreshold, captureDirThreshold, protectUnsynced, clock, logger,
+				deletedFileCount,
 			)
 		}
 	}
@@ -60,11 +63,16 @@ func maybeDeleteExcessFiles(
 	deleteEveryNth int,
 	diskUsageThreshold float64,
 	captureDirThreshold float64,
+	protectUnsynced time.Duration,
 	clock clock.Clock,
 	logger logging.Logger,
 	deletedFileCount *atomic.Int64,
 ) {
 	start := clock.Now()
+	var protectedAfter time.Time
+	if protectUnsynced > 0 {
+		protectedAfter = start.Add(-protectUnsynced)
+	}
 	usage, err := diskusage.Statfs(captureDir)
 	if err != nil {
 		logger.Error(errors.Wrap(err, "error checking file system stats"))
@@ -83,6 +91,7 @@ func maybeDeleteExcessFiles(
 		deleteEveryNth,
 		diskUsageThreshold,
 		captureDirThreshold,
+		protectedAfter,
 		logger)
 
 	duration := clock.Since(start)
@@ -105,6 +114,7 @@ func deleteExcessFiles(
 	deleteEveryNth int,
 	diskUsageThreshold float64,
 	captureDirToFSThreshold float64,
+	protectedAfter time.Time,
 	logger logging.Logger,
 ) (int, error) {
 	shouldDelete, err := shouldDeleteBasedOnDiskUsage(
@@ -123,7 +133,7 @@ func deleteExcessFiles(
 	}
 
 	logger.Warnf("current disk usage of the data capture directory exceeds threshold (%f)", captureDirToFSThreshold)
-	return deleteFiles(ctx, fileTracker, deleteEveryNth, captureDir, logger)
+	return deleteFiles(ctx, fileTracker, deleteEveryNth, captureDir, protectedAfter, logger)
 }
 
 func shouldDeleteBasedOnDiskUsage(
@@ -210,6 +220,7 @@ func deleteFiles(
 	fileTracker *fileTracker,
 	deleteEveryNth int,
 	captureDirPath string,
+	protectedAfter time.Time,
 	logger logging.Logger,
 ) (int, error) {
 	index := 0
@@ -241,8 +252,10 @@ func deleteFiles(
 			return err
 		}
 		isCompletedDataCaptureFile := strings.Contains(fileInfo.Name(), data.CompletedCaptureFileExt)
+		// files modified after protectedAfter, if it is set, are protected by retention from being deleted
+		protected := !protectedAfter.IsZero() && fileInfo.ModTime().After(protectedAfter)
 		// if at nth file and the file is not currently being written, mark as in progress if possible
-		if isCompletedDataCaptureFile && index%deleteEveryNth == 0 {
+		if isCompletedDataCaptureFile && index%deleteEveryNth == 0 && !protected {
 			if !fileTracker.markInProgress(path) {
 				logger.Debugw("Tried to mark file as in progress but lock already held", "file", d.Name())
 				return nil
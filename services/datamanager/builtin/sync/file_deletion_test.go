@@ -6,6 +6,7 @@ import (
 	"math"
 	"os"
 	"testing"
+	"time"
 
 	"go.viam.com/test"
 
@@ -20,6 +21,7 @@ func TestFileDeletion(t *testing.T) {
 		expectedDeleteFilenames []string
 		fileList                []string
 		syncerInProgressFiles   []string
+		protectedAfter          time.Time
 	}{
 		{
 			name: "if sync disabled, file deleter should delete every 5th file",
@@ -51,6 +53,12 @@ func TestFileDeletion(t *testing.T) {
 			fileList:                []string{"0.fe", "1.fi", "2.fo", "3.fum", "4.foo", "5.capture"},
 			expectedDeleteFilenames: []string{"5.capture"},
 		},
+		{
+			name:                    "file deleter should not delete files protected by retention",
+			fileList:                []string{"0.capture", "1.capture", "2.capture", "3.capture", "4.capture", "5.capture"},
+			protectedAfter:          time.Now().Add(-time.Hour),
+			expectedDeleteFilenames: []string{},
+		},
 		{
 			name:                "if cancelled context is cancelled, file deleter should return an error",
 			shouldCancelContext: true,
@@ -73,7 +81,7 @@ func TestFileDeletion(t *testing.T) {
 			if tc.shouldCancelContext {
 				cancelFunc()
 			}
-			deletedFileCount, err := deleteFiles(ctx, ft, 5, tempCaptureDir, logger)
+			deletedFileCount, err := deleteFiles(ctx, ft, 5, tempCaptureDir, tc.protectedAfter, logger)
 			if tc.shouldCancelContext {
 				test.That(t, err, test.ShouldBeError, context.Canceled)
 			} else {
@@ -0,0 +1,50 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// onWiFi returns true if the machine's default route is through a wireless interface. This can only be told on
+// linux; elsewhere the machine is assumed to be on Wi-Fi so that binary data is never deferred indefinitely.
+func onWiFi() bool {
+	if runtime.GOOS != "linux" {
+		return true
+	}
+	routes, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return false
+	}
+	iface, ok := parseDefaultRouteInterface(string(routes))
+	if !ok {
+		return false
+	}
+	_, err = os.Stat(filepath.Join("/sys/class/net", iface, "wireless"))
+	return err == nil
+}
+
+// parseDefaultRouteInterface returns the interface of the default route in /proc/net/route content. When there
+// are multiple default routes, the one with the lowest metric is the kernel's preferred route.
+func parseDefaultRouteInterface(routes string) (string, bool) {
+	bestIface := ""
+	bestMetric := -1
+	for _, line := range strings.Split(routes, "\n")[1:] {
+		fields := strings.Fields(line)
+		// Need at least 7 fields: Iface Destination Gateway Flags RefCnt Use Metric
+		if len(fields) < 7 || fields[1] != "00000000" {
+			continue
+		}
+		metric, err := strconv.Atoi(fields[6])
+		if err != nil {
+			continue
+		}
+		if bestMetric < 0 || metric < bestMetric {
+			bestMetric = metric
+			bestIface = fields[0]
+		}
+	}
+	return bestIface, bestIface != ""
+}
@@ -0,0 +1,21 @@
+package sync
+
+import (
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestParseDefaultRouteInterface(t *testing.T) {
+	routes := `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+wwan0	00000000	0101A8C0	0003	0	0	700	00000000	0	0	0
+wlan0	00000000	0100A8C0	0003	0	0	600	00000000	0	0	0
+wlan0	0000A8C0	00000000	0001	0	0	600	00FFFFFF	0	0	0
+`
+	iface, ok := parseDefaultRouteInterface(routes)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, iface, test.ShouldEqual, "wlan0")
+
+	_, ok = parseDefaultRouteInterface("Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT\n")
+	test.That(t, ok, test.ShouldBeFalse)
+}
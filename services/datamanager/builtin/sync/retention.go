@@ -0,0 +1,299 @@
+package sync
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	v1 "go.viam.com/api/app/datasync/v1"
+	goutils "go.viam.com/utils"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+)
+
+// RetentionCheckInterval is how often retention rules are evaluated, temporarily public for tests.
+var RetentionCheckInterval = 5 * time.Minute
+
+// RetentionConfig describes how long data is kept in the capture directory, and the other directories sync uploads
+// from, before it is deleted, whether or not it has been synced.
+type RetentionConfig struct {
+	// Rules delete the data which matches them once it is older than their maximum age. Data which matches several
+	// rules is kept for the longest of their maximum ages, and data which matches none is kept.
+	Rules []RetentionRule `json:"rules"`
+	// ProtectUnsyncedHours, when positive, keeps files younger than it from being deleted by retention rules or when
+	// the disk is full.
+	ProtectUnsyncedHours float64 `json:"protect_unsynced_hours,omitempty"`
+	// DryRun, when true, logs the files retention rules would delete instead of deleting them.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// RetentionRule matches captured data by the resource it was captured from, its method and its tags. Empty fields
+// match any data, and a rule with no fields set matches all files in the directories sync uploads from, including
+// arbitrary files.
+type RetentionRule struct {
+	// Name identifies the rule in logs and reports.
+	Name         string  `json:"name,omitempty"`
+	ResourceName string  `json:"resource_name,omitempty"`
+	Method       string  `json:"method,omitempty"`
+	Tag          string  `json:"tag,omitempty"`
+	MaxAgeHours  float64 `json:"max_age_hours"`
+}
+
+// Validate returns an error if a rule has no maximum age.
+func (c *RetentionConfig) Validate() error {
+	if c.ProtectUnsyncedHours < 0 {
+		return errors.New("retention protect_unsynced_hours can't be negative")
+	}
+	for _, rule := range c.Rules {
+		if rule.MaxAgeHours <= 0 {
+			return errors.Errorf("retention rule %q needs a positive max_age_hours", rule.String())
+		}
+	}
+	return nil
+}
+
+// protectUnsynced returns how long files are protected from deletion after they are last modified.
+func (c *RetentionConfig) protectUnsynced() time.Duration {
+	if c == nil || c.ProtectUnsyncedHours <= 0 {
+		return 0
+	}
+	return hoursToDuration(c.ProtectUnsyncedHours)
+}
+
+// String returns the name of the rule, or describes what it matches if it has none.
+func (r RetentionRule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	var matches []string
+	for _, field := range []struct{ key, value string }{
+		{"resource_name", r.ResourceName}, {"method", r.Method}, {"tag", r.Tag},
+	} {
+		if field.value != "" {
+			matches = append(matches, field.key+"="+field.value)
+		}
+	}
+	if len(matches) == 0 {
+		return "all data"
+	}
+	return strings.Join(matches, " ")
+}
+
+func (r RetentionRule) needsMetadata() bool {
+	return r.ResourceName != "" || r.Method != "" || r.Tag != ""
+}
+
+// matches returns true if data with metadata md matches the rule. md is nil for files which aren't capture files,
+// or whose metadata can't be read.
+func (r RetentionRule) matches(md *v1.DataCaptureMetadata) bool {
+	if !r.needsMetadata() {
+		return true
+	}
+	if md == nil {
+		return false
+	}
+	return (r.ResourceName == "" || r.ResourceName == md.GetComponentName()) &&
+		(r.Method == "" || r.Method == md.GetMethodName()) &&
+		(r.Tag == "" || slices.Contains(md.GetTags(), r.Tag))
+}
+
+// RetentionReport lists the files retention rules delete, or would delete in a dry run.
+type RetentionReport struct {
+	DryRun bool
+	Files  []RetentionDeletion
+	// Bytes is the total size of the files.
+	Bytes int64
+}
+
+// RetentionDeletion is a file deleted by a retention rule.
+type RetentionDeletion struct {
+	Path string
+	// Rule is the rule which kept the file the longest.
+	Rule string
+	Age  time.Duration
+	Size int64
+}
+
+// planRetention returns the files in captureDirs which are older than the rules they match allow. Files being
+// written or synced are skipped, and a file in more than one of the directories is only reported once. The metadata
+// of encrypted capture files is decrypted with keyring.
+func planRetention(
+	ctx context.Context,
+	captureDirs []string,
+	config RetentionConfig,
+	keyring *data.CaptureKeyring,
+	fileTracker *fileTracker,
+	now time.Time,
+) (RetentionReport, error) {
+	report := RetentionReport{DryRun: config.DryRun}
+	if len(config.Rules) == 0 {
+		return report, nil
+	}
+	needsMetadata := slices.ContainsFunc(config.Rules, RetentionRule.needsMetadata)
+	protectUnsynced := config.protectUnsynced()
+	planned := map[string]bool{}
+	var errs []error
+	for _, captureDir := range captureDirs {
+		errs = append(errs, filepath.WalkDir(captureDir, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				// the file may have been synced, or completed, since the walk started
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || isInProgressFile(path) || fileTracker.inProgress(path) || planned[path] {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			age := now.Sub(info.ModTime())
+			if age < protectUnsynced {
+				return nil
+			}
+
+			var md *v1.DataCaptureMetadata
+			if needsMetadata && filepath.Ext(path) == data.CompletedCaptureFileExt {
+				md = readCaptureMetadata(path, keyring)
+			}
+			var keptBy *RetentionRule
+			for i, rule := range config.Rules {
+				if rule.matches(md) && (keptBy == nil || rule.MaxAgeHours > keptBy.MaxAgeHours) {
+					keptBy = &config.Rules[i]
+				}
+			}
+			if keptBy == nil || age <= hoursToDuration(keptBy.MaxAgeHours) {
+				return nil
+			}
+			planned[path] = true
+			report.Files = append(report.Files, RetentionDeletion{Path: path, Rule: keptBy.String(), Age: age, Size: info.Size()})
+			report.Bytes += info.Size()
+			return nil
+		}))
+	}
+	return report, multierr.Combine(errs...)
+}
+
+// enforceRetention deletes the files in report, unless it is a dry run, returning the number of files deleted.
+func enforceRetention(report RetentionReport, fileTracker *fileTracker, logger logging.Logger) (int, error) {
+	if report.DryRun {
+		for _, file := range report.Files {
+			logger.Debugw("retention dry run would delete file", "file", file.Path, "rule", file.Rule, "age", file.Age.String())
+		}
+		if len(report.Files) > 0 {
+			logger.Infof("retention dry run would delete %d files (%d bytes)", len(report.Files), report.Bytes)
+		}
+		return 0, nil
+	}
+
+	deleted := 0
+	var errs []error
+	for _, file := range report.Files {
+		// skip files sync has started uploading since the report was made
+		if !fileTracker.markInProgress(file.Path) {
+			continue
+		}
+		err := os.Remove(file.Path)
+		fileTracker.unmarkInProgress(file.Path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		if err == nil {
+			logger.Debugw("retention deleted file", "file", file.Path, "rule", file.Rule, "age", file.Age.String())
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		logger.Infof("retention rules deleted %d files", deleted)
+	}
+	return deleted, errors.Wrap(multierr.Combine(errs...), "error deleting files past their retention")
+}
+
+func enforceRetentionOnSchedule(
+	ctx context.Context,
+	fileTracker *fileTracker,
+	captureDirs []string,
+	config RetentionConfig,
+	keyring *data.CaptureKeyring,
+	clock clock.Clock,
+	logger logging.Logger,
+	deletedFileCount *atomic.Int64,
+) {
+	t := clock.Ticker(RetentionCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			report, err := planRetention(ctx, captureDirs, config, keyring, fileTracker, clock.Now())
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					logger.Errorw("error evaluating retention rules", "error", err)
+				}
+				continue
+			}
+			count, err := enforceRetention(report, fileTracker, logger)
+			if err != nil {
+				logger.Error(err)
+			}
+			deletedFileCount.Add(int64(count))
+		}
+	}
+}
+
+// RetentionReport returns the files the configured retention rules would delete now.
+func (s *Sync) RetentionReport(ctx context.Context) (RetentionReport, error) {
+	s.configMu.Lock()
+	config := s.config
+	s.configMu.Unlock()
+	if config.Retention == nil || len(config.Retention.Rules) == 0 {
+		return RetentionReport{}, errors.New("no retention rules are configured")
+	}
+	report, err := planRetention(ctx, config.SyncPaths(), *config.Retention, config.Keyring, s.fileTracker, s.clock.Now())
+	// the report describes what would be deleted rather than what was
+	report.DryRun = true
+	return report, err
+}
+
+// readCaptureMetadata returns the metadata of the capture file at path, or nil if it can't be read.
+func readCaptureMetadata(path string, keyring *data.CaptureKeyring) *v1.DataCaptureMetadata {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer goutils.UncheckedErrorFunc(f.Close)
+	captureFile, err := data.ReadCaptureFile(f, data.WithCaptureKeyring(keyring))
+	if err != nil {
+		return nil
+	}
+	return captureFile.ReadMetadata()
+}
+
+func isInProgressFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == data.InProgressCaptureFileExt || ext == data.InProgressSequenceFileExt
+}
+
+func hoursToDuration(hours float64) time.Duration {
+	return time.Duration(hours * float64(time.Hour))
+}
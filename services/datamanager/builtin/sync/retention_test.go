@@ -0,0 +1,170 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"go.viam.com/utils/testutils"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/internal/cloud"
+	"go.viam.com/rdk/internal/testutils/inject"
+	"go.viam.com/rdk/logging"
+)
+
+// writeAgedCaptureFile writes a completed capture file with md to dir, last modified age before now.
+func writeAgedCaptureFile(t *testing.T, dir string, md *v1.DataCaptureMetadata, now time.Time, age time.Duration) string {
+	t.Helper()
+	f, err := data.NewCaptureFile(dir, md)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+	// capture files are named by the time they are created, so wait for the next name
+	time.Sleep(time.Millisecond)
+	test.That(t, os.Chtimes(f.GetPath(), now.Add(-age), now.Add(-age)), test.ShouldBeNil)
+	return f.GetPath()
+}
+
+func TestPlanRetention(t *testing.T) {
+	now := time.Now()
+	captureDir := t.TempDir()
+	camera := &v1.DataCaptureMetadata{ComponentName: "camera-1", MethodName: "ReadImage"}
+	incident := &v1.DataCaptureMetadata{ComponentName: "camera-1", MethodName: "ReadImage", Tags: []string{"incident"}}
+	sensor := &v1.DataCaptureMetadata{ComponentName: "sensor-1", MethodName: "Readings"}
+
+	oldCamera := writeAgedCaptureFile(t, captureDir, camera, now, 48*time.Hour)
+	recentCamera := writeAgedCaptureFile(t, captureDir, camera, now, time.Hour)
+	oldIncident := writeAgedCaptureFile(t, captureDir, incident, now, 48*time.Hour)
+	expiredIncident := writeAgedCaptureFile(t, captureDir, incident, now, 31*24*time.Hour)
+	oldSensor := writeAgedCaptureFile(t, captureDir, sensor, now, 48*time.Hour)
+	notes := writeTestFile(t, captureDir, "notes.txt", []byte("notes"))
+	test.That(t, os.Chtimes(notes, now.Add(-48*time.Hour), now.Add(-48*time.Hour)), test.ShouldBeNil)
+
+	config := RetentionConfig{
+		Rules: []RetentionRule{
+			{ResourceName: "camera-1", MaxAgeHours: 24},
+			{Name: "incidents", Tag: "incident", MaxAgeHours: 30 * 24},
+		},
+	}
+	paths := func(report RetentionReport) []string {
+		var paths []string
+		for _, file := range report.Files {
+			paths = append(paths, file.Path)
+		}
+		return paths
+	}
+
+	t.Run("deletes data older than the longest rule it matches", func(t *testing.T) {
+		report, err := planRetention(context.Background(), []string{captureDir}, config, nil, newFileTracker(), now)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, paths(report), test.ShouldResemble, []string{oldCamera, expiredIncident})
+		test.That(t, report.Files[0].Rule, test.ShouldEqual, "resource_name=camera-1")
+		test.That(t, report.Files[1].Rule, test.ShouldEqual, "incidents")
+		test.That(t, report.Files[0].Age, test.ShouldAlmostEqual, 48*time.Hour, time.Second)
+	})
+
+	t.Run("a rule without selectors matches all files", func(t *testing.T) {
+		config := config
+		config.Rules = append([]RetentionRule{{MaxAgeHours: 36}}, config.Rules...)
+		report, err := planRetention(context.Background(), []string{captureDir}, config, nil, newFileTracker(), now)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, paths(report), test.ShouldResemble, []string{oldCamera, expiredIncident, oldSensor, notes})
+		test.That(t, report.Files[2].Rule, test.ShouldEqual, "all data")
+	})
+
+	t.Run("evaluates every directory sync uploads from", func(t *testing.T) {
+		additional := t.TempDir()
+		oldAdditional := writeAgedCaptureFile(t, additional, camera, now, 48*time.Hour)
+		// a directory given twice has its files reported once
+		report, err := planRetention(context.Background(), []string{captureDir, additional, filepath.Dir(oldCamera)},
+			config, nil, newFileTracker(), now)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, paths(report), test.ShouldResemble, []string{oldCamera, expiredIncident, oldAdditional})
+	})
+
+	t.Run("protects unsynced data and files being synced", func(t *testing.T) {
+		config := config
+		config.Rules = []RetentionRule{{MaxAgeHours: 0.5}}
+		config.ProtectUnsyncedHours = 36
+		ft := newFileTracker()
+		ft.markInProgress(oldCamera)
+		report, err := planRetention(context.Background(), []string{captureDir}, config, nil, ft, now)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, paths(report), test.ShouldResemble, []string{oldIncident, expiredIncident, oldSensor, notes})
+		test.That(t, paths(report), test.ShouldNotContain, recentCamera)
+	})
+
+	t.Run("dry run doesn't delete", func(t *testing.T) {
+		config := config
+		config.DryRun = true
+		report, err := planRetention(context.Background(), []string{captureDir}, config, nil, newFileTracker(), now)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, report.DryRun, test.ShouldBeTrue)
+		deleted, err := enforceRetention(report, newFileTracker(), logging.NewTestLogger(t))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, deleted, test.ShouldEqual, 0)
+		_, err = os.Stat(oldCamera)
+		test.That(t, err, test.ShouldBeNil)
+	})
+
+	t.Run("deletes the files in the report", func(t *testing.T) {
+		report, err := planRetention(context.Background(), []string{captureDir}, config, nil, newFileTracker(), now)
+		test.That(t, err, test.ShouldBeNil)
+		deleted, err := enforceRetention(report, newFileTracker(), logging.NewTestLogger(t))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, deleted, test.ShouldEqual, 2)
+		for _, path := range []string{oldCamera, expiredIncident} {
+			_, err := os.Stat(path)
+			test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+		}
+		for _, path := range []string{recentCamera, oldIncident, oldSensor, notes} {
+			_, err := os.Stat(path)
+			test.That(t, err, test.ShouldBeNil)
+		}
+	})
+}
+
+func TestEnforceRetentionOnSchedule(t *testing.T) {
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	captureDir := t.TempDir()
+	old := writeAgedCaptureFile(t, captureDir, &v1.DataCaptureMetadata{ComponentName: "camera-1"}, clk.Now(), 48*time.Hour)
+
+	s := newTestSync(t, MockDataSyncServiceClient{T: t}, true)
+	s.clock = clk
+	config := Config{
+		CaptureDir:            captureDir,
+		CaptureDisabled:       true,
+		ScheduledSyncDisabled: true,
+		Retention:             &RetentionConfig{Rules: []RetentionRule{{ResourceName: "camera-1", MaxAgeHours: 24}}},
+	}
+	s.Reconfigure(context.Background(), config, &inject.CloudConnectionService{AcquireConnectionErr: cloud.ErrNotCloudManaged})
+
+	report, err := s.RetentionReport(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, report.DryRun, test.ShouldBeTrue)
+	test.That(t, report.Files, test.ShouldHaveLength, 1)
+
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		// the ticker may not have been created yet, so keep advancing the clock until it fires
+		clk.Add(RetentionCheckInterval)
+		_, err := os.Stat(old)
+		test.That(tb, os.IsNotExist(err), test.ShouldBeTrue)
+		test.That(tb, s.GetStats().FilesDeletedByRetention, test.ShouldEqual, 1)
+	})
+	_, err = os.Stat(filepath.Dir(old))
+	test.That(t, err, test.ShouldBeNil)
+}
+
+func TestRetentionConfigValidate(t *testing.T) {
+	test.That(t, (&RetentionConfig{Rules: []RetentionRule{{Tag: "incident", MaxAgeHours: 720}}}).Validate(), test.ShouldBeNil)
+	test.That(t, (&RetentionConfig{Rules: []RetentionRule{{Tag: "incident"}}}).Validate(), test.ShouldBeError,
+		`retention rule "tag=incident" needs a positive max_age_hours`)
+	test.That(t, (&RetentionConfig{ProtectUnsyncedHours: -1}).Validate(), test.ShouldBeError,
+		"retention protect_unsynced_hours can't be negative")
+}
@@ -4,10 +4,13 @@ package sync
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -65,6 +68,7 @@ type dataTypeUploadStats struct {
 // FTDCStats represents upload and deleted file metric values for a given moment. Returned by Sync.GetStats().
 type FTDCStats struct {
 	FilesDeletedToFreeSpace int64
+	FilesDeletedByRetention int64
 	Upload                  FTDCUploadStats
 }
 
@@ -108,9 +112,17 @@ type Sync struct {
 	clock             clock.Clock
 	uploadStats       *uploadStats
 	deletedFileCount  atomic.Int64
+	// retentionDeletedFileCount is the number of files deleted by retention rules.
+	retentionDeletedFileCount atomic.Int64
 
 	configMu sync.Mutex
 	config   Config
+	// destination is where files are uploaded to instead of the cloud, if one is configured.
+	destination Destination
+	// limiter caps the upload rate, if a maximum is configured.
+	limiter *bandwidthLimiter
+	// onWiFi reports whether binary data can be synced when DeferBinarySyncUntilWiFi is configured.
+	onWiFi func() bool
 
 	configCtx        context.Context
 	configCancelFunc func()
@@ -121,6 +133,8 @@ type Sync struct {
 	cloudConnManager *goutils.StoppableWorkers
 	// FileDeletingWorkers is only public for tests
 	FileDeletingWorkers *goutils.StoppableWorkers
+	// RetentionWorkers is only public for tests
+	RetentionWorkers *goutils.StoppableWorkers
 	// MaxSyncThreads only exists for tests
 	MaxSyncThreads int
 }
@@ -146,7 +160,9 @@ func New(
 		Scheduler:           goutils.NewBackgroundStoppableWorkers(),
 		cloudConn:           cloudConn{ready: make(chan struct{})},
 		FileDeletingWorkers: goutils.NewBackgroundStoppableWorkers(),
+		RetentionWorkers:    goutils.NewBackgroundStoppableWorkers(),
 		uploadStats:         &uploadStats,
+		onWiFi:              onWiFi,
 	}
 	return &s
 }
@@ -181,6 +197,7 @@ func (s *Sync) Reconfigure(_ context.Context, config Config, cloudConnSvc cloud.
 	}
 	s.configCancelFunc()
 	s.FileDeletingWorkers.Stop()
+	s.RetentionWorkers.Stop()
 	s.Scheduler.Stop()
 	s.ScheduledTicker = nil
 	// wait for workers to stop
@@ -189,6 +206,16 @@ func (s *Sync) Reconfigure(_ context.Context, config Config, cloudConnSvc cloud.
 	// update config and reset config context
 	s.configMu.Lock()
 	s.config = config
+	s.destination = nil
+	if config.Destination != nil {
+		destination, err := NewDestination(*config.Destination)
+		if err != nil {
+			s.logger.Errorw("failed to create sync destination, files will not be synced", "error", err)
+			destination = brokenDestination{err: err}
+		}
+		s.destination = destination
+	}
+	s.limiter = newBandwidthLimiter(s.clock, config.MaximumSyncBytesPerSec)
 	s.configCtx, s.configCancelFunc = context.WithCancel(context.Background())
 	s.configMu.Unlock()
 
@@ -221,12 +248,20 @@ func (s *Sync) Reconfigure(_ context.Context, config Config, cloudConnSvc cloud.
 				config.DeleteEveryNthWhenDiskFull,
 				config.DiskUsageDeletionThreshold,
 				config.CaptureDirDeletionThreshold,
+				config.Retention.protectUnsynced(),
 				s.clock,
 				s.logger,
 				&s.deletedFileCount,
 			)
 		})
 	}
+	if config.Retention != nil && len(config.Retention.Rules) > 0 {
+		s.RetentionWorkers = goutils.NewBackgroundStoppableWorkers(func(ctx context.Context) {
+			enforceRetentionOnSchedule(
+				ctx, s.fileTracker, config.SyncPaths(), *config.Retention, config.Keyring, s.clock, s.logger,
+				&s.retentionDeletedFileCount)
+		})
+	}
 }
 
 // GetStats returns cumulative file deletion and upload metrics.
@@ -234,6 +269,7 @@ func (s *Sync) GetStats() FTDCStats {
 	return FTDCStats{
 		// File deletion metric.
 		FilesDeletedToFreeSpace: s.deletedFileCount.Load(),
+		FilesDeletedByRetention: s.retentionDeletedFileCount.Load(),
 
 		Upload: FTDCUploadStats{
 			// Upload metrics - arbitrary files.
@@ -260,6 +296,7 @@ func (s *Sync) GetStats() FTDCStats {
 func (s *Sync) Close() {
 	s.configCancelFunc()
 	s.FileDeletingWorkers.Stop()
+	s.RetentionWorkers.Stop()
 	s.Scheduler.Stop()
 	s.workersWg.Wait()
 	if s.cloudConnManager != nil {
@@ -279,14 +316,16 @@ func (s *Sync) CloudConnReady() chan struct{} {
 // If automated sync is also enabled, calling Sync will upload the files,
 // regardless of whether or not is the scheduled time.
 func (s *Sync) Sync(ctx context.Context, _ map[string]interface{}) error {
-	select {
-	case <-s.cloudConn.ready:
-	default:
-		return errors.New("not connected to the cloud")
-	}
 	s.configMu.Lock()
 	config := s.config
 	s.configMu.Unlock()
+	if config.Destination == nil {
+		select {
+		case <-s.cloudConn.ready:
+		default:
+			return errors.New("not connected to the cloud")
+		}
+	}
 	return s.walkDirsAndSendFilesToSync(ctx, config)
 }
 
@@ -427,6 +466,30 @@ func (s *Sync) syncFile(config Config, filePath string) {
 	}
 	defer s.fileTracker.unmarkInProgress(filePath)
 
+	// Reconfigure replaces the limiter and destination, so use the ones configured when the sync started.
+	s.configMu.Lock()
+	limiter := s.limiter
+	destination := s.destination
+	s.configMu.Unlock()
+
+	if limiter != nil {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				s.logger.Errorw("error reading file", "error", err)
+			}
+			return
+		}
+		if err := limiter.wait(s.configCtx, info.Size()); err != nil {
+			return
+		}
+	}
+
+	if destination != nil {
+		s.syncToDestination(config, destination, filePath)
+		return
+	}
+
 	// Sequence files upload via CreateSequence; dispatch by path before opening so we don't
 	// leak a file descriptor on the sequence path (which does its own os.ReadFile).
 	if isSequenceFile(filePath) {
@@ -446,7 +509,7 @@ func (s *Sync) syncFile(config Config, filePath string) {
 	}
 
 	if data.IsDataCaptureFile(f) {
-		s.syncDataCaptureFile(f, config.CaptureDir, s.logger)
+		s.syncDataCaptureFile(f, config.CaptureDir, config.Keyring, s.logger)
 	} else {
 		if _, err = s.syncArbitraryFile(s.configCtx, f, config.Tags, []string{}, config.FileLastModifiedMillis, s.logger); err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -458,8 +521,14 @@ func (s *Sync) syncFile(config Config, filePath string) {
 	}
 }
 
-func (s *Sync) syncDataCaptureFile(f *os.File, captureDir string, logger logging.Logger) {
-	captureFile, err := data.ReadCaptureFile(f)
+func (s *Sync) syncDataCaptureFile(f *os.File, captureDir string, keyring *data.CaptureKeyring, logger logging.Logger) {
+	captureFile, err := data.ReadCaptureFile(f, data.WithCaptureKeyring(keyring))
+	// a file encrypted with a key which isn't configured is left to be synced once the key is
+	if errors.Is(err, data.ErrCaptureKeyUnavailable) {
+		logger.Warnw("skipping sync of encrypted capture file", "filename", f.Name(), "error", err)
+		goutils.UncheckedError(f.Close())
+		return
+	}
 	// if you can't read the capture file's metadata field, close & move it to the failed directory
 	if err != nil {
 		cause := errors.Wrap(err, "ReadCaptureFile failed")
@@ -583,6 +652,79 @@ func (s *Sync) syncArbitraryFile(
 	return uploadedID, nil
 }
 
+// syncToDestination uploads a file to the configured destination, named by its path relative to the sync directory
+// it is in, deleting it once it is uploaded or moving it to the failed directory if the destination rejects it.
+func (s *Sync) syncToDestination(config Config, destination Destination, filePath string) {
+	syncDir := filepath.Dir(filePath)
+	for _, dir := range config.SyncPaths() {
+		if rel, err := filepath.Rel(dir, filePath); err == nil && !strings.HasPrefix(rel, "..") {
+			syncDir = dir
+			break
+		}
+	}
+	name, err := filepath.Rel(syncDir, filePath)
+	if err != nil {
+		s.logger.Errorw("error naming file for sync destination", "error", err)
+		return
+	}
+	name = filepath.ToSlash(name)
+
+	//nolint:gosec
+	f, err := os.Open(filePath)
+	if err != nil {
+		// Don't log if the file does not exist, because that means it was successfully synced and deleted
+		// in between paths being built and this executing.
+		if !errors.Is(err, os.ErrNotExist) {
+			s.logger.Errorw("error opening file", "error", err)
+		}
+		return
+	}
+	stats := &s.uploadStats.arbitrary
+	if isCompletedCaptureFile(filePath) {
+		stats = &s.uploadStats.tabular
+		if captureFile, err := data.ReadCaptureFile(f, data.WithCaptureKeyring(config.Keyring)); err == nil &&
+			captureFile.ReadMetadata().GetType() == v1.DataType_DATA_TYPE_BINARY_SENSOR {
+			stats = &s.uploadStats.binary
+		}
+	}
+	info, err := f.Stat()
+	if err != nil {
+		s.logger.Errorw("error reading file", "error", err)
+		goutils.UncheckedError(f.Close())
+		return
+	}
+
+	retry := newExponentialRetry(s.configCtx, s.clock, s.logger, filePath, func(ctx context.Context) (uint64, error) {
+		bytesUploaded, err := destination.Upload(ctx, name, io.NewSectionReader(f, 0, info.Size()))
+		if err != nil {
+			return 0, errors.Wrapf(err, "error uploading %s to sync destination", filePath)
+		}
+		return bytesUploaded, nil
+	})
+	bytesUploaded, err := retry.run()
+	if closeErr := f.Close(); closeErr != nil {
+		s.logger.Error(errors.Wrap(closeErr, "error closing file").Error())
+	}
+	if err != nil {
+		// if we stopped due to a cancelled context,
+		// return without deleting the file or moving it to the failed directory
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err := moveFailedData(filePath, syncDir, err, s.logger); err != nil {
+			s.logger.Error(err)
+		}
+		stats.uploadFailedFileCount.Add(1)
+		return
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		s.logger.Error(errors.Wrapf(err, "error deleting file %s", filePath).Error())
+	}
+	stats.uploadedFileCount.Add(1)
+	stats.completedUploadBytes.Add(bytesUploaded)
+}
+
 // UploadBinaryDataToDatasets simultaneously uploads binary data and adds it to a dataset.
 func (s *Sync) UploadBinaryDataToDatasets(ctx context.Context, binaryData []byte, datasetIDs, tags []string, mimeType v1.MimeType) error {
 	errChan := make(chan error, 1)
@@ -667,13 +809,15 @@ func (s *Sync) runScheduler(ctx context.Context, tkr *clock.Ticker, config Confi
 		}
 
 		// wait for the cloud connection to be ready
-		// or the scheduler to be cancelled
-		select {
-		case <-ctx.Done():
-			return
-		case <-s.cloudConn.ready:
-			if !readyLogged {
-				readyLogged = true
+		// or the scheduler to be cancelled, unless syncing to another destination
+		if config.Destination == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.cloudConn.ready:
+				if !readyLogged {
+					readyLogged = true
+				}
 			}
 		}
 
@@ -682,8 +826,7 @@ func (s *Sync) runScheduler(ctx context.Context, tkr *clock.Ticker, config Confi
 			return
 		case <-tkr.C:
 			shouldSync := ReadyToSyncDirectories(ctx, config, s.logger)
-			state := s.cloudConn.conn.GetState()
-			if state != connectivity.Ready {
+			if state := s.cloudConnState(config); state != connectivity.Ready {
 				if now := s.clock.Now(); now.Sub(lastNotSyncedLog) >= time.Minute {
 					lastNotSyncedLog = now
 					logf := s.logger.Debugf
@@ -702,6 +845,13 @@ func (s *Sync) runScheduler(ctx context.Context, tkr *clock.Ticker, config Confi
 				}
 				continue
 			}
+			if !config.InSyncWindow(s.clock.Now()) {
+				if now := s.clock.Now(); now.Sub(lastNotSyncedLog) >= time.Minute {
+					lastNotSyncedLog = now
+					s.logger.Infof("data manager: NOT syncing data as it is outside of the sync windows %+v", config.SyncWindows)
+				}
+				continue
+			}
 			// syncing; reset the throttle so a future not-synced reason logs immediately.
 			lastNotSyncedLog = time.Time{}
 
@@ -712,59 +862,132 @@ func (s *Sync) runScheduler(ctx context.Context, tkr *clock.Ticker, config Confi
 	}
 }
 
+// walkDirsAndSendFilesToSync sends the files in the sync paths which are ready to sync to the sync workers, in
+// order of their capture directory's priority. Binary data is left for a later sync if it is deferred until the
+// machine is on Wi-Fi and it isn't.
+//
 // returns early with an error if either ctx is cancelled or if the reconfigure is called
 // while walkDirsAndSendFilesToSync.
 func (s *Sync) walkDirsAndSendFilesToSync(ctx context.Context, config Config) error {
 	s.flushCollectors()
-	var errs []error
+	deferBinary := config.DeferBinarySyncUntilWiFi && !s.onWiFi()
+
+	// Capture directories with a priority are walked on their own, those above 0 before the sync paths and those
+	// below 0 after them, so files are sent as they are found rather than collected and sorted first.
+	var prioritized []string
+	for dir := range config.CapturePriorities {
+		prioritized = append(prioritized, dir)
+	}
+	sort.Slice(prioritized, func(i, j int) bool {
+		pi, pj := config.CapturePriorities[prioritized[i]], config.CapturePriorities[prioritized[j]]
+		if pi != pj {
+			return pi > pj
+		}
+		return prioritized[i] < prioritized[j]
+	})
+	firstLow := sort.Search(len(prioritized), func(i int) bool {
+		return config.CapturePriorities[prioritized[i]] < 0
+	})
+
+	dirs := append([]string{}, prioritized[:firstLow]...)
 	for _, dir := range config.SyncPaths() {
-		s.logger.Debugf("syncing from: %s", dir)
-		loggedDirPaths := map[string]bool{}
-		// Retrieve all files in capture dir and send them to the syncer
-		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			if err := ctx.Err(); err != nil {
-				// if the context is cancelled, bail out
-				return filepath.SkipAll
-			}
+		if _, ok := config.CapturePriorities[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, prioritized[firstLow:]...)
 
-			if err := s.configCtx.Err(); err != nil {
-				return filepath.SkipAll
-			}
+	var deferred int
+	var errs []error
+	for _, dir := range dirs {
+		n, err := s.walkDirAndSendFilesToSync(ctx, config, dir, deferBinary)
+		deferred += n
+		errs = append(errs, err)
+	}
+	if deferred > 0 {
+		s.logger.Debugf("deferring sync of %d binary files until on Wi-Fi", deferred)
+	}
+	errs = append(errs, ctx.Err(), s.configCtx.Err())
+	return multierr.Combine(errs...)
+}
 
-			if err != nil {
-				s.logger.Debugf("walkDirsAndSendFilesToSync ignoring error walking path: %s, err: %v", path, err)
-				return nil
-			}
+// walkDirAndSendFilesToSync sends the files in dir which are ready to sync to the sync workers as they are walked,
+// skipping the capture directories with a priority within it. It returns the number of binary files deferred until
+// the machine is on Wi-Fi.
+func (s *Sync) walkDirAndSendFilesToSync(ctx context.Context, config Config, dir string, deferBinary bool) (int, error) {
+	s.logger.Debugf("syncing from: %s", dir)
+	loggedDirPaths := map[string]bool{}
+	var deferred int
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			// if the context is cancelled, bail out
+			return filepath.SkipAll
+		}
+
+		if err := s.configCtx.Err(); err != nil {
+			return filepath.SkipAll
+		}
+
+		if err != nil {
+			s.logger.Debugf("walkDirsAndSendFilesToSync ignoring error walking path: %s, err: %v", path, err)
+			return nil
+		}
+
+		// Do not sync the files in the corrupted data directory or in the directory that holds files
+		// that are simultaneously uploaded and added to a dataset.
+		if info.IsDir() && (info.Name() == FailedDir || info.Name() == DatasetDir) {
+			return filepath.SkipDir
+		}
 
-			// Do not sync the files in the corrupted data directory or in the directory that holds files
-			// that are simultaneously uploaded and added to a dataset.
-			if info.IsDir() && (info.Name() == FailedDir || info.Name() == DatasetDir) {
+		if info.IsDir() {
+			// capture directories with a priority are walked on their own
+			if _, ok := config.CapturePriorities[path]; ok && path != dir {
 				return filepath.SkipDir
 			}
+			return nil
+		}
 
-			if info.IsDir() {
+		// If a non data capture owned file was modified within the past lastModifiedMillis, do not sync it (data
+		// may still be being written).
+		// When using a mock clock in tests, s.clock.Since(info.ModTime()) can be negative since the file system will still use the system clock.
+		// Take max(timeSinceMod, 0) to account for this.
+		timeSinceMod := max(s.clock.Since(info.ModTime()), 0)
+		if readyToSyncFile(timeSinceMod, path, info, config.FileLastModifiedMillis, s.fileTracker) {
+			dirPath := filepath.Dir(path)
+			if !loggedDirPaths[dirPath] {
+				loggedDirPaths[dirPath] = true
+				s.logger.Debugf("syncing from subdirectory: %s", dirPath)
+			}
+			if deferBinary && isBinaryData(path) {
+				deferred++
 				return nil
 			}
+			s.sendToSync(ctx, path)
+		}
+		return nil
+	})
+	return deferred, err
+}
 
-			// If a non data capture owned file was modified within the past lastModifiedMillis, do not sync it (data
-			// may still be being written).
-			// When using a mock clock in tests, s.clock.Since(info.ModTime()) can be negative since the file system will still use the system clock.
-			// Take max(timeSinceMod, 0) to account for this.
-			timeSinceMod := max(s.clock.Since(info.ModTime()), 0)
-			if readyToSyncFile(timeSinceMod, path, info, config.FileLastModifiedMillis, s.fileTracker) {
-				dirPath := filepath.Dir(path)
-				if !loggedDirPaths[dirPath] {
-					loggedDirPaths[dirPath] = true
-					s.logger.Debugf("syncing from subdirectory: %s", dirPath)
-				}
-				s.sendToSync(ctx, path)
-			}
-			return nil
-		})
-		errs = append(errs, err)
+// cloudConnState returns the state of the cloud connection, which is always ready when syncing to another
+// destination.
+func (s *Sync) cloudConnState(config Config) connectivity.State {
+	if config.Destination != nil {
+		return connectivity.Ready
 	}
-	errs = append(errs, ctx.Err(), s.configCtx.Err())
-	return multierr.Combine(errs...)
+	return s.cloudConn.conn.GetState()
+}
+
+// isBinaryData returns true if path is a binary capture file or an arbitrary file, which is uploaded as binary data.
+// A capture file's data type is that of the method its collector captures, which names the directory it is in.
+func isBinaryData(path string) bool {
+	if isSequenceFile(path) {
+		return false
+	}
+	if !isCompletedCaptureFile(path) {
+		return true
+	}
+	return data.MethodToCaptureType(filepath.Base(filepath.Dir(path))) == data.CaptureTypeBinary
 }
 
 func readyToSyncFile(timeSinceMod time.Duration, path string, info fs.FileInfo, fileLastModifiedMillis int, fileTracker *fileTracker) bool {
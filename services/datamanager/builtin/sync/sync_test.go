@@ -0,0 +1,142 @@
+package sync
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+)
+
+func writeTestCaptureFile(t *testing.T, dir string, dataType v1.DataType) string {
+	t.Helper()
+	test.That(t, os.MkdirAll(dir, 0o700), test.ShouldBeNil)
+	f, err := data.NewCaptureFile(dir, &v1.DataCaptureMetadata{Type: dataType})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+data.CompletedCaptureFileExt))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, paths, test.ShouldHaveLength, 1)
+	return paths[0]
+}
+
+func TestWalkDirsAndSendFilesToSync(t *testing.T) {
+	captureDir := t.TempDir()
+	alertsDir := filepath.Join(captureDir, "rdk_component_sensor", "alerts", "Readings")
+	imagesDir := filepath.Join(captureDir, "rdk_component_camera", "cam", "ReadImage")
+	alerts := writeTestCaptureFile(t, alertsDir, v1.DataType_DATA_TYPE_TABULAR_SENSOR)
+	// the data type comes from the directory's method, so the file's own metadata is not read
+	images := writeTestCaptureFile(t, imagesDir, v1.DataType_DATA_TYPE_UNSPECIFIED)
+	readings := writeTestCaptureFile(t, filepath.Join(captureDir, "rdk_component_sensor", "readings", "Readings"),
+		v1.DataType_DATA_TYPE_TABULAR_SENSOR)
+	notes := writeTestFile(t, captureDir, "notes.txt", []byte("notes"))
+	config := Config{
+		CaptureDir: captureDir,
+		CapturePriorities: map[string]int{
+			alertsDir: 10,
+			imagesDir: -1,
+		},
+	}
+
+	walk := func(s *Sync, config Config) []string {
+		done := make(chan error)
+		go func() {
+			done <- s.walkDirsAndSendFilesToSync(context.Background(), config)
+		}()
+		var paths []string
+		for {
+			select {
+			case path := <-s.filesToSync:
+				paths = append(paths, path)
+			case err := <-done:
+				test.That(t, err, test.ShouldBeNil)
+				return paths
+			}
+		}
+	}
+
+	t.Run("sends files in order of priority", func(t *testing.T) {
+		s := newTestSync(t, MockDataSyncServiceClient{T: t}, true)
+		// files of the same priority keep the order they were walked in
+		test.That(t, walk(s, config), test.ShouldResemble, []string{alerts, notes, readings, images})
+	})
+
+	t.Run("defers binary data until on Wi-Fi", func(t *testing.T) {
+		s := newTestSync(t, MockDataSyncServiceClient{T: t}, true)
+		wifi := false
+		s.onWiFi = func() bool { return wifi }
+		config := config
+		config.DeferBinarySyncUntilWiFi = true
+		test.That(t, walk(s, config), test.ShouldResemble, []string{alerts, readings})
+
+		wifi = true
+		test.That(t, walk(s, config), test.ShouldResemble, []string{alerts, notes, readings, images})
+	})
+}
+
+func TestSyncEncryptedCaptureFile(t *testing.T) {
+	key := make([]byte, data.CaptureKeySize)
+	_, err := rand.Read(key)
+	test.That(t, err, test.ShouldBeNil)
+	keyring, err := data.NewCaptureKeyring(key)
+	test.That(t, err, test.ShouldBeNil)
+
+	captureDir := t.TempDir()
+	path := writeTestCaptureFile(t, captureDir, v1.DataType_DATA_TYPE_TABULAR_SENSOR)
+	cf, err := data.NewCaptureFile(captureDir, &v1.DataCaptureMetadata{
+		ComponentName: "sensor-1",
+		Type:          v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+	}, data.WithCaptureKeyring(keyring))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, cf.WriteNext(&v1.SensorData{
+		Metadata: &v1.SensorMetadata{},
+		Data:     &v1.SensorData_Struct{Struct: &structpb.Struct{}},
+	}), test.ShouldBeNil)
+	test.That(t, cf.Close(), test.ShouldBeNil)
+	test.That(t, os.Remove(path), test.ShouldBeNil)
+	path = cf.GetPath()
+
+	uploads := make(chan *v1.DataCaptureUploadRequest, 1)
+	s := newTestSync(t, MockDataSyncServiceClient{
+		T: t,
+		DataCaptureUploadFunc: func(
+			ctx context.Context,
+			in *v1.DataCaptureUploadRequest,
+			opts ...grpc.CallOption,
+		) (*v1.DataCaptureUploadResponse, error) {
+			uploads <- in
+			return &v1.DataCaptureUploadResponse{}, nil
+		},
+	}, true)
+	logger := logging.NewTestLogger(t)
+
+	t.Run("leaves files encrypted with an unavailable key in place", func(t *testing.T) {
+		//nolint:gosec
+		f, err := os.Open(path)
+		test.That(t, err, test.ShouldBeNil)
+		s.syncDataCaptureFile(f, captureDir, nil, logger)
+		_, err = os.Stat(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, uploads, test.ShouldBeEmpty)
+	})
+
+	t.Run("uploads decrypted data", func(t *testing.T) {
+		//nolint:gosec
+		f, err := os.Open(path)
+		test.That(t, err, test.ShouldBeNil)
+		s.syncDataCaptureFile(f, captureDir, keyring, logger)
+		upload := <-uploads
+		test.That(t, upload.GetMetadata().GetComponentName(), test.ShouldEqual, "sensor-1")
+		test.That(t, upload.GetSensorContents(), test.ShouldHaveLength, 1)
+		_, err = os.Stat(path)
+		test.That(t, err, test.ShouldBeError)
+	})
+}
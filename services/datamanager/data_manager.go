@@ -172,6 +172,45 @@ type DataCaptureConfig struct {
 	Disabled           bool                   `json:"disabled"`
 	Tags               []string               `json:"tags,omitempty"`
 	CaptureDirectory   string                 `json:"capture_directory"`
+	// Triggered, when set, only persists the readings captured around a trigger.
+	Triggered *TriggeredCaptureConfig `json:"triggered,omitempty"`
+	// SyncPriority orders sync: files captured by methods with a higher priority are synced before those with a
+	// lower one, e.g. alerts before images. Defaults to 0.
+	SyncPriority int `json:"sync_priority,omitempty"`
+	// ChangeDetection, when set, only captures the samples which changed meaningfully since the last one captured.
+	ChangeDetection *ChangeDetectionConfig `json:"change_detection,omitempty"`
+}
+
+// ChangeDetectionConfig suppresses the samples of a capture method which haven't changed since the last one captured.
+// Numbers in tabular readings change when they differ by more than their tolerance, and images when their perceptual
+// hashes differ by more than ImageHashDistance bits. Any other change to a reading is captured.
+type ChangeDetectionConfig struct {
+	// DefaultTolerance is the tolerance of numbers whose key isn't in Tolerances.
+	DefaultTolerance float64 `json:"default_tolerance"`
+	// Tolerances are the tolerances of numbers by key, with the keys of nested values joined by dots, e.g.
+	// "readings.temperature".
+	Tolerances map[string]float64 `json:"tolerances,omitempty"`
+	// ImageHashDistance is the number of bits, out of 64, the perceptual hashes of images may differ by.
+	ImageHashDistance int `json:"image_hash_distance"`
+	// MaxSuppressedSec, when positive, captures a sample once that long has passed since the last one, even if it
+	// hasn't changed.
+	MaxSuppressedSec float64 `json:"max_suppressed_sec,omitempty"`
+}
+
+// TriggeredCaptureConfig makes a collector keep its readings in a bounded ring buffer, and only persist those
+// captured from PreTriggerSec before to PostTriggerSec after its trigger fires.
+//
+// The ring buffer is held in memory only: readings which haven't been persisted by a trigger are lost when the
+// machine restarts or the collector is reconfigured, and PreTriggerSec is limited by what fits in MaxBufferBytes of
+// memory.
+type TriggeredCaptureConfig struct {
+	// Trigger is the name of the data manager trigger which persists the buffer.
+	Trigger        string  `json:"trigger"`
+	PreTriggerSec  float64 `json:"pre_trigger_sec"`
+	PostTriggerSec float64 `json:"post_trigger_sec"`
+	// MaxBufferBytes bounds the memory used by the ring buffer; the oldest readings are dropped to stay within it.
+	// Defaults to 32 MiB.
+	MaxBufferBytes int64 `json:"max_buffer_bytes,omitempty"`
 }
 
 // Equals checks if one capture config is equal to another.
@@ -184,13 +223,29 @@ func (c *DataCaptureConfig) Equals(other *DataCaptureConfig) bool {
 		c.Disabled == other.Disabled &&
 		slices.Compare(c.Tags, other.Tags) == 0 &&
 		reflect.DeepEqual(c.AdditionalParams, other.AdditionalParams) &&
-		c.CaptureDirectory == other.CaptureDirectory
+		c.CaptureDirectory == other.CaptureDirectory &&
+		reflect.DeepEqual(c.Triggered, other.Triggered) &&
+		c.SyncPriority == other.SyncPriority &&
+		reflect.DeepEqual(c.ChangeDetection, other.ChangeDetection)
 }
 
 // ShouldSyncKey is a special key we use within a modular sensor to pass a boolean
 // that indicates to the datamanager whether or not we want to sync.
 var ShouldSyncKey = "should_sync"
 
+// DoTrigger is the DoCommand key which fires a data capture trigger by name, persisting the ring buffers of the
+// collectors which use it.
+const DoTrigger = "trigger"
+
+// DoQuery is the DoCommand key which aggregates the tabular data captured on the machine. Its value is a map with the
+// resource_name, method and path of the value to aggregate, and optionally the start and end times in RFC3339 and the
+// bucket_sec size of the buckets to aggregate into.
+const DoQuery = "query"
+
+// DoRetentionReport is the DoCommand key which reports the files the configured retention rules would delete now,
+// without deleting them.
+const DoRetentionReport = "retention_report"
+
 // SequencesKey is the key under which a capture control sensor returns sequence readings.
 var SequencesKey = "sequences"
 
@@ -15,6 +15,7 @@ import (
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
@@ -23,6 +24,7 @@ import (
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"go.viam.com/rdk/components/arm"
 	"go.viam.com/rdk/components/movementsensor"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/motionplan"
@@ -62,6 +64,11 @@ const (
 	DoTeleopMove   = "teleop_move"
 	DoTeleopStop   = "teleop_stop"
 	DoTeleopStatus = "teleop_status"
+
+	DoServoStart  = "servo_start"
+	DoServoTwist  = "servo_twist"
+	DoServoStop   = "servo_stop"
+	DoServoStatus = "servo_status"
 )
 
 const (
@@ -111,6 +118,15 @@ type Config struct {
 	// more latency); higher alpha is more responsive and closer to the raw planned motion. The
 	// valid range is (0, 1]: 1 disables smoothing, and 0 (the zero value) selects the default of 0.5.
 	TeleopSmoothAlpha float64 `json:"teleop_smooth_alpha"`
+
+	// PlanCache enables reuse of previous plans for repeated Move requests with the same frame system, start
+	// configuration, goals and constraints. Cached plans are re-validated against the current obstacles before reuse.
+	// Individual requests may opt out by setting "skip_plan_cache" in their extra.
+	PlanCache bool `json:"plan_cache"`
+	// PlanCacheDir is the directory plans are cached in. Defaults to a directory under the viam home.
+	PlanCacheDir string `json:"plan_cache_dir"`
+	// PlanCacheMaxEntries bounds the number of cached plans. Defaults to 1000.
+	PlanCacheMaxEntries int `json:"plan_cache_max_entries"`
 }
 
 func (c *Config) shouldWritePlan(start time.Time, err error) bool {
@@ -140,6 +156,10 @@ func (c *Config) Validate(path string) ([]string, []string, error) {
 		return nil, nil, fmt.Errorf("need a plan_file_path if you sent LogSlowPlanThresholdMS to %v", c.LogSlowPlanThresholdMS)
 	}
 
+	if c.PlanCacheMaxEntries < 0 {
+		return nil, nil, fmt.Errorf("plan_cache_max_entries cannot be negative, got %d", c.PlanCacheMaxEntries)
+	}
+
 	// teleop_smooth_alpha must be in [0, 1]; 0 (the zero value) selects the default.
 	if c.TeleopSmoothAlpha < 0 || c.TeleopSmoothAlpha > 1 {
 		return nil, nil, fmt.Errorf("teleop_smooth_alpha must be in [0, 1] (0 selects the default), got %v", c.TeleopSmoothAlpha)
@@ -159,10 +179,15 @@ type builtIn struct {
 	components              map[string]resource.Resource
 	logger                  logging.Logger
 	configuredDefaultExtras map[string]any
+	planCache               *armplanning.PlanCache
 
 	// Teleop pipeline. Protected by teleopMu (separate from mu to simplify lock ordering).
 	teleopMu       sync.RWMutex
 	teleopPipeline *teleopPipeline
+
+	// Cartesian servo sessions keyed by component name. Protected by servoMu.
+	servoMu       sync.RWMutex
+	servoSessions map[string]*servoSession
 }
 
 // NewBuiltIn returns a new move and grab service for the given robot.
@@ -173,6 +198,7 @@ func NewBuiltIn(
 		Named:                   conf.ResourceName().AsNamed(),
 		logger:                  logger,
 		configuredDefaultExtras: make(map[string]any),
+		servoSessions:           make(map[string]*servoSession),
 	}
 
 	if err := ms.BuiltInReconfigure(ctx, deps, conf); err != nil {
@@ -194,6 +220,7 @@ func (ms *builtIn) BuiltInReconfigure(
 		ms.teleopPipeline = nil
 	}
 	ms.teleopMu.Unlock()
+	ms.stopServo("")
 
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
@@ -210,6 +237,13 @@ func (ms *builtIn) BuiltInReconfigure(
 	if config.NumThreads > 0 {
 		ms.configuredDefaultExtras["num_threads"] = config.NumThreads
 	}
+	ms.planCache = nil
+	if config.PlanCache {
+		ms.planCache, err = armplanning.NewPlanCache(config.PlanCacheDir, config.PlanCacheMaxEntries, ms.logger.Sublogger("plan_cache"))
+		if err != nil {
+			return err
+		}
+	}
 
 	movementSensors := make(map[string]movementsensor.MovementSensor)
 	slamServices := make(map[string]slam.Service)
@@ -244,6 +278,7 @@ func (ms *builtIn) Close(ctx context.Context) error {
 		ms.teleopPipeline = nil
 	}
 	ms.teleopMu.Unlock()
+	ms.stopServo("")
 
 	return nil
 }
@@ -258,7 +293,11 @@ func (ms *builtIn) Move(ctx context.Context, req motion.MoveReq) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	err = ms.execute(ctx, plan.Trajectory(), math.MaxFloat64)
+	if req.Constraints.Synchronized() {
+		err = ms.executeSynchronized(ctx, plan.Trajectory())
+	} else {
+		err = ms.execute(ctx, plan.Trajectory(), math.MaxFloat64)
+	}
 	return err == nil, err
 }
 
@@ -314,11 +353,23 @@ func (ms *builtIn) PlanHistory(
 //     required key: DoExecute
 //     input value: a motionplan.Trajectory
 //     output value: a bool
+//
+// It also supports Cartesian servoing, which streams joint targets computed from a commanded twist instead of planning:
+//   - DoServoStart begins servoing a component
+//     input value: {"component_name": ..., "world_state": <protojson WorldState>, "rate_hz": ..., "twist_timeout_ms": ...}
+//     plus any motionplan.ServoConfig fields
+//   - DoServoTwist sets the twist of a servoed component, in mm/s and rad/s in the component's base frame
+//     input value: {"component_name": ..., "linear": {"x": ..., "y": ..., "z": ...}, "angular": {...}}
+//   - DoServoStop stops servoing the named component, or every component if the name is empty
+//   - DoServoStatus reports per-component step counts, scaling and errors
 func (ms *builtIn) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
 	// Handle teleop commands first (they manage their own locking).
 	if resp, handled, err := ms.handleTeleopCommand(ctx, cmd); handled {
 		return resp, err
 	}
+	if resp, handled, err := ms.handleServoCommand(ctx, cmd); handled {
+		return resp, err
+	}
 
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
@@ -476,6 +527,10 @@ func (ms *builtIn) plan(ctx context.Context, req motion.MoveReq, logger logging.
 	if err != nil {
 		return nil, err
 	}
+	if len(waypoints) == 0 && req.Constraints != nil && len(req.Constraints.SynchronizedArrival) > 0 {
+		// The goals of the synchronized components are added to the final goal by the planner.
+		waypoints = append(waypoints, armplanning.NewPlanState(referenceframe.FrameSystemPoses{}, nil))
+	}
 	if len(waypoints) == 0 {
 		return nil, errors.New("could not find any waypoints to plan for in MoveRequest. Fill in Destination or goal_state")
 	}
@@ -532,8 +587,23 @@ func (ms *builtIn) plan(ctx context.Context, req motion.MoveReq, logger logging.
 		PlannerOptions:        planOpts,
 	}
 
+	useCache := ms.planCache != nil
+	if skip, ok := req.Extra["skip_plan_cache"].(bool); ok && skip {
+		useCache = false
+	}
+	if useCache {
+		if plan, ok := ms.planCache.Lookup(ctx, planRequest); ok {
+			return plan, nil
+		}
+	}
+
 	start := time.Now()
 	plan, meta, err := armplanning.PlanMotion(ctx, logger, planRequest)
+	if useCache && err == nil && !meta.Partial {
+		if err := ms.planCache.Store(planRequest, plan); err != nil {
+			logger.CWarnf(ctx, "couldn't cache plan: %v", err)
+		}
+	}
 	if ms.conf.shouldWritePlan(start, err) {
 		var traceID string
 		if span := trace.FromContext(ctx); span != nil {
@@ -708,6 +778,158 @@ func (ms *builtIn) execute(ctx context.Context, trajectory motionplan.Trajectory
 	return nil
 }
 
+// Joint speeds at which a synchronized motion is timed. The motion service can't ask an arm for its speed limits, so
+// the shared timing is kept slow enough for any arm to follow.
+const (
+	synchronizedJointSpeedRads = 0.5
+	synchronizedJointSpeedMm   = 50.
+)
+
+// executeSynchronized executes a trajectory as one timed trajectory shared by every arm moving in it. Each step takes as
+// long as the joint moving furthest in it needs, and every arm is streamed the same times, so that the arms start and
+// finish each step together and hold their relative poses along the way.
+func (ms *builtIn) executeSynchronized(ctx context.Context, trajectory motionplan.Trajectory) error {
+	arms := map[string]arm.Arm{}
+	limits := map[string][]referenceframe.Limit{}
+	// steps are the indices of the steps in which anything moves, since times streamed to an arm must strictly increase
+	steps := []int{0}
+	for i := 1; i < len(trajectory); i++ {
+		moved := false
+		for name, inputs := range trajectory[i] {
+			if len(inputs) == 0 || referenceframe.InputsLinfDistance(trajectory[i-1][name], inputs) == 0 {
+				continue
+			}
+			moved = true
+			if _, ok := arms[name]; ok {
+				continue
+			}
+			r, ok := ms.components[name]
+			if !ok {
+				return fmt.Errorf("plan had step for resource %s but the motion service is not aware of a component of that name", name)
+			}
+			a, ok := r.(arm.Arm)
+			if !ok {
+				return fmt.Errorf("component %s cannot follow a synchronized motion, only arms can", name)
+			}
+			model, err := a.Kinematics(ctx)
+			if err != nil {
+				return err
+			}
+			arms[name] = a
+			limits[name] = model.DoF()
+		}
+		if moved {
+			steps = append(steps, i)
+		}
+	}
+	if len(steps) == 1 {
+		return nil
+	}
+	for name := range arms {
+		for _, i := range steps {
+			if len(trajectory[i][name]) != len(limits[name]) {
+				return fmt.Errorf("plan step %d has %d inputs for arm %s, which has %d joints",
+					i, len(trajectory[i][name]), name, len(limits[name]))
+			}
+		}
+	}
+
+	// times[k] is the time at which every arm reaches steps[k]
+	times := make([]time.Duration, len(steps))
+	for k := 1; k < len(steps); k++ {
+		seconds := 0.
+		for name := range arms {
+			from, to := trajectory[steps[k-1]][name], trajectory[steps[k]][name]
+			for j, limit := range limits[name] {
+				speed := synchronizedJointSpeedMm
+				if limit.IsRotational() {
+					speed = synchronizedJointSpeedRads
+				}
+				seconds = math.Max(seconds, math.Abs(to[j]-from[j])/speed)
+			}
+		}
+		times[k] = times[k-1] + max(time.Duration(seconds*float64(time.Second)), time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, 0, len(arms))
+	var errsMu sync.Mutex
+	var wg sync.WaitGroup
+	for name, a := range arms {
+		points := synchronizedPoints(trajectory, steps, times, name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := followTrajectory(ctx, a, points); err != nil {
+				errsMu.Lock()
+				errs = append(errs, errors.Wrapf(err, "arm %s", name))
+				errsMu.Unlock()
+				// the other arms can no longer move together with this one
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := multierr.Combine(errs...); err != nil {
+		// Stop every arm, not just the failed ones, since they can no longer move together.
+		for _, a := range arms {
+			//nolint:errcheck
+			_ = a.Stop(context.WithoutCancel(ctx), nil)
+		}
+		return err
+	}
+	return nil
+}
+
+// synchronizedPoints returns the timed points of the named arm at the given steps of the trajectory. The velocity at
+// each point between the first and last is that of the straight line between its neighbors, so that the arm passes
+// through it without stopping.
+func synchronizedPoints(
+	trajectory motionplan.Trajectory,
+	steps []int,
+	times []time.Duration,
+	name string,
+) []arm.TrajectoryPoint {
+	points := make([]arm.TrajectoryPoint, len(steps))
+	for k, i := range steps {
+		positions := trajectory[i][name]
+		velocities := make([]float64, len(positions))
+		if k > 0 && k < len(steps)-1 {
+			prev, next := trajectory[steps[k-1]][name], trajectory[steps[k+1]][name]
+			seconds := (times[k+1] - times[k-1]).Seconds()
+			for j := range velocities {
+				velocities[j] = (next[j] - prev[j]) / seconds
+			}
+		}
+		points[k] = arm.TrajectoryPoint{
+			Time:        times[k],
+			Positions:   positions,
+			Constraints: &arm.KinematicConstraints{Velocities: velocities},
+		}
+	}
+	return points
+}
+
+// followTrajectory streams the points to the arm as one batch, returning once it has followed them.
+func followTrajectory(ctx context.Context, a arm.Arm, points []arm.TrajectoryPoint) error {
+	batches := make(chan []arm.TrajectoryPoint, 1)
+	batches <- points
+	close(batches)
+	responses := make(chan arm.Response)
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for range responses {
+		}
+	}()
+	err := a.MoveThroughJointPositionsStreamed(ctx, batches, responses, nil)
+	close(responses)
+	<-drained
+	return err
+}
+
 // applyDefaultExtras iterates through the list of default extras configured on the builtIn motion service and adds them to the
 // given map of extras if the key does not already exist.
 func (ms *builtIn) applyDefaultExtras(extras map[string]any) {
@@ -6,6 +6,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	commonpb "go.viam.com/api/common/v1"
 	"go.viam.com/test"
 	"go.viam.com/utils/protoutils"
+	"go.viam.com/utils/testutils"
 	"google.golang.org/protobuf/encoding/protojson"
 
 	"go.viam.com/rdk/components/arm"
@@ -30,6 +32,8 @@ import (
 	robotimpl "go.viam.com/rdk/robot/impl"
 	"go.viam.com/rdk/services/motion"
 	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/testutils/inject"
+	"go.viam.com/rdk/utils"
 )
 
 func setupMotionServiceFromConfig(t *testing.T, configFilename string) (motion.Service, func()) {
@@ -884,3 +888,137 @@ func TestErrorMessageContext(t *testing.T) {
 	// E.g: `Frame: arm2.shoulder_lift_joint (joint 1): input out of bounds...`
 	test.That(t, err.Error(), test.ShouldContainSubstring, "arm2")
 }
+
+func TestServo(t *testing.T) {
+	ctx := context.Background()
+	ms, teardown := setupMotionServiceFromConfig(t, "../data/moving_arm.json")
+	defer teardown()
+
+	armComp, ok := ms.(*builtIn).components["pieceArm"].(arm.Arm)
+	test.That(t, ok, test.ShouldBeTrue)
+	// Start away from the singular all-zero configuration.
+	start := []referenceframe.Input{0.2, -1.2, 1.4, -1.8, -1.5, 0.3}
+	test.That(t, armComp.MoveToJointPositions(ctx, start, nil), test.ShouldBeNil)
+
+	_, err := ms.DoCommand(ctx, map[string]interface{}{
+		DoServoTwist: map[string]interface{}{"component_name": "pieceArm"},
+	})
+	test.That(t, err, test.ShouldNotBeNil)
+
+	_, err = ms.DoCommand(ctx, map[string]interface{}{
+		DoServoStart: map[string]interface{}{"component_name": "notAnArm"},
+	})
+	test.That(t, err, test.ShouldNotBeNil)
+
+	resp, err := ms.DoCommand(ctx, map[string]interface{}{
+		DoServoStart: map[string]interface{}{"component_name": "pieceArm", "rate_hz": 100., "twist_timeout_ms": 5000},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp[DoServoStart], test.ShouldBeTrue)
+
+	startPose, err := armComp.EndPosition(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+
+	_, err = ms.DoCommand(ctx, map[string]interface{}{
+		DoServoTwist: map[string]interface{}{
+			"component_name": "pieceArm",
+			"linear":         map[string]interface{}{"z": 100.},
+		},
+	})
+	test.That(t, err, test.ShouldBeNil)
+
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		pose, err := armComp.EndPosition(ctx, nil)
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, pose.Point().Z-startPose.Point().Z, test.ShouldBeGreaterThan, 5)
+	})
+
+	resp, err = ms.DoCommand(ctx, map[string]interface{}{DoServoStatus: true})
+	test.That(t, err, test.ShouldBeNil)
+	statuses, ok := resp[DoServoStatus].(map[string]any)
+	test.That(t, ok, test.ShouldBeTrue)
+	status, ok := statuses["pieceArm"].(map[string]any)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, status["step_count"], test.ShouldBeGreaterThan, 0)
+	test.That(t, status["error"], test.ShouldBeNil)
+
+	resp, err = ms.DoCommand(ctx, map[string]interface{}{DoServoStop: "pieceArm"})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp[DoServoStop], test.ShouldBeTrue)
+	test.That(t, ms.(*builtIn).servoSessions, test.ShouldBeEmpty)
+}
+
+func TestExecuteSynchronized(t *testing.T) {
+	ctx := context.Background()
+	model, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/ur5e.json"), "")
+	test.That(t, err, test.ShouldBeNil)
+
+	streamed := map[string][]arm.TrajectoryPoint{}
+	var streamedMu sync.Mutex
+	stopped := map[string]bool{}
+	newArm := func(name string, moveErr error) *inject.Arm {
+		injectArm := inject.NewArm(name)
+		injectArm.KinematicsFunc = func(ctx context.Context) (referenceframe.Model, error) {
+			return model, nil
+		}
+		injectArm.MoveThroughJointPositionsStreamedFunc = func(
+			ctx context.Context,
+			batches <-chan []arm.TrajectoryPoint,
+			responses chan<- arm.Response,
+			extra map[string]interface{},
+		) error {
+			for batch := range batches {
+				streamedMu.Lock()
+				streamed[name] = append(streamed[name], batch...)
+				streamedMu.Unlock()
+				responses <- arm.Response{}
+			}
+			return moveErr
+		}
+		injectArm.StopFunc = func(ctx context.Context, extra map[string]interface{}) error {
+			stopped[name] = true
+			return nil
+		}
+		return injectArm
+	}
+	ms := &builtIn{components: map[string]resource.Resource{"left": newArm("left", nil), "right": newArm("right", nil)}}
+
+	inputs := func(first float64) []referenceframe.Input {
+		return []referenceframe.Input{first, 0, 0, 0, 0, 0}
+	}
+	trajectory := motionplan.Trajectory{
+		{"left": inputs(0), "right": inputs(0)},
+		// the left arm moves twice as far as the right in the first step, and neither moves in the second
+		{"left": inputs(1), "right": inputs(0.5)},
+		{"left": inputs(1), "right": inputs(0.5)},
+		{"left": inputs(1.5), "right": inputs(1.5)},
+	}
+	test.That(t, ms.executeSynchronized(ctx, trajectory), test.ShouldBeNil)
+
+	// both arms are streamed the same times, set by the joint moving furthest in each step
+	test.That(t, streamed["left"], test.ShouldHaveLength, 3)
+	test.That(t, streamed["right"], test.ShouldHaveLength, 3)
+	for k, seconds := range []float64{0, 1 / synchronizedJointSpeedRads, 2 / synchronizedJointSpeedRads} {
+		test.That(t, streamed["left"][k].Time.Seconds(), test.ShouldAlmostEqual, seconds)
+		test.That(t, streamed["right"][k].Time, test.ShouldEqual, streamed["left"][k].Time)
+	}
+	test.That(t, streamed["right"][1].Positions, test.ShouldResemble, inputs(0.5))
+	// the arms start and finish at rest, and pass through the step between at the speed of the line joining its neighbors
+	test.That(t, streamed["left"][0].Constraints.Velocities[0], test.ShouldEqual, 0)
+	test.That(t, streamed["left"][1].Constraints.Velocities[0], test.ShouldAlmostEqual, 1.5/streamed["left"][2].Time.Seconds())
+	test.That(t, streamed["left"][2].Constraints.Velocities[0], test.ShouldEqual, 0)
+
+	// when one arm fails, both are stopped
+	ms.components["right"] = newArm("right", errors.New("fault"))
+	err = ms.executeSynchronized(ctx, trajectory)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "fault")
+	test.That(t, stopped["left"], test.ShouldBeTrue)
+
+	// only arms can follow a shared timed trajectory
+	ms.components["right"] = inject.NewGantry("right")
+	err = ms.executeSynchronized(ctx, trajectory)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "only arms")
+}
@@ -0,0 +1,408 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	commonpb "go.viam.com/api/common/v1"
+	goutils "go.viam.com/utils"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"go.viam.com/rdk/components/arm"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/motionplan"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/robot/framesystem"
+	"go.viam.com/rdk/utils"
+)
+
+const (
+	defaultServoRateHz         = 50.
+	defaultServoTwistTimeoutMS = 250
+)
+
+// servoStartRequest is the payload of a DoServoStart command.
+type servoStartRequest struct {
+	ComponentName string `json:"component_name"`
+	// WorldState is an optional protojson-encoded commonpb.WorldState whose obstacles slow and stop servoing.
+	WorldState string `json:"world_state"`
+	// RateHz is how often joint targets are streamed to the component.
+	RateHz float64 `json:"rate_hz"`
+	// TwistTimeoutMS is how long a twist remains in effect without a fresh DoServoTwist before the component holds
+	// position. It guards against a client that disconnects mid-motion.
+	TwistTimeoutMS int `json:"twist_timeout_ms"`
+	motionplan.ServoConfig
+}
+
+// servoTwistRequest is the payload of a DoServoTwist command.
+type servoTwistRequest struct {
+	ComponentName string `json:"component_name"`
+	motionplan.Twist
+}
+
+type receivedTwist struct {
+	twist    motionplan.Twist
+	received time.Time
+}
+
+// servoSession streams joint targets computed from the latest commanded twist to a single component.
+//
+//	servo_twist → latest twist → streaming goroutine → arm.MoveThroughJointPositionsStreamed()
+type servoSession struct {
+	name         string
+	logger       logging.Logger
+	controller   *motionplan.ServoController
+	component    resource.Resource
+	period       time.Duration
+	twistTimeout time.Duration
+
+	latest atomic.Pointer[receivedTwist]
+
+	// Commanded joint positions, integrated from the controller output. Only accessed by the streaming goroutine
+	// once the session is running.
+	current []referenceframe.Input
+
+	lastErr   atomic.Pointer[error]
+	stepCount atomic.Int64
+
+	statusMu       sync.Mutex
+	lastScale      float64
+	manipulability float64
+
+	workers *goutils.StoppableWorkers
+}
+
+// run ticks at the session rate, turning the latest twist into joint targets.
+func (ss *servoSession) run(ctx context.Context) {
+	var sendTargets func(ctx context.Context, step *motionplan.ServoStep) error
+	if armComp, ok := ss.component.(arm.Arm); ok {
+		send, stop, err := ss.openArmStream(ctx, armComp)
+		defer stop()
+		if err != nil {
+			ss.lastErr.Store(&err)
+			return
+		}
+		sendTargets = send
+	} else {
+		ie, err := utils.AssertType[framesystem.InputEnabled](ss.component)
+		if err != nil {
+			ss.lastErr.Store(&err)
+			return
+		}
+		sendTargets = func(ctx context.Context, step *motionplan.ServoStep) error {
+			return ie.GoToInputs(ctx, step.Positions)
+		}
+	}
+
+	ticker := time.NewTicker(ss.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var twist motionplan.Twist
+		if latest := ss.latest.Load(); latest != nil && time.Since(latest.received) <= ss.twistTimeout {
+			twist = latest.twist
+		}
+		if twist.IsZero() {
+			continue
+		}
+
+		step, err := ss.controller.Step(ctx, twist, ss.current, ss.period.Seconds())
+		if err != nil {
+			ss.lastErr.Store(&err)
+			ss.logger.CWarnf(ctx, "servo step failed: %v", err)
+			// Drop the twist so the component holds until the client commands a new one.
+			ss.latest.Store(nil)
+			continue
+		}
+		ss.statusMu.Lock()
+		ss.lastScale = step.Scale
+		ss.manipulability = step.Manipulability
+		ss.statusMu.Unlock()
+
+		if err := sendTargets(ctx, step); err != nil {
+			ss.lastErr.Store(&err)
+			ss.logger.CWarnf(ctx, "servo failed to send joint targets: %v", err)
+			if actuator, ok := ss.component.(inputEnabledActuator); ok {
+				//nolint:errcheck
+				_ = actuator.Stop(context.WithoutCancel(ctx), nil)
+			}
+			return
+		}
+		ss.current = step.Positions
+		ss.stepCount.Add(1)
+		ss.lastErr.Store(nil)
+	}
+}
+
+// openArmStream starts a streamed trajectory on the arm, anchored at the current joint positions. It returns a
+// function which appends one point carrying both the joint position and velocity targets, and a function which ends
+// the stream. The stop function must be called even if an error is returned.
+func (ss *servoSession) openArmStream(
+	ctx context.Context,
+	armComp arm.Arm,
+) (func(context.Context, *motionplan.ServoStep) error, func(), error) {
+	batches := make(chan []arm.TrajectoryPoint, 1)
+	responses := make(chan arm.Response, 1)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- armComp.MoveThroughJointPositionsStreamed(ctx, batches, responses, nil)
+	}()
+	go func() {
+		//nolint:revive
+		for range responses {
+		}
+	}()
+	stop := func() {
+		close(batches)
+		// Wait for the arm to finish consuming the stream so responses is safe to close.
+		if err := <-streamErr; err != nil && !errors.Is(err, context.Canceled) {
+			ss.logger.Debugf("servo stream ended: %v", err)
+		}
+		close(responses)
+	}
+
+	// The first point of a stream must be at time zero.
+	start := time.Now()
+	if err := ss.sendPoint(ctx, batches, streamErr, arm.TrajectoryPoint{Positions: ss.current}); err != nil {
+		return nil, stop, err
+	}
+
+	var lastTime time.Duration
+	send := func(ctx context.Context, step *motionplan.ServoStep) error {
+		lastTime = max(time.Since(start), lastTime+time.Nanosecond)
+		return ss.sendPoint(ctx, batches, streamErr, arm.TrajectoryPoint{
+			Time:        lastTime,
+			Positions:   step.Positions,
+			Constraints: &arm.KinematicConstraints{Velocities: step.Velocities},
+		})
+	}
+	return send, stop, nil
+}
+
+func (ss *servoSession) sendPoint(
+	ctx context.Context,
+	batches chan<- []arm.TrajectoryPoint,
+	streamErr chan error,
+	point arm.TrajectoryPoint,
+) error {
+	select {
+	case batches <- []arm.TrajectoryPoint{point}:
+		return nil
+	case err := <-streamErr:
+		// Put the error back so stop() can still observe that the stream has ended.
+		streamErr <- err
+		if err == nil {
+			err = errors.New("arm ended the servo stream")
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (ss *servoSession) stop() {
+	ss.workers.Stop()
+}
+
+func (ss *servoSession) status() map[string]any {
+	ss.statusMu.Lock()
+	defer ss.statusMu.Unlock()
+	status := map[string]any{
+		"step_count":     ss.stepCount.Load(),
+		"scale":          ss.lastScale,
+		"manipulability": ss.manipulability,
+	}
+	if lastErr := ss.lastErr.Load(); lastErr != nil {
+		status["error"] = (*lastErr).Error()
+	}
+	return status
+}
+
+// startServo creates a servo session for a component, replacing any existing session for it.
+func (ms *builtIn) startServo(ctx context.Context, req servoStartRequest) error {
+	if req.RateHz < 0 || req.TwistTimeoutMS < 0 {
+		return fmt.Errorf("servo rate_hz and twist_timeout_ms must be non-negative, got %v and %v", req.RateHz, req.TwistTimeoutMS)
+	}
+	if req.RateHz == 0 {
+		req.RateHz = defaultServoRateHz
+	}
+	if req.TwistTimeoutMS == 0 {
+		req.TwistTimeoutMS = defaultServoTwistTimeoutMS
+	}
+
+	var worldState *referenceframe.WorldState
+	if req.WorldState != "" {
+		var wsProto commonpb.WorldState
+		if err := protojson.Unmarshal([]byte(req.WorldState), &wsProto); err != nil {
+			return err
+		}
+		var err error
+		if worldState, err = referenceframe.WorldStateFromProtobuf(&wsProto); err != nil {
+			return err
+		}
+	}
+
+	ms.mu.RLock()
+	component, ok := ms.components[req.ComponentName]
+	if !ok {
+		ms.mu.RUnlock()
+		return fmt.Errorf("component %q is not known to the motion service", req.ComponentName)
+	}
+	frameSys, err := ms.getFrameSystem(ctx, worldState.Transforms())
+	if err != nil {
+		ms.mu.RUnlock()
+		return err
+	}
+	fsInputs, err := ms.fsService.CurrentInputs(ctx)
+	ms.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	frame := frameSys.Frame(req.ComponentName)
+	if frame == nil {
+		return referenceframe.NewFrameMissingError(req.ComponentName)
+	}
+	current, ok := fsInputs[req.ComponentName]
+	if !ok {
+		return fmt.Errorf("no current inputs for component %q", req.ComponentName)
+	}
+	obstacles, err := worldState.ObstaclesInWorldFrame(frameSys, fsInputs)
+	if err != nil {
+		return err
+	}
+
+	logger := ms.logger.Sublogger("servo")
+	clearance, err := motionplan.NewServoClearanceFunc(frameSys, req.ComponentName, fsInputs, obstacles, 0, logger)
+	if err != nil {
+		return err
+	}
+	controller, err := motionplan.NewServoController(frame, req.ServoConfig, clearance, logger)
+	if err != nil {
+		return err
+	}
+
+	ss := &servoSession{
+		name:         req.ComponentName,
+		logger:       logger,
+		controller:   controller,
+		component:    component,
+		period:       time.Duration(float64(time.Second) / req.RateHz),
+		twistTimeout: time.Duration(req.TwistTimeoutMS) * time.Millisecond,
+		current:      current,
+	}
+
+	ms.servoMu.Lock()
+	defer ms.servoMu.Unlock()
+	if existing, ok := ms.servoSessions[req.ComponentName]; ok {
+		existing.stop()
+	}
+	ss.workers = goutils.NewBackgroundStoppableWorkers(ss.run)
+	ms.servoSessions[req.ComponentName] = ss
+	return nil
+}
+
+// stopServo stops the servo session for the named component, or all sessions if name is empty.
+func (ms *builtIn) stopServo(name string) {
+	ms.servoMu.Lock()
+	defer ms.servoMu.Unlock()
+	for n, ss := range ms.servoSessions {
+		if name == "" || n == name {
+			ss.stop()
+			delete(ms.servoSessions, n)
+		}
+	}
+}
+
+// handleServoCommand handles servo DoCommand requests.
+// Returns (response, handled, error). If handled is false, the caller should
+// continue processing other DoCommand keys.
+func (ms *builtIn) handleServoCommand(
+	ctx context.Context,
+	cmd map[string]interface{},
+) (map[string]interface{}, bool, error) {
+	resp := make(map[string]interface{})
+
+	if req, ok := cmd[DoServoStart]; ok {
+		var startReq servoStartRequest
+		if err := decodeServoRequest(req, &startReq); err != nil {
+			return nil, true, err
+		}
+		if err := ms.startServo(ctx, startReq); err != nil {
+			return nil, true, err
+		}
+		resp[DoServoStart] = true
+		return resp, true, nil
+	}
+
+	if req, ok := cmd[DoServoTwist]; ok {
+		var twistReq servoTwistRequest
+		if err := decodeServoRequest(req, &twistReq); err != nil {
+			return nil, true, err
+		}
+		for _, v := range []float64{
+			twistReq.Linear.X, twistReq.Linear.Y, twistReq.Linear.Z,
+			twistReq.Angular.X, twistReq.Angular.Y, twistReq.Angular.Z,
+		} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, true, fmt.Errorf("servo twist must be finite, got %+v", twistReq.Twist)
+			}
+		}
+
+		ms.servoMu.RLock()
+		ss, ok := ms.servoSessions[twistReq.ComponentName]
+		ms.servoMu.RUnlock()
+		if !ok {
+			return nil, true, fmt.Errorf("component %q is not being servoed; call %s first", twistReq.ComponentName, DoServoStart)
+		}
+		ss.latest.Store(&receivedTwist{twist: twistReq.Twist, received: time.Now()})
+		resp[DoServoTwist] = true
+		return resp, true, nil
+	}
+
+	if req, ok := cmd[DoServoStop]; ok {
+		name, _ := req.(string)
+		ms.stopServo(name)
+		resp[DoServoStop] = true
+		return resp, true, nil
+	}
+
+	if _, ok := cmd[DoServoStatus]; ok {
+		ms.servoMu.RLock()
+		statuses := make(map[string]any, len(ms.servoSessions))
+		for name, ss := range ms.servoSessions {
+			statuses[name] = ss.status()
+		}
+		ms.servoMu.RUnlock()
+		resp[DoServoStatus] = statuses
+		return resp, true, nil
+	}
+
+	return resp, false, nil
+}
+
+// decodeServoRequest decodes a DoCommand payload, given either as a map or a JSON string, into out.
+func decodeServoRequest(req interface{}, out interface{}) error {
+	var data []byte
+	if s, ok := req.(string); ok {
+		data = []byte(s)
+	} else {
+		var err error
+		if data, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, out)
+}
@@ -1459,3 +1459,39 @@ func validMoveOnGlobeRequest() MoveOnGlobeReq {
 		Extra: nil,
 	}
 }
+
+func TestMoveReq(t *testing.T) {
+	t.Run("coordinated constraints round trip through extra", func(t *testing.T) {
+		rightGoal := referenceframe.NewPoseInFrame(referenceframe.World, spatialmath.NewPoseFromPoint(r3.Vector{X: 300, Y: -300}))
+		constraints := motionplan.NewEmptyConstraints()
+		constraints.AddLinearConstraint(motionplan.LinearConstraint{LineToleranceMm: 1})
+		constraints.AddRelativePoseConstraint(motionplan.RelativePoseConstraint{Frame1: "left", Frame2: "right", LineToleranceMm: 2})
+		constraints.AddSynchronizedArrival(motionplan.SynchronizedArrival{
+			Goals: map[string]*referenceframe.PoseInFrame{"right": rightGoal},
+		})
+		constraints.AddClearanceConstraint(motionplan.ClearanceConstraint{Frame1: "left", Frame2: "right", MinDistanceMm: 20})
+		extra := map[string]interface{}{"num_threads": 2.}
+		req := MoveReq{
+			ComponentName: "left",
+			Destination:   referenceframe.NewPoseInFrame(referenceframe.World, spatialmath.NewPoseFromPoint(r3.Vector{X: 300, Y: 300})),
+			Constraints:   constraints,
+			Extra:         extra,
+		}
+
+		reqPB, err := req.ToProto("motion")
+		test.That(t, err, test.ShouldBeNil)
+		// The caller's extra is not modified.
+		test.That(t, extra, test.ShouldResemble, map[string]interface{}{"num_threads": 2.})
+
+		parsed, err := MoveReqFromProto(reqPB)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, parsed.Extra, test.ShouldResemble, extra)
+		test.That(t, parsed.Constraints.LinearConstraint, test.ShouldResemble, constraints.LinearConstraint)
+		test.That(t, parsed.Constraints.RelativePoseConstraint, test.ShouldResemble, constraints.RelativePoseConstraint)
+		test.That(t, parsed.Constraints.ClearanceConstraint, test.ShouldResemble, constraints.ClearanceConstraint)
+		test.That(t, len(parsed.Constraints.SynchronizedArrival), test.ShouldEqual, 1)
+		parsedGoal := parsed.Constraints.SynchronizedArrival[0].Goals["right"]
+		test.That(t, parsedGoal.Parent(), test.ShouldEqual, referenceframe.World)
+		test.That(t, spatialmath.PoseAlmostEqual(parsedGoal.Pose(), rightGoal.Pose()), test.ShouldBeTrue)
+	})
+}
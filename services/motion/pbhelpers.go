@@ -1,6 +1,7 @@
 package motion
 
 import (
+	"encoding/json"
 	"math"
 
 	"github.com/google/uuid"
@@ -21,7 +22,11 @@ var ErrEmptyComponentName = errors.New("component name cannot be empty")
 // ToProto converts a MoveReq to a pb.MoveRequest
 // the name argument should correspond to the name of the motion service the request will be used with.
 func (r MoveReq) ToProto(name string) (*pb.MoveRequest, error) {
-	ext, err := vprotoutils.StructToStructPb(r.Extra)
+	extra, err := coordinatedConstraintsToExtra(r.Constraints, r.Extra)
+	if err != nil {
+		return nil, err
+	}
+	ext, err := vprotoutils.StructToStructPb(extra)
 	if err != nil {
 		return nil, err
 	}
@@ -57,15 +62,70 @@ func MoveReqFromProto(req *pb.MoveRequest) (MoveReq, error) {
 		destination = referenceframe.ProtobufToPoseInFrame(req.GetDestination())
 	}
 
+	constraints := motionplan.ConstraintsFromProtobuf(req.GetConstraints())
+	extra := req.Extra.AsMap()
+	if err := coordinatedConstraintsFromExtra(constraints, extra); err != nil {
+		return MoveReq{}, err
+	}
+
 	return MoveReq{
 		req.GetComponentName(),
 		destination,
 		worldState,
-		motionplan.ConstraintsFromProtobuf(req.GetConstraints()),
-		req.Extra.AsMap(),
+		constraints,
+		extra,
 	}, nil
 }
 
+// coordinatedConstraintsExtraKey is the key of the extra in which constraints with no protobuf representation are sent.
+const coordinatedConstraintsExtraKey = "coordinated_constraints"
+
+// coordinatedConstraintsToExtra returns a copy of extra carrying the constraints which cannot be sent as protobuf.
+func coordinatedConstraintsToExtra(c *motionplan.Constraints, extra map[string]interface{}) (map[string]interface{}, error) {
+	if c == nil || (len(c.RelativePoseConstraint) == 0 && len(c.SynchronizedArrival) == 0 && len(c.ClearanceConstraint) == 0) {
+		return extra, nil
+	}
+	data, err := json.Marshal(&motionplan.Constraints{
+		RelativePoseConstraint: c.RelativePoseConstraint,
+		SynchronizedArrival:    c.SynchronizedArrival,
+		ClearanceConstraint:    c.ClearanceConstraint,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var coordinated map[string]interface{}
+	if err := json.Unmarshal(data, &coordinated); err != nil {
+		return nil, err
+	}
+	withConstraints := make(map[string]interface{}, len(extra)+1)
+	for k, v := range extra {
+		withConstraints[k] = v
+	}
+	withConstraints[coordinatedConstraintsExtraKey] = coordinated
+	return withConstraints, nil
+}
+
+// coordinatedConstraintsFromExtra moves the constraints carried in extra by coordinatedConstraintsToExtra into c.
+func coordinatedConstraintsFromExtra(c *motionplan.Constraints, extra map[string]interface{}) error {
+	coordinated, ok := extra[coordinatedConstraintsExtraKey]
+	if !ok {
+		return nil
+	}
+	delete(extra, coordinatedConstraintsExtraKey)
+	data, err := json.Marshal(coordinated)
+	if err != nil {
+		return err
+	}
+	var fromExtra motionplan.Constraints
+	if err := json.Unmarshal(data, &fromExtra); err != nil {
+		return errors.Wrapf(err, "could not parse %s", coordinatedConstraintsExtraKey)
+	}
+	c.RelativePoseConstraint = fromExtra.RelativePoseConstraint
+	c.SynchronizedArrival = fromExtra.SynchronizedArrival
+	c.ClearanceConstraint = fromExtra.ClearanceConstraint
+	return nil
+}
+
 // planWithStatusFromProto converts a *pb.PlanWithStatus to a PlanWithStatus.
 func planWithStatusFromProto(pws *pb.PlanWithStatus) (PlanWithStatus, error) {
 	if pws == nil {
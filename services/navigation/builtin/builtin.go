@@ -15,6 +15,7 @@ import (
 	geo "github.com/kellydunn/golang-geo"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/multierr"
 	"go.viam.com/utils"
 
 	"go.viam.com/rdk/components/base"
@@ -109,6 +110,9 @@ type Config struct {
 	PlanDeviationM             float64                          `json:"plan_deviation_m,omitempty"`
 	ReplanCostFactor           float64                          `json:"replan_cost_factor,omitempty"`
 	LogFilePath                string                           `json:"log_file_path"`
+
+	// ActionResources are the resources which route waypoints may send a DoCommand to on arrival.
+	ActionResources []string `json:"action_resources,omitempty"`
 }
 
 type executionWaypoint struct {
@@ -200,6 +204,13 @@ func (conf *Config) Validate(path string) ([]string, []string, error) {
 		}
 	}
 
+	for _, name := range conf.ActionResources {
+		if name == "" {
+			return nil, nil, resource.NewConfigValidationError(path, errors.New("action_resources cannot contain an empty name"))
+		}
+		deps = append(deps, name)
+	}
+
 	// add framesystem service as dependency to be used by builtin and explore motion service
 	deps = append(deps, framesystem.InternalServiceName.String())
 
@@ -235,6 +246,7 @@ type builtIn struct {
 	base                 base.Base
 	movementSensor       movementsensor.MovementSensor
 	visionServicesByName map[string]vision.Service
+	actionResources      map[string]resource.Resource
 	motionService        motion.Service
 	obstacles            []*spatialmath.GeoGeometry
 	boundingRegions      []*spatialmath.GeoGeometry
@@ -356,6 +368,15 @@ func (svc *builtIn) reconfigure(ctx context.Context, deps resource.Dependencies,
 		visionServicesByName[visionSvc.Name().Name] = visionSvc
 	}
 
+	actionResources := make(map[string]resource.Resource, len(svcConfig.ActionResources))
+	for _, name := range svcConfig.ActionResources {
+		res, err := actionResourceFromDeps(deps, name)
+		if err != nil {
+			return err
+		}
+		actionResources[name] = res
+	}
+
 	// Parse movement sensor from the configuration if map type is GPS
 	if mapType == navigation.GPSMap {
 		movementSensor, err := movementsensor.FromProvider(deps, svcConfig.MovementSensorName)
@@ -371,6 +392,17 @@ func (svc *builtIn) reconfigure(ctx context.Context, deps resource.Dependencies,
 		if err != nil {
 			return err
 		}
+		// waypoints, routes and missions in memory would be lost, so move them to the new store
+		if oldStore, ok := svc.store.(*navigation.MemoryNavigationStore); ok {
+			if err := navigation.MigrateNavStore(ctx, oldStore, newStore); err != nil {
+				return multierr.Combine(err, newStore.Close(ctx))
+			}
+		}
+		if svc.store != nil {
+			if err := svc.store.Close(ctx); err != nil {
+				svc.logger.CWarnw(ctx, "failed to close previous navigation store", "error", err)
+			}
+		}
 		svc.store = newStore
 		svc.storeType = string(storeCfg.Type)
 	}
@@ -395,6 +427,7 @@ func (svc *builtIn) reconfigure(ctx context.Context, deps resource.Dependencies,
 	svc.boundingRegions = newBoundingRegions
 	svc.replanCostFactor = replanCostFactor
 	svc.visionServicesByName = visionServicesByName
+	svc.actionResources = actionResources
 	svc.motionCfg = &motion.MotionConfiguration{
 		ObstacleDetectors:     obstacleDetectorNamePairs,
 		LinearMPerSec:         metersPerSec,
@@ -543,6 +576,8 @@ func (svc *builtIn) Close(ctx context.Context) error {
 }
 
 func (svc *builtIn) moveToWaypoint(ctx context.Context, wp navigation.Waypoint, extra map[string]interface{}) error {
+	// obstacles and bounding regions may be added by DoCommand while navigating
+	svc.mu.RLock()
 	req := motion.MoveOnGlobeReq{
 		ComponentName:      svc.base.Name().Name,
 		Destination:        wp.ToPoint(),
@@ -553,6 +588,7 @@ func (svc *builtIn) moveToWaypoint(ctx context.Context, wp navigation.Waypoint,
 		BoundingRegions:    svc.boundingRegions,
 		Extra:              extra,
 	}
+	svc.mu.RUnlock()
 	cancelCtx, cancelFn := context.WithCancel(ctx)
 	defer cancelFn()
 	executionID, err := svc.motionService.MoveOnGlobe(cancelCtx, req)
@@ -592,11 +628,7 @@ func (svc *builtIn) moveToWaypoint(ctx context.Context, wp navigation.Waypoint,
 			LastPlanOnly:  true,
 		},
 	)
-	if err != nil {
-		return err
-	}
-
-	return svc.waypointReached(cancelCtx)
+	return err
 }
 
 func (svc *builtIn) startWaypointMode(ctx context.Context, extra map[string]interface{}) {
@@ -614,6 +646,22 @@ func (svc *builtIn) startWaypointMode(ctx context.Context, extra map[string]inte
 				return
 			}
 
+			// the current mission, if any, takes precedence over the waypoint queue
+			mission, err := svc.store.Mission(ctx)
+			if err != nil {
+				svc.logger.CWarnf(ctx, "unable to get the current mission: %s", err)
+				utils.SelectContextOrWait(ctx, planHistoryPollFrequency)
+				continue
+			}
+			if mission != nil {
+				if mission.Paused {
+					utils.SelectContextOrWait(ctx, planHistoryPollFrequency)
+					continue
+				}
+				svc.driveMission(ctx, *mission, extra)
+				continue
+			}
+
 			wp, err := svc.store.NextWaypoint(ctx)
 			if err != nil {
 				time.Sleep(planHistoryPollFrequency)
@@ -626,7 +674,11 @@ func (svc *builtIn) startWaypointMode(ctx context.Context, extra map[string]inte
 			svc.mu.Unlock()
 
 			svc.logger.CInfof(ctx, "navigating to waypoint: %+v", wp)
-			if err := svc.moveToWaypoint(cancelCtx, wp, extra); err != nil {
+			err = svc.moveToWaypoint(cancelCtx, wp, extra)
+			if err == nil {
+				err = svc.waypointReached(cancelCtx)
+			}
+			if err != nil {
 				if svc.waypointIsDeleted() {
 					svc.logger.CInfof(ctx, "skipping waypoint %+v since it was deleted", wp)
 					continue
@@ -639,6 +691,17 @@ func (svc *builtIn) startWaypointMode(ctx context.Context, extra map[string]inte
 	}, svc.activeBackgroundWorkers.Done)
 }
 
+// actionResourceFromDeps finds the dependency with the given name, which may be either a short name or a fully
+// qualified resource name.
+func actionResourceFromDeps(deps resource.Dependencies, name string) (resource.Resource, error) {
+	for depName, dep := range deps {
+		if depName.ShortName() == name || depName.String() == name {
+			return dep, nil
+		}
+	}
+	return nil, errors.Errorf("action resource %q not found in dependencies", name)
+}
+
 func (svc *builtIn) stopActiveMode() {
 	if svc.wholeServiceCancelFunc != nil {
 		svc.wholeServiceCancelFunc()
@@ -0,0 +1,88 @@
+package builtin
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/services/navigation"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+const (
+	coverageBoundingRegionKey = "bounding_region"
+	coverageDryRunKey         = "dry_run"
+)
+
+// handleCoverageCommand handles the navigation.DoPlanCoverage command.
+func (svc *builtIn) handleCoverageCommand(
+	ctx context.Context,
+	cmd map[string]interface{},
+) (map[string]interface{}, bool, error) {
+	req, ok := cmd[navigation.DoPlanCoverage]
+	if !ok {
+		return nil, false, nil
+	}
+	args, err := rdkutils.AssertType[map[string]interface{}](req)
+	if err != nil {
+		return nil, true, err
+	}
+	var cfg navigation.CoverageConfig
+	if err := remarshal(args, &cfg); err != nil {
+		return nil, true, errors.Wrapf(err, "invalid %s command", navigation.DoPlanCoverage)
+	}
+	label, _ := args[coverageBoundingRegionKey].(string)
+	dryRun, _ := args[coverageDryRunKey].(bool)
+
+	svc.mu.RLock()
+	region, err := svc.coverageRegion(label)
+	obstacles := svc.obstacles
+	svc.mu.RUnlock()
+	if err != nil {
+		return nil, true, err
+	}
+
+	wps, err := navigation.PlanCoverage(region, obstacles, cfg)
+	if err != nil {
+		return nil, true, err
+	}
+	points := make([]interface{}, 0, len(wps))
+	for _, wp := range wps {
+		points = append(points, []interface{}{wp.Lat(), wp.Lng()})
+	}
+	if !dryRun {
+		for _, wp := range wps {
+			if _, err := svc.store.AddWaypoint(ctx, wp); err != nil {
+				return nil, true, err
+			}
+		}
+		svc.logger.CInfof(ctx, "enqueued %d coverage waypoints", len(wps))
+	}
+	return map[string]interface{}{
+		navigation.DoPlanCoverage: map[string]interface{}{
+			"waypoints": points,
+			"enqueued":  !dryRun,
+		},
+	}, true, nil
+}
+
+// coverageRegion returns the bounding region with a geometry labelled label, or the only bounding region if label is
+// empty. svc.mu must be held.
+func (svc *builtIn) coverageRegion(label string) (*spatialmath.GeoGeometry, error) {
+	if label == "" {
+		if len(svc.boundingRegions) != 1 {
+			return nil, errors.Errorf("there are %d bounding regions, %q is required to choose which to cover",
+				len(svc.boundingRegions), coverageBoundingRegionKey)
+		}
+		return svc.boundingRegions[0], nil
+	}
+	for _, region := range svc.boundingRegions {
+		for _, g := range region.Geometries() {
+			if g.Label() == label {
+				return region, nil
+			}
+		}
+	}
+	return nil, errors.Errorf("no bounding region labelled %q", label)
+}
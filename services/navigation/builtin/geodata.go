@@ -0,0 +1,149 @@
+package builtin
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/services/navigation"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+const (
+	geoFormatKey = "format"
+	geoDataKey   = "data"
+)
+
+// handleGeoCommand handles the navigation.DoImportGeo and navigation.DoExportGeo commands.
+func (svc *builtIn) handleGeoCommand(
+	ctx context.Context,
+	cmd map[string]interface{},
+) (map[string]interface{}, bool, error) {
+	resp := make(map[string]interface{})
+
+	if req, ok := cmd[navigation.DoImportGeo]; ok {
+		args, err := rdkutils.AssertType[map[string]interface{}](req)
+		if err != nil {
+			return nil, true, err
+		}
+		format, err := geoFormatArg(args)
+		if err != nil {
+			return nil, true, err
+		}
+		data, err := rdkutils.AssertType[string](args[geoDataKey])
+		if err != nil {
+			return nil, true, errors.Wrapf(err, "%s requires %q", navigation.DoImportGeo, geoDataKey)
+		}
+		gd, err := navigation.UnmarshalGeoData(format, []byte(data))
+		if err != nil {
+			return nil, true, err
+		}
+		imported, err := svc.importGeoData(ctx, gd)
+		if err != nil {
+			return nil, true, err
+		}
+		resp[navigation.DoImportGeo] = imported
+		return resp, true, nil
+	}
+
+	if req, ok := cmd[navigation.DoExportGeo]; ok {
+		args, err := rdkutils.AssertType[map[string]interface{}](req)
+		if err != nil {
+			return nil, true, err
+		}
+		format, err := geoFormatArg(args)
+		if err != nil {
+			return nil, true, err
+		}
+		gd, err := svc.exportGeoData(ctx)
+		if err != nil {
+			return nil, true, err
+		}
+		data, err := navigation.MarshalGeoData(format, gd)
+		if err != nil {
+			return nil, true, err
+		}
+		resp[navigation.DoExportGeo] = map[string]interface{}{geoDataKey: string(data)}
+		return resp, true, nil
+	}
+
+	return nil, false, nil
+}
+
+func geoFormatArg(args map[string]interface{}) (navigation.GeoFormat, error) {
+	format, err := rdkutils.AssertType[string](args[geoFormatKey])
+	if err != nil {
+		return "", errors.Wrapf(err, "%q is required", geoFormatKey)
+	}
+	return navigation.GeoFormat(format), nil
+}
+
+// importGeoData adds imported waypoints to the store and imported obstacles and bounding regions to those the service
+// is configured with, returning how many waypoints were added and the configs of the obstacles and bounding regions.
+func (svc *builtIn) importGeoData(ctx context.Context, gd *navigation.GeoData) (map[string]interface{}, error) {
+	obstacleCfgs, err := geoGeometryConfigs(gd.Obstacles)
+	if err != nil {
+		return nil, err
+	}
+	boundingRegionCfgs, err := geoGeometryConfigs(gd.BoundingRegions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pt := range gd.Waypoints {
+		if _, err := svc.store.AddWaypoint(ctx, pt); err != nil {
+			return nil, err
+		}
+	}
+
+	svc.mu.Lock()
+	// clipped so that appending to the obstacles elsewhere never writes into a shared backing array
+	svc.obstacles = slices.Clip(slices.Concat(svc.obstacles, gd.Obstacles))
+	svc.boundingRegions = slices.Clip(slices.Concat(svc.boundingRegions, gd.BoundingRegions))
+	svc.mu.Unlock()
+
+	svc.logger.CInfof(ctx, "imported %d waypoints, %d obstacles and %d bounding regions",
+		len(gd.Waypoints), len(gd.Obstacles), len(gd.BoundingRegions))
+	return map[string]interface{}{
+		"waypoints":        len(gd.Waypoints),
+		"obstacles":        obstacleCfgs,
+		"bounding_regions": boundingRegionCfgs,
+	}, nil
+}
+
+func (svc *builtIn) exportGeoData(ctx context.Context) (*navigation.GeoData, error) {
+	wps, err := svc.store.Waypoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gd := &navigation.GeoData{}
+	for _, wp := range wps {
+		gd.Waypoints = append(gd.Waypoints, wp.ToPoint())
+	}
+
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+	gd.Obstacles = svc.obstacles
+	gd.BoundingRegions = svc.boundingRegions
+	return gd, nil
+}
+
+// geoGeometryConfigs returns the configs of GeoGeometries in the form used by the obstacles and bounding_regions
+// attributes of the service config.
+func geoGeometryConfigs(ggs []*spatialmath.GeoGeometry) ([]interface{}, error) {
+	cfgs := make([]*spatialmath.GeoGeometryConfig, 0, len(ggs))
+	for _, gg := range ggs {
+		cfg, err := spatialmath.NewGeoGeometryConfig(gg)
+		if err != nil {
+			return nil, err
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	var out []interface{}
+	if err := remarshal(cfgs, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,371 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/navigation"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// export keys to be used with DoCommand so they can be referenced by clients.
+const (
+	// DoSaveRoute adds or replaces a route, given as a map in the form of a navigation.Route.
+	DoSaveRoute = "save_route"
+	// DoRemoveRoute removes the named route.
+	DoRemoveRoute = "remove_route"
+	// DoRoutes returns all saved routes.
+	DoRoutes = "routes"
+	// DoStartMission starts driving the named route, replacing any current mission. Missions are only driven in
+	// waypoint mode and take precedence over the waypoint queue.
+	DoStartMission = "start_mission"
+	// DoPauseMission pauses the current mission, stopping the base where it is.
+	DoPauseMission = "pause_mission"
+	// DoResumeMission resumes the current mission from the waypoint it was driving to.
+	DoResumeMission = "resume_mission"
+	// DoStopMission abandons the current mission.
+	DoStopMission = "stop_mission"
+	// DoMission returns the current mission, or nil if there is none.
+	DoMission = "mission"
+	// DoWaypointHistory returns every waypoint added, visited or not, with when it was added and visited. Only stores
+	// which keep visited waypoints, such as the bolt store, support it.
+	DoWaypointHistory = "waypoint_history"
+)
+
+// waypointHistoryStore is a navigation.NavStore which keeps a history of the waypoints visited.
+type waypointHistoryStore interface {
+	WaypointHistory(ctx context.Context) ([]navigation.WaypointRecord, error)
+}
+
+var errNoMission = errors.New("there is no current mission")
+
+func (svc *builtIn) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if resp, handled, err := svc.handleGeoCommand(ctx, cmd); handled {
+		return resp, err
+	}
+	if resp, handled, err := svc.handleCoverageCommand(ctx, cmd); handled {
+		return resp, err
+	}
+
+	resp := make(map[string]interface{})
+
+	if req, ok := cmd[DoSaveRoute]; ok {
+		var route navigation.Route
+		if err := remarshal(req, &route); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s command", DoSaveRoute)
+		}
+		if err := svc.saveRoute(ctx, route); err != nil {
+			return nil, err
+		}
+		resp[DoSaveRoute] = route.Name
+		return resp, nil
+	}
+
+	if req, ok := cmd[DoRemoveRoute]; ok {
+		name, err := rdkutils.AssertType[string](req)
+		if err != nil {
+			return nil, err
+		}
+		if err := svc.removeRoute(ctx, name); err != nil {
+			return nil, err
+		}
+		resp[DoRemoveRoute] = name
+		return resp, nil
+	}
+
+	if _, ok := cmd[DoRoutes]; ok {
+		routes, err := svc.store.Routes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var routesResp []interface{}
+		if err := remarshal(routes, &routesResp); err != nil {
+			return nil, err
+		}
+		resp[DoRoutes] = routesResp
+		return resp, nil
+	}
+
+	if req, ok := cmd[DoStartMission]; ok {
+		name, err := rdkutils.AssertType[string](req)
+		if err != nil {
+			return nil, err
+		}
+		if err := svc.startMission(ctx, name); err != nil {
+			return nil, err
+		}
+		resp[DoStartMission] = name
+		return resp, nil
+	}
+
+	if _, ok := cmd[DoPauseMission]; ok {
+		if err := svc.setMissionPaused(ctx, true); err != nil {
+			return nil, err
+		}
+		resp[DoPauseMission] = true
+		return resp, nil
+	}
+
+	if _, ok := cmd[DoResumeMission]; ok {
+		if err := svc.setMissionPaused(ctx, false); err != nil {
+			return nil, err
+		}
+		resp[DoResumeMission] = true
+		return resp, nil
+	}
+
+	if _, ok := cmd[DoStopMission]; ok {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		if err := svc.store.ClearMission(ctx); err != nil {
+			return nil, err
+		}
+		svc.cancelCurrentWaypoint()
+		resp[DoStopMission] = true
+		return resp, nil
+	}
+
+	if _, ok := cmd[DoMission]; ok {
+		mission, err := svc.store.Mission(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp[DoMission] = nil
+		if mission != nil {
+			var missionResp map[string]interface{}
+			if err := remarshal(mission, &missionResp); err != nil {
+				return nil, err
+			}
+			resp[DoMission] = missionResp
+		}
+		return resp, nil
+	}
+
+	if _, ok := cmd[DoWaypointHistory]; ok {
+		history, err := svc.waypointHistory(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp[DoWaypointHistory] = history
+		return resp, nil
+	}
+
+	return nil, resource.ErrDoUnimplemented
+}
+
+func (svc *builtIn) saveRoute(ctx context.Context, route navigation.Route) error {
+	if err := route.Validate(); err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	for i, wp := range route.Waypoints {
+		if wp.Action == nil {
+			continue
+		}
+		if _, ok := svc.actionResources[wp.Action.Resource]; !ok {
+			return errors.Errorf("waypoint %d of route %q has an action on %q, which is not one of the configured action_resources",
+				i, route.Name, wp.Action.Resource)
+		}
+	}
+	if err := svc.checkRouteNotInUse(ctx, route.Name); err != nil {
+		return err
+	}
+	svc.logger.CInfof(ctx, "saving route %q with %d waypoints", route.Name, len(route.Waypoints))
+	return svc.store.SaveRoute(ctx, route)
+}
+
+func (svc *builtIn) removeRoute(ctx context.Context, name string) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if err := svc.checkRouteNotInUse(ctx, name); err != nil {
+		return err
+	}
+	svc.logger.CInfof(ctx, "removing route %q", name)
+	return svc.store.RemoveRoute(ctx, name)
+}
+
+// checkRouteNotInUse returns an error if the current mission is driving the named route, as changing it would
+// invalidate the progress of the mission. It must be called with svc.mu held.
+func (svc *builtIn) checkRouteNotInUse(ctx context.Context, name string) error {
+	mission, err := svc.store.Mission(ctx)
+	if err != nil {
+		return err
+	}
+	if mission != nil && mission.Route == name {
+		return errors.Errorf("route %q is in use by the current mission", name)
+	}
+	return nil
+}
+
+func (svc *builtIn) startMission(ctx context.Context, name string) error {
+	if _, err := svc.store.Route(ctx, name); err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.logger.CInfof(ctx, "starting mission on route %q", name)
+	if err := svc.store.SetMission(ctx, navigation.Mission{Route: name}); err != nil {
+		return err
+	}
+	svc.cancelCurrentWaypoint()
+	return nil
+}
+
+func (svc *builtIn) setMissionPaused(ctx context.Context, paused bool) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	mission, err := svc.store.Mission(ctx)
+	if err != nil {
+		return err
+	}
+	if mission == nil {
+		return errNoMission
+	}
+	mission.Paused = paused
+	if err := svc.store.SetMission(ctx, *mission); err != nil {
+		return err
+	}
+	if paused {
+		svc.cancelCurrentWaypoint()
+	}
+	return nil
+}
+
+// cancelCurrentWaypoint stops the base driving to the waypoint in progress, which the waypoint loop then retries or
+// replaces. It must be called with svc.mu held.
+func (svc *builtIn) cancelCurrentWaypoint() {
+	if svc.currentWaypointCancelFunc != nil {
+		svc.currentWaypointCancelFunc()
+	}
+}
+
+// driveMission drives to the next waypoint of the current mission, runs its action and waits out its dwell before
+// advancing the mission. If the mission is changed in the meantime its progress is left as is.
+func (svc *builtIn) driveMission(ctx context.Context, mission navigation.Mission, extra map[string]interface{}) {
+	route, err := svc.store.Route(ctx, mission.Route)
+	if err != nil {
+		svc.logger.CWarnf(ctx, "unable to load route %q of the current mission: %s", mission.Route, err)
+		utils.SelectContextOrWait(ctx, planHistoryPollFrequency)
+		return
+	}
+	if mission.Index >= len(route.Waypoints) {
+		svc.logger.CWarnf(ctx, "stopping mission since route %q has no waypoint %d", route.Name, mission.Index)
+		svc.finishMission(ctx, mission, nil)
+		return
+	}
+	rwp := route.Waypoints[mission.Index]
+	wp := navigation.Waypoint{ID: primitive.NewObjectID(), Lat: rwp.Lat, Long: rwp.Long}
+
+	svc.mu.Lock()
+	svc.waypointInProgress = &wp
+	cancelCtx, cancelFunc := context.WithCancel(ctx)
+	svc.currentWaypointCancelFunc = cancelFunc
+	svc.mu.Unlock()
+	defer cancelFunc()
+
+	svc.logger.CInfof(ctx, "navigating to waypoint %d of route %q: %+v", mission.Index, route.Name, rwp)
+	if err := svc.moveToWaypoint(cancelCtx, wp, extra); err != nil {
+		if cancelCtx.Err() == nil {
+			svc.logger.CWarnf(ctx, "retrying navigation to waypoint %d of route %q since it errored out: %s", mission.Index, route.Name, err)
+		}
+		return
+	}
+	svc.logger.CInfof(ctx, "reached waypoint %d of route %q", mission.Index, route.Name)
+
+	if rwp.Action != nil {
+		svc.runWaypointAction(cancelCtx, *rwp.Action)
+	}
+	if rwp.DwellSec > 0 && !utils.SelectContextOrWait(cancelCtx, time.Duration(rwp.DwellSec*float64(time.Second))) {
+		return
+	}
+
+	next, done := mission.Advance(route)
+	if done {
+		svc.logger.CInfof(ctx, "completed mission on route %q", route.Name)
+		svc.finishMission(ctx, mission, nil)
+		return
+	}
+	svc.finishMission(ctx, mission, &next)
+}
+
+// finishMission replaces the mission with next, or clears it if next is nil, provided the mission has not been
+// changed since it was read.
+func (svc *builtIn) finishMission(ctx context.Context, mission navigation.Mission, next *navigation.Mission) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	current, err := svc.store.Mission(ctx)
+	if err != nil {
+		svc.logger.CErrorf(ctx, "unable to update mission: %s", err)
+		return
+	}
+	if current == nil || *current != mission {
+		return
+	}
+	if next == nil {
+		err = svc.store.ClearMission(ctx)
+	} else {
+		err = svc.store.SetMission(ctx, *next)
+	}
+	if err != nil {
+		svc.logger.CErrorf(ctx, "unable to update mission: %s", err)
+	}
+}
+
+// runWaypointAction sends the DoCommand of an action to its resource. Failures are logged rather than stopping the
+// mission.
+func (svc *builtIn) runWaypointAction(ctx context.Context, action navigation.WaypointAction) {
+	svc.mu.RLock()
+	res, ok := svc.actionResources[action.Resource]
+	svc.mu.RUnlock()
+	if !ok {
+		svc.logger.CErrorf(ctx, "skipping waypoint action since %q is not one of the configured action_resources", action.Resource)
+		return
+	}
+	if _, err := res.DoCommand(ctx, action.Command); err != nil {
+		svc.logger.CErrorf(ctx, "waypoint action on %q failed: %s", action.Resource, err)
+	}
+}
+
+// remarshal converts between a DoCommand value and its typed form by way of JSON.
+func remarshal(from, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
+
+func (svc *builtIn) waypointHistory(ctx context.Context) ([]interface{}, error) {
+	store, ok := svc.store.(waypointHistoryStore)
+	if !ok {
+		return nil, errors.Errorf("the %q store does not keep a waypoint history", svc.storeType)
+	}
+	records, err := store.WaypointHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		entry := map[string]interface{}{
+			"id":        record.ID.Hex(),
+			"latitude":  record.Lat,
+			"longitude": record.Long,
+			"visited":   record.Visited,
+			"added_at":  record.AddedAt.Format(time.RFC3339Nano),
+		}
+		if record.VisitedAt != nil {
+			entry["visited_at"] = record.VisitedAt.Format(time.RFC3339Nano)
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
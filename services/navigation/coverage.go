@@ -0,0 +1,232 @@
+package navigation
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+// DoPlanCoverage is the DoCommand key which plans a coverage pattern over a bounding region and enqueues it as
+// waypoints. It is given a CoverageConfig along with the label of the bounding region to cover as "bounding_region",
+// which may be omitted if there is only one, and "dry_run" to return the waypoints without enqueueing them.
+const DoPlanCoverage = "plan_coverage"
+
+// CoverageConfig describes a boustrophedon coverage pattern, which covers a region with parallel passes driven in
+// alternating directions.
+type CoverageConfig struct {
+	// SwathWidthM is the width covered by a single pass, and so the distance between neighbouring passes.
+	SwathWidthM float64 `json:"swath_width_m"`
+	// HeadingDeg is the compass heading of the passes. The first pass is on the far left of the region, looking along
+	// the heading, and is driven along the heading, with each pass after it driven the opposite way.
+	HeadingDeg float64 `json:"heading_deg,omitempty"`
+	// HeadlandWidthM is the margin left at both ends of each pass, where the base turns between passes, and around
+	// obstacles.
+	HeadlandWidthM float64 `json:"headland_width_m,omitempty"`
+	// MinTurnRadiusM is the tightest turn the base can make. When a turn between neighbouring passes would be tighter,
+	// passes are driven out of order so that consecutive passes are far enough apart to turn between.
+	MinTurnRadiusM float64 `json:"min_turn_radius_m,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *CoverageConfig) Validate() error {
+	if cfg.SwathWidthM <= 0 {
+		return errors.New("swath_width_m must be positive")
+	}
+	if cfg.HeadlandWidthM < 0 {
+		return errors.New("headland_width_m must be non-negative")
+	}
+	if cfg.MinTurnRadiusM < 0 {
+		return errors.New("min_turn_radius_m must be non-negative")
+	}
+	return nil
+}
+
+// maximum number of passes planned, so that a swath width given in the wrong units fails rather than planning forever.
+const maxCoveragePasses = 10000
+
+// PlanCoverage returns the waypoints of a boustrophedon pattern covering the footprint of region while avoiding the
+// footprints of obstacles. Each pass is given by a waypoint at either end. Where an obstacle or a concave part of the
+// region splits a pass, the pieces are driven one after the other and the base is left to find its way around the
+// obstacle between them, as MoveOnGlobe is given the obstacles when driving to each waypoint.
+func PlanCoverage(region *spatialmath.GeoGeometry, obstacles []*spatialmath.GeoGeometry, cfg CoverageConfig) ([]*geo.Point, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	regionFootprints := spatialmath.GeoGeometryFootprints(region)
+	if len(regionFootprints) != 1 || len(regionFootprints[0]) < 3 {
+		return nil, errors.New("the region to cover must be a single polygon")
+	}
+
+	// work in meters in a frame whose x axis runs along the passes, so that passes are lines of constant y
+	origin := region.Location()
+	heading := utils.DegToRad(cfg.HeadingDeg)
+	along := r3.Vector{X: math.Sin(heading), Y: math.Cos(heading)}
+	across := r3.Vector{X: math.Cos(heading), Y: -math.Sin(heading)}
+	toLocal := func(pts []*geo.Point) []r3.Vector {
+		local := make([]r3.Vector, 0, len(pts))
+		for _, pt := range pts {
+			p := spatialmath.GeoPointToPoint(pt, origin).Mul(1e-3)
+			local = append(local, r3.Vector{X: p.Dot(along), Y: p.Dot(across)})
+		}
+		return local
+	}
+	toGeo := func(p r3.Vector) *geo.Point {
+		return spatialmath.PointToGeoPoint(along.Mul(1e3*p.X).Add(across.Mul(1e3*p.Y)), origin)
+	}
+
+	boundary := toLocal(regionFootprints[0])
+	var obstaclePolygons [][]r3.Vector
+	for _, obstacle := range obstacles {
+		for _, footprint := range spatialmath.GeoGeometryFootprints(obstacle) {
+			obstaclePolygons = append(obstaclePolygons, toLocal(footprint))
+		}
+	}
+
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range boundary {
+		minY = math.Min(minY, p.Y)
+		maxY = math.Max(maxY, p.Y)
+	}
+	// allowing for the error in projecting the region onto the ground, so that an exact fit doesn't add a pass
+	numPasses := max(1, int(math.Ceil((maxY-minY)/cfg.SwathWidthM-1e-3)))
+	if numPasses > maxCoveragePasses {
+		return nil, errors.Errorf("covering the region takes more than %d passes, check swath_width_m", maxCoveragePasses)
+	}
+	// center the passes within the region, so any part of the region left uncovered is split between the sides
+	firstY := (minY+maxY)/2 - float64(numPasses-1)*cfg.SwathWidthM/2
+
+	type pass struct {
+		y        float64
+		segments [][2]float64
+	}
+	passes := make([]pass, 0, numPasses)
+	for i := range numPasses {
+		y := firstY + float64(i)*cfg.SwathWidthM
+		segments := trimSegments(polygonLineSegments(boundary, y), cfg.HeadlandWidthM)
+		for _, polygon := range obstaclePolygons {
+			lo, hi, ok := polygonBandExtent(polygon, y-cfg.SwathWidthM/2, y+cfg.SwathWidthM/2)
+			if ok {
+				segments = subtractSegment(segments, lo-cfg.HeadlandWidthM, hi+cfg.HeadlandWidthM)
+			}
+		}
+		if len(segments) > 0 {
+			passes = append(passes, pass{y: y, segments: segments})
+		}
+	}
+	if len(passes) == 0 {
+		return nil, errors.New("no passes fit in the region, it may be narrower than the swath width or headlands")
+	}
+
+	waypoints := []*geo.Point{}
+	forward := true
+	for _, i := range coveragePassOrder(len(passes), cfg.SwathWidthM, cfg.MinTurnRadiusM) {
+		y, segments := passes[i].y, passes[i].segments
+		for j := range segments {
+			segment := segments[j]
+			if !forward {
+				segment = segments[len(segments)-1-j]
+				segment[0], segment[1] = segment[1], segment[0]
+			}
+			waypoints = append(waypoints, toGeo(r3.Vector{X: segment[0], Y: y}), toGeo(r3.Vector{X: segment[1], Y: y}))
+		}
+		forward = !forward
+	}
+	return waypoints, nil
+}
+
+// coveragePassOrder returns the order in which to drive the passes. Neighbouring passes are driven one after the other
+// unless the base cannot turn between them, in which case every stride'th pass is driven, stepping back across the
+// field for each remaining set of passes.
+func coveragePassOrder(numPasses int, swathWidthM, minTurnRadiusM float64) []int {
+	stride := max(1, int(math.Ceil(2*minTurnRadiusM/swathWidthM-1e-9)))
+	order := make([]int, 0, numPasses)
+	for offset := range min(stride, numPasses) {
+		var set []int
+		for i := offset; i < numPasses; i += stride {
+			set = append(set, i)
+		}
+		if offset%2 == 1 {
+			for l, r := 0, len(set)-1; l < r; l, r = l+1, r-1 {
+				set[l], set[r] = set[r], set[l]
+			}
+		}
+		order = append(order, set...)
+	}
+	return order
+}
+
+// polygonLineSegments returns the sorted segments of the line of constant y that lie inside the polygon, as pairs of
+// x coordinates.
+func polygonLineSegments(polygon []r3.Vector, y float64) [][2]float64 {
+	var xs []float64
+	for i, p := range polygon {
+		q := polygon[(i+1)%len(polygon)]
+		// half open so that a vertex on the line is counted once
+		if (p.Y <= y) == (q.Y <= y) {
+			continue
+		}
+		xs = append(xs, p.X+(y-p.Y)*(q.X-p.X)/(q.Y-p.Y))
+	}
+	sort.Float64s(xs)
+	segments := make([][2]float64, 0, len(xs)/2)
+	for i := 0; i+1 < len(xs); i += 2 {
+		segments = append(segments, [2]float64{xs[i], xs[i+1]})
+	}
+	return segments
+}
+
+// polygonBandExtent returns the range of x covered by the part of the polygon between the lines y = lo and y = hi.
+func polygonBandExtent(polygon []r3.Vector, lo, hi float64) (float64, float64, bool) {
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	include := func(x float64) {
+		minX = math.Min(minX, x)
+		maxX = math.Max(maxX, x)
+	}
+	for i, p := range polygon {
+		if p.Y >= lo && p.Y <= hi {
+			include(p.X)
+		}
+		q := polygon[(i+1)%len(polygon)]
+		for _, y := range []float64{lo, hi} {
+			if (p.Y < y) != (q.Y < y) {
+				include(p.X + (y-p.Y)*(q.X-p.X)/(q.Y-p.Y))
+			}
+		}
+	}
+	return minX, maxX, minX <= maxX
+}
+
+// trimSegments shortens both ends of each segment by margin, dropping segments which are too short.
+func trimSegments(segments [][2]float64, margin float64) [][2]float64 {
+	trimmed := make([][2]float64, 0, len(segments))
+	for _, s := range segments {
+		if s[1]-s[0] > 2*margin {
+			trimmed = append(trimmed, [2]float64{s[0] + margin, s[1] - margin})
+		}
+	}
+	return trimmed
+}
+
+// subtractSegment removes the range lo to hi from the sorted segments.
+func subtractSegment(segments [][2]float64, lo, hi float64) [][2]float64 {
+	result := make([][2]float64, 0, len(segments)+1)
+	for _, s := range segments {
+		if hi <= s[0] || lo >= s[1] {
+			result = append(result, s)
+			continue
+		}
+		if lo > s[0] {
+			result = append(result, [2]float64{s[0], lo})
+		}
+		if hi < s[1] {
+			result = append(result, [2]float64{hi, s[1]})
+		}
+	}
+	return result
+}
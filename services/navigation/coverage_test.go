@@ -0,0 +1,99 @@
+package navigation_test
+
+import (
+	"testing"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/services/navigation"
+	"go.viam.com/rdk/spatialmath"
+)
+
+func TestPlanCoverage(t *testing.T) {
+	origin := geo.NewPoint(40, -74)
+	// rectangle returns a prism whose footprint spans the given x (east) and y (north) ranges in meters from origin.
+	rectangle := func(minX, maxX, minY, maxY float64) *spatialmath.GeoGeometry {
+		var corners []*geo.Point
+		for _, c := range [][2]float64{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}} {
+			corners = append(corners, spatialmath.PointToGeoPoint(r3.Vector{X: 1e3 * c[0], Y: 1e3 * c[1]}, origin))
+		}
+		gg, err := spatialmath.NewGeoPolygonPrism(corners, 1000, "")
+		test.That(t, err, test.ShouldBeNil)
+		return gg
+	}
+	local := func(pts []*geo.Point) []r3.Vector {
+		out := make([]r3.Vector, 0, len(pts))
+		for _, pt := range pts {
+			out = append(out, spatialmath.GeoPointToPoint(pt, origin).Mul(1e-3))
+		}
+		return out
+	}
+	checkPoints := func(got []r3.Vector, want [][2]float64) {
+		t.Helper()
+		test.That(t, got, test.ShouldHaveLength, len(want))
+		for i, w := range want {
+			test.That(t, got[i].X, test.ShouldAlmostEqual, w[0], 0.05)
+			test.That(t, got[i].Y, test.ShouldAlmostEqual, w[1], 0.05)
+		}
+	}
+
+	// a field 100m east-west and 40m north-south
+	field := rectangle(0, 100, 0, 40)
+
+	t.Run("passes east and west", func(t *testing.T) {
+		wps, err := navigation.PlanCoverage(field, nil, navigation.CoverageConfig{SwathWidthM: 10, HeadingDeg: 90})
+		test.That(t, err, test.ShouldBeNil)
+		checkPoints(local(wps), [][2]float64{
+			{0, 35}, {100, 35},
+			{100, 25}, {0, 25},
+			{0, 15}, {100, 15},
+			{100, 5}, {0, 5},
+		})
+	})
+
+	t.Run("passes north and south with headlands", func(t *testing.T) {
+		wps, err := navigation.PlanCoverage(field, nil, navigation.CoverageConfig{SwathWidthM: 25, HeadlandWidthM: 5})
+		test.That(t, err, test.ShouldBeNil)
+		checkPoints(local(wps), [][2]float64{
+			{12.5, 5}, {12.5, 35},
+			{37.5, 35}, {37.5, 5},
+			{62.5, 5}, {62.5, 35},
+			{87.5, 35}, {87.5, 5},
+		})
+	})
+
+	t.Run("around an obstacle", func(t *testing.T) {
+		obstacle := rectangle(40, 60, 10, 20)
+		wps, err := navigation.PlanCoverage(field, []*spatialmath.GeoGeometry{obstacle},
+			navigation.CoverageConfig{SwathWidthM: 10, HeadingDeg: 90, HeadlandWidthM: 2})
+		test.That(t, err, test.ShouldBeNil)
+		checkPoints(local(wps), [][2]float64{
+			{2, 35}, {98, 35},
+			{98, 25}, {62, 25}, {38, 25}, {2, 25},
+			{2, 15}, {38, 15}, {62, 15}, {98, 15},
+			{98, 5}, {2, 5},
+		})
+	})
+
+	t.Run("wide turns", func(t *testing.T) {
+		wps, err := navigation.PlanCoverage(field, nil, navigation.CoverageConfig{SwathWidthM: 10, HeadingDeg: 90, MinTurnRadiusM: 8})
+		test.That(t, err, test.ShouldBeNil)
+		checkPoints(local(wps), [][2]float64{
+			{0, 35}, {100, 35},
+			{100, 15}, {0, 15},
+			{0, 5}, {100, 5},
+			{100, 25}, {0, 25},
+		})
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := navigation.PlanCoverage(field, nil, navigation.CoverageConfig{})
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = navigation.PlanCoverage(field, nil, navigation.CoverageConfig{SwathWidthM: 0.001})
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = navigation.PlanCoverage(field, nil, navigation.CoverageConfig{SwathWidthM: 10, HeadlandWidthM: 60, HeadingDeg: 90})
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,630 @@
+package navigation
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+// GeoFormat is a file format used to exchange waypoints, obstacles and bounding regions with GIS tools.
+type GeoFormat string
+
+// The supported GeoFormats.
+const (
+	GeoFormatGeoJSON GeoFormat = "geojson"
+	GeoFormatKML     GeoFormat = "kml"
+)
+
+// GeoFeatureKind is what an imported feature becomes.
+type GeoFeatureKind string
+
+// The kinds of feature, set by the "kind" property of a GeoJSON feature or the "kind" ExtendedData of a KML Placemark.
+// Features without a kind become waypoints if they are points or lines and obstacles if they are polygons. In KML a
+// Folder named after a kind, such as "Bounding Regions", sets the kind of the Placemarks in it.
+const (
+	GeoFeatureWaypoint       GeoFeatureKind = "waypoint"
+	GeoFeatureObstacle       GeoFeatureKind = "obstacle"
+	GeoFeatureBoundingRegion GeoFeatureKind = "bounding_region"
+)
+
+// DoCommand keys for exchanging waypoints, obstacles and bounding regions as GeoJSON or KML, exported here so that
+// clients need not depend on the builtin implementation.
+const (
+	// DoImportGeo imports the waypoints, obstacles and bounding regions of a GeoJSON or KML document, given as
+	// {"format": "geojson" | "kml", "data": "<document>"}. Waypoints are added to the store, while obstacles and bounding
+	// regions are used until the service is next reconfigured. Their configs are returned so they can be made permanent.
+	DoImportGeo = "import_geo"
+	// DoExportGeo returns the unvisited waypoints, obstacles and bounding regions as a GeoJSON or KML document, given
+	// {"format": "geojson" | "kml"}.
+	DoExportGeo = "export_geo"
+)
+
+// DefaultGeoPrismHeightMm is the height of the prism made from an imported polygon without a "height_mm" property.
+const DefaultGeoPrismHeightMm = 5000.
+
+// GeoData holds the waypoints, obstacles and bounding regions exchanged with GIS tools such as QGIS or Google Earth.
+// Imported polygons become GeoGeometries holding a prism, and exported obstacles and bounding regions are the
+// outlines of their geometries on the ground.
+type GeoData struct {
+	Waypoints       []*geo.Point
+	Obstacles       []*spatialmath.GeoGeometry
+	BoundingRegions []*spatialmath.GeoGeometry
+}
+
+// GeoFormatFromPath returns the GeoFormat of a file based on its extension.
+func GeoFormatFromPath(path string) (GeoFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".geojson", ".json":
+		return GeoFormatGeoJSON, nil
+	case ".kml":
+		return GeoFormatKML, nil
+	default:
+		return "", errors.Errorf("cannot determine the format of %q, expected a .geojson, .json or .kml file", path)
+	}
+}
+
+// UnmarshalGeoData parses GeoJSON or KML into GeoData.
+func UnmarshalGeoData(format GeoFormat, data []byte) (*GeoData, error) {
+	var features []geoFeature
+	var err error
+	switch format {
+	case GeoFormatGeoJSON:
+		features, err = parseGeoJSON(data)
+	case GeoFormatKML:
+		features, err = parseKML(data)
+	default:
+		return nil, errors.Errorf("unsupported geo format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gd := &GeoData{}
+	for i, f := range features {
+		if err := gd.add(f); err != nil {
+			if f.name != "" {
+				return nil, errors.Wrapf(err, "feature %q", f.name)
+			}
+			return nil, errors.Wrapf(err, "feature %d", i)
+		}
+	}
+	return gd, nil
+}
+
+// MarshalGeoData writes GeoData as GeoJSON or KML.
+func MarshalGeoData(format GeoFormat, gd *GeoData) ([]byte, error) {
+	features := gd.features()
+	switch format {
+	case GeoFormatGeoJSON:
+		return marshalGeoJSON(features)
+	case GeoFormatKML:
+		return marshalKML(features)
+	default:
+		return nil, errors.Errorf("unsupported geo format %q", format)
+	}
+}
+
+type geoFeatureShape int
+
+const (
+	geoShapePoint geoFeatureShape = iota
+	geoShapeLine
+	geoShapePolygon
+)
+
+// geoFeature is a single shape common to GeoJSON and KML, with a polygon given by its outer ring.
+type geoFeature struct {
+	name     string
+	kind     GeoFeatureKind
+	heightMm float64
+	shape    geoFeatureShape
+	points   []*geo.Point
+}
+
+func (gd *GeoData) add(f geoFeature) error {
+	kind := f.kind
+	if kind == "" {
+		kind = GeoFeatureWaypoint
+		if f.shape == geoShapePolygon {
+			kind = GeoFeatureObstacle
+		}
+	}
+
+	if kind == GeoFeatureWaypoint {
+		if f.shape == geoShapePolygon {
+			return errors.New("polygons cannot be waypoints")
+		}
+		gd.Waypoints = append(gd.Waypoints, f.points...)
+		return nil
+	}
+	if f.shape != geoShapePolygon {
+		return errors.Errorf("only polygons can be a %s", kind)
+	}
+	heightMm := f.heightMm
+	if heightMm == 0 {
+		heightMm = DefaultGeoPrismHeightMm
+	}
+	gg, err := spatialmath.NewGeoPolygonPrism(f.points, heightMm, f.name)
+	if err != nil {
+		return err
+	}
+	if kind == GeoFeatureObstacle {
+		gd.Obstacles = append(gd.Obstacles, gg)
+	} else {
+		gd.BoundingRegions = append(gd.BoundingRegions, gg)
+	}
+	return nil
+}
+
+func (gd *GeoData) features() []geoFeature {
+	var features []geoFeature
+	for i, wp := range gd.Waypoints {
+		features = append(features, geoFeature{
+			name:   fmt.Sprintf("waypoint %d", i),
+			kind:   GeoFeatureWaypoint,
+			shape:  geoShapePoint,
+			points: []*geo.Point{wp},
+		})
+	}
+	addPolygons := func(kind GeoFeatureKind, ggs []*spatialmath.GeoGeometry) {
+		for i, gg := range ggs {
+			footprints := spatialmath.GeoGeometryFootprints(gg)
+			for j, outline := range footprints {
+				if len(outline) < 3 {
+					continue
+				}
+				name := gg.Geometries()[j].Label()
+				if name == "" {
+					name = fmt.Sprintf("%s %d", kind, i)
+				}
+				// close the ring, as GeoJSON and KML require
+				ring := append(append([]*geo.Point{}, outline...), outline[0])
+				features = append(features, geoFeature{name: name, kind: kind, shape: geoShapePolygon, points: ring})
+			}
+		}
+	}
+	addPolygons(GeoFeatureObstacle, gd.Obstacles)
+	addPolygons(GeoFeatureBoundingRegion, gd.BoundingRegions)
+	return features
+}
+
+// parseGeoFeatureKind parses a kind, accepting plurals, any case and spaces or dashes in place of underscores.
+func parseGeoFeatureKind(s string) (GeoFeatureKind, bool) {
+	s = strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(s)))
+	s = strings.TrimSuffix(s, "s")
+	switch kind := GeoFeatureKind(s); kind {
+	case GeoFeatureWaypoint, GeoFeatureObstacle, GeoFeatureBoundingRegion:
+		return kind, true
+	default:
+		return "", false
+	}
+}
+
+type geoJSONObject struct {
+	Type string `json:"type"`
+
+	// FeatureCollection
+	Features []geoJSONObject `json:"features,omitempty"`
+
+	// Feature
+	Geometry   *geoJSONObject         `json:"geometry,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+
+	// Geometry
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []geoJSONObject `json:"geometries,omitempty"`
+}
+
+func parseGeoJSON(data []byte) ([]geoFeature, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, errors.Wrap(err, "invalid GeoJSON")
+	}
+	var features []geoFeature
+	if err := appendGeoJSONFeatures(&features, obj); err != nil {
+		return nil, err
+	}
+	return features, nil
+}
+
+func appendGeoJSONFeatures(features *[]geoFeature, obj geoJSONObject) error {
+	switch obj.Type {
+	case "FeatureCollection":
+		for _, f := range obj.Features {
+			if err := appendGeoJSONFeatures(features, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Feature":
+		if obj.Geometry == nil {
+			return nil
+		}
+		template := geoFeature{}
+		if name, ok := obj.Properties["name"].(string); ok {
+			template.name = name
+		}
+		if kindProp, ok := obj.Properties["kind"].(string); ok {
+			kind, ok := parseGeoFeatureKind(kindProp)
+			if !ok {
+				return errors.Errorf("unknown kind %q of feature %q", kindProp, template.name)
+			}
+			template.kind = kind
+		}
+		if height, ok := obj.Properties["height_mm"].(float64); ok {
+			template.heightMm = height
+		}
+		return appendGeoJSONGeometry(features, template, *obj.Geometry)
+	default:
+		return appendGeoJSONGeometry(features, geoFeature{}, obj)
+	}
+}
+
+func appendGeoJSONGeometry(features *[]geoFeature, template geoFeature, obj geoJSONObject) error {
+	add := func(shape geoFeatureShape, points []*geo.Point) {
+		f := template
+		f.shape = shape
+		f.points = points
+		*features = append(*features, f)
+	}
+
+	var err error
+	switch obj.Type {
+	case "Point":
+		var coords []float64
+		if err = json.Unmarshal(obj.Coordinates, &coords); err == nil {
+			var pt *geo.Point
+			if pt, err = geoJSONPoint(coords); err == nil {
+				add(geoShapePoint, []*geo.Point{pt})
+			}
+		}
+	case "MultiPoint", "LineString":
+		var coords [][]float64
+		if err = json.Unmarshal(obj.Coordinates, &coords); err == nil {
+			var pts []*geo.Point
+			if pts, err = geoJSONPoints(coords); err == nil {
+				if obj.Type == "LineString" {
+					add(geoShapeLine, pts)
+				} else {
+					for _, pt := range pts {
+						add(geoShapePoint, []*geo.Point{pt})
+					}
+				}
+			}
+		}
+	case "MultiLineString", "Polygon":
+		var coords [][][]float64
+		if err = json.Unmarshal(obj.Coordinates, &coords); err == nil {
+			if obj.Type == "Polygon" {
+				err = addGeoJSONPolygon(add, coords)
+			} else {
+				for _, line := range coords {
+					var pts []*geo.Point
+					if pts, err = geoJSONPoints(line); err != nil {
+						break
+					}
+					add(geoShapeLine, pts)
+				}
+			}
+		}
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err = json.Unmarshal(obj.Coordinates, &coords); err == nil {
+			for _, polygon := range coords {
+				if err = addGeoJSONPolygon(add, polygon); err != nil {
+					break
+				}
+			}
+		}
+	case "GeometryCollection":
+		for _, g := range obj.Geometries {
+			if err = appendGeoJSONGeometry(features, template, g); err != nil {
+				break
+			}
+		}
+	default:
+		return errors.Errorf("unsupported GeoJSON type %q", obj.Type)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "invalid GeoJSON %s", obj.Type)
+	}
+	return nil
+}
+
+func addGeoJSONPolygon(add func(geoFeatureShape, []*geo.Point), rings [][][]float64) error {
+	if len(rings) == 0 {
+		return errors.New("polygon has no rings")
+	}
+	if len(rings) > 1 {
+		return errors.New("polygons with holes are not supported")
+	}
+	pts, err := geoJSONPoints(rings[0])
+	if err != nil {
+		return err
+	}
+	add(geoShapePolygon, pts)
+	return nil
+}
+
+// geoJSONPoint converts a GeoJSON position, which is longitude first, to a geo.Point.
+func geoJSONPoint(coords []float64) (*geo.Point, error) {
+	if len(coords) < 2 {
+		return nil, errors.New("position must have a longitude and latitude")
+	}
+	return geo.NewPoint(coords[1], coords[0]), nil
+}
+
+func geoJSONPoints(coords [][]float64) ([]*geo.Point, error) {
+	pts := make([]*geo.Point, 0, len(coords))
+	for _, c := range coords {
+		pt, err := geoJSONPoint(c)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, pt)
+	}
+	return pts, nil
+}
+
+func marshalGeoJSON(features []geoFeature) ([]byte, error) {
+	collection := geoJSONObject{Type: "FeatureCollection", Features: []geoJSONObject{}}
+	for _, f := range features {
+		coords := make([][]float64, 0, len(f.points))
+		for _, pt := range f.points {
+			coords = append(coords, []float64{pt.Lng(), pt.Lat()})
+		}
+		var geometry geoJSONObject
+		var err error
+		switch f.shape {
+		case geoShapePoint:
+			geometry = geoJSONObject{Type: "Point"}
+			geometry.Coordinates, err = json.Marshal(coords[0])
+		case geoShapeLine:
+			geometry = geoJSONObject{Type: "LineString"}
+			geometry.Coordinates, err = json.Marshal(coords)
+		case geoShapePolygon:
+			geometry = geoJSONObject{Type: "Polygon"}
+			geometry.Coordinates, err = json.Marshal([][][]float64{coords})
+		}
+		if err != nil {
+			return nil, err
+		}
+		collection.Features = append(collection.Features, geoJSONObject{
+			Type:       "Feature",
+			Geometry:   &geometry,
+			Properties: map[string]interface{}{"name": f.name, "kind": f.kind},
+		})
+	}
+	return json.MarshalIndent(collection, "", "  ")
+}
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+type kmlFile struct {
+	XMLName xml.Name `xml:"kml"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	kmlContainer
+}
+
+// kmlContainer is a Document or Folder, or the top level of a KML file.
+type kmlContainer struct {
+	Name       string         `xml:"name,omitempty"`
+	Documents  []kmlContainer `xml:"Document"`
+	Folders    []kmlContainer `xml:"Folder"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name          string            `xml:"name,omitempty"`
+	ExtendedData  *kmlExtendedData  `xml:"ExtendedData,omitempty"`
+	Point         *kmlCoordinates   `xml:"Point,omitempty"`
+	LineString    *kmlCoordinates   `xml:"LineString,omitempty"`
+	Polygon       *kmlPolygon       `xml:"Polygon,omitempty"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlCoordinates struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	OuterBoundary   kmlCoordinates   `xml:"outerBoundaryIs>LinearRing"`
+	InnerBoundaries []kmlCoordinates `xml:"innerBoundaryIs>LinearRing"`
+}
+
+type kmlMultiGeometry struct {
+	Points      []kmlCoordinates `xml:"Point"`
+	LineStrings []kmlCoordinates `xml:"LineString"`
+	Polygons    []kmlPolygon     `xml:"Polygon"`
+}
+
+func parseKML(data []byte) ([]geoFeature, error) {
+	var file kmlFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "invalid KML")
+	}
+	var features []geoFeature
+	if err := appendKMLFeatures(&features, file.kmlContainer, ""); err != nil {
+		return nil, err
+	}
+	return features, nil
+}
+
+func appendKMLFeatures(features *[]geoFeature, c kmlContainer, kind GeoFeatureKind) error {
+	if folderKind, ok := parseGeoFeatureKind(c.Name); ok {
+		kind = folderKind
+	}
+	for _, child := range append(c.Documents, c.Folders...) {
+		if err := appendKMLFeatures(features, child, kind); err != nil {
+			return err
+		}
+	}
+	for _, p := range c.Placemarks {
+		if err := appendKMLPlacemark(features, p, kind); err != nil {
+			if p.Name != "" {
+				return errors.Wrapf(err, "placemark %q", p.Name)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func appendKMLPlacemark(features *[]geoFeature, p kmlPlacemark, kind GeoFeatureKind) error {
+	template := geoFeature{name: p.Name, kind: kind}
+	if p.ExtendedData != nil {
+		for _, d := range p.ExtendedData.Data {
+			switch d.Name {
+			case "kind":
+				kind, ok := parseGeoFeatureKind(d.Value)
+				if !ok {
+					return errors.Errorf("unknown kind %q", d.Value)
+				}
+				template.kind = kind
+			case "height_mm":
+				height, err := strconv.ParseFloat(strings.TrimSpace(d.Value), 64)
+				if err != nil {
+					return errors.Wrap(err, "invalid height_mm")
+				}
+				template.heightMm = height
+			}
+		}
+	}
+
+	add := func(shape geoFeatureShape, coords kmlCoordinates) error {
+		pts, err := parseKMLCoordinates(coords.Coordinates)
+		if err != nil {
+			return err
+		}
+		f := template
+		f.shape = shape
+		f.points = pts
+		*features = append(*features, f)
+		return nil
+	}
+	addPolygon := func(polygon kmlPolygon) error {
+		if len(polygon.InnerBoundaries) > 0 {
+			return errors.New("polygons with holes are not supported")
+		}
+		return add(geoShapePolygon, polygon.OuterBoundary)
+	}
+
+	multi := kmlMultiGeometry{}
+	if p.MultiGeometry != nil {
+		multi = *p.MultiGeometry
+	}
+	if p.Point != nil {
+		multi.Points = append(multi.Points, *p.Point)
+	}
+	if p.LineString != nil {
+		multi.LineStrings = append(multi.LineStrings, *p.LineString)
+	}
+	if p.Polygon != nil {
+		multi.Polygons = append(multi.Polygons, *p.Polygon)
+	}
+	for _, coords := range multi.Points {
+		if err := add(geoShapePoint, coords); err != nil {
+			return err
+		}
+	}
+	for _, coords := range multi.LineStrings {
+		if err := add(geoShapeLine, coords); err != nil {
+			return err
+		}
+	}
+	for _, polygon := range multi.Polygons {
+		if err := addPolygon(polygon); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseKMLCoordinates parses a KML coordinates string of whitespace separated "longitude,latitude[,altitude]" tuples.
+func parseKMLCoordinates(s string) ([]*geo.Point, error) {
+	tuples := strings.Fields(s)
+	if len(tuples) == 0 {
+		return nil, errors.New("missing coordinates")
+	}
+	pts := make([]*geo.Point, 0, len(tuples))
+	for _, tuple := range tuples {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 {
+			return nil, errors.Errorf("invalid coordinates %q", tuple)
+		}
+		lng, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid coordinates %q", tuple)
+		}
+		lat, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid coordinates %q", tuple)
+		}
+		pts = append(pts, geo.NewPoint(lat, lng))
+	}
+	return pts, nil
+}
+
+func formatKMLCoordinates(pts []*geo.Point) kmlCoordinates {
+	tuples := make([]string, 0, len(pts))
+	for _, pt := range pts {
+		tuples = append(tuples, strconv.FormatFloat(pt.Lng(), 'f', -1, 64)+","+strconv.FormatFloat(pt.Lat(), 'f', -1, 64))
+	}
+	return kmlCoordinates{Coordinates: strings.Join(tuples, " ")}
+}
+
+func marshalKML(features []geoFeature) ([]byte, error) {
+	folders := map[GeoFeatureKind]*kmlContainer{}
+	doc := kmlContainer{Name: "navigation"}
+	for _, kind := range []GeoFeatureKind{GeoFeatureWaypoint, GeoFeatureObstacle, GeoFeatureBoundingRegion} {
+		doc.Folders = append(doc.Folders, kmlContainer{Name: string(kind) + "s"})
+	}
+	for i := range doc.Folders {
+		kind, _ := parseGeoFeatureKind(doc.Folders[i].Name)
+		folders[kind] = &doc.Folders[i]
+	}
+
+	for _, f := range features {
+		p := kmlPlacemark{
+			Name:         f.name,
+			ExtendedData: &kmlExtendedData{Data: []kmlData{{Name: "kind", Value: string(f.kind)}}},
+		}
+		coords := formatKMLCoordinates(f.points)
+		switch f.shape {
+		case geoShapePoint:
+			p.Point = &coords
+		case geoShapeLine:
+			p.LineString = &coords
+		case geoShapePolygon:
+			p.Polygon = &kmlPolygon{OuterBoundary: coords}
+		}
+		folder := folders[f.kind]
+		folder.Placemarks = append(folder.Placemarks, p)
+	}
+
+	out, err := xml.MarshalIndent(kmlFile{
+		Xmlns:        kmlNamespace,
+		kmlContainer: kmlContainer{Documents: []kmlContainer{doc}},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,152 @@
+package navigation_test
+
+import (
+	"testing"
+
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/services/navigation"
+	"go.viam.com/rdk/spatialmath"
+)
+
+const testGeoJSON = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [-74.0, 40.0]}},
+    {"type": "Feature", "properties": {"name": "row"},
+     "geometry": {"type": "LineString", "coordinates": [[-74.0, 40.0001], [-74.0001, 40.0001, 12]]}},
+    {"type": "Feature", "properties": {"name": "shed", "height_mm": 3000},
+     "geometry": {"type": "Polygon", "coordinates": [[[-74.0, 40.0], [-73.9999, 40.0], [-73.9999, 40.0001], [-74.0, 40.0]]]}},
+    {"type": "Feature", "properties": {"name": "field", "kind": "Bounding Region"},
+     "geometry": {"type": "Polygon", "coordinates": [[[-74.01, 39.99], [-73.99, 39.99], [-73.99, 40.01], [-74.01, 40.01], [-74.01, 39.99]]]}}
+  ]
+}`
+
+const testKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <Placemark><Point><coordinates>-74.0,40.0,0</coordinates></Point></Placemark>
+    <Placemark>
+      <name>row</name>
+      <LineString><coordinates>-74.0,40.0001 -74.0001,40.0001,12</coordinates></LineString>
+    </Placemark>
+    <Folder>
+      <name>Obstacles</name>
+      <Placemark>
+        <name>shed</name>
+        <ExtendedData><Data name="height_mm"><value>3000</value></Data></ExtendedData>
+        <Polygon><outerBoundaryIs><LinearRing>
+          <coordinates>-74.0,40.0 -73.9999,40.0 -73.9999,40.0001 -74.0,40.0</coordinates>
+        </LinearRing></outerBoundaryIs></Polygon>
+      </Placemark>
+    </Folder>
+    <Placemark>
+      <name>field</name>
+      <ExtendedData><Data name="kind"><value>bounding_region</value></Data></ExtendedData>
+      <Polygon><outerBoundaryIs><LinearRing>
+        <coordinates>-74.01,39.99 -73.99,39.99 -73.99,40.01 -74.01,40.01 -74.01,39.99</coordinates>
+      </LinearRing></outerBoundaryIs></Polygon>
+    </Placemark>
+  </Document>
+</kml>`
+
+func TestGeoData(t *testing.T) {
+	checkGeoData := func(t *testing.T, gd *navigation.GeoData) {
+		t.Helper()
+		test.That(t, gd.Waypoints, test.ShouldResemble, []*geo.Point{
+			geo.NewPoint(40.0, -74.0),
+			geo.NewPoint(40.0001, -74.0),
+			geo.NewPoint(40.0001, -74.0001),
+		})
+		test.That(t, gd.Obstacles, test.ShouldHaveLength, 1)
+		test.That(t, gd.BoundingRegions, test.ShouldHaveLength, 1)
+
+		shed := gd.Obstacles[0].Geometries()
+		test.That(t, shed, test.ShouldHaveLength, 1)
+		test.That(t, shed[0].Label(), test.ShouldEqual, "shed")
+		mesh, ok := shed[0].(*spatialmath.Mesh)
+		test.That(t, ok, test.ShouldBeTrue)
+		// a triangular prism
+		test.That(t, mesh.Triangles(), test.ShouldHaveLength, 8)
+		for _, tri := range mesh.Triangles() {
+			for _, pt := range tri.Points() {
+				test.That(t, pt.Z == 1500 || pt.Z == -1500, test.ShouldBeTrue)
+			}
+		}
+		test.That(t, gd.BoundingRegions[0].Geometries()[0].Label(), test.ShouldEqual, "field")
+	}
+
+	for _, tc := range []struct {
+		format navigation.GeoFormat
+		data   string
+	}{
+		{navigation.GeoFormatGeoJSON, testGeoJSON},
+		{navigation.GeoFormatKML, testKML},
+	} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			gd, err := navigation.UnmarshalGeoData(tc.format, []byte(tc.data))
+			test.That(t, err, test.ShouldBeNil)
+			checkGeoData(t, gd)
+
+			// exported data imports to the same waypoints and polygons, at the default height
+			out, err := navigation.MarshalGeoData(tc.format, gd)
+			test.That(t, err, test.ShouldBeNil)
+			roundTrip, err := navigation.UnmarshalGeoData(tc.format, out)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, roundTrip.Waypoints, test.ShouldResemble, gd.Waypoints)
+			test.That(t, roundTrip.Obstacles, test.ShouldHaveLength, 1)
+			test.That(t, roundTrip.BoundingRegions, test.ShouldHaveLength, 1)
+			test.That(t, roundTrip.Obstacles[0].Geometries()[0].Label(), test.ShouldEqual, "shed")
+			want := spatialmath.GeoGeometryFootprints(gd.Obstacles[0])[0]
+			got := spatialmath.GeoGeometryFootprints(roundTrip.Obstacles[0])[0]
+			test.That(t, got, test.ShouldHaveLength, len(want))
+			for i := range want {
+				test.That(t, got[i].GreatCircleDistance(want[i]), test.ShouldBeLessThan, 1e-5)
+			}
+		})
+	}
+
+	t.Run("exports other geometries by their outline", func(t *testing.T) {
+		sphere, err := spatialmath.NewSphere(spatialmath.NewZeroPose(), 1000, "")
+		test.That(t, err, test.ShouldBeNil)
+		gd := &navigation.GeoData{Obstacles: []*spatialmath.GeoGeometry{
+			spatialmath.NewGeoGeometry(geo.NewPoint(40, -74), []spatialmath.Geometry{sphere}),
+		}}
+		out, err := navigation.MarshalGeoData(navigation.GeoFormatGeoJSON, gd)
+		test.That(t, err, test.ShouldBeNil)
+		roundTrip, err := navigation.UnmarshalGeoData(navigation.GeoFormatGeoJSON, out)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, roundTrip.Obstacles, test.ShouldHaveLength, 1)
+		test.That(t, roundTrip.Obstacles[0].Geometries()[0].Label(), test.ShouldEqual, "obstacle 0")
+	})
+
+	t.Run("invalid features", func(t *testing.T) {
+		for _, data := range []string{
+			`{"type": "Feature", "properties": {"kind": "obstacle"}, "geometry": {"type": "Point", "coordinates": [-74, 40]}}`,
+			`{"type": "Feature", "properties": {"kind": "waypoint"},
+			  "geometry": {"type": "Polygon", "coordinates": [[[-74, 40], [-73.9, 40], [-73.9, 40.1], [-74, 40]]]}}`,
+			`{"type": "Feature", "properties": {"kind": "lake"}, "geometry": {"type": "Point", "coordinates": [-74, 40]}}`,
+			`{"type": "Polygon", "coordinates": [[[-74, 40], [-73.9, 40], [-73.9, 40.1], [-74, 40]], [[-74, 40], [-73.95, 40], [-74, 40]]]}`,
+			`{"type": "Point", "coordinates": [-74]}`,
+			`{"type": "Circle"}`,
+		} {
+			_, err := navigation.UnmarshalGeoData(navigation.GeoFormatGeoJSON, []byte(data))
+			test.That(t, err, test.ShouldNotBeNil)
+		}
+
+		_, err := navigation.UnmarshalGeoData(navigation.GeoFormatKML, []byte(`<kml><Placemark><Point><coordinates>x,y</coordinates></Point></Placemark></kml>`))
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
+
+func TestGeoFormatFromPath(t *testing.T) {
+	format, err := navigation.GeoFormatFromPath("mission.GeoJSON")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, format, test.ShouldEqual, navigation.GeoFormatGeoJSON)
+	format, err = navigation.GeoFormatFromPath("/tmp/mission.kml")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, format, test.ShouldEqual, navigation.GeoFormatKML)
+	_, err = navigation.GeoFormatFromPath("mission.kmz")
+	test.That(t, err, test.ShouldNotBeNil)
+}
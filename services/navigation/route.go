@@ -0,0 +1,77 @@
+package navigation
+
+import (
+	"github.com/pkg/errors"
+)
+
+// A Route is a named, ordered list of waypoints which the navigation service can drive as a mission.
+type Route struct {
+	Name      string          `bson:"_id" json:"name"`
+	Waypoints []RouteWaypoint `bson:"waypoints" json:"waypoints"`
+	// Loops is how many times the route is driven. Zero drives it once, a negative value repeats it until the
+	// mission is stopped.
+	Loops int `bson:"loops" json:"loops,omitempty"`
+}
+
+// A RouteWaypoint is a stop along a Route.
+type RouteWaypoint struct {
+	Lat  float64 `bson:"latitude" json:"latitude"`
+	Long float64 `bson:"longitude" json:"longitude"`
+	// DwellSec is how long to wait at the waypoint, after running its action, before moving on.
+	DwellSec float64 `bson:"dwell_sec" json:"dwell_sec,omitempty"`
+	// Action is run once the waypoint is reached, if set.
+	Action *WaypointAction `bson:"action,omitempty" json:"action,omitempty"`
+}
+
+// A WaypointAction is a DoCommand sent to a named resource on arrival at a waypoint.
+type WaypointAction struct {
+	Resource string                 `bson:"resource" json:"resource"`
+	Command  map[string]interface{} `bson:"command" json:"command"`
+}
+
+// Validate ensures all parts of the route are valid.
+func (r *Route) Validate() error {
+	if r.Name == "" {
+		return errors.New("route name cannot be empty")
+	}
+	if len(r.Waypoints) == 0 {
+		return errors.Errorf("route %q has no waypoints", r.Name)
+	}
+	for i, wp := range r.Waypoints {
+		if wp.Lat < -90 || wp.Lat > 90 || wp.Long < -180 || wp.Long > 180 {
+			return errors.Errorf("waypoint %d of route %q has invalid location (%v, %v)", i, r.Name, wp.Lat, wp.Long)
+		}
+		if wp.DwellSec < 0 {
+			return errors.Errorf("waypoint %d of route %q has negative dwell_sec", i, r.Name)
+		}
+		if wp.Action != nil && wp.Action.Resource == "" {
+			return errors.Errorf("waypoint %d of route %q has an action without a resource", i, r.Name)
+		}
+	}
+	return nil
+}
+
+// A Mission is the progress of the navigation service through a Route.
+type Mission struct {
+	Route string `bson:"route" json:"route"`
+	// Loop is the number of times the route has been completed.
+	Loop int `bson:"loop" json:"loop"`
+	// Index is the index of the next waypoint of the route to drive to.
+	Index  int  `bson:"index" json:"index"`
+	Paused bool `bson:"paused" json:"paused"`
+}
+
+// Advance returns the mission after the current waypoint of the route has been reached, and whether the mission is
+// complete.
+func (m Mission) Advance(route Route) (Mission, bool) {
+	m.Index++
+	if m.Index < len(route.Waypoints) {
+		return m, false
+	}
+	m.Index = 0
+	m.Loop++
+	if route.Loops < 0 {
+		return m, false
+	}
+	return m, m.Loop >= max(route.Loops, 1)
+}
@@ -0,0 +1,128 @@
+package navigation_test
+
+import (
+	"context"
+	"testing"
+
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/services/navigation"
+)
+
+func TestRouteValidate(t *testing.T) {
+	route := navigation.Route{
+		Name: "patrol",
+		Waypoints: []navigation.RouteWaypoint{
+			{Lat: 40.7, Long: -74, DwellSec: 2},
+			{Lat: 40.8, Long: -74.1, Action: &navigation.WaypointAction{Resource: "camera", Command: map[string]interface{}{"snap": true}}},
+		},
+	}
+	test.That(t, route.Validate(), test.ShouldBeNil)
+
+	noName := route
+	noName.Name = ""
+	test.That(t, noName.Validate(), test.ShouldNotBeNil)
+
+	noWaypoints := route
+	noWaypoints.Waypoints = nil
+	test.That(t, noWaypoints.Validate(), test.ShouldNotBeNil)
+
+	badLocation := route
+	badLocation.Waypoints = []navigation.RouteWaypoint{{Lat: 91}}
+	test.That(t, badLocation.Validate(), test.ShouldNotBeNil)
+
+	negativeDwell := route
+	negativeDwell.Waypoints = []navigation.RouteWaypoint{{DwellSec: -1}}
+	test.That(t, negativeDwell.Validate(), test.ShouldNotBeNil)
+
+	noResource := route
+	noResource.Waypoints = []navigation.RouteWaypoint{{Action: &navigation.WaypointAction{}}}
+	test.That(t, noResource.Validate(), test.ShouldNotBeNil)
+}
+
+func TestMissionAdvance(t *testing.T) {
+	route := navigation.Route{Name: "patrol", Waypoints: make([]navigation.RouteWaypoint, 2)}
+
+	t.Run("once", func(t *testing.T) {
+		m, done := navigation.Mission{Route: "patrol"}.Advance(route)
+		test.That(t, done, test.ShouldBeFalse)
+		test.That(t, m, test.ShouldResemble, navigation.Mission{Route: "patrol", Index: 1})
+		_, done = m.Advance(route)
+		test.That(t, done, test.ShouldBeTrue)
+	})
+
+	t.Run("loops", func(t *testing.T) {
+		route := route
+		route.Loops = 2
+		m, done := navigation.Mission{Route: "patrol", Index: 1}.Advance(route)
+		test.That(t, done, test.ShouldBeFalse)
+		test.That(t, m, test.ShouldResemble, navigation.Mission{Route: "patrol", Loop: 1})
+		_, done = navigation.Mission{Route: "patrol", Loop: 1, Index: 1}.Advance(route)
+		test.That(t, done, test.ShouldBeTrue)
+	})
+
+	t.Run("forever", func(t *testing.T) {
+		route := route
+		route.Loops = -1
+		m, done := navigation.Mission{Route: "patrol", Loop: 100, Index: 1}.Advance(route)
+		test.That(t, done, test.ShouldBeFalse)
+		test.That(t, m, test.ShouldResemble, navigation.Mission{Route: "patrol", Loop: 101})
+	})
+}
+
+func TestMemoryStoreRoutes(t *testing.T) {
+	ctx := context.Background()
+	store := navigation.NewMemoryNavigationStore()
+
+	routes, err := store.Routes(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, routes, test.ShouldBeEmpty)
+
+	patrol := navigation.Route{Name: "patrol", Waypoints: []navigation.RouteWaypoint{{Lat: 1, Long: 2}}}
+	survey := navigation.Route{Name: "survey", Waypoints: []navigation.RouteWaypoint{{Lat: 3, Long: 4}}, Loops: -1}
+	test.That(t, store.SaveRoute(ctx, patrol), test.ShouldBeNil)
+	test.That(t, store.SaveRoute(ctx, survey), test.ShouldBeNil)
+
+	// saving a route with an existing name replaces it
+	patrol.Waypoints = append(patrol.Waypoints, navigation.RouteWaypoint{Lat: 5, Long: 6, DwellSec: 1})
+	test.That(t, store.SaveRoute(ctx, patrol), test.ShouldBeNil)
+	routes, err = store.Routes(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, routes, test.ShouldResemble, []navigation.Route{patrol, survey})
+
+	// returned routes are copies
+	got, err := store.Route(ctx, "patrol")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, got, test.ShouldResemble, patrol)
+	got.Waypoints[0].Lat = 10
+	got, err = store.Route(ctx, "patrol")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, got.Waypoints[0].Lat, test.ShouldEqual, 1)
+
+	test.That(t, store.RemoveRoute(ctx, "patrol"), test.ShouldBeNil)
+	_, err = store.Route(ctx, "patrol")
+	test.That(t, err, test.ShouldNotBeNil)
+	routes, err = store.Routes(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, routes, test.ShouldResemble, []navigation.Route{survey})
+}
+
+func TestMemoryStoreMission(t *testing.T) {
+	ctx := context.Background()
+	store := navigation.NewMemoryNavigationStore()
+
+	mission, err := store.Mission(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, mission, test.ShouldBeNil)
+
+	want := navigation.Mission{Route: "patrol", Loop: 1, Index: 2, Paused: true}
+	test.That(t, store.SetMission(ctx, want), test.ShouldBeNil)
+	mission, err = store.Mission(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, *mission, test.ShouldResemble, want)
+
+	test.That(t, store.ClearMission(ctx), test.ShouldBeNil)
+	mission, err = store.Mission(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, mission, test.ShouldBeNil)
+}
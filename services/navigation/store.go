@@ -15,10 +15,16 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.uber.org/multierr"
 	mongoutils "go.viam.com/utils/mongo"
+
+	"go.viam.com/rdk/resource"
 )
 
 var errNoMoreWaypoints = errors.New("no more waypoints")
 
+func newRouteNotFoundError(name string) error {
+	return errors.Errorf("route %q not found", name)
+}
+
 // NavStore handles the waypoints for a navigation service.
 type NavStore interface {
 	Waypoints(ctx context.Context) ([]Waypoint, error)
@@ -26,6 +32,17 @@ type NavStore interface {
 	RemoveWaypoint(ctx context.Context, id primitive.ObjectID) error
 	NextWaypoint(ctx context.Context) (Waypoint, error)
 	WaypointVisited(ctx context.Context, id primitive.ObjectID) error
+
+	Routes(ctx context.Context) ([]Route, error)
+	Route(ctx context.Context, name string) (Route, error)
+	// SaveRoute adds a route, replacing any route with the same name.
+	SaveRoute(ctx context.Context, route Route) error
+	RemoveRoute(ctx context.Context, name string) error
+	// Mission returns the current mission, or nil if there is none.
+	Mission(ctx context.Context) (*Mission, error)
+	SetMission(ctx context.Context, mission Mission) error
+	ClearMission(ctx context.Context) error
+
 	Close(ctx context.Context) error
 }
 
@@ -38,6 +55,8 @@ const (
 	StoreTypeMemory = "memory"
 	// StoreTypeMongoDB is the constant for the mongodb store type.
 	StoreTypeMongoDB = "mongodb"
+	// StoreTypeBolt is the constant for the bolt store type, an embedded database kept in a local file.
+	StoreTypeBolt = "bolt"
 )
 
 // StoreConfig describes how to configure data storage.
@@ -50,6 +69,10 @@ type StoreConfig struct {
 func (config *StoreConfig) Validate(path string) error {
 	switch config.Type {
 	case StoreTypeMemory, StoreTypeMongoDB, StoreTypeUnset:
+	case StoreTypeBolt:
+		if p, ok := config.Config[boltNavStorePathKey].(string); !ok || p == "" {
+			return resource.NewConfigValidationFieldRequiredError(path, "store.config."+boltNavStorePathKey)
+		}
 	default:
 		return errors.Errorf("unknown store type %q", config.Type)
 	}
@@ -63,11 +86,46 @@ func NewStoreFromConfig(ctx context.Context, conf StoreConfig) (NavStore, error)
 		return NewMemoryNavigationStore(), nil
 	case StoreTypeMongoDB:
 		return NewMongoDBNavigationStore(ctx, conf.Config)
+	case StoreTypeBolt:
+		return NewBoltNavigationStore(ctx, conf.Config)
 	default:
 		return nil, errors.Errorf("unknown store type %q", conf.Type)
 	}
 }
 
+// MigrateNavStore copies the unvisited waypoints, routes and current mission of one store into another, so that
+// switching to a persistent store keeps what was added to a MemoryNavigationStore. Waypoints are given new IDs.
+func MigrateNavStore(ctx context.Context, from, to NavStore) error {
+	wps, err := from.Waypoints(ctx)
+	if err != nil {
+		return err
+	}
+	for _, wp := range wps {
+		if _, err := to.AddWaypoint(ctx, wp.ToPoint()); err != nil {
+			return err
+		}
+	}
+
+	routes, err := from.Routes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		if err := to.SaveRoute(ctx, route); err != nil {
+			return err
+		}
+	}
+
+	mission, err := from.Mission(ctx)
+	if err != nil {
+		return err
+	}
+	if mission != nil {
+		return to.SetMission(ctx, *mission)
+	}
+	return nil
+}
+
 // A Waypoint designates a location within a path to navigate to.
 type Waypoint struct {
 	ID      primitive.ObjectID `bson:"_id"`
@@ -93,10 +151,12 @@ func NewMemoryNavigationStore() *MemoryNavigationStore {
 	return &MemoryNavigationStore{}
 }
 
-// MemoryNavigationStore holds the waypoints for the navigation service.
+// MemoryNavigationStore holds the waypoints, routes and mission for the navigation service.
 type MemoryNavigationStore struct {
 	mu        sync.RWMutex
 	waypoints []*Waypoint
+	routes    []Route
+	mission   *Mission
 }
 
 // Waypoints returns a copy of all of the waypoints in the MemoryNavigationStore.
@@ -185,17 +245,126 @@ func (store *MemoryNavigationStore) WaypointVisited(ctx context.Context, id prim
 	return nil
 }
 
+// Routes returns a copy of all of the routes in the MemoryNavigationStore, in the order they were added.
+func (store *MemoryNavigationStore) Routes(ctx context.Context) ([]Route, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	routes := make([]Route, 0, len(store.routes))
+	for _, route := range store.routes {
+		routes = append(routes, copyRoute(route))
+	}
+	return routes, nil
+}
+
+// Route returns a copy of the named route in the MemoryNavigationStore.
+func (store *MemoryNavigationStore) Route(ctx context.Context, name string) (Route, error) {
+	if ctx.Err() != nil {
+		return Route{}, ctx.Err()
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	for _, route := range store.routes {
+		if route.Name == name {
+			return copyRoute(route), nil
+		}
+	}
+	return Route{}, newRouteNotFoundError(name)
+}
+
+// SaveRoute adds a route to the MemoryNavigationStore, replacing any route with the same name.
+func (store *MemoryNavigationStore) SaveRoute(ctx context.Context, route Route) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for i, existing := range store.routes {
+		if existing.Name == route.Name {
+			store.routes[i] = copyRoute(route)
+			return nil
+		}
+	}
+	store.routes = append(store.routes, copyRoute(route))
+	return nil
+}
+
+// RemoveRoute removes a route from the MemoryNavigationStore.
+func (store *MemoryNavigationStore) RemoveRoute(ctx context.Context, name string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	newRoutes := make([]Route, 0, len(store.routes))
+	for _, route := range store.routes {
+		if route.Name == name {
+			continue
+		}
+		newRoutes = append(newRoutes, route)
+	}
+	store.routes = newRoutes
+	return nil
+}
+
+// Mission returns a copy of the current mission of the MemoryNavigationStore, or nil if there is none.
+func (store *MemoryNavigationStore) Mission(ctx context.Context) (*Mission, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if store.mission == nil {
+		return nil, nil
+	}
+	mission := *store.mission
+	return &mission, nil
+}
+
+// SetMission sets the current mission of the MemoryNavigationStore.
+func (store *MemoryNavigationStore) SetMission(ctx context.Context, mission Mission) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.mission = &mission
+	return nil
+}
+
+// ClearMission removes the current mission of the MemoryNavigationStore.
+func (store *MemoryNavigationStore) ClearMission(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.mission = nil
+	return nil
+}
+
 // Close does nothing.
 func (store *MemoryNavigationStore) Close(ctx context.Context) error {
 	return nil
 }
 
+func copyRoute(route Route) Route {
+	route.Waypoints = append([]RouteWaypoint(nil), route.Waypoints...)
+	return route
+}
+
 // Database and collection names used by the MongoDBNavigationStore.
 var (
 	defaultMongoDBURI                = "mongodb://127.0.0.1:27017"
 	MongoDBNavStoreDBName            = "navigation"
 	MongoDBNavStoreWaypointsCollName = "waypoints"
-	mongoDBNavStoreIndexes           = []mongo.IndexModel{
+	MongoDBNavStoreRoutesCollName    = "routes"
+	MongoDBNavStoreMissionsCollName  = "missions"
+	// the missions collection holds at most one document, the current mission.
+	mongoDBNavStoreMissionID = "current"
+	mongoDBNavStoreIndexes   = []mongo.IndexModel{
 		{
 			Keys: bson.D{
 				{"order", -1},
@@ -228,16 +397,21 @@ func NewMongoDBNavigationStore(ctx context.Context, config map[string]interface{
 		return nil, err
 	}
 
+	db := mongoClient.Database(MongoDBNavStoreDBName)
 	return &MongoDBNavigationStore{
 		mongoClient:   mongoClient,
 		waypointsColl: waypoints,
+		routesColl:    db.Collection(MongoDBNavStoreRoutesCollName),
+		missionsColl:  db.Collection(MongoDBNavStoreMissionsCollName),
 	}, nil
 }
 
-// MongoDBNavigationStore holds the mongodb client and waypoints collection.
+// MongoDBNavigationStore holds the mongodb client and the waypoints, routes and missions collections.
 type MongoDBNavigationStore struct {
 	mongoClient   *mongo.Client
 	waypointsColl *mongo.Collection
+	routesColl    *mongo.Collection
+	missionsColl  *mongo.Collection
 }
 
 // Close closes the connection with the mongodb client.
@@ -307,3 +481,70 @@ func (store *MongoDBNavigationStore) WaypointVisited(ctx context.Context, id pri
 	_, err := store.waypointsColl.UpdateOne(ctx, bson.D{{"_id", id}}, bson.D{{"$set", bson.D{{"visited", true}}}})
 	return err
 }
+
+// Routes returns all the routes in the MongoDBNavigationStore, ordered by name.
+func (store *MongoDBNavigationStore) Routes(ctx context.Context) ([]Route, error) {
+	cursor, err := store.routesColl.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{"_id", 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	all := []Route{}
+	if err := cursor.All(ctx, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// Route returns the named route in the MongoDBNavigationStore.
+func (store *MongoDBNavigationStore) Route(ctx context.Context, name string) (Route, error) {
+	var route Route
+	if err := store.routesColl.FindOne(ctx, bson.D{{"_id", name}}).Decode(&route); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Route{}, newRouteNotFoundError(name)
+		}
+		return Route{}, err
+	}
+	return route, nil
+}
+
+// SaveRoute adds a route to the MongoDBNavigationStore, replacing any route with the same name.
+func (store *MongoDBNavigationStore) SaveRoute(ctx context.Context, route Route) error {
+	_, err := store.routesColl.ReplaceOne(ctx, bson.D{{"_id", route.Name}}, route, options.Replace().SetUpsert(true))
+	return err
+}
+
+// RemoveRoute removes a route from the MongoDBNavigationStore.
+func (store *MongoDBNavigationStore) RemoveRoute(ctx context.Context, name string) error {
+	_, err := store.routesColl.DeleteOne(ctx, bson.D{{"_id", name}})
+	return err
+}
+
+// Mission returns the current mission of the MongoDBNavigationStore, or nil if there is none.
+func (store *MongoDBNavigationStore) Mission(ctx context.Context) (*Mission, error) {
+	var mission Mission
+	if err := store.missionsColl.FindOne(ctx, bson.D{{"_id", mongoDBNavStoreMissionID}}).Decode(&mission); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mission, nil
+}
+
+// SetMission sets the current mission of the MongoDBNavigationStore.
+func (store *MongoDBNavigationStore) SetMission(ctx context.Context, mission Mission) error {
+	_, err := store.missionsColl.ReplaceOne(
+		ctx,
+		bson.D{{"_id", mongoDBNavStoreMissionID}},
+		mission,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// ClearMission removes the current mission of the MongoDBNavigationStore.
+func (store *MongoDBNavigationStore) ClearMission(ctx context.Context) error {
+	_, err := store.missionsColl.DeleteOne(ctx, bson.D{{"_id", mongoDBNavStoreMissionID}})
+	return err
+}
@@ -0,0 +1,340 @@
+package navigation
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/multierr"
+)
+
+// Bucket names and keys used by the BoltNavigationStore.
+var (
+	boltNavStoreWaypointsBucket = []byte("waypoints")
+	boltNavStoreRoutesBucket    = []byte("routes")
+	boltNavStoreMissionsBucket  = []byte("missions")
+	// the missions bucket holds at most one mission, the current one.
+	boltNavStoreMissionKey = []byte("current")
+)
+
+const (
+	boltNavStorePathKey = "path"
+	// how long to wait for another process to release the database file before giving up.
+	boltNavStoreOpenTimeout = 5 * time.Second
+)
+
+// A WaypointRecord is a waypoint along with when it was added and, once it has been visited, when it was visited.
+type WaypointRecord struct {
+	Waypoint  `bson:",inline"`
+	AddedAt   time.Time  `bson:"added_at"`
+	VisitedAt *time.Time `bson:"visited_at,omitempty"`
+}
+
+// NewBoltNavigationStore opens, or creates, a navigation store in the bolt database file at the "path" of the config.
+func NewBoltNavigationStore(ctx context.Context, config map[string]interface{}) (*BoltNavigationStore, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	path, ok := config[boltNavStorePathKey].(string)
+	if !ok || path == "" {
+		return nil, errors.Errorf("bolt navigation store requires a %q", boltNavStorePathKey)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: boltNavStoreOpenTimeout})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open navigation store %q", path)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltNavStoreWaypointsBucket, boltNavStoreRoutesBucket, boltNavStoreMissionsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, multierr.Combine(err, db.Close())
+	}
+	return &BoltNavigationStore{db: db}, nil
+}
+
+// BoltNavigationStore holds the waypoints, routes and mission for the navigation service in a local bolt database,
+// so that they are kept across restarts without needing a database server. Visited waypoints are kept as a history
+// of the waypoints driven to.
+type BoltNavigationStore struct {
+	db *bolt.DB
+}
+
+// Close closes the database file.
+func (store *BoltNavigationStore) Close(ctx context.Context) error {
+	return store.db.Close()
+}
+
+// waypointRecords calls f with each waypoint record in the order they were added, stopping if f returns false.
+func waypointRecords(tx *bolt.Tx, f func(key []byte, record WaypointRecord) (bool, error)) error {
+	c := tx.Bucket(boltNavStoreWaypointsBucket).Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		var record WaypointRecord
+		if err := bson.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		more, err := f(k, record)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// Waypoints returns all of the unvisited waypoints in the BoltNavigationStore.
+func (store *BoltNavigationStore) Waypoints(ctx context.Context) ([]Waypoint, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	wps := []Waypoint{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return waypointRecords(tx, func(_ []byte, record WaypointRecord) (bool, error) {
+			if !record.Visited {
+				wps = append(wps, record.Waypoint)
+			}
+			return true, nil
+		})
+	})
+	return wps, err
+}
+
+// WaypointHistory returns every waypoint in the BoltNavigationStore, visited or not, in the order they were added.
+func (store *BoltNavigationStore) WaypointHistory(ctx context.Context) ([]WaypointRecord, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	records := []WaypointRecord{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return waypointRecords(tx, func(_ []byte, record WaypointRecord) (bool, error) {
+			records = append(records, record)
+			return true, nil
+		})
+	})
+	return records, err
+}
+
+// AddWaypoint adds a waypoint to the BoltNavigationStore.
+func (store *BoltNavigationStore) AddWaypoint(ctx context.Context, point *geo.Point) (Waypoint, error) {
+	if ctx.Err() != nil {
+		return Waypoint{}, ctx.Err()
+	}
+	record := WaypointRecord{
+		Waypoint: Waypoint{
+			ID:   primitive.NewObjectID(),
+			Lat:  point.Lat(),
+			Long: point.Lng(),
+		},
+		AddedAt: time.Now().UTC(),
+	}
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltNavStoreWaypointsBucket)
+		// keyed by a sequence number so that waypoints are iterated in the order they were added
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return putBSON(bucket, binary.BigEndian.AppendUint64(nil, seq), record)
+	})
+	if err != nil {
+		return Waypoint{}, err
+	}
+	return record.Waypoint, nil
+}
+
+// RemoveWaypoint removes a waypoint from the BoltNavigationStore.
+func (store *BoltNavigationStore) RemoveWaypoint(ctx context.Context, id primitive.ObjectID) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		key, _, err := findWaypointRecord(tx, id)
+		if err != nil || key == nil {
+			return err
+		}
+		return tx.Bucket(boltNavStoreWaypointsBucket).Delete(key)
+	})
+}
+
+// NextWaypoint gets the next waypoint that has not been visited.
+func (store *BoltNavigationStore) NextWaypoint(ctx context.Context) (Waypoint, error) {
+	if ctx.Err() != nil {
+		return Waypoint{}, ctx.Err()
+	}
+	var next *Waypoint
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return waypointRecords(tx, func(_ []byte, record WaypointRecord) (bool, error) {
+			if record.Visited {
+				return true, nil
+			}
+			next = &record.Waypoint
+			return false, nil
+		})
+	})
+	if err != nil {
+		return Waypoint{}, err
+	}
+	if next == nil {
+		return Waypoint{}, errNoMoreWaypoints
+	}
+	return *next, nil
+}
+
+// WaypointVisited sets that a waypoint has been visited, recording when.
+func (store *BoltNavigationStore) WaypointVisited(ctx context.Context, id primitive.ObjectID) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		key, record, err := findWaypointRecord(tx, id)
+		if err != nil || key == nil || record.Visited {
+			return err
+		}
+		now := time.Now().UTC()
+		record.Visited = true
+		record.VisitedAt = &now
+		return putBSON(tx.Bucket(boltNavStoreWaypointsBucket), key, record)
+	})
+}
+
+// findWaypointRecord returns the key and record of the waypoint with the given id, or a nil key if there is none.
+func findWaypointRecord(tx *bolt.Tx, id primitive.ObjectID) ([]byte, WaypointRecord, error) {
+	var found []byte
+	var foundRecord WaypointRecord
+	err := waypointRecords(tx, func(key []byte, record WaypointRecord) (bool, error) {
+		if record.ID != id {
+			return true, nil
+		}
+		// keys are only valid for the life of the transaction, which is as long as they are used for
+		found, foundRecord = key, record
+		return false, nil
+	})
+	return found, foundRecord, err
+}
+
+// Routes returns all the routes in the BoltNavigationStore, ordered by name.
+func (store *BoltNavigationStore) Routes(ctx context.Context) ([]Route, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	routes := []Route{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltNavStoreRoutesBucket).ForEach(func(_, v []byte) error {
+			var route Route
+			if err := bson.Unmarshal(v, &route); err != nil {
+				return err
+			}
+			routes = append(routes, route)
+			return nil
+		})
+	})
+	return routes, err
+}
+
+// Route returns the named route in the BoltNavigationStore.
+func (store *BoltNavigationStore) Route(ctx context.Context, name string) (Route, error) {
+	if ctx.Err() != nil {
+		return Route{}, ctx.Err()
+	}
+	var route Route
+	var found bool
+	err := store.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = getBSON(tx.Bucket(boltNavStoreRoutesBucket), []byte(name), &route)
+		return err
+	})
+	if err != nil {
+		return Route{}, err
+	}
+	if !found {
+		return Route{}, newRouteNotFoundError(name)
+	}
+	return route, nil
+}
+
+// SaveRoute adds a route to the BoltNavigationStore, replacing any route with the same name.
+func (store *BoltNavigationStore) SaveRoute(ctx context.Context, route Route) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return putBSON(tx.Bucket(boltNavStoreRoutesBucket), []byte(route.Name), route)
+	})
+}
+
+// RemoveRoute removes a route from the BoltNavigationStore.
+func (store *BoltNavigationStore) RemoveRoute(ctx context.Context, name string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltNavStoreRoutesBucket).Delete([]byte(name))
+	})
+}
+
+// Mission returns the current mission of the BoltNavigationStore, or nil if there is none.
+func (store *BoltNavigationStore) Mission(ctx context.Context) (*Mission, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var mission Mission
+	var found bool
+	err := store.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = getBSON(tx.Bucket(boltNavStoreMissionsBucket), boltNavStoreMissionKey, &mission)
+		return err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return &mission, nil
+}
+
+// SetMission sets the current mission of the BoltNavigationStore.
+func (store *BoltNavigationStore) SetMission(ctx context.Context, mission Mission) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return putBSON(tx.Bucket(boltNavStoreMissionsBucket), boltNavStoreMissionKey, mission)
+	})
+}
+
+// ClearMission removes the current mission of the BoltNavigationStore.
+func (store *BoltNavigationStore) ClearMission(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltNavStoreMissionsBucket).Delete(boltNavStoreMissionKey)
+	})
+}
+
+// values are stored as BSON, the same documents the MongoDBNavigationStore stores.
+func putBSON(bucket *bolt.Bucket, key []byte, v interface{}) error {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, b)
+}
+
+func getBSON(bucket *bolt.Bucket, key []byte, v interface{}) (bool, error) {
+	b := bucket.Get(key)
+	if b == nil {
+		return false, nil
+	}
+	return true, bson.Unmarshal(b, v)
+}
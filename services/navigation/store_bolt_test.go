@@ -0,0 +1,117 @@
+package navigation_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/services/navigation"
+)
+
+func TestBoltStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nav", "store.db")
+	cfg := navigation.StoreConfig{Type: navigation.StoreTypeBolt, Config: map[string]interface{}{"path": path}}
+	test.That(t, cfg.Validate("path"), test.ShouldBeNil)
+	test.That(t, (&navigation.StoreConfig{Type: navigation.StoreTypeBolt}).Validate("path"), test.ShouldNotBeNil)
+
+	ns, err := navigation.NewStoreFromConfig(ctx, cfg)
+	test.That(t, err, test.ShouldBeNil)
+	store, ok := ns.(*navigation.BoltNavigationStore)
+	test.That(t, ok, test.ShouldBeTrue)
+
+	var added []navigation.Waypoint
+	for i := range 3 {
+		wp, err := store.AddWaypoint(ctx, geo.NewPoint(40+float64(i), -74))
+		test.That(t, err, test.ShouldBeNil)
+		added = append(added, wp)
+	}
+	wps, err := store.Waypoints(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, wps, test.ShouldResemble, added)
+
+	test.That(t, store.WaypointVisited(ctx, added[0].ID), test.ShouldBeNil)
+	test.That(t, store.RemoveWaypoint(ctx, added[1].ID), test.ShouldBeNil)
+	next, err := store.NextWaypoint(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, next, test.ShouldResemble, added[2])
+
+	route := navigation.Route{Name: "rows", Waypoints: []navigation.RouteWaypoint{{Lat: 40, Long: -74, DwellSec: 2}}}
+	test.That(t, store.SaveRoute(ctx, route), test.ShouldBeNil)
+	test.That(t, store.SetMission(ctx, navigation.Mission{Route: "rows", Index: 1}), test.ShouldBeNil)
+	test.That(t, store.Close(ctx), test.ShouldBeNil)
+
+	// everything is kept across reopening the store
+	store, err = navigation.NewBoltNavigationStore(ctx, cfg.Config)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, store.Close(ctx), test.ShouldBeNil)
+	}()
+
+	wps, err = store.Waypoints(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, wps, test.ShouldResemble, []navigation.Waypoint{added[2]})
+
+	history, err := store.WaypointHistory(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, history, test.ShouldHaveLength, 2)
+	test.That(t, history[0].ID, test.ShouldEqual, added[0].ID)
+	test.That(t, history[0].Visited, test.ShouldBeTrue)
+	test.That(t, history[0].VisitedAt, test.ShouldNotBeNil)
+	test.That(t, history[0].VisitedAt.Before(history[0].AddedAt), test.ShouldBeFalse)
+	test.That(t, history[1].ID, test.ShouldEqual, added[2].ID)
+	test.That(t, history[1].VisitedAt, test.ShouldBeNil)
+
+	got, err := store.Route(ctx, "rows")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, got, test.ShouldResemble, route)
+	mission, err := store.Mission(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, mission, test.ShouldResemble, &navigation.Mission{Route: "rows", Index: 1})
+
+	test.That(t, store.ClearMission(ctx), test.ShouldBeNil)
+	mission, err = store.Mission(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, mission, test.ShouldBeNil)
+	test.That(t, store.RemoveRoute(ctx, "rows"), test.ShouldBeNil)
+	_, err = store.Route(ctx, "rows")
+	test.That(t, err, test.ShouldNotBeNil)
+
+	test.That(t, store.WaypointVisited(ctx, added[2].ID), test.ShouldBeNil)
+	_, err = store.NextWaypoint(ctx)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestMigrateNavStore(t *testing.T) {
+	ctx := context.Background()
+	from := navigation.NewMemoryNavigationStore()
+	visited, err := from.AddWaypoint(ctx, geo.NewPoint(40, -74))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, from.WaypointVisited(ctx, visited.ID), test.ShouldBeNil)
+	_, err = from.AddWaypoint(ctx, geo.NewPoint(41, -74))
+	test.That(t, err, test.ShouldBeNil)
+	route := navigation.Route{Name: "rows", Waypoints: []navigation.RouteWaypoint{{Lat: 40, Long: -74}}}
+	test.That(t, from.SaveRoute(ctx, route), test.ShouldBeNil)
+	test.That(t, from.SetMission(ctx, navigation.Mission{Route: "rows", Paused: true}), test.ShouldBeNil)
+
+	to, err := navigation.NewBoltNavigationStore(ctx, map[string]interface{}{"path": filepath.Join(t.TempDir(), "store.db")})
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, to.Close(ctx), test.ShouldBeNil)
+	}()
+	test.That(t, navigation.MigrateNavStore(ctx, from, to), test.ShouldBeNil)
+
+	wps, err := to.Waypoints(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, wps, test.ShouldHaveLength, 1)
+	test.That(t, wps[0].Lat, test.ShouldEqual, 41)
+	routes, err := to.Routes(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, routes, test.ShouldResemble, []navigation.Route{route})
+	mission, err := to.Mission(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, mission, test.ShouldResemble, &navigation.Mission{Route: "rows", Paused: true})
+}
@@ -134,6 +134,14 @@ func GeoPointToPoint(point, origin *geo.Point) r3.Vector {
 	}
 }
 
+// PointToGeoPoint is the inverse of GeoPointToPoint, returning the geopoint which the point (in mm, with X east and
+// Y north) translates the origin to.
+func PointToGeoPoint(point r3.Vector, origin *geo.Point) *geo.Point {
+	distKm := 1e-6 * math.Hypot(point.X, point.Y)
+	bearing := utils.RadToDeg(math.Atan2(point.X, point.Y))
+	return origin.PointAtDistanceAndBearing(distKm, bearing)
+}
+
 // GeoGeometriesToGeometries converts a list of GeoGeometries into a list of Geometries.
 func GeoGeometriesToGeometries(obstacles []*GeoGeometry, origin *geo.Point) []Geometry {
 	// we note that there are two transformations to be accounted for
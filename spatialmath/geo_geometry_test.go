@@ -33,6 +33,7 @@ func TestGeoPointToPoint(t *testing.T) {
 		t.Run(fmt.Sprint(i), func(t *testing.T) {
 			point := GeoPointToPoint(tc.Point, origin)
 			test.That(t, R3VectorAlmostEqual(point, tc.Vector, 0.1), test.ShouldBeTrue)
+			test.That(t, PointToGeoPoint(point, origin).GreatCircleDistance(tc.Point), test.ShouldBeLessThan, 1e-9)
 		})
 	}
 }
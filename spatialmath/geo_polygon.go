@@ -0,0 +1,353 @@
+package spatialmath
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+)
+
+// number of points used to approximate round footprints.
+const footprintCirclePoints = 32
+
+// NewGeoPolygonPrism returns a GeoGeometry holding a vertical prism of the given height, centered on the ground plane,
+// whose footprint is the polygon with the given vertices. The polygon may be concave and may repeat its first vertex
+// at the end, as GeoJSON and KML rings do, but must not intersect itself. The GeoGeometry is located at the mean of the
+// vertices and the prism is represented as a Mesh.
+func NewGeoPolygonPrism(vertices []*geo.Point, heightMm float64, label string) (*GeoGeometry, error) {
+	if heightMm <= 0 {
+		return nil, errors.New("polygon prism height must be positive")
+	}
+	if len(vertices) > 1 && vertices[0].Lat() == vertices[len(vertices)-1].Lat() &&
+		vertices[0].Lng() == vertices[len(vertices)-1].Lng() {
+		vertices = vertices[:len(vertices)-1]
+	}
+	if len(vertices) < 3 {
+		return nil, errors.New("polygon must have at least 3 distinct vertices")
+	}
+
+	var lat, lng float64
+	for _, v := range vertices {
+		lat += v.Lat()
+		lng += v.Lng()
+	}
+	origin := geo.NewPoint(lat/float64(len(vertices)), lng/float64(len(vertices)))
+
+	ring := make([]r3.Vector, 0, len(vertices))
+	for _, v := range vertices {
+		pt := GeoPointToPoint(v, origin)
+		if len(ring) > 0 && pt.Sub(ring[len(ring)-1]).Norm() < floatEpsilon {
+			continue
+		}
+		ring = append(ring, pt)
+	}
+	if len(ring) > 1 && ring[0].Sub(ring[len(ring)-1]).Norm() < floatEpsilon {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 3 {
+		return nil, errors.New("polygon must have at least 3 distinct vertices")
+	}
+	if ringSelfIntersects(ring) {
+		return nil, errors.New("polygon must not intersect itself")
+	}
+	if polygonArea(ring) < 0 {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+	}
+
+	triangles, err := prismTriangles(ring, heightMm)
+	if err != nil {
+		return nil, err
+	}
+	return NewGeoGeometry(origin, []Geometry{NewMesh(NewZeroPose(), triangles, label)}), nil
+}
+
+// GeoGeometryFootprints returns the outline of each geometry of a GeoGeometry as projected onto the ground, as a
+// counterclockwise polygon of geo points. Prisms made by NewGeoPolygonPrism return their original polygon, while the
+// outline of other geometries is their convex hull, with round geometries approximated by polygons.
+func GeoGeometryFootprints(gg *GeoGeometry) [][]*geo.Point {
+	footprints := make([][]*geo.Point, 0, len(gg.geometries))
+	for _, g := range gg.geometries {
+		outline := footprint(g)
+		points := make([]*geo.Point, 0, len(outline))
+		for _, pt := range outline {
+			points = append(points, PointToGeoPoint(pt, gg.location))
+		}
+		footprints = append(footprints, points)
+	}
+	return footprints
+}
+
+// footprint returns the outline of a geometry projected onto the XY plane, in counterclockwise order.
+func footprint(g Geometry) []r3.Vector {
+	switch geom := g.(type) {
+	case *box:
+		return convexHull2D(geom.vertices())
+	case *sphere:
+		return circlePoints(geom.pose.Point(), geom.radius)
+	case *capsule:
+		return convexHull2D(append(circlePoints(geom.segA, geom.radius), circlePoints(geom.segB, geom.radius)...))
+	case *Cylinder:
+		return convexHull2D(meshVertices(geom.mesh))
+	case *Mesh:
+		if outline := meshBottomOutline(geom); outline != nil {
+			return outline
+		}
+		return convexHull2D(meshVertices(geom))
+	default:
+		return []r3.Vector{{X: g.Pose().Point().X, Y: g.Pose().Point().Y}}
+	}
+}
+
+// PointInPolygon returns whether p is inside the polygon with the given vertices, in the XY plane, by counting the
+// crossings of a ray from p. The polygon may be concave and need not repeat its first vertex.
+func PointInPolygon(p r3.Vector, polygon []r3.Vector) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// polygonArea returns the signed area of a polygon in the XY plane, which is positive if it is counterclockwise.
+func polygonArea(ring []r3.Vector) float64 {
+	area := 0.
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	return area / 2
+}
+
+// prismTriangles triangulates a counterclockwise polygon by ear clipping and extrudes it into a closed prism spanning
+// z = ±heightMm/2.
+func prismTriangles(ring []r3.Vector, heightMm float64) ([]*Triangle, error) {
+	bottom := func(p r3.Vector) r3.Vector { return r3.Vector{X: p.X, Y: p.Y, Z: -heightMm / 2} }
+	top := func(p r3.Vector) r3.Vector { return r3.Vector{X: p.X, Y: p.Y, Z: heightMm / 2} }
+
+	remaining := make([]int, len(ring))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	triangles := make([]*Triangle, 0, 4*len(ring))
+	for len(remaining) > 3 {
+		clipped := false
+		for i := range remaining {
+			prev := remaining[(i+len(remaining)-1)%len(remaining)]
+			cur := remaining[i]
+			next := remaining[(i+1)%len(remaining)]
+			if !isEar(ring, remaining, prev, cur, next) {
+				continue
+			}
+			triangles = append(triangles,
+				NewTriangle(top(ring[prev]), top(ring[cur]), top(ring[next])),
+				NewTriangle(bottom(ring[next]), bottom(ring[cur]), bottom(ring[prev])),
+			)
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			clipped = true
+			break
+		}
+		if !clipped {
+			return nil, errors.New("polygon must not be degenerate")
+		}
+	}
+	a, b, c := ring[remaining[0]], ring[remaining[1]], ring[remaining[2]]
+	triangles = append(triangles, NewTriangle(top(a), top(b), top(c)), NewTriangle(bottom(c), bottom(b), bottom(a)))
+
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		triangles = append(triangles,
+			NewTriangle(bottom(p), bottom(q), top(q)),
+			NewTriangle(bottom(p), top(q), top(p)),
+		)
+	}
+	return triangles, nil
+}
+
+// ringSelfIntersects returns whether any two non-adjacent edges of a polygon in the XY plane intersect.
+func ringSelfIntersects(ring []r3.Vector) bool {
+	n := len(ring)
+	for i := range n {
+		a, b := ring[i], ring[(i+1)%n]
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue
+			}
+			c, d := ring[j], ring[(j+1)%n]
+			if segmentsIntersect2D(a, b, c, d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsIntersect2D returns whether the segments ab and cd in the XY plane touch.
+func segmentsIntersect2D(a, b, c, d r3.Vector) bool {
+	onSegment := func(p, q, r r3.Vector) bool {
+		return math.Min(p.X, q.X) <= r.X && r.X <= math.Max(p.X, q.X) && math.Min(p.Y, q.Y) <= r.Y && r.Y <= math.Max(p.Y, q.Y)
+	}
+	d1, d2 := cross2D(c, d, a), cross2D(c, d, b)
+	d3, d4 := cross2D(a, b, c), cross2D(a, b, d)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(c, d, a)) || (d2 == 0 && onSegment(c, d, b)) ||
+		(d3 == 0 && onSegment(a, b, c)) || (d4 == 0 && onSegment(a, b, d))
+}
+
+// isEar returns whether the triangle prev, cur, next of a counterclockwise polygon is convex and contains none of the
+// polygon's other remaining vertices.
+func isEar(ring []r3.Vector, remaining []int, prev, cur, next int) bool {
+	a, b, c := ring[prev], ring[cur], ring[next]
+	if cross2D(a, b, c) <= 0 {
+		return false
+	}
+	for _, i := range remaining {
+		if i == prev || i == cur || i == next {
+			continue
+		}
+		p := ring[i]
+		if cross2D(a, b, p) >= 0 && cross2D(b, c, p) >= 0 && cross2D(c, a, p) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// cross2D returns the z component of (b - a) x (c - a), which is positive if a, b, c turn counterclockwise.
+func cross2D(a, b, c r3.Vector) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+// convexHull2D returns the convex hull of the points projected onto the XY plane, in counterclockwise order, using
+// Andrew's monotone chain algorithm.
+func convexHull2D(points []r3.Vector) []r3.Vector {
+	pts := make([]r3.Vector, 0, len(points))
+	for _, p := range points {
+		pts = append(pts, r3.Vector{X: p.X, Y: p.Y})
+	}
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i].X != pts[j].X {
+			return pts[i].X < pts[j].X
+		}
+		return pts[i].Y < pts[j].Y
+	})
+	if len(pts) < 3 {
+		return pts
+	}
+	hull := make([]r3.Vector, 0, 2*len(pts))
+	for _, p := range pts {
+		for len(hull) >= 2 && cross2D(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(pts) - 2; i >= 0; i-- {
+		p := pts[i]
+		for len(hull) >= lower && cross2D(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+func meshVertices(m *Mesh) []r3.Vector {
+	vertices := make([]r3.Vector, 0, 3*len(m.triangles))
+	for _, tri := range m.triangles {
+		for _, p := range tri.Points() {
+			vertices = append(vertices, TransformPointByPose(m.pose, p))
+		}
+	}
+	return vertices
+}
+
+func circlePoints(center r3.Vector, radius float64) []r3.Vector {
+	points := make([]r3.Vector, 0, footprintCirclePoints)
+	for i := range footprintCirclePoints {
+		theta := 2 * math.Pi * float64(i) / footprintCirclePoints
+		points = append(points, r3.Vector{X: center.X + radius*math.Cos(theta), Y: center.Y + radius*math.Sin(theta)})
+	}
+	return points
+}
+
+// meshBottomOutline returns the boundary of the flat bottom face of a mesh, such as a prism made by
+// NewGeoPolygonPrism, in counterclockwise order. It returns nil if the mesh has no flat bottom face bounded by a single
+// loop.
+func meshBottomOutline(m *Mesh) []r3.Vector {
+	minZ := math.Inf(1)
+	for _, tri := range m.triangles {
+		for _, p := range tri.Points() {
+			minZ = math.Min(minZ, TransformPointByPose(m.pose, p).Z)
+		}
+	}
+
+	type edge struct{ from, to string }
+	keyOf := func(p r3.Vector) string { return fmt.Sprintf("%.6f,%.6f", p.X, p.Y) }
+	points := map[string]r3.Vector{}
+	edges := map[edge]int{}
+	for _, tri := range m.triangles {
+		pts := tri.Points()
+		flat := true
+		for i := range pts {
+			pts[i] = TransformPointByPose(m.pose, pts[i])
+			if math.Abs(pts[i].Z-minZ) > floatEpsilon {
+				flat = false
+			}
+		}
+		if !flat {
+			continue
+		}
+		for i := range pts {
+			from, to := keyOf(pts[i]), keyOf(pts[(i+1)%3])
+			points[from] = r3.Vector{X: pts[i].X, Y: pts[i].Y}
+			edges[edge{from, to}]++
+		}
+	}
+
+	// boundary edges are the ones not shared with a neighbouring triangle of the face
+	next := map[string]string{}
+	for e := range edges {
+		if edges[edge{e.to, e.from}] == 0 {
+			next[e.from] = e.to
+		}
+	}
+	if len(next) < 3 {
+		return nil
+	}
+	var start string
+	for from := range next {
+		if start == "" || from < start {
+			start = from
+		}
+	}
+	outline := []r3.Vector{}
+	for key := start; ; {
+		outline = append(outline, points[key])
+		to, ok := next[key]
+		if !ok || len(outline) > len(next) {
+			return nil
+		}
+		if key = to; key == start {
+			break
+		}
+	}
+	if len(outline) != len(next) {
+		return nil
+	}
+	if polygonArea(outline) < 0 {
+		for i, j := 0, len(outline)-1; i < j; i, j = i+1, j-1 {
+			outline[i], outline[j] = outline[j], outline[i]
+		}
+	}
+	return outline
+}
@@ -0,0 +1,107 @@
+package spatialmath
+
+import (
+	"testing"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+)
+
+func TestGeoPolygonPrism(t *testing.T) {
+	// an L shaped polygon roughly 20m on a side, given clockwise and closed as in GeoJSON
+	lShape := []*geo.Point{
+		geo.NewPoint(40.0000, -74.0000),
+		geo.NewPoint(40.0002, -74.0000),
+		geo.NewPoint(40.0002, -73.9999),
+		geo.NewPoint(40.0001, -73.9999),
+		geo.NewPoint(40.0001, -73.9998),
+		geo.NewPoint(40.0000, -73.9998),
+		geo.NewPoint(40.0000, -74.0000),
+	}
+
+	gg, err := NewGeoPolygonPrism(lShape, 2000, "field")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, gg.Location().Lat(), test.ShouldAlmostEqual, 40.0001, 1e-4)
+	test.That(t, gg.Geometries(), test.ShouldHaveLength, 1)
+
+	mesh, ok := gg.Geometries()[0].(*Mesh)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, mesh.Label(), test.ShouldEqual, "field")
+	// 2(n-2) triangles for the top and bottom faces and 2n for the sides
+	test.That(t, mesh.Triangles(), test.ShouldHaveLength, 2*4+2*6)
+	for _, pt := range meshVertices(mesh) {
+		test.That(t, pt.Z == 1000 || pt.Z == -1000, test.ShouldBeTrue)
+	}
+
+	checkFootprint := func(gg *GeoGeometry) {
+		footprints := GeoGeometryFootprints(gg)
+		test.That(t, footprints, test.ShouldHaveLength, 1)
+		test.That(t, footprints[0], test.ShouldHaveLength, 6)
+		for _, want := range lShape[:6] {
+			found := false
+			for _, got := range footprints[0] {
+				if want.GreatCircleDistance(got) < 1e-5 {
+					found = true
+				}
+			}
+			test.That(t, found, test.ShouldBeTrue)
+		}
+	}
+	checkFootprint(gg)
+
+	// the polygon survives a round trip through the config
+	cfg, err := NewGeoGeometryConfig(gg)
+	test.That(t, err, test.ShouldBeNil)
+	fromCfg, err := GeoGeometriesFromConfig(cfg)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, fromCfg, test.ShouldHaveLength, 1)
+	checkFootprint(fromCfg[0])
+
+	t.Run("invalid polygons", func(t *testing.T) {
+		_, err := NewGeoPolygonPrism(lShape[:2], 2000, "")
+		test.That(t, err, test.ShouldNotBeNil)
+
+		_, err = NewGeoPolygonPrism(lShape, 0, "")
+		test.That(t, err, test.ShouldNotBeNil)
+
+		bowtie := []*geo.Point{
+			geo.NewPoint(40.0000, -74.0000),
+			geo.NewPoint(40.0001, -73.9999),
+			geo.NewPoint(40.0001, -74.0000),
+			geo.NewPoint(40.0000, -73.9999),
+		}
+		_, err = NewGeoPolygonPrism(bowtie, 2000, "")
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
+
+func TestFootprint(t *testing.T) {
+	b, err := NewBox(NewPose(r3.Vector{X: 10}, &OrientationVectorDegrees{OZ: 1, Theta: 90}), r3.Vector{X: 4, Y: 2, Z: 6}, "")
+	test.That(t, err, test.ShouldBeNil)
+	outline := footprint(b)
+	test.That(t, outline, test.ShouldHaveLength, 4)
+	test.That(t, polygonArea(outline), test.ShouldAlmostEqual, 8)
+	for _, pt := range outline {
+		test.That(t, pt.X, test.ShouldAlmostEqual, 10, 1+1e-6)
+		test.That(t, pt.Y, test.ShouldAlmostEqual, 0, 2+1e-6)
+	}
+
+	s, err := NewSphere(NewZeroPose(), 10, "")
+	test.That(t, err, test.ShouldBeNil)
+	outline = footprint(s)
+	test.That(t, outline, test.ShouldHaveLength, footprintCirclePoints)
+	for _, pt := range outline {
+		test.That(t, pt.Norm(), test.ShouldAlmostEqual, 10)
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+	// an L shape, whose notch is outside
+	polygon := []r3.Vector{{}, {X: 20}, {X: 20, Y: 10}, {X: 10, Y: 10}, {X: 10, Y: 20}, {Y: 20}}
+	test.That(t, PointInPolygon(r3.Vector{X: 5, Y: 5}, polygon), test.ShouldBeTrue)
+	test.That(t, PointInPolygon(r3.Vector{X: 15, Y: 5}, polygon), test.ShouldBeTrue)
+	test.That(t, PointInPolygon(r3.Vector{X: 5, Y: 15}, polygon), test.ShouldBeTrue)
+	test.That(t, PointInPolygon(r3.Vector{X: 15, Y: 15}, polygon), test.ShouldBeFalse)
+	test.That(t, PointInPolygon(r3.Vector{X: -1, Y: 5}, polygon), test.ShouldBeFalse)
+}